@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "consolidated_subdomains" ADD COLUMN "wildcard_suspect" BOOLEAN NOT NULL DEFAULT false;
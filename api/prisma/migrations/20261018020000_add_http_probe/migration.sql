@@ -0,0 +1,5 @@
+-- AlterTable
+ALTER TABLE "target_urls" ADD COLUMN "http_probe" JSONB;
+
+-- AlterTable
+ALTER TABLE "live_web_servers" ADD COLUMN "http_probe" JSONB;
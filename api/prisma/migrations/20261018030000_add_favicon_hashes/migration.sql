@@ -0,0 +1,15 @@
+-- AlterTable
+ALTER TABLE "target_urls" ADD COLUMN "favicon_mmh3" INTEGER,
+ADD COLUMN "favicon_sha256" TEXT,
+ADD COLUMN "favicon_url" TEXT;
+
+-- AlterTable
+ALTER TABLE "live_web_servers" ADD COLUMN "favicon_mmh3" INTEGER,
+ADD COLUMN "favicon_sha256" TEXT,
+ADD COLUMN "favicon_url" TEXT;
+
+-- CreateIndex
+CREATE INDEX "target_urls_favicon_mmh3_idx" ON "target_urls"("favicon_mmh3");
+
+-- CreateIndex
+CREATE INDEX "live_web_servers_favicon_mmh3_idx" ON "live_web_servers"("favicon_mmh3");
@@ -0,0 +1,8 @@
+-- AlterTable
+ALTER TABLE "target_urls" ADD COLUMN "technology_details" JSONB;
+
+-- AlterTable
+ALTER TABLE "live_web_servers" ADD COLUMN "technology_details" JSONB;
+
+-- AlterTable
+ALTER TABLE "consolidated_attack_surface_assets" ADD COLUMN "technology_details" JSONB;
@@ -0,0 +1,2 @@
+-- AlterEnum
+ALTER TYPE "AttackSurfaceAssetType" ADD VALUE 'vendor';
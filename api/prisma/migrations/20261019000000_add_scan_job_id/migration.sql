@@ -0,0 +1,80 @@
+-- AlterTable
+ALTER TABLE "amass_intel_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "amass_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "assetfinder_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "censys_company_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "cewl_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "cloud_enum_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "ctl_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "ffuf_url_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "gau_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "gau_url_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "github_recon_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "gospider_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "httpx_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "ip_port_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "katana_url_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "linkfinder_url_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "metabigor_company_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "metadata_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "nuclei_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "nuclei_screenshots" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "securitytrails_company_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "shodan_company_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "shuffledns_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "subdomainizer_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "subfinder_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "sublist3r_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
+
+-- AlterTable
+ALTER TABLE "waybackurls_scans" ADD COLUMN IF NOT EXISTS "job_id" TEXT;
@@ -0,0 +1,38 @@
+-- CreateTable
+CREATE TABLE IF NOT EXISTS "scan_schedules" (
+    "id" TEXT NOT NULL DEFAULT (gen_random_uuid())::text,
+    "scope_target_id" TEXT NOT NULL,
+    "name" TEXT NOT NULL DEFAULT '',
+    "cron_expression" TEXT NOT NULL,
+    "timezone" TEXT NOT NULL DEFAULT 'UTC',
+    "jitter_seconds" INTEGER NOT NULL DEFAULT 0,
+    "mode" TEXT NOT NULL DEFAULT 'tools',
+    "tools" TEXT[] DEFAULT ARRAY[]::TEXT[],
+    "auto_scan_config" JSONB,
+    "misfire_policy" TEXT NOT NULL DEFAULT 'run_once',
+    "enabled" BOOLEAN NOT NULL DEFAULT true,
+    "next_run_at" TIMESTAMPTZ(6),
+    "scheduled_run_at" TIMESTAMPTZ(6),
+    "last_run_at" TIMESTAMPTZ(6),
+    "last_run_status" TEXT,
+    "last_run_error" TEXT,
+    "last_run_scan_ids" TEXT[],
+    "last_session_id" TEXT,
+    "created_at" TIMESTAMPTZ(6) DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMPTZ(6) DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "scan_schedules_pkey" PRIMARY KEY ("id")
+);
+
+-- AlterTable
+ALTER TABLE "scan_schedules" ADD COLUMN IF NOT EXISTS "scheduled_run_at" TIMESTAMPTZ(6);
+
+-- CreateIndex
+CREATE INDEX IF NOT EXISTS "scan_schedules_due_idx" ON "scan_schedules"("next_run_at");
+
+-- CreateIndex
+CREATE INDEX IF NOT EXISTS "scan_schedules_scope_target_idx" ON "scan_schedules"("scope_target_id");
+
+-- AddForeignKey
+ALTER TABLE "scan_schedules" DROP CONSTRAINT IF EXISTS "scan_schedules_scope_target_id_fkey";
+ALTER TABLE "scan_schedules" ADD CONSTRAINT "scan_schedules_scope_target_id_fkey" FOREIGN KEY ("scope_target_id") REFERENCES "scope_targets"("id") ON DELETE CASCADE ON UPDATE CASCADE;
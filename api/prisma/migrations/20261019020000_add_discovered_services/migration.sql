@@ -0,0 +1,26 @@
+-- CreateTable
+CREATE TABLE IF NOT EXISTS "discovered_services" (
+    "id" TEXT NOT NULL DEFAULT (gen_random_uuid())::text,
+    "scan_id" TEXT NOT NULL,
+    "ip_address" INET NOT NULL,
+    "port" INTEGER NOT NULL,
+    "protocol" VARCHAR(10) NOT NULL DEFAULT 'tcp',
+    "service" VARCHAR(50) NOT NULL,
+    "product" TEXT,
+    "version" TEXT,
+    "banner" TEXT,
+    "tls" BOOLEAN DEFAULT false,
+    "discovered_at" TIMESTAMP(3) DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "discovered_services_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX IF NOT EXISTS "discovered_services_scan_id_ip_address_port_key" ON "discovered_services"("scan_id", "ip_address", "port");
+
+-- CreateIndex
+CREATE INDEX IF NOT EXISTS "idx_discovered_services_scan_id" ON "discovered_services"("scan_id");
+
+-- AddForeignKey
+ALTER TABLE "discovered_services" DROP CONSTRAINT IF EXISTS "discovered_services_scan_id_fkey";
+ALTER TABLE "discovered_services" ADD CONSTRAINT "discovered_services_scan_id_fkey" FOREIGN KEY ("scan_id") REFERENCES "ip_port_scans"("scan_id") ON DELETE CASCADE ON UPDATE CASCADE;
@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "consolidated_attack_surface_assets" ADD COLUMN IF NOT EXISTS "cloud_tag_source" TEXT;
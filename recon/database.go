@@ -1298,33 +1298,8 @@ func createTables() {
 		}
 	}
 
-	deletePendingScansQuery := `
-		DELETE FROM amass_scans WHERE status = 'pending';
-		DELETE FROM amass_intel_scans WHERE status = 'pending';
-		DELETE FROM httpx_scans WHERE status = 'pending';
-		DELETE FROM gau_scans WHERE status = 'pending';
-		DELETE FROM sublist3r_scans WHERE status = 'pending';
-		DELETE FROM assetfinder_scans WHERE status = 'pending';
-		DELETE FROM ctl_scans WHERE status = 'pending';
-		DELETE FROM subfinder_scans WHERE status = 'pending';
-		DELETE FROM shuffledns_scans WHERE status = 'pending';
-		DELETE FROM cewl_scans WHERE status = 'pending';
-		DELETE FROM shufflednscustom_scans WHERE status = 'pending';
-		DELETE FROM gospider_scans WHERE status = 'pending';
-		DELETE FROM subdomainizer_scans WHERE status = 'pending';
-		DELETE FROM nuclei_screenshots WHERE status = 'pending';
-		DELETE FROM metadata_scans WHERE status = 'pending';
-		DELETE FROM ip_port_scans WHERE status = 'pending';
-		DELETE FROM katana_company_scans WHERE status = 'pending' OR status = 'running';
-		DELETE FROM amass_enum_company_scans WHERE status = 'pending' OR status = 'running';
-		DELETE FROM nuclei_scans WHERE status = 'pending' OR status = 'running';`
-
-	_, err := dbPool.Exec(context.Background(), deletePendingScansQuery)
-	if err != nil {
-		log.Printf("[WARN] Failed to delete pending scans: %v", err)
-	} else {
-		log.Println("[INFO] Deleted any scans with status 'pending'")
-	}
+	// Pending and running scans are no longer deleted here: they are backed by
+	// scan_jobs and utils.StartScanJobWorkers resumes them after a restart.
 
 	log.Println("[INFO] Database schema created successfully")
 }
@@ -10,7 +10,6 @@ import (
 	"math"
 	"net/http"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
@@ -30,6 +29,15 @@ func min(a, b int) int {
 }
 
 func main() {
+	// `recon-engine import-cloud-ranges [provider...]` refreshes the cloud
+	// provider IP range files and exits
+	if len(os.Args) > 1 && os.Args[1] == "import-cloud-ranges" {
+		if err := utils.ImportCloudRanges(context.Background(), os.Args[2:]); err != nil {
+			log.Fatalf("Cloud range import incomplete: %v", err)
+		}
+		return
+	}
+
 	connStr := os.Getenv("DATABASE_URL")
 	if connStr == "" {
 		log.Fatal("Environment variable DATABASE_URL is not set")
@@ -54,6 +62,12 @@ func main() {
 	utils.InitDB(dbPool)
 	defer dbPool.Close()
 
+	utils.RegisterScanJobKind("nuclei", "nuclei_scans", executeNucleiScanJob)
+	utils.StartScanJobWorkers(context.Background())
+	utils.ResumeAutoScanSessions()
+	utils.StartScanScheduler(context.Background())
+	utils.StartResolverHealthMonitor(context.Background())
+
 	// NOTE: createTables() is now managed by Prisma
 	// Run: npx prisma migrate dev --name init
 	// before starting the engine
@@ -69,9 +83,21 @@ func main() {
 	r.HandleFunc("/scopetarget/read", utils.ReadScopeTarget).Methods("GET", "OPTIONS")
 	r.HandleFunc("/scopetarget/delete/{id}", utils.DeleteScopeTarget).Methods("DELETE", "OPTIONS")
 	r.HandleFunc("/scopetarget/{id}/activate", utils.ActivateScopeTarget).Methods("POST", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/amass", utils.GetAmassScansForScopeTarget).Methods("GET", "OPTIONS")
-	r.HandleFunc("/amass/run", utils.RunAmassScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/amass/{scanID}", utils.GetAmassScanStatus).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/amass", utils.WithTool("amass", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/schedules", utils.GetScanSchedulesForScopeTarget).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/schedules", utils.CreateScanSchedule).Methods("POST", "OPTIONS")
+	r.HandleFunc("/schedules/preview", utils.PreviewScanSchedule).Methods("GET", "OPTIONS")
+	r.HandleFunc("/schedules/{schedule_id}", utils.GetScanSchedule).Methods("GET", "OPTIONS")
+	r.HandleFunc("/schedules/{schedule_id}", utils.UpdateScanSchedule).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/schedules/{schedule_id}", utils.DeleteScanSchedule).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/schedules/{schedule_id}/next-runs", utils.GetScanScheduleNextRuns).Methods("GET", "OPTIONS")
+	r.HandleFunc("/jobs", utils.GetScanJobs).Methods("GET", "OPTIONS")
+	r.HandleFunc("/jobs/{job_id}/retry", utils.RetryScanJob).Methods("POST", "OPTIONS")
+	r.HandleFunc("/scans/{scan_id}/stream", utils.StreamScan).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scans/{scan_id}/job", utils.GetScanJobForScan).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scan/{tool}/{scan_id}/cancel", utils.CancelScanHandler).Methods("POST", "OPTIONS")
+	r.HandleFunc("/amass/run", utils.WithTool("amass", utils.RunToolScan)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/amass/{scanID}", utils.WithTool("amass", utils.GetToolScan)).Methods("GET", "OPTIONS")
 	r.HandleFunc("/amass/{scan_id}/dns", utils.GetDNSRecords).Methods("GET", "OPTIONS")
 	r.HandleFunc("/amass/{scan_id}/ip", utils.GetIPs).Methods("GET", "OPTIONS")
 	r.HandleFunc("/amass/{scan_id}/subdomain", utils.GetSubdomains).Methods("GET", "OPTIONS")
@@ -79,9 +105,9 @@ func main() {
 	r.HandleFunc("/amass/{scan_id}/sp", utils.GetServiceProviders).Methods("GET", "OPTIONS")
 	r.HandleFunc("/amass/{scan_id}/asn", utils.GetASNs).Methods("GET", "OPTIONS")
 	r.HandleFunc("/amass/{scan_id}/subnet", utils.GetSubnets).Methods("GET", "OPTIONS")
-	r.HandleFunc("/amass-intel/run", utils.RunAmassIntelScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/amass-intel/{scanID}", utils.GetAmassIntelScanStatus).Methods("GET", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/amass-intel", utils.GetAmassIntelScansForScopeTarget).Methods("GET", "OPTIONS")
+	r.HandleFunc("/amass-intel/run", utils.WithTool("amass-intel", utils.RunToolScan)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/amass-intel/{scanID}", utils.WithTool("amass-intel", utils.GetToolScan)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/amass-intel", utils.WithTool("amass-intel", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
 	r.HandleFunc("/amass-intel/{scan_id}/networks", utils.GetIntelNetworkRanges).Methods("GET", "OPTIONS")
 	r.HandleFunc("/amass-intel/{scan_id}/asn", utils.GetIntelASNData).Methods("GET", "OPTIONS")
 	r.HandleFunc("/amass-intel/network-range/{id}", utils.DeleteIntelNetworkRange).Methods("DELETE", "OPTIONS")
@@ -90,27 +116,27 @@ func main() {
 	r.HandleFunc("/httpx/{scanID}", utils.GetHttpxScanStatus).Methods("GET", "OPTIONS")
 	r.HandleFunc("/scopetarget/{id}/scans/httpx", utils.GetHttpxScansForScopeTarget).Methods("GET", "OPTIONS")
 	r.HandleFunc("/scopetarget/{id}/scans", utils.GetAllScansForScopeTarget).Methods("GET", "OPTIONS")
-	r.HandleFunc("/gau/run", utils.RunGauScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/gau/{scanID}", utils.GetGauScanStatus).Methods("GET", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/gau", utils.GetGauScansForScopeTarget).Methods("GET", "OPTIONS")
-	r.HandleFunc("/sublist3r/run", utils.RunSublist3rScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/sublist3r/{scan_id}", utils.GetSublist3rScanStatus).Methods("GET", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/sublist3r", utils.GetSublist3rScansForScopeTarget).Methods("GET", "OPTIONS")
-	r.HandleFunc("/assetfinder/run", utils.RunAssetfinderScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/assetfinder/{scan_id}", utils.GetAssetfinderScanStatus).Methods("GET", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/assetfinder", utils.GetAssetfinderScansForScopeTarget).Methods("GET", "OPTIONS")
-	r.HandleFunc("/ctl/run", utils.RunCTLScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/ctl/{scan_id}", utils.GetCTLScanStatus).Methods("GET", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/ctl", utils.GetCTLScansForScopeTarget).Methods("GET", "OPTIONS")
-	r.HandleFunc("/ctl-company/run", utils.RunCTLCompanyScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/ctl-company/{scan_id}", utils.GetCTLCompanyScanStatus).Methods("GET", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/ctl-company", utils.GetCTLCompanyScansForScopeTarget).Methods("GET", "OPTIONS")
-	r.HandleFunc("/cloud-enum/run", utils.RunCloudEnumScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/cloud-enum/{scan_id}", utils.GetCloudEnumScanStatus).Methods("GET", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/cloud-enum", utils.GetCloudEnumScansForScopeTarget).Methods("GET", "OPTIONS")
-	r.HandleFunc("/metabigor-company/run", utils.RunMetabigorCompanyScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/metabigor-company/{scan_id}", utils.GetMetabigorCompanyScanStatus).Methods("GET", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/metabigor-company", utils.GetMetabigorCompanyScansForScopeTarget).Methods("GET", "OPTIONS")
+	r.HandleFunc("/gau/run", utils.WithTool("gau", utils.RunToolScan)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/gau/{scanID}", utils.WithTool("gau", utils.GetToolScan)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/gau", utils.WithTool("gau", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/sublist3r/run", utils.WithTool("sublist3r", utils.RunToolScan)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/sublist3r/{scan_id}", utils.WithTool("sublist3r", utils.GetToolScan)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/sublist3r", utils.WithTool("sublist3r", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/assetfinder/run", utils.WithTool("assetfinder", utils.RunToolScan)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/assetfinder/{scan_id}", utils.WithTool("assetfinder", utils.GetToolScan)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/assetfinder", utils.WithTool("assetfinder", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/ctl/run", utils.WithTool("ctl", utils.RunToolScan)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/ctl/{scan_id}", utils.WithTool("ctl", utils.GetToolScan)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/ctl", utils.WithTool("ctl", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/ctl-company/run", utils.WithTool("ctl-company", utils.RunToolScan)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/ctl-company/{scan_id}", utils.WithTool("ctl-company", utils.GetToolScan)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/ctl-company", utils.WithTool("ctl-company", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/cloud-enum/run", utils.WithTool("cloud-enum", utils.RunToolScan)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/cloud-enum/{scan_id}", utils.WithTool("cloud-enum", utils.GetToolScan)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/cloud-enum", utils.WithTool("cloud-enum", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/metabigor-company/run", utils.WithTool("metabigor-company", utils.RunToolScan)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/metabigor-company/{scan_id}", utils.WithTool("metabigor-company", utils.GetToolScan)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/metabigor-company", utils.WithTool("metabigor-company", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
 	r.HandleFunc("/metabigor-company/{scan_id}/networks", utils.GetMetabigorNetworkRanges).Methods("GET", "OPTIONS")
 	r.HandleFunc("/metabigor/network-range/{id}", utils.DeleteMetabigorNetworkRange).Methods("DELETE", "OPTIONS")
 	r.HandleFunc("/metabigor/scan/{scan_id}/network-ranges", utils.DeleteAllMetabigorNetworkRanges).Methods("DELETE", "OPTIONS")
@@ -119,11 +145,13 @@ func main() {
 	r.HandleFunc("/metabigor-asn/run", utils.RunMetabigorASNScan).Methods("POST", "OPTIONS")
 	r.HandleFunc("/metabigor-ip/run", utils.RunMetabigorIPIntelligence).Methods("POST", "OPTIONS")
 	r.HandleFunc("/metabigor-ip/{scan_id}/intelligence", utils.GetMetabigorIPIntelligence).Methods("GET", "OPTIONS")
-	r.HandleFunc("/subfinder/run", utils.RunSubfinderScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/subfinder/{scan_id}", utils.GetSubfinderScanStatus).Methods("GET", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/subfinder", utils.GetSubfinderScansForScopeTarget).Methods("GET", "OPTIONS")
+	r.HandleFunc("/subfinder/run", utils.WithTool("subfinder", utils.RunToolScan)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/subfinder/{scan_id}", utils.WithTool("subfinder", utils.GetToolScan)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/subfinder", utils.WithTool("subfinder", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
 	r.HandleFunc("/consolidate-subdomains/{id}", utils.HandleConsolidateSubdomains).Methods("GET", "OPTIONS")
 	r.HandleFunc("/consolidated-subdomains/{id}", utils.GetConsolidatedSubdomains).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/wildcard-dns", utils.GetWildcardDNS).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/wildcard-dns/config", utils.SaveWildcardDNSConfig).Methods("POST", "OPTIONS")
 	r.HandleFunc("/consolidate-company-domains/{id}", utils.HandleConsolidateCompanyDomains).Methods("GET", "OPTIONS")
 	r.HandleFunc("/consolidated-company-domains/{id}", utils.GetConsolidatedCompanyDomains).Methods("GET", "OPTIONS")
 	r.HandleFunc("/consolidate-network-ranges/{id}", utils.HandleConsolidateNetworkRanges).Methods("GET", "OPTIONS")
@@ -132,30 +160,30 @@ func main() {
 	r.HandleFunc("/investigate-fqdns/{scope_target_id}", utils.InvestigateFQDNs).Methods("POST", "OPTIONS")
 	r.HandleFunc("/attack-surface-asset-counts/{scope_target_id}", utils.GetAttackSurfaceAssetCounts).Methods("GET", "OPTIONS")
 	r.HandleFunc("/attack-surface-assets/{scope_target_id}", utils.GetAttackSurfaceAssets).Methods("GET", "OPTIONS")
-	r.HandleFunc("/shuffledns/run", utils.RunShuffleDNSScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/shuffledns/{scan_id}", utils.GetShuffleDNSScanStatus).Methods("GET", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/shuffledns", utils.GetShuffleDNSScansForScopeTarget).Methods("GET", "OPTIONS")
-	r.HandleFunc("/cewl/run", utils.RunCeWLScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/cewl/{scan_id}", utils.GetCeWLScanStatus).Methods("GET", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/cewl", utils.GetCeWLScansForScopeTarget).Methods("GET", "OPTIONS")
+	r.HandleFunc("/shuffledns/run", utils.WithTool("shuffledns", utils.RunToolScan)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/shuffledns/{scan_id}", utils.WithTool("shuffledns", utils.GetToolScan)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/shuffledns", utils.WithTool("shuffledns", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/cewl/run", utils.WithTool("cewl", utils.RunToolScan)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/cewl/{scan_id}", utils.WithTool("cewl", utils.GetToolScan)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/cewl", utils.WithTool("cewl", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
 	r.HandleFunc("/cewl-urls/run", utils.RunCeWLScansForUrls).Methods("POST", "OPTIONS")
 	r.HandleFunc("/cewl-wordlist/run", utils.RunShuffleDNSWithWordlist).Methods("POST", "OPTIONS")
-	r.HandleFunc("/cewl-wordlist/{scan_id}", utils.GetShuffleDNSScanStatus).Methods("GET", "OPTIONS")
+	r.HandleFunc("/cewl-wordlist/{scan_id}", utils.WithTool("cewl-wordlist", utils.GetToolScan)).Methods("GET", "OPTIONS")
 	r.HandleFunc("/api/scope-targets/{id}/shufflednscustom-scans", utils.GetShuffleDNSCustomScansForScopeTarget).Methods("GET", "OPTIONS")
-	r.HandleFunc("/gospider/run", utils.RunGoSpiderScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/gospider/{scan_id}", utils.GetGoSpiderScanStatus).Methods("GET", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/gospider", utils.GetGoSpiderScansForScopeTarget).Methods("GET", "OPTIONS")
-	r.HandleFunc("/subdomainizer/run", utils.RunSubdomainizerScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/subdomainizer/{scan_id}", utils.GetSubdomainizerScanStatus).Methods("GET", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/subdomainizer", utils.GetSubdomainizerScansForScopeTarget).Methods("GET", "OPTIONS")
+	r.HandleFunc("/gospider/run", utils.WithTool("gospider", utils.RunToolScan)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/gospider/{scan_id}", utils.WithTool("gospider", utils.GetToolScan)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/gospider", utils.WithTool("gospider", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/subdomainizer/run", utils.WithTool("subdomainizer", utils.RunToolScan)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/subdomainizer/{scan_id}", utils.WithTool("subdomainizer", utils.GetToolScan)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/subdomainizer", utils.WithTool("subdomainizer", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
 	r.HandleFunc("/scopetarget/{id}/nuclei-screenshot/run", utils.RunNucleiScreenshotScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/nuclei-screenshot", utils.GetNucleiScreenshotScansForScopeTarget).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/nuclei-screenshot", utils.WithTool("nuclei-screenshot", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
 	r.HandleFunc("/nuclei-screenshot/run", utils.RunNucleiScreenshotScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/nuclei-screenshot/{scan_id}", utils.GetNucleiScreenshotScanStatus).Methods("GET", "OPTIONS")
+	r.HandleFunc("/nuclei-screenshot/{scan_id}", utils.WithTool("nuclei-screenshot", utils.GetToolScan)).Methods("GET", "OPTIONS")
 	r.HandleFunc("/api/scope-targets/{id}/target-urls", utils.GetTargetURLsForScopeTarget).Methods("GET", "OPTIONS")
 	r.HandleFunc("/metadata/run", utils.RunMetaDataScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/metadata/{scan_id}", utils.GetMetaDataScanStatus).Methods("GET", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/metadata", utils.GetMetaDataScansForScopeTarget).Methods("GET", "OPTIONS")
+	r.HandleFunc("/metadata/{scan_id}", utils.WithTool("metadata", utils.GetToolScan)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/metadata", utils.WithTool("metadata", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
 	r.HandleFunc("/metadata/run-company", utils.RunCompanyMetaDataScan).Methods("POST", "OPTIONS")
 	r.HandleFunc("/ip-port-scan/{scan_id}/metadata-scans", utils.GetCompanyMetaDataScansForIPPortScan).Methods("GET", "OPTIONS")
 	r.HandleFunc("/ip-port-scan/{scan_id}/metadata-results", utils.GetCompanyMetaDataResults).Methods("GET", "OPTIONS")
@@ -200,20 +228,20 @@ func main() {
 	r.HandleFunc("/api/ai-api-keys", createAiAPIKey).Methods("POST", "OPTIONS")
 	r.HandleFunc("/api/ai-api-keys/{id}", updateAiAPIKey).Methods("PUT", "OPTIONS")
 	r.HandleFunc("/api/ai-api-keys/{id}", deleteAiAPIKey).Methods("DELETE", "OPTIONS")
-	r.HandleFunc("/securitytrails-company/run", utils.RunSecurityTrailsCompanyScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/securitytrails-company/status/{scan_id}", utils.GetSecurityTrailsCompanyScanStatus).Methods("GET", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/securitytrails-company", utils.GetSecurityTrailsCompanyScansForScopeTarget).Methods("GET", "OPTIONS")
-	r.HandleFunc("/censys-company/run", utils.RunCensysCompanyScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/censys-company/status/{scan_id}", utils.GetCensysCompanyScanStatus).Methods("GET", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/censys-company", utils.GetCensysCompanyScansForScopeTarget).Methods("GET", "OPTIONS")
+	r.HandleFunc("/securitytrails-company/run", utils.WithTool("securitytrails-company", utils.RunToolScan)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/securitytrails-company/status/{scan_id}", utils.WithTool("securitytrails-company", utils.GetToolScan)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/securitytrails-company", utils.WithTool("securitytrails-company", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/censys-company/run", utils.WithTool("censys-company", utils.RunToolScan)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/censys-company/status/{scan_id}", utils.WithTool("censys-company", utils.GetToolScan)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/censys-company", utils.WithTool("censys-company", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
 	r.HandleFunc("/shodan-company/run", utils.RunShodanCompanyScan).Methods("POST", "OPTIONS")
 	r.HandleFunc("/shodan-company/status/{scan_id}", utils.GetShodanCompanyScanStatus).Methods("GET", "OPTIONS")
 	r.HandleFunc("/scopetarget/{id}/scans/shodan-company", utils.GetShodanCompanyScansForScopeTarget).Methods("GET", "OPTIONS")
 
 	// GitHub Recon routes
-	r.HandleFunc("/github-recon/run", utils.RunGitHubReconScan).Methods("POST", "OPTIONS")
-	r.HandleFunc("/github-recon/status/{scan_id}", utils.GetGitHubReconScanStatus).Methods("GET", "OPTIONS")
-	r.HandleFunc("/scopetarget/{id}/scans/github-recon", utils.GetGitHubReconScansForScopeTarget).Methods("GET", "OPTIONS")
+	r.HandleFunc("/github-recon/run", utils.WithTool("github-recon", utils.RunToolScan)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/github-recon/status/{scan_id}", utils.WithTool("github-recon", utils.GetToolScan)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/github-recon", utils.WithTool("github-recon", utils.GetToolScansForScopeTarget)).Methods("GET", "OPTIONS")
 
 	// IP/Port scan routes
 	r.HandleFunc("/ip-port-scan/run", utils.RunIPPortScan).Methods("POST", "OPTIONS")
@@ -221,6 +249,29 @@ func main() {
 	r.HandleFunc("/scopetarget/{id}/scans/ip-port", utils.GetIPPortScansForScopeTarget).Methods("GET", "OPTIONS")
 	r.HandleFunc("/ip-port-scan/{scan_id}/live-web-servers", utils.GetLiveWebServers).Methods("GET", "OPTIONS")
 	r.HandleFunc("/ip-port-scan/{scan_id}/discovered-ips", utils.GetDiscoveredIPs).Methods("GET", "OPTIONS")
+	r.HandleFunc("/ip-port-scan/{scan_id}/services", utils.GetDiscoveredServices).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/tls-fingerprints", utils.GetTLSFingerprintGroups).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/tls-posture", utils.GetTLSPostureResults).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/subdomain-takeovers", utils.GetTakeoverFindings).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/subdomain-takeovers/run", utils.RunTakeoverDetectionHandler).Methods("POST", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/favicons", utils.GetFaviconGroups).Methods("GET", "OPTIONS")
+	r.HandleFunc("/favicons", utils.GetFaviconGroups).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/ptr-sweep/progress", utils.GetPTRSweepProgress).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/ptr-records", utils.GetPTRSweepRecords).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/vhosts", utils.GetVHostBindings).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/mail-posture", utils.GetMailPosture).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/mail-posture/run", utils.RunMailPostureHandler).Methods("POST", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/vendors", utils.GetVendorInventory).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/vendors/run", utils.RunVendorAttributionHandler).Methods("POST", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/nameserver-posture", utils.GetNameserverPosture).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/nameserver-posture/run", utils.RunNameserverPostureHandler).Methods("POST", "OPTIONS")
+	r.HandleFunc("/cloud-ranges", utils.GetCloudRanges).Methods("GET", "OPTIONS")
+	r.HandleFunc("/cloud-ranges/import", utils.ImportCloudRangesHandler).Methods("POST", "OPTIONS")
+	r.HandleFunc("/dns-resolvers", utils.GetDNSResolvers).Methods("GET", "OPTIONS")
+	r.HandleFunc("/dns-resolvers", utils.UploadDNSResolvers).Methods("POST", "OPTIONS")
+	r.HandleFunc("/dns-resolvers/validate", utils.ValidateDNSResolvers).Methods("POST", "OPTIONS")
+	r.HandleFunc("/ip-port-scan-config/{scope_target_id}", utils.GetIPPortScanConfig).Methods("GET", "OPTIONS")
+	r.HandleFunc("/ip-port-scan-config/{scope_target_id}", utils.SaveIPPortScanConfig).Methods("POST", "OPTIONS")
 
 	// Company domain management routes
 	r.HandleFunc("/api/company-domains/{scope_target_id}/{tool}", getCompanyDomainsByTool).Methods("GET", "OPTIONS")
@@ -336,6 +387,12 @@ func main() {
 	r.HandleFunc("/threat-model/{threat_id}", utils.UpdateThreatModel).Methods("PUT", "OPTIONS")
 	r.HandleFunc("/threat-model/{threat_id}", utils.DeleteThreatModel).Methods("DELETE", "OPTIONS")
 
+	// Generic tool routes. Registered last so the per-tool routes above win.
+	r.HandleFunc("/tools", utils.GetTools).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scans/{tool}/run", utils.RunToolScan).Methods("POST", "OPTIONS")
+	r.HandleFunc("/scans/{tool}/{id}", utils.GetToolScan).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/{tool}", utils.GetToolScansForScopeTarget).Methods("GET", "OPTIONS")
+
 	log.Println("API server started on :8443")
 	http.ListenAndServe(":8443", r)
 }
@@ -940,6 +997,7 @@ func startAutoScanSession(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to create session", http.StatusInternalServerError)
 		return
 	}
+	utils.StartAutoScanSession(sessionID, req.ScopeTargetID)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{"session_id": sessionID})
 }
@@ -1046,6 +1104,11 @@ func cancelAutoScanSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Stop the session's tools too, not just the session row
+	if status == "cancelled" {
+		utils.CancelAutoScanSessionScans(sessionID)
+	}
+
 	// Verify the update was successful
 	var newStatus string
 	err = dbPool.QueryRow(context.Background(), `SELECT status FROM auto_scan_sessions WHERE id = $1`,
@@ -1871,6 +1934,8 @@ func getCompanyDomainsByTool(w http.ResponseWriter, r *http.Request) {
 		domains, err = utils.GetShodanCompanyDomainsForTool(scopeTargetID)
 	case "live_web_servers":
 		domains, err = utils.GetLiveWebServerDomainsForTool(scopeTargetID)
+	case "ptr_sweep":
+		domains, err = utils.GetPTRSweepDomainsForTool(scopeTargetID)
 	default:
 		http.Error(w, "Invalid tool specified", http.StatusBadRequest)
 		return
@@ -1929,6 +1994,8 @@ func deleteCompanyDomainFromTool(w http.ResponseWriter, r *http.Request) {
 		success, err = utils.DeleteShodanCompanyDomainFromTool(scopeTargetID, domain)
 	case "live_web_servers":
 		success, err = utils.DeleteLiveWebServerDomainFromTool(scopeTargetID, domain)
+	case "ptr_sweep":
+		success, err = utils.DeletePTRSweepDomainFromTool(scopeTargetID, domain)
 	default:
 		log.Printf("[DOMAIN-API] [ERROR] Invalid tool specified: %s", tool)
 		http.Error(w, "Invalid tool specified", http.StatusBadRequest)
@@ -1994,6 +2061,8 @@ func deleteAllCompanyDomainsFromTool(w http.ResponseWriter, r *http.Request) {
 		count, err = utils.DeleteAllShodanCompanyDomainsFromTool(scopeTargetID)
 	case "live_web_servers":
 		count, err = utils.DeleteAllLiveWebServerDomainsFromTool(scopeTargetID)
+	case "ptr_sweep":
+		count, err = utils.DeleteAllPTRSweepDomainsFromTool(scopeTargetID)
 	default:
 		log.Printf("[DOMAIN-API] [ERROR] Invalid tool specified: %s", tool)
 		http.Error(w, "Invalid tool specified", http.StatusBadRequest)
@@ -2827,7 +2896,6 @@ func buildWordlistFromDomains(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Build wordlist in container using the same pattern as resolvers
-	containerName := "ars0n-framework-v2-cloud_enum-1"
 	tempFile := fmt.Sprintf("/tmp/generated_%s_%s.txt", wordlistType, scopeTargetID)
 
 	// Create the wordlist generation script
@@ -2854,7 +2922,7 @@ EOF
 	log.Printf("[BUILD-WORDLIST] Extracted %d unique words from domains", len(domainWordsList))
 
 	// First test if rs0nfuzz.txt exists in container
-	testCmd := exec.Command("docker", "exec", containerName, "test", "-f", "/app/rs0nfuzz.txt")
+	testCmd := utils.NewToolCommand("cloud_enum", "test", "-f", "/app/rs0nfuzz.txt")
 	if err := testCmd.Run(); err != nil {
 		log.Printf("[BUILD-WORDLIST] rs0nfuzz.txt not found in container, using fallback approach")
 
@@ -2884,7 +2952,7 @@ EOF
 	log.Printf("[BUILD-WORDLIST] rs0nfuzz.txt found in container, executing script...")
 
 	// Execute script in container
-	cmd := exec.Command("docker", "exec", containerName, "sh", "-c", createScript)
+	cmd := utils.NewToolCommand("cloud_enum", "sh", "-c", createScript)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		log.Printf("[BUILD-WORDLIST] Error creating wordlist in container: %v", err)
@@ -2896,11 +2964,8 @@ EOF
 
 	// Copy the generated wordlist back to host for download
 	hostTempFile := fmt.Sprintf("/tmp/wordlist_%s_%s.txt", wordlistType, scopeTargetID)
-	copyCmd := exec.Command("docker", "cp", fmt.Sprintf("%s:%s", containerName, tempFile), hostTempFile)
-	copyOutput, copyErr := copyCmd.CombinedOutput()
-	if copyErr != nil {
+	if copyErr := utils.CopyFromTool("cloud_enum", tempFile, hostTempFile); copyErr != nil {
 		log.Printf("[BUILD-WORDLIST] Error copying wordlist from container: %v", copyErr)
-		log.Printf("[BUILD-WORDLIST] Copy output: %s", string(copyOutput))
 		http.Error(w, "Failed to retrieve wordlist", http.StatusInternalServerError)
 		return
 	}
@@ -2944,7 +3009,7 @@ EOF
 	os.Remove(hostTempFile)
 
 	// Clean up container temp file
-	cleanupCmd := exec.Command("docker", "exec", containerName, "rm", "-f", tempFile)
+	cleanupCmd := utils.NewToolCommand("cloud_enum", "rm", "-f", tempFile)
 	cleanupCmd.Run()
 }
 
@@ -3447,84 +3512,114 @@ func startNucleiScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Start scan in background goroutine
-	go func() {
-		log.Printf("[INFO] Starting background Nuclei scan %s", scanID)
+	// Queue the scan; a job worker runs executeNucleiScanJob
+	if _, err := utils.EnqueueScanJob("nuclei", scanID, nucleiScanJobPayload{
+		ScopeTargetID:     scopeTargetID,
+		Targets:           targets,
+		Templates:         templates,
+		Severities:        severities,
+		UploadedTemplates: uploadedTemplates,
+	}); err != nil {
+		log.Printf("[ERROR] Failed to queue Nuclei scan: %v", err)
+		http.Error(w, "Failed to queue scan", http.StatusInternalServerError)
+		return
+	}
 
-		// Update status to running
-		_, err := dbPool.Exec(context.Background(), `
-			UPDATE nuclei_scans SET status = 'running', updated_at = NOW() WHERE scan_id = $1
-		`, scanID)
+	// Return scan ID immediately
+	response := map[string]string{
+		"scan_id": scanID,
+		"status":  "pending",
+		"message": "Nuclei scan started successfully",
+	}
 
-		if err != nil {
-			log.Printf("[ERROR] Failed to update scan status to running: %v", err)
-			return
-		}
+	log.Printf("[INFO] Nuclei scan %s initiated for scope target %s", scanID, scopeTargetID)
+	json.NewEncoder(w).Encode(response)
+}
 
-		startTime := time.Now()
+type nucleiScanJobPayload struct {
+	ScopeTargetID     string                   `json:"scope_target_id"`
+	Targets           []string                 `json:"targets"`
+	Templates         []string                 `json:"templates"`
+	Severities        []string                 `json:"severities"`
+	UploadedTemplates []map[string]interface{} `json:"uploaded_templates,omitempty"`
+}
 
-		// Execute the scan
-		outputFile, findings, err := utils.ExecuteNucleiScanForScopeTarget(scopeTargetID, targets, templates, severities, uploadedTemplates, dbPool)
+func executeNucleiScanJob(ctx context.Context, job *utils.ScanJob) error {
+	var payload nucleiScanJobPayload
+	if err := job.DecodePayload(&payload); err != nil {
+		return err
+	}
+	scanID := job.ScanID
+	scopeTargetID := payload.ScopeTargetID
+	targets, templates, severities, uploadedTemplates := payload.Targets, payload.Templates, payload.Severities, payload.UploadedTemplates
 
-		executionTime := time.Since(startTime)
+	log.Printf("[INFO] Starting background Nuclei scan %s", scanID)
 
-		if err != nil {
-			log.Printf("[ERROR] Nuclei scan failed: %v", err)
-
-			// Update scan with error status
-			_, updateErr := dbPool.Exec(context.Background(), `
-				UPDATE nuclei_scans SET 
-					status = 'failed', 
-					error = $1, 
-					execution_time = $2,
-					updated_at = NOW() 
-				WHERE scan_id = $3
-			`, err.Error(), executionTime.String(), scanID)
-
-			if updateErr != nil {
-				log.Printf("[ERROR] Failed to update scan with error: %v", updateErr)
-			}
-			return
-		}
+	// Update status to running
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE nuclei_scans SET status = 'running', updated_at = NOW() WHERE scan_id = $1
+	`, scanID)
 
-		// Convert findings to JSON
-		findingsJSON, err := json.Marshal(findings)
-		if err != nil {
-			log.Printf("[ERROR] Failed to marshal findings: %v", err)
-			findingsJSON = []byte("[]")
-		}
+	if err != nil {
+		log.Printf("[ERROR] Failed to update scan status to running: %v", err)
+		return err
+	}
+
+	startTime := time.Now()
+
+	// Execute the scan
+	outputFile, findings, err := utils.ExecuteNucleiScanForScopeTarget(ctx, scopeTargetID, targets, templates, severities, uploadedTemplates, dbPool)
+
+	executionTime := time.Since(startTime)
 
-		// Update scan with success status and results
-		_, err = dbPool.Exec(context.Background(), `
+	if err != nil {
+		log.Printf("[ERROR] Nuclei scan failed: %v", err)
+
+		// Update scan with error status
+		_, updateErr := dbPool.Exec(context.Background(), `
 			UPDATE nuclei_scans SET 
-				status = 'success', 
-				result = $1, 
+				status = 'failed', 
+				error = $1, 
 				execution_time = $2,
 				updated_at = NOW() 
 			WHERE scan_id = $3
-		`, string(findingsJSON), executionTime.String(), scanID)
+		`, err.Error(), executionTime.String(), scanID)
 
-		if err != nil {
-			log.Printf("[ERROR] Failed to update scan with results: %v", err)
-		} else {
-			log.Printf("[INFO] Nuclei scan %s completed successfully with %d findings", scanID, len(findings))
+		if updateErr != nil {
+			log.Printf("[ERROR] Failed to update scan with error: %v", updateErr)
 		}
+		return nil
+	}
 
-		// Clean up output file
-		if outputFile != "" {
-			os.Remove(outputFile)
-		}
-	}()
+	// Convert findings to JSON
+	findingsJSON, err := json.Marshal(findings)
+	if err != nil {
+		log.Printf("[ERROR] Failed to marshal findings: %v", err)
+		findingsJSON = []byte("[]")
+	}
 
-	// Return scan ID immediately
-	response := map[string]string{
-		"scan_id": scanID,
-		"status":  "pending",
-		"message": "Nuclei scan started successfully",
+	// Update scan with success status and results
+	_, err = dbPool.Exec(context.Background(), `
+		UPDATE nuclei_scans SET 
+			status = 'success', 
+			result = $1, 
+			execution_time = $2,
+			updated_at = NOW() 
+		WHERE scan_id = $3
+	`, string(findingsJSON), executionTime.String(), scanID)
+
+	if err != nil {
+		log.Printf("[ERROR] Failed to update scan with results: %v", err)
+	} else {
+		log.Printf("[INFO] Nuclei scan %s completed successfully with %d findings", scanID, len(findings))
 	}
 
-	log.Printf("[INFO] Nuclei scan %s initiated for scope target %s", scanID, scopeTargetID)
-	json.NewEncoder(w).Encode(response)
+	// Clean up output file
+	if outputFile != "" {
+		os.Remove(outputFile)
+	}
+
+	return nil
 }
 
 func getNucleiScanStatus(w http.ResponseWriter, r *http.Request) {
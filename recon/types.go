@@ -93,38 +93,6 @@ type GauScanStatus struct {
 	AutoScanSessionID sql.NullString `json:"auto_scan_session_id"`
 }
 
-type Sublist3rScanStatus struct {
-	ID                string         `json:"id"`
-	ScanID            string         `json:"scan_id"`
-	Domain            string         `json:"domain"`
-	Status            string         `json:"status"`
-	Result            sql.NullString `json:"result,omitempty"`
-	Error             sql.NullString `json:"error,omitempty"`
-	StdOut            sql.NullString `json:"stdout,omitempty"`
-	StdErr            sql.NullString `json:"stderr,omitempty"`
-	Command           sql.NullString `json:"command,omitempty"`
-	ExecTime          sql.NullString `json:"execution_time,omitempty"`
-	CreatedAt         time.Time      `json:"created_at"`
-	ScopeTargetID     string         `json:"scope_target_id"`
-	AutoScanSessionID sql.NullString `json:"auto_scan_session_id"`
-}
-
-type AssetfinderScanStatus struct {
-	ID                string         `json:"id"`
-	ScanID            string         `json:"scan_id"`
-	Domain            string         `json:"domain"`
-	Status            string         `json:"status"`
-	Result            sql.NullString `json:"result,omitempty"`
-	Error             sql.NullString `json:"error,omitempty"`
-	StdOut            sql.NullString `json:"stdout,omitempty"`
-	StdErr            sql.NullString `json:"stderr,omitempty"`
-	Command           sql.NullString `json:"command,omitempty"`
-	ExecTime          sql.NullString `json:"execution_time,omitempty"`
-	CreatedAt         time.Time      `json:"created_at"`
-	ScopeTargetID     string         `json:"scope_target_id"`
-	AutoScanSessionID sql.NullString `json:"auto_scan_session_id"`
-}
-
 type CTLScanStatus struct {
 	ID                string         `json:"id"`
 	ScanID            string         `json:"scan_id"`
@@ -141,22 +109,6 @@ type CTLScanStatus struct {
 	AutoScanSessionID sql.NullString `json:"auto_scan_session_id"`
 }
 
-type SubfinderScanStatus struct {
-	ID                string         `json:"id"`
-	ScanID            string         `json:"scan_id"`
-	Domain            string         `json:"domain"`
-	Status            string         `json:"status"`
-	Result            sql.NullString `json:"result,omitempty"`
-	Error             sql.NullString `json:"error,omitempty"`
-	StdOut            sql.NullString `json:"stdout,omitempty"`
-	StdErr            sql.NullString `json:"stderr,omitempty"`
-	Command           sql.NullString `json:"command,omitempty"`
-	ExecTime          sql.NullString `json:"execution_time,omitempty"`
-	CreatedAt         time.Time      `json:"created_at"`
-	ScopeTargetID     string         `json:"scope_target_id"`
-	AutoScanSessionID sql.NullString `json:"auto_scan_session_id"`
-}
-
 type ShuffleDNSScanStatus struct {
 	ID            string         `json:"id"`
 	ScanID        string         `json:"scan_id"`
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os/exec"
 	"regexp"
 	"strings"
 	"time"
@@ -92,13 +91,17 @@ func RunAmassEnumCompanyScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteAmassEnumCompanyScan(scanID, payload.Domains, scopeTargetID)
+	if _, err := EnqueueScanJob("amass-enum-company", scanID, ScanJobPayload{Targets: payload.Domains, ScopeTargetID: scopeTargetID}); err != nil {
+		log.Printf("[ERROR] Failed to queue amass-enum-company scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteAmassEnumCompanyScan(scanID string, domains []string, scopeTargetID string) {
+func ExecuteAmassEnumCompanyScan(ctx context.Context, scanID string, domains []string, scopeTargetID string) {
 	log.Printf("[AMASS-ENUM-COMPANY] [INFO] Starting Amass Enum Company scan (scan ID: %s) for %d domains", scanID, len(domains))
 	startTime := time.Now()
 
@@ -143,34 +146,14 @@ func ExecuteAmassEnumCompanyScan(scanID string, domains []string, scopeTargetID
 		rateLimit := GetAmassRateLimit()
 		log.Printf("[AMASS-ENUM-COMPANY] [INFO] Using rate limit of %d for Amass scan", rateLimit)
 
-		cmd := exec.Command(
-			"docker", "run", "--rm",
-			"caffix/amass",
+		args := []string{
 			"enum", "-passive", "-alts", "-brute", "-nocolor",
 			"-min-for-recursive", "2", "-timeout", "300",
 			"-d", domain,
-			// Primary public DNS resolvers
-			"-r", "8.8.8.8", // Google
-			"-r", "8.8.4.4", // Google Secondary
-			"-r", "1.1.1.1", // Cloudflare
-			"-r", "1.0.0.1", // Cloudflare Secondary
-			"-r", "9.9.9.9", // Quad9
-			"-r", "149.112.112.112", // Quad9 Secondary
-			"-r", "64.6.64.6", // Verisign
-			"-r", "64.6.65.6", // Verisign Secondary
-			// Additional reliable resolvers
-			"-r", "208.67.222.222", // OpenDNS
-			"-r", "208.67.220.220", // OpenDNS Secondary
-			"-r", "76.76.19.19", // Alternate DNS
-			"-r", "76.223.100.101", // Alternate DNS Secondary
-			"-r", "8.26.56.26", // Comodo Secure DNS
-			"-r", "8.20.247.20", // Comodo Secure DNS Secondary
-			"-r", "185.228.168.9", // CleanBrowsing
-			"-r", "185.228.169.9", // CleanBrowsing Secondary
-			"-r", "77.88.8.8", // Yandex DNS
-			"-r", "77.88.8.1", // Yandex DNS Secondary
-			"-rqps", fmt.Sprintf("%d", rateLimit),
-		)
+		}
+		args = append(args, resolverArgs("-r", maxAmassResolvers)...)
+		args = append(args, "-rqps", fmt.Sprintf("%d", rateLimit))
+		cmd := NewToolCommandContext(ctx, "amass", append([]string{"amass"}, args...)...)
 
 		commandsExecuted = append(commandsExecuted, cmd.String())
 		log.Printf("[AMASS-ENUM-COMPANY] [INFO] Executing command: %s", cmd.String())
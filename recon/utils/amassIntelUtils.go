@@ -3,11 +3,10 @@ package utils
 import (
 	"bytes"
 	"context"
-	"database/sql"
 	"encoding/json"
 	"log"
+	"net"
 	"net/http"
-	"os/exec"
 	"regexp"
 	"strings"
 	"time"
@@ -16,21 +15,6 @@ import (
 	"github.com/gorilla/mux"
 )
 
-type AmassIntelScanStatus struct {
-	ID                string         `json:"id"`
-	ScanID            string         `json:"scan_id"`
-	CompanyName       string         `json:"company_name"`
-	Status            string         `json:"status"`
-	Result            sql.NullString `json:"result,omitempty"`
-	Error             sql.NullString `json:"error,omitempty"`
-	StdOut            sql.NullString `json:"stdout,omitempty"`
-	StdErr            sql.NullString `json:"stderr,omitempty"`
-	Command           sql.NullString `json:"command,omitempty"`
-	ExecTime          sql.NullString `json:"execution_time,omitempty"`
-	CreatedAt         time.Time      `json:"created_at"`
-	AutoScanSessionID sql.NullString `json:"auto_scan_session_id"`
-}
-
 type IntelNetworkRangeResponse struct {
 	ID           string `json:"id"`
 	CIDRBlock    string `json:"cidr_block"`
@@ -49,57 +33,12 @@ type IntelASNResponse struct {
 	ScanID       string `json:"scan_id"`
 }
 
-func RunAmassIntelScan(w http.ResponseWriter, r *http.Request) {
-	var payload struct {
-		CompanyName       string  `json:"company_name" binding:"required"`
-		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.CompanyName == "" {
-		http.Error(w, "Invalid request body. `company_name` is required.", http.StatusBadRequest)
-		return
-	}
-
-	companyName := payload.CompanyName
-
-	query := `SELECT id FROM scope_targets WHERE type = 'Company' AND scope_target = $1`
-	var requestID string
-	err := dbPool.QueryRow(context.Background(), query, companyName).Scan(&requestID)
-	if err != nil {
-		log.Printf("[ERROR] No matching company scope target found for company %s", companyName)
-		http.Error(w, "No matching company scope target found.", http.StatusBadRequest)
-		return
-	}
-
-	scanID := uuid.New().String()
-	var insertQuery string
-	var args []interface{}
-	if payload.AutoScanSessionID != nil && *payload.AutoScanSessionID != "" {
-		insertQuery = `INSERT INTO amass_intel_scans (scan_id, company_name, status, scope_target_id, auto_scan_session_id) VALUES ($1, $2, $3, $4, $5)`
-		args = []interface{}{scanID, companyName, "pending", requestID, *payload.AutoScanSessionID}
-	} else {
-		insertQuery = `INSERT INTO amass_intel_scans (scan_id, company_name, status, scope_target_id) VALUES ($1, $2, $3, $4)`
-		args = []interface{}{scanID, companyName, "pending", requestID}
-	}
-	_, err = dbPool.Exec(context.Background(), insertQuery, args...)
-	if err != nil {
-		log.Printf("[ERROR] Failed to create amass intel scan record: %v", err)
-		http.Error(w, "Failed to create scan record.", http.StatusInternalServerError)
-		return
-	}
-
-	go ExecuteAmassIntelScan(scanID, companyName)
-
-	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
-}
-
-func ExecuteAmassIntelScan(scanID, companyName string) {
+func ExecuteAmassIntelScan(ctx context.Context, scanID, companyName string) {
 	log.Printf("[INFO] Starting Amass Intel scan for company %s (scan ID: %s)", companyName, scanID)
 	startTime := time.Now()
 
-	cmd := exec.Command(
-		"docker", "run", "--rm",
-		"caffix/amass",
+	cmd := NewToolCommandContext(ctx, 
+		"amass", "amass",
 		"intel",
 		"-org", companyName,
 		"-whois",
@@ -146,7 +85,7 @@ func ParseAndStoreIntelNetworkResults(scanID, companyName, result string) {
 	log.Printf("[INFO] Processing %d lines of Intel output", len(lines))
 
 	asnPattern := regexp.MustCompile(`^ASN:\s*(\d+)\s*-\s*(.+?)\s*-\s*(.+)$`)
-	cidrPattern := regexp.MustCompile(`^\s+(\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}/\d{1,2})\s*$`)
+	cidrPattern := regexp.MustCompile(`^\s+(\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}/\d{1,2}|[0-9a-fA-F:]+:[0-9a-fA-F:]*/\d{1,3})\s*$`)
 
 	var currentASN, currentDescription, currentOrganization string
 
@@ -173,6 +112,10 @@ func ParseAndStoreIntelNetworkResults(scanID, companyName, result string) {
 			matches := cidrPattern.FindStringSubmatch(originalLine)
 			if len(matches) == 2 {
 				cidrBlock := matches[1]
+				if _, _, err := net.ParseCIDR(cidrBlock); err != nil {
+					log.Printf("[WARN] Skipping invalid network range on line %d: %s", lineNum+1, cidrBlock)
+					continue
+				}
 
 				asn := ""
 				if currentASN != "" {
@@ -220,114 +163,6 @@ func UpdateIntelScanStatus(scanID, status, result, stderr, command, execTime str
 	}
 }
 
-func GetAmassIntelScanStatus(w http.ResponseWriter, r *http.Request) {
-	scanID := mux.Vars(r)["scanID"]
-	if scanID == "" {
-		http.Error(w, "Scan ID is required", http.StatusBadRequest)
-		return
-	}
-
-	var scan AmassIntelScanStatus
-	query := `SELECT id, scan_id, company_name, status, result, error, stdout, stderr, command, execution_time, created_at, auto_scan_session_id FROM amass_intel_scans WHERE scan_id = $1`
-	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
-		&scan.ID,
-		&scan.ScanID,
-		&scan.CompanyName,
-		&scan.Status,
-		&scan.Result,
-		&scan.Error,
-		&scan.StdOut,
-		&scan.StdErr,
-		&scan.Command,
-		&scan.ExecTime,
-		&scan.CreatedAt,
-		&scan.AutoScanSessionID,
-	)
-	if err != nil {
-		log.Printf("[ERROR] Failed to fetch Intel scan status: %v", err)
-		http.Error(w, "Scan not found.", http.StatusNotFound)
-		return
-	}
-
-	response := map[string]interface{}{
-		"id":                   scan.ID,
-		"scan_id":              scan.ScanID,
-		"company_name":         scan.CompanyName,
-		"status":               scan.Status,
-		"result":               nullStringToString(scan.Result),
-		"error":                nullStringToString(scan.Error),
-		"stdout":               nullStringToString(scan.StdOut),
-		"stderr":               nullStringToString(scan.StdErr),
-		"command":              nullStringToString(scan.Command),
-		"execution_time":       nullStringToString(scan.ExecTime),
-		"created_at":           scan.CreatedAt.Format(time.RFC3339),
-		"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-	}
-
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
-}
-
-func GetAmassIntelScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
-	scopeTargetID := mux.Vars(r)["id"]
-	if scopeTargetID == "" {
-		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
-		return
-	}
-
-	query := `SELECT id, scan_id, company_name, status, result, error, stdout, stderr, command, execution_time, created_at, auto_scan_session_id 
-              FROM amass_intel_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
-	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
-	if err != nil {
-		log.Printf("[ERROR] Failed to fetch Intel scans for scope target ID %s: %v", scopeTargetID, err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	var scans []map[string]interface{}
-	for rows.Next() {
-		var scan AmassIntelScanStatus
-		err := rows.Scan(
-			&scan.ID,
-			&scan.ScanID,
-			&scan.CompanyName,
-			&scan.Status,
-			&scan.Result,
-			&scan.Error,
-			&scan.StdOut,
-			&scan.StdErr,
-			&scan.Command,
-			&scan.ExecTime,
-			&scan.CreatedAt,
-			&scan.AutoScanSessionID,
-		)
-		if err != nil {
-			log.Printf("[ERROR] Failed to scan row: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-
-		scans = append(scans, map[string]interface{}{
-			"id":                   scan.ID,
-			"scan_id":              scan.ScanID,
-			"company_name":         scan.CompanyName,
-			"status":               scan.Status,
-			"result":               nullStringToString(scan.Result),
-			"error":                nullStringToString(scan.Error),
-			"stdout":               nullStringToString(scan.StdOut),
-			"stderr":               nullStringToString(scan.StdErr),
-			"command":              nullStringToString(scan.Command),
-			"execution_time":       nullStringToString(scan.ExecTime),
-			"created_at":           scan.CreatedAt.Format(time.RFC3339),
-			"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-		})
-	}
-
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(scans)
-}
-
 func GetIntelNetworkRanges(w http.ResponseWriter, r *http.Request) {
 	scanID := mux.Vars(r)["scan_id"]
 	if scanID == "" || scanID == "No scans available" {
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os/exec"
 	"regexp"
 	"strings"
 	"time"
@@ -173,66 +172,6 @@ func ParseAndStoreResults(scanID, domain, result string) {
 	log.Printf("[INFO] Completed parsing results for scan %s", scanID)
 }
 
-func GetAmassScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
-	scopeTargetID := mux.Vars(r)["id"]
-	if scopeTargetID == "" {
-		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
-		return
-	}
-
-	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, auto_scan_session_id 
-              FROM amass_scans WHERE scope_target_id = $1`
-	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
-	if err != nil {
-		log.Printf("[ERROR] Failed to fetch scans for scope target ID %s: %v", scopeTargetID, err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	var scans []map[string]interface{}
-	for rows.Next() {
-		var scan AmassScanStatus
-		err := rows.Scan(
-			&scan.ID,
-			&scan.ScanID,
-			&scan.Domain,
-			&scan.Status,
-			&scan.Result,
-			&scan.Error,
-			&scan.StdOut,
-			&scan.StdErr,
-			&scan.Command,
-			&scan.ExecTime,
-			&scan.CreatedAt,
-			&scan.AutoScanSessionID,
-		)
-		if err != nil {
-			log.Printf("[ERROR] Failed to scan row: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-
-		scans = append(scans, map[string]interface{}{
-			"id":                   scan.ID,
-			"scan_id":              scan.ScanID,
-			"domain":               scan.Domain,
-			"status":               scan.Status,
-			"result":               nullStringToString(scan.Result),
-			"error":                nullStringToString(scan.Error),
-			"stdout":               nullStringToString(scan.StdOut),
-			"stderr":               nullStringToString(scan.StdErr),
-			"command":              nullStringToString(scan.Command),
-			"execution_time":       nullStringToString(scan.ExecTime),
-			"created_at":           scan.CreatedAt.Format(time.RFC3339),
-			"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-		})
-	}
-
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(scans)
-}
-
 func GetServiceProviders(w http.ResponseWriter, r *http.Request) {
 	scanID := mux.Vars(r)["scan_id"]
 	if scanID == "" || scanID == "No scans available" {
@@ -501,100 +440,7 @@ func GetDNSRecords(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func GetAmassScanStatus(w http.ResponseWriter, r *http.Request) {
-	scanID := mux.Vars(r)["scanID"]
-	if scanID == "" {
-		http.Error(w, "Scan ID is required", http.StatusBadRequest)
-		return
-	}
-
-	var scan AmassScanStatus
-	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, auto_scan_session_id FROM amass_scans WHERE scan_id = $1`
-	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
-		&scan.ID,
-		&scan.ScanID,
-		&scan.Domain,
-		&scan.Status,
-		&scan.Result,
-		&scan.Error,
-		&scan.StdOut,
-		&scan.StdErr,
-		&scan.Command,
-		&scan.ExecTime,
-		&scan.CreatedAt,
-		&scan.AutoScanSessionID,
-	)
-	if err != nil {
-		log.Printf("[ERROR] Failed to fetch scan status: %v", err)
-		http.Error(w, "Scan not found.", http.StatusNotFound)
-		return
-	}
-
-	response := map[string]interface{}{
-		"id":                   scan.ID,
-		"scan_id":              scan.ScanID,
-		"domain":               scan.Domain,
-		"status":               scan.Status,
-		"result":               nullStringToString(scan.Result),
-		"error":                nullStringToString(scan.Error),
-		"stdout":               nullStringToString(scan.StdOut),
-		"stderr":               nullStringToString(scan.StdErr),
-		"command":              nullStringToString(scan.Command),
-		"execution_time":       nullStringToString(scan.ExecTime),
-		"created_at":           scan.CreatedAt.Format(time.RFC3339),
-		"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-	}
-
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
-}
-
-func RunAmassScan(w http.ResponseWriter, r *http.Request) {
-	var payload struct {
-		FQDN              string  `json:"fqdn" binding:"required"`
-		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.FQDN == "" {
-		http.Error(w, "Invalid request body. `fqdn` is required.", http.StatusBadRequest)
-		return
-	}
-
-	domain := payload.FQDN
-	wildcardDomain := fmt.Sprintf("*.%s", domain)
-
-	query := `SELECT id FROM scope_targets WHERE type = 'Wildcard' AND scope_target = $1`
-	var requestID string
-	err := dbPool.QueryRow(context.Background(), query, wildcardDomain).Scan(&requestID)
-	if err != nil {
-		log.Printf("[ERROR] No matching wildcard scope target found for domain %s", domain)
-		http.Error(w, "No matching wildcard scope target found.", http.StatusBadRequest)
-		return
-	}
-
-	scanID := uuid.New().String()
-	var insertQuery string
-	var args []interface{}
-	if payload.AutoScanSessionID != nil && *payload.AutoScanSessionID != "" {
-		insertQuery = `INSERT INTO amass_scans (scan_id, domain, status, scope_target_id, auto_scan_session_id) VALUES ($1, $2, $3, $4, $5)`
-		args = []interface{}{scanID, domain, "pending", requestID, *payload.AutoScanSessionID}
-	} else {
-		insertQuery = `INSERT INTO amass_scans (scan_id, domain, status, scope_target_id) VALUES ($1, $2, $3, $4)`
-		args = []interface{}{scanID, domain, "pending", requestID}
-	}
-	_, err = dbPool.Exec(context.Background(), insertQuery, args...)
-	if err != nil {
-		log.Printf("[ERROR] Failed to create scan record: %v", err)
-		http.Error(w, "Failed to create scan record.", http.StatusInternalServerError)
-		return
-	}
-
-	go ExecuteAndParseAmassScan(scanID, domain)
-
-	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
-}
-
-func ExecuteAndParseAmassScan(scanID, domain string) {
+func ExecuteAndParseAmassScan(ctx context.Context, scanID, domain string) {
 	log.Printf("[INFO] Starting Amass scan for domain %s (scan ID: %s)", domain, scanID)
 	startTime := time.Now()
 
@@ -602,34 +448,14 @@ func ExecuteAndParseAmassScan(scanID, domain string) {
 	rateLimit := GetAmassRateLimit()
 	log.Printf("[INFO] Using rate limit of %d for Amass scan", rateLimit)
 
-	cmd := exec.Command(
-		"docker", "run", "--rm",
-		"caffix/amass",
+	args := []string{
 		"enum", "-active", "-alts", "-brute", "-nocolor",
 		"-min-for-recursive", "2", "-timeout", "60",
 		"-d", domain,
-		"-r", "8.8.8.8",
-		"-r", "1.1.1.1",
-		"-r", "9.9.9.9",
-		"-r", "64.6.64.6",
-		"-r", "208.67.222.222",
-		"-r", "208.67.220.220",
-		"-r", "8.26.56.26",
-		"-r", "8.20.247.20",
-		"-r", "185.228.168.9",
-		"-r", "185.228.169.9",
-		"-r", "76.76.19.19",
-		"-r", "76.223.122.150",
-		"-r", "198.101.242.72",
-		"-r", "176.103.130.130",
-		"-r", "176.103.130.131",
-		"-r", "94.140.14.14",
-		"-r", "94.140.15.15",
-		"-r", "1.0.0.1",
-		"-r", "77.88.8.8",
-		"-r", "77.88.8.1",
-		"-rqps", fmt.Sprintf("%d", rateLimit),
-	)
+	}
+	args = append(args, resolverArgs("-r", maxAmassResolvers)...)
+	args = append(args, "-rqps", fmt.Sprintf("%d", rateLimit))
+	cmd := NewToolCommandContext(ctx, "amass", append([]string{"amass"}, args...)...)
 
 	log.Printf("[INFO] Executing command: %s", cmd.String())
 
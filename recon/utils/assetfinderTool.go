@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+)
+
+// assetfinderTool finds subdomains of a wildcard target with assetfinder.
+type assetfinderTool struct{ toolBase }
+
+func init() {
+	RegisterTool(assetfinderTool{toolBase{name: "assetfinder", input: ToolInputDomain, table: "assetfinder_scans"}})
+}
+
+func (assetfinderTool) BuildArgs(input ToolInput) []string {
+	return []string{"assetfinder", "--subs-only", input.Target}
+}
+
+func (assetfinderTool) ParseOutput(input ToolInput, stdout, stderr string) (ToolResult, error) {
+	return lineResult(uniqueLines(stdout)), nil
+}
+
+// uniqueLines returns the non-empty trimmed lines of output, de-duplicated
+// and sorted.
+func uniqueLines(output string) []string {
+	seen := make(map[string]bool)
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || seen[line] {
+			continue
+		}
+		seen[line] = true
+		lines = append(lines, line)
+	}
+	sort.Strings(lines)
+	return lines
+}
@@ -0,0 +1,586 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const autoScanPollInterval = 5 * time.Second
+
+// AutoScanConfig mirrors a row of auto_scan_config. The JSON tags match the
+// config_snapshot stored with each session.
+type AutoScanConfig struct {
+	Amass                     bool `json:"amass"`
+	Sublist3r                 bool `json:"sublist3r"`
+	Assetfinder               bool `json:"assetfinder"`
+	Gau                       bool `json:"gau"`
+	Ctl                       bool `json:"ctl"`
+	Subfinder                 bool `json:"subfinder"`
+	ConsolidateHttpxRound1    bool `json:"consolidate_httpx_round1"`
+	Shuffledns                bool `json:"shuffledns"`
+	Cewl                      bool `json:"cewl"`
+	ConsolidateHttpxRound2    bool `json:"consolidate_httpx_round2"`
+	Gospider                  bool `json:"gospider"`
+	Subdomainizer             bool `json:"subdomainizer"`
+	ConsolidateHttpxRound3    bool `json:"consolidate_httpx_round3"`
+	NucleiScreenshot          bool `json:"nuclei_screenshot"`
+	Metadata                  bool `json:"metadata"`
+	MaxConsolidatedSubdomains int  `json:"maxConsolidatedSubdomains"`
+	MaxLiveWebServers         int  `json:"maxLiveWebServers"`
+}
+
+func defaultAutoScanConfig() AutoScanConfig {
+	return AutoScanConfig{
+		Amass: true, Sublist3r: true, Assetfinder: true, Gau: true, Ctl: true, Subfinder: true,
+		ConsolidateHttpxRound1: true, Shuffledns: true, Cewl: true, ConsolidateHttpxRound2: true,
+		Gospider: true, Subdomainizer: true, ConsolidateHttpxRound3: true, NucleiScreenshot: true,
+		Metadata:                  true,
+		MaxConsolidatedSubdomains: 2500,
+		MaxLiveWebServers:         500,
+	}
+}
+
+// autoScanStep is one stage of an auto-scan session. Tool steps run a queued
+// scan job; consolidate steps consolidate subdomains and then run httpx.
+type autoScanStep struct {
+	name        string
+	kind        string
+	table       string
+	targetCol   string
+	consolidate bool
+	enabled     func(AutoScanConfig) bool
+}
+
+// autoScanSteps is the fixed order a session walks through. Step names are
+// the values stored in auto_scan_state.current_step.
+var autoScanSteps = []autoScanStep{
+	{name: "amass", kind: "amass", table: "amass_scans", targetCol: "domain", enabled: func(c AutoScanConfig) bool { return c.Amass }},
+	{name: "sublist3r", kind: "sublist3r", table: "sublist3r_scans", targetCol: "domain", enabled: func(c AutoScanConfig) bool { return c.Sublist3r }},
+	{name: "assetfinder", kind: "assetfinder", table: "assetfinder_scans", targetCol: "domain", enabled: func(c AutoScanConfig) bool { return c.Assetfinder }},
+	{name: "gau", kind: "gau", table: "gau_scans", targetCol: "domain", enabled: func(c AutoScanConfig) bool { return c.Gau }},
+	{name: "ctl", kind: "ctl", table: "ctl_scans", targetCol: "domain", enabled: func(c AutoScanConfig) bool { return c.Ctl }},
+	{name: "subfinder", kind: "subfinder", table: "subfinder_scans", targetCol: "domain", enabled: func(c AutoScanConfig) bool { return c.Subfinder }},
+	{name: "consolidate_httpx_round1", kind: "httpx", table: "httpx_scans", targetCol: "domain", consolidate: true, enabled: func(c AutoScanConfig) bool { return c.ConsolidateHttpxRound1 }},
+	{name: "shuffledns", kind: "shuffledns", table: "shuffledns_scans", targetCol: "domain", enabled: func(c AutoScanConfig) bool { return c.Shuffledns }},
+	{name: "cewl", kind: "cewl", table: "cewl_scans", targetCol: "url", enabled: func(c AutoScanConfig) bool { return c.Cewl }},
+	{name: "consolidate_httpx_round2", kind: "httpx", table: "httpx_scans", targetCol: "domain", consolidate: true, enabled: func(c AutoScanConfig) bool { return c.ConsolidateHttpxRound2 }},
+	{name: "gospider", kind: "gospider", table: "gospider_scans", targetCol: "domain", enabled: func(c AutoScanConfig) bool { return c.Gospider }},
+	{name: "subdomainizer", kind: "subdomainizer", table: "subdomainizer_scans", targetCol: "domain", enabled: func(c AutoScanConfig) bool { return c.Subdomainizer }},
+	{name: "consolidate_httpx_round3", kind: "httpx", table: "httpx_scans", targetCol: "domain", consolidate: true, enabled: func(c AutoScanConfig) bool { return c.ConsolidateHttpxRound3 }},
+	{name: "nuclei_screenshot", kind: "nuclei-screenshot", table: "nuclei_screenshots", targetCol: "domain", enabled: func(c AutoScanConfig) bool { return c.NucleiScreenshot }},
+	{name: "metadata", kind: "metadata", table: "metadata_scans", targetCol: "domain", enabled: func(c AutoScanConfig) bool { return c.Metadata }},
+}
+
+// AutoScanStepRecord is one entry of auto_scan_sessions.steps_run.
+type AutoScanStepRecord struct {
+	Step                   string    `json:"step"`
+	Status                 string    `json:"status"`
+	ScanID                 string    `json:"scan_id,omitempty"`
+	StartedAt              time.Time `json:"started_at"`
+	EndedAt                time.Time `json:"ended_at"`
+	ConsolidatedSubdomains *int      `json:"consolidated_subdomains,omitempty"`
+	LiveWebServers         *int      `json:"live_web_servers,omitempty"`
+	Message                string    `json:"message,omitempty"`
+}
+
+// Step outcomes recorded in steps_run.
+const (
+	autoScanStepSucceeded = "success"
+	autoScanStepFailed    = "failed"
+	autoScanStepSkipped   = "skipped"
+	autoScanStepCancelled = "cancelled"
+)
+
+var (
+	autoScanRunnersMu sync.Mutex
+	autoScanRunners   = make(map[string]bool)
+)
+
+type autoScanSession struct {
+	id            string
+	scopeTargetID string
+	domain        string
+	config        AutoScanConfig
+	done          map[string]AutoScanStepRecord
+	usedScanIDs   map[string]bool
+}
+
+// errAutoScanStopped ends a session early without treating it as a failure.
+type errAutoScanStopped struct {
+	status  string
+	message string
+}
+
+func (e *errAutoScanStopped) Error() string { return e.message }
+
+// StartAutoScanSession runs every enabled step of an auto-scan session in the
+// background. Scans are executed through the job queue, so a session survives
+// individual tool failures; a step that fails is recorded and the session
+// moves on.
+func StartAutoScanSession(sessionID, scopeTargetID string) {
+	autoScanRunnersMu.Lock()
+	if autoScanRunners[sessionID] {
+		autoScanRunnersMu.Unlock()
+		return
+	}
+	autoScanRunners[sessionID] = true
+	autoScanRunnersMu.Unlock()
+
+	go func() {
+		defer func() {
+			autoScanRunnersMu.Lock()
+			delete(autoScanRunners, sessionID)
+			autoScanRunnersMu.Unlock()
+		}()
+		runAutoScanSession(sessionID, scopeTargetID)
+	}()
+}
+
+// ResumeAutoScanSessions restarts the runner for sessions that were still
+// running when the engine stopped. Steps already in steps_run are skipped and
+// a step whose scan was in flight is picked up where the job queue left it.
+func ResumeAutoScanSessions() {
+	rows, err := dbPool.Query(context.Background(),
+		`SELECT id::text, scope_target_id::text FROM auto_scan_sessions WHERE status = 'running'`)
+	if err != nil {
+		log.Printf("[AUTO-SCAN] [ERROR] Failed to load running sessions: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sessionID, scopeTargetID string
+		if err := rows.Scan(&sessionID, &scopeTargetID); err != nil {
+			log.Printf("[AUTO-SCAN] [ERROR] Failed to scan session row: %v", err)
+			continue
+		}
+		log.Printf("[AUTO-SCAN] [INFO] Resuming auto-scan session %s", sessionID)
+		StartAutoScanSession(sessionID, scopeTargetID)
+	}
+}
+
+func runAutoScanSession(sessionID, scopeTargetID string) {
+	log.Printf("[AUTO-SCAN] [INFO] Starting auto-scan session %s for scope target %s", sessionID, scopeTargetID)
+
+	if err := ensureAutoScanStateColumns(); err != nil {
+		log.Printf("[AUTO-SCAN] [WARN] Failed to ensure auto_scan_state columns: %v", err)
+	}
+
+	session, err := loadAutoScanSession(sessionID, scopeTargetID)
+	if err != nil {
+		log.Printf("[AUTO-SCAN] [ERROR] Failed to load session %s: %v", sessionID, err)
+		finishAutoScanSession(sessionID, scopeTargetID, "failed", err.Error())
+		return
+	}
+
+	if len(session.done) == 0 {
+		// A fresh session must not inherit pause/cancel flags left over from
+		// an earlier session on the same target.
+		resetAutoScanState(scopeTargetID, autoScanSteps[0].name)
+	}
+
+	for _, step := range autoScanSteps {
+		if _, ok := session.done[step.name]; ok {
+			continue
+		}
+
+		if err := waitWhileAutoScanPaused(session); err != nil {
+			finishAutoScanSession(sessionID, scopeTargetID, autoScanStopStatus(err), err.Error())
+			return
+		}
+
+		if !step.enabled(session.config) {
+			now := time.Now()
+			recordAutoScanStep(session, AutoScanStepRecord{Step: step.name, Status: autoScanStepSkipped, StartedAt: now, EndedAt: now, Message: "disabled in config"})
+			continue
+		}
+
+		setAutoScanCurrentStep(scopeTargetID, step.name)
+		log.Printf("[AUTO-SCAN] [INFO] Session %s: running step %s", sessionID, step.name)
+
+		record, err := runAutoScanStep(session, step)
+		recordAutoScanStep(session, record)
+		if err != nil {
+			log.Printf("[AUTO-SCAN] [INFO] Session %s stopped at step %s: %v", sessionID, step.name, err)
+			finishAutoScanSession(sessionID, scopeTargetID, autoScanStopStatus(err), err.Error())
+			return
+		}
+		log.Printf("[AUTO-SCAN] [INFO] Session %s: step %s finished with status %s", sessionID, step.name, record.Status)
+	}
+
+	finishAutoScanSession(sessionID, scopeTargetID, "completed", "")
+}
+
+func autoScanStopStatus(err error) string {
+	if stopped, ok := err.(*errAutoScanStopped); ok {
+		return stopped.status
+	}
+	return "failed"
+}
+
+func loadAutoScanSession(sessionID, scopeTargetID string) (*autoScanSession, error) {
+	var scopeTarget, targetType string
+	var snapshot, stepsRun []byte
+	err := dbPool.QueryRow(context.Background(), `
+		SELECT st.scope_target, st.type, s.config_snapshot, COALESCE(s.steps_run, '[]'::jsonb)
+		FROM auto_scan_sessions s
+		JOIN scope_targets st ON st.id = s.scope_target_id
+		WHERE s.id = $1`, sessionID).Scan(&scopeTarget, &targetType, &snapshot, &stepsRun)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %v", err)
+	}
+	if targetType != "Wildcard" {
+		return nil, fmt.Errorf("auto scan requires a wildcard scope target, got %s", targetType)
+	}
+
+	session := &autoScanSession{
+		id:            sessionID,
+		scopeTargetID: scopeTargetID,
+		domain:        strings.TrimPrefix(scopeTarget, "*."),
+		config:        loadAutoScanConfig(snapshot),
+		done:          make(map[string]AutoScanStepRecord),
+		usedScanIDs:   make(map[string]bool),
+	}
+
+	var records []AutoScanStepRecord
+	if err := json.Unmarshal(stepsRun, &records); err != nil {
+		log.Printf("[AUTO-SCAN] [WARN] Ignoring unreadable steps_run for session %s: %v", sessionID, err)
+	}
+	for _, record := range records {
+		session.done[record.Step] = record
+		if record.ScanID != "" {
+			session.usedScanIDs[record.ScanID] = true
+		}
+	}
+
+	return session, nil
+}
+
+// loadAutoScanConfig prefers the session's config snapshot and falls back to
+// the saved auto_scan_config row.
+func loadAutoScanConfig(snapshot []byte) AutoScanConfig {
+	config := defaultAutoScanConfig()
+	if len(snapshot) > 0 && string(snapshot) != "null" {
+		if err := json.Unmarshal(snapshot, &config); err == nil {
+			return config
+		}
+	}
+
+	config = defaultAutoScanConfig()
+	err := dbPool.QueryRow(context.Background(), `
+		SELECT amass, sublist3r, assetfinder, gau, ctl, subfinder, consolidate_httpx_round1, shuffledns, cewl, consolidate_httpx_round2, gospider, subdomainizer, consolidate_httpx_round3, nuclei_screenshot, metadata, max_consolidated_subdomains, max_live_web_servers
+		FROM auto_scan_config
+		LIMIT 1`).Scan(
+		&config.Amass, &config.Sublist3r, &config.Assetfinder, &config.Gau, &config.Ctl, &config.Subfinder,
+		&config.ConsolidateHttpxRound1, &config.Shuffledns, &config.Cewl, &config.ConsolidateHttpxRound2,
+		&config.Gospider, &config.Subdomainizer, &config.ConsolidateHttpxRound3, &config.NucleiScreenshot,
+		&config.Metadata, &config.MaxConsolidatedSubdomains, &config.MaxLiveWebServers,
+	)
+	if err != nil {
+		return defaultAutoScanConfig()
+	}
+	return config
+}
+
+func runAutoScanStep(session *autoScanSession, step autoScanStep) (AutoScanStepRecord, error) {
+	record := AutoScanStepRecord{Step: step.name, StartedAt: time.Now()}
+
+	if step.consolidate {
+		subdomains, err := ConsolidateSubdomains(session.scopeTargetID)
+		if err != nil {
+			record.Status = autoScanStepFailed
+			record.Message = fmt.Sprintf("consolidation failed: %v", err)
+			record.EndedAt = time.Now()
+			return record, nil
+		}
+		count := len(subdomains)
+		record.ConsolidatedSubdomains = &count
+		updateAutoScanFinalStats(session.id, &count, nil)
+
+		if session.config.MaxConsolidatedSubdomains > 0 && count > session.config.MaxConsolidatedSubdomains {
+			record.Status = autoScanStepSkipped
+			record.Message = fmt.Sprintf("%d consolidated subdomains exceeds the limit of %d", count, session.config.MaxConsolidatedSubdomains)
+			record.EndedAt = time.Now()
+			return record, &errAutoScanStopped{status: "completed", message: record.Message}
+		}
+	}
+
+	scanID, status, err := runAutoScanTool(session, step)
+	record.ScanID = scanID
+	record.EndedAt = time.Now()
+	if err != nil {
+		record.Status = autoScanStepFailed
+		record.Message = err.Error()
+		if _, ok := err.(*errAutoScanStopped); ok {
+			record.Status = autoScanStepCancelled
+			return record, err
+		}
+		return record, nil
+	}
+
+	switch status {
+	case "success", "completed":
+		record.Status = autoScanStepSucceeded
+	case ScanCancelled:
+		record.Status = autoScanStepCancelled
+		if stopErr := autoScanStopRequested(session); stopErr != nil {
+			return record, stopErr
+		}
+	default:
+		record.Status = autoScanStepFailed
+		record.Message = fmt.Sprintf("scan finished with status %s", status)
+	}
+
+	if step.consolidate && record.Status == autoScanStepSucceeded {
+		live := countHttpxLiveServers(scanID)
+		record.LiveWebServers = &live
+		updateAutoScanFinalStats(session.id, nil, &live)
+
+		if session.config.MaxLiveWebServers > 0 && live > session.config.MaxLiveWebServers {
+			record.Message = fmt.Sprintf("%d live web servers exceeds the limit of %d", live, session.config.MaxLiveWebServers)
+			return record, &errAutoScanStopped{status: "completed", message: record.Message}
+		}
+	}
+
+	return record, nil
+}
+
+// runAutoScanTool queues the step's scan (or reattaches to one queued before
+// a restart) and blocks until its job is finished. It returns the final scan
+// row status.
+func runAutoScanTool(session *autoScanSession, step autoScanStep) (string, string, error) {
+	job := &ScanJob{ScanTable: step.table, ScanID: findAutoScanInFlightScan(session, step)}
+	if job.ScanID != "" {
+		log.Printf("[AUTO-SCAN] [INFO] Session %s: reattaching to %s scan %s", session.id, step.name, job.ScanID)
+		err := dbPool.QueryRow(context.Background(), fmt.Sprintf(`
+			SELECT COALESCE(t.job_id, (
+				SELECT j.id::text FROM scan_jobs j WHERE j.scan_id = t.scan_id::text AND j.scan_table = $2
+				ORDER BY j.created_at DESC LIMIT 1
+			))
+			FROM %s t WHERE t.scan_id = $1`, step.table),
+			job.ScanID, step.table).Scan(&job.ID)
+		if err != nil {
+			job.ID = ""
+		}
+	} else {
+		job.ScanID = uuid.New().String()
+		_, err := dbPool.Exec(context.Background(),
+			fmt.Sprintf(`INSERT INTO %s (scan_id, %s, status, scope_target_id, auto_scan_session_id) VALUES ($1, $2, $3, $4, $5)`, step.table, step.targetCol),
+			job.ScanID, session.domain, "pending", session.scopeTargetID, session.id)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to create scan record: %v", err)
+		}
+	}
+	session.usedScanIDs[job.ScanID] = true
+
+	if job.ID == "" {
+		jobID, err := EnqueueScanJob(step.kind, job.ScanID, ScanJobPayload{Target: session.domain})
+		if err != nil {
+			markScanRowFailed(job)
+			return job.ScanID, "", fmt.Errorf("failed to queue scan: %v", err)
+		}
+		job.ID = jobID
+	}
+
+	for {
+		switch scanJobStatus(job) {
+		case ScanJobSucceeded, ScanJobDeadLettered, ScanJobCancelled:
+			status, err := scanRowStatus(job)
+			if err != nil {
+				return job.ScanID, "", fmt.Errorf("failed to read scan status: %v", err)
+			}
+			return job.ScanID, status, nil
+		}
+
+		if err := autoScanStopRequested(session); err != nil {
+			if cancelErr := CancelScan(step.table, job.ScanID); cancelErr != nil {
+				log.Printf("[AUTO-SCAN] [ERROR] Failed to cancel scan %s: %v", job.ScanID, cancelErr)
+			}
+			return job.ScanID, ScanCancelled, err
+		}
+
+		time.Sleep(autoScanPollInterval)
+	}
+}
+
+// findAutoScanInFlightScan returns a scan this session already created for
+// step but has not recorded yet, so a resumed session does not rerun it.
+func findAutoScanInFlightScan(session *autoScanSession, step autoScanStep) string {
+	rows, err := dbPool.Query(context.Background(),
+		fmt.Sprintf(`SELECT scan_id::text FROM %s WHERE auto_scan_session_id = $1 ORDER BY created_at`, step.table),
+		session.id)
+	if err != nil {
+		return ""
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var scanID string
+		if err := rows.Scan(&scanID); err != nil {
+			continue
+		}
+		if !session.usedScanIDs[scanID] {
+			return scanID
+		}
+	}
+	return ""
+}
+
+func countHttpxLiveServers(scanID string) int {
+	var result string
+	err := dbPool.QueryRow(context.Background(),
+		`SELECT COALESCE(result, '') FROM httpx_scans WHERE scan_id = $1`, scanID).Scan(&result)
+	if err != nil {
+		log.Printf("[AUTO-SCAN] [ERROR] Failed to read httpx result for scan %s: %v", scanID, err)
+		return 0
+	}
+
+	count := 0
+	for _, line := range strings.Split(result, "\n") {
+		if strings.TrimSpace(line) != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// autoScanStopRequested reports whether the session was cancelled, either
+// through its own status or through auto_scan_state.
+func autoScanStopRequested(session *autoScanSession) error {
+	var status string
+	var isCancelled bool
+	err := dbPool.QueryRow(context.Background(), `
+		SELECT s.status, COALESCE(a.is_cancelled, false)
+		FROM auto_scan_sessions s
+		LEFT JOIN auto_scan_state a ON a.scope_target_id = s.scope_target_id
+		WHERE s.id = $1`, session.id).Scan(&status, &isCancelled)
+	if err != nil {
+		return &errAutoScanStopped{status: "failed", message: fmt.Sprintf("failed to read session status: %v", err)}
+	}
+	if status == "cancelled" || isCancelled {
+		return &errAutoScanStopped{status: "cancelled", message: "auto scan cancelled"}
+	}
+	return nil
+}
+
+// waitWhileAutoScanPaused blocks between steps while auto_scan_state.is_paused
+// is set. The step in flight when a pause is requested runs to completion.
+func waitWhileAutoScanPaused(session *autoScanSession) error {
+	logged := false
+	for {
+		if err := autoScanStopRequested(session); err != nil {
+			return err
+		}
+
+		var isPaused bool
+		err := dbPool.QueryRow(context.Background(),
+			`SELECT COALESCE(is_paused, false) FROM auto_scan_state WHERE scope_target_id = $1`,
+			session.scopeTargetID).Scan(&isPaused)
+		if err != nil || !isPaused {
+			if logged {
+				log.Printf("[AUTO-SCAN] [INFO] Session %s resumed", session.id)
+			}
+			return nil
+		}
+
+		if !logged {
+			log.Printf("[AUTO-SCAN] [INFO] Session %s paused", session.id)
+			logged = true
+		}
+		time.Sleep(autoScanPollInterval)
+	}
+}
+
+func ensureAutoScanStateColumns() error {
+	_, err := dbPool.Exec(context.Background(), `
+		ALTER TABLE auto_scan_state
+		ADD COLUMN IF NOT EXISTS is_paused BOOLEAN DEFAULT false,
+		ADD COLUMN IF NOT EXISTS is_cancelled BOOLEAN DEFAULT false`)
+	return err
+}
+
+func setAutoScanCurrentStep(scopeTargetID, step string) {
+	_, err := dbPool.Exec(context.Background(), `
+		INSERT INTO auto_scan_state (scope_target_id, current_step)
+		VALUES ($1, $2)
+		ON CONFLICT (scope_target_id)
+		DO UPDATE SET current_step = $2, updated_at = NOW()`, scopeTargetID, step)
+	if err != nil {
+		log.Printf("[AUTO-SCAN] [ERROR] Failed to update current step to %s: %v", step, err)
+	}
+}
+
+func resetAutoScanState(scopeTargetID, step string) {
+	_, err := dbPool.Exec(context.Background(), `
+		INSERT INTO auto_scan_state (scope_target_id, current_step, is_paused, is_cancelled)
+		VALUES ($1, $2, false, false)
+		ON CONFLICT (scope_target_id)
+		DO UPDATE SET current_step = $2, is_paused = false, is_cancelled = false, updated_at = NOW()`, scopeTargetID, step)
+	if err != nil {
+		log.Printf("[AUTO-SCAN] [ERROR] Failed to reset auto scan state for %s: %v", scopeTargetID, err)
+	}
+}
+
+func recordAutoScanStep(session *autoScanSession, record AutoScanStepRecord) {
+	session.done[record.Step] = record
+
+	entry, err := json.Marshal([]AutoScanStepRecord{record})
+	if err != nil {
+		log.Printf("[AUTO-SCAN] [ERROR] Failed to encode step record: %v", err)
+		return
+	}
+	_, err = dbPool.Exec(context.Background(), `
+		UPDATE auto_scan_sessions
+		SET steps_run = COALESCE(steps_run, '[]'::jsonb) || $1::jsonb
+		WHERE id = $2`, string(entry), session.id)
+	if err != nil {
+		log.Printf("[AUTO-SCAN] [ERROR] Failed to record step %s for session %s: %v", record.Step, session.id, err)
+	}
+}
+
+func updateAutoScanFinalStats(sessionID string, consolidated, live *int) {
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE auto_scan_sessions
+		SET final_consolidated_subdomains = COALESCE($1, final_consolidated_subdomains),
+			final_live_web_servers = COALESCE($2, final_live_web_servers)
+		WHERE id = $3`, consolidated, live, sessionID)
+	if err != nil {
+		log.Printf("[AUTO-SCAN] [ERROR] Failed to update final stats for session %s: %v", sessionID, err)
+	}
+}
+
+// finishAutoScanSession closes the session and resets auto_scan_state. A
+// session that was already cancelled keeps that status.
+func finishAutoScanSession(sessionID, scopeTargetID, status, message string) {
+	var errorMessage *string
+	if message != "" {
+		errorMessage = &message
+	}
+
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE auto_scan_sessions
+		SET status = CASE WHEN status = 'cancelled' THEN status ELSE $1 END,
+			ended_at = COALESCE(ended_at, NOW()),
+			error_message = COALESCE($2, error_message)
+		WHERE id = $3`, status, errorMessage, sessionID)
+	if err != nil {
+		log.Printf("[AUTO-SCAN] [ERROR] Failed to finish session %s: %v", sessionID, err)
+	}
+
+	_, err = dbPool.Exec(context.Background(), `
+		UPDATE auto_scan_state
+		SET current_step = $1, is_paused = false, is_cancelled = false, updated_at = NOW()
+		WHERE scope_target_id = $2`, "completed", scopeTargetID)
+	if err != nil {
+		log.Printf("[AUTO-SCAN] [ERROR] Failed to reset auto scan state for %s: %v", scopeTargetID, err)
+	}
+
+	log.Printf("[AUTO-SCAN] [INFO] Auto-scan session %s finished with status %s", sessionID, status)
+}
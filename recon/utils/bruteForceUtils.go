@@ -9,15 +9,14 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
-	"github.com/jackc/pgx/v5"
 )
 
 type ShuffleDNSScanStatus struct {
@@ -52,51 +51,6 @@ type CeWLScanStatus struct {
 	AutoScanSessionID sql.NullString `json:"auto_scan_session_id"`
 }
 
-func RunShuffleDNSScan(w http.ResponseWriter, r *http.Request) {
-	var payload struct {
-		FQDN              string  `json:"fqdn" binding:"required"`
-		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.FQDN == "" {
-		http.Error(w, "Invalid request body. `fqdn` is required.", http.StatusBadRequest)
-		return
-	}
-
-	domain := payload.FQDN
-	wildcardDomain := fmt.Sprintf("*.%s", domain)
-
-	query := `SELECT id FROM scope_targets WHERE type = 'Wildcard' AND scope_target = $1`
-	var scopeTargetID string
-	err := dbPool.QueryRow(context.Background(), query, wildcardDomain).Scan(&scopeTargetID)
-	if err != nil {
-		log.Printf("[ERROR] No matching wildcard scope target found for domain %s", domain)
-		http.Error(w, "No matching wildcard scope target found.", http.StatusBadRequest)
-		return
-	}
-
-	scanID := uuid.New().String()
-	var insertQuery string
-	var args []interface{}
-	if payload.AutoScanSessionID != nil && *payload.AutoScanSessionID != "" {
-		insertQuery = `INSERT INTO shuffledns_scans (scan_id, domain, status, scope_target_id, auto_scan_session_id) VALUES ($1, $2, $3, $4, $5)`
-		args = []interface{}{scanID, domain, "pending", scopeTargetID, *payload.AutoScanSessionID}
-	} else {
-		insertQuery = `INSERT INTO shuffledns_scans (scan_id, domain, status, scope_target_id) VALUES ($1, $2, $3, $4)`
-		args = []interface{}{scanID, domain, "pending", scopeTargetID}
-	}
-	_, err = dbPool.Exec(context.Background(), insertQuery, args...)
-	if err != nil {
-		log.Printf("[ERROR] Failed to create scan record: %v", err)
-		http.Error(w, "Failed to create scan record.", http.StatusInternalServerError)
-		return
-	}
-
-	go ExecuteAndParseShuffleDNSScan(scanID, domain)
-
-	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
-}
-
 func RunCeWLScansForUrls(w http.ResponseWriter, r *http.Request) {
 	var payload struct {
 		URLs []string `json:"urls" binding:"required"`
@@ -115,7 +69,11 @@ func RunCeWLScansForUrls(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteAndParseCeWLScansForUrls(scanID, payload.URLs)
+	if _, err := EnqueueScanJob("cewl-urls", scanID, ScanJobPayload{Targets: payload.URLs}); err != nil {
+		log.Printf("[ERROR] Failed to queue cewl-urls scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
@@ -139,13 +97,17 @@ func RunShuffleDNSWithWordlist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteAndParseShuffleDNSWithWordlist(scanID, payload.Wordlist)
+	if _, err := EnqueueScanJob("cewl-wordlist", scanID, ScanJobPayload{Target: payload.Wordlist}); err != nil {
+		log.Printf("[ERROR] Failed to queue cewl-wordlist scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteAndParseShuffleDNSWithWordlist(scanID, wordlist string) {
+func ExecuteAndParseShuffleDNSWithWordlist(ctx context.Context, scanID, wordlist string) {
 	log.Printf("[INFO] Starting ShuffleDNS scan with wordlist (scan ID: %s)", scanID)
 	startTime := time.Now()
 
@@ -170,13 +132,14 @@ func ExecuteAndParseShuffleDNSWithWordlist(scanID, wordlist string) {
 		return
 	}
 
-	cmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-shuffledns-1",
+	resolversFile := healthyResolverFile("shuffledns", fmt.Sprintf("resolvers_%s.txt", scanID), "/app/wordlists/resolvers.txt")
+	defer removeStagedFile("shuffledns", resolversFile)
+	cmd := NewToolCommandContext(ctx, 
+		"shuffledns",
 		"shuffledns",
 		"-d", wordlistFile,
 		"-w", "/app/wordlists/all.txt",
-		"-r", "/app/wordlists/resolvers.txt",
+		"-r", resolversFile,
 		"-silent",
 		"-massdns", "/usr/local/bin/massdns",
 		"-t", fmt.Sprintf("%d", rateLimit),
@@ -214,19 +177,26 @@ func ExecuteAndParseShuffleDNSWithWordlist(scanID, wordlist string) {
 	log.Printf("[INFO] Scan status updated for scan %s", scanID)
 }
 
-func ExecuteAndParseCeWLScansForUrls(scanID string, urls []string) {
+func ExecuteAndParseCeWLScansForUrls(ctx context.Context, scanID string, urls []string) {
 	log.Printf("[INFO] Starting CeWL scans for URLs (scan ID: %s)", scanID)
 	startTime := time.Now()
 
+	// Wait for every URL so the queue does not count the job as done early.
+	var wg sync.WaitGroup
 	for _, url := range urls {
-		go ExecuteAndParseCeWLScan(scanID, url)
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			ExecuteAndParseCeWLScan(ctx, scanID, url)
+		}(url)
 	}
+	wg.Wait()
 
 	execTime := time.Since(startTime).String()
 	log.Printf("[INFO] CeWL scans completed in %s", execTime)
 }
 
-func ExecuteAndParseShuffleDNSScan(scanID, domain string) {
+func ExecuteAndParseShuffleDNSScan(ctx context.Context, scanID, domain string) {
 	log.Printf("[INFO] Starting ShuffleDNS scan for domain %s (scan ID: %s)", domain, scanID)
 	startTime := time.Now()
 
@@ -251,13 +221,14 @@ func ExecuteAndParseShuffleDNSScan(scanID, domain string) {
 		return
 	}
 
-	cmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-shuffledns-1",
+	resolversFile := healthyResolverFile("shuffledns", fmt.Sprintf("resolvers_%s.txt", scanID), "/app/wordlists/resolvers.txt")
+	defer removeStagedFile("shuffledns", resolversFile)
+	cmd := NewToolCommandContext(ctx, 
+		"shuffledns",
 		"shuffledns",
 		"-d", domain,
 		"-w", "/app/wordlists/all.txt",
-		"-r", "/app/wordlists/resolvers.txt",
+		"-r", resolversFile,
 		"-silent",
 		"-massdns", "/usr/local/bin/massdns",
 		"-t", fmt.Sprintf("%d", rateLimit),
@@ -290,6 +261,7 @@ func ExecuteAndParseShuffleDNSScan(scanID, domain string) {
 	} else {
 		log.Printf("[DEBUG] ShuffleDNS output: %s", result)
 		UpdateShuffleDNSScanStatus(scanID, "success", result, stderr.String(), cmd.String(), execTime)
+		reportShuffleDNSWildcards(ctx, scanID, domain, result)
 	}
 
 	log.Printf("[INFO] Scan status updated for scan %s", scanID)
@@ -306,168 +278,7 @@ func UpdateShuffleDNSScanStatus(scanID, status, result, stderr, command, execTim
 	}
 }
 
-func GetShuffleDNSScanStatus(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	scanID := vars["scan_id"]
-
-	var scan ShuffleDNSScanStatus
-	query := `SELECT * FROM shuffledns_scans WHERE scan_id = $1`
-	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
-		&scan.ID,
-		&scan.ScanID,
-		&scan.Domain,
-		&scan.Status,
-		&scan.Result,
-		&scan.Error,
-		&scan.StdOut,
-		&scan.StdErr,
-		&scan.Command,
-		&scan.ExecTime,
-		&scan.CreatedAt,
-		&scan.ScopeTargetID,
-		&scan.AutoScanSessionID,
-	)
-
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			http.Error(w, "Scan not found", http.StatusNotFound)
-		} else {
-			log.Printf("[ERROR] Failed to get scan status: %v", err)
-			http.Error(w, "Failed to get scan status", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	response := map[string]interface{}{
-		"id":                   scan.ID,
-		"scan_id":              scan.ScanID,
-		"domain":               scan.Domain,
-		"status":               scan.Status,
-		"result":               nullStringToString(scan.Result),
-		"error":                nullStringToString(scan.Error),
-		"stdout":               nullStringToString(scan.StdOut),
-		"stderr":               nullStringToString(scan.StdErr),
-		"command":              nullStringToString(scan.Command),
-		"execution_time":       nullStringToString(scan.ExecTime),
-		"created_at":           scan.CreatedAt.Format(time.RFC3339),
-		"scope_target_id":      scan.ScopeTargetID,
-		"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-func GetShuffleDNSScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	scopeTargetID := vars["id"]
-
-	if scopeTargetID == "" {
-		log.Printf("[ERROR] No scope target ID provided")
-		http.Error(w, "No scope target ID provided", http.StatusBadRequest)
-		return
-	}
-
-	query := `SELECT * FROM shuffledns_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
-	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
-	if err != nil {
-		log.Printf("[ERROR] Failed to get scans: %v", err)
-		http.Error(w, "Failed to get scans", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	var scans []map[string]interface{}
-	for rows.Next() {
-		var scan ShuffleDNSScanStatus
-		err := rows.Scan(
-			&scan.ID,
-			&scan.ScanID,
-			&scan.Domain,
-			&scan.Status,
-			&scan.Result,
-			&scan.Error,
-			&scan.StdOut,
-			&scan.StdErr,
-			&scan.Command,
-			&scan.ExecTime,
-			&scan.CreatedAt,
-			&scan.ScopeTargetID,
-			&scan.AutoScanSessionID,
-		)
-		if err != nil {
-			log.Printf("[ERROR] Failed to scan row: %v", err)
-			continue
-		}
-
-		scans = append(scans, map[string]interface{}{
-			"id":                   scan.ID,
-			"scan_id":              scan.ScanID,
-			"domain":               scan.Domain,
-			"status":               scan.Status,
-			"result":               nullStringToString(scan.Result),
-			"error":                nullStringToString(scan.Error),
-			"stdout":               nullStringToString(scan.StdOut),
-			"stderr":               nullStringToString(scan.StdErr),
-			"command":              nullStringToString(scan.Command),
-			"execution_time":       nullStringToString(scan.ExecTime),
-			"created_at":           scan.CreatedAt.Format(time.RFC3339),
-			"scope_target_id":      scan.ScopeTargetID,
-			"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-		})
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(scans)
-}
-
-func RunCeWLScan(w http.ResponseWriter, r *http.Request) {
-	var payload struct {
-		FQDN              string  `json:"fqdn" binding:"required"`
-		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.FQDN == "" {
-		http.Error(w, "Invalid request body. `fqdn` is required.", http.StatusBadRequest)
-		return
-	}
-
-	domain := payload.FQDN
-	wildcardDomain := fmt.Sprintf("*.%s", domain)
-
-	// Get the scope target ID
-	query := `SELECT id FROM scope_targets WHERE type = 'Wildcard' AND scope_target = $1`
-	var scopeTargetID string
-	err := dbPool.QueryRow(context.Background(), query, wildcardDomain).Scan(&scopeTargetID)
-	if err != nil {
-		log.Printf("[ERROR] No matching wildcard scope target found for domain %s", domain)
-		http.Error(w, "No matching wildcard scope target found.", http.StatusBadRequest)
-		return
-	}
-
-	scanID := uuid.New().String()
-	var insertQuery string
-	var args []interface{}
-	if payload.AutoScanSessionID != nil && *payload.AutoScanSessionID != "" {
-		insertQuery = `INSERT INTO cewl_scans (scan_id, url, status, scope_target_id, auto_scan_session_id) VALUES ($1, $2, $3, $4, $5)`
-		args = []interface{}{scanID, domain, "pending", scopeTargetID, *payload.AutoScanSessionID}
-	} else {
-		insertQuery = `INSERT INTO cewl_scans (scan_id, url, status, scope_target_id) VALUES ($1, $2, $3, $4)`
-		args = []interface{}{scanID, domain, "pending", scopeTargetID}
-	}
-	_, err = dbPool.Exec(context.Background(), insertQuery, args...)
-	if err != nil {
-		log.Printf("[ERROR] Failed to create scan record: %v", err)
-		http.Error(w, "Failed to create scan record.", http.StatusInternalServerError)
-		return
-	}
-
-	go ExecuteAndParseCeWLScan(scanID, domain)
-
-	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
-}
-
-func ExecuteAndParseCeWLScan(scanID, domain string) {
+func ExecuteAndParseCeWLScan(ctx context.Context, scanID, domain string) {
 	log.Printf("[DEBUG] ====== Starting CeWL + ShuffleDNS Process ======")
 	log.Printf("[DEBUG] ScanID: %s, Domain: %s", scanID, domain)
 	startTime := time.Now()
@@ -535,8 +346,6 @@ func ExecuteAndParseCeWLScan(scanID, domain string) {
 
 		// Build CeWL command
 		cmdArgs := []string{
-			"docker", "exec",
-			"ars0n-framework-v2-cewl-1",
 			"timeout", "600",
 			"ruby", "/app/cewl.rb",
 			cleanURL,
@@ -551,7 +360,7 @@ func ExecuteAndParseCeWLScan(scanID, domain string) {
 			cmdArgs = append(cmdArgs, "--ua", customUserAgent)
 		}
 
-		cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+		cmd := NewToolCommandContext(ctx, "cewl", cmdArgs...)
 
 		var stdout, stderr bytes.Buffer
 		cmd.Stdout = &stdout
@@ -631,11 +440,7 @@ func ExecuteAndParseCeWLScan(scanID, domain string) {
 	}
 
 	// Copy wordlist to container
-	copyCmd := exec.Command(
-		"docker", "cp",
-		wordlistFile,
-		"ars0n-framework-v2-shuffledns-1:/tmp/wordlist.txt")
-	if err := copyCmd.Run(); err != nil {
+	if err := CopyToTool("shuffledns", wordlistFile, "/tmp/wordlist.txt"); err != nil {
 		log.Printf("[ERROR] Failed to copy wordlist to container: %v", err)
 		UpdateCeWLScanStatus(scanID, "error", "", fmt.Sprintf("Failed to copy wordlist to container: %v", err), "", time.Since(startTime).String())
 		return
@@ -644,11 +449,7 @@ func ExecuteAndParseCeWLScan(scanID, domain string) {
 	log.Printf("[DEBUG] Wordlist copied to ShuffleDNS container")
 
 	// Verify file in container
-	checkCmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-shuffledns-1",
-		"cat", "/tmp/wordlist.txt",
-	)
+	checkCmd := NewToolCommandContext(ctx, "shuffledns", "cat", "/tmp/wordlist.txt")
 	var checkOutput bytes.Buffer
 	checkCmd.Stdout = &checkOutput
 	if err := checkCmd.Run(); err == nil {
@@ -683,28 +484,16 @@ func ExecuteAndParseCeWLScan(scanID, domain string) {
 		return
 	}
 
-	// Debug: Check resolvers file
-	resolversCmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-shuffledns-1",
-		"cat", "/app/wordlists/resolvers.txt",
-	)
-	var resolversOutput bytes.Buffer
-	resolversCmd.Stdout = &resolversOutput
-	if err := resolversCmd.Run(); err == nil {
-		log.Printf("[DEBUG] Resolvers file size: %d bytes", len(resolversOutput.String()))
-	} else {
-		log.Printf("[ERROR] Failed to read resolvers file: %v", err)
-	}
+	resolversFile := healthyResolverFile("shuffledns", fmt.Sprintf("resolvers_%s.txt", shuffleDNSScanID), "/app/wordlists/resolvers.txt")
+	defer removeStagedFile("shuffledns", resolversFile)
 
 	// Run ShuffleDNS with the combined wordlist
-	shuffleCmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-shuffledns-1",
+	shuffleCmd := NewToolCommandContext(ctx, 
+		"shuffledns",
 		"shuffledns",
 		"-d", domain,
 		"-w", "/tmp/wordlist.txt",
-		"-r", "/app/wordlists/resolvers.txt",
+		"-r", resolversFile,
 		"-silent",
 		"-massdns", "/usr/local/bin/massdns",
 		"-mode", "bruteforce",
@@ -738,6 +527,7 @@ func ExecuteAndParseCeWLScan(scanID, domain string) {
 	} else {
 		log.Printf("[INFO] ShuffleDNS found results")
 		UpdateShuffleDNSCustomScanStatus(shuffleDNSScanID, "success", shuffleResult, shuffleStderr.String(), shuffleCmd.String(), shuffleExecTime)
+		reportShuffleDNSWildcards(ctx, shuffleDNSScanID, domain, shuffleResult)
 	}
 
 	log.Printf("[DEBUG] ====== Completed CeWL + ShuffleDNS Process ======")
@@ -754,121 +544,6 @@ func UpdateCeWLScanStatus(scanID, status, result, stderr, command, execTime stri
 	}
 }
 
-func GetCeWLScanStatus(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	scanID := vars["scan_id"]
-
-	var scan CeWLScanStatus
-	query := `SELECT * FROM cewl_scans WHERE scan_id = $1`
-	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
-		&scan.ID,
-		&scan.ScanID,
-		&scan.URL,
-		&scan.Status,
-		&scan.Result,
-		&scan.Error,
-		&scan.StdOut,
-		&scan.StdErr,
-		&scan.Command,
-		&scan.ExecTime,
-		&scan.CreatedAt,
-		&scan.ScopeTargetID,
-		&scan.AutoScanSessionID,
-	)
-
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			http.Error(w, "Scan not found", http.StatusNotFound)
-		} else {
-			log.Printf("[ERROR] Failed to get scan status: %v", err)
-			http.Error(w, "Failed to get scan status", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	response := map[string]interface{}{
-		"id":                   scan.ID,
-		"scan_id":              scan.ScanID,
-		"url":                  scan.URL,
-		"status":               scan.Status,
-		"result":               nullStringToString(scan.Result),
-		"error":                nullStringToString(scan.Error),
-		"stdout":               nullStringToString(scan.StdOut),
-		"stderr":               nullStringToString(scan.StdErr),
-		"command":              nullStringToString(scan.Command),
-		"execution_time":       nullStringToString(scan.ExecTime),
-		"created_at":           scan.CreatedAt.Format(time.RFC3339),
-		"scope_target_id":      scan.ScopeTargetID,
-		"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-func GetCeWLScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	scopeTargetID := vars["id"]
-
-	if scopeTargetID == "" {
-		log.Printf("[ERROR] No scope target ID provided")
-		http.Error(w, "No scope target ID provided", http.StatusBadRequest)
-		return
-	}
-
-	query := `SELECT * FROM cewl_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
-	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
-	if err != nil {
-		log.Printf("[ERROR] Failed to get scans: %v", err)
-		http.Error(w, "Failed to get scans", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	var scans []map[string]interface{}
-	for rows.Next() {
-		var scan CeWLScanStatus
-		err := rows.Scan(
-			&scan.ID,
-			&scan.ScanID,
-			&scan.URL,
-			&scan.Status,
-			&scan.Result,
-			&scan.Error,
-			&scan.StdOut,
-			&scan.StdErr,
-			&scan.Command,
-			&scan.ExecTime,
-			&scan.CreatedAt,
-			&scan.ScopeTargetID,
-			&scan.AutoScanSessionID,
-		)
-		if err != nil {
-			log.Printf("[ERROR] Failed to scan row: %v", err)
-			continue
-		}
-
-		scans = append(scans, map[string]interface{}{
-			"id":                   scan.ID,
-			"scan_id":              scan.ScanID,
-			"url":                  scan.URL,
-			"status":               scan.Status,
-			"result":               nullStringToString(scan.Result),
-			"error":                nullStringToString(scan.Error),
-			"stdout":               nullStringToString(scan.StdOut),
-			"stderr":               nullStringToString(scan.StdErr),
-			"command":              nullStringToString(scan.Command),
-			"execution_time":       nullStringToString(scan.ExecTime),
-			"created_at":           scan.CreatedAt.Format(time.RFC3339),
-			"scope_target_id":      scan.ScopeTargetID,
-			"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-		})
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(scans)
-}
-
 func UpdateShuffleDNSCustomScanStatus(scanID, status, result, stderr, command, execTime string) {
 	log.Printf("[INFO] Updating ShuffleDNS custom scan status for %s to %s", scanID, status)
 	query := `UPDATE shufflednscustom_scans SET status = $1, result = $2, stderr = $3, command = $4, execution_time = $5 WHERE scan_id = $6`
@@ -890,7 +565,7 @@ func GetShuffleDNSCustomScansForScopeTarget(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	query := `SELECT * FROM shufflednscustom_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
+	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM shufflednscustom_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
 	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get scans: %v", err)
@@ -2,7 +2,6 @@ package utils
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,104 +9,10 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/google/uuid"
-	"github.com/gorilla/mux"
 	"github.com/jackc/pgx/v5"
 )
 
-type CensysCompanyScanStatus struct {
-	ID                string         `json:"id"`
-	ScanID            string         `json:"scan_id"`
-	CompanyName       string         `json:"company_name"`
-	Status            string         `json:"status"`
-	Result            sql.NullString `json:"result,omitempty"`
-	Error             sql.NullString `json:"error,omitempty"`
-	StdOut            sql.NullString `json:"stdout,omitempty"`
-	StdErr            sql.NullString `json:"stderr,omitempty"`
-	Command           sql.NullString `json:"command,omitempty"`
-	ExecTime          sql.NullString `json:"execution_time,omitempty"`
-	CreatedAt         time.Time      `json:"created_at"`
-	ScopeTargetID     string         `json:"scope_target_id"`
-	AutoScanSessionID sql.NullString `json:"auto_scan_session_id"`
-}
-
-func RunCensysCompanyScan(w http.ResponseWriter, r *http.Request) {
-	log.Printf("[CENSYS-COMPANY] [INFO] Starting Censys Company scan request handling")
-	var payload struct {
-		CompanyName       string  `json:"company_name" binding:"required"`
-		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.CompanyName == "" {
-		log.Printf("[CENSYS-COMPANY] [ERROR] Invalid request body: %v", err)
-		http.Error(w, "Invalid request body. `company_name` is required.", http.StatusBadRequest)
-		return
-	}
-
-	companyName := payload.CompanyName
-	log.Printf("[CENSYS-COMPANY] [INFO] Processing Censys Company scan for company: %s", companyName)
-
-	query := `SELECT id FROM scope_targets WHERE type = 'Company' AND scope_target = $1`
-	var scopeTargetID string
-	err := dbPool.QueryRow(context.Background(), query, companyName).Scan(&scopeTargetID)
-	if err != nil {
-		log.Printf("[CENSYS-COMPANY] [ERROR] No matching company scope target found for company %s: %v", companyName, err)
-		http.Error(w, "No matching company scope target found.", http.StatusBadRequest)
-		return
-	}
-	log.Printf("[CENSYS-COMPANY] [INFO] Found scope target ID: %s for company: %s", scopeTargetID, companyName)
-
-	scanID := uuid.New().String()
-	log.Printf("[CENSYS-COMPANY] [INFO] Generated new scan ID: %s", scanID)
-
-	createTableQuery := `
-		CREATE TABLE IF NOT EXISTS censys_company_scans (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			scan_id UUID NOT NULL UNIQUE,
-			company_name TEXT NOT NULL,
-			status VARCHAR(50) NOT NULL,
-			result TEXT,
-			error TEXT,
-			stdout TEXT,
-			stderr TEXT,
-			command TEXT,
-			execution_time TEXT,
-			created_at TIMESTAMP DEFAULT NOW(),
-			scope_target_id UUID REFERENCES scope_targets(id) ON DELETE CASCADE,
-			auto_scan_session_id UUID REFERENCES auto_scan_sessions(id) ON DELETE SET NULL
-		);`
-	_, err = dbPool.Exec(context.Background(), createTableQuery)
-	if err != nil {
-		log.Printf("[CENSYS-COMPANY] [ERROR] Failed to create censys_company_scans table: %v", err)
-		http.Error(w, "Failed to create scan table.", http.StatusInternalServerError)
-		return
-	}
-	log.Printf("[CENSYS-COMPANY] [INFO] Ensured censys_company_scans table exists")
-
-	var insertQuery string
-	var args []interface{}
-	if payload.AutoScanSessionID != nil && *payload.AutoScanSessionID != "" {
-		insertQuery = `INSERT INTO censys_company_scans (scan_id, company_name, status, scope_target_id, auto_scan_session_id) VALUES ($1, $2, $3, $4, $5)`
-		args = []interface{}{scanID, companyName, "pending", scopeTargetID, *payload.AutoScanSessionID}
-	} else {
-		insertQuery = `INSERT INTO censys_company_scans (scan_id, company_name, status, scope_target_id) VALUES ($1, $2, $3, $4)`
-		args = []interface{}{scanID, companyName, "pending", scopeTargetID}
-	}
-	_, err = dbPool.Exec(context.Background(), insertQuery, args...)
-	if err != nil {
-		log.Printf("[CENSYS-COMPANY] [ERROR] Failed to create scan record: %v", err)
-		http.Error(w, "Failed to create scan record.", http.StatusInternalServerError)
-		return
-	}
-	log.Printf("[CENSYS-COMPANY] [INFO] Successfully created Censys Company scan record in database")
-
-	go ExecuteCensysCompanyScan(scanID, companyName)
-
-	log.Printf("[CENSYS-COMPANY] [INFO] Censys Company scan initiated successfully, returning scan ID: %s", scanID)
-	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
-}
-
-func ExecuteCensysCompanyScan(scanID, companyName string) {
+func ExecuteCensysCompanyScan(ctx context.Context, scanID, companyName string) {
 	log.Printf("[CENSYS-COMPANY] [INFO] Starting Censys Company scan execution for company %s (scan ID: %s)", companyName, scanID)
 	startTime := time.Now()
 
@@ -235,160 +140,3 @@ func UpdateCensysCompanyScanStatus(scanID, status, result, stderr, command, exec
 		log.Printf("[CENSYS-COMPANY] [INFO] Successfully updated Censys Company scan status to %s for scan ID %s", status, scanID)
 	}
 }
-
-func GetCensysCompanyScanStatus(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	scanID := vars["scan_id"]
-	log.Printf("[CENSYS-COMPANY] [INFO] Retrieving Censys Company scan status for scan ID: %s", scanID)
-
-	var scan CensysCompanyScanStatus
-	query := `SELECT id, scan_id, company_name, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM censys_company_scans WHERE scan_id = $1`
-	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
-		&scan.ID,
-		&scan.ScanID,
-		&scan.CompanyName,
-		&scan.Status,
-		&scan.Result,
-		&scan.Error,
-		&scan.StdOut,
-		&scan.StdErr,
-		&scan.Command,
-		&scan.ExecTime,
-		&scan.CreatedAt,
-		&scan.ScopeTargetID,
-		&scan.AutoScanSessionID,
-	)
-
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			log.Printf("[CENSYS-COMPANY] [ERROR] Censys Company scan not found for scan ID: %s", scanID)
-			http.Error(w, "Scan not found", http.StatusNotFound)
-		} else {
-			log.Printf("[CENSYS-COMPANY] [ERROR] Failed to get Censys Company scan status for scan ID %s: %v", scanID, err)
-			http.Error(w, "Failed to get scan status", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	log.Printf("[CENSYS-COMPANY] [INFO] Successfully retrieved Censys Company scan status for scan ID %s: %s", scanID, scan.Status)
-	if scan.Result.Valid {
-		log.Printf("[CENSYS-COMPANY] [DEBUG] Scan has valid results of length: %d bytes", len(scan.Result.String))
-	}
-
-	response := map[string]interface{}{
-		"id":                   scan.ID,
-		"scan_id":              scan.ScanID,
-		"company_name":         scan.CompanyName,
-		"status":               scan.Status,
-		"result":               nullStringToString(scan.Result),
-		"error":                nullStringToString(scan.Error),
-		"stdout":               nullStringToString(scan.StdOut),
-		"stderr":               nullStringToString(scan.StdErr),
-		"command":              nullStringToString(scan.Command),
-		"execution_time":       nullStringToString(scan.ExecTime),
-		"created_at":           scan.CreatedAt.Format(time.RFC3339),
-		"scope_target_id":      scan.ScopeTargetID,
-		"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("[CENSYS-COMPANY] [ERROR] Failed to encode Censys Company scan response: %v", err)
-	} else {
-		log.Printf("[CENSYS-COMPANY] [INFO] Successfully sent Censys Company scan status response")
-	}
-}
-
-func GetCensysCompanyScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	scopeTargetID := vars["id"]
-	log.Printf("[CENSYS-COMPANY] [INFO] Fetching Censys Company scans for scope target ID: %s", scopeTargetID)
-
-	if scopeTargetID == "" {
-		log.Printf("[CENSYS-COMPANY] [ERROR] No scope target ID provided")
-		http.Error(w, "No scope target ID provided", http.StatusBadRequest)
-		return
-	}
-
-	createTableQuery := `
-		CREATE TABLE IF NOT EXISTS censys_company_scans (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			scan_id UUID NOT NULL UNIQUE,
-			company_name TEXT NOT NULL,
-			status VARCHAR(50) NOT NULL,
-			result TEXT,
-			error TEXT,
-			stdout TEXT,
-			stderr TEXT,
-			command TEXT,
-			execution_time TEXT,
-			created_at TIMESTAMP DEFAULT NOW(),
-			scope_target_id UUID REFERENCES scope_targets(id) ON DELETE CASCADE,
-			auto_scan_session_id UUID REFERENCES auto_scan_sessions(id) ON DELETE SET NULL
-		);`
-	_, err := dbPool.Exec(context.Background(), createTableQuery)
-	if err != nil {
-		log.Printf("[CENSYS-COMPANY] [ERROR] Failed to create censys_company_scans table: %v", err)
-		http.Error(w, "Failed to create scan table.", http.StatusInternalServerError)
-		return
-	}
-
-	query := `SELECT id, scan_id, company_name, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM censys_company_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
-	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
-	if err != nil {
-		log.Printf("[CENSYS-COMPANY] [ERROR] Failed to get scans: %v", err)
-		http.Error(w, "Failed to get scans", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	var scans []map[string]interface{}
-	for rows.Next() {
-		var scan CensysCompanyScanStatus
-		err := rows.Scan(
-			&scan.ID,
-			&scan.ScanID,
-			&scan.CompanyName,
-			&scan.Status,
-			&scan.Result,
-			&scan.Error,
-			&scan.StdOut,
-			&scan.StdErr,
-			&scan.Command,
-			&scan.ExecTime,
-			&scan.CreatedAt,
-			&scan.ScopeTargetID,
-			&scan.AutoScanSessionID,
-		)
-		if err != nil {
-			log.Printf("[CENSYS-COMPANY] [ERROR] Error scanning Censys Company scan row: %v", err)
-			continue
-		}
-
-		scanMap := map[string]interface{}{
-			"id":                   scan.ID,
-			"scan_id":              scan.ScanID,
-			"company_name":         scan.CompanyName,
-			"status":               scan.Status,
-			"result":               nullStringToString(scan.Result),
-			"error":                nullStringToString(scan.Error),
-			"stdout":               nullStringToString(scan.StdOut),
-			"stderr":               nullStringToString(scan.StdErr),
-			"command":              nullStringToString(scan.Command),
-			"execution_time":       nullStringToString(scan.ExecTime),
-			"created_at":           scan.CreatedAt.Format(time.RFC3339),
-			"scope_target_id":      scan.ScopeTargetID,
-			"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-		}
-		scans = append(scans, scanMap)
-	}
-
-	log.Printf("[CENSYS-COMPANY] [INFO] Successfully retrieved %d Censys Company scans for scope target %s", len(scans), scopeTargetID)
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(scans); err != nil {
-		log.Printf("[CENSYS-COMPANY] [ERROR] Failed to encode scans response: %v", err)
-	} else {
-		log.Printf("[CENSYS-COMPANY] [INFO] Successfully sent Censys Company scans response")
-	}
-}
@@ -0,0 +1,609 @@
+package utils
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cloud range attribution tags the IP and live web server assets of a scope
+// target with the cloud provider, service and region whose published
+// address ranges contain them. The range files (AWS, GCP and Oracle JSON,
+// Azure service tags, the DigitalOcean geofeed CSV and Cloudflare's IP list)
+// live in RECON_CLOUD_RANGES_DIR (default data/cloud-ranges) and are
+// refreshed with `recon-engine import-cloud-ranges [provider...]` or
+// POST /cloud-ranges/import. Lookups go through a path-compressed radix
+// tree over 128-bit keys, IPv4 being mapped into ::ffff:0:0/96, so the most
+// specific published prefix wins.
+
+const cloudRangesDownloadLimit = 128 << 20
+
+type CloudRange struct {
+	Provider string `json:"provider"`
+	Service  string `json:"service,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Prefix   string `json:"prefix"`
+
+	// generic marks catch-all entries (AWS "AMAZON", Azure "AzureCloud")
+	// that a named service for the same prefix replaces
+	generic bool
+}
+
+type cloudRangeSource struct {
+	provider string
+	file     string
+	url      string
+	parse    func([]byte) ([]CloudRange, error)
+}
+
+var cloudRangeSources = []cloudRangeSource{
+	{"aws", "aws.json", "https://ip-ranges.amazonaws.com/ip-ranges.json", parseAWSRanges},
+	{"gcp", "gcp.json", "https://www.gstatic.com/ipranges/cloud.json", parseGCPRanges},
+	{"azure", "azure.json", "", parseAzureRanges},
+	{"oracle", "oracle.json", "https://docs.oracle.com/en-us/iaas/tools/public_ip_ranges.json", parseOracleRanges},
+	{"digitalocean", "digitalocean.csv", "https://digitalocean.com/geo/google.csv", parseDigitalOceanRanges},
+	{"cloudflare", "cloudflare.json", "https://api.cloudflare.com/client/v4/ips", parseCloudflareRanges},
+}
+
+// The service tags file name changes weekly, so its link is read from the
+// download page unless RECON_AZURE_SERVICE_TAGS_URL is set
+const azureServiceTagsPage = "https://www.microsoft.com/en-us/download/details.aspx?id=56519"
+
+var azureServiceTagsLink = regexp.MustCompile(`https://download\.microsoft\.com/download/[^"'\s]+ServiceTags_Public_\d+\.json`)
+
+func cloudRangesDir() string {
+	if dir := os.Getenv("RECON_CLOUD_RANGES_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join("data", "cloud-ranges")
+}
+
+// cloudRangeNode is a radix tree node covering key/bits. Nodes without an
+// entry only join two subtrees.
+type cloudRangeNode struct {
+	key      [16]byte
+	bits     int
+	entry    *CloudRange
+	children [2]*cloudRangeNode
+}
+
+type cloudRangeTree struct {
+	root      *cloudRangeNode
+	size      int
+	providers map[string]int
+}
+
+func keyBit(key [16]byte, i int) int {
+	return int(key[i/8]>>(7-i%8)) & 1
+}
+
+// commonKeyBits counts the leading bits a and b share, up to limit.
+func commonKeyBits(a, b [16]byte, limit int) int {
+	n := 0
+	for n < limit && keyBit(a, n) == keyBit(b, n) {
+		n++
+	}
+	return n
+}
+
+func maskKey(key [16]byte, bits int) [16]byte {
+	for i := bits; i < 128; i++ {
+		key[i/8] &^= 1 << (7 - i%8)
+	}
+	return key
+}
+
+func cloudRangeKey(prefix netip.Prefix) ([16]byte, int) {
+	prefix = prefix.Masked()
+	bits := prefix.Bits()
+	if prefix.Addr().Is4() {
+		bits += 96
+	}
+	return prefix.Addr().As16(), bits
+}
+
+func (t *cloudRangeTree) insert(prefix netip.Prefix, entry CloudRange) {
+	key, bits := cloudRangeKey(prefix)
+	link := &t.root
+	for {
+		n := *link
+		if n == nil {
+			*link = &cloudRangeNode{key: key, bits: bits, entry: &entry}
+			t.size++
+			t.providers[entry.Provider]++
+			return
+		}
+		common := commonKeyBits(n.key, key, min(n.bits, bits))
+		if common < n.bits {
+			split := &cloudRangeNode{key: maskKey(key, common), bits: common}
+			split.children[keyBit(n.key, common)] = n
+			*link = split
+			if common == bits {
+				split.entry = &entry
+			} else {
+				split.children[keyBit(key, common)] = &cloudRangeNode{key: key, bits: bits, entry: &entry}
+			}
+			t.size++
+			t.providers[entry.Provider]++
+			return
+		}
+		if n.bits == bits {
+			if n.entry == nil {
+				n.entry = &entry
+				t.size++
+				t.providers[entry.Provider]++
+				return
+			}
+			// The same prefix from another provider keeps the first listing
+			if n.entry.Provider != entry.Provider {
+				return
+			}
+			if n.entry.generic && !entry.generic {
+				n.entry.Service, n.entry.generic = entry.Service, false
+			}
+			if n.entry.Region == "" {
+				n.entry.Region = entry.Region
+			}
+			return
+		}
+		link = &n.children[keyBit(key, n.bits)]
+	}
+}
+
+// lookup returns the most specific range containing addr. A missing region
+// is taken from the nearest enclosing range of the same provider.
+func (t *cloudRangeTree) lookup(addr netip.Addr) (CloudRange, bool) {
+	key := addr.As16()
+	var path []*CloudRange
+	for n := t.root; n != nil; {
+		if commonKeyBits(n.key, key, n.bits) < n.bits {
+			break
+		}
+		if n.entry != nil {
+			path = append(path, n.entry)
+		}
+		if n.bits == 128 {
+			break
+		}
+		n = n.children[keyBit(key, n.bits)]
+	}
+	if len(path) == 0 {
+		return CloudRange{}, false
+	}
+	best := *path[len(path)-1]
+	for i := len(path) - 2; i >= 0 && best.Region == ""; i-- {
+		if path[i].Provider == best.Provider {
+			best.Region = path[i].Region
+		}
+	}
+	return best, true
+}
+
+func parseCloudPrefixes(provider, service, region string, generic bool, prefixes []string) []CloudRange {
+	var ranges []CloudRange
+	for _, p := range prefixes {
+		if p = strings.TrimSpace(p); p != "" {
+			ranges = append(ranges, CloudRange{Provider: provider, Service: service, Region: region, Prefix: p, generic: generic})
+		}
+	}
+	return ranges
+}
+
+func parseAWSRanges(data []byte) ([]CloudRange, error) {
+	var doc struct {
+		Prefixes []struct {
+			IPPrefix string `json:"ip_prefix"`
+			Region   string `json:"region"`
+			Service  string `json:"service"`
+		} `json:"prefixes"`
+		IPv6Prefixes []struct {
+			IPv6Prefix string `json:"ipv6_prefix"`
+			Region     string `json:"region"`
+			Service    string `json:"service"`
+		} `json:"ipv6_prefixes"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var ranges []CloudRange
+	for _, p := range doc.Prefixes {
+		ranges = append(ranges, parseCloudPrefixes("aws", p.Service, awsRegion(p.Region), p.Service == "AMAZON", []string{p.IPPrefix})...)
+	}
+	for _, p := range doc.IPv6Prefixes {
+		ranges = append(ranges, parseCloudPrefixes("aws", p.Service, awsRegion(p.Region), p.Service == "AMAZON", []string{p.IPv6Prefix})...)
+	}
+	return ranges, nil
+}
+
+func awsRegion(region string) string {
+	if region == "GLOBAL" {
+		return ""
+	}
+	return region
+}
+
+func parseGCPRanges(data []byte) ([]CloudRange, error) {
+	var doc struct {
+		Prefixes []struct {
+			IPv4Prefix string `json:"ipv4Prefix"`
+			IPv6Prefix string `json:"ipv6Prefix"`
+			Service    string `json:"service"`
+			Scope      string `json:"scope"`
+		} `json:"prefixes"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var ranges []CloudRange
+	for _, p := range doc.Prefixes {
+		region := p.Scope
+		if region == "global" {
+			region = ""
+		}
+		ranges = append(ranges, parseCloudPrefixes("gcp", p.Service, region, false, []string{p.IPv4Prefix, p.IPv6Prefix})...)
+	}
+	return ranges, nil
+}
+
+func parseAzureRanges(data []byte) ([]CloudRange, error) {
+	var doc struct {
+		Values []struct {
+			Name       string `json:"name"`
+			Properties struct {
+				Region          string   `json:"region"`
+				SystemService   string   `json:"systemService"`
+				AddressPrefixes []string `json:"addressPrefixes"`
+			} `json:"properties"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var ranges []CloudRange
+	for _, v := range doc.Values {
+		service, _, _ := strings.Cut(v.Name, ".")
+		if v.Properties.SystemService != "" {
+			service = v.Properties.SystemService
+		}
+		generic := v.Properties.SystemService == ""
+		ranges = append(ranges, parseCloudPrefixes("azure", service, v.Properties.Region, generic, v.Properties.AddressPrefixes)...)
+	}
+	return ranges, nil
+}
+
+func parseOracleRanges(data []byte) ([]CloudRange, error) {
+	var doc struct {
+		Regions []struct {
+			Region string `json:"region"`
+			CIDRs  []struct {
+				CIDR string   `json:"cidr"`
+				Tags []string `json:"tags"`
+			} `json:"cidrs"`
+		} `json:"regions"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var ranges []CloudRange
+	for _, r := range doc.Regions {
+		for _, c := range r.CIDRs {
+			generic := len(c.Tags) == 1 && c.Tags[0] == "OCI"
+			ranges = append(ranges, parseCloudPrefixes("oracle", strings.Join(c.Tags, ","), r.Region, generic, []string{c.CIDR})...)
+		}
+	}
+	return ranges, nil
+}
+
+// parseDigitalOceanRanges reads the RFC 8805 geofeed: prefix, country,
+// region, city, postal code.
+func parseDigitalOceanRanges(data []byte) ([]CloudRange, error) {
+	reader := csv.NewReader(strings.NewReader(string(data)))
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var ranges []CloudRange
+	for _, record := range records {
+		if len(record) == 0 {
+			continue
+		}
+		region := ""
+		if len(record) > 2 {
+			region = record[2]
+		}
+		if region == "" && len(record) > 1 {
+			region = record[1]
+		}
+		ranges = append(ranges, parseCloudPrefixes("digitalocean", "", region, false, []string{record[0]})...)
+	}
+	return ranges, nil
+}
+
+func parseCloudflareRanges(data []byte) ([]CloudRange, error) {
+	var doc struct {
+		Result struct {
+			IPv4 []string `json:"ipv4_cidrs"`
+			IPv6 []string `json:"ipv6_cidrs"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return parseCloudPrefixes("cloudflare", "CDN", "", false, append(doc.Result.IPv4, doc.Result.IPv6...)), nil
+}
+
+var (
+	cloudRangesMu        sync.Mutex
+	cloudRanges          *cloudRangeTree
+	cloudRangesSignature string
+)
+
+// loadCloudRanges returns the range tree, rebuilding it when a file in the
+// ranges directory has changed since the last load, which also picks up
+// imports run from another process.
+func loadCloudRanges() *cloudRangeTree {
+	dir := cloudRangesDir()
+	var signature strings.Builder
+	for _, src := range cloudRangeSources {
+		if info, err := os.Stat(filepath.Join(dir, src.file)); err == nil {
+			fmt.Fprintf(&signature, "%s:%d:%d;", src.file, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+
+	cloudRangesMu.Lock()
+	defer cloudRangesMu.Unlock()
+	if cloudRanges != nil && signature.String() == cloudRangesSignature {
+		return cloudRanges
+	}
+
+	tree := &cloudRangeTree{providers: make(map[string]int)}
+	for _, src := range cloudRangeSources {
+		data, err := os.ReadFile(filepath.Join(dir, src.file))
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				log.Printf("[CLOUD-RANGES] [ERROR] Failed to read %s: %v", src.file, err)
+			}
+			continue
+		}
+		ranges, err := src.parse(data)
+		if err != nil {
+			log.Printf("[CLOUD-RANGES] [ERROR] Failed to parse %s: %v", src.file, err)
+			continue
+		}
+		for _, r := range ranges {
+			prefix, err := netip.ParsePrefix(r.Prefix)
+			if err != nil {
+				continue
+			}
+			tree.insert(prefix, r)
+		}
+	}
+	cloudRanges, cloudRangesSignature = tree, signature.String()
+	log.Printf("[CLOUD-RANGES] [INFO] Loaded %d cloud prefixes from %s", tree.size, dir)
+	return tree
+}
+
+// LookupCloudRange returns the published cloud range containing ip.
+func LookupCloudRange(ip string) (CloudRange, bool) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return CloudRange{}, false
+	}
+	return loadCloudRanges().lookup(addr.Unmap())
+}
+
+func downloadCloudRanges(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, cloudRangesDownloadLimit))
+}
+
+func azureServiceTagsURL(ctx context.Context, client *http.Client) (string, error) {
+	if url := os.Getenv("RECON_AZURE_SERVICE_TAGS_URL"); url != "" {
+		return url, nil
+	}
+	page, err := downloadCloudRanges(ctx, client, azureServiceTagsPage)
+	if err != nil {
+		return "", err
+	}
+	url := azureServiceTagsLink.Find(page)
+	if url == nil {
+		return "", errors.New("no service tags link on the Azure download page")
+	}
+	return string(url), nil
+}
+
+// ImportCloudRanges downloads the published range files of the given
+// providers (all when empty) into the ranges directory. A file is only
+// replaced once the new copy parses, so a failed download keeps the last
+// good one.
+func ImportCloudRanges(ctx context.Context, providers []string) error {
+	dir := cloudRangesDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", dir, err)
+	}
+	client := &http.Client{Timeout: 2 * time.Minute}
+
+	var errs []error
+	for _, src := range cloudRangeSources {
+		if len(providers) > 0 && !slices.Contains(providers, src.provider) {
+			continue
+		}
+		url := src.url
+		if src.provider == "azure" {
+			var err error
+			if url, err = azureServiceTagsURL(ctx, client); err != nil {
+				errs = append(errs, fmt.Errorf("azure: %v", err))
+				continue
+			}
+		}
+		data, err := downloadCloudRanges(ctx, client, url)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", src.provider, err))
+			continue
+		}
+		ranges, err := src.parse(data)
+		if err != nil || len(ranges) == 0 {
+			errs = append(errs, fmt.Errorf("%s: no ranges in download (%v)", src.provider, err))
+			continue
+		}
+		tmp := filepath.Join(dir, src.file+".tmp")
+		if err := os.WriteFile(tmp, data, 0644); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", src.provider, err))
+			continue
+		}
+		if err := os.Rename(tmp, filepath.Join(dir, src.file)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", src.provider, err))
+			continue
+		}
+		log.Printf("[CLOUD-RANGES] [INFO] Imported %d %s prefixes", len(ranges), src.provider)
+	}
+	return errors.Join(errs...)
+}
+
+// cloudTagSourceRanges marks cloud_* columns set by attachCloudRanges, so a
+// later pass clears only its own tags.
+const cloudTagSourceRanges = "ranges"
+
+func createCloudTagSourceColumn() {
+	_, err := dbPool.Exec(context.Background(), `ALTER TABLE consolidated_attack_surface_assets ADD COLUMN IF NOT EXISTS cloud_tag_source TEXT`)
+	if err != nil {
+		log.Printf("[CLOUD-RANGES] [ERROR] Failed to add cloud_tag_source column: %v", err)
+	}
+}
+
+// attachCloudRanges fills cloud_provider, cloud_service_type and
+// cloud_region of the IP and live web server assets whose address is in a
+// published cloud range, and clears them on the assets it tagged earlier
+// that no longer are. Tags set by other sources are left alone.
+func attachCloudRanges(scopeTargetID string) error {
+	createCloudTagSourceColumn()
+	tree := loadCloudRanges()
+	if tree.size == 0 {
+		return nil
+	}
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT id::text, ip_address FROM consolidated_attack_surface_assets
+		WHERE scope_target_id = $1::uuid AND asset_type IN ('ip_address', 'live_web_server')
+			AND ip_address IS NOT NULL AND ip_address != ''`, scopeTargetID)
+	if err != nil {
+		return err
+	}
+	ids := []string{}
+	var providers, services, regions []string
+	for rows.Next() {
+		var id, ip string
+		if err := rows.Scan(&id, &ip); err != nil {
+			rows.Close()
+			return err
+		}
+		addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+		if err != nil {
+			continue
+		}
+		if r, ok := tree.lookup(addr.Unmap()); ok {
+			ids = append(ids, id)
+			providers = append(providers, r.Provider)
+			services = append(services, r.Service)
+			regions = append(regions, r.Region)
+		}
+	}
+	rows.Close()
+
+	ctx := context.Background()
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, `
+		UPDATE consolidated_attack_surface_assets SET
+			cloud_provider = NULL, cloud_service_type = NULL, cloud_region = NULL, cloud_tag_source = NULL
+		WHERE scope_target_id = $1::uuid AND asset_type IN ('ip_address', 'live_web_server')
+			AND cloud_tag_source = $3
+			AND NOT (id = ANY($2::uuid[]))`, scopeTargetID, ids, cloudTagSourceRanges)
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		_, err = tx.Exec(ctx, `
+			UPDATE consolidated_attack_surface_assets a SET
+				cloud_provider = t.provider,
+				cloud_service_type = NULLIF(t.service, ''),
+				cloud_region = NULLIF(t.region, ''),
+				cloud_tag_source = $5
+			FROM unnest($1::uuid[], $2::text[], $3::text[], $4::text[]) AS t(id, provider, service, region)
+			WHERE a.id = t.id`, ids, providers, services, regions, cloudTagSourceRanges)
+		if err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	log.Printf("[CLOUD-RANGES] [INFO] Tagged %d assets with cloud ranges for %s", len(ids), scopeTargetID)
+	return nil
+}
+
+// GetCloudRanges reports the loaded prefixes per provider, and with ?ip=
+// the range containing that address.
+func GetCloudRanges(w http.ResponseWriter, r *http.Request) {
+	tree := loadCloudRanges()
+	result := map[string]interface{}{
+		"directory": cloudRangesDir(),
+		"prefixes":  tree.size,
+		"providers": tree.providers,
+	}
+	if ip := r.URL.Query().Get("ip"); ip != "" {
+		if match, ok := LookupCloudRange(ip); ok {
+			result["match"] = match
+		} else {
+			result["match"] = nil
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// ImportCloudRangesHandler refreshes the range files in the background.
+// ?provider= may be repeated to limit the import.
+func ImportCloudRangesHandler(w http.ResponseWriter, r *http.Request) {
+	providers := r.URL.Query()["provider"]
+	go func() {
+		if err := ImportCloudRanges(context.Background(), providers); err != nil {
+			log.Printf("[CLOUD-RANGES] [ERROR] Import incomplete: %v", err)
+		}
+		loadCloudRanges()
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "started"})
+}
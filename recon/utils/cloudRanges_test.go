@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testCloudRangeTree(ranges []CloudRange) *cloudRangeTree {
+	tree := &cloudRangeTree{providers: make(map[string]int)}
+	for _, r := range ranges {
+		tree.insert(netip.MustParsePrefix(r.Prefix), r)
+	}
+	return tree
+}
+
+func TestCloudRangeTreeLookup(t *testing.T) {
+	ranges := []CloudRange{
+		{Provider: "aws", Service: "AMAZON", Prefix: "52.0.0.0/8", generic: true},
+		{Provider: "aws", Service: "EC2", Region: "us-east-1", Prefix: "52.95.0.0/16"},
+		{Provider: "aws", Service: "S3", Prefix: "52.95.128.0/20"},
+		{Provider: "aws", Service: "ROUTE53", Region: "us-west-2", Prefix: "52.96.0.0/16"},
+		{Provider: "aws", Service: "EC2", Region: "eu-west-1", Prefix: "2600:1f00::/24"},
+		{Provider: "digitalocean", Region: "NL-NH", Prefix: "198.51.100.7/32"},
+		{Provider: "oracle", Service: "OCI", Region: "uk-london-1", Prefix: "10.1.2.3/8"},
+	}
+	forward := testCloudRangeTree(ranges)
+	reversed := make([]CloudRange, len(ranges))
+	for i, r := range ranges {
+		reversed[len(ranges)-1-i] = r
+	}
+	backward := testCloudRangeTree(reversed)
+
+	tests := []struct {
+		addr        string
+		wantService string
+		wantRegion  string
+		wantOK      bool
+	}{
+		{addr: "52.1.2.3", wantService: "AMAZON", wantOK: true},
+		{addr: "52.95.0.1", wantService: "EC2", wantRegion: "us-east-1", wantOK: true},
+		// S3 publishes no region, so it comes from the enclosing EC2 range.
+		{addr: "52.95.130.1", wantService: "S3", wantRegion: "us-east-1", wantOK: true},
+		{addr: "52.95.144.1", wantService: "EC2", wantRegion: "us-east-1", wantOK: true},
+		{addr: "52.96.255.255", wantService: "ROUTE53", wantRegion: "us-west-2", wantOK: true},
+		{addr: "52.97.0.0", wantService: "AMAZON", wantOK: true},
+		{addr: "2600:1f00:1234::1", wantService: "EC2", wantRegion: "eu-west-1", wantOK: true},
+		{addr: "198.51.100.7", wantRegion: "NL-NH", wantOK: true},
+		{addr: "10.200.0.1", wantService: "OCI", wantRegion: "uk-london-1", wantOK: true},
+		{addr: "198.51.100.8"},
+		{addr: "53.0.0.1"},
+		{addr: "2600:1e00::1"},
+		// An IPv6 address sharing the low bits of an IPv4 range.
+		{addr: "::3400:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			for name, tree := range map[string]*cloudRangeTree{"forward": forward, "reversed": backward} {
+				got, ok := tree.lookup(netip.MustParseAddr(tt.addr))
+				if ok != tt.wantOK || got.Service != tt.wantService || got.Region != tt.wantRegion {
+					t.Errorf("%s lookup() = %+v, %v; want service %q region %q, %v", name, got, ok, tt.wantService, tt.wantRegion, tt.wantOK)
+				}
+			}
+		})
+	}
+
+	if forward.size != len(ranges) || backward.size != len(ranges) || forward.providers["aws"] != 5 {
+		t.Errorf("size = %d/%d, aws = %d; want %d, 5", forward.size, backward.size, forward.providers["aws"], len(ranges))
+	}
+	if _, ok := (&cloudRangeTree{}).lookup(netip.MustParseAddr("52.1.2.3")); ok {
+		t.Error("lookup() in an empty tree succeeded")
+	}
+}
+
+func TestCloudRangeTreeDuplicates(t *testing.T) {
+	tree := testCloudRangeTree([]CloudRange{
+		{Provider: "cloudflare", Service: "CDN", Prefix: "104.16.0.0/13"},
+		{Provider: "gcp", Service: "Google Cloud", Region: "us-central1", Prefix: "104.16.0.0/13"},
+		{Provider: "aws", Service: "AMAZON", Region: "eu-west-1", Prefix: "3.5.0.0/16", generic: true},
+		{Provider: "aws", Service: "EC2", Prefix: "3.5.0.0/16"},
+		{Provider: "azure", Service: "AzureFrontDoor", Prefix: "13.107.0.0/16"},
+		{Provider: "azure", Service: "AzureCloud", Region: "westeurope", Prefix: "13.107.0.0/16", generic: true},
+	})
+
+	tests := []struct {
+		addr         string
+		wantProvider string
+		wantService  string
+		wantRegion   string
+	}{
+		// The first provider to list a prefix keeps it.
+		{addr: "104.16.1.1", wantProvider: "cloudflare", wantService: "CDN"},
+		// A named service replaces the catch-all and keeps its region.
+		{addr: "3.5.1.1", wantProvider: "aws", wantService: "EC2", wantRegion: "eu-west-1"},
+		{addr: "13.107.1.1", wantProvider: "azure", wantService: "AzureFrontDoor", wantRegion: "westeurope"},
+	}
+	for _, tt := range tests {
+		got, ok := tree.lookup(netip.MustParseAddr(tt.addr))
+		if !ok || got.Provider != tt.wantProvider || got.Service != tt.wantService || got.Region != tt.wantRegion {
+			t.Errorf("lookup(%s) = %+v, %v; want %s %q %q", tt.addr, got, ok, tt.wantProvider, tt.wantService, tt.wantRegion)
+		}
+	}
+	if tree.size != 3 {
+		t.Errorf("size = %d, want 3", tree.size)
+	}
+}
+
+func TestParseCloudRangeFeeds(t *testing.T) {
+	tests := []struct {
+		name    string
+		parse   func([]byte) ([]CloudRange, error)
+		data    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "aws", parse: parseAWSRanges,
+			data: `{"prefixes":[{"ip_prefix":"3.5.0.0/16","region":"GLOBAL","service":"AMAZON"},{"ip_prefix":" ","region":"us-east-1","service":"EC2"}],
+				"ipv6_prefixes":[{"ipv6_prefix":"2600:1f00::/24","region":"eu-west-1","service":"EC2"}]}`,
+			want: []string{"aws AMAZON  3.5.0.0/16", "aws EC2 eu-west-1 2600:1f00::/24"}},
+		{name: "aws truncated", parse: parseAWSRanges, data: `{"prefixes":[{"ip_prefix":"3.5.0.0/16"`, wantErr: true},
+		{name: "aws wrong shape", parse: parseAWSRanges, data: `{"prefixes":{"ip_prefix":"3.5.0.0/16"}}`, wantErr: true},
+		{name: "gcp", parse: parseGCPRanges,
+			data: `{"prefixes":[{"ipv4Prefix":"34.1.0.0/16","service":"Google Cloud","scope":"global"},{"ipv6Prefix":"2600:1900::/28","service":"Google Cloud","scope":"us-east1"}]}`,
+			want: []string{"gcp Google Cloud  34.1.0.0/16", "gcp Google Cloud us-east1 2600:1900::/28"}},
+		{name: "gcp not JSON", parse: parseGCPRanges, data: `<html>rate limited</html>`, wantErr: true},
+		{name: "azure", parse: parseAzureRanges,
+			data: `{"values":[{"name":"AzureCloud.westeurope","properties":{"region":"westeurope","addressPrefixes":["13.69.0.0/17"]}},
+				{"name":"AzureFrontDoor.Frontend","properties":{"systemService":"AzureFrontDoor","addressPrefixes":["13.107.246.0/24"]}}]}`,
+			want: []string{"azure AzureCloud westeurope 13.69.0.0/17", "azure AzureFrontDoor  13.107.246.0/24"}},
+		{name: "azure truncated", parse: parseAzureRanges, data: `{"values":[{"name":"AzureCloud`, wantErr: true},
+		{name: "oracle", parse: parseOracleRanges,
+			data: `{"regions":[{"region":"uk-london-1","cidrs":[{"cidr":"132.145.0.0/16","tags":["OCI","OSN"]}]}]}`,
+			want: []string{"oracle OCI,OSN uk-london-1 132.145.0.0/16"}},
+		{name: "oracle empty", parse: parseOracleRanges, data: `{}`},
+		{name: "digitalocean", parse: parseDigitalOceanRanges,
+			data: "# geofeed\n198.51.100.0/24,NL,NL-NH,Amsterdam,\n\n203.0.113.0/24,SG,,,\n192.0.2.0/24\n",
+			want: []string{"digitalocean  NL-NH 198.51.100.0/24", "digitalocean  SG 203.0.113.0/24", "digitalocean   192.0.2.0/24"}},
+		{name: "digitalocean bad quoting", parse: parseDigitalOceanRanges, data: "198.51.100.0/24,\"NL\n", wantErr: true},
+		{name: "cloudflare", parse: parseCloudflareRanges,
+			data: `{"result":{"ipv4_cidrs":["104.16.0.0/13"],"ipv6_cidrs":["2606:4700::/32"]},"success":true}`,
+			want: []string{"cloudflare CDN  104.16.0.0/13", "cloudflare CDN  2606:4700::/32"}},
+		{name: "cloudflare truncated", parse: parseCloudflareRanges, data: `{"result":{"ipv4_cidrs":["104.16.0.0/13"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges, err := tt.parse([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, r := range ranges {
+				got = append(got, strings.Join([]string{r.Provider, r.Service, r.Region, r.Prefix}, " "))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("parse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupCloudRangeSkipsBadInput(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("RECON_CLOUD_RANGES_DIR", dir)
+	t.Cleanup(func() {
+		cloudRangesMu.Lock()
+		cloudRanges, cloudRangesSignature = nil, ""
+		cloudRangesMu.Unlock()
+	})
+	os.WriteFile(filepath.Join(dir, "aws.json"),
+		[]byte(`{"prefixes":[{"ip_prefix":"not-a-prefix","service":"EC2"},{"ip_prefix":"52.95.0.0/16","region":"us-east-1","service":"EC2"}]}`), 0644)
+	os.WriteFile(filepath.Join(dir, "cloudflare.json"), []byte(`{"result":`), 0644)
+
+	tests := []struct {
+		ip     string
+		wantOK bool
+	}{
+		{ip: "52.95.1.1", wantOK: true},
+		{ip: " 52.95.1.1\n", wantOK: true},
+		{ip: "::ffff:52.95.1.1", wantOK: true},
+		{ip: "104.16.0.1"},
+		{ip: "52.95.1"},
+		{ip: ""},
+	}
+	for _, tt := range tests {
+		if got, ok := LookupCloudRange(tt.ip); ok != tt.wantOK || (ok && got.Region != "us-east-1") {
+			t.Errorf("LookupCloudRange(%q) = %+v, %v; want %v", tt.ip, got, ok, tt.wantOK)
+		}
+	}
+}
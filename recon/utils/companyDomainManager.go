@@ -779,6 +779,39 @@ func DeleteAllLiveWebServerDomainsFromTool(scopeTargetID string) (int64, error)
 	return result.RowsAffected(), nil
 }
 
+// PTR sweep domain functions
+func GetPTRSweepDomainsForTool(scopeTargetID string) ([]string, error) {
+	return ptrSweepRootDomains(scopeTargetID)
+}
+
+func DeletePTRSweepDomainFromTool(scopeTargetID, domainToDelete string) (bool, error) {
+	createPTRSweepTables()
+	result, err := dbPool.Exec(context.Background(),
+		`DELETE FROM ptr_sweep_records
+		 WHERE scope_target_id = $1 AND (hostname = $2 OR hostname LIKE '%.' || $2)`,
+		scopeTargetID, domainToDelete)
+	if err != nil {
+		log.Printf("[DOMAIN-MANAGER] [ERROR] Failed to delete PTR sweep domain: %v", err)
+		return false, err
+	}
+	if result.RowsAffected() == 0 {
+		log.Printf("[DOMAIN-MANAGER] [WARNING] PTR sweep domain '%s' not found", domainToDelete)
+		return false, nil
+	}
+	log.Printf("[DOMAIN-MANAGER] [INFO] Successfully deleted PTR sweep domain '%s' (%d records)", domainToDelete, result.RowsAffected())
+	return true, nil
+}
+
+func DeleteAllPTRSweepDomainsFromTool(scopeTargetID string) (int64, error) {
+	createPTRSweepTables()
+	result, err := dbPool.Exec(context.Background(),
+		`DELETE FROM ptr_sweep_records WHERE scope_target_id = $1`, scopeTargetID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 // Helper functions for live web server domain extraction
 func isIPv4Address(s string) bool {
 	return strings.Contains(s, ".") &&
@@ -47,6 +47,7 @@ type AttackSurfaceAsset struct {
 	Title               *string                `json:"title,omitempty"`
 	WebServer           *string                `json:"web_server,omitempty"`
 	Technologies        []string               `json:"technologies,omitempty"`
+	TechnologyDetails   []Technology           `json:"technology_details,omitempty"`
 	ContentLength       *int                   `json:"content_length,omitempty"`
 	ResponseTime        *float64               `json:"response_time_ms,omitempty"`
 	ScreenshotPath      *string                `json:"screenshot_path,omitempty"`
@@ -128,6 +129,7 @@ type ConsolidationResult struct {
 	NetworkRanges      int                  `json:"network_ranges"`
 	IPAddresses        int                  `json:"ip_addresses"`
 	LiveWebServers     int                  `json:"live_web_servers"`
+	Services           int                  `json:"services"`
 	CloudAssets        int                  `json:"cloud_assets"`
 	FQDNs              int                  `json:"fqdns"`
 	TotalRelationships int                  `json:"total_relationships"`
@@ -195,6 +197,15 @@ func ConsolidateAttackSurface(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("[ATTACK SURFACE] Consolidated %d live web servers", liveWebServers)
 
+	log.Printf("[ATTACK SURFACE] Consolidating services...")
+	services, err := consolidateServices(scopeTargetID)
+	if err != nil {
+		log.Printf("Error consolidating services: %v", err)
+		http.Error(w, "Failed to consolidate services", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[ATTACK SURFACE] Consolidated %d services", services)
+
 	// Consolidate FQDNs (before cloud assets so we can parse them for cloud domains)
 	log.Printf("[ATTACK SURFACE] Consolidating FQDNs...")
 	fqdns, err := consolidateFQDNs(scopeTargetID)
@@ -224,6 +235,48 @@ func ConsolidateAttackSurface(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("[ATTACK SURFACE] Created %d asset relationships", relationshipCount)
 
+	// Re-attach metadata that lives outside the consolidated tables. These are
+	// enrichments, so a failure is logged and the consolidated assets are
+	// still returned.
+	attachSteps := []struct {
+		name   string
+		attach func(string) error
+	}{
+		{"TLS fingerprints", attachTLSFingerprintMetadata},
+		{"TLS posture", attachTLSPosture},
+		{"technology details", attachTechnologyDetails},
+		{"PTR sweep records", attachPTRSweepRecords},
+		{"vhost bindings", attachVHostBindings},
+		{"cloud ranges", attachCloudRanges},
+	}
+	for _, step := range attachSteps {
+		if err := step.attach(scopeTargetID); err != nil {
+			log.Printf("[ATTACK SURFACE] [ERROR] Failed to attach %s: %v", step.name, err)
+		}
+	}
+
+	// Check the new FQDN assets for dangling CNAMEs in the background
+	go func() {
+		if err := RunTakeoverDetection(scopeTargetID); err != nil {
+			log.Printf("[TAKEOVER] [ERROR] Detection failed for %s: %v", scopeTargetID, err)
+		}
+	}()
+	go func() {
+		if err := RunMailPostureAnalysis(scopeTargetID); err != nil {
+			log.Printf("[MAIL-POSTURE] [ERROR] Analysis failed for %s: %v", scopeTargetID, err)
+		}
+	}()
+	go func() {
+		if err := RunVendorAttribution(scopeTargetID); err != nil {
+			log.Printf("[VENDORS] [ERROR] Attribution failed for %s: %v", scopeTargetID, err)
+		}
+	}()
+	go func() {
+		if err := RunNameserverPosture(scopeTargetID); err != nil {
+			log.Printf("[NS-POSTURE] [ERROR] Checks failed for %s: %v", scopeTargetID, err)
+		}
+	}()
+
 	// Fetch all consolidated assets
 	log.Printf("[ATTACK SURFACE] Fetching consolidated assets...")
 	assets, err := fetchConsolidatedAssets(scopeTargetID)
@@ -241,6 +294,7 @@ func ConsolidateAttackSurface(w http.ResponseWriter, r *http.Request) {
 		NetworkRanges:      networkRanges,
 		IPAddresses:        ipAddresses,
 		LiveWebServers:     liveWebServers,
+		Services:           services,
 		CloudAssets:        cloudAssets,
 		FQDNs:              fqdns,
 		TotalRelationships: relationshipCount,
@@ -257,6 +311,7 @@ func ConsolidateAttackSurface(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[ATTACK SURFACE]   • Network Ranges: %d", networkRanges)
 	log.Printf("[ATTACK SURFACE]   • IP Addresses: %d", ipAddresses)
 	log.Printf("[ATTACK SURFACE]   • Live Web Servers: %d", liveWebServers)
+	log.Printf("[ATTACK SURFACE]   • Services: %d", services)
 	log.Printf("[ATTACK SURFACE]   • Cloud Assets: %d", cloudAssets)
 	log.Printf("[ATTACK SURFACE]   • FQDNs: %d", fqdns)
 	log.Printf("[ATTACK SURFACE]   • Asset Relationships: %d", relationshipCount)
@@ -285,6 +340,19 @@ func InvestigateFQDNs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The quick SSL check above only knows the issuer and expiry; keep the
+	// full TLS posture where one exists
+	if err := attachTLSPosture(scopeTargetID); err != nil {
+		log.Printf("Error attaching TLS posture: %v", err)
+	}
+
+	// Re-attribute vendors now that the stored DNS is fresh
+	go func() {
+		if err := RunVendorAttribution(scopeTargetID); err != nil {
+			log.Printf("[VENDORS] [ERROR] Attribution failed for %s: %v", scopeTargetID, err)
+		}
+	}()
+
 	executionTime := time.Since(startTime)
 
 	result := map[string]interface{}{
@@ -335,8 +403,10 @@ func GetAttackSurfaceAssetCounts(w http.ResponseWriter, r *http.Request) {
 		"network_ranges":   0,
 		"ip_addresses":     0,
 		"live_web_servers": 0,
+		"services":         0,
 		"cloud_assets":     0,
 		"fqdns":            0,
+		"vendors":          0,
 	}
 
 	for rows.Next() {
@@ -357,10 +427,14 @@ func GetAttackSurfaceAssetCounts(w http.ResponseWriter, r *http.Request) {
 			counts["ip_addresses"] = count
 		case "live_web_server":
 			counts["live_web_servers"] = count
+		case "service":
+			counts["services"] = count
 		case "cloud_asset":
 			counts["cloud_assets"] = count
 		case "fqdn":
 			counts["fqdns"] = count
+		case "vendor":
+			counts["vendors"] = count
 		}
 	}
 
@@ -859,31 +933,33 @@ func consolidateIPAddresses(scopeTargetID string) (int, error) {
 			FROM target_urls tu
 			WHERE tu.scope_target_id = $1::uuid
 			AND tu.ip_address IS NOT NULL AND tu.ip_address != ''
-			AND tu.ip_address ~ '^(\d{1,3}\.){3}\d{1,3}$'
+			AND (tu.ip_address ~ '^(\d{1,3}\.){3}\d{1,3}$' OR tu.ip_address ~ '^[0-9A-Fa-f]{0,4}(:[0-9A-Fa-f]{0,4}){2,7}$')
 
 			UNION
 
-			-- 4. DNS A records from Amass scans (validate before casting)
+			-- 4. DNS A/AAAA records from Amass scans (validate before casting)
 			SELECT DISTINCT
 				dr.record::inet as ip_address,
-				'dns_a_record' as source_type,
+				CASE WHEN dr.record_type = 'AAAA' THEN 'dns_aaaa_record' ELSE 'dns_a_record' END as source_type,
 				ARRAY[dr.record]::text[] as source_ips
 			FROM dns_records dr
 			JOIN amass_scans ams ON dr.scan_id = ams.scan_id
 			WHERE ams.scope_target_id = $1::uuid AND ams.status = 'success'
-			AND dr.record_type = 'A' AND dr.record ~ '^(\d{1,3}\.){3}\d{1,3}$'
+			AND ((dr.record_type = 'A' AND dr.record ~ '^(\d{1,3}\.){3}\d{1,3}$')
+				OR (dr.record_type = 'AAAA' AND dr.record ~ '^[0-9A-Fa-f]{0,4}(:[0-9A-Fa-f]{0,4}){2,7}$'))
 			AND dr.record IS NOT NULL AND dr.record != ''
 
 			UNION
 
-			-- 5. DNS A records from DNSx company scans (validate before casting)
+			-- 5. DNS A/AAAA records from DNSx company scans (validate before casting)
 			SELECT DISTINCT
 				dcdr.record::inet as ip_address,
-				'dnsx_a_record' as source_type,
+				CASE WHEN dcdr.record_type = 'AAAA' THEN 'dnsx_aaaa_record' ELSE 'dnsx_a_record' END as source_type,
 				ARRAY[dcdr.record]::text[] as source_ips
 			FROM dnsx_company_dns_records dcdr
-			WHERE dcdr.scope_target_id = $1::uuid AND dcdr.record_type = 'A'
-			AND dcdr.record ~ '^(\d{1,3}\.){3}\d{1,3}$'
+			WHERE dcdr.scope_target_id = $1::uuid
+			AND ((dcdr.record_type = 'A' AND dcdr.record ~ '^(\d{1,3}\.){3}\d{1,3}$')
+				OR (dcdr.record_type = 'AAAA' AND dcdr.record ~ '^[0-9A-Fa-f]{0,4}(:[0-9A-Fa-f]{0,4}){2,7}$'))
 			AND dcdr.record IS NOT NULL AND dcdr.record != ''
 
 			UNION
@@ -918,21 +994,22 @@ func consolidateIPAddresses(scopeTargetID string) (int, error) {
 				'amass_enum_dns' as source_type,
 				ARRAY[aecdr.record]::text[] as source_ips
 			FROM amass_enum_company_dns_records aecdr
-			WHERE aecdr.scope_target_id = $1::uuid AND aecdr.record_type = 'A'
-			AND aecdr.record ~ '^(\d{1,3}\.){3}\d{1,3}$'
+			WHERE aecdr.scope_target_id = $1::uuid
+			AND ((aecdr.record_type = 'A' AND aecdr.record ~ '^(\d{1,3}\.){3}\d{1,3}$')
+				OR (aecdr.record_type = 'AAAA' AND aecdr.record ~ '^[0-9A-Fa-f]{0,4}(:[0-9A-Fa-f]{0,4}){2,7}$'))
 			AND aecdr.record IS NOT NULL AND aecdr.record != ''
 		),
 		ip_enriched_data AS (
 			SELECT
 				ip.ip_address,
-				string_agg(DISTINCT ip.source_type, ', ') as ip_type,
+				CASE WHEN family(ip.ip_address) = 6 THEN 'ipv6' ELSE 'ipv4' END as ip_type,
 				TRIM(BOTH 'AS' FROM mnr.asn) as asn_number,
 				mnr.organization as asn_organization,
 				mnr.country as asn_country,
 				ARRAY_AGG(DISTINCT lws.hostname) FILTER (WHERE lws.hostname IS NOT NULL) as hostnames,
 				ARRAY_AGG(DISTINCT ptr_record) FILTER (WHERE ptr_record IS NOT NULL) as ptr_records,
-				ARRAY_AGG(DISTINCT dcdr.record) FILTER (WHERE dcdr.record_type = 'A' AND dcdr.record = host(ip.ip_address)) as dnsx_a_records,
-				ARRAY_AGG(DISTINCT aedr.record) FILTER (WHERE aedr.record_type = 'A' AND aedr.record = host(ip.ip_address)) as amass_a_records,
+				ARRAY_AGG(DISTINCT dcdr.record) FILTER (WHERE dcdr.record_type IN ('A', 'AAAA') AND dcdr.record = host(ip.ip_address)) as dnsx_a_records,
+				ARRAY_AGG(DISTINCT aedr.record) FILTER (WHERE aedr.record_type IN ('A', 'AAAA') AND aedr.record = host(ip.ip_address)) as amass_a_records,
 				ARRAY_AGG(DISTINCT ip.source_type) as httpx_sources
 			FROM comprehensive_ip_data ip
 			LEFT JOIN metabigor_network_ranges mnr ON ip.ip_address << mnr.cidr_block::inet
@@ -1478,6 +1555,81 @@ func consolidateLiveWebServers(scopeTargetID string) (int, error) {
 	return insertedCount, nil
 }
 
+// consolidateServices turns the non-HTTP services fingerprinted by IP/Port
+// scans into assets, keeping the most recent result for each ip:port.
+func consolidateServices(scopeTargetID string) (int, error) {
+	log.Printf("[SERVICE CONSOLIDATION] Starting service consolidation for scope target: %s", scopeTargetID)
+
+	consolidatedQuery := `
+		INSERT INTO consolidated_attack_surface_assets (
+			scope_target_id, asset_type, asset_subtype, asset_identifier,
+			ip_address, ip_type, port, protocol, title
+		)
+		SELECT DISTINCT ON (ds.ip_address, ds.port)
+			$1::uuid,
+			'service',
+			ds.service,
+			host(ds.ip_address) || ':' || ds.port::text || '/' || ds.protocol,
+			host(ds.ip_address),
+			CASE WHEN family(ds.ip_address) = 6 THEN 'ipv6' ELSE 'ipv4' END,
+			ds.port,
+			ds.protocol,
+			NULLIF(TRIM(COALESCE(ds.product, '') || ' ' || COALESCE(ds.version, '')), '')
+		FROM discovered_services ds
+		JOIN ip_port_scans ips ON ds.scan_id = ips.scan_id
+		WHERE ips.scope_target_id = $1::uuid AND ips.status = 'success'
+		ORDER BY ds.ip_address, ds.port, ds.discovered_at DESC
+		ON CONFLICT (scope_target_id, asset_type, asset_identifier) DO UPDATE SET
+			asset_subtype = EXCLUDED.asset_subtype,
+			ip_address = EXCLUDED.ip_address,
+			ip_type = EXCLUDED.ip_type,
+			port = EXCLUDED.port,
+			protocol = EXCLUDED.protocol,
+			title = EXCLUDED.title,
+			last_updated = NOW()
+	`
+
+	result, err := dbPool.Exec(context.Background(), consolidatedQuery, scopeTargetID)
+	if err != nil {
+		log.Printf("[SERVICE CONSOLIDATION] Error inserting consolidated services: %v", err)
+		return 0, err
+	}
+	insertedCount := int(result.RowsAffected())
+
+	// Product, version and banner are kept as metadata on the asset
+	metadataQuery := `
+		INSERT INTO consolidated_attack_surface_metadata (asset_id, metadata_type, metadata_key, metadata_value)
+		SELECT a.id, 'service', m.key, m.value
+		FROM (
+			SELECT DISTINCT ON (ds.ip_address, ds.port)
+				host(ds.ip_address) || ':' || ds.port::text || '/' || ds.protocol AS asset_identifier,
+				ds.product, ds.version, ds.banner, ds.tls
+			FROM discovered_services ds
+			JOIN ip_port_scans ips ON ds.scan_id = ips.scan_id
+			WHERE ips.scope_target_id = $1::uuid AND ips.status = 'success'
+			ORDER BY ds.ip_address, ds.port, ds.discovered_at DESC
+		) latest
+		JOIN consolidated_attack_surface_assets a
+			ON a.scope_target_id = $1::uuid AND a.asset_type = 'service' AND a.asset_identifier = latest.asset_identifier
+		CROSS JOIN LATERAL (VALUES
+			('product', latest.product),
+			('version', latest.version),
+			('banner', latest.banner),
+			('tls', latest.tls::text)
+		) AS m(key, value)
+		WHERE m.value IS NOT NULL AND m.value <> ''
+		ON CONFLICT (asset_id, metadata_type, metadata_key) DO UPDATE SET
+			metadata_value = EXCLUDED.metadata_value
+	`
+	if _, err := dbPool.Exec(context.Background(), metadataQuery, scopeTargetID); err != nil {
+		log.Printf("[SERVICE CONSOLIDATION] Error inserting service metadata: %v", err)
+		return insertedCount, err
+	}
+
+	log.Printf("[SERVICE CONSOLIDATION] ✅ Successfully inserted/updated %d service records", insertedCount)
+	return insertedCount, nil
+}
+
 func consolidateCloudAssets(scopeTargetID string) (int, error) {
 	log.Printf("[CLOUD ASSET CONSOLIDATION] Starting cloud asset consolidation for scope target: %s", scopeTargetID)
 
@@ -3114,7 +3266,35 @@ func createComprehensiveAssetRelationships(scopeTargetID string) (int, error) {
 	totalRelationships += liveWebServerToIPCount
 	log.Printf("[RELATIONSHIP MAPPING] Created %d Live Web Server -> IP Address relationships", liveWebServerToIPCount)
 
-	// 7. Live Web Servers -> Cloud Assets (via domain/URL matching)
+	// 7. Services -> IP Addresses (via IP matching)
+	log.Printf("[RELATIONSHIP MAPPING] Creating Service -> IP Address relationships...")
+	serviceToIPQuery := `
+		INSERT INTO consolidated_attack_surface_relationships (
+			parent_asset_id, child_asset_id, relationship_type
+		)
+		SELECT DISTINCT 
+			ip.id, svc.id, 'hosts'
+		FROM consolidated_attack_surface_assets ip
+		JOIN consolidated_attack_surface_assets svc ON ip.scope_target_id = svc.scope_target_id
+		WHERE ip.scope_target_id = $1::uuid 
+			AND ip.asset_type = 'ip_address'
+			AND svc.asset_type = 'service'
+			AND ip.ip_address IS NOT NULL
+			AND svc.ip_address IS NOT NULL
+			AND ip.ip_address = svc.ip_address
+		ON CONFLICT (parent_asset_id, child_asset_id, relationship_type) DO NOTHING
+	`
+
+	serviceToIPResult, err := dbPool.Exec(context.Background(), serviceToIPQuery, scopeTargetID)
+	if err != nil {
+		log.Printf("[RELATIONSHIP MAPPING] Error creating Service -> IP Address relationships: %v", err)
+		return totalRelationships, err
+	}
+	serviceToIPCount := int(serviceToIPResult.RowsAffected())
+	totalRelationships += serviceToIPCount
+	log.Printf("[RELATIONSHIP MAPPING] Created %d Service -> IP Address relationships", serviceToIPCount)
+
+	// 8. Live Web Servers -> Cloud Assets (via domain/URL matching)
 	log.Printf("[RELATIONSHIP MAPPING] Creating Live Web Server -> Cloud Asset relationships...")
 	liveWebServerToCloudQuery := `
 		INSERT INTO consolidated_attack_surface_relationships (
@@ -3155,6 +3335,7 @@ func createComprehensiveAssetRelationships(scopeTargetID string) (int, error) {
 	log.Printf("[RELATIONSHIP MAPPING]   • Cloud Asset -> FQDN: %d", cloudToFQDNCount)
 	log.Printf("[RELATIONSHIP MAPPING]   • Live Web Server -> FQDN: %d", liveWebServerToFQDNCount)
 	log.Printf("[RELATIONSHIP MAPPING]   • Live Web Server -> IP Address: %d", liveWebServerToIPCount)
+	log.Printf("[RELATIONSHIP MAPPING]   • Service -> IP Address: %d", serviceToIPCount)
 	log.Printf("[RELATIONSHIP MAPPING]   • Live Web Server -> Cloud Asset: %d", liveWebServerToCloudCount)
 	log.Printf("[RELATIONSHIP MAPPING]   • Total Relationships: %d", totalRelationships)
 
@@ -3180,6 +3361,7 @@ func fetchConsolidatedAssets(scopeTargetID string) ([]AttackSurfaceAsset, error)
 			COALESCE(title, '') as title, 
 			COALESCE(web_server, '') as web_server, 
 			COALESCE(technologies, ARRAY[]::text[]) as technologies, 
+			technology_details,
 			content_length,
 			response_time_ms, 
 			COALESCE(screenshot_path, '') as screenshot_path, 
@@ -3235,7 +3417,7 @@ func fetchConsolidatedAssets(scopeTargetID string) ([]AttackSurfaceAsset, error)
 	for rows.Next() {
 		var asset AttackSurfaceAsset
 		var technologies []string
-		var sslInfo, httpHeaders, findings []byte
+		var sslInfo, httpHeaders, findings, technologyDetails []byte
 		var whoisInfo, sslCertificate, soaRecord []byte
 
 		// Variables for nullable fields
@@ -3251,7 +3433,7 @@ func fetchConsolidatedAssets(scopeTargetID string) ([]AttackSurfaceAsset, error)
 			&asset.ID, &asset.ScopeTargetID, &asset.AssetType, &asset.AssetIdentifier, &assetSubtype,
 			&asnNumber, &asnOrganization, &asnDescription, &asnCountry,
 			&cidrBlock, &ipAddress, &ipType, &url, &domain, &asset.Port, &asset.Protocol,
-			&asset.StatusCode, &title, &webServer, &technologies, &asset.ContentLength,
+			&asset.StatusCode, &title, &webServer, &technologies, &technologyDetails, &asset.ContentLength,
 			&asset.ResponseTime, &screenshotPath, &sslInfo, &httpHeaders,
 			&findings, &cloudProvider, &cloudServiceType,
 			&cloudRegion, &fqdn, &rootDomain, &subdomain, &registrar, &asset.CreationDate,
@@ -3352,6 +3534,9 @@ func fetchConsolidatedAssets(scopeTargetID string) ([]AttackSurfaceAsset, error)
 
 		// Assign arrays
 		asset.Technologies = technologies
+		if technologyDetails != nil {
+			json.Unmarshal(technologyDetails, &asset.TechnologyDetails)
+		}
 		asset.NameServers = nameServers
 		asset.Status = status
 		asset.SSLProtocols = sslProtocols
@@ -3432,6 +3617,56 @@ func fetchAssetRelationships(assetID string) ([]AssetRelationship, error) {
 	return relationships, nil
 }
 
+func fetchAssetDNSRecords(assetID string) ([]AttackSurfaceDNSRecord, error) {
+	query := `
+		SELECT id, asset_id, record_type, record_value, ttl, created_at
+		FROM consolidated_attack_surface_dns_records
+		WHERE asset_id = $1::uuid
+		ORDER BY record_type, record_value
+	`
+
+	rows, err := dbPool.Query(context.Background(), query, assetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []AttackSurfaceDNSRecord
+	for rows.Next() {
+		var record AttackSurfaceDNSRecord
+		if err := rows.Scan(&record.ID, &record.AssetID, &record.RecordType, &record.RecordValue, &record.TTL, &record.CreatedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// storeAssetDNSRecords replaces the DNS records of an asset, keeping the
+// TTL each record was served with.
+func storeAssetDNSRecords(assetID string, records []DNSResourceRecord) error {
+	if _, err := dbPool.Exec(context.Background(),
+		`DELETE FROM consolidated_attack_surface_dns_records WHERE asset_id = $1::uuid`, assetID); err != nil {
+		return err
+	}
+	for _, rr := range records {
+		value := rr.Value
+		if rr.Type == "SRV" || rr.Type == "PTR" {
+			value = rr.Name + " " + rr.Value
+		}
+		_, err := dbPool.Exec(context.Background(), `
+			INSERT INTO consolidated_attack_surface_dns_records (asset_id, record_type, record_value, ttl)
+			VALUES ($1::uuid, $2, $3, $4)
+			ON CONFLICT (asset_id, record_type, record_value) DO UPDATE SET ttl = EXCLUDED.ttl`,
+			assetID, rr.Type, value, int(rr.TTL))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func GetAttackSurfaceAssets(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	scopeTargetID := vars["scope_target_id"]
@@ -3454,6 +3689,13 @@ func GetAttackSurfaceAssets(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 		assets[i].Relationships = relationships
+
+		dnsRecords, err := fetchAssetDNSRecords(assets[i].ID)
+		if err != nil {
+			log.Printf("Error fetching DNS records for asset %s: %v", assets[i].ID, err)
+			continue
+		}
+		assets[i].DNSRecords = dnsRecords
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -3603,6 +3845,9 @@ func enrichFQDNsWithInvestigateData(scopeTargetID string) (int, error) {
 		var aRecords, aaaaRecords, cnameRecords, mxRecords, txtRecords, nsRecords []string
 		var spfRecord, dmarcRecord, dkimRecord string
 		var mailServers, nameServers []string
+		var caaRecords, srvRecords, ptrRecords []string
+		var soaRecordJSON []byte
+		var dnsRecords []DNSResourceRecord
 
 		if dnsRecordData != nil {
 			if records, ok := dnsRecordData["a_records"].([]string); ok {
@@ -3638,6 +3883,21 @@ func enrichFQDNsWithInvestigateData(scopeTargetID string) (int, error) {
 			if servers, ok := dnsRecordData["name_servers"].([]string); ok {
 				nameServers = servers
 			}
+			if records, ok := dnsRecordData["caa_records"].([]string); ok {
+				caaRecords = records
+			}
+			if records, ok := dnsRecordData["srv_records"].([]string); ok {
+				srvRecords = records
+			}
+			if records, ok := dnsRecordData["ptr_records"].([]string); ok {
+				ptrRecords = records
+			}
+			if soa, ok := dnsRecordData["soa_record"].(map[string]interface{}); ok {
+				soaRecordJSON, _ = json.Marshal(soa)
+			}
+			if records, ok := dnsRecordData["records"].([]DNSResourceRecord); ok {
+				dnsRecords = records
+			}
 		}
 
 		// Update the database record with enriched data
@@ -3670,6 +3930,10 @@ func enrichFQDNsWithInvestigateData(scopeTargetID string) (int, error) {
 				name_servers = $25,
 				last_ssl_scan = $26,
 				last_dns_scan = $27,
+				caa_records = $28,
+				srv_records = $29,
+				ptr_records = $30,
+				soa_record = $31,
 				last_updated = NOW()
 			WHERE id = $1
 		`
@@ -3719,6 +3983,10 @@ func enrichFQDNsWithInvestigateData(scopeTargetID string) (int, error) {
 			nameServersArray,
 			lastSSLScan,
 			lastDNSScan,
+			caaRecords,
+			srvRecords,
+			ptrRecords,
+			soaRecordJSON,
 		)
 
 		if err != nil {
@@ -3726,6 +3994,10 @@ func enrichFQDNsWithInvestigateData(scopeTargetID string) (int, error) {
 			continue
 		}
 
+		if err := storeAssetDNSRecords(fqdn.ID, dnsRecords); err != nil {
+			log.Printf("[FQDN ENRICHMENT] Error storing DNS records for %s: %v", fqdn.FQDN, err)
+		}
+
 		enrichedCount++
 		log.Printf("[FQDN ENRICHMENT] ✓ Successfully enriched domain %d/%d: %s", i+1, len(fqdnsToEnrich), fqdn.FQDN)
 	}
@@ -3787,13 +4059,12 @@ func getASNInfoFast(domain string) (string, string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	resolver := &net.Resolver{}
-	ips, err := resolver.LookupIPAddr(ctx, domain)
-	if err != nil || len(ips) == 0 {
+	ips := defaultDNSPool().LookupIPs(ctx, domain)
+	if len(ips) == 0 {
 		return "", ""
 	}
 
-	ip := ips[0].IP.String()
+	ip := ips[0]
 
 	// 2 second timeout for HTTP client
 	client := &http.Client{Timeout: 2 * time.Second}
@@ -3868,51 +4139,47 @@ func getHTTPInfoFast(domain string) (int, string, string) {
 }
 
 func getDNSInfoFast(domain string) ([]string, map[string]interface{}) {
-	// 2 second timeout for DNS lookups
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	// 5 second timeout for the whole DNS profile
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	resolver := &net.Resolver{}
-	var resolvedIPs []string
+	records := defaultDNSPool().Profile(ctx, domain)
 	dnsInfo := make(map[string]interface{})
+	if len(records) == 0 {
+		return nil, dnsInfo
+	}
+	dnsInfo["records"] = records
 
 	// A Records (IPv4) and AAAA Records (IPv6)
-	if ips, err := resolver.LookupIPAddr(ctx, domain); err == nil {
-		var aRecords, aaaaRecords []string
-		for _, ip := range ips {
-			ipStr := ip.IP.String()
-			resolvedIPs = append(resolvedIPs, ipStr)
-			if ip.IP.To4() != nil {
-				aRecords = append(aRecords, ipStr)
-			} else {
-				aaaaRecords = append(aaaaRecords, ipStr)
-			}
-		}
-		if len(aRecords) > 0 {
-			dnsInfo["a_records"] = aRecords
-		}
-		if len(aaaaRecords) > 0 {
-			dnsInfo["aaaa_records"] = aaaaRecords
-		}
+	aRecords := dnsRecordValues(records, "A")
+	aaaaRecords := dnsRecordValues(records, "AAAA")
+	resolvedIPs := append(append([]string{}, aRecords...), aaaaRecords...)
+	if len(aRecords) > 0 {
+		dnsInfo["a_records"] = aRecords
+	}
+	if len(aaaaRecords) > 0 {
+		dnsInfo["aaaa_records"] = aaaaRecords
 	}
 
 	// CNAME Records
-	if cname, err := resolver.LookupCNAME(ctx, domain); err == nil && cname != domain+"." {
-		dnsInfo["cname_records"] = []string{strings.TrimSuffix(cname, ".")}
+	if cnames := dnsRecordValues(records, "CNAME"); len(cnames) > 0 {
+		dnsInfo["cname_records"] = cnames
 	}
 
 	// MX Records
-	if mxRecords, err := resolver.LookupMX(ctx, domain); err == nil && len(mxRecords) > 0 {
+	if mxRecords := dnsRecordValues(records, "MX"); len(mxRecords) > 0 {
 		var mxHosts []string
 		for _, mx := range mxRecords {
-			mxHosts = append(mxHosts, strings.TrimSuffix(mx.Host, "."))
+			if fields := strings.Fields(mx); len(fields) == 2 {
+				mxHosts = append(mxHosts, fields[1])
+			}
 		}
 		dnsInfo["mx_records"] = mxHosts
 		dnsInfo["mail_servers"] = mxHosts // Also populate mail_servers field
 	}
 
 	// TXT Records
-	if txtRecords, err := resolver.LookupTXT(ctx, domain); err == nil && len(txtRecords) > 0 {
+	if txtRecords := dnsRecordValues(records, "TXT"); len(txtRecords) > 0 {
 		dnsInfo["txt_records"] = txtRecords
 
 		// Extract specific TXT record types
@@ -3922,21 +4189,55 @@ func getDNSInfoFast(domain string) ([]string, map[string]interface{}) {
 				dnsInfo["spf_record"] = txt
 			} else if strings.HasPrefix(lower, "v=dmarc1") {
 				dnsInfo["dmarc_record"] = txt
-			} else if strings.Contains(lower, "dkim") {
+			} else if strings.HasPrefix(lower, "v=dkim1") {
 				dnsInfo["dkim_record"] = txt
 			}
 		}
 	}
 
 	// NS Records
-	if nsRecords, err := resolver.LookupNS(ctx, domain); err == nil && len(nsRecords) > 0 {
-		var nsHosts []string
-		for _, ns := range nsRecords {
-			nsHosts = append(nsHosts, strings.TrimSuffix(ns.Host, "."))
-		}
+	if nsHosts := dnsRecordValues(records, "NS"); len(nsHosts) > 0 {
 		dnsInfo["ns_records"] = nsHosts
 		dnsInfo["name_servers"] = nsHosts // Also populate name_servers field
 	}
 
+	// CAA Records
+	if caaRecords := dnsRecordValues(records, "CAA"); len(caaRecords) > 0 {
+		dnsInfo["caa_records"] = caaRecords
+	}
+
+	// SRV and PTR Records, prefixed with the name they were found under
+	var srvRecords, ptrRecords []string
+	for _, rr := range records {
+		switch rr.Type {
+		case "SRV":
+			srvRecords = append(srvRecords, rr.Name+" "+rr.Value)
+		case "PTR":
+			ptrRecords = append(ptrRecords, rr.Value)
+		}
+	}
+	if len(srvRecords) > 0 {
+		dnsInfo["srv_records"] = srvRecords
+	}
+	if len(ptrRecords) > 0 {
+		dnsInfo["ptr_records"] = ptrRecords
+	}
+
+	// SOA Record (only present at zone apexes)
+	for _, rr := range records {
+		if rr.Type != "SOA" {
+			continue
+		}
+		if fields := strings.Fields(rr.Value); len(fields) == 7 {
+			soa := map[string]interface{}{"mname": fields[0], "rname": fields[1], "ttl": rr.TTL}
+			for i, key := range []string{"serial", "refresh", "retry", "expire", "minimum"} {
+				n, _ := strconv.ParseUint(fields[i+2], 10, 32)
+				soa[key] = n
+			}
+			dnsInfo["soa_record"] = soa
+		}
+		break
+	}
+
 	return resolvedIPs, dnsInfo
 }
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCronSchedule(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{expr: "* * * * *"},
+		{expr: "  0 3 * * 1-5  "},
+		{expr: "*/15 0,12 1-7 JAN-mar sun"},
+		{expr: "5/20 * ? * ?"},
+		{expr: "@Daily"},
+		{expr: "0 0 * * 7"},
+		{expr: "", wantErr: "expected 5 fields, got 0"},
+		{expr: "* * * *", wantErr: "expected 5 fields, got 4"},
+		{expr: "* * * * * *", wantErr: "expected 5 fields, got 6"},
+		{expr: "@every 5m", wantErr: "expected 5 fields"},
+		{expr: "60 * * * *", wantErr: "minute: value 60 out of range 0-59"},
+		{expr: "* 24 * * *", wantErr: "hour: value 24 out of range"},
+		{expr: "* * 0 * *", wantErr: "day of month: value 0 out of range"},
+		{expr: "* * * 13 *", wantErr: "month: value 13 out of range"},
+		{expr: "* * * * 8", wantErr: "day of week: value 8 out of range"},
+		{expr: "-1 * * * *", wantErr: "minute: invalid value \"\""},
+		{expr: "1,,2 * * * *", wantErr: "empty list item"},
+		{expr: "1, * * * *", wantErr: "empty list item"},
+		{expr: "*/0 * * * *", wantErr: "invalid step"},
+		{expr: "*/ * * * *", wantErr: "invalid step"},
+		{expr: "*/-5 * * * *", wantErr: "invalid step"},
+		{expr: "30-10 * * * *", wantErr: "range 30-10 is backwards"},
+		{expr: "1-2-3 * * * *", wantErr: "invalid value \"2-3\""},
+		{expr: "* * * foo *", wantErr: "month: invalid value \"foo\""},
+		{expr: "* * * * mon-", wantErr: "day of week: invalid value \"\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseCronSchedule(tt.expr)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ParseCronSchedule(%q) error = %v", tt.expr, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ParseCronSchedule(%q) error = %v, want %q", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	utc := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	// 2026-10-18 is a Sunday.
+	from := time.Date(2026, 10, 18, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{expr: "* * * * *", from: from, want: utc(2026, 10, 18, 10, 8)},
+		{expr: "*/15 * * * *", from: from, want: utc(2026, 10, 18, 10, 15)},
+		{expr: "5/20 * * * *", from: from, want: utc(2026, 10, 18, 10, 25)},
+		{expr: "0 3 * * *", from: from, want: utc(2026, 10, 19, 3, 0)},
+		{expr: "@monthly", from: from, want: utc(2026, 11, 1, 0, 0)},
+		{expr: "@yearly", from: from, want: utc(2027, 1, 1, 0, 0)},
+		{expr: "0 9 * * mon-fri", from: from, want: utc(2026, 10, 19, 9, 0)},
+		{expr: "0 9 * * 7", from: from, want: utc(2026, 10, 25, 9, 0)},
+		// A restricted day of month and day of week match if either does.
+		{expr: "0 0 1 * fri", from: from, want: utc(2026, 10, 23, 0, 0)},
+		{expr: "0 0 31 * *", from: from, want: utc(2026, 10, 31, 0, 0)},
+		{expr: "0 0 29 2 *", from: from, want: utc(2028, 2, 29, 0, 0)},
+		{expr: "0 0 30 2 *", from: from, want: time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := ParseCronSchedule(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCronSchedule(%q) error = %v", tt.expr, err)
+			}
+			if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviewScanScheduleRuns(t *testing.T) {
+	now := time.Date(2026, 3, 29, 0, 30, 0, 0, time.UTC)
+	runs, err := previewScanScheduleRuns("0 9 * * *", "America/New_York", now, 2)
+	if err != nil {
+		t.Fatalf("previewScanScheduleRuns() error = %v", err)
+	}
+	want := []time.Time{
+		time.Date(2026, 3, 29, 13, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 30, 13, 0, 0, 0, time.UTC),
+	}
+	if len(runs) != len(want) || !runs[0].Equal(want[0]) || !runs[1].Equal(want[1]) {
+		t.Errorf("previewScanScheduleRuns() = %v, want %v", runs, want)
+	}
+
+	for _, tt := range []struct{ expr, timezone, wantErr string }{
+		{expr: "* * *", timezone: "UTC", wantErr: "invalid cron expression"},
+		{expr: "* * * * *", timezone: "Mars/Olympus_Mons", wantErr: "invalid timezone"},
+		{expr: "0 0 31 4 *", timezone: "UTC", wantErr: "never fires"},
+	} {
+		if _, err := previewScanScheduleRuns(tt.expr, tt.timezone, now, 1); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("previewScanScheduleRuns(%q, %q) error = %v, want %q", tt.expr, tt.timezone, err, tt.wantErr)
+		}
+	}
+}
@@ -153,12 +153,13 @@ var exportTableQueries = map[string]string{
 		       has_self_signed_ssl, has_untrusted_root_ssl, has_wildcard_tls, findings_json,
 		       http_response, http_response_headers, dns_a_records, dns_aaaa_records,
 		       dns_cname_records, dns_mx_records, dns_txt_records, dns_ns_records,
-		       dns_ptr_records, dns_srv_records, katana_results, ffuf_results, roi_score, ip_address
+		       dns_ptr_records, dns_srv_records, katana_results, ffuf_results, roi_score, ip_address,
+		       http_probe, favicon_mmh3, favicon_sha256, favicon_url, technology_details
 		FROM target_urls 
 		WHERE scope_target_id = ANY($1)`,
 
 	"consolidated_subdomains": `
-		SELECT id, scope_target_id, subdomain, created_at
+		SELECT id, scope_target_id, subdomain, wildcard_suspect, created_at
 		FROM consolidated_subdomains 
 		WHERE scope_target_id = ANY($1)`,
 
@@ -187,9 +188,9 @@ var exportTableQueries = map[string]string{
 		       asn_organization, asn_description, asn_country, cidr_block, subnet_size,
 		       responsive_ip_count, responsive_port_count, ip_address, ip_type, dnsx_a_records,
 		       amass_a_records, httpx_sources, url, domain, port, protocol, status_code, title,
-		       web_server, technologies, content_length, response_time_ms, screenshot_path,
+		       web_server, technologies, technology_details, content_length, response_time_ms, screenshot_path,
 		       ssl_info, http_response_headers, findings_json, cloud_provider, cloud_service_type,
-		       cloud_region, fqdn, root_domain, subdomain, registrar, creation_date, expiration_date,
+		       cloud_region, cloud_tag_source, fqdn, root_domain, subdomain, registrar, creation_date, expiration_date,
 		       updated_date, name_servers, status, whois_info, ssl_certificate, ssl_expiry_date,
 		       ssl_issuer, ssl_subject, ssl_version, ssl_cipher_suite, ssl_protocols, resolved_ips,
 		       mail_servers, spf_record, dkim_record, dmarc_record, caa_records, txt_records,
@@ -291,11 +292,87 @@ var exportTableQueries = map[string]string{
 		SELECT lws.id, lws.scan_id, lws.ip_address, lws.hostname, lws.port, lws.protocol,
 		       lws.url, lws.status_code, lws.title, lws.server_header, lws.content_length,
 		       lws.technologies, lws.response_time_ms, lws.screenshot_path, lws.ssl_info,
-		       lws.http_response_headers, lws.findings_json, lws.last_checked
+		       lws.http_response_headers, lws.findings_json, lws.http_probe,
+		       lws.favicon_mmh3, lws.favicon_sha256, lws.favicon_url, lws.technology_details, lws.last_checked
 		FROM live_web_servers lws
 		JOIN ip_port_scans ips ON lws.scan_id = ips.scan_id
 		WHERE ips.scope_target_id = ANY($1)`,
 
+	"discovered_services": `
+		SELECT ds.id, ds.scan_id, ds.ip_address, ds.port, ds.protocol, ds.service,
+		       ds.product, ds.version, ds.banner, ds.tls, ds.discovered_at
+		FROM discovered_services ds
+		JOIN ip_port_scans ips ON ds.scan_id = ips.scan_id
+		WHERE ips.scope_target_id = ANY($1)`,
+
+	"tls_fingerprints": `
+		SELECT id, scan_id, scope_target_id, source, url, host, ip_address, port, jarm, ja4s, ja4x,
+		       tls_version, cipher_suite, alpn, cert_subject, cert_issuer, created_at
+		FROM tls_fingerprints 
+		WHERE scope_target_id = ANY($1)`,
+
+	"wildcard_dns_zones": `
+		SELECT id, scope_target_id, zone, is_wildcard, answers, http_signature, suspects, checked_at
+		FROM wildcard_dns_zones
+		WHERE scope_target_id = ANY($1)`,
+
+	"subdomain_takeover_findings": `
+		SELECT id, scope_target_id, asset_id, fqdn, service, status, method, cname_chain, evidence,
+		       http_status, reference, detected_at
+		FROM subdomain_takeover_findings
+		WHERE scope_target_id = ANY($1)`,
+
+	"ptr_sweep_records": `
+		SELECT id, scope_target_id, scan_id, cidr_block, ip_address, hostname, generic, first_seen, last_seen
+		FROM ptr_sweep_records
+		WHERE scope_target_id = ANY($1)`,
+
+	"ptr_sweep_cursors": `
+		SELECT id, scope_target_id, cidr_block, scan_id, total_addresses, next_offset, status, updated_at, completed_at
+		FROM ptr_sweep_cursors
+		WHERE scope_target_id = ANY($1)`,
+
+	"vhost_bindings": `
+		SELECT id, scope_target_id, scan_id, ip_address, port, scheme, hostname, source, url, status_code,
+		       content_length, body_sha256, title, baseline_status_code, baseline_content_length, first_seen, last_seen
+		FROM vhost_bindings
+		WHERE scope_target_id = ANY($1)`,
+
+	"mail_posture_results": `
+		SELECT id, scope_target_id, asset_id, domain, grade, score, spf_record, dmarc_record, dmarc_policy,
+		       report, checked_at
+		FROM mail_posture_results
+		WHERE scope_target_id = ANY($1)`,
+
+	"vendor_inventory": `
+		SELECT id, scope_target_id, vendor, category, website, fqdn_count, first_seen, last_seen
+		FROM vendor_inventory
+		WHERE scope_target_id = ANY($1)`,
+
+	"vendor_evidence": `
+		SELECT id, scope_target_id, vendor, fqdn, evidence_type, evidence_value, first_seen, last_seen
+		FROM vendor_evidence
+		WHERE scope_target_id = ANY($1)`,
+
+	"nameserver_posture_results": `
+		SELECT id, scope_target_id, asset_id, domain, axfr_open, open_recursion, dnssec_status,
+		       delegation_consistent, issue_count, report, checked_at
+		FROM nameserver_posture_results
+		WHERE scope_target_id = ANY($1)`,
+
+	"nameserver_axfr_records": `
+		SELECT id, scope_target_id, domain, nameserver, name, record_type, record_value, ttl, first_seen, last_seen
+		FROM nameserver_axfr_records
+		WHERE scope_target_id = ANY($1)`,
+
+	"tls_posture_results": `
+		SELECT id, scan_id, scope_target_id, source, url, host, ip_address, port, ssl_version, ssl_cipher_suite,
+		       ssl_protocols, weak_ciphers, cert_subject, cert_issuer, cert_not_after, chain_valid,
+		       has_deprecated_tls, has_expired_ssl, has_mismatched_ssl, has_revoked_ssl, has_self_signed_ssl,
+		       has_untrusted_root_ssl, has_wildcard_tls, posture, created_at
+		FROM tls_posture_results
+		WHERE scope_target_id = ANY($1)`,
+
 	"metabigor_network_ranges": `
 		SELECT mnr.id, mnr.scan_id, mnr.cidr_block, mnr.asn, mnr.organization, 
 		       mnr.country, mnr.scan_type, mnr.created_at
@@ -390,6 +467,19 @@ var exportTableQueries = map[string]string{
 		FROM katana_company_configs 
 		WHERE scope_target_id = ANY($1)`,
 
+	"wildcard_dns_configs": `
+		SELECT id, scope_target_id, include_wildcard_suspects, created_at, updated_at
+		FROM wildcard_dns_configs 
+		WHERE scope_target_id = ANY($1)`,
+
+	"ip_port_scan_configs": `
+		SELECT id, scope_target_id, port_profile, custom_ports, skip_host_discovery, max_ips_per_range,
+		       max_concurrent_ips, max_concurrent_ports, max_concurrent_connections,
+		       host_probe_timeout_ms, port_scan_timeout_ms, web_service_timeout_ms,
+		       created_at, updated_at
+		FROM ip_port_scan_configs 
+		WHERE scope_target_id = ANY($1)`,
+
 	"cloud_enum_configs": `
 		SELECT id, scope_target_id, keywords, threads, enabled_platforms, custom_dns_server,
 		       dns_resolver_mode, resolver_config, additional_resolvers, mutations_file_path,
@@ -750,7 +840,7 @@ func exportDatabaseData(scopeTargetIDs []string) (*ExportData, error) {
 	var tablesExported []string
 
 	// First pass: export all regular tables
-	for tableName, query := range exportTableQueries {
+	for tableName, query := range databaseExportQueries() {
 		log.Printf("[INFO] Exporting data from table: %s", tableName)
 
 		rows, err := dbPool.Query(context.Background(), query, scopeTargetIDs)
@@ -1133,7 +1223,8 @@ func importTableData(tx pgx.Tx, tableData map[string][]map[string]interface{}) e
 		"investigate_scans",
 
 		// IP/Port scanning
-		"ip_port_scans",
+		"ip_port_scans", "tls_fingerprint_scans", "tls_posture_scans", "ptr_sweep_scans",
+		"vhost_discovery_scans",
 
 		// Child tables of scan tables (must come after parent scans)
 		"dns_records", "ips", "subdomains", "cloud_domains", "asns", "subnets", "service_providers",
@@ -1141,7 +1232,8 @@ func importTableData(tx pgx.Tx, tableData map[string][]map[string]interface{}) e
 		"metabigor_network_ranges",
 		"amass_enum_cloud_domains", "amass_enum_dns_records", "amass_enum_raw_results",
 		"dnsx_dns_records", "dnsx_raw_results",
-		"discovered_live_ips", "live_web_servers",
+		"discovered_live_ips", "live_web_servers", "discovered_services",
+		"tls_fingerprints", "tls_posture_results",
 
 		// Domain-centric result tables
 		"dnsx_company_domain_results", "amass_enum_company_domain_results",
@@ -1153,18 +1245,21 @@ func importTableData(tx pgx.Tx, tableData map[string][]map[string]interface{}) e
 		// Target URLs and consolidated data
 		"target_urls",
 		"consolidated_subdomains", "consolidated_company_domains", "consolidated_network_ranges",
-		"google_dorking_domains", "reverse_whois_domains",
+		"wildcard_dns_zones", "subdomain_takeover_findings", "google_dorking_domains", "reverse_whois_domains",
+		"ptr_sweep_cursors", "ptr_sweep_records", "vhost_bindings", "vendor_inventory", "vendor_evidence",
+		"nameserver_axfr_records",
 
 		// Attack surface assets (parent)
 		"consolidated_attack_surface_assets",
 
 		// Attack surface child tables
 		"consolidated_attack_surface_relationships", "consolidated_attack_surface_dns_records",
-		"consolidated_attack_surface_metadata",
+		"consolidated_attack_surface_metadata", "mail_posture_results", "nameserver_posture_results",
 
 		// Configuration tables (can be imported any time after scope_targets)
 		"amass_enum_configs", "amass_intel_configs", "dnsx_configs",
-		"katana_company_configs", "cloud_enum_configs", "nuclei_configs",
+		"katana_company_configs", "cloud_enum_configs", "nuclei_configs", "ip_port_scan_configs",
+		"wildcard_dns_configs",
 	}
 
 	for _, tableName := range tableOrder {
@@ -0,0 +1,898 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The DNS engine speaks the wire protocol (RFC 1035) directly so every
+// record type can be queried, against a resolver pool configured with
+// RECON_DNS_RESOLVERS (comma separated, default: /etc/resolv.conf),
+// RECON_DNS_QPS (per resolver, default 50), RECON_DNS_RETRIES (default 2)
+// and RECON_DNS_TIMEOUT_MS (default 2000).
+
+const (
+	dnsTypeA      uint16 = 1
+	dnsTypeNS     uint16 = 2
+	dnsTypeCNAME  uint16 = 5
+	dnsTypeSOA    uint16 = 6
+	dnsTypePTR    uint16 = 12
+	dnsTypeMX     uint16 = 15
+	dnsTypeTXT    uint16 = 16
+	dnsTypeAAAA   uint16 = 28
+	dnsTypeSRV    uint16 = 33
+	dnsTypeNAPTR  uint16 = 35
+	dnsTypeOPT    uint16 = 41
+	dnsTypeDS     uint16 = 43
+	dnsTypeRRSIG  uint16 = 46
+	dnsTypeNSEC   uint16 = 47
+	dnsTypeDNSKEY uint16 = 48
+	dnsTypeNSEC3  uint16 = 50
+	dnsTypeSVCB   uint16 = 64
+	dnsTypeHTTPS  uint16 = 65
+	dnsTypeIXFR   uint16 = 251
+	dnsTypeAXFR   uint16 = 252
+	dnsTypeANY    uint16 = 255
+	dnsTypeCAA    uint16 = 257
+)
+
+var dnsTypeNames = map[uint16]string{
+	dnsTypeA: "A", dnsTypeNS: "NS", dnsTypeCNAME: "CNAME", dnsTypeSOA: "SOA", dnsTypePTR: "PTR",
+	dnsTypeMX: "MX", dnsTypeTXT: "TXT", dnsTypeAAAA: "AAAA", dnsTypeSRV: "SRV", dnsTypeNAPTR: "NAPTR",
+	dnsTypeOPT: "OPT", dnsTypeDS: "DS", dnsTypeRRSIG: "RRSIG", dnsTypeNSEC: "NSEC", dnsTypeDNSKEY: "DNSKEY",
+	dnsTypeNSEC3: "NSEC3", dnsTypeSVCB: "SVCB", dnsTypeHTTPS: "HTTPS", dnsTypeIXFR: "IXFR", dnsTypeAXFR: "AXFR",
+	dnsTypeANY: "ANY", dnsTypeCAA: "CAA",
+}
+
+const (
+	dnsRcodeSuccess  = 0
+	dnsRcodeFormErr  = 1
+	dnsRcodeServFail = 2
+	dnsRcodeNXDomain = 3
+	dnsRcodeNotImp   = 4
+	dnsRcodeRefused  = 5
+	dnsRcodeNotAuth  = 9
+)
+
+var dnsRcodeNames = map[int]string{
+	dnsRcodeSuccess: "NOERROR", dnsRcodeFormErr: "FORMERR", dnsRcodeServFail: "SERVFAIL",
+	dnsRcodeNXDomain: "NXDOMAIN", dnsRcodeNotImp: "NOTIMP", dnsRcodeRefused: "REFUSED",
+	dnsRcodeNotAuth: "NOTAUTH",
+}
+
+// dnsHostRecordTypes are resolved for every host profile.
+var dnsHostRecordTypes = []uint16{dnsTypeA, dnsTypeAAAA, dnsTypeCNAME, dnsTypeMX, dnsTypeTXT, dnsTypeNS, dnsTypeSOA, dnsTypeCAA}
+
+// dnsSRVServices are the SRV names probed under every host.
+var dnsSRVServices = []string{
+	"_http._tcp", "_https._tcp", "_ldap._tcp", "_kerberos._tcp",
+	"_autodiscover._tcp", "_sip._tls", "_sipfederationtls._tcp", "_xmpp-server._tcp",
+}
+
+func dnsTypeName(t uint16) string {
+	if name, ok := dnsTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", t)
+}
+
+func dnsRcodeName(rcode int) string {
+	if name, ok := dnsRcodeNames[rcode]; ok {
+		return name
+	}
+	return fmt.Sprintf("RCODE%d", rcode)
+}
+
+// DNSResourceRecord is one record in presentation form. Value holds the
+// RDATA as it would appear in a zone file, e.g. "10 mx.example.com" for MX.
+type DNSResourceRecord struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	TTL   uint32 `json:"ttl"`
+	Value string `json:"value"`
+}
+
+type DNSResponse struct {
+	Resolver      string              `json:"resolver"`
+	Rcode         int                 `json:"rcode"`
+	Authoritative bool                `json:"authoritative"`
+	Authenticated bool                `json:"authenticated"`
+	RecursionOK   bool                `json:"recursion_available"`
+	Answers       []DNSResourceRecord `json:"answers"`
+	Authority     []DNSResourceRecord `json:"authority"`
+	Additional    []DNSResourceRecord `json:"additional"`
+}
+
+// dnsQueryOptions controls the header bits of a query.
+type dnsQueryOptions struct {
+	Recursion bool // RD
+	DNSSEC    bool // EDNS0 DO
+}
+
+type dnsResolver struct {
+	address  string
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// wait blocks until the resolver's next query slot.
+func (r *dnsResolver) wait(ctx context.Context) error {
+	r.mu.Lock()
+	now := time.Now()
+	slot := r.next
+	if slot.Before(now) {
+		slot = now
+	}
+	r.next = slot.Add(r.interval)
+	r.mu.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DNSResolverPool spreads queries round-robin over a set of recursive
+// resolvers, rate-limiting each and retrying failures on the next one.
+type DNSResolverPool struct {
+	resolvers []*dnsResolver
+	retries   int
+	timeout   time.Duration
+	counter   atomic.Uint32
+}
+
+func NewDNSResolverPool(addresses []string, qps, retries int, timeout time.Duration) *DNSResolverPool {
+	pool := &DNSResolverPool{retries: retries, timeout: timeout}
+	interval := time.Duration(0)
+	if qps > 0 {
+		interval = time.Second / time.Duration(qps)
+	}
+	for _, address := range addresses {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			address = net.JoinHostPort(strings.Trim(address, "[]"), "53")
+		}
+		pool.resolvers = append(pool.resolvers, &dnsResolver{address: address, interval: interval})
+	}
+	return pool
+}
+
+var (
+	dnsPoolOnce    sync.Once
+	dnsDefaultPool *DNSResolverPool
+)
+
+// defaultDNSPool returns the process-wide resolver pool.
+func defaultDNSPool() *DNSResolverPool {
+	dnsPoolOnce.Do(func() {
+		addresses := systemNameservers()
+		if configured := strings.TrimSpace(os.Getenv("RECON_DNS_RESOLVERS")); configured != "" {
+			addresses = strings.Split(configured, ",")
+		}
+		dnsDefaultPool = NewDNSResolverPool(addresses, envInt("RECON_DNS_QPS", 50), envInt("RECON_DNS_RETRIES", 2),
+			time.Duration(envInt("RECON_DNS_TIMEOUT_MS", 2000))*time.Millisecond)
+	})
+	return dnsDefaultPool
+}
+
+// systemNameservers reads the nameservers of /etc/resolv.conf, falling
+// back to public resolvers.
+func systemNameservers() []string {
+	var servers []string
+	if f, err := os.Open("/etc/resolv.conf"); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "nameserver" {
+				servers = append(servers, fields[1])
+			}
+		}
+		f.Close()
+	}
+	if len(servers) == 0 {
+		servers = []string{"8.8.8.8", "1.1.1.1"}
+	}
+	return servers
+}
+
+// Query resolves name/qtype through the pool. SERVFAIL, REFUSED and
+// transport errors are retried on the next resolver.
+func (p *DNSResolverPool) Query(ctx context.Context, name string, qtype uint16) (*DNSResponse, error) {
+	return p.query(ctx, name, qtype, dnsQueryOptions{Recursion: true})
+}
+
+// QueryDNSSEC is Query with the DO bit set, so RRSIGs are returned and the
+// AD flag reflects the resolver's validation.
+func (p *DNSResolverPool) QueryDNSSEC(ctx context.Context, name string, qtype uint16) (*DNSResponse, error) {
+	return p.query(ctx, name, qtype, dnsQueryOptions{Recursion: true, DNSSEC: true})
+}
+
+func (p *DNSResolverPool) query(ctx context.Context, name string, qtype uint16, opts dnsQueryOptions) (*DNSResponse, error) {
+	if len(p.resolvers) == 0 {
+		return nil, errors.New("no DNS resolvers configured")
+	}
+	start := int(p.counter.Add(1))
+	var lastErr error
+	for attempt := 0; attempt <= p.retries; attempt++ {
+		r := p.resolvers[(start+attempt)%len(p.resolvers)]
+		if err := r.wait(ctx); err != nil {
+			return nil, err
+		}
+		resp, err := queryDNSServer(ctx, r.address, name, qtype, opts, p.timeout)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.Rcode == dnsRcodeServFail || resp.Rcode == dnsRcodeRefused {
+			lastErr = fmt.Errorf("%s answered %s for %s %s", r.address, dnsRcodeName(resp.Rcode), name, dnsTypeName(qtype))
+			continue
+		}
+		return resp, nil
+	}
+	return nil, lastErr
+}
+
+// Lookup returns the answer records of type qtype. NXDOMAIN and empty
+// answers are not errors.
+func (p *DNSResolverPool) Lookup(ctx context.Context, name string, qtype uint16) ([]DNSResourceRecord, error) {
+	resp, err := p.Query(ctx, name, qtype)
+	if err != nil {
+		return nil, err
+	}
+	var records []DNSResourceRecord
+	for _, rr := range resp.Answers {
+		if rr.Type == dnsTypeName(qtype) {
+			records = append(records, rr)
+		}
+	}
+	return records, nil
+}
+
+// LookupIPs returns the A and AAAA addresses of host.
+func (p *DNSResolverPool) LookupIPs(ctx context.Context, host string) []string {
+	if ip := net.ParseIP(host); ip != nil {
+		return []string{ip.String()}
+	}
+	var ips []string
+	for _, qtype := range []uint16{dnsTypeA, dnsTypeAAAA} {
+		records, _ := p.Lookup(ctx, host, qtype)
+		for _, rr := range records {
+			ips = append(ips, rr.Value)
+		}
+	}
+	return ips
+}
+
+// LookupPTR returns the reverse names of an IP address.
+func (p *DNSResolverPool) LookupPTR(ctx context.Context, ip string) ([]string, error) {
+	name, err := reverseDNSName(ip)
+	if err != nil {
+		return nil, err
+	}
+	records, err := p.Lookup(ctx, name, dnsTypePTR)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, rr := range records {
+		names = append(names, rr.Value)
+	}
+	return names, nil
+}
+
+// Profile resolves everything the attack surface tracks for a host: the
+// dnsHostRecordTypes, SRV records of dnsSRVServices and PTR records of
+// every address found. PTR records are named after the reverse name.
+func (p *DNSResolverPool) Profile(ctx context.Context, host string) []DNSResourceRecord {
+	names := make([]string, 0, len(dnsHostRecordTypes)+len(dnsSRVServices))
+	types := make([]uint16, 0, cap(names))
+	for _, t := range dnsHostRecordTypes {
+		names, types = append(names, host), append(types, t)
+	}
+	for _, service := range dnsSRVServices {
+		names, types = append(names, service+"."+host), append(types, dnsTypeSRV)
+	}
+
+	results := make([][]DNSResourceRecord, len(names))
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = p.Lookup(ctx, names[i], types[i])
+		}(i)
+	}
+	wg.Wait()
+
+	var records []DNSResourceRecord
+	var ips []string
+	for _, set := range results {
+		for _, rr := range set {
+			records = append(records, rr)
+			if rr.Type == "A" || rr.Type == "AAAA" {
+				ips = append(ips, rr.Value)
+			}
+		}
+	}
+
+	ptrs := make([][]DNSResourceRecord, len(ips))
+	for i, ip := range ips {
+		wg.Add(1)
+		go func(i int, ip string) {
+			defer wg.Done()
+			if name, err := reverseDNSName(ip); err == nil {
+				ptrs[i], _ = p.Lookup(ctx, name, dnsTypePTR)
+			}
+		}(i, ip)
+	}
+	wg.Wait()
+	for _, set := range ptrs {
+		records = append(records, set...)
+	}
+	return records
+}
+
+// dnsRecordValues returns the values of the records of one type.
+func dnsRecordValues(records []DNSResourceRecord, recordType string) []string {
+	var values []string
+	for _, rr := range records {
+		if rr.Type == recordType {
+			values = append(values, rr.Value)
+		}
+	}
+	return values
+}
+
+// reverseDNSName returns the in-addr.arpa or ip6.arpa name of an IP.
+func reverseDNSName(ip string) (string, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", fmt.Errorf("invalid IP address %q", ip)
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa", v4[3], v4[2], v4[1], v4[0]), nil
+	}
+	const digits = "0123456789abcdef"
+	var sb strings.Builder
+	for i := len(parsed) - 1; i >= 0; i-- {
+		sb.WriteByte(digits[parsed[i]&0x0f])
+		sb.WriteByte('.')
+		sb.WriteByte(digits[parsed[i]>>4])
+		sb.WriteByte('.')
+	}
+	sb.WriteString("ip6.arpa")
+	return sb.String(), nil
+}
+
+// queryDNSServer sends one query to server over UDP, retrying over TCP if
+// the answer is truncated.
+func queryDNSServer(ctx context.Context, server, name string, qtype uint16, opts dnsQueryOptions, timeout time.Duration) (*DNSResponse, error) {
+	id := uint16(rand.Intn(1 << 16))
+	query, err := buildDNSQuery(id, name, qtype, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := exchangeDNSUDP(ctx, server, id, query, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if reply[2]&0x02 != 0 { // TC
+		if reply, err = exchangeDNSTCP(ctx, server, id, query, timeout); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := parseDNSMessage(reply)
+	if err != nil {
+		return nil, err
+	}
+	resp.Resolver = server
+	return resp, nil
+}
+
+func exchangeDNSUDP(ctx context.Context, server string, id uint16, query []byte, timeout time.Duration) ([]byte, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		// Ignore stray or spoofed datagrams that don't answer this query
+		if n >= 12 && binary.BigEndian.Uint16(buf) == id && dnsReplyMatches(query, buf[:n]) {
+			return append([]byte(nil), buf[:n]...), nil
+		}
+	}
+}
+
+func exchangeDNSTCP(ctx context.Context, server string, id uint16, query []byte, timeout time.Duration) ([]byte, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if err := writeDNSTCP(conn, query); err != nil {
+		return nil, err
+	}
+	reply, err := readDNSTCP(conn)
+	if err != nil {
+		return nil, err
+	}
+	if binary.BigEndian.Uint16(reply) != id {
+		return nil, errors.New("DNS reply ID mismatch")
+	}
+	if !dnsReplyMatches(query, reply) {
+		return nil, errors.New("DNS reply does not answer the query")
+	}
+	return reply, nil
+}
+
+// dnsReplyMatches reports whether reply is a response carrying the same
+// question as query. Names compare case-insensitively.
+func dnsReplyMatches(query, reply []byte) bool {
+	if len(reply) < 12 || reply[2]&0x80 == 0 || binary.BigEndian.Uint16(reply[4:]) != 1 {
+		return false
+	}
+	qname, qoff, err := readDNSName(query, 12)
+	if err != nil || qoff+4 > len(query) {
+		return false
+	}
+	rname, roff, err := readDNSName(reply, 12)
+	if err != nil || roff+4 > len(reply) {
+		return false
+	}
+	return strings.EqualFold(qname, rname) && bytes.Equal(query[qoff:qoff+4], reply[roff:roff+4])
+}
+
+var errZoneTransferRefused = errors.New("zone transfer refused")
+
+// transferDNSZone requests a zone transfer from server over TCP and returns
+// the records of the zone, the opening SOA first. qtype is dnsTypeAXFR or
+// dnsTypeIXFR; IXFR is sent with serial 0, which asks for the whole zone.
+// Transfers larger than limit records are cut short with an error. A
+// server that declines the transfer, by answering REFUSED or NOTAUTH or by
+// sending only the SOA, yields an error wrapping errZoneTransferRefused.
+func transferDNSZone(ctx context.Context, server, zone string, qtype uint16, limit int, timeout time.Duration) ([]DNSResourceRecord, error) {
+	id := uint16(rand.Intn(1 << 16))
+	query, err := buildDNSTransferQuery(id, zone, qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	if err := writeDNSTCP(conn, query); err != nil {
+		return nil, err
+	}
+	var records []DNSResourceRecord
+	for {
+		reply, err := readDNSTCP(conn)
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(records) == 1 {
+				return nil, fmt.Errorf("%w: %s sent only the SOA for %s %s", errZoneTransferRefused, server, zone, dnsTypeName(qtype))
+			}
+			return records, err
+		}
+		if binary.BigEndian.Uint16(reply) != id {
+			return records, errors.New("DNS reply ID mismatch")
+		}
+		resp, err := parseDNSMessage(reply)
+		if err != nil {
+			return records, err
+		}
+		if resp.Rcode == dnsRcodeRefused || resp.Rcode == dnsRcodeNotAuth {
+			return records, fmt.Errorf("%w: %s answered %s for %s %s", errZoneTransferRefused, server, dnsRcodeName(resp.Rcode), zone, dnsTypeName(qtype))
+		}
+		if resp.Rcode != dnsRcodeSuccess {
+			return records, fmt.Errorf("%s answered %s for %s %s", server, dnsRcodeName(resp.Rcode), zone, dnsTypeName(qtype))
+		}
+		if len(records) == 0 && (len(resp.Answers) == 0 || resp.Answers[0].Type != "SOA") {
+			return nil, fmt.Errorf("%s sent no SOA for %s %s", server, zone, dnsTypeName(qtype))
+		}
+		records = append(records, resp.Answers...)
+		if len(records) > limit {
+			return records[:limit], fmt.Errorf("transfer of %s exceeded %d records", zone, limit)
+		}
+		// An IXFR answered with just the SOA means the server won't send the zone
+		if qtype == dnsTypeIXFR && len(records) == 1 {
+			return nil, fmt.Errorf("%w: %s sent only the SOA for %s IXFR", errZoneTransferRefused, server, zone)
+		}
+		// The transfer ends with the opening SOA repeated, which is dropped
+		if last := records[len(records)-1]; len(records) > 1 && last.Type == "SOA" && last.Value == records[0].Value {
+			return records[:len(records)-1], nil
+		}
+	}
+}
+
+// buildDNSTransferQuery encodes an AXFR or IXFR query. IXFR carries the
+// client's SOA in the authority section (RFC 1995); serial 0 with empty
+// names is enough for servers to fall back to a full transfer.
+func buildDNSTransferQuery(id uint16, zone string, qtype uint16) ([]byte, error) {
+	authority := 0
+	if qtype == dnsTypeIXFR {
+		authority = 1
+	}
+	msg := binary.BigEndian.AppendUint16(nil, id)
+	msg = binary.BigEndian.AppendUint16(msg, 0)
+	msg = append(msg, 0, 1, 0, 0, 0, byte(authority), 0, 0)
+
+	encoded, err := encodeDNSName(zone)
+	if err != nil {
+		return nil, err
+	}
+	msg = append(msg, encoded...)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, 1) // IN
+
+	if qtype == dnsTypeIXFR {
+		msg = append(msg, encoded...)
+		msg = binary.BigEndian.AppendUint16(msg, dnsTypeSOA)
+		msg = binary.BigEndian.AppendUint16(msg, 1)
+		msg = binary.BigEndian.AppendUint32(msg, 0)
+		msg = binary.BigEndian.AppendUint16(msg, 22)
+		msg = append(msg, 0, 0) // root MNAME and RNAME
+		msg = append(msg, make([]byte, 20)...)
+	}
+	return msg, nil
+}
+
+// writeDNSTCP and readDNSTCP frame messages with the two-byte length
+// prefix of DNS over TCP.
+func writeDNSTCP(conn net.Conn, msg []byte) error {
+	_, err := conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(msg))), msg...))
+	return err
+}
+
+func readDNSTCP(conn net.Conn) ([]byte, error) {
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, msg); err != nil {
+		return nil, err
+	}
+	if len(msg) < 12 {
+		return nil, errors.New("short DNS message")
+	}
+	return msg, nil
+}
+
+// buildDNSQuery encodes a single-question query with an EDNS0 OPT record
+// advertising a 1232 byte UDP payload.
+func buildDNSQuery(id uint16, name string, qtype uint16, opts dnsQueryOptions) ([]byte, error) {
+	flags := uint16(0)
+	if opts.Recursion {
+		flags |= 0x0100
+	}
+	msg := binary.BigEndian.AppendUint16(nil, id)
+	msg = binary.BigEndian.AppendUint16(msg, flags)
+	msg = append(msg, 0, 1, 0, 0, 0, 0, 0, 1) // QD=1, AN=0, NS=0, AR=1
+
+	encoded, err := encodeDNSName(name)
+	if err != nil {
+		return nil, err
+	}
+	msg = append(msg, encoded...)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, 1) // IN
+
+	msg = append(msg, 0) // root
+	msg = binary.BigEndian.AppendUint16(msg, dnsTypeOPT)
+	msg = binary.BigEndian.AppendUint16(msg, 1232)
+	extFlags := uint32(0)
+	if opts.DNSSEC {
+		extFlags |= 0x8000
+	}
+	msg = binary.BigEndian.AppendUint32(msg, extFlags)
+	msg = binary.BigEndian.AppendUint16(msg, 0)
+	return msg, nil
+}
+
+func encodeDNSName(name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	var out []byte
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if label == "" || len(label) > 63 {
+				return nil, fmt.Errorf("invalid DNS name %q", name)
+			}
+			out = append(out, byte(len(label)))
+			out = append(out, label...)
+		}
+	}
+	out = append(out, 0)
+	if len(out) > 255 {
+		return nil, fmt.Errorf("DNS name too long: %q", name)
+	}
+	return out, nil
+}
+
+func parseDNSMessage(msg []byte) (*DNSResponse, error) {
+	if len(msg) < 12 {
+		return nil, errors.New("short DNS message")
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	resp := &DNSResponse{
+		Rcode:         int(flags & 0x000f),
+		Authoritative: flags&0x0400 != 0,
+		RecursionOK:   flags&0x0080 != 0,
+		Authenticated: flags&0x0020 != 0,
+	}
+	qd := int(binary.BigEndian.Uint16(msg[4:]))
+	counts := []int{
+		int(binary.BigEndian.Uint16(msg[6:])),
+		int(binary.BigEndian.Uint16(msg[8:])),
+		int(binary.BigEndian.Uint16(msg[10:])),
+	}
+
+	off := 12
+	for i := 0; i < qd; i++ {
+		_, next, err := readDNSName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		off = next + 4
+	}
+
+	sections := []*[]DNSResourceRecord{&resp.Answers, &resp.Authority, &resp.Additional}
+	for s, count := range counts {
+		for i := 0; i < count; i++ {
+			rr, next, err := readDNSRecord(msg, off)
+			if err != nil {
+				return nil, err
+			}
+			off = next
+			if rr.Type == "OPT" {
+				// The extended RCODE lives in the OPT TTL
+				resp.Rcode |= int(rr.TTL>>24) << 4
+				continue
+			}
+			*sections[s] = append(*sections[s], rr)
+		}
+	}
+	return resp, nil
+}
+
+func readDNSRecord(msg []byte, off int) (DNSResourceRecord, int, error) {
+	name, off, err := readDNSName(msg, off)
+	if err != nil {
+		return DNSResourceRecord{}, 0, err
+	}
+	if off+10 > len(msg) {
+		return DNSResourceRecord{}, 0, errors.New("truncated DNS record")
+	}
+	rtype := binary.BigEndian.Uint16(msg[off:])
+	ttl := binary.BigEndian.Uint32(msg[off+4:])
+	rdlen := int(binary.BigEndian.Uint16(msg[off+8:]))
+	start := off + 10
+	end := start + rdlen
+	if end > len(msg) {
+		return DNSResourceRecord{}, 0, errors.New("truncated DNS record data")
+	}
+	value, err := formatDNSRData(msg, rtype, start, end)
+	if err != nil {
+		return DNSResourceRecord{}, 0, err
+	}
+	return DNSResourceRecord{Name: name, Type: dnsTypeName(rtype), TTL: ttl, Value: value}, end, nil
+}
+
+// readDNSName decodes a possibly compressed name at off and returns it
+// without the trailing dot, plus the offset after it.
+func readDNSName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return "", 0, errors.New("truncated DNS name")
+		}
+		length := int(msg[off])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.Join(labels, "."), next, nil
+		case length&0xc0 == 0xc0:
+			if off+1 >= len(msg) {
+				return "", 0, errors.New("truncated DNS name pointer")
+			}
+			if jumps++; jumps > 32 {
+				return "", 0, errors.New("DNS name compression loop")
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+		default:
+			if off+1+length > len(msg) {
+				return "", 0, errors.New("truncated DNS label")
+			}
+			labels = append(labels, string(msg[off+1:off+1+length]))
+			off += 1 + length
+		}
+	}
+}
+
+// formatDNSRData renders RDATA in zone file presentation. Types without a
+// dedicated format use the RFC 3597 \# form.
+func formatDNSRData(msg []byte, rtype uint16, start, end int) (string, error) {
+	rdata := msg[start:end]
+	// name reads a name inside the RDATA; compression may point anywhere in
+	// msg, but the name itself must end within the record.
+	name := func(off int) (string, int, error) {
+		n, next, err := readDNSName(msg, off)
+		if err == nil && next > end {
+			return "", 0, fmt.Errorf("%s record name overruns its data", dnsTypeName(rtype))
+		}
+		return n, next, err
+	}
+	need := func(n int) error {
+		if len(rdata) < n {
+			return fmt.Errorf("short %s record", dnsTypeName(rtype))
+		}
+		return nil
+	}
+
+	switch rtype {
+	case dnsTypeA:
+		if err := need(4); err != nil {
+			return "", err
+		}
+		return net.IP(rdata[:4]).String(), nil
+	case dnsTypeAAAA:
+		if err := need(16); err != nil {
+			return "", err
+		}
+		return net.IP(rdata[:16]).String(), nil
+	case dnsTypeNS, dnsTypeCNAME, dnsTypePTR:
+		n, _, err := name(start)
+		return n, err
+	case dnsTypeMX:
+		if err := need(3); err != nil {
+			return "", err
+		}
+		n, _, err := name(start + 2)
+		return fmt.Sprintf("%d %s", binary.BigEndian.Uint16(rdata), n), err
+	case dnsTypeTXT:
+		var parts []string
+		for i := 0; i < len(rdata); {
+			l := int(rdata[i])
+			if i+1+l > len(rdata) {
+				return "", errors.New("short TXT record")
+			}
+			parts = append(parts, string(rdata[i+1:i+1+l]))
+			i += 1 + l
+		}
+		return strings.Join(parts, ""), nil
+	case dnsTypeSOA:
+		mname, off, err := name(start)
+		if err != nil {
+			return "", err
+		}
+		rname, off, err := name(off)
+		if err != nil {
+			return "", err
+		}
+		if off+20 > end {
+			return "", errors.New("short SOA record")
+		}
+		return fmt.Sprintf("%s %s %d %d %d %d %d", mname, rname,
+			binary.BigEndian.Uint32(msg[off:]), binary.BigEndian.Uint32(msg[off+4:]),
+			binary.BigEndian.Uint32(msg[off+8:]), binary.BigEndian.Uint32(msg[off+12:]),
+			binary.BigEndian.Uint32(msg[off+16:])), nil
+	case dnsTypeSRV:
+		if err := need(7); err != nil {
+			return "", err
+		}
+		target, _, err := name(start + 6)
+		return fmt.Sprintf("%d %d %d %s", binary.BigEndian.Uint16(rdata), binary.BigEndian.Uint16(rdata[2:]),
+			binary.BigEndian.Uint16(rdata[4:]), target), err
+	case dnsTypeCAA:
+		if err := need(2); err != nil {
+			return "", err
+		}
+		tagLen := int(rdata[1])
+		if err := need(2 + tagLen); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d %s %q", rdata[0], rdata[2:2+tagLen], rdata[2+tagLen:]), nil
+	case dnsTypeNAPTR:
+		if err := need(4); err != nil {
+			return "", err
+		}
+		fields := []string{strconv.Itoa(int(binary.BigEndian.Uint16(rdata))), strconv.Itoa(int(binary.BigEndian.Uint16(rdata[2:])))}
+		i := 4
+		for k := 0; k < 3; k++ {
+			if i >= len(rdata) || i+1+int(rdata[i]) > len(rdata) {
+				return "", errors.New("short NAPTR record")
+			}
+			fields = append(fields, strconv.Quote(string(rdata[i+1:i+1+int(rdata[i])])))
+			i += 1 + int(rdata[i])
+		}
+		replacement, _, err := name(start + i)
+		return strings.Join(append(fields, replacement), " "), err
+	case dnsTypeDS:
+		if err := need(4); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d %d %d %s", binary.BigEndian.Uint16(rdata), rdata[2], rdata[3],
+			strings.ToUpper(hex.EncodeToString(rdata[4:]))), nil
+	case dnsTypeDNSKEY:
+		if err := need(4); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d %d %d %s", binary.BigEndian.Uint16(rdata), rdata[2], rdata[3],
+			base64.StdEncoding.EncodeToString(rdata[4:])), nil
+	case dnsTypeRRSIG:
+		if err := need(18); err != nil {
+			return "", err
+		}
+		signer, off, err := name(start + 18)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s %d %d %d %s %s %d %s %s", dnsTypeName(binary.BigEndian.Uint16(rdata)), rdata[2], rdata[3],
+			binary.BigEndian.Uint32(rdata[4:]),
+			time.Unix(int64(binary.BigEndian.Uint32(rdata[8:])), 0).UTC().Format("20060102150405"),
+			time.Unix(int64(binary.BigEndian.Uint32(rdata[12:])), 0).UTC().Format("20060102150405"),
+			binary.BigEndian.Uint16(rdata[16:]), signer,
+			base64.StdEncoding.EncodeToString(msg[off:end])), nil
+	}
+	return fmt.Sprintf("\\# %d %s", len(rdata), hex.EncodeToString(rdata)), nil
+}
@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testDNSName encodes name in wire format, panicking on invalid input.
+func testDNSName(name string) []byte {
+	encoded, err := encodeDNSName(name)
+	if err != nil {
+		panic(err)
+	}
+	return encoded
+}
+
+// testDNSRecord builds a resource record with the given owner, type and
+// RDATA. rdlen overrides the RDATA length when non-negative.
+func testDNSRecord(owner []byte, rtype uint16, rdata []byte, rdlen int) []byte {
+	if rdlen < 0 {
+		rdlen = len(rdata)
+	}
+	rr := append([]byte(nil), owner...)
+	rr = binary.BigEndian.AppendUint16(rr, rtype)
+	rr = binary.BigEndian.AppendUint16(rr, 1)
+	rr = binary.BigEndian.AppendUint32(rr, 300)
+	rr = binary.BigEndian.AppendUint16(rr, uint16(rdlen))
+	return append(rr, rdata...)
+}
+
+// testDNSMessage builds a response to the qname/qtype question carrying answers.
+func testDNSMessage(id uint16, qname string, qtype uint16, answers ...[]byte) []byte {
+	msg := binary.BigEndian.AppendUint16(nil, id)
+	msg = binary.BigEndian.AppendUint16(msg, 0x8180)
+	msg = binary.BigEndian.AppendUint16(msg, 1)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(len(answers)))
+	msg = append(msg, 0, 0, 0, 0)
+	msg = append(msg, testDNSName(qname)...)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, 1)
+	for _, rr := range answers {
+		msg = append(msg, rr...)
+	}
+	return msg
+}
+
+func testRRSIGData(signer []byte, signature []byte) []byte {
+	rdata := binary.BigEndian.AppendUint16(nil, dnsTypeA)
+	rdata = append(rdata, 13, 2)
+	rdata = binary.BigEndian.AppendUint32(rdata, 300)
+	rdata = binary.BigEndian.AppendUint32(rdata, uint32(time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC).Unix()))
+	rdata = binary.BigEndian.AppendUint32(rdata, uint32(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC).Unix()))
+	rdata = binary.BigEndian.AppendUint16(rdata, 12345)
+	rdata = append(rdata, signer...)
+	return append(rdata, signature...)
+}
+
+func TestParseDNSMessage(t *testing.T) {
+	owner := []byte{0xc0, 12} // pointer to the question name
+	signer := testDNSName("example.com")
+	rrsig := testRRSIGData(signer, []byte{1, 2, 3})
+	// A CNAME whose RDATA is a pointer to itself.
+	loop := testDNSMessage(1, "example.com", dnsTypeCNAME, testDNSRecord(owner, dnsTypeCNAME, []byte{0, 0}, -1))
+	binary.BigEndian.PutUint16(loop[len(loop)-2:], uint16(len(loop)-2)|0xc000)
+
+	tests := []struct {
+		name    string
+		msg     []byte
+		want    string
+		wantErr string
+	}{
+		{name: "A", msg: testDNSMessage(1, "example.com", dnsTypeA, testDNSRecord(owner, dnsTypeA, []byte{192, 0, 2, 1}, -1)), want: "192.0.2.1"},
+		{name: "compressed CNAME", msg: testDNSMessage(1, "example.com", dnsTypeCNAME, testDNSRecord(owner, dnsTypeCNAME, []byte{3, 'w', 'w', 'w', 0xc0, 12}, -1)), want: "www.example.com"},
+		{name: "MX", msg: testDNSMessage(1, "example.com", dnsTypeMX, testDNSRecord(owner, dnsTypeMX, append([]byte{0, 10}, testDNSName("mail.example.com")...), -1)), want: "10 mail.example.com"},
+		{name: "RRSIG", msg: testDNSMessage(1, "example.com", dnsTypeRRSIG, testDNSRecord(owner, dnsTypeRRSIG, rrsig, -1)),
+			want: "A 13 2 300 20261101000000 20261001000000 12345 example.com AQID"},
+		{name: "RRSIG without signature", msg: testDNSMessage(1, "example.com", dnsTypeRRSIG, testDNSRecord(owner, dnsTypeRRSIG, testRRSIGData(signer, nil), -1)),
+			want: "A 13 2 300 20261101000000 20261001000000 12345 example.com "},
+		{name: "RRSIG short fixed fields", msg: testDNSMessage(1, "example.com", dnsTypeRRSIG, testDNSRecord(owner, dnsTypeRRSIG, rrsig[:17], -1)), wantErr: "short RRSIG"},
+		// The signer name runs past RDLENGTH into bytes that follow the record.
+		{name: "RRSIG signer overruns rdata", msg: append(testDNSMessage(1, "example.com", dnsTypeRRSIG, testDNSRecord(owner, dnsTypeRRSIG, rrsig[:20], -1)), rrsig[20:]...), wantErr: "overruns"},
+		{name: "NS name overruns rdata", msg: testDNSMessage(1, "example.com", dnsTypeNS, testDNSRecord(owner, dnsTypeNS, testDNSName("ns1.example.com"), 4)), wantErr: "overruns"},
+		{name: "MX without name", msg: testDNSMessage(1, "example.com", dnsTypeMX, testDNSRecord(owner, dnsTypeMX, []byte{0, 10}, -1)), wantErr: "short MX"},
+		{name: "rdlength past end", msg: testDNSMessage(1, "example.com", dnsTypeA, testDNSRecord(owner, dnsTypeA, []byte{192, 0, 2, 1}, 40)), wantErr: "truncated DNS record data"},
+		{name: "compression loop", msg: loop, wantErr: "compression loop"},
+		{name: "short header", msg: []byte{0, 1, 0x81}, wantErr: "short DNS message"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := parseDNSMessage(tt.msg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseDNSMessage() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDNSMessage() error = %v", err)
+			}
+			if len(resp.Answers) != 1 || resp.Answers[0].Value != tt.want {
+				t.Fatalf("parseDNSMessage() answers = %+v, want %q", resp.Answers, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDNSMessageTruncated(t *testing.T) {
+	owner := []byte{0xc0, 12}
+	msg := testDNSMessage(1, "example.com", dnsTypeRRSIG,
+		testDNSRecord(owner, dnsTypeRRSIG, testRRSIGData(testDNSName("example.com"), []byte{1, 2, 3}), -1),
+		testDNSRecord(owner, dnsTypeSOA, append(append(testDNSName("ns1.example.com"), 0xc0, 12), make([]byte, 20)...), -1),
+		testDNSRecord(owner, dnsTypeSRV, append([]byte{0, 1, 0, 2, 0, 3}, 0xc0, 12), -1),
+		testDNSRecord(owner, dnsTypeNAPTR, append([]byte{0, 1, 0, 2, 1, 'u', 0, 0}, 0), -1),
+	)
+	if _, err := parseDNSMessage(msg); err != nil {
+		t.Fatalf("parseDNSMessage(full) error = %v", err)
+	}
+	for n := 0; n < len(msg); n++ {
+		if _, err := parseDNSMessage(msg[:n]); err == nil {
+			t.Errorf("parseDNSMessage(%d of %d bytes) succeeded, want error", n, len(msg))
+		}
+	}
+}
+
+func TestDNSReplyMatches(t *testing.T) {
+	query, err := buildDNSQuery(7, "example.com", dnsTypeA, dnsQueryOptions{Recursion: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	notResponse := testDNSMessage(7, "example.com", dnsTypeA)
+	notResponse[2] &^= 0x80
+	noQuestion := testDNSMessage(7, "example.com", dnsTypeA)
+	noQuestion[5] = 0
+
+	tests := []struct {
+		name  string
+		reply []byte
+		want  bool
+	}{
+		{name: "same question", reply: testDNSMessage(7, "example.com", dnsTypeA), want: true},
+		{name: "name differs in case", reply: testDNSMessage(7, "ExAmple.COM", dnsTypeA), want: true},
+		{name: "other name", reply: testDNSMessage(7, "attacker.example", dnsTypeA)},
+		{name: "other type", reply: testDNSMessage(7, "example.com", dnsTypeAAAA)},
+		{name: "query echoed back", reply: notResponse},
+		{name: "no question", reply: noQuestion},
+		{name: "truncated question", reply: testDNSMessage(7, "example.com", dnsTypeA)[:20]},
+		{name: "header only", reply: testDNSMessage(7, "example.com", dnsTypeA)[:12]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dnsReplyMatches(query, tt.reply); got != tt.want {
+				t.Errorf("dnsReplyMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExchangeDNSUDPIgnoresMismatchedReplies(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("no loopback UDP: %v", err)
+	}
+	defer conn.Close()
+
+	go func() {
+		buf := make([]byte, 512)
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil || n < 12 {
+			return
+		}
+		id := binary.BigEndian.Uint16(buf)
+		owner := []byte{0xc0, 12}
+		// A reply with the right ID for another question arrives first.
+		conn.WriteTo(testDNSMessage(id, "other.example", dnsTypeA, testDNSRecord(owner, dnsTypeA, []byte{203, 0, 113, 66}, -1)), addr)
+		conn.WriteTo(testDNSMessage(id, "example.com", dnsTypeA, testDNSRecord(owner, dnsTypeA, []byte{192, 0, 2, 1}, -1)), addr)
+	}()
+
+	query, err := buildDNSQuery(99, "example.com", dnsTypeA, dnsQueryOptions{Recursion: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := exchangeDNSUDP(context.Background(), conn.LocalAddr().String(), 99, query, 2*time.Second)
+	if err != nil {
+		t.Fatalf("exchangeDNSUDP() error = %v", err)
+	}
+	resp, err := parseDNSMessage(reply)
+	if err != nil {
+		t.Fatalf("parseDNSMessage() error = %v", err)
+	}
+	if len(resp.Answers) != 1 || resp.Answers[0].Value != "192.0.2.1" {
+		t.Fatalf("answers = %+v, want 192.0.2.1", resp.Answers)
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os/exec"
 	"strings"
 	"time"
 
@@ -84,13 +83,17 @@ func RunDNSxCompanyScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteDNSxCompanyScan(scanID, payload.Domains, scopeTargetID)
+	if _, err := EnqueueScanJob("dnsx-company", scanID, ScanJobPayload{Targets: payload.Domains, ScopeTargetID: scopeTargetID}); err != nil {
+		log.Printf("[ERROR] Failed to queue dnsx-company scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteDNSxCompanyScan(scanID string, domains []string, scopeTargetID string) {
+func ExecuteDNSxCompanyScan(ctx context.Context, scanID string, domains []string, scopeTargetID string) {
 	log.Printf("[DNSX-COMPANY] [INFO] Starting DNSx Company scan (scan ID: %s) for %d domains", scanID, len(domains))
 	startTime := time.Now()
 
@@ -123,9 +126,8 @@ func ExecuteDNSxCompanyScan(scanID string, domains []string, scopeTargetID strin
 	for i, domain := range domains {
 		log.Printf("[DNSX-COMPANY] [INFO] Processing domain %d/%d: %s", i+1, len(domains), domain)
 
-		cmd := exec.Command(
-			"docker", "exec", "-i",
-			"ars0n-framework-v2-dnsx-1",
+		cmd := NewToolCommandContext(ctx, 
+			"dnsx",
 			"dnsx",
 			"-a", "-aaaa", "-cname", "-mx", "-ns", "-txt", "-ptr", "-srv",
 			"-re", "-j",
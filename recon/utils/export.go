@@ -44,6 +44,8 @@ type ExportRequest struct {
 	CeWL                      bool `json:"cewl"`
 	IPPortScans               bool `json:"ip_port_scans"`
 	ConsolidatedAttackSurface bool `json:"consolidated_attack_surface"`
+	// Tools names registered tools to export with the generic CSV layout.
+	Tools []string `json:"tools"`
 }
 
 type AmassRecord struct {
@@ -136,7 +138,7 @@ func HandleExportData(w http.ResponseWriter, r *http.Request) {
 
 	if req.Sublist3r {
 		log.Println("[INFO] Starting Sublist3r data export")
-		if err := exportSublist3rData(zipWriter, tempDir); err != nil {
+		if err := exportNamedToolData(zipWriter, tempDir, "sublist3r"); err != nil {
 			log.Printf("[ERROR] Failed to export Sublist3r data: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to export Sublist3r data: %v", err), http.StatusInternalServerError)
 			return
@@ -146,7 +148,7 @@ func HandleExportData(w http.ResponseWriter, r *http.Request) {
 
 	if req.Assetfinder {
 		log.Println("[INFO] Starting Assetfinder data export")
-		if err := exportAssetfinderData(zipWriter, tempDir); err != nil {
+		if err := exportNamedToolData(zipWriter, tempDir, "assetfinder"); err != nil {
 			log.Printf("[ERROR] Failed to export Assetfinder data: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to export Assetfinder data: %v", err), http.StatusInternalServerError)
 			return
@@ -166,7 +168,7 @@ func HandleExportData(w http.ResponseWriter, r *http.Request) {
 
 	if req.Subfinder {
 		log.Println("[INFO] Starting Subfinder data export")
-		if err := exportSubfinderData(zipWriter, tempDir); err != nil {
+		if err := exportNamedToolData(zipWriter, tempDir, "subfinder"); err != nil {
 			log.Printf("[ERROR] Failed to export Subfinder data: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to export Subfinder data: %v", err), http.StatusInternalServerError)
 			return
@@ -364,6 +366,25 @@ func HandleExportData(w http.ResponseWriter, r *http.Request) {
 		log.Println("[INFO] Completed Consolidated Attack Surface data export")
 	}
 
+	exportedTools := map[string]bool{
+		"sublist3r":   req.Sublist3r,
+		"assetfinder": req.Assetfinder,
+		"subfinder":   req.Subfinder,
+	}
+	for _, name := range req.Tools {
+		if exportedTools[name] {
+			continue
+		}
+		exportedTools[name] = true
+		log.Printf("[INFO] Starting %s data export", name)
+		if err := exportNamedToolData(zipWriter, tempDir, name); err != nil {
+			log.Printf("[ERROR] Failed to export %s data: %v", name, err)
+			http.Error(w, fmt.Sprintf("Failed to export %s data: %v", name, err), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("[INFO] Completed %s data export", name)
+	}
+
 	// Close the zip writer
 	log.Println("[INFO] Finalizing zip file")
 	if err := zipWriter.Close(); err != nil {
@@ -736,120 +757,6 @@ func exportGauData(zipWriter *zip.Writer, tempDir string) error {
 	return addFileToZip(zipWriter, gauFile, "gau_data.csv")
 }
 
-func exportSublist3rData(zipWriter *zip.Writer, tempDir string) error {
-	sublist3rFile := filepath.Join(tempDir, "sublist3r_data.csv")
-	file, err := os.Create(sublist3rFile)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	headers := []string{"Scan ID", "Target", "Result", "Error", "Execution Time", "Command"}
-	if err := writer.Write(headers); err != nil {
-		return err
-	}
-
-	rows, err := dbPool.Query(context.Background(), `
-		SELECT 
-			s.scan_id,
-			st.scope_target,
-			COALESCE(s.result, ''),
-			COALESCE(s.error, ''),
-			COALESCE(s.execution_time, ''),
-			COALESCE(s.command, '')
-		FROM sublist3r_scans s
-		JOIN scope_targets st ON s.scope_target_id = st.id
-		ORDER BY s.scan_id
-	`)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var scanID, target, result, errorMsg, execTime, cmd string
-
-		if err := rows.Scan(&scanID, &target, &result, &errorMsg, &execTime, &cmd); err != nil {
-			return fmt.Errorf("error scanning sublist3r row: %v", err)
-		}
-
-		record := []string{
-			scanID,
-			target,
-			result,
-			errorMsg,
-			execTime,
-			cmd,
-		}
-
-		if err := writer.Write(record); err != nil {
-			return err
-		}
-	}
-
-	return addFileToZip(zipWriter, sublist3rFile, "sublist3r_data.csv")
-}
-
-func exportAssetfinderData(zipWriter *zip.Writer, tempDir string) error {
-	assetfinderFile := filepath.Join(tempDir, "assetfinder_data.csv")
-	file, err := os.Create(assetfinderFile)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	headers := []string{"Scan ID", "Target", "Result", "Error", "Execution Time", "Command"}
-	if err := writer.Write(headers); err != nil {
-		return err
-	}
-
-	rows, err := dbPool.Query(context.Background(), `
-		SELECT 
-			s.scan_id,
-			st.scope_target,
-			COALESCE(s.result, ''),
-			COALESCE(s.error, ''),
-			COALESCE(s.execution_time, ''),
-			COALESCE(s.command, '')
-		FROM assetfinder_scans s
-		JOIN scope_targets st ON s.scope_target_id = st.id
-		ORDER BY s.scan_id
-	`)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var scanID, target, result, errorMsg, execTime, cmd string
-
-		if err := rows.Scan(&scanID, &target, &result, &errorMsg, &execTime, &cmd); err != nil {
-			return fmt.Errorf("error scanning assetfinder row: %v", err)
-		}
-
-		record := []string{
-			scanID,
-			target,
-			result,
-			errorMsg,
-			execTime,
-			cmd,
-		}
-
-		if err := writer.Write(record); err != nil {
-			return err
-		}
-	}
-
-	return addFileToZip(zipWriter, assetfinderFile, "assetfinder_data.csv")
-}
-
 func exportCtlData(zipWriter *zip.Writer, tempDir string) error {
 	ctlFile := filepath.Join(tempDir, "ctl_data.csv")
 	file, err := os.Create(ctlFile)
@@ -907,63 +814,6 @@ func exportCtlData(zipWriter *zip.Writer, tempDir string) error {
 	return addFileToZip(zipWriter, ctlFile, "ctl_data.csv")
 }
 
-func exportSubfinderData(zipWriter *zip.Writer, tempDir string) error {
-	subfinderFile := filepath.Join(tempDir, "subfinder_data.csv")
-	file, err := os.Create(subfinderFile)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	headers := []string{"Scan ID", "Target", "Result", "Error", "Execution Time", "Command"}
-	if err := writer.Write(headers); err != nil {
-		return err
-	}
-
-	rows, err := dbPool.Query(context.Background(), `
-		SELECT 
-			s.scan_id,
-			st.scope_target,
-			COALESCE(s.result, ''),
-			COALESCE(s.error, ''),
-			COALESCE(s.execution_time, ''),
-			COALESCE(s.command, '')
-		FROM subfinder_scans s
-		JOIN scope_targets st ON s.scope_target_id = st.id
-		ORDER BY s.scan_id
-	`)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var scanID, target, result, errorMsg, execTime, cmd string
-
-		if err := rows.Scan(&scanID, &target, &result, &errorMsg, &execTime, &cmd); err != nil {
-			return fmt.Errorf("error scanning subfinder row: %v", err)
-		}
-
-		record := []string{
-			scanID,
-			target,
-			result,
-			errorMsg,
-			execTime,
-			cmd,
-		}
-
-		if err := writer.Write(record); err != nil {
-			return err
-		}
-	}
-
-	return addFileToZip(zipWriter, subfinderFile, "subfinder_data.csv")
-}
-
 func exportShufflednsData(zipWriter *zip.Writer, tempDir string) error {
 	shufflednsFile := filepath.Join(tempDir, "shuffledns_data.csv")
 	file, err := os.Create(shufflednsFile)
@@ -0,0 +1,355 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Favicons are hashed the way Shodan does (mmh3 of the base64 body with a
+// newline every 76 characters) and with SHA-256. data/favicon_hashes.json
+// names the products behind well-known mmh3 hashes; RECON_FAVICON_HASHES
+// points at a replacement file.
+
+//go:embed data/favicon_hashes.json
+var defaultFaviconHashes []byte
+
+const (
+	faviconSizeLimit     = 512 << 10
+	faviconMaxCandidates = 3
+	faviconMaxRedirects  = 3
+)
+
+type FaviconHash struct {
+	URL     string `json:"url"`
+	MMH3    int32  `json:"mmh3"`
+	SHA256  string `json:"sha256"`
+	Size    int    `json:"size"`
+	Product string `json:"product,omitempty"`
+}
+
+// mmh3 is MurmurHash3 x86 32-bit with seed 0, as a signed value like the
+// Python mmh3 module returns.
+func mmh3(data []byte) int32 {
+	const c1, c2 = 0xcc9e2d51, 0x1b873593
+	var h uint32
+	n := len(data) / 4
+	for i := 0; i < n; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+	var k uint32
+	tail := data[n*4:]
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return int32(h)
+}
+
+// shodanFaviconHash is mmh3 over Python's base64.encodebytes of data.
+func shodanFaviconHash(data []byte) int32 {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	b.WriteByte('\n')
+	return mmh3([]byte(b.String()))
+}
+
+var (
+	faviconProductsOnce sync.Once
+	faviconProducts     map[int32]string
+)
+
+// faviconProduct names the product behind a known mmh3 favicon hash.
+func faviconProduct(hash int32) string {
+	faviconProductsOnce.Do(func() {
+		data := defaultFaviconHashes
+		if file := os.Getenv("RECON_FAVICON_HASHES"); file != "" {
+			if custom, err := os.ReadFile(file); err != nil {
+				log.Printf("[FAVICON] [ERROR] Failed to read %s, using built-in hashes: %v", file, err)
+			} else {
+				data = custom
+			}
+		}
+		var byHash map[string]string
+		if err := json.Unmarshal(data, &byHash); err != nil {
+			log.Printf("[FAVICON] [ERROR] Failed to parse favicon hashes: %v", err)
+		}
+		faviconProducts = make(map[int32]string, len(byHash))
+		for key, product := range byHash {
+			if n, err := strconv.ParseInt(key, 10, 32); err == nil {
+				faviconProducts[int32(n)] = product
+			}
+		}
+	})
+	return faviconProducts[hash]
+}
+
+var (
+	linkTagPattern = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+	relPattern     = regexp.MustCompile(`(?i)\brel\s*=\s*["']?([^"'>]+)`)
+	hrefPattern    = regexp.MustCompile(`(?i)\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+)
+
+// faviconCandidates returns up to faviconMaxCandidates <link rel=icon>
+// targets in body followed by /favicon.ico, resolved against pageURL.
+func faviconCandidates(pageURL string, body []byte) []string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	var candidates []string
+	seen := make(map[string]bool)
+	add := func(ref string) {
+		u, err := base.Parse(strings.TrimSpace(ref))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || seen[u.String()] {
+			return
+		}
+		seen[u.String()] = true
+		candidates = append(candidates, u.String())
+	}
+	for _, tag := range linkTagPattern.FindAll(body, -1) {
+		if len(candidates) >= faviconMaxCandidates {
+			break
+		}
+		rel := relPattern.FindSubmatch(tag)
+		if rel == nil || !strings.Contains(strings.ToLower(string(rel[1])), "icon") {
+			continue
+		}
+		if href := hrefPattern.FindSubmatch(tag); href != nil {
+			ref := string(href[1]) + string(href[2]) + string(href[3])
+			if ref != "" && !strings.HasPrefix(ref, "data:") {
+				add(ref)
+			}
+		}
+	}
+	add("/favicon.ico")
+	return candidates
+}
+
+// fetch GETs target, following a few redirects, and returns the status,
+// content type and up to limit bytes of body.
+func (p *HTTPProber) fetch(ctx context.Context, target string, limit int64) (int, string, []byte, error) {
+	for hop := 0; ; hop++ {
+		resp, _, err := p.do(ctx, target)
+		if err != nil {
+			return 0, "", nil, err
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, limit))
+		resp.Body.Close()
+		location := resp.Header.Get("Location")
+		if resp.StatusCode >= 300 && resp.StatusCode < 400 && location != "" && hop < faviconMaxRedirects {
+			if next, err := resp.Request.URL.Parse(location); err == nil {
+				target = next.String()
+				continue
+			}
+		}
+		return resp.StatusCode, resp.Header.Get("Content-Type"), body, nil
+	}
+}
+
+// Favicons fetches the icons of the page at pageURL (body is the page,
+// fetched here if nil) and hashes each one that is an image.
+func (p *HTTPProber) Favicons(ctx context.Context, pageURL string, body []byte) []FaviconHash {
+	if body == nil {
+		status, _, page, err := p.fetch(ctx, pageURL, httpProbeBodyLimit)
+		if err != nil || status >= 400 {
+			page = nil
+		}
+		body = page
+	}
+
+	var favicons []FaviconHash
+	for _, candidate := range faviconCandidates(pageURL, body) {
+		status, contentType, data, err := p.fetch(ctx, candidate, faviconSizeLimit)
+		if err != nil || status != http.StatusOK || len(data) == 0 {
+			continue
+		}
+		// Soft 404s serve the site's HTML page instead of an icon
+		if strings.Contains(contentType, "text/html") || strings.HasPrefix(http.DetectContentType(data), "text/html") {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		hash := shodanFaviconHash(data)
+		favicons = append(favicons, FaviconHash{
+			URL:     candidate,
+			MMH3:    hash,
+			SHA256:  hex.EncodeToString(sum[:]),
+			Size:    len(data),
+			Product: faviconProduct(hash),
+		})
+	}
+	return favicons
+}
+
+// primaryFavicon is /favicon.ico when it was found, else the first icon.
+func primaryFavicon(favicons []FaviconHash) *FaviconHash {
+	for i := range favicons {
+		if strings.HasSuffix(favicons[i].URL, "/favicon.ico") {
+			return &favicons[i]
+		}
+	}
+	if len(favicons) > 0 {
+		return &favicons[0]
+	}
+	return nil
+}
+
+type FaviconAsset struct {
+	Source        string `json:"source"`
+	URL           string `json:"url"`
+	ScopeTargetID string `json:"scope_target_id"`
+	ScopeTarget   string `json:"scope_target"`
+	FaviconURL    string `json:"favicon_url"`
+}
+
+type FaviconGroup struct {
+	MMH3           int32          `json:"mmh3"`
+	SHA256         []string       `json:"sha256"`
+	Product        string         `json:"product,omitempty"`
+	Count          int            `json:"count"`
+	ScopeTargetIDs []string       `json:"scope_target_ids"`
+	Assets         []FaviconAsset `json:"assets"`
+	LastSeen       time.Time      `json:"last_seen"`
+}
+
+// GetFaviconGroups groups target URLs and live web servers by favicon
+// mmh3 hash. With {id} it covers one scope target, otherwise all of them;
+// ?scope=cross keeps only hashes shared by more than one scope target,
+// ?min_count= hides smaller groups and ?hash= returns one hash.
+func GetFaviconGroups(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	minCount := 1
+	if v := r.URL.Query().Get("min_count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "min_count must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		minCount = n
+	}
+	var hashFilter *int32
+	if v := r.URL.Query().Get("hash"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			http.Error(w, "hash must be an mmh3 integer", http.StatusBadRequest)
+			return
+		}
+		h := int32(n)
+		hashFilter = &h
+	}
+	crossOnly := r.URL.Query().Get("scope") == "cross"
+
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT f.source, f.url, f.scope_target_id, st.scope_target, f.favicon_mmh3,
+		       COALESCE(f.favicon_sha256, ''), COALESCE(f.favicon_url, ''), f.seen_at
+		FROM (
+			SELECT 'target_url' AS source, url, scope_target_id::text AS scope_target_id, favicon_mmh3, favicon_sha256, favicon_url,
+			       updated_at AS seen_at
+			FROM target_urls
+			WHERE favicon_mmh3 IS NOT NULL AND NOT no_longer_live
+			UNION ALL
+			SELECT 'live_web_server', lws.url, ips.scope_target_id::text, lws.favicon_mmh3, lws.favicon_sha256,
+			       lws.favicon_url, lws.last_checked
+			FROM live_web_servers lws
+			JOIN ip_port_scans ips ON ips.scan_id = lws.scan_id
+			WHERE lws.favicon_mmh3 IS NOT NULL
+		) f
+		JOIN scope_targets st ON st.id::text = f.scope_target_id
+		WHERE ($1 = '' OR f.scope_target_id = $1)
+		  AND ($2::int IS NULL OR f.favicon_mmh3 = $2)
+		ORDER BY f.favicon_mmh3, f.url`, scopeTargetID, hashFilter)
+	if err != nil {
+		log.Printf("[FAVICON] [ERROR] Failed to fetch favicon hashes: %v", err)
+		http.Error(w, "Failed to fetch favicon groups", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	groups := make(map[int32]*FaviconGroup)
+	var order []int32
+	for rows.Next() {
+		var asset FaviconAsset
+		var hash int32
+		var sha string
+		var seen time.Time
+		if err := rows.Scan(&asset.Source, &asset.URL, &asset.ScopeTargetID, &asset.ScopeTarget, &hash, &sha, &asset.FaviconURL, &seen); err != nil {
+			log.Printf("[FAVICON] [ERROR] Error scanning favicon row: %v", err)
+			continue
+		}
+		group := groups[hash]
+		if group == nil {
+			group = &FaviconGroup{MMH3: hash, Product: faviconProduct(hash), SHA256: []string{}, ScopeTargetIDs: []string{}}
+			groups[hash] = group
+			order = append(order, hash)
+		}
+		group.Assets = append(group.Assets, asset)
+		group.Count++
+		if sha != "" && !slices.Contains(group.SHA256, sha) {
+			group.SHA256 = append(group.SHA256, sha)
+		}
+		if !slices.Contains(group.ScopeTargetIDs, asset.ScopeTargetID) {
+			group.ScopeTargetIDs = append(group.ScopeTargetIDs, asset.ScopeTargetID)
+		}
+		if seen.After(group.LastSeen) {
+			group.LastSeen = seen
+		}
+	}
+
+	result := []*FaviconGroup{}
+	for _, hash := range order {
+		group := groups[hash]
+		if group.Count < minCount || (crossOnly && len(group.ScopeTargetIDs) < 2) {
+			continue
+		}
+		result = append(result, group)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
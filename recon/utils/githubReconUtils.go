@@ -3,114 +3,17 @@ package utils
 import (
 	"bytes"
 	"context"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
-	"net/http"
-	"os/exec"
 	"regexp"
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
-	"github.com/gorilla/mux"
 	"github.com/jackc/pgx/v5"
 )
 
-type GitHubReconScanStatus struct {
-	ID                string         `json:"id"`
-	ScanID            string         `json:"scan_id"`
-	CompanyName       string         `json:"company_name"`
-	Status            string         `json:"status"`
-	Result            sql.NullString `json:"result,omitempty"`
-	Error             sql.NullString `json:"error,omitempty"`
-	StdOut            sql.NullString `json:"stdout,omitempty"`
-	StdErr            sql.NullString `json:"stderr,omitempty"`
-	Command           sql.NullString `json:"command,omitempty"`
-	ExecTime          sql.NullString `json:"execution_time,omitempty"`
-	CreatedAt         time.Time      `json:"created_at"`
-	ScopeTargetID     string         `json:"scope_target_id"`
-	AutoScanSessionID sql.NullString `json:"auto_scan_session_id"`
-}
-
-func RunGitHubReconScan(w http.ResponseWriter, r *http.Request) {
-	log.Printf("[GITHUB-RECON] [INFO] Starting GitHub Recon scan request handling")
-	var payload struct {
-		CompanyName       string  `json:"company_name" binding:"required"`
-		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.CompanyName == "" {
-		log.Printf("[GITHUB-RECON] [ERROR] Invalid request body: %v", err)
-		http.Error(w, "Invalid request body. `company_name` is required.", http.StatusBadRequest)
-		return
-	}
-
-	companyName := payload.CompanyName
-	log.Printf("[GITHUB-RECON] [INFO] Processing GitHub Recon scan for company: %s", companyName)
-
-	query := `SELECT id FROM scope_targets WHERE type = 'Company' AND scope_target = $1`
-	var scopeTargetID string
-	err := dbPool.QueryRow(context.Background(), query, companyName).Scan(&scopeTargetID)
-	if err != nil {
-		log.Printf("[GITHUB-RECON] [ERROR] No matching company scope target found for company %s: %v", companyName, err)
-		http.Error(w, "No matching company scope target found.", http.StatusBadRequest)
-		return
-	}
-	log.Printf("[GITHUB-RECON] [INFO] Found scope target ID: %s for company: %s", scopeTargetID, companyName)
-
-	scanID := uuid.New().String()
-	log.Printf("[GITHUB-RECON] [INFO] Generated new scan ID: %s", scanID)
-
-	createTableQuery := `
-		CREATE TABLE IF NOT EXISTS github_recon_scans (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			scan_id UUID NOT NULL UNIQUE,
-			company_name TEXT NOT NULL,
-			status VARCHAR(50) NOT NULL,
-			result TEXT,
-			error TEXT,
-			stdout TEXT,
-			stderr TEXT,
-			command TEXT,
-			execution_time TEXT,
-			created_at TIMESTAMP DEFAULT NOW(),
-			scope_target_id UUID REFERENCES scope_targets(id) ON DELETE CASCADE,
-			auto_scan_session_id UUID REFERENCES auto_scan_sessions(id) ON DELETE SET NULL
-		);`
-	_, err = dbPool.Exec(context.Background(), createTableQuery)
-	if err != nil {
-		log.Printf("[GITHUB-RECON] [ERROR] Failed to create github_recon_scans table: %v", err)
-		http.Error(w, "Failed to create scan table.", http.StatusInternalServerError)
-		return
-	}
-	log.Printf("[GITHUB-RECON] [INFO] Ensured github_recon_scans table exists")
-
-	var insertQuery string
-	var args []interface{}
-	if payload.AutoScanSessionID != nil && *payload.AutoScanSessionID != "" {
-		insertQuery = `INSERT INTO github_recon_scans (scan_id, company_name, status, scope_target_id, auto_scan_session_id) VALUES ($1, $2, $3, $4, $5)`
-		args = []interface{}{scanID, companyName, "pending", scopeTargetID, *payload.AutoScanSessionID}
-	} else {
-		insertQuery = `INSERT INTO github_recon_scans (scan_id, company_name, status, scope_target_id) VALUES ($1, $2, $3, $4)`
-		args = []interface{}{scanID, companyName, "pending", scopeTargetID}
-	}
-	_, err = dbPool.Exec(context.Background(), insertQuery, args...)
-	if err != nil {
-		log.Printf("[GITHUB-RECON] [ERROR] Failed to create scan record: %v", err)
-		http.Error(w, "Failed to create scan record.", http.StatusInternalServerError)
-		return
-	}
-	log.Printf("[GITHUB-RECON] [INFO] Successfully created GitHub Recon scan record in database")
-
-	go ExecuteGitHubReconScan(scanID, companyName)
-
-	log.Printf("[GITHUB-RECON] [INFO] GitHub Recon scan initiated successfully, returning scan ID: %s", scanID)
-	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
-}
-
-func ExecuteGitHubReconScan(scanID, companyName string) {
+func ExecuteGitHubReconScan(ctx context.Context, scanID, companyName string) {
 	log.Printf("[GITHUB-RECON] [INFO] Starting GitHub Recon scan execution for company %s (scan ID: %s)", companyName, scanID)
 	startTime := time.Now()
 
@@ -154,26 +57,16 @@ func ExecuteGitHubReconScan(scanID, companyName string) {
 	domainName = reg.ReplaceAllString(domainName, "")
 	log.Printf("[GITHUB-RECON] [INFO] Transformed company name '%s' to domain format '%s'", companyName, domainName)
 
-	// First, check if the GitHub recon container is running
-	checkCmd := exec.Command("docker", "ps", "--filter", "name=ars0n-framework-v2-github-recon-1", "--format", "{{.Status}}")
-	checkOutput, err := checkCmd.Output()
-	if err != nil {
-		log.Printf("[GITHUB-RECON] [ERROR] Failed to check container status: %v", err)
-		UpdateGitHubReconScanStatus(scanID, "error", "", "", fmt.Sprintf("Failed to check container status: %v", err), "", time.Since(startTime).String())
-		return
-	}
-
-	containerStatus := strings.TrimSpace(string(checkOutput))
-	log.Printf("[GITHUB-RECON] [DEBUG] Container status: %s", containerStatus)
-
-	if containerStatus == "" {
-		log.Printf("[GITHUB-RECON] [ERROR] GitHub recon container is not running")
-		UpdateGitHubReconScanStatus(scanID, "error", "", "", "GitHub recon container is not running", "", time.Since(startTime).String())
+	// First, check that the GitHub recon tool is reachable
+	checkCmd := NewToolCommandContext(ctx, "github-recon", "true")
+	if err := checkCmd.Run(); err != nil {
+		log.Printf("[GITHUB-RECON] [ERROR] GitHub recon tool is not available: %v", err)
+		UpdateGitHubReconScanStatus(scanID, "error", "", "", fmt.Sprintf("GitHub recon tool is not available: %v", err), checkCmd.String(), time.Since(startTime).String())
 		return
 	}
 
 	// Debug: Check what's in the container
-	debugCmd := exec.Command("docker", "exec", "ars0n-framework-v2-github-recon-1", "ls", "-la", "/app/github-search")
+	debugCmd := NewToolCommandContext(ctx, "github-recon", "ls", "-la", "/app/github-search")
 	debugOutput, debugErr := debugCmd.Output()
 	if debugErr != nil {
 		log.Printf("[GITHUB-RECON] [DEBUG] Failed to list directory contents: %v", debugErr)
@@ -182,7 +75,7 @@ func ExecuteGitHubReconScan(scanID, companyName string) {
 	}
 
 	// Debug: Check if the Python script exists
-	pythonCheckCmd := exec.Command("docker", "exec", "ars0n-framework-v2-github-recon-1", "ls", "-la", "/app/github-search/github-endpoints.py")
+	pythonCheckCmd := NewToolCommandContext(ctx, "github-recon", "ls", "-la", "/app/github-search/github-endpoints.py")
 	pythonCheckOutput, pythonCheckErr := pythonCheckCmd.Output()
 	if pythonCheckErr != nil {
 		log.Printf("[GITHUB-RECON] [DEBUG] Python script check failed: %v", pythonCheckErr)
@@ -191,7 +84,7 @@ func ExecuteGitHubReconScan(scanID, companyName string) {
 	}
 
 	// Debug: Check the script help to see available parameters
-	helpCmd := exec.Command("docker", "exec", "ars0n-framework-v2-github-recon-1", "python3", "/app/github-search/github-endpoints.py", "-h")
+	helpCmd := NewToolCommandContext(ctx, "github-recon", "python3", "/app/github-search/github-endpoints.py", "-h")
 	helpOutput, helpErr := helpCmd.Output()
 	if helpErr != nil {
 		log.Printf("[GITHUB-RECON] [DEBUG] Failed to get help output: %v", helpErr)
@@ -199,8 +92,12 @@ func ExecuteGitHubReconScan(scanID, companyName string) {
 		log.Printf("[GITHUB-RECON] [DEBUG] Script help output:\n%s", string(helpOutput))
 	}
 
+	// Add timeout context
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
 	// Construct the command with unbuffered Python output
-	cmd := exec.Command("docker", "exec", "ars0n-framework-v2-github-recon-1", "python3", "-u", "/app/github-search/github-endpoints.py", "-d", domainName, "-t", apiKey)
+	cmd := NewToolCommandContext(ctx, "github-recon", "python3", "-u", "/app/github-search/github-endpoints.py", "-d", domainName, "-t", apiKey)
 	log.Printf("[GITHUB-RECON] [DEBUG] Executing command: %s", cmd.String())
 
 	// Set up separate stdout and stderr pipes
@@ -208,13 +105,6 @@ func ExecuteGitHubReconScan(scanID, companyName string) {
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	// Add timeout context
-	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
-	defer cancel()
-	cmd = exec.CommandContext(ctx, "docker", "exec", "ars0n-framework-v2-github-recon-1", "python3", "-u", "/app/github-search/github-endpoints.py", "-d", domainName, "-t", apiKey)
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
 	err = cmd.Run()
 	stdoutStr := stdout.String()
 	stderrStr := stderr.String()
@@ -346,160 +236,3 @@ func UpdateGitHubReconScanStatus(scanID, status, result, stdout, stderr, command
 		log.Printf("[GITHUB-RECON] [INFO] Successfully updated GitHub Recon scan status to %s for scan ID %s", status, scanID)
 	}
 }
-
-func GetGitHubReconScanStatus(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	scanID := vars["scan_id"]
-	log.Printf("[GITHUB-RECON] [INFO] Retrieving GitHub Recon scan status for scan ID: %s", scanID)
-
-	var scan GitHubReconScanStatus
-	query := `SELECT id, scan_id, company_name, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM github_recon_scans WHERE scan_id = $1`
-	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
-		&scan.ID,
-		&scan.ScanID,
-		&scan.CompanyName,
-		&scan.Status,
-		&scan.Result,
-		&scan.Error,
-		&scan.StdOut,
-		&scan.StdErr,
-		&scan.Command,
-		&scan.ExecTime,
-		&scan.CreatedAt,
-		&scan.ScopeTargetID,
-		&scan.AutoScanSessionID,
-	)
-
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			log.Printf("[GITHUB-RECON] [ERROR] GitHub Recon scan not found for scan ID: %s", scanID)
-			http.Error(w, "Scan not found", http.StatusNotFound)
-		} else {
-			log.Printf("[GITHUB-RECON] [ERROR] Failed to get GitHub Recon scan status for scan ID %s: %v", scanID, err)
-			http.Error(w, "Failed to get scan status", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	log.Printf("[GITHUB-RECON] [INFO] Successfully retrieved GitHub Recon scan status for scan ID %s: %s", scanID, scan.Status)
-	if scan.Result.Valid {
-		log.Printf("[GITHUB-RECON] [DEBUG] Scan has valid results of length: %d bytes", len(scan.Result.String))
-	}
-
-	response := map[string]interface{}{
-		"id":                   scan.ID,
-		"scan_id":              scan.ScanID,
-		"company_name":         scan.CompanyName,
-		"status":               scan.Status,
-		"result":               nullStringToString(scan.Result),
-		"error":                nullStringToString(scan.Error),
-		"stdout":               nullStringToString(scan.StdOut),
-		"stderr":               nullStringToString(scan.StdErr),
-		"command":              nullStringToString(scan.Command),
-		"execution_time":       nullStringToString(scan.ExecTime),
-		"created_at":           scan.CreatedAt.Format(time.RFC3339),
-		"scope_target_id":      scan.ScopeTargetID,
-		"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("[GITHUB-RECON] [ERROR] Failed to encode GitHub Recon scan response: %v", err)
-	} else {
-		log.Printf("[GITHUB-RECON] [INFO] Successfully sent GitHub Recon scan status response")
-	}
-}
-
-func GetGitHubReconScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	scopeTargetID := vars["id"]
-	log.Printf("[GITHUB-RECON] [INFO] Fetching GitHub Recon scans for scope target ID: %s", scopeTargetID)
-
-	if scopeTargetID == "" {
-		log.Printf("[GITHUB-RECON] [ERROR] No scope target ID provided")
-		http.Error(w, "No scope target ID provided", http.StatusBadRequest)
-		return
-	}
-
-	createTableQuery := `
-		CREATE TABLE IF NOT EXISTS github_recon_scans (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			scan_id UUID NOT NULL UNIQUE,
-			company_name TEXT NOT NULL,
-			status VARCHAR(50) NOT NULL,
-			result TEXT,
-			error TEXT,
-			stdout TEXT,
-			stderr TEXT,
-			command TEXT,
-			execution_time TEXT,
-			created_at TIMESTAMP DEFAULT NOW(),
-			scope_target_id UUID REFERENCES scope_targets(id) ON DELETE CASCADE,
-			auto_scan_session_id UUID REFERENCES auto_scan_sessions(id) ON DELETE SET NULL
-		);`
-	_, err := dbPool.Exec(context.Background(), createTableQuery)
-	if err != nil {
-		log.Printf("[GITHUB-RECON] [ERROR] Failed to create github_recon_scans table: %v", err)
-		http.Error(w, "Failed to create scan table.", http.StatusInternalServerError)
-		return
-	}
-
-	query := `SELECT id, scan_id, company_name, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM github_recon_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
-	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
-	if err != nil {
-		log.Printf("[GITHUB-RECON] [ERROR] Failed to get scans: %v", err)
-		http.Error(w, "Failed to get scans", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	var scans []map[string]interface{}
-	for rows.Next() {
-		var scan GitHubReconScanStatus
-		err := rows.Scan(
-			&scan.ID,
-			&scan.ScanID,
-			&scan.CompanyName,
-			&scan.Status,
-			&scan.Result,
-			&scan.Error,
-			&scan.StdOut,
-			&scan.StdErr,
-			&scan.Command,
-			&scan.ExecTime,
-			&scan.CreatedAt,
-			&scan.ScopeTargetID,
-			&scan.AutoScanSessionID,
-		)
-		if err != nil {
-			log.Printf("[GITHUB-RECON] [ERROR] Error scanning GitHub Recon scan row: %v", err)
-			continue
-		}
-
-		scanMap := map[string]interface{}{
-			"id":                   scan.ID,
-			"scan_id":              scan.ScanID,
-			"company_name":         scan.CompanyName,
-			"status":               scan.Status,
-			"result":               nullStringToString(scan.Result),
-			"error":                nullStringToString(scan.Error),
-			"stdout":               nullStringToString(scan.StdOut),
-			"stderr":               nullStringToString(scan.StdErr),
-			"command":              nullStringToString(scan.Command),
-			"execution_time":       nullStringToString(scan.ExecTime),
-			"created_at":           scan.CreatedAt.Format(time.RFC3339),
-			"scope_target_id":      scan.ScopeTargetID,
-			"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-		}
-		scans = append(scans, scanMap)
-	}
-
-	log.Printf("[GITHUB-RECON] [INFO] Successfully retrieved %d GitHub Recon scans for scope target %s", len(scans), scopeTargetID)
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(scans); err != nil {
-		log.Printf("[GITHUB-RECON] [ERROR] Failed to encode scans response: %v", err)
-	} else {
-		log.Printf("[GITHUB-RECON] [INFO] Successfully sent GitHub Recon scans response")
-	}
-}
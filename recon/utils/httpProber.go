@@ -0,0 +1,501 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The native prober replaces the httpx container for live web server
+// discovery. It follows redirects itself so the chain is kept, and its JSON
+// uses httpx's field names so stored results read the same either way.
+//
+// HTTP/3 coverage is partial: no QUIC client is available to the module, so
+// a host is only reported as offering h3 when its Alt-Svc header says so.
+// Hosts that serve h3 without advertising it, or advertise it without
+// serving it, are not told apart.
+
+const (
+	httpProbeBodyLimit    = 1 << 20
+	httpProbeMaxRedirects = 10
+
+	// hostLimiterSweepInterval is how often the per-host limiter drops
+	// hosts whose next slot has passed.
+	hostLimiterSweepInterval = time.Minute
+)
+
+type HTTPRedirectHop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+}
+
+type HTTPProbeTLS struct {
+	Version     string    `json:"tls_version"`
+	CipherSuite string    `json:"cipher"`
+	ServerName  string    `json:"sni,omitempty"`
+	SubjectCN   string    `json:"subject_cn,omitempty"`
+	SubjectAN   []string  `json:"subject_an,omitempty"`
+	IssuerCN    string    `json:"issuer_cn,omitempty"`
+	NotAfter    time.Time `json:"not_after"`
+}
+
+type HTTPProbeResult struct {
+	Input             string            `json:"input"`
+	URL               string            `json:"url"`
+	FinalURL          string            `json:"final_url"`
+	Scheme            string            `json:"scheme"`
+	Host              string            `json:"host"`
+	Port              int               `json:"port"`
+	IPAddress         string            `json:"a,omitempty"`
+	StatusCode        int               `json:"status_code"`
+	Title             string            `json:"title"`
+	WebServer         string            `json:"webserver"`
+	ContentLength     int64             `json:"content_length"`
+	ContentType       string            `json:"content_type"`
+	BodySHA256        string            `json:"body_sha256"`
+	Technologies      []string          `json:"tech,omitempty"`
+	TechnologyDetails []Technology      `json:"technologies,omitempty"`
+	RedirectChain     []HTTPRedirectHop `json:"chain,omitempty"`
+	Protocol          string            `json:"protocol"`
+	HTTP2             bool              `json:"http2"`
+	HTTP3Advertised   bool              `json:"http3_advertised"` // Alt-Svc offers h3; not verified over QUIC
+	CDN               bool              `json:"cdn"`
+	CDNName           string            `json:"cdn_name,omitempty"`
+	TLS               *HTTPProbeTLS     `json:"tls,omitempty"`
+	Headers           map[string]string `json:"header,omitempty"`
+	Favicon           *int32            `json:"favicon,omitempty"`
+	FaviconSHA256     string            `json:"favicon_sha256,omitempty"`
+	FaviconURL        string            `json:"favicon_url,omitempty"`
+	Favicons          []FaviconHash     `json:"favicons,omitempty"`
+	ResponseTimeMS    float64           `json:"time_ms"`
+	Timestamp         time.Time         `json:"timestamp"`
+}
+
+type HTTPProberOptions struct {
+	Timeout     time.Duration
+	Concurrency int
+	RateLimit   int // requests per second across all hosts, 0 for none
+	HostRPS     int // requests per second to any one host
+	UserAgent   string
+	Header      string // "Name: value"
+	// SkipFavicons leaves out the favicon requests made after each probe.
+	SkipFavicons bool
+	// Resolve pins hostnames to addresses, like curl --resolve. The URL's
+	// host still sets the Host header and SNI.
+	Resolve map[string]string
+}
+
+// hostRateLimiter spaces requests to each host by interval. Hosts idle
+// past their next slot are evicted, so a long scan doesn't keep every host
+// it has seen.
+type hostRateLimiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     map[string]time.Time
+	swept    time.Time
+}
+
+func newHostRateLimiter(rps int) *hostRateLimiter {
+	if rps <= 0 {
+		return nil
+	}
+	return &hostRateLimiter{interval: time.Second / time.Duration(rps), next: make(map[string]time.Time)}
+}
+
+func (l *hostRateLimiter) wait(ctx context.Context, host string) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if now.Sub(l.swept) >= hostLimiterSweepInterval {
+		// A missing host and a host whose slot has passed wait the same
+		for h, t := range l.next {
+			if t.Before(now) {
+				delete(l.next, h)
+			}
+		}
+		l.swept = now
+	}
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(l.interval)
+	l.mu.Unlock()
+
+	if delay := time.Until(at); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
+
+type HTTPProber struct {
+	client      *http.Client
+	opts        HTTPProberOptions
+	hostLimiter *hostRateLimiter
+	rateLimiter *hostRateLimiter
+}
+
+// NewHTTPProber builds a prober. Zero options fall back to a 10s timeout,
+// 50 workers and RECON_HTTP_PROBE_HOST_RPS (default 5) per host.
+func NewHTTPProber(opts HTTPProberOptions) *HTTPProber {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 50
+	}
+	if opts.HostRPS <= 0 {
+		opts.HostRPS = envInt("RECON_HTTP_PROBE_HOST_RPS", 5)
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36"
+	}
+
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	dial := dialer.DialContext
+	if len(opts.Resolve) > 0 {
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if host, port, err := net.SplitHostPort(addr); err == nil {
+				if ip, ok := opts.Resolve[strings.ToLower(host)]; ok {
+					addr = net.JoinHostPort(ip, port)
+				}
+			}
+			return dialer.DialContext(ctx, network, addr)
+		}
+	}
+	transport := &http.Transport{
+		Proxy:               nil,
+		DialContext:         dial,
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		TLSHandshakeTimeout: opts.Timeout,
+		ForceAttemptHTTP2:   true,
+		DisableKeepAlives:   true,
+	}
+	return &HTTPProber{
+		client: &http.Client{
+			Timeout:   opts.Timeout,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		opts:        opts,
+		hostLimiter: newHostRateLimiter(opts.HostRPS),
+		rateLimiter: newHostRateLimiter(opts.RateLimit),
+	}
+}
+
+func (p *HTTPProber) do(ctx context.Context, target string) (*http.Response, string, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := p.rateLimiter.wait(ctx, "*"); err != nil {
+		return nil, "", err
+	}
+	if err := p.hostLimiter.wait(ctx, u.Hostname()); err != nil {
+		return nil, "", err
+	}
+
+	var remote string
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if host, _, err := net.SplitHostPort(info.Conn.RemoteAddr().String()); err == nil {
+				remote = host
+			}
+		},
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, target, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", p.opts.UserAgent)
+	req.Header.Set("Accept", "*/*")
+	if name, value, ok := strings.Cut(p.opts.Header, ":"); ok {
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	resp, err := p.client.Do(req)
+	return resp, remote, err
+}
+
+// Probe requests target and follows up to httpProbeMaxRedirects redirects.
+// Status, title, body and TLS details describe the last response.
+func (p *HTTPProber) Probe(ctx context.Context, target string) (*HTTPProbeResult, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q", target)
+	}
+	result := &HTTPProbeResult{
+		Input:     target,
+		URL:       target,
+		Scheme:    u.Scheme,
+		Host:      u.Hostname(),
+		Port:      urlPort(u),
+		Timestamp: time.Now(),
+	}
+
+	start := time.Now()
+	current := target
+	var resp *http.Response
+	for hop := 0; ; hop++ {
+		r, remote, err := p.do(ctx, current)
+		if err != nil {
+			if hop == 0 {
+				return nil, err
+			}
+			break
+		}
+		if hop == 0 {
+			result.IPAddress = remote
+		}
+		location := r.Header.Get("Location")
+		if r.StatusCode >= 300 && r.StatusCode < 400 && location != "" && hop < httpProbeMaxRedirects {
+			next, err := url.Parse(current)
+			if err == nil {
+				next, err = next.Parse(location)
+			}
+			if err == nil {
+				io.Copy(io.Discard, io.LimitReader(r.Body, httpProbeBodyLimit))
+				r.Body.Close()
+				result.RedirectChain = append(result.RedirectChain, HTTPRedirectHop{URL: current, StatusCode: r.StatusCode, Location: next.String()})
+				current = next.String()
+				continue
+			}
+		}
+		resp = r
+		break
+	}
+	if resp == nil {
+		// The chain ended on a redirect that could not be followed
+		last := result.RedirectChain[len(result.RedirectChain)-1]
+		result.FinalURL = last.URL
+		result.StatusCode = last.StatusCode
+		result.ResponseTimeMS = float64(time.Since(start).Microseconds()) / 1000
+		return result, nil
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, httpProbeBodyLimit))
+	result.ResponseTimeMS = float64(time.Since(start).Microseconds()) / 1000
+	result.FinalURL = current
+	result.StatusCode = resp.StatusCode
+	result.WebServer = resp.Header.Get("Server")
+	result.ContentType = resp.Header.Get("Content-Type")
+	result.ContentLength = resp.ContentLength
+	if result.ContentLength < 0 {
+		result.ContentLength = int64(len(body))
+	}
+	sum := sha256.Sum256(body)
+	result.BodySHA256 = hex.EncodeToString(sum[:])
+	result.Title = probeTitle(body)
+	result.Protocol = resp.Proto
+	result.HTTP2 = resp.ProtoMajor == 2
+	result.HTTP3Advertised = strings.Contains(resp.Header.Get("Alt-Svc"), "h3")
+	result.CDNName = detectCDN(resp.Header)
+	result.CDN = result.CDNName != ""
+	result.Headers = make(map[string]string, len(resp.Header))
+	for name, values := range resp.Header {
+		result.Headers[strings.ToLower(name)] = strings.Join(values, ", ")
+	}
+	if resp.TLS != nil {
+		result.TLS = probeTLSInfo(resp.TLS)
+	}
+	if !p.opts.SkipFavicons {
+		result.Favicons = p.Favicons(ctx, current, body)
+		if favicon := primaryFavicon(result.Favicons); favicon != nil {
+			hash := favicon.MMH3
+			result.Favicon = &hash
+			result.FaviconSHA256 = favicon.SHA256
+			result.FaviconURL = favicon.URL
+		}
+	}
+	result.TechnologyDetails = DetectTechnologies(current, resp.Header, body, result.Favicons)
+	result.Technologies = technologyNames(result.TechnologyDetails)
+	return result, nil
+}
+
+// ProbeEndpoint tries HTTPS and then HTTP on host:port and returns the
+// first that answers. Default ports are left out of the URL.
+func (p *HTTPProber) ProbeEndpoint(ctx context.Context, host string, port int) (*HTTPProbeResult, error) {
+	var lastErr error
+	for _, scheme := range []string{"https", "http"} {
+		target := scheme + "://" + host
+		if !(scheme == "https" && port == 443) && !(scheme == "http" && port == 80) {
+			target = scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port))
+		}
+		result, err := p.Probe(ctx, target)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// ProbeAll probes every input on every port, the way httpx -ports does.
+// Inputs that already carry a scheme are probed as given. onResult is
+// called for each live endpoint as it is found.
+func (p *HTTPProber) ProbeAll(ctx context.Context, inputs []string, ports []int, onResult func(*HTTPProbeResult)) []*HTTPProbeResult {
+	type job struct {
+		target string
+		host   string
+		port   int
+	}
+	jobs := make(chan job)
+	var results []*HTTPProbeResult
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < p.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				var result *HTTPProbeResult
+				if j.target != "" {
+					result, _ = p.Probe(ctx, j.target)
+				} else {
+					result, _ = p.ProbeEndpoint(ctx, j.host, j.port)
+				}
+				if result == nil {
+					continue
+				}
+				mu.Lock()
+				results = append(results, result)
+				mu.Unlock()
+				if onResult != nil {
+					onResult(result)
+				}
+			}
+		}()
+	}
+
+send:
+	for _, input := range inputs {
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+		if strings.Contains(input, "://") {
+			select {
+			case jobs <- job{target: input}:
+			case <-ctx.Done():
+				break send
+			}
+			continue
+		}
+		for _, port := range ports {
+			select {
+			case jobs <- job{host: input, port: port}:
+			case <-ctx.Done():
+				break send
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func urlPort(u *url.URL) int {
+	if port, err := strconv.Atoi(u.Port()); err == nil {
+		return port
+	}
+	if u.Scheme == "https" {
+		return 443
+	}
+	return 80
+}
+
+var titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+func probeTitle(body []byte) string {
+	matches := titlePattern.FindSubmatch(body)
+	if len(matches) < 2 {
+		return ""
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(string(matches[1]))), " ")
+}
+
+func probeTLSInfo(state *tls.ConnectionState) *HTTPProbeTLS {
+	info := &HTTPProbeTLS{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ServerName:  state.ServerName,
+	}
+	if len(state.PeerCertificates) > 0 {
+		leaf := state.PeerCertificates[0]
+		info.SubjectCN = leaf.Subject.CommonName
+		info.SubjectAN = leaf.DNSNames
+		info.IssuerCN = leaf.Issuer.CommonName
+		info.NotAfter = leaf.NotAfter
+	}
+	return info
+}
+
+// cdnHeaders maps response headers to the CDN or edge that sets them. A
+// value of "" matches any value.
+var cdnHeaders = []struct {
+	header, contains, cdn string
+}{
+	{"cf-ray", "", "Cloudflare"},
+	{"server", "cloudflare", "Cloudflare"},
+	{"x-amz-cf-id", "", "Amazon CloudFront"},
+	{"via", "cloudfront", "Amazon CloudFront"},
+	{"x-akamai-transformed", "", "Akamai"},
+	{"akamai-grn", "", "Akamai"},
+	{"server", "akamaighost", "Akamai"},
+	{"x-fastly-request-id", "", "Fastly"},
+	{"x-served-by", "cache-", "Fastly"},
+	{"x-azure-ref", "", "Azure Front Door"},
+	{"x-msedge-ref", "", "Azure CDN"},
+	{"via", "google", "Google Cloud CDN"},
+	{"x-iinfo", "", "Imperva"},
+	{"x-cdn", "incapsula", "Imperva"},
+	{"x-sucuri-id", "", "Sucuri"},
+	{"server", "bunnycdn", "BunnyCDN"},
+	{"server", "keycdn", "KeyCDN"},
+	{"x-vercel-id", "", "Vercel"},
+	{"x-nf-request-id", "", "Netlify"},
+	{"server", "stackpath", "StackPath"},
+	{"x-hw", "", "StackPath"},
+	{"x-edgeconnect-midmile-rtt", "", "Akamai"},
+	{"x-cache", "cloudfront", "Amazon CloudFront"},
+}
+
+func detectCDN(header http.Header) string {
+	for _, c := range cdnHeaders {
+		value := header.Get(c.header)
+		if value == "" {
+			continue
+		}
+		if c.contains == "" || strings.Contains(strings.ToLower(value), c.contains) {
+			return c.cdn
+		}
+	}
+	return ""
+}
@@ -78,13 +78,17 @@ func RunInvestigateScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteInvestigateScan(scanID, payload.ScopeTargetID)
+	if _, err := EnqueueScanJob("investigate", scanID, ScanJobPayload{ScopeTargetID: payload.ScopeTargetID}); err != nil {
+		log.Printf("[ERROR] Failed to queue investigate scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteInvestigateScan(scanID, scopeTargetID string) {
+func ExecuteInvestigateScan(ctx context.Context, scanID, scopeTargetID string) {
 	log.Printf("[INFO] Starting investigate scan for scope target %s (scan ID: %s)", scopeTargetID, scanID)
 	startTime := time.Now()
 
@@ -132,10 +136,10 @@ func ExecuteInvestigateScan(scanID, scopeTargetID string) {
 		result := InvestigateResult{Domain: domain}
 
 		// Get IP address
-		if ips, err := net.LookupIP(domain); err == nil && len(ips) > 0 {
-			result.IPAddress = ips[0].String()
+		if ips := resolveDomainIPs(domain); len(ips) > 0 {
+			result.IPAddress = ips[0]
 		} else {
-			log.Printf("[WARN] Failed to resolve IP for %s: %v", domain, err)
+			log.Printf("[WARN] Failed to resolve IP for %s", domain)
 			result.IPAddress = "N/A"
 		}
 
@@ -170,6 +174,12 @@ func ExecuteInvestigateScan(scanID, scopeTargetID string) {
 	log.Printf("[INFO] Investigate scan completed for scope target %s", scopeTargetID)
 }
 
+func resolveDomainIPs(domain string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return defaultDNSPool().LookupIPs(ctx, domain)
+}
+
 func getSSLInfo(domain string) *SSLInfo {
 	dialer := &net.Dialer{Timeout: 10 * time.Second}
 	conn, err := tls.DialWithDialer(dialer, "tcp", domain+":443", &tls.Config{
@@ -208,13 +218,13 @@ func getSSLInfo(domain string) *SSLInfo {
 }
 
 func getASNInfo(domain string) *InvestigateASN {
-	ips, err := net.LookupIP(domain)
-	if err != nil || len(ips) == 0 {
-		log.Printf("[WARN] Failed to resolve IP for %s: %v", domain, err)
+	ips := resolveDomainIPs(domain)
+	if len(ips) == 0 {
+		log.Printf("[WARN] Failed to resolve IP for %s", domain)
 		return nil
 	}
 
-	ip := ips[0].String()
+	ip := ips[0]
 
 	// Try multiple APIs for better reliability
 	client := &http.Client{Timeout: 10 * time.Second}
@@ -329,7 +339,7 @@ func GetInvestigateScanStatus(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	scanID := vars["scan_id"]
 
-	query := `SELECT * FROM investigate_scans WHERE scan_id = $1`
+	query := `SELECT id, scan_id, scope_target_id, status, result, error, stdout, stderr, command, execution_time, created_at FROM investigate_scans WHERE scan_id = $1`
 	var scan InvestigateStatus
 	var result, error, stdout, stderr, command, execTime sql.NullString
 
@@ -385,7 +395,7 @@ func GetInvestigateScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `SELECT * FROM investigate_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
+	query := `SELECT id, scan_id, scope_target_id, status, result, error, stdout, stderr, command, execution_time, created_at FROM investigate_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
 	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get investigate scans for target %s: %v", scopeTargetID, err)
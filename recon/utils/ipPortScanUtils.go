@@ -2,15 +2,14 @@ package utils
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net"
 	"net/http"
-	"regexp"
-	"strings"
+	"runtime/debug"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -35,20 +34,21 @@ type IPPortScan struct {
 }
 
 type LiveWebServer struct {
-	ID            string    `json:"id"`
-	ScanID        string    `json:"scan_id"`
-	IPAddress     string    `json:"ip_address"`
-	Hostname      string    `json:"hostname,omitempty"`
-	Port          int       `json:"port"`
-	Protocol      string    `json:"protocol"`
-	URL           string    `json:"url"`
-	StatusCode    *int      `json:"status_code,omitempty"`
-	Title         string    `json:"title,omitempty"`
-	ServerHeader  string    `json:"server_header,omitempty"`
-	ContentLength *int64    `json:"content_length,omitempty"`
-	Technologies  []string  `json:"technologies,omitempty"`
-	ResponseTime  *float64  `json:"response_time_ms,omitempty"`
-	LastChecked   time.Time `json:"last_checked"`
+	ID            string           `json:"id"`
+	ScanID        string           `json:"scan_id"`
+	IPAddress     string           `json:"ip_address"`
+	Hostname      string           `json:"hostname,omitempty"`
+	Port          int              `json:"port"`
+	Protocol      string           `json:"protocol"`
+	URL           string           `json:"url"`
+	StatusCode    *int             `json:"status_code,omitempty"`
+	Title         string           `json:"title,omitempty"`
+	ServerHeader  string           `json:"server_header,omitempty"`
+	ContentLength *int64           `json:"content_length,omitempty"`
+	Technologies  []string         `json:"technologies,omitempty"`
+	ResponseTime  *float64         `json:"response_time_ms,omitempty"`
+	Probe         *HTTPProbeResult `json:"http_probe,omitempty"`
+	LastChecked   time.Time        `json:"last_checked"`
 }
 
 type DiscoveredIP struct {
@@ -62,12 +62,16 @@ type DiscoveredIP struct {
 }
 
 type ScanConfig struct {
-	MaxIPsPerRange     int           `json:"max_ips_per_range"`
-	MaxConcurrentIPs   int           `json:"max_concurrent_ips"`
-	MaxConcurrentPorts int           `json:"max_concurrent_ports"`
-	HostProbeTimeout   time.Duration `json:"host_probe_timeout"`
-	PortScanTimeout    time.Duration `json:"port_scan_timeout"`
-	WebServiceTimeout  time.Duration `json:"web_service_timeout"`
+	MaxIPsPerRange           int           `json:"max_ips_per_range"`
+	MaxConcurrentIPs         int           `json:"max_concurrent_ips"`
+	MaxConcurrentPorts       int           `json:"max_concurrent_ports"`
+	MaxConcurrentConnections int           `json:"max_concurrent_connections"`
+	HostProbeTimeout         time.Duration `json:"host_probe_timeout"`
+	PortScanTimeout          time.Duration `json:"port_scan_timeout"`
+	WebServiceTimeout        time.Duration `json:"web_service_timeout"`
+	PortProfile              string        `json:"port_profile"`
+	Ports                    []int         `json:"ports"`
+	SkipHostDiscovery        bool          `json:"skip_host_discovery"`
 }
 
 // Common ports to probe for host discovery
@@ -84,12 +88,15 @@ var webPorts = []int{
 
 func getDefaultScanConfig() ScanConfig {
 	return ScanConfig{
-		MaxIPsPerRange:     254,             // Limit IPs per CIDR
-		MaxConcurrentIPs:   50,              // Max concurrent IP probes
-		MaxConcurrentPorts: 20,              // Max concurrent port scans
-		HostProbeTimeout:   1 * time.Second, // Per port connection timeout
-		PortScanTimeout:    1 * time.Second, // Per port connection timeout
-		WebServiceTimeout:  5 * time.Second, // Per HTTP request timeout
+		MaxIPsPerRange:           254,             // Limit IPs per CIDR
+		MaxConcurrentIPs:         50,              // Max concurrent IP probes
+		MaxConcurrentPorts:       20,              // Max concurrent port scans
+		MaxConcurrentConnections: 10,              // Max concurrent connections per IP
+		HostProbeTimeout:         1 * time.Second, // Per port connection timeout
+		PortScanTimeout:          1 * time.Second, // Per port connection timeout
+		WebServiceTimeout:        5 * time.Second, // Per HTTP request timeout
+		PortProfile:              PortProfileWeb,
+		Ports:                    webPorts,
 	}
 }
 
@@ -97,8 +104,9 @@ func getDefaultScanConfig() ScanConfig {
 func RunIPPortScan(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[IP-PORT-SCAN] [INFO] Starting IP/Port scan request handling")
 	var payload struct {
-		ScopeTargetID     string  `json:"scope_target_id" binding:"required"`
-		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
+		ScopeTargetID     string            `json:"scope_target_id" binding:"required"`
+		AutoScanSessionID *string           `json:"auto_scan_session_id,omitempty"`
+		Config            *IPPortScanConfig `json:"config,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.ScopeTargetID == "" {
@@ -107,6 +115,15 @@ func RunIPPortScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A config sent with the request applies to this scan only; it travels in
+	// the job payload and leaves the scope target's saved config untouched.
+	if payload.Config != nil {
+		if err := payload.Config.validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid config: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
 	log.Printf("[IP-PORT-SCAN] [INFO] Processing IP/Port scan for scope target: %s", payload.ScopeTargetID)
 
 	scanID := uuid.New().String()
@@ -134,17 +151,43 @@ func RunIPPortScan(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Start the scan in background
-	go ExecuteIPPortScan(scanID, payload.ScopeTargetID)
+	if _, err := EnqueueScanJob("ip-port-scan", scanID, ScanJobPayload{ScopeTargetID: payload.ScopeTargetID, IPPortScanConfig: payload.Config}); err != nil {
+		log.Printf("[ERROR] Failed to queue ip-port-scan scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-// Execute the complete IP/Port scan process
-func ExecuteIPPortScan(scanID, scopeTargetID string) {
+// ExecuteIPPortScan runs the complete IP/Port scan process, with override
+// when the request carried a config and with the scope target's saved config
+// otherwise.
+func ExecuteIPPortScan(ctx context.Context, scanID, scopeTargetID string, override *IPPortScanConfig) {
 	log.Printf("[IP-PORT-SCAN] [INFO] Starting IP/Port scan execution for scope target: %s", scopeTargetID)
 	startTime := time.Now()
 
+	var scanSettings IPPortScanConfig
+	if override != nil {
+		scanSettings = *override
+	} else {
+		createIPPortScanConfigTable()
+		savedConfig, err := loadIPPortScanConfig(scopeTargetID)
+		if err != nil {
+			updateIPPortScanStatus(scanID, "error", fmt.Sprintf("Failed to load scan config: %v", err))
+			return
+		}
+		scanSettings = savedConfig
+	}
+	config, err := scanSettings.scanConfig()
+	if err != nil {
+		updateIPPortScanStatus(scanID, "error", fmt.Sprintf("Invalid scan config: %v", err))
+		return
+	}
+	updateIPPortScanCommand(scanID, config.describe())
+	log.Printf("[IP-PORT-SCAN] [INFO] Using port profile %s (%d ports)", config.PortProfile, len(config.Ports))
+
 	// Get consolidated network ranges
 	networkRanges, err := getConsolidatedNetworkRanges(scopeTargetID)
 	if err != nil {
@@ -163,26 +206,34 @@ func ExecuteIPPortScan(scanID, scopeTargetID string) {
 	updateIPPortScanProgress(scanID, "discovering_ips", len(networkRanges), 0, 0, 0, 0)
 
 	// Phase 1: Discover live IPs
-	liveIPs, err := discoverLiveIPs(scanID, networkRanges)
+	liveIPs, err := discoverLiveIPs(ctx, scanID, scopeTargetID, networkRanges, config)
 	if err != nil {
 		updateIPPortScanStatus(scanID, "error", fmt.Sprintf("IP discovery failed: %v", err))
 		return
 	}
+	if ctx.Err() != nil {
+		log.Printf("[IP-PORT-SCAN] [INFO] Scan %s cancelled after IP discovery", scanID)
+		return
+	}
 
 	log.Printf("[IP-PORT-SCAN] [INFO] Discovered %d live IPs", len(liveIPs))
 	updateIPPortScanProgress(scanID, "port_scanning", len(networkRanges), len(networkRanges), len(liveIPs), 0, 0)
 
 	// Phase 2: Port scan for web services
-	liveWebServers, err := discoverLiveWebServers(scanID, liveIPs)
+	liveWebServers, err := discoverLiveWebServers(ctx, scanID, liveIPs, config)
 	if err != nil {
 		updateIPPortScanStatus(scanID, "error", fmt.Sprintf("Port scanning failed: %v", err))
 		return
 	}
+	if ctx.Err() != nil {
+		log.Printf("[IP-PORT-SCAN] [INFO] Scan %s cancelled during port scanning", scanID)
+		return
+	}
 
 	log.Printf("[IP-PORT-SCAN] [INFO] Found %d live web servers", len(liveWebServers))
 
 	// Update final status
-	totalPortsScanned := len(liveIPs) * len(webPorts)
+	totalPortsScanned := len(liveIPs) * len(config.Ports)
 	updateIPPortScanProgress(scanID, "success", len(networkRanges), len(networkRanges), len(liveIPs), totalPortsScanned, len(liveWebServers))
 	updateIPPortScanExecutionTime(scanID, time.Since(startTime).String())
 
@@ -220,10 +271,12 @@ func getConsolidatedNetworkRanges(scopeTargetID string) ([]ConsolidatedNetworkRa
 }
 
 // Discover live IPs using TCP connect probes
-func discoverLiveIPs(scanID string, networkRanges []ConsolidatedNetworkRange) ([]string, error) {
+func discoverLiveIPs(ctx context.Context, scanID, scopeTargetID string, networkRanges []ConsolidatedNetworkRange, config ScanConfig) ([]string, error) {
 	log.Printf("[IP-PORT-SCAN] [INFO] Starting IP discovery for %d network ranges", len(networkRanges))
+	if config.SkipHostDiscovery {
+		log.Printf("[IP-PORT-SCAN] [INFO] Host discovery skipped; treating every IP as alive")
+	}
 
-	config := getDefaultScanConfig()
 	var allLiveIPs []string
 	var mu sync.Mutex
 	var wg sync.WaitGroup
@@ -232,6 +285,9 @@ func discoverLiveIPs(scanID string, networkRanges []ConsolidatedNetworkRange) ([
 	// Semaphore to limit concurrent operations
 	semaphore := make(chan struct{}, config.MaxConcurrentIPs)
 
+	var ipv6Seeds []net.IP
+	ipv6SeedsLoaded := false
+
 	for rangeIdx, networkRange := range networkRanges {
 		log.Printf("[IP-PORT-SCAN] [DEBUG] Processing network range %d/%d: %s", rangeIdx+1, len(networkRanges), networkRange.CIDRBlock)
 
@@ -242,8 +298,17 @@ func discoverLiveIPs(scanID string, networkRanges []ConsolidatedNetworkRange) ([
 			continue
 		}
 
-		// Generate all IPs in the range
-		ips := generateIPsFromCIDR(ipNet)
+		// Generate all IPs in the range, or targeted candidates for IPv6
+		var ips []string
+		if ipNet.IP.To4() == nil {
+			if !ipv6SeedsLoaded {
+				ipv6Seeds = loadIPv6Seeds(scopeTargetID)
+				ipv6SeedsLoaded = true
+			}
+			ips = generateIPv6Candidates(ipNet, ipv6Seeds, config.MaxIPsPerRange)
+		} else {
+			ips = generateIPsFromCIDR(ipNet)
+		}
 		log.Printf("[IP-PORT-SCAN] [DEBUG] Generated %d IPs from CIDR %s", len(ips), networkRange.CIDRBlock)
 
 		// Limit the number of IPs to scan per range
@@ -262,14 +327,20 @@ func discoverLiveIPs(scanID string, networkRanges []ConsolidatedNetworkRange) ([
 				semaphore <- struct{}{}        // Acquire
 				defer func() { <-semaphore }() // Release
 
+				if ctx.Err() != nil {
+					return
+				}
+
 				if idx%50 == 0 {
 					log.Printf("[IP-PORT-SCAN] [DEBUG] Probing IP %d/%d in range %s: %s", idx+1, len(ips), cidr, ipAddr)
 				}
 
-				if isHostAlive(ipAddr, config.HostProbeTimeout) {
+				if config.SkipHostDiscovery || isHostAlive(ipAddr, config.HostProbeTimeout) {
 					mu.Lock()
 					allLiveIPs = append(allLiveIPs, ipAddr)
+					found := len(allLiveIPs)
 					mu.Unlock()
+					PublishScanCounts(scanID, map[string]int{"live_ips_found": found})
 
 					// Store in database
 					insertDiscoveredIP(scanID, ipAddr, cidr)
@@ -297,7 +368,7 @@ func isHostAlive(ip string, timeout time.Duration) bool {
 	// Each port gets the full timeout (1 second)
 
 	for _, port := range hostDiscoveryPorts {
-		address := fmt.Sprintf("%s:%d", ip, port)
+		address := net.JoinHostPort(ip, strconv.Itoa(port))
 		conn, err := net.DialTimeout("tcp", address, timeout)
 		if err == nil {
 			conn.Close()
@@ -314,9 +385,9 @@ func generateIPsFromCIDR(ipNet *net.IPNet) []string {
 	// Get the network address
 	ip := ipNet.IP
 
-	// Convert to 4-byte representation
+	// Convert to 4-byte representation; IPv6 ranges go through
+	// generateIPv6Candidates instead
 	if ip.To4() == nil {
-		// IPv6 not supported for now
 		return ips
 	}
 
@@ -358,41 +429,74 @@ func generateIPsFromCIDR(ipNet *net.IPNet) []string {
 	return ips
 }
 
-// Port scan live IPs for web services
-func discoverLiveWebServers(scanID string, liveIPs []string) ([]LiveWebServer, error) {
-	log.Printf("[IP-PORT-SCAN] [INFO] Starting port scanning for %d live IPs", len(liveIPs))
+// Port scan live IPs for web services, fingerprinting any other open ports
+func discoverLiveWebServers(ctx context.Context, scanID string, liveIPs []string, config ScanConfig) ([]LiveWebServer, error) {
+	log.Printf("[IP-PORT-SCAN] [INFO] Starting port scanning for %d live IPs (%d ports each)", len(liveIPs), len(config.Ports))
 
-	config := getDefaultScanConfig()
 	var allWebServers []LiveWebServer
+	servicesFound := 0
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 
 	// Semaphore to limit concurrent port scans
 	semaphore := make(chan struct{}, config.MaxConcurrentPorts)
 
+	// One prober for the scan so per-host rate limits apply across ports
+	customUserAgent, customHeader := GetCustomHTTPSettings()
+	prober := NewHTTPProber(HTTPProberOptions{
+		Timeout:     config.WebServiceTimeout,
+		Concurrency: config.MaxConcurrentPorts,
+		UserAgent:   customUserAgent,
+		Header:      customHeader,
+	})
+
 	for ipIdx, ip := range liveIPs {
 		wg.Add(1)
 		go func(idx int, ipAddr string) {
 			defer wg.Done()
 			semaphore <- struct{}{}        // Acquire
 			defer func() { <-semaphore }() // Release
+			// A probe that trips over a malformed reply loses this IP only,
+			// not the whole scan process.
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("[IP-PORT-SCAN] [ERROR] Panic while scanning %s: %v\n%s", ipAddr, r, debug.Stack())
+				}
+			}()
+
+			if ctx.Err() != nil {
+				return
+			}
 
 			log.Printf("[IP-PORT-SCAN] [DEBUG] Port scanning IP %d/%d: %s", idx+1, len(liveIPs), ipAddr)
 
-			// Scan web ports
-			openPorts := scanTCPPorts(ipAddr, webPorts, config.PortScanTimeout)
+			// Scan the profile's ports
+			openPorts := scanTCPPorts(ctx, ipAddr, config.Ports, config.PortScanTimeout, config.MaxConcurrentConnections)
 
 			// Check each open port for web services
 			for _, port := range openPorts {
-				webServer := checkForWebService(scanID, ipAddr, port, config.WebServiceTimeout)
+				webServer := checkForWebService(ctx, prober, scanID, ipAddr, port)
 				if webServer != nil {
 					mu.Lock()
 					allWebServers = append(allWebServers, *webServer)
+					found := len(allWebServers)
 					mu.Unlock()
+					PublishScanCounts(scanID, map[string]int{"live_web_servers_found": found})
 
 					// Store in database
 					insertLiveWebServer(scanID, *webServer)
+					continue
 				}
+
+				// Not HTTP: identify the service from its banner or a protocol probe
+				service := fingerprintService(ctx, ipAddr, port, config.WebServiceTimeout)
+				insertDiscoveredService(scanID, *service)
+				mu.Lock()
+				servicesFound++
+				found := servicesFound
+				mu.Unlock()
+				PublishScanCounts(scanID, map[string]int{"services_found": found})
+				log.Printf("[IP-PORT-SCAN] [DEBUG] %s:%d identified as %s %s %s", ipAddr, port, service.Service, service.Product, service.Version)
 			}
 
 		}(ipIdx, ip)
@@ -401,26 +505,34 @@ func discoverLiveWebServers(scanID string, liveIPs []string) ([]LiveWebServer, e
 	wg.Wait()
 
 	log.Printf("[IP-PORT-SCAN] [INFO] Total live web servers found: %d", len(allWebServers))
+	log.Printf("[IP-PORT-SCAN] [INFO] Total non-HTTP services found: %d", servicesFound)
 	return allWebServers, nil
 }
 
 // TCP port scanner using connect() method
-func scanTCPPorts(ip string, ports []int, timeout time.Duration) []int {
+func scanTCPPorts(ctx context.Context, ip string, ports []int, timeout time.Duration, concurrency int) []int {
 	var openPorts []int
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 
 	// Semaphore for port scanning concurrency
-	semaphore := make(chan struct{}, 10) // Max 10 concurrent connections per IP
+	semaphore := make(chan struct{}, concurrency) // Max concurrent connections per IP
 
 	for _, port := range ports {
+		if ctx.Err() != nil {
+			break
+		}
+		semaphore <- struct{}{} // Acquire before starting so at most concurrency goroutines exist
 		wg.Add(1)
 		go func(p int) {
 			defer wg.Done()
-			semaphore <- struct{}{}        // Acquire
 			defer func() { <-semaphore }() // Release
 
-			address := fmt.Sprintf("%s:%d", ip, p)
+			if ctx.Err() != nil {
+				return
+			}
+
+			address := net.JoinHostPort(ip, strconv.Itoa(p))
 			conn, err := net.DialTimeout("tcp", address, timeout)
 			if err == nil {
 				conn.Close()
@@ -432,139 +544,39 @@ func scanTCPPorts(ip string, ports []int, timeout time.Duration) []int {
 	}
 
 	wg.Wait()
+	sort.Ints(openPorts)
 	return openPorts
 }
 
 // Check if an open port is running a web service
-func checkForWebService(scanID, ipAddr string, port int, timeout time.Duration) *LiveWebServer {
-	protocols := []string{"http", "https"}
-
-	for _, protocol := range protocols {
-		url := fmt.Sprintf("%s://%s:%d", protocol, ipAddr, port)
-
-		// Custom HTTP client with short timeout
-		client := &http.Client{
-			Timeout: timeout,
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{
-					InsecureSkipVerify: true,
-				},
-				DisableKeepAlives: true,
-			},
-		}
-
-		startTime := time.Now()
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			continue
-		}
-
-		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
-
-		resp, err := client.Do(req)
-		responseTime := float64(time.Since(startTime).Nanoseconds()) / 1e6 // Convert to milliseconds
-
-		if err != nil {
-			// Try next protocol
-			continue
-		}
-		defer resp.Body.Close()
-
-		// We found a web service!
-		webServer := &LiveWebServer{
-			ScanID:       scanID,
-			IPAddress:    ipAddr,
-			Port:         port,
-			Protocol:     protocol,
-			URL:          url,
-			StatusCode:   &resp.StatusCode,
-			ResponseTime: &responseTime,
-			LastChecked:  time.Now(),
-		}
-
-		// Extract server header
-		if server := resp.Header.Get("Server"); server != "" {
-			webServer.ServerHeader = server
-		}
-
-		// Get content length
-		if resp.ContentLength > 0 {
-			webServer.ContentLength = &resp.ContentLength
-		}
-
-		// Try to extract page title
-		if title := extractPageTitle(resp); title != "" {
-			webServer.Title = title
-		}
-
-		// Detect technologies from headers
-		technologies := detectTechnologies(resp)
-		if len(technologies) > 0 {
-			webServer.Technologies = technologies
-		}
-
-		return webServer
-	}
-
-	return nil
-}
-
-// Extract page title from HTTP response
-func extractPageTitle(resp *http.Response) string {
-	contentType := resp.Header.Get("Content-Type")
-	if contentType == "" || !strings.Contains(strings.ToLower(contentType), "text/html") {
-		return ""
-	}
-
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 8192)) // Read first 8KB only
+func checkForWebService(ctx context.Context, prober *HTTPProber, scanID, ipAddr string, port int) *LiveWebServer {
+	probe, err := prober.ProbeEndpoint(ctx, ipAddr, port)
 	if err != nil {
-		return ""
-	}
-
-	titleRegex := regexp.MustCompile(`(?i)<title[^>]*>([^<]+)</title>`)
-	matches := titleRegex.FindStringSubmatch(string(body))
-	if len(matches) > 1 {
-		return strings.TrimSpace(matches[1])
-	}
-
-	return ""
-}
-
-// Detect technologies from HTTP headers
-func detectTechnologies(resp *http.Response) []string {
-	var technologies []string
-
-	// Check Server header
-	if server := resp.Header.Get("Server"); server != "" {
-		technologies = append(technologies, server)
-	}
-
-	// Check X-Powered-By header
-	if poweredBy := resp.Header.Get("X-Powered-By"); poweredBy != "" {
-		technologies = append(technologies, poweredBy)
-	}
-
-	// Check other technology indicators
-	for header, values := range resp.Header {
-		switch strings.ToLower(header) {
-		case "x-aspnet-version":
-			if len(values) > 0 {
-				technologies = append(technologies, fmt.Sprintf("ASP.NET %s", values[0]))
-			}
-		case "x-generator":
-			if len(values) > 0 {
-				technologies = append(technologies, values[0])
-			}
-		case "x-drupal-cache":
-			technologies = append(technologies, "Drupal")
-		case "x-powered-cms":
-			if len(values) > 0 {
-				technologies = append(technologies, values[0])
-			}
-		}
-	}
-
-	return technologies
+		return nil
+	}
+
+	// We found a web service!
+	statusCode := probe.StatusCode
+	responseTime := probe.ResponseTimeMS
+	webServer := &LiveWebServer{
+		ScanID:       scanID,
+		IPAddress:    ipAddr,
+		Port:         port,
+		Protocol:     probe.Scheme,
+		URL:          fmt.Sprintf("%s://%s", probe.Scheme, net.JoinHostPort(ipAddr, strconv.Itoa(port))),
+		StatusCode:   &statusCode,
+		Title:        probe.Title,
+		ServerHeader: probe.WebServer,
+		Technologies: probe.Technologies,
+		ResponseTime: &responseTime,
+		Probe:        probe,
+		LastChecked:  time.Now(),
+	}
+	if probe.ContentLength > 0 {
+		contentLength := probe.ContentLength
+		webServer.ContentLength = &contentLength
+	}
+	return webServer
 }
 
 // Database helper functions
@@ -613,11 +625,29 @@ func createIPPortScanTables() {
 			last_checked TIMESTAMP DEFAULT NOW(),
 			UNIQUE(scan_id, ip_address, port, protocol)
 		);`,
+		`CREATE TABLE IF NOT EXISTS discovered_services (
+			id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+			scan_id TEXT NOT NULL REFERENCES ip_port_scans(scan_id) ON DELETE CASCADE,
+			ip_address INET NOT NULL,
+			port INT NOT NULL,
+			protocol VARCHAR(10) NOT NULL DEFAULT 'tcp',
+			service VARCHAR(50) NOT NULL,
+			product TEXT,
+			version TEXT,
+			banner TEXT,
+			tls BOOLEAN DEFAULT false,
+			discovered_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scan_id, ip_address, port)
+		);`,
 		`CREATE INDEX IF NOT EXISTS idx_discovered_live_ips_scan_id ON discovered_live_ips(scan_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_discovered_services_scan_id ON discovered_services(scan_id);`,
 		`CREATE INDEX IF NOT EXISTS idx_live_web_servers_scan_id ON live_web_servers(scan_id);`,
 		`CREATE INDEX IF NOT EXISTS idx_live_web_servers_ip_port ON live_web_servers(ip_address, port);`,
 		`ALTER TABLE discovered_live_ips ADD COLUMN IF NOT EXISTS hostname TEXT;`,
 		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS hostname TEXT;`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS ssl_info JSONB;`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS http_response_headers JSONB;`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS http_probe JSONB;`,
 	}
 
 	for _, tableQuery := range tables {
@@ -647,19 +677,36 @@ func insertLiveWebServer(scanID string, webServer LiveWebServer) {
 		webServer.Hostname = resolveHostname(webServer.IPAddress)
 	}
 
-	query := `INSERT INTO live_web_servers (scan_id, ip_address, hostname, port, protocol, url, status_code, title, server_header, content_length, technologies, response_time_ms) 
-			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	query := `INSERT INTO live_web_servers (scan_id, ip_address, hostname, port, protocol, url, status_code, title, server_header, content_length, technologies, response_time_ms, ssl_info, http_response_headers, http_probe, favicon_mmh3, favicon_sha256, favicon_url, technology_details) 
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 			  ON CONFLICT (scan_id, ip_address, port, protocol) DO UPDATE SET
 			  hostname = EXCLUDED.hostname, status_code = EXCLUDED.status_code, title = EXCLUDED.title, server_header = EXCLUDED.server_header,
 			  content_length = EXCLUDED.content_length, technologies = EXCLUDED.technologies, 
-			  response_time_ms = EXCLUDED.response_time_ms, last_checked = NOW()`
+			  response_time_ms = EXCLUDED.response_time_ms, ssl_info = EXCLUDED.ssl_info,
+			  http_response_headers = EXCLUDED.http_response_headers, http_probe = EXCLUDED.http_probe,
+			  favicon_mmh3 = EXCLUDED.favicon_mmh3, favicon_sha256 = EXCLUDED.favicon_sha256, favicon_url = EXCLUDED.favicon_url,
+			  technology_details = EXCLUDED.technology_details, last_checked = NOW()`
 
 	technologiesJSON, _ := json.Marshal(webServer.Technologies)
+	var sslInfo, headers, probe, technologyDetails []byte
+	var faviconHash *int32
+	var faviconSHA256, faviconURL *string
+	if webServer.Probe != nil {
+		if webServer.Probe.Favicon != nil {
+			faviconHash, faviconSHA256, faviconURL = webServer.Probe.Favicon, &webServer.Probe.FaviconSHA256, &webServer.Probe.FaviconURL
+		}
+		probe, _ = json.Marshal(webServer.Probe)
+		technologyDetails, _ = json.Marshal(webServer.Probe.TechnologyDetails)
+		headers, _ = json.Marshal(webServer.Probe.Headers)
+		if webServer.Probe.TLS != nil {
+			sslInfo, _ = json.Marshal(webServer.Probe.TLS)
+		}
+	}
 
 	_, err := dbPool.Exec(context.Background(), query,
 		scanID, webServer.IPAddress, webServer.Hostname, webServer.Port, webServer.Protocol, webServer.URL,
 		webServer.StatusCode, webServer.Title, webServer.ServerHeader, webServer.ContentLength,
-		technologiesJSON, webServer.ResponseTime)
+		technologiesJSON, webServer.ResponseTime, sslInfo, headers, probe, faviconHash, faviconSHA256, faviconURL, technologyDetails)
 	if err != nil {
 		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to insert live web server: %v", err)
 	} else if webServer.Hostname != "" {
@@ -673,6 +720,7 @@ func updateIPPortScanStatus(scanID, status, errorMessage string) {
 	if err != nil {
 		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to update scan status: %v", err)
 	}
+	PublishScanStatus(scanID, status)
 }
 
 func updateIPPortScanProgress(scanID string, status string, totalRanges, processedRanges, totalIPs, totalPorts, liveServers int) {
@@ -689,6 +737,23 @@ func updateIPPortScanProgress(scanID string, status string, totalRanges, process
 	if err != nil {
 		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to update scan progress: %v", err)
 	}
+
+	PublishScanPhase(scanID, status)
+	PublishScanCounts(scanID, map[string]int{
+		"total_network_ranges":     totalRanges,
+		"processed_network_ranges": processedRanges,
+		"total_ips_discovered":     totalIPs,
+		"total_ports_scanned":      totalPorts,
+		"live_web_servers_found":   liveServers,
+	})
+}
+
+func updateIPPortScanCommand(scanID, command string) {
+	query := `UPDATE ip_port_scans SET command = $1 WHERE scan_id = $2`
+	_, err := dbPool.Exec(context.Background(), query, command, scanID)
+	if err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to update scan command: %v", err)
+	}
 }
 
 func updateIPPortScanExecutionTime(scanID, executionTime string) {
@@ -715,31 +780,15 @@ func removeDuplicateIPs(ips []string) []string {
 
 // Resolve hostname for an IP address with timeout
 func resolveHostname(ipAddr string) string {
-	// Set a timeout for DNS resolution
-	resolver := &net.Resolver{
-		PreferGo: true,
-		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-			d := net.Dialer{
-				Timeout: 2 * time.Second, // 2 second timeout for DNS resolution
-			}
-			return d.DialContext(ctx, network, address)
-		},
-	}
-
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	names, err := resolver.LookupAddr(ctx, ipAddr)
+	names, err := defaultDNSPool().LookupPTR(ctx, ipAddr)
 	if err != nil || len(names) == 0 {
 		return "" // No hostname found
 	}
 
-	// Return the first hostname, removing trailing dot
-	hostname := names[0]
-	if strings.HasSuffix(hostname, ".") {
-		hostname = hostname[:len(hostname)-1]
-	}
-	return hostname
+	return names[0]
 }
 
 // API handlers
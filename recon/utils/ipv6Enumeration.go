@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"context"
+	"encoding/binary"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// An IPv6 range is far too large to sweep, so the IP/Port scan probes a
+// targeted candidate list instead:
+//
+//   - addresses already seen in AAAA records and their close neighbours
+//   - EUI-64 interface IDs derived from those addresses, with nearby MACs,
+//     applied to each /64 in play
+//   - low-byte addresses (::1 to ::ff) and service-port style addresses
+//     (::80, ::443, ...) in each /64 in play
+//
+// The /64s in play are the ones that hold a known address plus the first
+// /64 of the range.
+
+const (
+	ipv6SeedNeighbours  = 8
+	ipv6EUI64Neighbours = 16
+	ipv6MaxLowByte      = 0xff
+)
+
+// ipv6ServicePatterns are interface IDs written to look like port numbers,
+// which operators often use for service addresses (e.g. 2001:db8::443).
+var ipv6ServicePatterns = []string{"21", "22", "25", "53", "80", "443", "8080", "8443", "1000", "2000"}
+
+// loadIPv6Seeds returns every IPv6 address the engine has seen in AAAA
+// records or target URLs. Callers filter it down to the range they scan.
+func loadIPv6Seeds(scopeTargetID string) []net.IP {
+	queries := []string{
+		`SELECT DISTINCT record FROM dns_records WHERE record_type = 'AAAA'`,
+		`SELECT DISTINCT record FROM dnsx_company_dns_records WHERE record_type = 'AAAA' AND scope_target_id = $1`,
+		`SELECT DISTINCT record FROM amass_enum_company_dns_records WHERE record_type = 'AAAA' AND scope_target_id = $1`,
+		`SELECT DISTINCT ip_address FROM target_urls WHERE ip_address LIKE '%:%'`,
+	}
+
+	seen := make(map[string]bool)
+	var seeds []net.IP
+	for _, query := range queries {
+		var args []interface{}
+		if strings.Contains(query, "$1") {
+			args = append(args, scopeTargetID)
+		}
+		rows, err := dbPool.Query(context.Background(), query, args...)
+		if err != nil {
+			log.Printf("[IP-PORT-SCAN] [DEBUG] Skipping IPv6 seed source: %v", err)
+			continue
+		}
+		for rows.Next() {
+			var record string
+			if err := rows.Scan(&record); err != nil {
+				continue
+			}
+			ip := net.ParseIP(record)
+			if ip == nil || ip.To4() != nil || seen[ip.String()] {
+				continue
+			}
+			seen[ip.String()] = true
+			seeds = append(seeds, ip)
+		}
+		rows.Close()
+	}
+
+	log.Printf("[IP-PORT-SCAN] [INFO] Loaded %d IPv6 seed addresses", len(seeds))
+	return seeds
+}
+
+// generateIPv6Candidates returns up to limit addresses in ipNet worth
+// probing, most promising first.
+func generateIPv6Candidates(ipNet *net.IPNet, seeds []net.IP, limit int) []string {
+	ones, bits := ipNet.Mask.Size()
+	if bits != 128 {
+		return nil
+	}
+
+	var candidates []string
+	seen := make(map[string]bool)
+	add := func(ip net.IP) bool {
+		if ip == nil || !ipNet.Contains(ip) {
+			return true
+		}
+		key := ip.String()
+		if !seen[key] {
+			seen[key] = true
+			candidates = append(candidates, key)
+		}
+		return len(candidates) < limit
+	}
+
+	// The /64s to guess in. Ranges longer than /64 are one subnet already.
+	subnetBits := 64
+	if ones > 64 {
+		subnetBits = ones
+	}
+	subnetMask := net.CIDRMask(subnetBits, 128)
+	var subnets []net.IP
+	seenSubnets := make(map[string]bool)
+	addSubnet := func(ip net.IP) {
+		base := ip.Mask(subnetMask)
+		if !seenSubnets[base.String()] {
+			seenSubnets[base.String()] = true
+			subnets = append(subnets, base)
+		}
+	}
+
+	var inRange []net.IP
+	for _, seed := range seeds {
+		if ipNet.Contains(seed) {
+			inRange = append(inRange, seed)
+			addSubnet(seed)
+		}
+	}
+	addSubnet(ipNet.IP)
+
+	// Known addresses and their neighbours.
+	for _, seed := range inRange {
+		if !add(seed) {
+			return candidates
+		}
+	}
+	for _, seed := range inRange {
+		for d := int64(1); d <= ipv6SeedNeighbours; d++ {
+			if !add(ipv6Offset(seed, d)) || !add(ipv6Offset(seed, -d)) {
+				return candidates
+			}
+		}
+	}
+
+	// EUI-64 guesses: MACs next to the ones seen, in every subnet in play.
+	for _, seed := range inRange {
+		mac, ok := eui64MAC(seed)
+		if !ok {
+			continue
+		}
+		for d := int64(-ipv6EUI64Neighbours); d <= ipv6EUI64Neighbours; d++ {
+			iid := eui64InterfaceID(macOffset(mac, d))
+			for _, subnet := range subnets {
+				if !add(withInterfaceID(subnet, iid)) {
+					return candidates
+				}
+			}
+		}
+	}
+
+	// Low-byte and service-port style addresses.
+	for _, subnet := range subnets {
+		for _, pattern := range ipv6ServicePatterns {
+			n, _ := strconv.ParseInt(pattern, 16, 64)
+			if !add(ipv6Offset(subnet, n)) {
+				return candidates
+			}
+		}
+	}
+	for n := int64(1); n <= ipv6MaxLowByte; n++ {
+		for _, subnet := range subnets {
+			if !add(ipv6Offset(subnet, n)) {
+				return candidates
+			}
+		}
+	}
+
+	return candidates
+}
+
+// ipv6Offset adds n to the low 64 bits of ip, carrying into the high half.
+func ipv6Offset(ip net.IP, n int64) net.IP {
+	ip = ip.To16()
+	if ip == nil {
+		return nil
+	}
+	hi := binary.BigEndian.Uint64(ip[:8])
+	lo := binary.BigEndian.Uint64(ip[8:])
+	sum := lo + uint64(n)
+	switch {
+	case n > 0 && sum < lo:
+		hi++
+	case n < 0 && sum > lo:
+		hi--
+	}
+	out := make(net.IP, net.IPv6len)
+	binary.BigEndian.PutUint64(out[:8], hi)
+	binary.BigEndian.PutUint64(out[8:], sum)
+	return out
+}
+
+// eui64MAC recovers the MAC address from an EUI-64 interface ID
+// (xx:xx:xx:ff:fe:xx:xx:xx with the universal/local bit flipped).
+func eui64MAC(ip net.IP) (net.HardwareAddr, bool) {
+	ip = ip.To16()
+	if ip == nil || ip[11] != 0xff || ip[12] != 0xfe {
+		return nil, false
+	}
+	return net.HardwareAddr{ip[8] ^ 0x02, ip[9], ip[10], ip[13], ip[14], ip[15]}, true
+}
+
+func eui64InterfaceID(mac net.HardwareAddr) [8]byte {
+	return [8]byte{mac[0] ^ 0x02, mac[1], mac[2], 0xff, 0xfe, mac[3], mac[4], mac[5]}
+}
+
+// macOffset moves the NIC-specific half of a MAC by d, keeping the OUI.
+func macOffset(mac net.HardwareAddr, d int64) net.HardwareAddr {
+	nic := (int64(mac[3])<<16 | int64(mac[4])<<8 | int64(mac[5])) + d
+	nic &= 0xffffff
+	return net.HardwareAddr{mac[0], mac[1], mac[2], byte(nic >> 16), byte(nic >> 8), byte(nic)}
+}
+
+func withInterfaceID(subnet net.IP, iid [8]byte) net.IP {
+	out := make(net.IP, net.IPv6len)
+	copy(out, subnet.To16())
+	copy(out[8:], iid[:])
+	return out
+}
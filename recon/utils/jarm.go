@@ -0,0 +1,648 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JARM sends ten crafted ClientHellos and hashes how the server answers
+// each one. This follows the reference implementation
+// (github.com/salesforce/jarm) byte for byte, including its parsing quirks,
+// so hashes match the ones published elsewhere.
+
+type jarmProbe struct {
+	version        string // TLS_1.1, TLS_1.2 or TLS_1.3
+	ciphers        string // ALL or NO1.3
+	cipherOrder    string
+	grease         bool
+	rareALPN       bool
+	support        string // 1.2_SUPPORT, 1.3_SUPPORT or NO_SUPPORT
+	extensionOrder string
+}
+
+var jarmProbes = []jarmProbe{
+	{"TLS_1.2", "ALL", "FORWARD", false, false, "1.2_SUPPORT", "REVERSE"},
+	{"TLS_1.2", "ALL", "REVERSE", false, false, "1.2_SUPPORT", "FORWARD"},
+	{"TLS_1.2", "ALL", "TOP_HALF", false, false, "NO_SUPPORT", "FORWARD"},
+	{"TLS_1.2", "ALL", "BOTTOM_HALF", false, true, "NO_SUPPORT", "FORWARD"},
+	{"TLS_1.2", "ALL", "MIDDLE_OUT", true, true, "NO_SUPPORT", "REVERSE"},
+	{"TLS_1.1", "ALL", "FORWARD", false, false, "NO_SUPPORT", "FORWARD"},
+	{"TLS_1.3", "ALL", "FORWARD", false, false, "1.3_SUPPORT", "REVERSE"},
+	{"TLS_1.3", "ALL", "REVERSE", false, false, "1.3_SUPPORT", "FORWARD"},
+	{"TLS_1.3", "NO1.3", "FORWARD", false, false, "1.3_SUPPORT", "FORWARD"},
+	{"TLS_1.3", "ALL", "MIDDLE_OUT", true, false, "1.3_SUPPORT", "REVERSE"},
+}
+
+// jarmProbeJA4S is the probe whose ServerHello JA4S is computed from: a
+// plain TLS 1.3-capable hello offering every cipher in the usual order.
+const jarmProbeJA4S = 6
+
+var jarmCiphersAll = []uint16{
+	0x0016, 0x0033, 0x0067, 0xc09e, 0xc0a2, 0x009e, 0x0039, 0x006b, 0xc09f, 0xc0a3, 0x009f, 0x0045, 0x00be, 0x0088,
+	0x00c4, 0x009a, 0xc008, 0xc009, 0xc023, 0xc0ac, 0xc0ae, 0xc02b, 0xc00a, 0xc024, 0xc0ad, 0xc0af, 0xc02c, 0xc072,
+	0xc073, 0xcca9, 0x1302, 0x1301, 0xcc14, 0xc007, 0xc012, 0xc013, 0xc027, 0xc02f, 0xc014, 0xc028, 0xc030, 0xc060,
+	0xc061, 0xc076, 0xc077, 0xcca8, 0x1305, 0x1304, 0x1303, 0xcc13, 0xc011, 0x000a, 0x002f, 0x003c, 0xc09c, 0xc0a0,
+	0x009c, 0x0035, 0x003d, 0xc09d, 0xc0a1, 0x009d, 0x0041, 0x00ba, 0x0084, 0x00c0, 0x0007, 0x0004, 0x0005,
+}
+
+// jarmCipherIndex is the cipher list the hash encodes selected ciphers against.
+var jarmCipherIndex = []uint16{
+	0x0004, 0x0005, 0x0007, 0x000a, 0x0016, 0x002f, 0x0033, 0x0035, 0x0039, 0x003c, 0x003d, 0x0041, 0x0045, 0x0067,
+	0x006b, 0x0084, 0x0088, 0x009a, 0x009c, 0x009d, 0x009e, 0x009f, 0x00ba, 0x00be, 0x00c0, 0x00c4, 0xc007, 0xc008,
+	0xc009, 0xc00a, 0xc011, 0xc012, 0xc013, 0xc014, 0xc023, 0xc024, 0xc027, 0xc028, 0xc02b, 0xc02c, 0xc02f, 0xc030,
+	0xc060, 0xc061, 0xc072, 0xc073, 0xc076, 0xc077, 0xc09c, 0xc09d, 0xc09e, 0xc09f, 0xc0a0, 0xc0a1, 0xc0a2, 0xc0a3,
+	0xc0ac, 0xc0ad, 0xc0ae, 0xc0af, 0xcc13, 0xcc14, 0xcca8, 0xcca9, 0x1301, 0x1302, 0x1303, 0x1304, 0x1305,
+}
+
+var (
+	jarmALPNs     = []string{"http/0.9", "http/1.0", "http/1.1", "spdy/1", "spdy/2", "spdy/3", "h2", "h2c", "hq"}
+	jarmRareALPNs = []string{"http/0.9", "http/1.0", "spdy/1", "spdy/2", "spdy/3", "h2c", "hq"}
+)
+
+// jarmEmptyHash is the JARM of a port that never completed a ServerHello.
+var jarmEmptyHash = strings.Repeat("0", 62)
+
+// errJARMTimeout aborts the remaining probes, as the reference does.
+var errJARMTimeout = errors.New("jarm probe timed out")
+
+// TLSFingerprint is what fingerprintTLS learns about one TLS endpoint.
+type TLSFingerprint struct {
+	JARM        string
+	JA4S        string
+	JA4X        string
+	TLSVersion  string
+	CipherSuite string
+	ALPN        string
+	CertSubject string
+	CertIssuer  string
+}
+
+// fingerprintTLS computes JARM and JA4S from raw handshakes with host:port
+// and JA4X from the leaf certificate. host doubles as the SNI value.
+func fingerprintTLS(ctx context.Context, host string, port int, timeout time.Duration) (*TLSFingerprint, error) {
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	fp := &TLSFingerprint{}
+
+	raw := make([]string, len(jarmProbes))
+	timedOut := false
+	for i, probe := range jarmProbes {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		reply, err := sendJARMProbe(ctx, address, buildJARMClientHello(host, probe), timeout)
+		if errors.Is(err, errJARMTimeout) {
+			timedOut = true
+			break
+		}
+		raw[i] = jarmReadPacket(reply)
+		if i == jarmProbeJA4S && err == nil {
+			if hello, ok := parseServerHello(reply); ok {
+				fp.JA4S = hello.ja4s()
+			}
+		}
+	}
+	if timedOut {
+		fp.JARM = jarmEmptyHash
+	} else {
+		fp.JARM = jarmHash(raw)
+	}
+
+	// JA4X needs the certificate, which TLS 1.3 encrypts, so it comes from
+	// a regular handshake.
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: timeout},
+		Config:    &tls.Config{InsecureSkipVerify: true},
+	}
+	if net.ParseIP(host) == nil {
+		dialer.Config.ServerName = host
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		if fp.JARM == jarmEmptyHash && fp.JA4S == "" {
+			return nil, fmt.Errorf("no TLS handshake with %s: %v", address, err)
+		}
+		return fp, nil
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	fp.TLSVersion = tls.VersionName(state.Version)
+	fp.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	fp.ALPN = state.NegotiatedProtocol
+	if len(state.PeerCertificates) > 0 {
+		leaf := state.PeerCertificates[0]
+		fp.JA4X = ja4x(leaf)
+		fp.CertSubject = leaf.Subject.String()
+		fp.CertIssuer = leaf.Issuer.String()
+	}
+	return fp, nil
+}
+
+// sendJARMProbe writes one ClientHello and returns the first read of the
+// reply, like the reference's single recv(1484).
+func sendJARMProbe(ctx context.Context, address string, hello []byte, timeout time.Duration) ([]byte, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, errJARMTimeout
+		}
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := conn.Write(hello); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 1484)
+	n, err := conn.Read(buf)
+	if err != nil && n == 0 {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, errJARMTimeout
+		}
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+func buildJARMClientHello(host string, probe jarmProbe) []byte {
+	var recordVersion, helloVersion []byte
+	switch probe.version {
+	case "TLS_1.3":
+		recordVersion, helloVersion = []byte{0x03, 0x01}, []byte{0x03, 0x03}
+	case "TLS_1.1":
+		recordVersion, helloVersion = []byte{0x03, 0x02}, []byte{0x03, 0x02}
+	default:
+		recordVersion, helloVersion = []byte{0x03, 0x03}, []byte{0x03, 0x03}
+	}
+
+	hello := append([]byte{}, helloVersion...)
+	hello = append(hello, randomBytes(32)...)
+	hello = append(hello, 32)
+	hello = append(hello, randomBytes(32)...)
+
+	ciphers := jarmCipherList(probe)
+	hello = binary.BigEndian.AppendUint16(hello, uint16(len(ciphers)))
+	hello = append(hello, ciphers...)
+	hello = append(hello, 0x01, 0x00) // one compression method: null
+	hello = append(hello, jarmExtensions(host, probe)...)
+
+	handshake := []byte{0x01, 0x00}
+	handshake = binary.BigEndian.AppendUint16(handshake, uint16(len(hello)))
+	handshake = append(handshake, hello...)
+
+	record := append([]byte{0x16}, recordVersion...)
+	record = binary.BigEndian.AppendUint16(record, uint16(len(handshake)))
+	return append(record, handshake...)
+}
+
+func jarmCipherList(probe jarmProbe) []byte {
+	var suites []uint16
+	for _, c := range jarmCiphersAll {
+		if probe.ciphers == "NO1.3" && c>>8 == 0x13 {
+			continue
+		}
+		suites = append(suites, c)
+	}
+	if probe.cipherOrder != "FORWARD" {
+		suites = jarmMung(suites, probe.cipherOrder)
+	}
+
+	var out []byte
+	if probe.grease {
+		out = append(out, randomGrease()...)
+	}
+	for _, c := range suites {
+		out = binary.BigEndian.AppendUint16(out, c)
+	}
+	return out
+}
+
+func jarmExtensions(host string, probe jarmProbe) []byte {
+	var ext []byte
+	if probe.grease {
+		ext = append(ext, randomGrease()...)
+		ext = append(ext, 0x00, 0x00)
+	}
+
+	// server_name
+	ext = append(ext, 0x00, 0x00)
+	ext = binary.BigEndian.AppendUint16(ext, uint16(len(host)+5))
+	ext = binary.BigEndian.AppendUint16(ext, uint16(len(host)+3))
+	ext = append(ext, 0x00)
+	ext = binary.BigEndian.AppendUint16(ext, uint16(len(host)))
+	ext = append(ext, host...)
+
+	ext = append(ext, 0x00, 0x17, 0x00, 0x00)                                                             // extended_master_secret
+	ext = append(ext, 0x00, 0x01, 0x00, 0x01, 0x01)                                                       // max_fragment_length
+	ext = append(ext, 0xff, 0x01, 0x00, 0x01, 0x00)                                                       // renegotiation_info
+	ext = append(ext, 0x00, 0x0a, 0x00, 0x0a, 0x00, 0x08, 0x00, 0x1d, 0x00, 0x17, 0x00, 0x18, 0x00, 0x19) // supported_groups
+	ext = append(ext, 0x00, 0x0b, 0x00, 0x02, 0x01, 0x00)                                                 // ec_point_formats
+	ext = append(ext, 0x00, 0x23, 0x00, 0x00)                                                             // session_ticket
+
+	// application_layer_protocol_negotiation
+	alpns := jarmALPNs
+	if probe.rareALPN {
+		alpns = jarmRareALPNs
+	}
+	if probe.extensionOrder != "FORWARD" {
+		alpns = jarmMung(alpns, probe.extensionOrder)
+	}
+	var alpnList []byte
+	for _, p := range alpns {
+		alpnList = append(alpnList, byte(len(p)))
+		alpnList = append(alpnList, p...)
+	}
+	ext = append(ext, 0x00, 0x10)
+	ext = binary.BigEndian.AppendUint16(ext, uint16(len(alpnList)+2))
+	ext = binary.BigEndian.AppendUint16(ext, uint16(len(alpnList)))
+	ext = append(ext, alpnList...)
+
+	// signature_algorithms
+	ext = append(ext, 0x00, 0x0d, 0x00, 0x14, 0x00, 0x12, 0x04, 0x03, 0x08, 0x04, 0x04, 0x01,
+		0x05, 0x03, 0x08, 0x05, 0x05, 0x01, 0x08, 0x06, 0x06, 0x01, 0x02, 0x01)
+
+	// key_share: x25519 with a random key
+	var share []byte
+	if probe.grease {
+		share = append(share, randomGrease()...)
+		share = append(share, 0x00, 0x01, 0x00)
+	}
+	share = append(share, 0x00, 0x1d, 0x00, 0x20)
+	share = append(share, randomBytes(32)...)
+	ext = append(ext, 0x00, 0x33)
+	ext = binary.BigEndian.AppendUint16(ext, uint16(len(share)+2))
+	ext = binary.BigEndian.AppendUint16(ext, uint16(len(share)))
+	ext = append(ext, share...)
+
+	ext = append(ext, 0x00, 0x2d, 0x00, 0x02, 0x01, 0x01) // psk_key_exchange_modes
+
+	if probe.version == "TLS_1.3" || probe.support == "1.2_SUPPORT" {
+		versions := []uint16{0x0301, 0x0302, 0x0303}
+		if probe.support != "1.2_SUPPORT" {
+			versions = append(versions, 0x0304)
+		}
+		if probe.extensionOrder != "FORWARD" {
+			versions = jarmMung(versions, probe.extensionOrder)
+		}
+		var list []byte
+		if probe.grease {
+			list = append(list, randomGrease()...)
+		}
+		for _, v := range versions {
+			list = binary.BigEndian.AppendUint16(list, v)
+		}
+		ext = append(ext, 0x00, 0x2b)
+		ext = binary.BigEndian.AppendUint16(ext, uint16(len(list)+1))
+		ext = append(ext, byte(len(list)))
+		ext = append(ext, list...)
+	}
+
+	out := binary.BigEndian.AppendUint16(nil, uint16(len(ext)))
+	return append(out, ext...)
+}
+
+// jarmMung reorders a list the way the reference's cipher_mung does.
+func jarmMung[T any](items []T, order string) []T {
+	n := len(items)
+	var out []T
+	switch order {
+	case "REVERSE":
+		for i := n - 1; i >= 0; i-- {
+			out = append(out, items[i])
+		}
+	case "BOTTOM_HALF":
+		if n%2 == 1 {
+			out = append(out, items[n/2+1:]...)
+		} else {
+			out = append(out, items[n/2:]...)
+		}
+	case "TOP_HALF":
+		if n%2 == 1 {
+			out = append(out, items[n/2])
+		}
+		out = append(out, jarmMung(jarmMung(items, "REVERSE"), "BOTTOM_HALF")...)
+	case "MIDDLE_OUT":
+		middle := n / 2
+		if n%2 == 1 {
+			out = append(out, items[middle])
+			for i := 1; i <= middle; i++ {
+				out = append(out, items[middle+i], items[middle-i])
+			}
+		} else {
+			for i := 1; i <= middle; i++ {
+				out = append(out, items[middle-1+i], items[middle-i])
+			}
+		}
+	}
+	return out
+}
+
+func randomGrease() []byte {
+	b := randomBytes(1)
+	v := b[0]&0xf0 | 0x0a
+	return []byte{v, v}
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
+
+// jarmReadPacket renders one reply as "cipher|version|alpn|extensions".
+// Offsets and error handling mirror the reference's read_packet.
+func jarmReadPacket(data []byte) string {
+	if len(data) < 6 || data[0] == 21 || data[0] != 22 || data[5] != 2 || len(data) < 44 {
+		return "|||"
+	}
+	helloLength := int(binary.BigEndian.Uint16(data[3:5]))
+	counter := int(data[43])
+
+	cipher := hex.EncodeToString(sliceBytes(data, counter+44, counter+46))
+	version := hex.EncodeToString(sliceBytes(data, 9, 11))
+	extensions, ok := jarmExtensionInfo(data, counter, helloLength)
+	if !ok {
+		return "|||"
+	}
+	return cipher + "|" + version + "|" + extensions
+}
+
+// jarmExtensionInfo returns "alpn|type-type-..." for the ServerHello
+// extensions. ok is false where the reference raises something other than
+// IndexError, which blanks the whole probe.
+func jarmExtensionInfo(data []byte, counter, helloLength int) (string, bool) {
+	if counter+47 >= len(data) {
+		return "|", true
+	}
+	if data[counter+47] == 11 {
+		return "|", true
+	}
+	if string(sliceBytes(data, counter+50, counter+53)) == "\x0e\xac\x0b" || string(sliceBytes(data, 82, 85)) == "\x0f\xf0\x0b" {
+		return "|", true
+	}
+	if counter+42 >= helloLength {
+		return "|", true
+	}
+
+	count := 49 + counter
+	lengthBytes := sliceBytes(data, counter+47, counter+49)
+	if len(lengthBytes) == 0 {
+		return "", false
+	}
+	maximum := int(beUint(lengthBytes)) + count - 1
+
+	var types, values [][]byte
+	for count < maximum {
+		types = append(types, sliceBytes(data, count, count+2))
+		extLength := sliceBytes(data, count+2, count+4)
+		if len(extLength) == 0 {
+			return "", false
+		}
+		n := int(beUint(extLength))
+		if n == 0 {
+			values = append(values, nil)
+			count += 4
+		} else {
+			values = append(values, sliceBytes(data, count+4, count+4+n))
+			count += n + 4
+		}
+	}
+
+	alpn := ""
+	for i, t := range types {
+		if string(t) == "\x00\x10" {
+			if values[i] == nil {
+				return "", false
+			}
+			alpn = string(sliceBytes(values[i], 3, len(values[i])))
+			break
+		}
+	}
+
+	hexTypes := make([]string, len(types))
+	for i, t := range types {
+		hexTypes[i] = hex.EncodeToString(t)
+	}
+	return alpn + "|" + strings.Join(hexTypes, "-"), true
+}
+
+// sliceBytes is Python slicing: out-of-range bounds are clamped.
+func sliceBytes(b []byte, from, to int) []byte {
+	if from > len(b) {
+		from = len(b)
+	}
+	if to > len(b) {
+		to = len(b)
+	}
+	if from > to {
+		return b[from:from]
+	}
+	return b[from:to]
+}
+
+func beUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func jarmHash(raw []string) string {
+	empty := true
+	for _, r := range raw {
+		if r != "|||" {
+			empty = false
+			break
+		}
+	}
+	if empty {
+		return jarmEmptyHash
+	}
+
+	var fuzzy strings.Builder
+	var alpnsAndExtensions strings.Builder
+	for _, r := range raw {
+		parts := strings.SplitN(r, "|", 4)
+		for len(parts) < 4 {
+			parts = append(parts, "")
+		}
+		fuzzy.WriteString(jarmCipherByte(parts[0]))
+		fuzzy.WriteString(jarmVersionByte(parts[1]))
+		alpnsAndExtensions.WriteString(parts[2])
+		alpnsAndExtensions.WriteString(parts[3])
+	}
+	sum := sha256.Sum256([]byte(alpnsAndExtensions.String()))
+	fuzzy.WriteString(hex.EncodeToString(sum[:])[:32])
+	return fuzzy.String()
+}
+
+func jarmCipherByte(cipher string) string {
+	if cipher == "" {
+		return "00"
+	}
+	count := len(jarmCipherIndex) + 1
+	for i, c := range jarmCipherIndex {
+		if fmt.Sprintf("%04x", c) == cipher {
+			count = i + 1
+			break
+		}
+	}
+	return fmt.Sprintf("%02x", count)
+}
+
+func jarmVersionByte(version string) string {
+	if version == "" {
+		return "0"
+	}
+	if len(version) < 4 {
+		return "0"
+	}
+	n, err := strconv.Atoi(version[3:4])
+	if err != nil || n > 5 {
+		return "0"
+	}
+	return string("abcdef"[n])
+}
+
+// serverHello is the part of a ServerHello JA4S needs.
+type serverHello struct {
+	version    uint16
+	cipher     uint16
+	extensions []uint16
+	alpn       string
+}
+
+func parseServerHello(data []byte) (serverHello, bool) {
+	var hello serverHello
+	// Record header (5) + handshake header (4) + version (2) + random (32)
+	if len(data) < 44 || data[0] != 22 || data[5] != 2 {
+		return hello, false
+	}
+	hello.version = binary.BigEndian.Uint16(data[9:11])
+	i := 43
+	i += 1 + int(data[i]) // session ID
+	if i+3 > len(data) {
+		return hello, false
+	}
+	hello.cipher = binary.BigEndian.Uint16(data[i:])
+	i += 3 // cipher + compression method
+	if i+2 > len(data) {
+		return hello, true
+	}
+	end := i + 2 + int(binary.BigEndian.Uint16(data[i:]))
+	i += 2
+	for i+4 <= end && i+4 <= len(data) {
+		extType := binary.BigEndian.Uint16(data[i:])
+		extLen := int(binary.BigEndian.Uint16(data[i+2:]))
+		value := sliceBytes(data, i+4, i+4+extLen)
+		hello.extensions = append(hello.extensions, extType)
+		switch extType {
+		case 0x002b: // supported_versions
+			if len(value) == 2 {
+				hello.version = binary.BigEndian.Uint16(value)
+			}
+		case 0x0010: // ALPN
+			if len(value) > 3 {
+				hello.alpn = string(value[3:])
+			}
+		}
+		i += 4 + extLen
+	}
+	return hello, true
+}
+
+// ja4s formats a ServerHello as t{version}{ext count}{alpn}_{cipher}_{ext hash}.
+func (h serverHello) ja4s() string {
+	version := "00"
+	switch h.version {
+	case 0x0304:
+		version = "13"
+	case 0x0303:
+		version = "12"
+	case 0x0302:
+		version = "11"
+	case 0x0301:
+		version = "10"
+	case 0x0300:
+		version = "s3"
+	}
+	count := len(h.extensions)
+	if count > 99 {
+		count = 99
+	}
+	alpn := "00"
+	if h.alpn != "" {
+		alpn = string(h.alpn[0]) + string(h.alpn[len(h.alpn)-1])
+	}
+
+	extensions := make([]string, len(h.extensions))
+	for i, e := range h.extensions {
+		extensions[i] = fmt.Sprintf("%04x", e)
+	}
+	return fmt.Sprintf("t%s%02d%s_%04x_%s", version, count, alpn, h.cipher, ja4Hash(extensions))
+}
+
+// ja4x hashes the OIDs of the issuer RDNs, subject RDNs and extensions of
+// a certificate.
+func ja4x(cert *x509.Certificate) string {
+	return ja4Hash(rdnOIDs(cert.RawIssuer)) + "_" + ja4Hash(rdnOIDs(cert.RawSubject)) + "_" + ja4Hash(extensionOIDs(cert))
+}
+
+func rdnOIDs(raw []byte) []string {
+	var rdns pkix.RDNSequence
+	if _, err := asn1.Unmarshal(raw, &rdns); err != nil {
+		return nil
+	}
+	var oids []string
+	for _, set := range rdns {
+		for _, attr := range set {
+			oids = append(oids, oidHex(attr.Type))
+		}
+	}
+	return oids
+}
+
+func extensionOIDs(cert *x509.Certificate) []string {
+	oids := make([]string, 0, len(cert.Extensions))
+	for _, ext := range cert.Extensions {
+		oids = append(oids, oidHex(ext.Id))
+	}
+	return oids
+}
+
+// oidHex is the hex of an OID's DER content octets, e.g. 550403 for CN.
+func oidHex(oid asn1.ObjectIdentifier) string {
+	der, err := asn1.Marshal(oid)
+	if err != nil || len(der) < 2 {
+		return ""
+	}
+	return hex.EncodeToString(der[2:])
+}
+
+// ja4Hash is the first 12 hex characters of the SHA-256 of the
+// comma-joined values, or twelve zeros for an empty list.
+func ja4Hash(values []string) string {
+	if len(values) == 0 {
+		return "000000000000"
+	}
+	sum := sha256.Sum256([]byte(strings.Join(values, ",")))
+	return hex.EncodeToString(sum[:])[:12]
+}
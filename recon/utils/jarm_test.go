@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTLSExtension encodes one ServerHello extension.
+func testTLSExtension(extType uint16, value []byte) []byte {
+	ext := binary.BigEndian.AppendUint16(nil, extType)
+	ext = binary.BigEndian.AppendUint16(ext, uint16(len(value)))
+	return append(ext, value...)
+}
+
+// testServerHello builds a TLS record holding a TLS 1.2 ServerHello. A nil
+// extensions slice leaves out the extensions block; extLen overrides its
+// length when non-negative.
+func testServerHello(sessionID []byte, cipher uint16, extLen int, extensions ...[]byte) []byte {
+	body := binary.BigEndian.AppendUint16(nil, 0x0303)
+	body = append(body, make([]byte, 32)...)
+	body = append(body, byte(len(sessionID)))
+	body = append(body, sessionID...)
+	body = binary.BigEndian.AppendUint16(body, cipher)
+	body = append(body, 0)
+	if extensions != nil {
+		var exts []byte
+		for _, ext := range extensions {
+			exts = append(exts, ext...)
+		}
+		if extLen < 0 {
+			extLen = len(exts)
+		}
+		body = binary.BigEndian.AppendUint16(body, uint16(extLen))
+		body = append(body, exts...)
+	}
+
+	handshake := []byte{2, byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body))}
+	handshake = append(handshake, body...)
+	record := []byte{22, 0x03, 0x03}
+	record = binary.BigEndian.AppendUint16(record, uint16(len(handshake)))
+	return append(record, handshake...)
+}
+
+func TestJARMReadPacket(t *testing.T) {
+	sessionID := make([]byte, 32)
+	alpn := testTLSExtension(0x0010, []byte{0, 3, 2, 'h', '2'})
+	renegotiation := testTLSExtension(0xff01, []byte{0})
+	notServerHello := testServerHello(nil, 0xc02f, -1, nil)
+	notServerHello[5] = 1
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{name: "ALPN and renegotiation", data: testServerHello(sessionID, 0xc02f, -1, renegotiation, alpn), want: "c02f|0303|h2|ff01-0010"},
+		{name: "no extensions", data: testServerHello(nil, 0xc02f, -1, nil), want: "c02f|0303||"},
+		{name: "empty ALPN value", data: testServerHello(sessionID, 0xc02f, -1, renegotiation, testTLSExtension(0x0010, nil)), want: "|||"},
+		{name: "extension list past end", data: testServerHello(sessionID, 0xc02f, 100, renegotiation), want: "|||"},
+		{name: "alert", data: []byte{21, 0x03, 0x03, 0, 2, 2, 40}, want: "|||"},
+		{name: "not a ServerHello", data: notServerHello, want: "|||"},
+		{name: "shorter than the random", data: testServerHello(nil, 0xc02f, -1, nil)[:43], want: "|||"},
+		{name: "empty", data: nil, want: "|||"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jarmReadPacket(tt.data); got != tt.want {
+				t.Errorf("jarmReadPacket() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJARMHash(t *testing.T) {
+	blank := make([]string, len(jarmProbes))
+	for i := range blank {
+		blank[i] = "|||"
+	}
+	if got := jarmHash(blank); got != jarmEmptyHash {
+		t.Errorf("jarmHash(no replies) = %q, want the empty hash", got)
+	}
+
+	// c02f is cipher 41 of the reference list; an unknown cipher sorts after
+	// all 69 of them and a missing version is 0.
+	raw := append([]string{"c02f|0303|h2|ff01-0010", "malformed"}, blank[2:]...)
+	want := "29d" + "460" + strings.Repeat("000", 8) + "a53adc13637d20998990e9e6aa9ab04c"
+	if got := jarmHash(raw); got != want {
+		t.Errorf("jarmHash() = %q, want %q", got, want)
+	}
+}
+
+func TestParseServerHello(t *testing.T) {
+	tls13 := testTLSExtension(0x002b, []byte{0x03, 0x04})
+	alpn := testTLSExtension(0x0010, []byte{0, 3, 2, 'h', '2'})
+	// The ALPN extension claims 16 bytes but the record ends after two.
+	overrun := append(testTLSExtension(0x0010, nil)[:2], 0, 16, 0, 3)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		want   string
+		wantOK bool
+	}{
+		{name: "TLS 1.3", data: testServerHello(make([]byte, 32), 0x1301, -1, tls13, alpn), want: "t1302h2_1301_14e9539264dc", wantOK: true},
+		{name: "TLS 1.2", data: testServerHello(nil, 0xc02f, -1, testTLSExtension(0xff01, []byte{0}), alpn), want: "t1202h2_c02f_87b1562aab70", wantOK: true},
+		{name: "no extensions", data: testServerHello(nil, 0xc02f, -1, nil), want: "t120000_c02f_000000000000", wantOK: true},
+		{name: "extension past end", data: testServerHello(nil, 0x1301, 20, tls13, overrun), want: "t130200_1301_14e9539264dc", wantOK: true},
+		{name: "supported_versions too short", data: testServerHello(nil, 0x1301, -1, testTLSExtension(0x002b, []byte{0x03})), want: "t120100_1301_b9a491fefe05", wantOK: true},
+		{name: "session ID past end", data: testServerHello(make([]byte, 32), 0x1301, -1, nil)[:60]},
+		{name: "not a ServerHello", data: append([]byte{22, 3, 3, 0, 40, 1}, make([]byte, 40)...)},
+		{name: "short record", data: []byte{22, 3, 3, 0, 2, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hello, ok := parseServerHello(tt.data)
+			if ok != tt.wantOK {
+				t.Fatalf("parseServerHello() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && hello.ja4s() != tt.want {
+				t.Errorf("ja4s() = %q, want %q", hello.ja4s(), tt.want)
+			}
+		})
+	}
+}
+
+func TestServerHelloTruncated(t *testing.T) {
+	data := testServerHello(make([]byte, 32), 0x1301, -1,
+		testTLSExtension(0x002b, []byte{0x03, 0x04}),
+		testTLSExtension(0x0010, []byte{0, 3, 2, 'h', '2'}))
+	for n := 0; n < len(data); n++ {
+		jarmReadPacket(data[:n])
+		if _, ok := parseServerHello(data[:n]); ok && n < 44+32+3 {
+			t.Errorf("parseServerHello(%d of %d bytes) succeeded before the cipher suite", n, len(data))
+		}
+	}
+}
+
+func TestJA4X(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	cert := testCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "example.com"},
+		NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour),
+		KeyUsage: x509.KeyUsageDigitalSignature,
+	}, nil, &key.PublicKey, key)
+
+	tests := []struct {
+		name string
+		cert *x509.Certificate
+		want string
+	}{
+		{name: "CN only", cert: cert, want: "7022c563de38_7022c563de38_7b9fa3fff95f"},
+		{name: "malformed names", cert: &x509.Certificate{RawIssuer: []byte{0x30, 0x05, 0x31}, RawSubject: []byte{0x02}},
+			want: "000000000000_000000000000_000000000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ja4x(tt.cert); got != tt.want {
+				t.Errorf("ja4x() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,101 +3,17 @@ package utils
 import (
 	"bytes"
 	"context"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
-	"net/http"
 	"net/url"
-	"os/exec"
 	"regexp"
 	"sort"
 	"strings"
 	"time"
-
-	"github.com/google/uuid"
-	"github.com/gorilla/mux"
-	"github.com/jackc/pgx/v5"
 )
 
-type GoSpiderScanStatus struct {
-	ID                string         `json:"id"`
-	ScanID            string         `json:"scan_id"`
-	Domain            string         `json:"domain"`
-	Status            string         `json:"status"`
-	Result            sql.NullString `json:"result,omitempty"`
-	Error             sql.NullString `json:"error,omitempty"`
-	StdOut            sql.NullString `json:"stdout,omitempty"`
-	StdErr            sql.NullString `json:"stderr,omitempty"`
-	Command           sql.NullString `json:"command,omitempty"`
-	ExecTime          sql.NullString `json:"execution_time,omitempty"`
-	CreatedAt         time.Time      `json:"created_at"`
-	ScopeTargetID     string         `json:"scope_target_id"`
-	AutoScanSessionID sql.NullString `json:"auto_scan_session_id"`
-}
-
-type SubdomainizerScanStatus struct {
-	ID                string         `json:"id"`
-	ScanID            string         `json:"scan_id"`
-	Domain            string         `json:"domain"`
-	Status            string         `json:"status"`
-	Result            sql.NullString `json:"result,omitempty"`
-	Error             sql.NullString `json:"error,omitempty"`
-	StdOut            sql.NullString `json:"stdout,omitempty"`
-	StdErr            sql.NullString `json:"stderr,omitempty"`
-	Command           sql.NullString `json:"command,omitempty"`
-	ExecTime          sql.NullString `json:"execution_time,omitempty"`
-	CreatedAt         time.Time      `json:"created_at"`
-	ScopeTargetID     string         `json:"scope_target_id"`
-	AutoScanSessionID sql.NullString `json:"auto_scan_session_id"`
-}
-
-func RunGoSpiderScan(w http.ResponseWriter, r *http.Request) {
-	var payload struct {
-		FQDN              string  `json:"fqdn" binding:"required"`
-		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.FQDN == "" {
-		http.Error(w, "Invalid request body. `fqdn` is required.", http.StatusBadRequest)
-		return
-	}
-
-	domain := payload.FQDN
-	wildcardDomain := fmt.Sprintf("*.%s", domain)
-
-	query := `SELECT id FROM scope_targets WHERE type = 'Wildcard' AND scope_target = $1`
-	var scopeTargetID string
-	err := dbPool.QueryRow(context.Background(), query, wildcardDomain).Scan(&scopeTargetID)
-	if err != nil {
-		log.Printf("[ERROR] No matching wildcard scope target found for domain %s", domain)
-		http.Error(w, "No matching wildcard scope target found.", http.StatusBadRequest)
-		return
-	}
-
-	scanID := uuid.New().String()
-	var insertQuery string
-	var args []interface{}
-	if payload.AutoScanSessionID != nil && *payload.AutoScanSessionID != "" {
-		insertQuery = `INSERT INTO gospider_scans (scan_id, domain, status, scope_target_id, auto_scan_session_id) VALUES ($1, $2, $3, $4, $5)`
-		args = []interface{}{scanID, domain, "pending", scopeTargetID, *payload.AutoScanSessionID}
-	} else {
-		insertQuery = `INSERT INTO gospider_scans (scan_id, domain, status, scope_target_id) VALUES ($1, $2, $3, $4)`
-		args = []interface{}{scanID, domain, "pending", scopeTargetID}
-	}
-	_, err = dbPool.Exec(context.Background(), insertQuery, args...)
-	if err != nil {
-		log.Printf("[ERROR] Failed to create scan record: %v", err)
-		http.Error(w, "Failed to create scan record.", http.StatusInternalServerError)
-		return
-	}
-
-	go executeAndParseGoSpiderScan(scanID, domain)
-
-	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
-}
-
-func executeAndParseGoSpiderScan(scanID, domain string) {
+func executeAndParseGoSpiderScan(ctx context.Context, scanID, domain string) {
 	log.Printf("[INFO] Starting GoSpider scan for domain %s (scan ID: %s)", domain, scanID)
 	startTime := time.Now()
 
@@ -152,9 +68,8 @@ func executeAndParseGoSpiderScan(scanID, domain string) {
 		log.Printf("[INFO] Running GoSpider against URL: %s", httpxResult.URL)
 		scanStartTime := time.Now()
 
-		cmd := exec.Command(
-			"docker", "exec",
-			"ars0n-framework-v2-gospider-1",
+		cmd := NewToolCommandContext(ctx, 
+			"gospider",
 			"timeout", "300",
 			"gospider",
 			"-s", httpxResult.URL,
@@ -339,167 +254,7 @@ func updateGoSpiderScanStatus(scanID, status, result, stderr, command, execTime,
 	}
 }
 
-func GetGoSpiderScanStatus(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	scanID := vars["scan_id"]
-
-	var scan GoSpiderScanStatus
-	query := `SELECT * FROM gospider_scans WHERE scan_id = $1`
-	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
-		&scan.ID,
-		&scan.ScanID,
-		&scan.Domain,
-		&scan.Status,
-		&scan.Result,
-		&scan.Error,
-		&scan.StdOut,
-		&scan.StdErr,
-		&scan.Command,
-		&scan.ExecTime,
-		&scan.CreatedAt,
-		&scan.ScopeTargetID,
-		&scan.AutoScanSessionID,
-	)
-
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			http.Error(w, "Scan not found", http.StatusNotFound)
-		} else {
-			log.Printf("[ERROR] Failed to get scan status: %v", err)
-			http.Error(w, "Failed to get scan status", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	response := map[string]interface{}{
-		"id":                   scan.ID,
-		"scan_id":              scan.ScanID,
-		"domain":               scan.Domain,
-		"status":               scan.Status,
-		"result":               nullStringToString(scan.Result),
-		"error":                nullStringToString(scan.Error),
-		"stdout":               nullStringToString(scan.StdOut),
-		"stderr":               nullStringToString(scan.StdErr),
-		"command":              nullStringToString(scan.Command),
-		"execution_time":       nullStringToString(scan.ExecTime),
-		"created_at":           scan.CreatedAt.Format(time.RFC3339),
-		"scope_target_id":      scan.ScopeTargetID,
-		"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-func GetGoSpiderScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	scopeTargetID := vars["id"]
-
-	if scopeTargetID == "" {
-		log.Printf("[ERROR] No scope target ID provided")
-		http.Error(w, "No scope target ID provided", http.StatusBadRequest)
-		return
-	}
-
-	query := `SELECT * FROM gospider_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
-	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
-	if err != nil {
-		log.Printf("[ERROR] Failed to get scans: %v", err)
-		http.Error(w, "Failed to get scans", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	var scans []map[string]interface{}
-	for rows.Next() {
-		var scan GoSpiderScanStatus
-		err := rows.Scan(
-			&scan.ID,
-			&scan.ScanID,
-			&scan.Domain,
-			&scan.Status,
-			&scan.Result,
-			&scan.Error,
-			&scan.StdOut,
-			&scan.StdErr,
-			&scan.Command,
-			&scan.ExecTime,
-			&scan.CreatedAt,
-			&scan.ScopeTargetID,
-			&scan.AutoScanSessionID,
-		)
-		if err != nil {
-			log.Printf("[ERROR] Failed to scan row: %v", err)
-			continue
-		}
-
-		scans = append(scans, map[string]interface{}{
-			"id":                   scan.ID,
-			"scan_id":              scan.ScanID,
-			"domain":               scan.Domain,
-			"status":               scan.Status,
-			"result":               nullStringToString(scan.Result),
-			"error":                nullStringToString(scan.Error),
-			"stdout":               nullStringToString(scan.StdOut),
-			"stderr":               nullStringToString(scan.StdErr),
-			"command":              nullStringToString(scan.Command),
-			"execution_time":       nullStringToString(scan.ExecTime),
-			"created_at":           scan.CreatedAt.Format(time.RFC3339),
-			"scope_target_id":      scan.ScopeTargetID,
-			"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-		})
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(scans)
-}
-
-func RunSubdomainizerScan(w http.ResponseWriter, r *http.Request) {
-	var payload struct {
-		FQDN              string  `json:"fqdn" binding:"required"`
-		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.FQDN == "" {
-		http.Error(w, "Invalid request body. `fqdn` is required.", http.StatusBadRequest)
-		return
-	}
-
-	domain := payload.FQDN
-	wildcardDomain := fmt.Sprintf("*.%s", domain)
-
-	query := `SELECT id FROM scope_targets WHERE type = 'Wildcard' AND scope_target = $1`
-	var scopeTargetID string
-	err := dbPool.QueryRow(context.Background(), query, wildcardDomain).Scan(&scopeTargetID)
-	if err != nil {
-		log.Printf("[ERROR] No matching wildcard scope target found for domain %s", domain)
-		http.Error(w, "No matching wildcard scope target found.", http.StatusBadRequest)
-		return
-	}
-
-	scanID := uuid.New().String()
-	var insertQuery string
-	var args []interface{}
-	if payload.AutoScanSessionID != nil && *payload.AutoScanSessionID != "" {
-		insertQuery = `INSERT INTO subdomainizer_scans (scan_id, domain, status, scope_target_id, auto_scan_session_id) VALUES ($1, $2, $3, $4, $5)`
-		args = []interface{}{scanID, domain, "pending", scopeTargetID, *payload.AutoScanSessionID}
-	} else {
-		insertQuery = `INSERT INTO subdomainizer_scans (scan_id, domain, status, scope_target_id) VALUES ($1, $2, $3, $4)`
-		args = []interface{}{scanID, domain, "pending", scopeTargetID}
-	}
-	_, err = dbPool.Exec(context.Background(), insertQuery, args...)
-	if err != nil {
-		log.Printf("[ERROR] Failed to create scan record: %v", err)
-		http.Error(w, "Failed to create scan record.", http.StatusInternalServerError)
-		return
-	}
-
-	go executeAndParseSubdomainizerScan(scanID, domain)
-
-	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
-}
-
-func executeAndParseSubdomainizerScan(scanID, domain string) {
+func executeAndParseSubdomainizerScan(ctx context.Context, scanID, domain string) {
 	log.Printf("[INFO] Starting Subdomainizer scan for domain %s (scan ID: %s)", domain, scanID)
 	startTime := time.Now()
 
@@ -519,9 +274,8 @@ func executeAndParseSubdomainizerScan(scanID, domain string) {
 		return
 	}
 
-	mkdirCmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-subdomainizer-1",
+	mkdirCmd := NewToolCommandContext(ctx, 
+		"subdomainizer",
 		"mkdir", "-p", "/tmp/subdomainizer-mounts",
 	)
 	if err := mkdirCmd.Run(); err != nil {
@@ -530,9 +284,8 @@ func executeAndParseSubdomainizerScan(scanID, domain string) {
 		return
 	}
 
-	chmodCmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-subdomainizer-1",
+	chmodCmd := NewToolCommandContext(ctx, 
+		"subdomainizer",
 		"chmod", "777", "/tmp/subdomainizer-mounts",
 	)
 	if err := chmodCmd.Run(); err != nil {
@@ -568,9 +321,8 @@ func executeAndParseSubdomainizerScan(scanID, domain string) {
 
 		log.Printf("[INFO] Running Subdomainizer against URL: %s", httpxResult.URL)
 
-		cmd := exec.Command(
-			"docker", "exec",
-			"ars0n-framework-v2-subdomainizer-1",
+		cmd := NewToolCommandContext(ctx, 
+			"subdomainizer",
 			"timeout", "300",
 			"python3", "SubDomainizer.py",
 			"-u", httpxResult.URL,
@@ -593,9 +345,8 @@ func executeAndParseSubdomainizerScan(scanID, domain string) {
 			continue
 		}
 
-		catCmd := exec.Command(
-			"docker", "exec",
-			"ars0n-framework-v2-subdomainizer-1",
+		catCmd := NewToolCommandContext(ctx, 
+			"subdomainizer",
 			"cat", "/tmp/subdomainizer-mounts/output.txt",
 		)
 
@@ -635,9 +386,8 @@ func executeAndParseSubdomainizerScan(scanID, domain string) {
 		updateSubdomainizerScanStatus(scanID, "success", result, allStderr.String(), strings.Join(commands, "\n"), execTime, allStdout.String())
 	}
 
-	cleanupCmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-subdomainizer-1",
+	cleanupCmd := NewToolCommandContext(ctx, 
+		"subdomainizer",
 		"rm", "-rf", "/tmp/subdomainizer-mounts",
 	)
 	if err := cleanupCmd.Run(); err != nil {
@@ -657,118 +407,3 @@ func updateSubdomainizerScanStatus(scanID, status, result, stderr, command, exec
 		log.Printf("[INFO] Successfully updated Subdomainizer scan status for %s", scanID)
 	}
 }
-
-func GetSubdomainizerScanStatus(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	scanID := vars["scan_id"]
-
-	var scan SubdomainizerScanStatus
-	query := `SELECT * FROM subdomainizer_scans WHERE scan_id = $1`
-	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
-		&scan.ID,
-		&scan.ScanID,
-		&scan.Domain,
-		&scan.Status,
-		&scan.Result,
-		&scan.Error,
-		&scan.StdOut,
-		&scan.StdErr,
-		&scan.Command,
-		&scan.ExecTime,
-		&scan.CreatedAt,
-		&scan.ScopeTargetID,
-		&scan.AutoScanSessionID,
-	)
-
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			http.Error(w, "Scan not found", http.StatusNotFound)
-		} else {
-			log.Printf("[ERROR] Failed to get scan status: %v", err)
-			http.Error(w, "Failed to get scan status", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	response := map[string]interface{}{
-		"id":                   scan.ID,
-		"scan_id":              scan.ScanID,
-		"domain":               scan.Domain,
-		"status":               scan.Status,
-		"result":               nullStringToString(scan.Result),
-		"error":                nullStringToString(scan.Error),
-		"stdout":               nullStringToString(scan.StdOut),
-		"stderr":               nullStringToString(scan.StdErr),
-		"command":              nullStringToString(scan.Command),
-		"execution_time":       nullStringToString(scan.ExecTime),
-		"created_at":           scan.CreatedAt.Format(time.RFC3339),
-		"scope_target_id":      scan.ScopeTargetID,
-		"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-func GetSubdomainizerScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	scopeTargetID := vars["id"]
-
-	if scopeTargetID == "" {
-		log.Printf("[ERROR] No scope target ID provided")
-		http.Error(w, "No scope target ID provided", http.StatusBadRequest)
-		return
-	}
-
-	query := `SELECT * FROM subdomainizer_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
-	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
-	if err != nil {
-		log.Printf("[ERROR] Failed to get scans: %v", err)
-		http.Error(w, "Failed to get scans", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	var scans []map[string]interface{}
-	for rows.Next() {
-		var scan SubdomainizerScanStatus
-		err := rows.Scan(
-			&scan.ID,
-			&scan.ScanID,
-			&scan.Domain,
-			&scan.Status,
-			&scan.Result,
-			&scan.Error,
-			&scan.StdOut,
-			&scan.StdErr,
-			&scan.Command,
-			&scan.ExecTime,
-			&scan.CreatedAt,
-			&scan.ScopeTargetID,
-			&scan.AutoScanSessionID,
-		)
-		if err != nil {
-			log.Printf("[ERROR] Failed to scan row: %v", err)
-			continue
-		}
-
-		scans = append(scans, map[string]interface{}{
-			"id":                   scan.ID,
-			"scan_id":              scan.ScanID,
-			"domain":               scan.Domain,
-			"status":               scan.Status,
-			"result":               nullStringToString(scan.Result),
-			"error":                nullStringToString(scan.Error),
-			"stdout":               nullStringToString(scan.StdOut),
-			"stderr":               nullStringToString(scan.StdErr),
-			"command":              nullStringToString(scan.Command),
-			"execution_time":       nullStringToString(scan.ExecTime),
-			"created_at":           scan.CreatedAt.Format(time.RFC3339),
-			"scope_target_id":      scan.ScopeTargetID,
-			"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-		})
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(scans)
-}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os/exec"
 	"regexp"
 	"strings"
 	"time"
@@ -104,14 +103,18 @@ func RunKatanaCompanyScan(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("[KATANA-COMPANY] [INFO] Scan record verified in database with ID: %s", verifyID)
 
-	go ExecuteKatanaCompanyScan(scanID, payload.Domains, scopeTargetID)
+	if _, err := EnqueueScanJob("katana-company", scanID, ScanJobPayload{Targets: payload.Domains, ScopeTargetID: scopeTargetID}); err != nil {
+		log.Printf("[ERROR] Failed to queue katana-company scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	log.Printf("[KATANA-COMPANY] [INFO] Katana Company scan initiated successfully, returning scan ID: %s", scanID)
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteKatanaCompanyScan(scanID string, domains []string, scopeTargetID string) {
+func ExecuteKatanaCompanyScan(ctx context.Context, scanID string, domains []string, scopeTargetID string) {
 	log.Printf("[KATANA-COMPANY] [INFO] Starting Katana Company scan execution (scan ID: %s) for %d domains", scanID, len(domains))
 	startTime := time.Now()
 
@@ -156,8 +159,8 @@ func ExecuteKatanaCompanyScan(scanID string, domains []string, scopeTargetID str
 			targetURL = "https://" + domain
 		}
 
-		cmd := exec.Command(
-			"docker", "exec", "ars0n-framework-v2-katana-1",
+		cmd := NewToolCommandContext(ctx, 
+			"katana",
 			"katana",
 			"-u", targetURL,
 			"-d", "3",
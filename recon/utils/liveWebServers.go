@@ -1,7 +1,6 @@
 package utils
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
@@ -9,11 +8,9 @@ import (
 	"log"
 	"net/http"
 	"net/url"
-	"os"
-	"os/exec"
-	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -113,6 +110,9 @@ type TargetURLResponse struct {
 	ROIScore            int                    `json:"roi_score"`
 }
 
+// httpxPorts are probed on every consolidated subdomain
+var httpxPorts = []int{80, 443, 7547, 8089, 8085, 8443, 8080, 4567, 7170, 8008, 2083, 8000, 2082, 8081, 2087, 2086, 8888, 8880, 60000, 40000, 9080, 5985, 9100, 2096, 3000, 1024, 30005, 81, 21, 5000, 2095}
+
 // RunHttpxScan handles the HTTP request to start a new httpx scan
 func RunHttpxScan(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[DEBUG] Received httpx scan request")
@@ -152,7 +152,11 @@ func RunHttpxScan(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("[DEBUG] Created new scan record in database")
 
-	go ExecuteAndParseHttpxScan(scanID, domain)
+	if _, err := EnqueueScanJob("httpx", scanID, ScanJobPayload{Target: domain}); err != nil {
+		log.Printf("[ERROR] Failed to queue httpx scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 	log.Printf("[DEBUG] Started httpx scan execution in background")
 
 	w.WriteHeader(http.StatusAccepted)
@@ -160,8 +164,9 @@ func RunHttpxScan(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[DEBUG] Sent scan ID response to client")
 }
 
-// ExecuteAndParseHttpxScan runs the httpx scan and processes its results
-func ExecuteAndParseHttpxScan(scanID, domain string) {
+// ExecuteAndParseHttpxScan runs the httpx scan through the native prober
+// and processes its results
+func ExecuteAndParseHttpxScan(ctx context.Context, scanID, domain string) {
 	log.Printf("[INFO] Starting httpx scan for domain %s (scan ID: %s)", domain, scanID)
 	startTime := time.Now()
 
@@ -188,9 +193,13 @@ func ExecuteAndParseHttpxScan(scanID, domain string) {
 
 	// Get consolidated subdomains
 	log.Printf("[DEBUG] Fetching consolidated subdomains from database")
+	// Wildcard suspects are skipped unless the scope target opts in, so let
+	// a wildcard pass started by consolidation flag them first
+	WaitForWildcardFlags(ctx, scopeTargetID)
+	includeSuspects := includeWildcardSuspects(scopeTargetID)
 	rows, err := dbPool.Query(context.Background(),
-		`SELECT subdomain FROM consolidated_subdomains WHERE scope_target_id = $1`,
-		scopeTargetID)
+		`SELECT subdomain FROM consolidated_subdomains WHERE scope_target_id = $1 AND ($2 OR NOT wildcard_suspect)`,
+		scopeTargetID, includeSuspects)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get consolidated subdomains: %v", err)
 		UpdateHttpxScanStatus(scanID, "error", "", fmt.Sprintf("Failed to get consolidated subdomains: %v", err), "", time.Since(startTime).String())
@@ -207,7 +216,7 @@ func ExecuteAndParseHttpxScan(scanID, domain string) {
 		}
 		domainsToScan = append(domainsToScan, subdomain)
 	}
-	log.Printf("[DEBUG] Found %d subdomains to scan", len(domainsToScan))
+	log.Printf("[DEBUG] Found %d subdomains to scan (wildcard suspects included: %t)", len(domainsToScan), includeSuspects)
 
 	// If no consolidated subdomains found, use the base domain
 	if len(domainsToScan) == 0 {
@@ -215,137 +224,51 @@ func ExecuteAndParseHttpxScan(scanID, domain string) {
 		domainsToScan = []string{domain}
 	}
 
-	// Create temporary directory for domains file
-	tempDir := filepath.Join("/tmp", fmt.Sprintf("httpx-%s", scanID))
-	if err := os.MkdirAll(tempDir, 0755); err != nil {
-		log.Printf("[ERROR] Failed to create temp directory: %v", err)
-		UpdateHttpxScanStatus(scanID, "error", "", fmt.Sprintf("Failed to create temp directory: %v", err), "", time.Since(startTime).String())
-		return
-	}
-	log.Printf("[DEBUG] Created temporary directory: %s", tempDir)
-	defer func() {
-		log.Printf("[DEBUG] Cleaning up temporary directory: %s", tempDir)
-		os.RemoveAll(tempDir)
-	}()
-
-	// Write domains to file
-	domainsFile := filepath.Join(tempDir, "domains.txt")
-	outputFile := filepath.Join(tempDir, "httpx-output.json")
-	if err := os.WriteFile(domainsFile, []byte(strings.Join(domainsToScan, "\n")), 0644); err != nil {
-		log.Printf("[ERROR] Failed to write domains file: %v", err)
-		UpdateHttpxScanStatus(scanID, "error", "", fmt.Sprintf("Failed to write domains file: %v", err), "", time.Since(startTime).String())
-		return
-	}
-	log.Printf("[DEBUG] Wrote %d domains to file: %s", len(domainsToScan), domainsFile)
-
-	ports := []int{80, 443, 7547, 8089, 8085, 8443, 8080, 4567, 7170, 8008, 2083, 8000, 2082, 8081, 2087, 2086, 8888, 8880, 60000, 40000, 9080, 5985, 9100, 2096, 3000, 1024, 30005, 81, 21, 5000, 2095}
-	portStrings := make([]string, len(ports))
-	for i, port := range ports {
-		portStrings[i] = fmt.Sprintf("%d", port)
-	}
-	portsFlag := strings.Join(portStrings, ",")
-
-	dockerCmd := []string{
-		"docker", "exec",
-		"ars0n-framework-v2-httpx-1",
-		"httpx",
-		"-l", filepath.Join("/tmp", fmt.Sprintf("httpx-%s", scanID), "domains.txt"),
-		"-ports", portsFlag,
-		"-json",
-		"-status-code",
-		"-title",
-		"-tech-detect",
-		"-server",
-		"-content-length",
-		"-no-color",
-		"-timeout", "10",
-		"-retries", "2",
-		"-rate-limit", fmt.Sprintf("%d", rateLimit),
-		"-mc", "100,101,200,201,202,203,204,205,206,207,208,226,300,301,302,303,304,305,307,308,400,401,402,403,404,405,406,407,408,409,410,411,412,413,414,415,416,417,418,421,422,423,424,426,428,429,431,451,500,501,502,503,504,505,506,507,508,510,511",
-	}
-
-	// Add custom headers if specified
-	// HTTPX uses -H for both headers and user agent
-	if customUserAgent != "" {
-		dockerCmd = append(dockerCmd, "-H", fmt.Sprintf("User-Agent: %s", customUserAgent))
-	}
-	if customHeader != "" {
-		dockerCmd = append(dockerCmd, "-H", customHeader)
-	}
-
-	// Add output file parameter
-	dockerCmd = append(dockerCmd, "-o", filepath.Join("/tmp", fmt.Sprintf("httpx-%s", scanID), "httpx-output.json"))
-
-	cmd := exec.Command(dockerCmd[0], dockerCmd[1:]...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	log.Printf("[DEBUG] Running command: %s", strings.Join(dockerCmd, " "))
-	err = cmd.Run()
-	execTime := time.Since(startTime).String()
+	executeNativeHTTPProbe(ctx, scanID, scopeTargetID, domainsToScan, rateLimit, customUserAgent, customHeader)
+}
 
-	if err != nil {
-		errMsg := stderr.String()
-		log.Printf("[ERROR] httpx scan failed for %s: %v\nStderr: %s", domain, err, errMsg)
-		log.Printf("[DEBUG] Command stdout: %s", stdout.String())
-		UpdateHttpxScanStatus(scanID, "error", "", errMsg, strings.Join(dockerCmd, " "), execTime)
-		return
-	}
-	log.Printf("[DEBUG] httpx scan completed successfully in %s", execTime)
+// executeNativeHTTPProbe is the httpx scan run through the native prober.
+// Results are written to target_urls as they arrive and stored as JSON
+// lines in the scan's result like httpx output.
+func executeNativeHTTPProbe(ctx context.Context, scanID, scopeTargetID string, domainsToScan []string, rateLimit int, customUserAgent, customHeader string) {
+	startTime := time.Now()
+	createHTTPProbeColumns()
+	command := fmt.Sprintf("native-http-prober targets=%d ports=%d rate-limit=%d", len(domainsToScan), len(httpxPorts), rateLimit)
+	log.Printf("[INFO] Running %s", command)
+
+	prober := NewHTTPProber(HTTPProberOptions{
+		RateLimit: rateLimit,
+		UserAgent: customUserAgent,
+		Header:    customHeader,
+	})
+	var mu sync.Mutex
+	var liveURLs, lines []string
+	prober.ProbeAll(ctx, domainsToScan, httpxPorts, func(result *HTTPProbeResult) {
+		line, _ := json.Marshal(result)
+		if err := upsertTargetURLFromProbe(scopeTargetID, result); err != nil {
+			log.Printf("[WARN] Failed to update target URL for %s: %v", result.URL, err)
+		}
+		mu.Lock()
+		liveURLs = append(liveURLs, NormalizeURL(result.URL))
+		lines = append(lines, string(line))
+		mu.Unlock()
+	})
+	execTime := time.Since(startTime).String()
 
-	// Read the output file
-	log.Printf("[DEBUG] Reading output file: %s", outputFile)
-	result, err := os.ReadFile(outputFile)
-	if err != nil {
-		log.Printf("[ERROR] Failed to read output file: %v", err)
-		UpdateHttpxScanStatus(scanID, "error", "", fmt.Sprintf("Failed to read output file: %v", err), strings.Join(dockerCmd, " "), execTime)
+	if ctx.Err() != nil {
+		UpdateHttpxScanStatus(scanID, "error", strings.Join(lines, "\n"), ctx.Err().Error(), command, execTime)
 		return
 	}
-
-	resultStr := string(result)
-	if resultStr == "" {
-		log.Printf("[INFO] No results found in output file")
-		UpdateHttpxScanStatus(scanID, "completed", "", "No results found", strings.Join(dockerCmd, " "), execTime)
+	log.Printf("[INFO] Found %d live URLs", len(liveURLs))
+	if len(lines) == 0 {
+		UpdateHttpxScanStatus(scanID, "completed", "", "No results found", command, execTime)
 		return
 	}
-	log.Printf("[DEBUG] Successfully read %d bytes from output file", len(resultStr))
-
-	// Process results and update target URLs
-	var liveURLs []string
-	lines := strings.Split(resultStr, "\n")
-	log.Printf("[DEBUG] Processing %d result lines", len(lines))
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-
-		var httpxResult map[string]interface{}
-		if err := json.Unmarshal([]byte(line), &httpxResult); err != nil {
-			log.Printf("[WARN] Failed to parse result line: %v", err)
-			continue
-		}
-
-		if url, ok := httpxResult["url"].(string); ok {
-			liveURLs = append(liveURLs, url)
-			if err := UpdateTargetURLFromHttpx(scopeTargetID, httpxResult); err != nil {
-				log.Printf("[WARN] Failed to update target URL for %s: %v", url, err)
-			} else {
-				log.Printf("[DEBUG] Successfully updated target URL: %s", url)
-			}
-		}
-	}
-	log.Printf("[INFO] Found %d live URLs", len(liveURLs))
-
-	// Mark URLs not found in this scan as no longer live
-	log.Printf("[DEBUG] Marking old URLs as no longer live")
 	if err := MarkOldTargetURLsAsNoLongerLive(scopeTargetID, liveURLs); err != nil {
 		log.Printf("[WARN] Failed to mark old target URLs as no longer live: %v", err)
 	}
-
-	log.Printf("[DEBUG] Updating final scan status")
-	UpdateHttpxScanStatus(scanID, "success", resultStr, stderr.String(), strings.Join(dockerCmd, " "), execTime)
-	log.Printf("[INFO] httpx scan completed successfully in %s", execTime)
+	UpdateHttpxScanStatus(scanID, "success", strings.Join(lines, "\n"), "", command, execTime)
+	log.Printf("[INFO] Native HTTP probe completed successfully in %s", execTime)
 }
 
 // UpdateHttpxScanStatus updates the status of a httpx scan in the database
@@ -711,6 +634,25 @@ func ConsolidateSubdomains(scopeTargetID string) ([]string, error) {
 		log.Printf("[INFO] Found %d new unique subdomains from %s", count, q.table)
 	}
 
+	// Names handed out by nameservers that allow zone transfers
+	axfrNames, err := axfrSubdomains(scopeTargetID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get AXFR subdomains: %v", err)
+	} else {
+		count := 0
+		for _, subdomain := range axfrNames {
+			if strings.HasSuffix(subdomain, baseDomain) {
+				if !uniqueSubdomains[subdomain] {
+					log.Printf("[DEBUG] Found new subdomain from axfr: %s", subdomain)
+					count++
+				}
+				uniqueSubdomains[subdomain] = true
+			}
+		}
+		toolResults["axfr"] = count
+		log.Printf("[INFO] Found %d new unique subdomains from axfr", count)
+	}
+
 	var consolidatedSubdomains []string
 	for subdomain := range uniqueSubdomains {
 		consolidatedSubdomains = append(consolidatedSubdomains, subdomain)
@@ -724,12 +666,29 @@ func ConsolidateSubdomains(scopeTargetID string) ([]string, error) {
 	log.Printf("[INFO] Total unique subdomains found: %d", len(consolidatedSubdomains))
 
 	// Update database
+	createWildcardDNSTables()
 	tx, err := dbPool.Begin(context.Background())
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %v", err)
 	}
 	defer tx.Rollback(context.Background())
 
+	// Names keep their wildcard flag until the background pass below
+	// re-judges them
+	wildcardSuspects := make(map[string]bool)
+	suspectRows, err := tx.Query(context.Background(),
+		`SELECT subdomain FROM consolidated_subdomains WHERE scope_target_id = $1 AND wildcard_suspect`, scopeTargetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load wildcard suspects: %v", err)
+	}
+	for suspectRows.Next() {
+		var subdomain string
+		if err := suspectRows.Scan(&subdomain); err == nil {
+			wildcardSuspects[subdomain] = true
+		}
+	}
+	suspectRows.Close()
+
 	_, err = tx.Exec(context.Background(), `DELETE FROM consolidated_subdomains WHERE scope_target_id = $1`, scopeTargetID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to delete old consolidated subdomains: %v", err)
@@ -737,9 +696,9 @@ func ConsolidateSubdomains(scopeTargetID string) ([]string, error) {
 
 	for _, subdomain := range consolidatedSubdomains {
 		_, err = tx.Exec(context.Background(),
-			`INSERT INTO consolidated_subdomains (scope_target_id, subdomain) VALUES ($1, $2)
+			`INSERT INTO consolidated_subdomains (scope_target_id, subdomain, wildcard_suspect) VALUES ($1, $2, $3)
 			ON CONFLICT (scope_target_id, subdomain) DO NOTHING`,
-			scopeTargetID, subdomain)
+			scopeTargetID, subdomain, wildcardSuspects[subdomain])
 		if err != nil {
 			return nil, fmt.Errorf("failed to insert consolidated subdomain: %v", err)
 		}
@@ -749,6 +708,9 @@ func ConsolidateSubdomains(scopeTargetID string) ([]string, error) {
 		return nil, fmt.Errorf("failed to commit transaction: %v", err)
 	}
 
+	// Flag names that only resolve because of a wildcard record
+	FlagWildcardSubdomains(scopeTargetID, baseDomain, consolidatedSubdomains)
+
 	return consolidatedSubdomains, nil
 }
 
@@ -805,24 +767,8 @@ func GetConsolidatedSubdomains(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// UpdateTargetURLFromHttpx updates target URL information from httpx scan results
-func UpdateTargetURLFromHttpx(scopeTargetID string, httpxData map[string]interface{}) error {
-	url, ok := httpxData["url"].(string)
-	if !ok || url == "" {
-		return fmt.Errorf("invalid or missing URL in httpx data")
-	}
-
-	url = NormalizeURL(url)
-	var technologies []string
-	if techInterface, ok := httpxData["tech"].([]interface{}); ok {
-		for _, tech := range techInterface {
-			if techStr, ok := tech.(string); ok {
-				technologies = append(technologies, techStr)
-			}
-		}
-	}
-
-	// Create findings_json array from technologies
+// technologyFindingsJSON turns detected technologies into info findings
+func technologyFindingsJSON(technologies []string) []byte {
 	findingsJSON := make([]map[string]interface{}, 0)
 	for _, tech := range technologies {
 		finding := map[string]interface{}{
@@ -838,56 +784,68 @@ func UpdateTargetURLFromHttpx(scopeTargetID string, httpxData map[string]interfa
 		log.Printf("[WARN] Failed to marshal findings JSON: %v", err)
 		findingsJSONBytes = []byte("[]")
 	}
+	return findingsJSONBytes
+}
 
-	// Check if target URL exists and update accordingly
-	var existingID string
-	var isNoLongerLive bool
-	err = dbPool.QueryRow(context.Background(),
-		`SELECT id, no_longer_live FROM target_urls WHERE url = $1`,
-		url).Scan(&existingID, &isNoLongerLive)
-
-	if err == pgx.ErrNoRows {
-		// Insert new target URL
-		_, err = dbPool.Exec(context.Background(),
-			`INSERT INTO target_urls (
-				url, status_code, title, web_server, technologies, 
-				content_length, scope_target_id, newly_discovered, no_longer_live,
-				findings_json, roi_score
-			) VALUES ($1, $2, $3, $4, $5::text[], $6, $7, true, false, $8::jsonb, 50)`,
-			url,
-			httpxData["status_code"],
-			httpxData["title"],
-			httpxData["webserver"],
-			technologies,
-			httpxData["content_length"],
-			scopeTargetID,
-			findingsJSONBytes)
-	} else if err == nil {
-		// Update existing target URL
-		updateQuery := `UPDATE target_urls SET 
-			status_code = $1,
-			title = $2,
-			web_server = $3,
-			technologies = $4::text[],
-			content_length = $5,
+// createHTTPProbeColumns adds the column holding native prober details
+func createHTTPProbeColumns() {
+	_, err := dbPool.Exec(context.Background(), `ALTER TABLE target_urls ADD COLUMN IF NOT EXISTS http_probe JSONB`)
+	if err != nil {
+		log.Printf("[ERROR] Failed to add http_probe column to target_urls: %v", err)
+	}
+}
+
+// upsertTargetURLFromProbe stores a native probe result in target_urls
+func upsertTargetURLFromProbe(scopeTargetID string, result *HTTPProbeResult) error {
+	return storeTargetURLFromProbe(scopeTargetID, result, `DO UPDATE SET
+			status_code = EXCLUDED.status_code,
+			title = EXCLUDED.title,
+			web_server = EXCLUDED.web_server,
+			technologies = EXCLUDED.technologies,
+			content_length = EXCLUDED.content_length,
+			newly_discovered = target_urls.no_longer_live,
 			no_longer_live = false,
-			newly_discovered = $6,
-			updated_at = NOW(),
-			findings_json = $7::jsonb
-		WHERE id = $8`
-
-		_, err = dbPool.Exec(context.Background(),
-			updateQuery,
-			httpxData["status_code"],
-			httpxData["title"],
-			httpxData["webserver"],
-			technologies,
-			httpxData["content_length"],
-			isNoLongerLive, // If previously marked as no longer live, mark as newly discovered
-			findingsJSONBytes,
-			existingID)
+			findings_json = EXCLUDED.findings_json,
+			http_response_headers = EXCLUDED.http_response_headers,
+			ip_address = COALESCE(EXCLUDED.ip_address, target_urls.ip_address),
+			http_probe = EXCLUDED.http_probe,
+			favicon_mmh3 = EXCLUDED.favicon_mmh3,
+			favicon_sha256 = EXCLUDED.favicon_sha256,
+			favicon_url = EXCLUDED.favicon_url,
+			technology_details = EXCLUDED.technology_details,
+			updated_at = NOW()`)
+}
+
+// insertTargetURLFromProbe adds a probe result to target_urls only when the
+// URL is not stored yet, so an existing row keeps its own probe data.
+func insertTargetURLFromProbe(scopeTargetID string, result *HTTPProbeResult) error {
+	return storeTargetURLFromProbe(scopeTargetID, result, `DO NOTHING`)
+}
+
+func storeTargetURLFromProbe(scopeTargetID string, result *HTTPProbeResult, onConflict string) error {
+	url := NormalizeURL(result.URL)
+	findingsJSONBytes := technologyFindingsJSON(result.Technologies)
+	headers, _ := json.Marshal(result.Headers)
+	probe, _ := json.Marshal(result)
+	technologyDetails, _ := json.Marshal(result.TechnologyDetails)
+	var ipAddress, faviconSHA256, faviconURL *string
+	if result.IPAddress != "" {
+		ipAddress = &result.IPAddress
+	}
+	if result.Favicon != nil {
+		faviconSHA256, faviconURL = &result.FaviconSHA256, &result.FaviconURL
 	}
 
+	_, err := dbPool.Exec(context.Background(),
+		`INSERT INTO target_urls (
+			url, status_code, title, web_server, technologies, content_length, scope_target_id,
+			newly_discovered, no_longer_live, findings_json, http_response_headers, ip_address, http_probe,
+			favicon_mmh3, favicon_sha256, favicon_url, technology_details, roi_score
+		) VALUES ($1, $2, $3, $4, $5::text[], $6, $7, true, false, $8::jsonb, $9::jsonb, $10, $11::jsonb, $12, $13, $14, $15::jsonb, 50)
+		ON CONFLICT (url, scope_target_id) `+onConflict,
+		url, result.StatusCode, result.Title, result.WebServer, result.Technologies, result.ContentLength,
+		scopeTargetID, findingsJSONBytes, headers, ipAddress, probe,
+		result.Favicon, faviconSHA256, faviconURL, technologyDetails)
 	return err
 }
 
@@ -1337,6 +1295,18 @@ func ConsolidateCompanyDomains(scopeTargetID string) ([]string, error) {
 		}
 	}
 
+	// 9. Get domains from PTR sweeps of the consolidated network ranges
+	log.Printf("[INFO] Fetching PTR sweep domains...")
+	ptrDomains, err := ptrSweepRootDomains(scopeTargetID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get PTR sweep domains: %v", err)
+	}
+	for _, domain := range ptrDomains {
+		if _, exists := domainMap[domain]; !exists {
+			domainMap[domain] = "ptr_sweep"
+		}
+	}
+
 	// Convert to sorted slice
 	var consolidatedDomains []string
 	for domain := range domainMap {
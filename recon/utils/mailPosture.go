@@ -0,0 +1,750 @@
+package utils
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Mail posture analysis grades the email authentication setup of each root
+// domain of a scope target: SPF (with include/redirect expansion and the
+// RFC 7208 limit of 10 DNS lookups), DMARC, DKIM on common selectors,
+// MTA-STS with its HTTPS policy, TLS-RPT and BIMI. Each check takes points
+// off a score of 100, which maps to a grade from A to F.
+// RECON_DKIM_SELECTORS adds selectors to the built-in list.
+
+const (
+	mailPostureConcurrency = 10
+	mailPostureTimeout     = 30 * time.Second
+	spfMaxLookups          = 10
+	spfMaxVoidLookups      = 2
+	mtaSTSPolicyLimit      = 64 << 10
+)
+
+var defaultDKIMSelectors = []string{
+	"default", "dkim", "mail", "email", "smtp", "k1", "k2", "k3", "s1", "s2", "selector1", "selector2",
+	"google", "mandrill", "mxvault", "everlytickey1", "everlytickey2", "zendesk1", "zendesk2",
+	"protonmail", "protonmail2", "protonmail3", "fm1", "fm2", "fm3", "mailjet", "sig1", "dkim1",
+	"smtpapi", "mailo", "pm", "cm", "mta",
+}
+
+type MailPostureIssue struct {
+	Severity string `json:"severity"`
+	Check    string `json:"check"`
+	Message  string `json:"message"`
+}
+
+type SPFAnalysis struct {
+	Record       string   `json:"record,omitempty"`
+	Lookups      int      `json:"lookups"`
+	VoidLookups  int      `json:"void_lookups"`
+	AllQualifier string   `json:"all_qualifier,omitempty"`
+	Includes     []string `json:"includes,omitempty"`
+	Redirect     string   `json:"redirect,omitempty"`
+	Errors       []string `json:"errors,omitempty"`
+}
+
+type DMARCAnalysis struct {
+	Record          string   `json:"record,omitempty"`
+	Policy          string   `json:"policy,omitempty"`
+	SubdomainPolicy string   `json:"subdomain_policy,omitempty"`
+	Percent         int      `json:"pct"`
+	RUA             []string `json:"rua,omitempty"`
+	RUF             []string `json:"ruf,omitempty"`
+	ADKIM           string   `json:"adkim,omitempty"`
+	ASPF            string   `json:"aspf,omitempty"`
+}
+
+type DKIMSelector struct {
+	Selector string `json:"selector"`
+	Record   string `json:"record"`
+	KeyType  string `json:"key_type"`
+	KeyBits  int    `json:"key_bits,omitempty"`
+	Revoked  bool   `json:"revoked,omitempty"`
+}
+
+type MTASTSAnalysis struct {
+	Record      string   `json:"record,omitempty"`
+	ID          string   `json:"id,omitempty"`
+	Mode        string   `json:"mode,omitempty"`
+	MX          []string `json:"mx,omitempty"`
+	MaxAge      int      `json:"max_age,omitempty"`
+	PolicyError string   `json:"policy_error,omitempty"`
+}
+
+type BIMIAnalysis struct {
+	Record      string `json:"record,omitempty"`
+	Logo        string `json:"logo,omitempty"`
+	Certificate string `json:"certificate,omitempty"`
+}
+
+type MailPosture struct {
+	Domain    string             `json:"domain"`
+	Grade     string             `json:"grade"`
+	Score     int                `json:"score"`
+	MX        []string           `json:"mx,omitempty"`
+	NullMX    bool               `json:"null_mx,omitempty"`
+	SPF       *SPFAnalysis       `json:"spf,omitempty"`
+	DMARC     *DMARCAnalysis     `json:"dmarc,omitempty"`
+	DKIM      []DKIMSelector     `json:"dkim"`
+	MTASTS    *MTASTSAnalysis    `json:"mta_sts,omitempty"`
+	TLSRPT    string             `json:"tls_rpt,omitempty"`
+	BIMI      *BIMIAnalysis      `json:"bimi,omitempty"`
+	Issues    []MailPostureIssue `json:"issues"`
+	CheckedAt time.Time          `json:"checked_at"`
+}
+
+var mailPostureRuns sync.Map
+
+func createMailPostureTable() {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS mail_posture_results (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			asset_id UUID REFERENCES consolidated_attack_surface_assets(id) ON DELETE SET NULL,
+			domain TEXT NOT NULL,
+			grade VARCHAR(2) NOT NULL,
+			score INTEGER NOT NULL,
+			spf_record TEXT,
+			dmarc_record TEXT,
+			dmarc_policy TEXT,
+			report JSONB,
+			checked_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scope_target_id, domain)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_mail_posture_results_scope_target_id ON mail_posture_results(scope_target_id);`,
+	}
+	for _, query := range queries {
+		if _, err := dbPool.Exec(context.Background(), query); err != nil {
+			log.Printf("[MAIL-POSTURE] [ERROR] Failed to create table/index: %v", err)
+		}
+	}
+}
+
+func dkimSelectors() []string {
+	selectors := append([]string{}, defaultDKIMSelectors...)
+	for _, s := range strings.Split(os.Getenv("RECON_DKIM_SELECTORS"), ",") {
+		if s = strings.TrimSpace(strings.ToLower(s)); s != "" && !slices.Contains(selectors, s) {
+			selectors = append(selectors, s)
+		}
+	}
+	return selectors
+}
+
+// lookupTXTPrefix returns the TXT records of name that start with prefix,
+// compared case-insensitively.
+func lookupTXTPrefix(ctx context.Context, name, prefix string) ([]string, bool, error) {
+	records, err := defaultDNSPool().Lookup(ctx, name, dnsTypeTXT)
+	if err != nil {
+		return nil, false, err
+	}
+	var matches []string
+	for _, rr := range records {
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(rr.Value)), prefix) {
+			matches = append(matches, strings.TrimSpace(rr.Value))
+		}
+	}
+	return matches, len(records) == 0, nil
+}
+
+// parseMailTags splits "k=v; k=v" records such as DMARC, MTA-STS and BIMI.
+func parseMailTags(record string) map[string]string {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(record, ";") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			tags[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+		}
+	}
+	return tags
+}
+
+// analyzeSPF evaluates the domain's SPF record the way a receiver counts
+// it: include, a, mx, ptr, exists and redirect each cost a DNS lookup, and
+// includes are expanded recursively.
+func analyzeSPF(ctx context.Context, domain string) *SPFAnalysis {
+	records, _, err := lookupTXTPrefix(ctx, domain, "v=spf1")
+	if err != nil || len(records) == 0 {
+		return nil
+	}
+	spf := &SPFAnalysis{Record: records[0]}
+	if len(records) > 1 {
+		spf.Errors = append(spf.Errors, "multiple SPF records (permerror)")
+	}
+	seen := map[string]bool{strings.ToLower(domain): true}
+	spf.AllQualifier = expandSPF(ctx, spf, records[0], seen, 0, true)
+	if spf.Lookups > spfMaxLookups {
+		spf.Errors = append(spf.Errors, fmt.Sprintf("%d DNS lookups exceed the limit of %d (permerror)", spf.Lookups, spfMaxLookups))
+	}
+	if spf.VoidLookups > spfMaxVoidLookups {
+		spf.Errors = append(spf.Errors, fmt.Sprintf("%d void lookups exceed the limit of %d", spf.VoidLookups, spfMaxVoidLookups))
+	}
+	return spf
+}
+
+// expandSPF walks one SPF record and returns its effective "all" qualifier,
+// following a redirect when the record has no all mechanism.
+func expandSPF(ctx context.Context, spf *SPFAnalysis, record string, seen map[string]bool, depth int, top bool) string {
+	if depth > spfMaxLookups {
+		spf.Errors = append(spf.Errors, "include depth exceeds the lookup limit")
+		return ""
+	}
+	all, redirect := "", ""
+	for _, term := range strings.Fields(record)[1:] {
+		term = strings.ToLower(term)
+		qualifier := "+"
+		if strings.ContainsRune("+-~?", rune(term[0])) {
+			qualifier, term = term[:1], term[1:]
+		}
+		name, value, _ := strings.Cut(term, ":")
+		if strings.HasPrefix(term, "redirect=") {
+			name, value = "redirect", strings.TrimPrefix(term, "redirect=")
+		}
+		switch strings.SplitN(name, "/", 2)[0] {
+		case "all":
+			all = qualifier
+		case "a", "mx", "exists":
+			spf.Lookups++
+		case "ptr":
+			spf.Lookups++
+			if top {
+				spf.Errors = append(spf.Errors, "ptr mechanism is deprecated")
+			}
+		case "include":
+			spf.Lookups++
+			if top {
+				spf.Includes = append(spf.Includes, value)
+			}
+			expandSPFInclude(ctx, spf, value, seen, depth)
+		case "redirect":
+			redirect = value
+		}
+	}
+	if all != "" || redirect == "" {
+		return all
+	}
+	spf.Lookups++
+	if top {
+		spf.Redirect = redirect
+	}
+	return expandSPFInclude(ctx, spf, redirect, seen, depth)
+}
+
+func expandSPFInclude(ctx context.Context, spf *SPFAnalysis, domain string, seen map[string]bool, depth int) string {
+	if domain == "" || strings.Contains(domain, "%{") {
+		return ""
+	}
+	if seen[domain] {
+		spf.Errors = append(spf.Errors, fmt.Sprintf("loop through %s", domain))
+		return ""
+	}
+	seen[domain] = true
+	records, empty, err := lookupTXTPrefix(ctx, domain, "v=spf1")
+	if err != nil {
+		spf.Errors = append(spf.Errors, fmt.Sprintf("lookup of %s failed: %v", domain, err))
+		return ""
+	}
+	if len(records) == 0 {
+		if empty {
+			spf.VoidLookups++
+		}
+		spf.Errors = append(spf.Errors, fmt.Sprintf("%s has no SPF record (permerror)", domain))
+		return ""
+	}
+	return expandSPF(ctx, spf, records[0], seen, depth+1, false)
+}
+
+func analyzeDMARC(ctx context.Context, domain string) *DMARCAnalysis {
+	records, _, err := lookupTXTPrefix(ctx, "_dmarc."+domain, "v=dmarc1")
+	if err != nil || len(records) == 0 {
+		return nil
+	}
+	tags := parseMailTags(records[0])
+	dmarc := &DMARCAnalysis{
+		Record:          records[0],
+		Policy:          strings.ToLower(tags["p"]),
+		SubdomainPolicy: strings.ToLower(tags["sp"]),
+		Percent:         100,
+		ADKIM:           tags["adkim"],
+		ASPF:            tags["aspf"],
+	}
+	if pct, err := strconv.Atoi(tags["pct"]); err == nil {
+		dmarc.Percent = pct
+	}
+	for _, field := range []struct {
+		tag string
+		out *[]string
+	}{{"rua", &dmarc.RUA}, {"ruf", &dmarc.RUF}} {
+		for _, uri := range strings.Split(tags[field.tag], ",") {
+			if uri = strings.TrimSpace(uri); uri != "" {
+				*field.out = append(*field.out, uri)
+			}
+		}
+	}
+	return dmarc
+}
+
+// analyzeDKIM probes selector._domainkey.domain for every known selector.
+func analyzeDKIM(ctx context.Context, domain string) []DKIMSelector {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	found := []DKIMSelector{}
+	for _, selector := range dkimSelectors() {
+		wg.Add(1)
+		go func(selector string) {
+			defer wg.Done()
+			records, err := defaultDNSPool().Lookup(ctx, selector+"._domainkey."+domain, dnsTypeTXT)
+			if err != nil {
+				return
+			}
+			for _, rr := range records {
+				tags := parseMailTags(rr.Value)
+				key, ok := tags["p"]
+				if !ok {
+					continue
+				}
+				d := DKIMSelector{Selector: selector, Record: rr.Value, KeyType: strings.ToLower(tags["k"]), Revoked: key == ""}
+				if d.KeyType == "" {
+					d.KeyType = "rsa"
+				}
+				d.KeyBits = dkimKeyBits(d.KeyType, key)
+				mu.Lock()
+				found = append(found, d)
+				mu.Unlock()
+				return
+			}
+		}(selector)
+	}
+	wg.Wait()
+	sort.Slice(found, func(i, j int) bool { return found[i].Selector < found[j].Selector })
+	return found
+}
+
+func dkimKeyBits(keyType, key string) int {
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(key), ""))
+	if err != nil || len(der) == 0 {
+		return 0
+	}
+	if keyType == "ed25519" {
+		return 256
+	}
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		if rsaKey, err := x509.ParsePKCS1PublicKey(der); err == nil {
+			return rsaKey.N.BitLen()
+		}
+		return 0
+	}
+	if rsaKey, ok := pub.(*rsa.PublicKey); ok {
+		return rsaKey.N.BitLen()
+	}
+	return 0
+}
+
+// analyzeMTASTS reads the _mta-sts record and fetches the policy it
+// announces. The policy host must present a valid certificate.
+func analyzeMTASTS(ctx context.Context, domain string) *MTASTSAnalysis {
+	records, _, err := lookupTXTPrefix(ctx, "_mta-sts."+domain, "v=stsv1")
+	if err != nil || len(records) == 0 {
+		return nil
+	}
+	sts := &MTASTSAnalysis{Record: records[0], ID: parseMailTags(records[0])["id"]}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://mta-sts."+domain+"/.well-known/mta-sts.txt", nil)
+	if err != nil {
+		sts.PolicyError = err.Error()
+		return sts
+	}
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		sts.PolicyError = err.Error()
+		return sts
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		sts.PolicyError = fmt.Sprintf("policy request returned HTTP %d", resp.StatusCode)
+		return sts
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, mtaSTSPolicyLimit))
+	for _, line := range strings.Split(string(body), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "mode":
+			sts.Mode = strings.ToLower(value)
+		case "mx":
+			sts.MX = append(sts.MX, value)
+		case "max_age":
+			sts.MaxAge, _ = strconv.Atoi(value)
+		}
+	}
+	if sts.Mode == "" {
+		sts.PolicyError = "policy has no mode"
+	}
+	return sts
+}
+
+func analyzeBIMI(ctx context.Context, domain string) *BIMIAnalysis {
+	records, _, err := lookupTXTPrefix(ctx, "default._bimi."+domain, "v=bimi1")
+	if err != nil || len(records) == 0 {
+		return nil
+	}
+	tags := parseMailTags(records[0])
+	return &BIMIAnalysis{Record: records[0], Logo: tags["l"], Certificate: tags["a"]}
+}
+
+// AnalyzeMailPosture runs every mail check against domain and grades it.
+func AnalyzeMailPosture(ctx context.Context, domain string) *MailPosture {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	p := &MailPosture{Domain: domain, DKIM: []DKIMSelector{}, CheckedAt: time.Now()}
+
+	if records, err := defaultDNSPool().Lookup(ctx, domain, dnsTypeMX); err == nil {
+		for _, rr := range records {
+			// A null MX (RFC 7505) targets the root, which formats as an
+			// empty name, so the value is just the preference
+			fields := strings.Fields(rr.Value)
+			if len(fields) == 0 || len(fields) > 2 {
+				continue
+			}
+			host := ""
+			if len(fields) == 2 {
+				host = strings.TrimSuffix(fields[1], ".")
+			}
+			if host == "" {
+				p.NullMX = true
+				continue
+			}
+			p.MX = append(p.MX, host)
+		}
+	}
+	var wg sync.WaitGroup
+	run := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+	run(func() { p.SPF = analyzeSPF(ctx, domain) })
+	run(func() { p.DMARC = analyzeDMARC(ctx, domain) })
+	run(func() { p.DKIM = analyzeDKIM(ctx, domain) })
+	run(func() { p.MTASTS = analyzeMTASTS(ctx, domain) })
+	run(func() { p.BIMI = analyzeBIMI(ctx, domain) })
+	run(func() {
+		if records, _, err := lookupTXTPrefix(ctx, "_smtp._tls."+domain, "v=tlsrptv1"); err == nil && len(records) > 0 {
+			p.TLSRPT = records[0]
+		}
+	})
+	wg.Wait()
+
+	gradeMailPosture(p)
+	return p
+}
+
+// gradeMailPosture fills Issues, Score and Grade. Domains that receive no
+// mail are only held to SPF and DMARC, which stop others sending as them.
+func gradeMailPosture(p *MailPosture) {
+	score := 100
+	issue := func(points int, severity, check, message string) {
+		score -= points
+		p.Issues = append(p.Issues, MailPostureIssue{Severity: severity, Check: check, Message: message})
+	}
+	receivesMail := len(p.MX) > 0
+
+	switch {
+	case p.SPF == nil:
+		issue(25, "high", "spf", "No SPF record")
+	case p.SPF.AllQualifier == "+":
+		issue(30, "critical", "spf", "SPF ends in +all and authorizes every sender")
+	case p.SPF.AllQualifier == "?" || p.SPF.AllQualifier == "":
+		issue(15, "medium", "spf", "SPF ends in ?all or has no all mechanism, so unlisted senders are neutral")
+	case p.SPF.AllQualifier == "~":
+		issue(5, "low", "spf", "SPF ends in ~all (softfail) rather than -all")
+	}
+	if p.SPF != nil {
+		for _, e := range p.SPF.Errors {
+			points := 0
+			if strings.Contains(e, "permerror") {
+				points = 15
+			}
+			issue(points, "medium", "spf", e)
+		}
+	}
+
+	switch {
+	case p.DMARC == nil:
+		issue(30, "high", "dmarc", "No DMARC record")
+	case p.DMARC.Policy == "none" || p.DMARC.Policy == "":
+		issue(20, "high", "dmarc", "DMARC policy is none, so failing mail is delivered")
+	case p.DMARC.Policy == "quarantine":
+		issue(5, "low", "dmarc", "DMARC policy is quarantine rather than reject")
+	}
+	if p.DMARC != nil {
+		if p.DMARC.Percent < 100 {
+			issue(5, "low", "dmarc", fmt.Sprintf("DMARC policy applies to %d%% of mail", p.DMARC.Percent))
+		}
+		if len(p.DMARC.RUA) == 0 {
+			issue(5, "low", "dmarc", "DMARC has no rua address for aggregate reports")
+		}
+	}
+
+	if receivesMail || (p.SPF != nil && p.SPF.AllQualifier != "-") {
+		active := 0
+		for _, d := range p.DKIM {
+			if d.Revoked {
+				continue
+			}
+			active++
+			if d.KeyType == "rsa" && d.KeyBits > 0 && d.KeyBits < 1024 {
+				issue(10, "medium", "dkim", fmt.Sprintf("DKIM selector %s uses a %d-bit RSA key", d.Selector, d.KeyBits))
+			}
+		}
+		if active == 0 {
+			issue(10, "medium", "dkim", "No DKIM key found on common selectors")
+		}
+	}
+
+	if receivesMail {
+		switch {
+		case p.MTASTS == nil:
+			issue(5, "low", "mta_sts", "No MTA-STS policy, so inbound TLS can be downgraded")
+		case p.MTASTS.PolicyError != "":
+			issue(5, "medium", "mta_sts", "MTA-STS policy could not be fetched: "+p.MTASTS.PolicyError)
+		case p.MTASTS.Mode != "enforce":
+			issue(2, "low", "mta_sts", fmt.Sprintf("MTA-STS mode is %s", p.MTASTS.Mode))
+		}
+		if p.TLSRPT == "" {
+			issue(2, "info", "tls_rpt", "No TLS-RPT record")
+		}
+	}
+
+	if p.BIMI != nil && (p.DMARC == nil || p.DMARC.Policy == "none" || p.DMARC.Percent < 100) {
+		issue(0, "info", "bimi", "BIMI is published but DMARC is not enforced, so logos will not show")
+	}
+
+	p.Score = max(score, 0)
+	switch {
+	case p.Score >= 90:
+		p.Grade = "A"
+	case p.Score >= 80:
+		p.Grade = "B"
+	case p.Score >= 65:
+		p.Grade = "C"
+	case p.Score >= 50:
+		p.Grade = "D"
+	default:
+		p.Grade = "F"
+	}
+}
+
+// RunMailPostureAnalysis grades the root domain of every FQDN asset and
+// company domain of a scope target, and attaches each result to the root
+// domain's FQDN asset.
+func RunMailPostureAnalysis(scopeTargetID string) error {
+	if _, running := mailPostureRuns.LoadOrStore(scopeTargetID, true); running {
+		log.Printf("[MAIL-POSTURE] [INFO] Analysis already running for %s, skipping", scopeTargetID)
+		return nil
+	}
+	defer mailPostureRuns.Delete(scopeTargetID)
+
+	createMailPostureTable()
+	startTime := time.Now()
+	ctx := context.Background()
+
+	assetIDs := make(map[string]string)
+	rows, err := dbPool.Query(ctx, `
+		SELECT id::text, LOWER(fqdn) FROM consolidated_attack_surface_assets
+		WHERE scope_target_id = $1::uuid AND asset_type = 'fqdn' AND fqdn IS NOT NULL AND fqdn != ''
+		UNION ALL
+		SELECT '', LOWER(domain) FROM consolidated_company_domains WHERE scope_target_id = $1::uuid`, scopeTargetID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch domains: %v", err)
+	}
+	roots := make(map[string]bool)
+	for rows.Next() {
+		var id, name string
+		if rows.Scan(&id, &name) != nil {
+			continue
+		}
+		if id != "" {
+			assetIDs[name] = id
+		}
+		if root := extractRootDomain(name); root != "" {
+			roots[root] = true
+		}
+	}
+	rows.Close()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var results []*MailPosture
+	semaphore := make(chan struct{}, mailPostureConcurrency)
+	for root := range roots {
+		wg.Add(1)
+		go func(domain string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			domainCtx, cancel := context.WithTimeout(ctx, mailPostureTimeout)
+			defer cancel()
+			p := AnalyzeMailPosture(domainCtx, domain)
+			mu.Lock()
+			results = append(results, p)
+			mu.Unlock()
+		}(root)
+	}
+	wg.Wait()
+
+	for _, p := range results {
+		assetID, ok := assetIDs[p.Domain]
+		if !ok {
+			err := dbPool.QueryRow(ctx, `
+				INSERT INTO consolidated_attack_surface_assets (scope_target_id, asset_type, asset_identifier, fqdn, root_domain)
+				VALUES ($1::uuid, 'fqdn', $2, $2, $2)
+				ON CONFLICT (scope_target_id, asset_type, asset_identifier) DO UPDATE SET last_updated = NOW()
+				RETURNING id::text`, scopeTargetID, p.Domain).Scan(&assetID)
+			if err != nil {
+				log.Printf("[MAIL-POSTURE] [ERROR] Failed to add FQDN asset for %s: %v", p.Domain, err)
+				continue
+			}
+		}
+		if err := storeMailPosture(ctx, scopeTargetID, assetID, p); err != nil {
+			log.Printf("[MAIL-POSTURE] [ERROR] Failed to store result for %s: %v", p.Domain, err)
+		}
+	}
+
+	log.Printf("[MAIL-POSTURE] [INFO] Graded %d root domains for %s in %s", len(results), scopeTargetID, time.Since(startTime))
+	return nil
+}
+
+func storeMailPosture(ctx context.Context, scopeTargetID, assetID string, p *MailPosture) error {
+	report, _ := json.Marshal(p)
+	var spfRecord, dmarcRecord, dmarcPolicy, dkimRecord *string
+	if p.SPF != nil {
+		spfRecord = &p.SPF.Record
+	}
+	if p.DMARC != nil {
+		dmarcRecord, dmarcPolicy = &p.DMARC.Record, &p.DMARC.Policy
+	}
+	for _, d := range p.DKIM {
+		if !d.Revoked {
+			record := d.Selector + "._domainkey: " + d.Record
+			dkimRecord = &record
+			break
+		}
+	}
+
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, `
+		INSERT INTO mail_posture_results (scope_target_id, asset_id, domain, grade, score, spf_record, dmarc_record, dmarc_policy, report, checked_at)
+		VALUES ($1::uuid, $2::uuid, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (scope_target_id, domain) DO UPDATE SET
+			asset_id = EXCLUDED.asset_id, grade = EXCLUDED.grade, score = EXCLUDED.score,
+			spf_record = EXCLUDED.spf_record, dmarc_record = EXCLUDED.dmarc_record,
+			dmarc_policy = EXCLUDED.dmarc_policy, report = EXCLUDED.report, checked_at = EXCLUDED.checked_at`,
+		scopeTargetID, assetID, p.Domain, p.Grade, p.Score, spfRecord, dmarcRecord, dmarcPolicy, report, p.CheckedAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO consolidated_attack_surface_metadata (asset_id, metadata_type, metadata_key, metadata_value, metadata_json)
+		VALUES ($1::uuid, 'mail_posture', 'grade', $2, $3)
+		ON CONFLICT (asset_id, metadata_type, metadata_key) DO UPDATE SET
+			metadata_value = EXCLUDED.metadata_value,
+			metadata_json = EXCLUDED.metadata_json`,
+		assetID, p.Grade, report)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE consolidated_attack_surface_assets SET spf_record = $2, dmarc_record = $3, dkim_record = $4,
+			mail_servers = COALESCE($5, mail_servers), last_updated = NOW()
+		WHERE id = $1::uuid`, assetID, spfRecord, dmarcRecord, dkimRecord, p.MX)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetMailPosture lists the graded root domains of a scope target, worst
+// first. ?grade= limits to one grade.
+func GetMailPosture(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	if scopeTargetID == "" {
+		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
+		return
+	}
+	grade := strings.ToUpper(r.URL.Query().Get("grade"))
+
+	createMailPostureTable()
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT report FROM mail_posture_results
+		WHERE scope_target_id = $1::uuid AND ($2 = '' OR grade = $2)
+		ORDER BY score, domain`, scopeTargetID, grade)
+	if err != nil {
+		log.Printf("[MAIL-POSTURE] [ERROR] Failed to get mail posture: %v", err)
+		http.Error(w, "Failed to get mail posture", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	results := []MailPosture{}
+	for rows.Next() {
+		var report []byte
+		var p MailPosture
+		if err := rows.Scan(&report); err != nil || json.Unmarshal(report, &p) != nil {
+			log.Printf("[MAIL-POSTURE] [ERROR] Error reading mail posture row: %v", err)
+			continue
+		}
+		results = append(results, p)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// RunMailPostureHandler starts a mail posture analysis in the background.
+func RunMailPostureHandler(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	if scopeTargetID == "" {
+		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
+		return
+	}
+
+	go func() {
+		if err := RunMailPostureAnalysis(scopeTargetID); err != nil {
+			log.Printf("[MAIL-POSTURE] [ERROR] Analysis failed for %s: %v", scopeTargetID, err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "started"})
+}
@@ -10,11 +10,9 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -22,7 +20,6 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
-	"github.com/jackc/pgx/v5"
 )
 
 type MetaDataStatus struct {
@@ -186,13 +183,17 @@ func RunMetaDataScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteAndParseMetaDataScan(scanID, domain)
+	if _, err := EnqueueScanJob("metadata", scanID, ScanJobPayload{Target: domain}); err != nil {
+		log.Printf("[ERROR] Failed to queue metadata scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteAndParseMetaDataScan(scanID, domain string) {
+func ExecuteAndParseMetaDataScan(ctx context.Context, scanID, domain string) {
 	log.Printf("[INFO] Starting metadata scan for domain %s (scan ID: %s)", domain, scanID)
 	startTime := time.Now()
 
@@ -282,24 +283,6 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 
 	log.Printf("[INFO] Processing %d URLs for scan ID: %s", len(urls), scanID)
 
-	// Create a temporary file for URLs
-	tempFile, err := os.CreateTemp("", "urls-*.txt")
-	if err != nil {
-		log.Printf("[ERROR] Failed to create temp file for scan ID %s: %v", scanID, err)
-		UpdateMetaDataScanStatus(scanID, "error", "", fmt.Sprintf("Failed to create temp file: %v", err), "", time.Since(startTime).String())
-		return
-	}
-	defer os.Remove(tempFile.Name())
-	log.Printf("[INFO] Created temporary file for URLs: %s", tempFile.Name())
-
-	// Write URLs to temp file
-	if err := os.WriteFile(tempFile.Name(), []byte(strings.Join(urls, "\n")), 0644); err != nil {
-		log.Printf("[ERROR] Failed to write URLs to temp file for scan ID %s: %v", scanID, err)
-		UpdateMetaDataScanStatus(scanID, "error", "", fmt.Sprintf("Failed to write URLs to temp file: %v", err), "", time.Since(startTime).String())
-		return
-	}
-	log.Printf("[INFO] Successfully wrote %d URLs to temp file for scan ID: %s", len(urls), scanID)
-
 	// Check which steps to run
 	runScreenshots := config == nil || config.Steps == nil || config.Steps["screenshots"]
 	runKatana := config == nil || config.Steps == nil || config.Steps["katana"]
@@ -317,8 +300,8 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 		customUserAgent, customHeader := GetCustomHTTPSettings()
 		
 		// Build nuclei command for screenshots
-		screenshotCmd := exec.Command(
-			"docker", "exec", "ars0n-framework-v2-nuclei-1",
+		screenshotCmd := NewToolCommandContext(ctx, 
+			"nuclei",
 			"bash", "-c",
 			fmt.Sprintf("echo '%s' > /urls.txt && nuclei -t /root/nuclei-templates/headless/screenshot.yaml -list /urls.txt -headless -c 25 -rl 150 -timeout 10 -retries 1 -bs 25%s%s",
 				strings.Join(urls, "\n"),
@@ -352,7 +335,7 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 			log.Printf("[INFO] Screenshots captured successfully for scan ID: %s, processing files...", scanID)
 			
 			// Process screenshot files
-			screenshotFiles, err := exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "ls", "/app/screenshots/").Output()
+			screenshotFiles, err := NewToolCommandContext(ctx, "nuclei", "ls", "/app/screenshots/").Output()
 			if err != nil {
 				log.Printf("[WARN] Failed to list screenshot files for scan ID %s: %v (continuing with other steps)", scanID, err)
 			} else {
@@ -366,7 +349,7 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 					}
 					
 					// Read the screenshot file
-					imgData, err := exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "cat", "/app/screenshots/"+file).Output()
+					imgData, err := NewToolCommandContext(ctx, "nuclei", "cat", "/app/screenshots/"+file).Output()
 					if err != nil {
 						log.Printf("[WARN] Failed to read screenshot file %s: %v", file, err)
 						continue
@@ -395,7 +378,7 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 				log.Printf("[INFO] Successfully processed %d screenshots for scan ID: %s", processedCount, scanID)
 				
 				// Clean up screenshots in the container
-				exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "rm", "-rf", "/app/screenshots/*").Run()
+				NewToolCommandContext(ctx, "nuclei", "rm", "-rf", "/app/screenshots/*").Run()
 			}
 		}
 	} else {
@@ -413,8 +396,8 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 		defer cancel()
 
-		katanaCmd := exec.CommandContext(ctx,
-			"docker", "exec", "ars0n-framework-v2-katana-1",
+		katanaCmd := NewToolCommandContext(ctx,
+			"katana",
 			"katana",
 			"-u", url,
 			"-jc",
@@ -591,131 +574,26 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 
 	// Run SSL scan if enabled
 	if runSSL {
-		// Copy the URLs file into the container for SSL scan
-		copyCmd := exec.Command(
-			"docker", "cp",
-			tempFile.Name(),
-			"ars0n-framework-v2-nuclei-1:/urls.txt",
-		)
-		if err := copyCmd.Run(); err != nil {
-			log.Printf("[ERROR] Failed to copy URLs file to container: %v", err)
-			UpdateMetaDataScanStatus(scanID, "error", "", fmt.Sprintf("Failed to copy URLs file: %v", err), "", time.Since(startTime).String())
+		endpoints := tlsEndpointsFromURLs(urls, "target_url")
+		log.Printf("[INFO] Starting native TLS posture checks for %d HTTPS URLs", len(endpoints))
+		lines := runTLSPostureChecks(ctx, "", scopeTargetID, endpoints)
+		if ctx.Err() != nil {
 			return
 		}
-
-		// Run all templates in one scan with JSON output
-		cmd := exec.Command(
-			"docker", "exec", "ars0n-framework-v2-nuclei-1",
-			"nuclei",
-			"-t", "/root/nuclei-templates/ssl/",
-			"-list", "/urls.txt",
-			"-j",
-			"-o", "/output.json",
-		)
-		log.Printf("[INFO] Executing command: %s", cmd.String())
-
-	var stderr bytes.Buffer
-	stdoutWriter := &NucleiLogWriter{prefix: "[NUCLEI-SSL]"}
-	stderrWriter := &NucleiLogWriter{prefix: "[NUCLEI-SSL-ERR]"}
-	
-	cmd.Stdout = stdoutWriter
-	cmd.Stderr = io.MultiWriter(&stderr, stderrWriter)
-
-	log.Printf("[INFO] Nuclei SSL scan started, streaming output...")
-	err = cmd.Run()
-	if err != nil {
-		log.Printf("[ERROR] Nuclei scan failed: %v", err)
-		UpdateMetaDataScanStatus(scanID, "error", "", stderr.String(), cmd.String(), time.Since(startTime).String())
-		return
-	}
-	log.Printf("[INFO] Nuclei SSL scan completed")
-
-	// Read the JSON output file
-	outputCmd := exec.Command(
-		"docker", "exec", "ars0n-framework-v2-nuclei-1",
-		"cat", "/output.json",
-	)
-	output, err := outputCmd.Output()
-	if err != nil {
-		log.Printf("[ERROR] Failed to read output file: %v", err)
-		UpdateMetaDataScanStatus(scanID, "error", "", fmt.Sprintf("Failed to read output file: %v", err), cmd.String(), time.Since(startTime).String())
-		return
-	}
-
-	// Process each finding and update the database
-	findings := strings.Split(string(output), "\n")
-	for _, finding := range findings {
-		if finding == "" {
-			continue
+		if err := attachTLSPosture(scopeTargetID); err != nil {
+			log.Printf("[ERROR] Failed to attach TLS posture to assets: %v", err)
 		}
 
-		var result map[string]interface{}
-		if err := json.Unmarshal([]byte(finding), &result); err != nil {
-			log.Printf("[ERROR] Failed to parse JSON finding: %v", err)
-			continue
-		}
-
-		templateID, ok := result["template-id"].(string)
-		if !ok {
-			continue
-		}
-
-		matchedURL, ok := result["matched-at"].(string)
-		if !ok {
-			continue
-		}
-
-		// Convert matched-at (host:port) to URL
-		url := "https://" + strings.TrimSuffix(matchedURL, ":443")
-
-		// Skip URLs with encoded characters that are nuclei test paths
-		if strings.Contains(url, "%") {
-			log.Printf("[DEBUG] Skipping nuclei test URL with encoded characters: %s", url)
-			continue
-		}
-
-		// Update the target_urls table based on the template
-		var updateField string
-		switch templateID {
-		case "deprecated-tls":
-			updateField = "has_deprecated_tls"
-		case "expired-ssl":
-			updateField = "has_expired_ssl"
-		case "mismatched-ssl-certificate":
-			updateField = "has_mismatched_ssl"
-		case "revoked-ssl-certificate":
-			updateField = "has_revoked_ssl"
-		case "self-signed-ssl":
-			updateField = "has_self_signed_ssl"
-		case "untrusted-root-certificate":
-			updateField = "has_untrusted_root_ssl"
-		default:
-			continue
-		}
-
-		query := fmt.Sprintf("UPDATE target_urls SET %s = true WHERE url = $1 AND scope_target_id = $2", updateField)
-		commandTag, err := dbPool.Exec(context.Background(), query, url, scopeTargetID)
-		if err != nil {
-			log.Printf("[ERROR] Failed to update target URL %s for template %s: %v", url, templateID, err)
-		} else {
-			rowsAffected := commandTag.RowsAffected()
-			log.Printf("[INFO] Successfully updated target URL %s with %s = true (Rows affected: %d)", url, updateField, rowsAffected)
-		}
-	}
-
 		// Update scan status to indicate SSL scan is complete but tech scan is pending
 		UpdateMetaDataScanStatus(
 			scanID,
 			"running",
-			string(output),
-			stderr.String(),
-			cmd.String(),
+			strings.Join(lines, "\n"),
+			"",
+			fmt.Sprintf("native TLS posture check of %d endpoints", len(endpoints)),
 			time.Since(startTime).String(),
 		)
 
-		// Clean up the output file
-		exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "rm", "/output.json").Run()
-
 		log.Printf("[INFO] SSL scan completed for scan ID: %s", scanID)
 	} else {
 		log.Printf("[INFO] SSL scan skipped (disabled in config)")
@@ -724,7 +602,7 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 	// Run the HTTP/technologies scan if enabled
 	if runTech {
 		log.Printf("[INFO] Starting technology detection scan")
-		if err := ExecuteAndParseNucleiTechScan(urls, scopeTargetID); err != nil {
+		if err := ExecuteAndParseNucleiTechScan(ctx, urls, scopeTargetID); err != nil {
 			log.Printf("[ERROR] Failed to run HTTP/technologies scan: %v", err)
 			UpdateMetaDataScanStatus(scanID, "error", "", fmt.Sprintf("Tech scan failed: %v", err), "", time.Since(startTime).String())
 			return
@@ -738,7 +616,7 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 	if runFfuf {
 		log.Printf("[INFO] Starting ffuf scans for all URLs")
 		for _, url := range urls {
-			if err := ExecuteFfufScan(url, scopeTargetID); err != nil {
+			if err := ExecuteFfufScan(ctx, url, scopeTargetID); err != nil {
 				log.Printf("[ERROR] Failed to run ffuf scan for URL %s: %v", url, err)
 				continue
 			}
@@ -761,7 +639,7 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 	log.Printf("[INFO] All enabled scans completed successfully for scan ID: %s", scanID)
 }
 
-func ExecuteAndParseNucleiTechScan(urls []string, scopeTargetID string) error {
+func ExecuteAndParseNucleiTechScan(ctx context.Context, urls []string, scopeTargetID string) error {
 	log.Printf("[INFO] Starting Nuclei HTTP/technologies scan")
 	startTime := time.Now()
 
@@ -871,18 +749,13 @@ func ExecuteAndParseNucleiTechScan(urls []string, scopeTargetID string) error {
 	}
 
 	// Copy the URLs file into the container
-	copyCmd := exec.Command(
-		"docker", "cp",
-		tempFile.Name(),
-		"ars0n-framework-v2-nuclei-1:/urls.txt",
-	)
-	if err := copyCmd.Run(); err != nil {
+	if err := CopyToTool("nuclei", tempFile.Name(), "/urls.txt"); err != nil {
 		return fmt.Errorf("failed to copy URLs file to container: %v", err)
 	}
 
 	// Run HTTP/technologies templates
-	cmd := exec.Command(
-		"docker", "exec", "ars0n-framework-v2-nuclei-1",
+	cmd := NewToolCommandContext(ctx, 
+		"nuclei",
 		"nuclei",
 		"-t", "/root/nuclei-templates/http/technologies/",
 		"-list", "/urls.txt",
@@ -906,8 +779,8 @@ func ExecuteAndParseNucleiTechScan(urls []string, scopeTargetID string) error {
 	log.Printf("[INFO] Nuclei tech scan completed")
 
 	// Read the JSON output file
-	outputCmd := exec.Command(
-		"docker", "exec", "ars0n-framework-v2-nuclei-1",
+	outputCmd := NewToolCommandContext(ctx, 
+		"nuclei",
 		"cat", "/tech-output.json",
 	)
 	output, err := outputCmd.Output()
@@ -1012,7 +885,7 @@ func ExecuteAndParseNucleiTechScan(urls []string, scopeTargetID string) error {
 	}
 
 	// Clean up the output file
-	exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "rm", "/tech-output.json").Run()
+	NewToolCommandContext(ctx, "nuclei", "rm", "/tech-output.json").Run()
 
 	log.Printf("[INFO] HTTP/technologies scan completed in %s", time.Since(startTime))
 	return nil
@@ -1024,114 +897,59 @@ func PerformDNSLookups(hostname string) DNSResults {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Create a custom resolver with shorter timeout
-	resolver := &net.Resolver{
-		PreferGo: true,
-		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-			d := net.Dialer{
-				Timeout: 500 * time.Millisecond,
-			}
-			// Try Docker's internal DNS first
-			conn, err := d.DialContext(ctx, network, "127.0.0.11:53")
-			if err == nil {
-				return conn, nil
-			}
-			// Fall back to Google DNS only
-			return d.DialContext(ctx, network, "8.8.8.8:53")
-		},
-	}
+	records := defaultDNSPool().Profile(ctx, hostname)
 
-	// A and AAAA records
-	log.Printf("[DEBUG] Looking up A/AAAA records for %s", hostname)
-	if ips, err := resolver.LookupIPAddr(ctx, hostname); err == nil {
-		for _, ip := range ips {
-			if ipv4 := ip.IP.To4(); ipv4 != nil {
-				results.ARecords = append(results.ARecords, ipv4.String())
-				log.Printf("[DEBUG] Found A record: %s", ipv4.String())
-			} else {
-				results.AAAARecords = append(results.AAAARecords, ip.IP.String())
-				log.Printf("[DEBUG] Found AAAA record: %s", ip.IP.String())
-			}
+	// PTR records are named after the reverse name of the address
+	reverseNames := make(map[string]string)
+	for _, ip := range append(dnsRecordValues(records, "A"), dnsRecordValues(records, "AAAA")...) {
+		if name, err := reverseDNSName(ip); err == nil {
+			reverseNames[name] = ip
 		}
 	}
 
-	// CNAME lookup
-	log.Printf("[DEBUG] Looking up CNAME records for %s", hostname)
-	if cname, err := resolver.LookupCNAME(ctx, hostname); err == nil && cname != "" {
-		cname = strings.TrimSuffix(cname, ".")
-		if cname != hostname {
-			record := fmt.Sprintf("%s -> %s", hostname, cname)
+	for _, rr := range records {
+		var record string
+		switch rr.Type {
+		case "A":
+			results.ARecords = append(results.ARecords, rr.Value)
+			record = rr.Value
+		case "AAAA":
+			results.AAAARecords = append(results.AAAARecords, rr.Value)
+			record = rr.Value
+		case "CNAME":
+			if rr.Value == hostname {
+				continue
+			}
+			record = fmt.Sprintf("%s -> %s", hostname, rr.Value)
 			results.CNAMERecords = append(results.CNAMERecords, record)
-			log.Printf("[DEBUG] Found CNAME record: %s", record)
-		}
-	}
-
-	// MX lookup
-	log.Printf("[DEBUG] Looking up MX records for %s", hostname)
-	if mxRecords, err := resolver.LookupMX(ctx, hostname); err == nil {
-		for _, mx := range mxRecords {
-			record := fmt.Sprintf("Priority: %d | Server: %s", mx.Pref, strings.TrimSuffix(mx.Host, "."))
+		case "MX":
+			fields := strings.Fields(rr.Value)
+			if len(fields) != 2 {
+				continue
+			}
+			record = fmt.Sprintf("Priority: %s | Server: %s", fields[0], fields[1])
 			results.MXRecords = append(results.MXRecords, record)
-			log.Printf("[DEBUG] Found MX record: %s", record)
-		}
-	}
-
-	// TXT lookup
-	log.Printf("[DEBUG] Looking up TXT records for %s", hostname)
-	if txtRecords, err := resolver.LookupTXT(ctx, hostname); err == nil {
-		for _, txt := range txtRecords {
-			results.TXTRecords = append(results.TXTRecords, txt)
-			log.Printf("[DEBUG] Found TXT record: %s", txt)
-		}
-	}
-
-	// NS lookup
-	log.Printf("[DEBUG] Looking up NS records for %s", hostname)
-	if nsRecords, err := resolver.LookupNS(ctx, hostname); err == nil {
-		for _, ns := range nsRecords {
-			record := strings.TrimSuffix(ns.Host, ".")
+		case "TXT":
+			record = rr.Value
+			results.TXTRecords = append(results.TXTRecords, record)
+		case "NS":
+			record = rr.Value
 			results.NSRecords = append(results.NSRecords, record)
-			log.Printf("[DEBUG] Found NS record: %s", record)
-		}
-	}
-
-	// PTR lookup for both IPv4 and IPv6
-	lookupPTR := func(ip string) {
-		log.Printf("[DEBUG] Looking up PTR records for IP %s", ip)
-		if names, err := resolver.LookupAddr(ctx, ip); err == nil {
-			for _, name := range names {
-				record := fmt.Sprintf("%s -> %s", ip, strings.TrimSuffix(name, "."))
-				results.PTRRecords = append(results.PTRRecords, record)
-				log.Printf("[DEBUG] Found PTR record: %s", record)
-			}
-		}
-	}
-
-	// Perform PTR lookups for both A and AAAA records
-	for _, ip := range results.ARecords {
-		lookupPTR(ip)
-	}
-	for _, ip := range results.AAAARecords {
-		lookupPTR(ip)
-	}
-
-	// SRV lookup
-	services := []string{"_http._tcp", "_https._tcp", "_ldap._tcp", "_kerberos._tcp"}
-	for _, service := range services {
-		fullService := service + "." + hostname
-		log.Printf("[DEBUG] Looking up SRV records for %s", fullService)
-		if _, addrs, err := resolver.LookupSRV(ctx, "", "", fullService); err == nil {
-			for _, addr := range addrs {
-				record := fmt.Sprintf("Service: %s | Target: %s | Port: %d | Priority: %d | Weight: %d",
-					service,
-					strings.TrimSuffix(addr.Target, "."),
-					addr.Port,
-					addr.Priority,
-					addr.Weight)
-				results.SRVRecords = append(results.SRVRecords, record)
-				log.Printf("[DEBUG] Found SRV record: %s", record)
+		case "PTR":
+			record = fmt.Sprintf("%s -> %s", reverseNames[rr.Name], rr.Value)
+			results.PTRRecords = append(results.PTRRecords, record)
+		case "SRV":
+			fields := strings.Fields(rr.Value)
+			if len(fields) != 4 {
+				continue
 			}
+			record = fmt.Sprintf("Service: %s | Target: %s | Port: %s | Priority: %s | Weight: %s",
+				strings.TrimSuffix(rr.Name, "."+hostname), fields[3], fields[2], fields[0], fields[1])
+			results.SRVRecords = append(results.SRVRecords, record)
+		default:
+			continue
 		}
+		log.Printf("[DEBUG] Found %s record: %s (ttl %d)", rr.Type, record, rr.TTL)
 	}
 
 	// Deduplicate all record types
@@ -1190,117 +1008,6 @@ func UpdateMetaDataScanStatus(scanID, status, result, stderr, command, execTime
 	}
 }
 
-func GetMetaDataScanStatus(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	scanID := vars["scan_id"]
-
-	var scan MetaDataStatus
-	query := `SELECT * FROM metadata_scans WHERE scan_id = $1`
-	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
-		&scan.ID,
-		&scan.ScanID,
-		&scan.Domain,
-		&scan.Status,
-		&scan.Result,
-		&scan.Error,
-		&scan.StdOut,
-		&scan.StdErr,
-		&scan.Command,
-		&scan.ExecTime,
-		&scan.CreatedAt,
-		&scan.ScopeTargetID,
-		&scan.AutoScanSessionID,
-		&scan.Config,
-	)
-
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			http.Error(w, "Scan not found", http.StatusNotFound)
-		} else {
-			log.Printf("[ERROR] Failed to get scan status: %v", err)
-			http.Error(w, "Failed to get scan status", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	response := map[string]interface{}{
-		"id":                   scan.ID,
-		"scan_id":              scan.ScanID,
-		"domain":               scan.Domain,
-		"status":               scan.Status,
-		"result":               nullStringToString(scan.Result),
-		"error":                nullStringToString(scan.Error),
-		"stdout":               nullStringToString(scan.StdOut),
-		"stderr":               nullStringToString(scan.StdErr),
-		"command":              nullStringToString(scan.Command),
-		"execution_time":       nullStringToString(scan.ExecTime),
-		"created_at":           scan.CreatedAt.Format(time.RFC3339),
-		"scope_target_id":      scan.ScopeTargetID,
-		"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-func GetMetaDataScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	scopeTargetID := vars["id"]
-
-	query := `SELECT * FROM metadata_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
-	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
-	if err != nil {
-		log.Printf("[ERROR] Failed to get scans: %v", err)
-		http.Error(w, "Failed to get scans", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	var scans []map[string]interface{}
-	for rows.Next() {
-		var scan MetaDataStatus
-		err := rows.Scan(
-			&scan.ID,
-			&scan.ScanID,
-			&scan.Domain,
-			&scan.Status,
-			&scan.Result,
-			&scan.Error,
-			&scan.StdOut,
-			&scan.StdErr,
-			&scan.Command,
-			&scan.ExecTime,
-			&scan.CreatedAt,
-			&scan.ScopeTargetID,
-			&scan.AutoScanSessionID,
-			&scan.Config,
-		)
-		if err != nil {
-			log.Printf("[ERROR] Failed to scan row: %v", err)
-			continue
-		}
-
-		scans = append(scans, map[string]interface{}{
-			"id":                   scan.ID,
-			"scan_id":              scan.ScanID,
-			"domain":               scan.Domain,
-			"status":               scan.Status,
-			"result":               nullStringToString(scan.Result),
-			"error":                nullStringToString(scan.Error),
-			"stdout":               nullStringToString(scan.StdOut),
-			"stderr":               nullStringToString(scan.StdErr),
-			"command":              nullStringToString(scan.Command),
-			"execution_time":       nullStringToString(scan.ExecTime),
-			"created_at":           scan.CreatedAt.Format(time.RFC3339),
-			"scope_target_id":      scan.ScopeTargetID,
-			"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-		})
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(scans)
-}
-
 func GanitizeResponse(input []byte) string {
 	// Remove null bytes
 	sanitized := bytes.ReplaceAll(input, []byte{0}, []byte{})
@@ -1319,7 +1026,7 @@ func GanitizeResponse(input []byte) string {
 	return str
 }
 
-func ExecuteFfufScan(url string, scopeTargetID string) error {
+func ExecuteFfufScan(ctx context.Context, url string, scopeTargetID string) error {
 	log.Printf("[INFO] Starting ffuf scan for URL: %s", url)
 	startTime := time.Now()
 
@@ -1331,9 +1038,8 @@ func ExecuteFfufScan(url string, scopeTargetID string) error {
 	defer os.RemoveAll(tempDir)
 
 	// Copy wordlist to container
-	copyCmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-ffuf-1",
+	copyCmd := NewToolCommandContext(ctx, 
+		"ffuf",
 		"cp",
 		"/wordlists/ffuf-wordlist-5000.txt",
 		"/wordlist.txt",
@@ -1349,9 +1055,8 @@ func ExecuteFfufScan(url string, scopeTargetID string) error {
 	log.Printf("[DEBUG] Successfully copied wordlist in container")
 
 	// Verify wordlist exists in container
-	checkCmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-ffuf-1",
+	checkCmd := NewToolCommandContext(ctx, 
+		"ffuf",
 		"ls", "-l", "/wordlist.txt",
 	)
 	if out, err := checkCmd.CombinedOutput(); err != nil {
@@ -1363,9 +1068,8 @@ func ExecuteFfufScan(url string, scopeTargetID string) error {
 
 	// Run ffuf scan only on the base target URL
 	fuzzyURL := fmt.Sprintf("%s/FUZZ", url)
-	cmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-ffuf-1",
+	cmd := NewToolCommandContext(ctx, 
+		"ffuf",
 		"ffuf",
 		"-w", "/wordlist.txt",
 		"-u", fuzzyURL,
@@ -1390,9 +1094,8 @@ func ExecuteFfufScan(url string, scopeTargetID string) error {
 	log.Printf("[INFO] Completed ffuf scan for URL: %s", url)
 
 	// Read and parse results
-	outputCmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-ffuf-1",
+	outputCmd := NewToolCommandContext(ctx, 
+		"ffuf",
 		"cat", "/output.json",
 	)
 	resultBytes, err := outputCmd.Output()
@@ -1473,13 +1176,17 @@ func RunCompanyMetaDataScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteAndParseCompanyMetaDataScan(scanID, payload.ScopeTargetID, payload.IPPortScanID)
+	if _, err := EnqueueScanJob("company-metadata", scanID, ScanJobPayload{ScopeTargetID: payload.ScopeTargetID, IPPortScanID: payload.IPPortScanID}); err != nil {
+		log.Printf("[ERROR] Failed to queue company-metadata scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteAndParseCompanyMetaDataScan(scanID, scopeTargetID, ipPortScanID string) {
+func ExecuteAndParseCompanyMetaDataScan(ctx context.Context, scanID, scopeTargetID, ipPortScanID string) {
 	log.Printf("[INFO] Starting Company metadata scan for IP/Port scan ID %s (scan ID: %s)", ipPortScanID, scanID)
 	startTime := time.Now()
 
@@ -1533,8 +1240,8 @@ func ExecuteAndParseCompanyMetaDataScan(scanID, scopeTargetID, ipPortScanID stri
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 		defer cancel()
 
-		katanaCmd := exec.CommandContext(ctx,
-			"docker", "exec", "ars0n-framework-v2-katana-1",
+		katanaCmd := NewToolCommandContext(ctx,
+			"katana",
 			"katana",
 			"-u", url,
 			"-jc",
@@ -1657,7 +1364,7 @@ func ExecuteAndParseCompanyMetaDataScan(scanID, scopeTargetID, ipPortScanID stri
 	for _, url := range liveWebServers {
 		completedFfuf++
 		log.Printf("[INFO] Running Ffuf scan for URL: %s (%d/%d)", url, completedFfuf, len(liveWebServers))
-		if err := ExecuteFfufScan(url, scopeTargetID); err != nil {
+		if err := ExecuteFfufScan(ctx, url, scopeTargetID); err != nil {
 			log.Printf("[WARN] Ffuf scan failed for URL %s (%d/%d): %v", url, completedFfuf, len(liveWebServers), err)
 			continue
 		}
@@ -1665,7 +1372,7 @@ func ExecuteAndParseCompanyMetaDataScan(scanID, scopeTargetID, ipPortScanID stri
 	}
 
 	// Execute Nuclei tech scan using the same logic as regular metadata scan
-	err = ExecuteAndParseNucleiTechScan(liveWebServers, scopeTargetID)
+	err = ExecuteAndParseNucleiTechScan(ctx, liveWebServers, scopeTargetID)
 	if err != nil {
 		log.Printf("[ERROR] Failed to execute Nuclei tech scan: %v", err)
 		UpdateCompanyMetaDataScanStatus(scanID, "error", fmt.Sprintf("Failed to execute Nuclei tech scan: %v", err), time.Since(startTime).String())
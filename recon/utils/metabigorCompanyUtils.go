@@ -2,12 +2,10 @@ package utils
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
@@ -15,25 +13,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
-	"github.com/jackc/pgx/v5"
 )
 
-type MetabigorCompanyScanStatus struct {
-	ID                string         `json:"id"`
-	ScanID            string         `json:"scan_id"`
-	CompanyName       string         `json:"company_name"`
-	Status            string         `json:"status"`
-	Result            sql.NullString `json:"result,omitempty"`
-	Error             sql.NullString `json:"error,omitempty"`
-	StdOut            sql.NullString `json:"stdout,omitempty"`
-	StdErr            sql.NullString `json:"stderr,omitempty"`
-	Command           sql.NullString `json:"command,omitempty"`
-	ExecTime          sql.NullString `json:"execution_time,omitempty"`
-	CreatedAt         time.Time      `json:"created_at"`
-	ScopeTargetID     string         `json:"scope_target_id"`
-	AutoScanSessionID sql.NullString `json:"auto_scan_session_id"`
-}
-
 // New structured data types
 type MetabigorNetworkRange struct {
 	ID           string `json:"id"`
@@ -65,83 +46,7 @@ type MetabigorIPIntelligence struct {
 	ScanID       string   `json:"scan_id"`
 }
 
-func RunMetabigorCompanyScan(w http.ResponseWriter, r *http.Request) {
-	log.Printf("[METABIGOR-COMPANY] [INFO] Starting Metabigor Company scan request handling")
-	var payload struct {
-		CompanyName       string  `json:"company_name" binding:"required"`
-		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.CompanyName == "" {
-		log.Printf("[METABIGOR-COMPANY] [ERROR] Invalid request body: %v", err)
-		http.Error(w, "Invalid request body. `company_name` is required.", http.StatusBadRequest)
-		return
-	}
-
-	companyName := payload.CompanyName
-	log.Printf("[METABIGOR-COMPANY] [INFO] Processing Metabigor Company scan for company: %s", companyName)
-
-	query := `SELECT id FROM scope_targets WHERE type = 'Company' AND scope_target = $1`
-	var scopeTargetID string
-	err := dbPool.QueryRow(context.Background(), query, companyName).Scan(&scopeTargetID)
-	if err != nil {
-		log.Printf("[METABIGOR-COMPANY] [ERROR] No matching company scope target found for company %s: %v", companyName, err)
-		http.Error(w, "No matching company scope target found.", http.StatusBadRequest)
-		return
-	}
-	log.Printf("[METABIGOR-COMPANY] [INFO] Found scope target ID: %s for company: %s", scopeTargetID, companyName)
-
-	scanID := uuid.New().String()
-	log.Printf("[METABIGOR-COMPANY] [INFO] Generated new scan ID: %s", scanID)
-
-	createTableQuery := `
-		CREATE TABLE IF NOT EXISTS metabigor_company_scans (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			scan_id UUID NOT NULL UNIQUE,
-			company_name TEXT NOT NULL,
-			status VARCHAR(50) NOT NULL,
-			result TEXT,
-			error TEXT,
-			stdout TEXT,
-			stderr TEXT,
-			command TEXT,
-			execution_time TEXT,
-			created_at TIMESTAMP DEFAULT NOW(),
-			scope_target_id UUID REFERENCES scope_targets(id) ON DELETE CASCADE,
-			auto_scan_session_id UUID REFERENCES auto_scan_sessions(id) ON DELETE SET NULL
-		);`
-	_, err = dbPool.Exec(context.Background(), createTableQuery)
-	if err != nil {
-		log.Printf("[METABIGOR-COMPANY] [ERROR] Failed to create metabigor_company_scans table: %v", err)
-		http.Error(w, "Failed to create scan table.", http.StatusInternalServerError)
-		return
-	}
-	log.Printf("[METABIGOR-COMPANY] [INFO] Ensured metabigor_company_scans table exists")
-
-	var insertQuery string
-	var args []interface{}
-	if payload.AutoScanSessionID != nil && *payload.AutoScanSessionID != "" {
-		insertQuery = `INSERT INTO metabigor_company_scans (scan_id, company_name, status, scope_target_id, auto_scan_session_id) VALUES ($1, $2, $3, $4, $5)`
-		args = []interface{}{scanID, companyName, "pending", scopeTargetID, *payload.AutoScanSessionID}
-	} else {
-		insertQuery = `INSERT INTO metabigor_company_scans (scan_id, company_name, status, scope_target_id) VALUES ($1, $2, $3, $4)`
-		args = []interface{}{scanID, companyName, "pending", scopeTargetID}
-	}
-	_, err = dbPool.Exec(context.Background(), insertQuery, args...)
-	if err != nil {
-		log.Printf("[METABIGOR-COMPANY] [ERROR] Failed to create scan record: %v", err)
-		http.Error(w, "Failed to create scan record.", http.StatusInternalServerError)
-		return
-	}
-	log.Printf("[METABIGOR-COMPANY] [INFO] Successfully created Metabigor Company scan record in database")
-
-	go ExecuteMetabigorCompanyScan(scanID, companyName)
-
-	log.Printf("[METABIGOR-COMPANY] [INFO] Metabigor Company scan initiated successfully, returning scan ID: %s", scanID)
-	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
-}
-
-func ExecuteMetabigorCompanyScan(scanID, companyName string) {
+func ExecuteMetabigorCompanyScan(ctx context.Context, scanID, companyName string) {
 	log.Printf("[METABIGOR-COMPANY] [INFO] Starting Metabigor Company scan execution for company %s (scan ID: %s)", companyName, scanID)
 	startTime := time.Now()
 
@@ -150,10 +55,11 @@ func ExecuteMetabigorCompanyScan(scanID, companyName string) {
 
 	// Helper function to execute the scan and count results
 	executeScan := func(name string) (string, int, error) {
-		command := fmt.Sprintf("echo '%s' | /usr/bin/docker exec -i ars0n-framework-v2-metabigor-1 metabigor net --org -v", name)
-		log.Printf("[METABIGOR-COMPANY] [DEBUG] Executing command: %s", command)
+		cmd := NewToolCommandContext(ctx, "metabigor", "metabigor", "net", "--org", "-v")
+		cmd.Stdin = strings.NewReader(name + "\n")
+		log.Printf("[METABIGOR-COMPANY] [DEBUG] Executing command: %s", cmd.String())
 
-		output, err := exec.Command("sh", "-c", command).CombinedOutput()
+		output, err := cmd.CombinedOutput()
 		if err != nil {
 			return string(output), 0, err
 		}
@@ -385,164 +291,6 @@ func UpdateMetabigorCompanyScanStatus(scanID, status, result, stderr, command, e
 	}
 }
 
-func GetMetabigorCompanyScanStatus(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	scanID := vars["scan_id"]
-	log.Printf("[METABIGOR-COMPANY] [INFO] Retrieving Metabigor Company scan status for scan ID: %s", scanID)
-
-	var scan MetabigorCompanyScanStatus
-	query := `SELECT id, scan_id, company_name, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM metabigor_company_scans WHERE scan_id = $1`
-	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
-		&scan.ID,
-		&scan.ScanID,
-		&scan.CompanyName,
-		&scan.Status,
-		&scan.Result,
-		&scan.Error,
-		&scan.StdOut,
-		&scan.StdErr,
-		&scan.Command,
-		&scan.ExecTime,
-		&scan.CreatedAt,
-		&scan.ScopeTargetID,
-		&scan.AutoScanSessionID,
-	)
-
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			log.Printf("[METABIGOR-COMPANY] [ERROR] Metabigor Company scan not found for scan ID: %s", scanID)
-			http.Error(w, "Scan not found", http.StatusNotFound)
-		} else {
-			log.Printf("[METABIGOR-COMPANY] [ERROR] Failed to get Metabigor Company scan status for scan ID %s: %v", scanID, err)
-			http.Error(w, "Failed to get scan status", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	log.Printf("[METABIGOR-COMPANY] [INFO] Successfully retrieved Metabigor Company scan status for scan ID %s: %s", scanID, scan.Status)
-	if scan.Result.Valid {
-		log.Printf("[METABIGOR-COMPANY] [DEBUG] Scan has valid results of length: %d bytes", len(scan.Result.String))
-	}
-
-	response := map[string]interface{}{
-		"id":                   scan.ID,
-		"scan_id":              scan.ScanID,
-		"company_name":         scan.CompanyName,
-		"status":               scan.Status,
-		"result":               nullStringToString(scan.Result),
-		"error":                nullStringToString(scan.Error),
-		"stdout":               nullStringToString(scan.StdOut),
-		"stderr":               nullStringToString(scan.StdErr),
-		"command":              nullStringToString(scan.Command),
-		"execution_time":       nullStringToString(scan.ExecTime),
-		"created_at":           scan.CreatedAt.Format(time.RFC3339),
-		"scope_target_id":      scan.ScopeTargetID,
-		"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("[METABIGOR-COMPANY] [ERROR] Failed to encode Metabigor Company scan response: %v", err)
-	} else {
-		log.Printf("[METABIGOR-COMPANY] [INFO] Successfully sent Metabigor Company scan status response")
-	}
-}
-
-func GetMetabigorCompanyScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	scopeTargetID := vars["id"]
-	log.Printf("[METABIGOR-COMPANY] [INFO] Fetching Metabigor Company scans for scope target ID: %s", scopeTargetID)
-
-	if scopeTargetID == "" {
-		log.Printf("[METABIGOR-COMPANY] [ERROR] No scope target ID provided")
-		http.Error(w, "No scope target ID provided", http.StatusBadRequest)
-		return
-	}
-
-	// Ensure the table exists before trying to query it
-	createTableQuery := `
-		CREATE TABLE IF NOT EXISTS metabigor_company_scans (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			scan_id UUID NOT NULL UNIQUE,
-			company_name TEXT NOT NULL,
-			status VARCHAR(50) NOT NULL,
-			result TEXT,
-			error TEXT,
-			stdout TEXT,
-			stderr TEXT,
-			command TEXT,
-			execution_time TEXT,
-			created_at TIMESTAMP DEFAULT NOW(),
-			scope_target_id UUID REFERENCES scope_targets(id) ON DELETE CASCADE,
-			auto_scan_session_id UUID REFERENCES auto_scan_sessions(id) ON DELETE SET NULL
-		);`
-	_, err := dbPool.Exec(context.Background(), createTableQuery)
-	if err != nil {
-		log.Printf("[METABIGOR-COMPANY] [ERROR] Failed to create metabigor_company_scans table: %v", err)
-		http.Error(w, "Failed to create scan table.", http.StatusInternalServerError)
-		return
-	}
-
-	query := `SELECT id, scan_id, company_name, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM metabigor_company_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
-	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
-	if err != nil {
-		log.Printf("[METABIGOR-COMPANY] [ERROR] Failed to get scans: %v", err)
-		http.Error(w, "Failed to get scans", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	var scans []map[string]interface{}
-	for rows.Next() {
-		var scan MetabigorCompanyScanStatus
-		err := rows.Scan(
-			&scan.ID,
-			&scan.ScanID,
-			&scan.CompanyName,
-			&scan.Status,
-			&scan.Result,
-			&scan.Error,
-			&scan.StdOut,
-			&scan.StdErr,
-			&scan.Command,
-			&scan.ExecTime,
-			&scan.CreatedAt,
-			&scan.ScopeTargetID,
-			&scan.AutoScanSessionID,
-		)
-		if err != nil {
-			log.Printf("[METABIGOR-COMPANY] [ERROR] Error scanning Metabigor Company scan row: %v", err)
-			continue
-		}
-
-		scanMap := map[string]interface{}{
-			"id":                   scan.ID,
-			"scan_id":              scan.ScanID,
-			"company_name":         scan.CompanyName,
-			"status":               scan.Status,
-			"result":               nullStringToString(scan.Result),
-			"error":                nullStringToString(scan.Error),
-			"stdout":               nullStringToString(scan.StdOut),
-			"stderr":               nullStringToString(scan.StdErr),
-			"command":              nullStringToString(scan.Command),
-			"execution_time":       nullStringToString(scan.ExecTime),
-			"created_at":           scan.CreatedAt.Format(time.RFC3339),
-			"scope_target_id":      scan.ScopeTargetID,
-			"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-		}
-		scans = append(scans, scanMap)
-	}
-
-	log.Printf("[METABIGOR-COMPANY] [INFO] Successfully retrieved %d Metabigor Company scans for scope target %s", len(scans), scopeTargetID)
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(scans); err != nil {
-		log.Printf("[METABIGOR-COMPANY] [ERROR] Failed to encode scans response: %v", err)
-	} else {
-		log.Printf("[METABIGOR-COMPANY] [INFO] Successfully sent Metabigor Company scans response")
-	}
-}
-
 // New endpoints for structured data
 func GetMetabigorNetworkRanges(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -650,21 +398,27 @@ func RunMetabigorNetdScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteMetabigorNetdScan(scanID, companyName)
+	if _, err := EnqueueScanJob("metabigor-netd", scanID, ScanJobPayload{Target: companyName}); err != nil {
+		log.Printf("[ERROR] Failed to queue metabigor-netd scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteMetabigorNetdScan(scanID, companyName string) {
+func ExecuteMetabigorNetdScan(ctx context.Context, scanID, companyName string) {
 	log.Printf("[METABIGOR-NETD] [INFO] Starting dynamic network scan for company %s (scan ID: %s)", companyName, scanID)
 	startTime := time.Now()
 
-	command := fmt.Sprintf("echo '%s' | /usr/bin/docker exec -i ars0n-framework-v2-metabigor-1 metabigor netd --org", companyName)
+	cmd := NewToolCommandContext(ctx, "metabigor", "metabigor", "netd", "--org")
+	cmd.Stdin = strings.NewReader(companyName + "\n")
+	command := cmd.String()
 
 	log.Printf("[METABIGOR-NETD] [DEBUG] Executing command: %s", command)
 
-	output, err := exec.Command("sh", "-c", command).CombinedOutput()
+	output, err := cmd.CombinedOutput()
 	if err != nil {
 		log.Printf("[METABIGOR-NETD] [ERROR] Command failed: %v", err)
 		UpdateMetabigorCompanyScanStatus(scanID, "error", "", fmt.Sprintf("Command failed: %v\nOutput: %s", err, string(output)), command, time.Since(startTime).String())
@@ -722,26 +476,32 @@ func RunMetabigorASNScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteMetabigorASNScan(scanID, asnNumber, scanType)
+	if _, err := EnqueueScanJob("metabigor-asn", scanID, ScanJobPayload{Target: asnNumber, ScanType: scanType}); err != nil {
+		log.Printf("[ERROR] Failed to queue metabigor-asn scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteMetabigorASNScan(scanID, asnNumber, scanType string) {
+func ExecuteMetabigorASNScan(ctx context.Context, scanID, asnNumber, scanType string) {
 	log.Printf("[METABIGOR-ASN] [INFO] Starting ASN scan for %s using %s (scan ID: %s)", asnNumber, scanType, scanID)
 	startTime := time.Now()
 
-	var command string
+	var cmd *ToolCommand
 	if scanType == "netd" {
-		command = fmt.Sprintf("echo '%s' | /usr/bin/docker exec -i ars0n-framework-v2-metabigor-1 metabigor netd --asn", asnNumber)
+		cmd = NewToolCommandContext(ctx, "metabigor", "metabigor", "netd", "--asn")
 	} else {
-		command = fmt.Sprintf("echo '%s' | /usr/bin/docker exec -i ars0n-framework-v2-metabigor-1 metabigor net --asn", asnNumber)
+		cmd = NewToolCommandContext(ctx, "metabigor", "metabigor", "net", "--asn")
 	}
+	cmd.Stdin = strings.NewReader(asnNumber + "\n")
+	command := cmd.String()
 
 	log.Printf("[METABIGOR-ASN] [DEBUG] Executing command: %s", command)
 
-	output, err := exec.Command("sh", "-c", command).CombinedOutput()
+	output, err := cmd.CombinedOutput()
 	if err != nil {
 		log.Printf("[METABIGOR-ASN] [ERROR] Command failed: %v", err)
 		UpdateMetabigorCompanyScanStatus(scanID, "error", "", fmt.Sprintf("Command failed: %v\nOutput: %s", err, string(output)), command, time.Since(startTime).String())
@@ -800,26 +560,32 @@ func RunMetabigorIPIntelligence(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteMetabigorIPIntelligence(scanID, ipList, scanType)
+	if _, err := EnqueueScanJob("metabigor-ip", scanID, ScanJobPayload{Target: ipList, ScanType: scanType}); err != nil {
+		log.Printf("[ERROR] Failed to queue metabigor-ip scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteMetabigorIPIntelligence(scanID, ipList, scanType string) {
+func ExecuteMetabigorIPIntelligence(ctx context.Context, scanID, ipList, scanType string) {
 	log.Printf("[METABIGOR-IP] [INFO] Starting IP intelligence scan (scan ID: %s)", scanID)
 	startTime := time.Now()
 
-	var command string
+	var cmd *ToolCommand
 	if scanType == "open" {
-		command = fmt.Sprintf("echo '%s' | /usr/bin/docker exec -i ars0n-framework-v2-metabigor-1 metabigor ip -open", ipList)
+		cmd = NewToolCommandContext(ctx, "metabigor", "metabigor", "ip", "-open")
 	} else {
-		command = fmt.Sprintf("echo '%s' | /usr/bin/docker exec -i ars0n-framework-v2-metabigor-1 metabigor ipc --json", ipList)
+		cmd = NewToolCommandContext(ctx, "metabigor", "metabigor", "ipc", "--json")
 	}
+	cmd.Stdin = strings.NewReader(ipList + "\n")
+	command := cmd.String()
 
 	log.Printf("[METABIGOR-IP] [DEBUG] Executing command: %s", command)
 
-	output, err := exec.Command("sh", "-c", command).CombinedOutput()
+	output, err := cmd.CombinedOutput()
 	if err != nil {
 		log.Printf("[METABIGOR-IP] [ERROR] Command failed: %v", err)
 		UpdateMetabigorCompanyScanStatus(scanID, "error", "", fmt.Sprintf("Command failed: %v\nOutput: %s", err, string(output)), command, time.Since(startTime).String())
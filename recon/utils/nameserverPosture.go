@@ -0,0 +1,861 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Nameserver posture tests the authoritative nameservers of each root
+// domain of a scope target: zone transfers (AXFR, then IXFR with serial 0),
+// open recursion, DNSSEC (DS at the parent, DNSKEYs that match it and
+// unexpired signatures) and whether the delegation at the parent agrees
+// with the NS records, SOA serials and authority of the servers
+// themselves. Names from a successful transfer become subdomains with the
+// source "axfr".
+
+const (
+	nsPostureConcurrency    = 5
+	nsPostureTimeout        = 90 * time.Second
+	nsPostureQueryTimeout   = 3 * time.Second
+	nsPostureMaxIPs         = 4
+	zoneTransferTimeout     = 30 * time.Second
+	zoneTransferRecordLimit = 50000
+	rrsigExpiryWarning      = 7 * 24 * time.Hour
+)
+
+// Out-of-zone names asked with RD set to detect open recursion
+var nsRecursionProbes = []string{"www.iana.org", "www.wikipedia.org"}
+
+type NameserverIssue struct {
+	Severity string `json:"severity"`
+	Check    string `json:"check"`
+	Message  string `json:"message"`
+}
+
+type NameserverCheck struct {
+	Host               string   `json:"host"`
+	IPs                []string `json:"ips"`
+	Responsive         bool     `json:"responsive"`
+	Authoritative      bool     `json:"authoritative"`
+	NS                 []string `json:"ns,omitempty"`
+	SOASerial          string   `json:"soa_serial,omitempty"`
+	OpenRecursion      bool     `json:"open_recursion"`
+	Transfer           string   `json:"transfer"` // refused, axfr, ixfr or unreachable
+	TransferError      string   `json:"transfer_error,omitempty"`
+	TransferredRecords int      `json:"transferred_records,omitempty"`
+	Error              string   `json:"error,omitempty"`
+
+	records []DNSResourceRecord
+}
+
+type DNSSECAnalysis struct {
+	Status           string     `json:"status"` // unsigned, no_ds, signed or bogus
+	DS               []string   `json:"ds,omitempty"`
+	DNSKEY           []string   `json:"dnskey,omitempty"`
+	DSKeyTags        []int      `json:"ds_key_tags,omitempty"`
+	DNSKEYKeyTags    []int      `json:"dnskey_key_tags,omitempty"`
+	DSMatched        bool       `json:"ds_matched"`
+	Validated        bool       `json:"validated"`
+	SignatureExpires *time.Time `json:"signature_expires,omitempty"`
+	Error            string     `json:"error,omitempty"`
+}
+
+type NameserverPosture struct {
+	Domain               string            `json:"domain"`
+	ParentZone           string            `json:"parent_zone"`
+	ParentNS             []string          `json:"parent_ns"`
+	ChildNS              []string          `json:"child_ns"`
+	Nameservers          []NameserverCheck `json:"nameservers"`
+	DelegationConsistent bool              `json:"delegation_consistent"`
+	AXFROpen             bool              `json:"axfr_open"`
+	OpenRecursion        bool              `json:"open_recursion"`
+	DNSSEC               *DNSSECAnalysis   `json:"dnssec"`
+	TransferredNames     int               `json:"transferred_names"`
+	Issues               []NameserverIssue `json:"issues"`
+	CheckedAt            time.Time         `json:"checked_at"`
+}
+
+var nameserverPostureRuns sync.Map
+
+func createNameserverPostureTables() {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS nameserver_posture_results (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			asset_id UUID REFERENCES consolidated_attack_surface_assets(id) ON DELETE SET NULL,
+			domain TEXT NOT NULL,
+			axfr_open BOOLEAN NOT NULL DEFAULT FALSE,
+			open_recursion BOOLEAN NOT NULL DEFAULT FALSE,
+			dnssec_status VARCHAR(20),
+			delegation_consistent BOOLEAN NOT NULL DEFAULT TRUE,
+			issue_count INTEGER NOT NULL DEFAULT 0,
+			report JSONB,
+			checked_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scope_target_id, domain)
+		);`,
+		`CREATE TABLE IF NOT EXISTS nameserver_axfr_records (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			domain TEXT NOT NULL,
+			nameserver TEXT NOT NULL,
+			name TEXT NOT NULL,
+			record_type VARCHAR(10) NOT NULL,
+			record_value TEXT NOT NULL,
+			ttl INTEGER,
+			first_seen TIMESTAMP DEFAULT NOW(),
+			last_seen TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scope_target_id, nameserver, name, record_type, record_value)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_nameserver_posture_results_scope_target_id ON nameserver_posture_results(scope_target_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_nameserver_axfr_records_scope_target_id ON nameserver_axfr_records(scope_target_id);`,
+	}
+	for _, query := range queries {
+		if _, err := dbPool.Exec(context.Background(), query); err != nil {
+			log.Printf("[NS-POSTURE] [ERROR] Failed to create table/index: %v", err)
+		}
+	}
+}
+
+func normalizeNSHosts(records []DNSResourceRecord, owner string) []string {
+	var hosts []string
+	for _, rr := range records {
+		if rr.Type != "NS" || !strings.EqualFold(strings.TrimSuffix(rr.Name, "."), owner) {
+			continue
+		}
+		if host := strings.ToLower(strings.TrimSuffix(rr.Value, ".")); host != "" && !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// parentDelegation asks the nameservers of the parent zone, without
+// recursion, for the NS records they hand out for domain.
+func parentDelegation(ctx context.Context, domain string) (string, []string, error) {
+	_, parent, ok := strings.Cut(domain, ".")
+	if !ok {
+		return "", nil, fmt.Errorf("%s has no parent zone", domain)
+	}
+	pool := defaultDNSPool()
+	records, err := pool.Lookup(ctx, parent, dnsTypeNS)
+	if err != nil {
+		return parent, nil, err
+	}
+	var lastErr error = fmt.Errorf("no nameservers answered for %s", parent)
+	for _, host := range normalizeNSHosts(records, parent) {
+		for _, ip := range pool.LookupIPs(ctx, host) {
+			resp, err := queryDNSServer(ctx, net.JoinHostPort(ip, "53"), domain, dnsTypeNS, dnsQueryOptions{}, nsPostureQueryTimeout)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			ns := normalizeNSHosts(append(resp.Authority, resp.Answers...), domain)
+			if len(ns) > 0 {
+				return parent, ns, nil
+			}
+			lastErr = fmt.Errorf("%s returned no delegation for %s (%s)", host, domain, dnsRcodeName(resp.Rcode))
+		}
+	}
+	return parent, nil, lastErr
+}
+
+// checkNameserver queries one of the domain's nameservers directly: its NS
+// and SOA answers, whether it recurses for strangers and whether it hands
+// out the zone.
+func checkNameserver(ctx context.Context, domain, host string) NameserverCheck {
+	check := NameserverCheck{Host: host, Transfer: "unreachable"}
+	check.IPs = defaultDNSPool().LookupIPs(ctx, host)
+	if len(check.IPs) == 0 {
+		check.Error = "nameserver does not resolve"
+		return check
+	}
+	ips := check.IPs
+	if len(ips) > nsPostureMaxIPs {
+		ips = ips[:nsPostureMaxIPs]
+	}
+
+	var probe string
+	for _, name := range nsRecursionProbes {
+		if !strings.HasSuffix(name, "."+domain) {
+			probe = name
+			break
+		}
+	}
+
+	for _, ip := range ips {
+		server := net.JoinHostPort(ip, "53")
+		resp, err := queryDNSServer(ctx, server, domain, dnsTypeNS, dnsQueryOptions{}, nsPostureQueryTimeout)
+		if err != nil {
+			check.Error = err.Error()
+			continue
+		}
+		check.Responsive = true
+		check.Error = ""
+		if resp.Rcode == dnsRcodeSuccess && resp.Authoritative {
+			check.Authoritative = true
+			check.NS = normalizeNSHosts(resp.Answers, domain)
+		} else {
+			check.Error = fmt.Sprintf("%s answered %s without authority", ip, dnsRcodeName(resp.Rcode))
+		}
+		if soa, err := queryDNSServer(ctx, server, domain, dnsTypeSOA, dnsQueryOptions{}, nsPostureQueryTimeout); err == nil {
+			for _, rr := range soa.Answers {
+				if fields := strings.Fields(rr.Value); rr.Type == "SOA" && len(fields) == 7 {
+					check.SOASerial = fields[2]
+				}
+			}
+		}
+		if probe != "" {
+			if rec, err := queryDNSServer(ctx, server, probe, dnsTypeA, dnsQueryOptions{Recursion: true}, nsPostureQueryTimeout); err == nil &&
+				rec.RecursionOK && rec.Rcode == dnsRcodeSuccess && len(rec.Answers) > 0 {
+				check.OpenRecursion = true
+			}
+		}
+		break
+	}
+	if !check.Responsive {
+		return check
+	}
+
+	// Transfer stays "unreachable" unless a server answers the request
+	for _, ip := range ips {
+		server := net.JoinHostPort(ip, "53")
+		for _, qtype := range []uint16{dnsTypeAXFR, dnsTypeIXFR} {
+			records, err := transferDNSZone(ctx, server, domain, qtype, zoneTransferRecordLimit, zoneTransferTimeout)
+			if len(records) > 1 {
+				check.Transfer = strings.ToLower(dnsTypeName(qtype))
+				check.TransferredRecords = len(records)
+				check.records = records
+				if err != nil {
+					check.TransferError = err.Error()
+				}
+				return check
+			}
+			if errors.Is(err, errZoneTransferRefused) {
+				check.Transfer = "refused"
+			}
+			if err != nil {
+				check.TransferError = err.Error()
+			}
+		}
+	}
+	return check
+}
+
+// dnskeyRDATA encodes a DNSKEY in presentation form ("flags protocol
+// algorithm base64key") back to its wire RDATA.
+func dnskeyRDATA(value string) ([]byte, bool) {
+	fields := strings.Fields(value)
+	if len(fields) < 4 {
+		return nil, false
+	}
+	flags, err1 := strconv.Atoi(fields[0])
+	protocol, err2 := strconv.Atoi(fields[1])
+	algorithm, err3 := strconv.Atoi(fields[2])
+	key, err4 := base64.StdEncoding.DecodeString(strings.Join(fields[3:], ""))
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return nil, false
+	}
+	rdata := binary.BigEndian.AppendUint16(nil, uint16(flags))
+	rdata = append(rdata, byte(protocol), byte(algorithm))
+	return append(rdata, key...), true
+}
+
+// dnskeyTag computes the RFC 4034 Appendix B key tag of a DNSKEY in
+// presentation form.
+func dnskeyTag(value string) (int, bool) {
+	rdata, ok := dnskeyRDATA(value)
+	if !ok {
+		return 0, false
+	}
+
+	var ac uint32
+	for i, b := range rdata {
+		if i&1 == 1 {
+			ac += uint32(b)
+		} else {
+			ac += uint32(b) << 8
+		}
+	}
+	ac += (ac >> 16) & 0xffff
+	return int(ac & 0xffff), true
+}
+
+// dsMatchesDNSKEY checks a DS ("keytag algorithm digesttype digest") against
+// a DNSKEY of owner: the digest must equal the hash of the owner name in
+// canonical wire form followed by the DNSKEY RDATA (RFC 4034 section 5.1.4).
+// Digest types other than SHA-1, SHA-256 and SHA-384 never match.
+func dsMatchesDNSKEY(owner, ds, dnskey string) bool {
+	fields := strings.Fields(ds)
+	if len(fields) < 4 {
+		return false
+	}
+	tag, err1 := strconv.Atoi(fields[0])
+	algorithm, err2 := strconv.Atoi(fields[1])
+	digestType, err3 := strconv.Atoi(fields[2])
+	digest, err4 := hex.DecodeString(strings.Join(fields[3:], ""))
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return false
+	}
+	rdata, ok := dnskeyRDATA(dnskey)
+	if !ok || int(rdata[3]) != algorithm {
+		return false
+	}
+	if keyTag, _ := dnskeyTag(dnskey); keyTag != tag {
+		return false
+	}
+	name, err := encodeDNSName(strings.ToLower(owner))
+	if err != nil {
+		return false
+	}
+
+	var h hash.Hash
+	switch digestType {
+	case 1:
+		h = sha1.New()
+	case 2:
+		h = sha256.New()
+	case 4:
+		h = sha512.New384()
+	default:
+		return false
+	}
+	h.Write(name)
+	h.Write(rdata)
+	return bytes.Equal(h.Sum(nil), digest)
+}
+
+// analyzeDNSSEC compares the DS records at the parent with the zone's
+// DNSKEYs and checks the signature over the DNSKEY set. A DS whose digest
+// matches no key, or a zone the resolvers fail to answer once DO is set,
+// is bogus and will not resolve for validating clients.
+func analyzeDNSSEC(ctx context.Context, domain string) *DNSSECAnalysis {
+	pool := defaultDNSPool()
+	a := &DNSSECAnalysis{Status: "unsigned"}
+
+	if resp, err := pool.QueryDNSSEC(ctx, domain, dnsTypeDS); err == nil {
+		for _, rr := range resp.Answers {
+			if rr.Type != "DS" {
+				continue
+			}
+			a.DS = append(a.DS, rr.Value)
+			if fields := strings.Fields(rr.Value); len(fields) == 4 {
+				if tag, err := strconv.Atoi(fields[0]); err == nil {
+					a.DSKeyTags = append(a.DSKeyTags, tag)
+				}
+			}
+		}
+	}
+
+	resp, err := pool.QueryDNSSEC(ctx, domain, dnsTypeDNSKEY)
+	if err != nil {
+		if len(a.DS) > 0 {
+			a.Status = "bogus"
+			a.Error = err.Error()
+		}
+		return a
+	}
+	a.Validated = resp.Authenticated
+	for _, rr := range resp.Answers {
+		switch rr.Type {
+		case "DNSKEY":
+			a.DNSKEY = append(a.DNSKEY, rr.Value)
+			if tag, ok := dnskeyTag(rr.Value); ok {
+				a.DNSKEYKeyTags = append(a.DNSKEYKeyTags, tag)
+			}
+			for _, ds := range a.DS {
+				if dsMatchesDNSKEY(domain, ds, rr.Value) {
+					a.DSMatched = true
+				}
+			}
+		case "RRSIG":
+			// "covered algorithm labels ttl expiration inception tag signer signature"
+			fields := strings.Fields(rr.Value)
+			if len(fields) < 5 || fields[0] != "DNSKEY" {
+				continue
+			}
+			if expires, err := time.Parse("20060102150405", fields[4]); err == nil {
+				if a.SignatureExpires == nil || expires.Before(*a.SignatureExpires) {
+					a.SignatureExpires = &expires
+				}
+			}
+		}
+	}
+
+	switch {
+	case len(a.DS) == 0 && len(a.DNSKEY) == 0:
+		a.Status = "unsigned"
+	case len(a.DS) == 0:
+		a.Status = "no_ds"
+	case !a.DSMatched:
+		a.Status = "bogus"
+		a.Error = "no DNSKEY matches the digest of a DS at the parent"
+	case a.SignatureExpires != nil && a.SignatureExpires.Before(time.Now()):
+		a.Status = "bogus"
+		a.Error = "DNSKEY signature has expired"
+	default:
+		a.Status = "signed"
+	}
+	return a
+}
+
+// AnalyzeNameserverPosture runs every nameserver check for one root domain.
+func AnalyzeNameserverPosture(ctx context.Context, domain string) *NameserverPosture {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	p := &NameserverPosture{Domain: domain, Nameservers: []NameserverCheck{}, Issues: []NameserverIssue{}, CheckedAt: time.Now()}
+	issue := func(severity, check, message string) {
+		p.Issues = append(p.Issues, NameserverIssue{Severity: severity, Check: check, Message: message})
+	}
+
+	parent, parentNS, err := parentDelegation(ctx, domain)
+	p.ParentZone, p.ParentNS = parent, parentNS
+	if err != nil {
+		issue("info", "delegation", "Parent delegation could not be read: "+err.Error())
+	}
+	if records, err := defaultDNSPool().Lookup(ctx, domain, dnsTypeNS); err == nil {
+		p.ChildNS = normalizeNSHosts(records, domain)
+	}
+	if len(p.ParentNS) == 0 && len(p.ChildNS) == 0 {
+		issue("info", "delegation", "No nameservers found")
+		p.DNSSEC = &DNSSECAnalysis{Status: "unsigned"}
+		return p
+	}
+
+	hosts := append([]string(nil), p.ParentNS...)
+	for _, host := range p.ChildNS {
+		if !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	checks := make([]NameserverCheck, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			checks[i] = checkNameserver(ctx, domain, host)
+		}(i, host)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.DNSSEC = analyzeDNSSEC(ctx, domain)
+	}()
+	wg.Wait()
+	p.Nameservers = checks
+
+	// Delegation: the parent and the zone should list the same servers,
+	// every server should answer with authority, and agree on the serial
+	p.DelegationConsistent = true
+	inconsistent := func(message string) {
+		p.DelegationConsistent = false
+		issue("medium", "delegation", message)
+	}
+	if len(p.ParentNS) > 0 && len(p.ChildNS) > 0 {
+		for _, host := range p.ParentNS {
+			if !slices.Contains(p.ChildNS, host) {
+				inconsistent(fmt.Sprintf("%s is delegated to by %s but missing from the zone's NS records", host, p.ParentZone))
+			}
+		}
+		for _, host := range p.ChildNS {
+			if !slices.Contains(p.ParentNS, host) {
+				inconsistent(fmt.Sprintf("%s is in the zone's NS records but not delegated to by %s", host, p.ParentZone))
+			}
+		}
+	}
+	serials := make(map[string][]string)
+	for _, c := range checks {
+		switch {
+		case !c.Responsive:
+			inconsistent(fmt.Sprintf("Lame delegation: %s does not answer (%s)", c.Host, c.Error))
+		case !c.Authoritative:
+			inconsistent(fmt.Sprintf("Lame delegation: %s is not authoritative for %s", c.Host, domain))
+		}
+		if c.SOASerial != "" {
+			serials[c.SOASerial] = append(serials[c.SOASerial], c.Host)
+		}
+		if c.Authoritative && len(p.ChildNS) > 0 && len(c.NS) > 0 && !slices.Equal(c.NS, p.ChildNS) {
+			inconsistent(fmt.Sprintf("%s answers with a different NS set: %s", c.Host, strings.Join(c.NS, ", ")))
+		}
+	}
+	if len(serials) > 1 {
+		var parts []string
+		for serial, hosts := range serials {
+			parts = append(parts, serial+" ("+strings.Join(hosts, ", ")+")")
+		}
+		sort.Strings(parts)
+		inconsistent("Nameservers disagree on the SOA serial: " + strings.Join(parts, "; "))
+	}
+	if len(hosts) < 2 {
+		issue("low", "delegation", "Only one nameserver is listed")
+	}
+
+	names := make(map[string]bool)
+	for _, c := range checks {
+		if c.Transfer == "axfr" || c.Transfer == "ixfr" {
+			p.AXFROpen = true
+			issue("high", "axfr", fmt.Sprintf("%s allows zone transfers (%s, %d records)", c.Host, strings.ToUpper(c.Transfer), c.TransferredRecords))
+			for _, rr := range c.records {
+				names[strings.ToLower(strings.TrimSuffix(rr.Name, "."))] = true
+			}
+		}
+		if c.OpenRecursion {
+			p.OpenRecursion = true
+			issue("medium", "recursion", fmt.Sprintf("%s resolves names outside its zones for anyone", c.Host))
+		}
+	}
+	p.TransferredNames = len(names)
+
+	switch p.DNSSEC.Status {
+	case "bogus":
+		issue("high", "dnssec", "DNSSEC is broken, so validating resolvers cannot resolve the domain: "+p.DNSSEC.Error)
+	case "no_ds":
+		issue("low", "dnssec", "Zone is signed but has no DS record at the parent, so it is never validated")
+	case "unsigned":
+		issue("low", "dnssec", "Zone is not signed with DNSSEC")
+	case "signed":
+		if !p.DNSSEC.Validated {
+			issue("medium", "dnssec", "A DS is published but the resolver answered the DNSKEY query without the AD flag, so the chain of trust did not validate")
+		}
+		if p.DNSSEC.SignatureExpires != nil && time.Until(*p.DNSSEC.SignatureExpires) < rrsigExpiryWarning {
+			issue("medium", "dnssec", "DNSKEY signature expires "+p.DNSSEC.SignatureExpires.Format(time.RFC3339))
+		}
+	}
+	return p
+}
+
+// RunNameserverPosture checks the root domain of every FQDN asset and
+// company domain of a scope target, stores the result against the root
+// domain's FQDN asset and ingests the names of any zone transfer.
+func RunNameserverPosture(scopeTargetID string) error {
+	if _, running := nameserverPostureRuns.LoadOrStore(scopeTargetID, true); running {
+		log.Printf("[NS-POSTURE] [INFO] Checks already running for %s, skipping", scopeTargetID)
+		return nil
+	}
+	defer nameserverPostureRuns.Delete(scopeTargetID)
+
+	createNameserverPostureTables()
+	startTime := time.Now()
+	ctx := context.Background()
+
+	assetIDs := make(map[string]string)
+	rows, err := dbPool.Query(ctx, `
+		SELECT id::text, LOWER(fqdn) FROM consolidated_attack_surface_assets
+		WHERE scope_target_id = $1::uuid AND asset_type = 'fqdn' AND fqdn IS NOT NULL AND fqdn != ''
+		UNION ALL
+		SELECT '', LOWER(domain) FROM consolidated_company_domains WHERE scope_target_id = $1::uuid`, scopeTargetID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch domains: %v", err)
+	}
+	roots := make(map[string]bool)
+	for rows.Next() {
+		var id, name string
+		if rows.Scan(&id, &name) != nil {
+			continue
+		}
+		if id != "" {
+			assetIDs[name] = id
+		}
+		if root := extractRootDomain(name); root != "" {
+			roots[root] = true
+		}
+	}
+	rows.Close()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var results []*NameserverPosture
+	semaphore := make(chan struct{}, nsPostureConcurrency)
+	for root := range roots {
+		wg.Add(1)
+		go func(domain string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			domainCtx, cancel := context.WithTimeout(ctx, nsPostureTimeout)
+			defer cancel()
+			p := AnalyzeNameserverPosture(domainCtx, domain)
+			mu.Lock()
+			results = append(results, p)
+			mu.Unlock()
+		}(root)
+	}
+	wg.Wait()
+
+	transferred := 0
+	checked := make([]string, 0, len(results))
+	storedAssets := make([]string, 0, len(results))
+	for _, p := range results {
+		checked = append(checked, p.Domain)
+		assetID, ok := assetIDs[p.Domain]
+		if !ok {
+			err := dbPool.QueryRow(ctx, `
+				INSERT INTO consolidated_attack_surface_assets (scope_target_id, asset_type, asset_identifier, fqdn, root_domain)
+				VALUES ($1::uuid, 'fqdn', $2, $2, $2)
+				ON CONFLICT (scope_target_id, asset_type, asset_identifier) DO UPDATE SET last_updated = NOW()
+				RETURNING id::text`, scopeTargetID, p.Domain).Scan(&assetID)
+			if err != nil {
+				log.Printf("[NS-POSTURE] [ERROR] Failed to add FQDN asset for %s: %v", p.Domain, err)
+				continue
+			}
+		}
+		if err := storeNameserverPosture(ctx, scopeTargetID, assetID, p); err != nil {
+			log.Printf("[NS-POSTURE] [ERROR] Failed to store result for %s: %v", p.Domain, err)
+		} else {
+			storedAssets = append(storedAssets, assetID)
+		}
+		count, err := ingestZoneTransfer(ctx, scopeTargetID, p)
+		if err != nil {
+			log.Printf("[NS-POSTURE] [ERROR] Failed to ingest zone transfer of %s: %v", p.Domain, err)
+		}
+		transferred += count
+	}
+
+	// Drop results for root domains that are no longer part of the scope target
+	stale := []struct{ what, query string }{
+		{"results", `DELETE FROM nameserver_posture_results WHERE scope_target_id = $1::uuid AND NOT (domain = ANY($2))`},
+		{"zone transfer records", `DELETE FROM nameserver_axfr_records WHERE scope_target_id = $1::uuid AND NOT (domain = ANY($2))`},
+	}
+	for _, q := range stale {
+		if _, err := dbPool.Exec(ctx, q.query, scopeTargetID, checked); err != nil {
+			log.Printf("[NS-POSTURE] [ERROR] Failed to clear stale %s: %v", q.what, err)
+		}
+	}
+	_, err = dbPool.Exec(ctx, `
+		DELETE FROM consolidated_attack_surface_metadata m
+		USING consolidated_attack_surface_assets a
+		WHERE m.asset_id = a.id AND a.scope_target_id = $1::uuid AND m.metadata_type = 'nameserver_posture'
+			AND NOT (a.id::text = ANY($2))`, scopeTargetID, storedAssets)
+	if err != nil {
+		log.Printf("[NS-POSTURE] [ERROR] Failed to clear stale metadata: %v", err)
+	}
+
+	log.Printf("[NS-POSTURE] [INFO] Checked %d root domains for %s in %s (%d names from zone transfers)",
+		len(results), scopeTargetID, time.Since(startTime), transferred)
+	return nil
+}
+
+func storeNameserverPosture(ctx context.Context, scopeTargetID, assetID string, p *NameserverPosture) error {
+	report, _ := json.Marshal(p)
+	nsRecords := p.ChildNS
+	if len(nsRecords) == 0 {
+		nsRecords = p.ParentNS
+	}
+
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, `
+		INSERT INTO nameserver_posture_results (scope_target_id, asset_id, domain, axfr_open, open_recursion, dnssec_status,
+			delegation_consistent, issue_count, report, checked_at)
+		VALUES ($1::uuid, $2::uuid, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (scope_target_id, domain) DO UPDATE SET
+			asset_id = EXCLUDED.asset_id, axfr_open = EXCLUDED.axfr_open, open_recursion = EXCLUDED.open_recursion,
+			dnssec_status = EXCLUDED.dnssec_status, delegation_consistent = EXCLUDED.delegation_consistent,
+			issue_count = EXCLUDED.issue_count, report = EXCLUDED.report, checked_at = EXCLUDED.checked_at`,
+		scopeTargetID, assetID, p.Domain, p.AXFROpen, p.OpenRecursion, p.DNSSEC.Status,
+		p.DelegationConsistent, len(p.Issues), report, p.CheckedAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO consolidated_attack_surface_metadata (asset_id, metadata_type, metadata_key, metadata_value, metadata_json)
+		VALUES ($1::uuid, 'nameserver_posture', 'dnssec', $2, $3)
+		ON CONFLICT (asset_id, metadata_type, metadata_key) DO UPDATE SET
+			metadata_value = EXCLUDED.metadata_value,
+			metadata_json = EXCLUDED.metadata_json`,
+		assetID, p.DNSSEC.Status, report)
+	if err != nil {
+		return err
+	}
+	if len(nsRecords) > 0 {
+		_, err = tx.Exec(ctx, `
+			UPDATE consolidated_attack_surface_assets SET ns_records = $2, last_updated = NOW()
+			WHERE id = $1::uuid`, assetID, nsRecords)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// ingestZoneTransfer replaces the stored records of every nameserver that
+// answered the transfer request with what it sent this time, and adds the
+// owner names as subdomains and FQDN assets. Service labels such as _dmarc
+// and wildcards are kept in the records but are not hosts. Records of an
+// unreachable nameserver are kept until it answers again.
+func ingestZoneTransfer(ctx context.Context, scopeTargetID string, p *NameserverPosture) (int, error) {
+	var baseDomain string
+	dbPool.QueryRow(ctx, `SELECT TRIM(LEADING '*.' FROM scope_target) FROM scope_targets WHERE id = $1`, scopeTargetID).Scan(&baseDomain)
+
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var answered []string
+	for _, c := range p.Nameservers {
+		if c.Transfer != "unreachable" {
+			answered = append(answered, c.Host)
+		}
+	}
+	_, err = tx.Exec(ctx, `
+		DELETE FROM nameserver_axfr_records
+		WHERE scope_target_id = $1::uuid AND domain = $2 AND nameserver = ANY($3)`, scopeTargetID, p.Domain, answered)
+	if err != nil {
+		return 0, err
+	}
+
+	hosts := make(map[string]bool)
+	for _, c := range p.Nameservers {
+		for _, rr := range c.records {
+			name := strings.ToLower(strings.TrimSuffix(rr.Name, "."))
+			_, err := tx.Exec(ctx, `
+				INSERT INTO nameserver_axfr_records (scope_target_id, domain, nameserver, name, record_type, record_value, ttl)
+				VALUES ($1::uuid, $2, $3, $4, $5, $6, $7)
+				ON CONFLICT (scope_target_id, nameserver, name, record_type, record_value) DO UPDATE SET
+					ttl = EXCLUDED.ttl, last_seen = NOW()`,
+				scopeTargetID, p.Domain, c.Host, name, rr.Type, rr.Value, int(rr.TTL))
+			if err != nil {
+				return 0, err
+			}
+			if isTransferredHost(name, p.Domain) {
+				hosts[name] = true
+			}
+		}
+	}
+
+	for host := range hosts {
+		if baseDomain != "" && strings.HasSuffix(host, baseDomain) {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO consolidated_subdomains (scope_target_id, subdomain) VALUES ($1, $2)
+				ON CONFLICT (scope_target_id, subdomain) DO NOTHING`, scopeTargetID, host)
+			if err != nil {
+				return 0, err
+			}
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO consolidated_attack_surface_assets (scope_target_id, asset_type, asset_identifier, fqdn, root_domain)
+			VALUES ($1::uuid, 'fqdn', $2, $2, $3)
+			ON CONFLICT (scope_target_id, asset_type, asset_identifier) DO NOTHING`, scopeTargetID, host, p.Domain)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(hosts), tx.Commit(ctx)
+}
+
+func isTransferredHost(name, domain string) bool {
+	if name != domain && !strings.HasSuffix(name, "."+domain) {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "*" || strings.HasPrefix(label, "_") {
+			return false
+		}
+	}
+	return true
+}
+
+// axfrSubdomains returns the host names learned from zone transfers, for
+// subdomain consolidation.
+func axfrSubdomains(scopeTargetID string) ([]string, error) {
+	createNameserverPostureTables()
+	rows, err := dbPool.Query(context.Background(),
+		`SELECT DISTINCT name, domain FROM nameserver_axfr_records WHERE scope_target_id = $1`, scopeTargetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name, domain string
+		if err := rows.Scan(&name, &domain); err != nil {
+			return nil, err
+		}
+		if isTransferredHost(name, domain) {
+			names = append(names, name)
+		}
+	}
+	return names, rows.Err()
+}
+
+// GetNameserverPosture lists the nameserver checks of a scope target's root
+// domains, most issues first. ?axfr=true limits to open zone transfers.
+func GetNameserverPosture(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	if scopeTargetID == "" {
+		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
+		return
+	}
+	axfrOnly := r.URL.Query().Get("axfr") == "true"
+
+	createNameserverPostureTables()
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT report FROM nameserver_posture_results
+		WHERE scope_target_id = $1::uuid AND (NOT $2 OR axfr_open)
+		ORDER BY axfr_open DESC, issue_count DESC, domain`, scopeTargetID, axfrOnly)
+	if err != nil {
+		log.Printf("[NS-POSTURE] [ERROR] Failed to get nameserver posture: %v", err)
+		http.Error(w, "Failed to get nameserver posture", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	results := []NameserverPosture{}
+	for rows.Next() {
+		var report []byte
+		var p NameserverPosture
+		if err := rows.Scan(&report); err != nil || json.Unmarshal(report, &p) != nil {
+			log.Printf("[NS-POSTURE] [ERROR] Error reading nameserver posture row: %v", err)
+			continue
+		}
+		results = append(results, p)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// RunNameserverPostureHandler starts the nameserver checks in the background.
+func RunNameserverPostureHandler(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	if scopeTargetID == "" {
+		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
+		return
+	}
+
+	go func() {
+		if err := RunNameserverPosture(scopeTargetID); err != nil {
+			log.Printf("[NS-POSTURE] [ERROR] Checks failed for %s: %v", scopeTargetID, err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "started"})
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -128,7 +127,7 @@ func (lw *logWriter) Write(p []byte) (n int, err error) {
 }
 
 // executeNucleiScan executes a Nuclei scan with the given parameters
-func executeNucleiScan(targets []string, templates []string, severities []string, uploadedTemplates []map[string]interface{}, outputFile string) error {
+func executeNucleiScan(ctx context.Context, targets []string, templates []string, severities []string, uploadedTemplates []map[string]interface{}, outputFile string) error {
 	log.Printf("[DEBUG] Starting Nuclei scan with %d targets", len(targets))
 	log.Printf("[DEBUG] Targets: %v", targets)
 	log.Printf("[DEBUG] Templates: %v", templates)
@@ -154,12 +153,7 @@ func executeNucleiScan(targets []string, templates []string, severities []string
 	log.Printf("[DEBUG] Number of targets: %d", len(targets))
 
 	// Copy the targets file into the container
-	copyCmd := exec.Command(
-		"docker", "cp",
-		tempFile.Name(),
-		"ars0n-framework-v2-nuclei-1:/targets.txt",
-	)
-	if err := copyCmd.Run(); err != nil {
+	if err := CopyToTool("nuclei", tempFile.Name(), "/targets.txt"); err != nil {
 		return fmt.Errorf("failed to copy targets file to container: %v", err)
 	}
 
@@ -213,7 +207,7 @@ func executeNucleiScan(targets []string, templates []string, severities []string
 	// Handle custom templates
 	if len(uploadedTemplates) > 0 {
 		// Create custom templates directory in container
-		mkdirCmd := exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "mkdir", "-p", "/custom_templates")
+		mkdirCmd := NewToolCommandContext(ctx, "nuclei", "mkdir", "-p", "/custom_templates")
 		if err := mkdirCmd.Run(); err != nil {
 			return fmt.Errorf("failed to create custom templates directory: %v", err)
 		}
@@ -237,8 +231,7 @@ func executeNucleiScan(targets []string, templates []string, severities []string
 
 				// Copy template to container
 				templatePath := fmt.Sprintf("/custom_templates/custom_%d.yaml", i)
-				copyTemplateCmd := exec.Command("docker", "cp", tempTemplateFile.Name(), "ars0n-framework-v2-nuclei-1:"+templatePath)
-				if err := copyTemplateCmd.Run(); err != nil {
+				if err := CopyToTool("nuclei", tempTemplateFile.Name(), templatePath); err != nil {
 					log.Printf("[WARN] Failed to copy custom template %d to container: %v", i, err)
 				}
 				
@@ -250,31 +243,21 @@ func executeNucleiScan(targets []string, templates []string, severities []string
 		args = append(args, "-t", "/custom_templates")
 	}
 
-	// Build docker exec command
-	dockerArgs := []string{"exec", "-i", "ars0n-framework-v2-nuclei-1", "nuclei"}
-	dockerArgs = append(dockerArgs, args...)
-
-	// Execute Nuclei command via docker exec
-	log.Printf("[INFO] Executing Nuclei command: docker %s", strings.Join(dockerArgs, " "))
-	dockerCmd := exec.Command("docker", dockerArgs...)
+	// Build nuclei command
+	nucleiCmd := NewToolCommandContext(ctx, "nuclei", append([]string{"nuclei"}, args...)...)
+	log.Printf("[INFO] Executing Nuclei command: %s", nucleiCmd.String())
 
 	// Create custom writers that log output in real-time
 	stdoutWriter := &logWriter{prefix: "[NUCLEI]"}
 	stderrWriter := &logWriter{prefix: "[NUCLEI-ERR]"}
 	
-	dockerCmd.Stdout = stdoutWriter
-	dockerCmd.Stderr = stderrWriter
-
-	// Start the command
-	if err = dockerCmd.Start(); err != nil {
-		log.Printf("[ERROR] Failed to start Nuclei command: %v", err)
-		return fmt.Errorf("failed to start nuclei: %v", err)
-	}
+	nucleiCmd.Stdout = stdoutWriter
+	nucleiCmd.Stderr = stderrWriter
 
 	log.Printf("[INFO] Nuclei scan started, streaming output...")
 
 	// Wait for command to complete
-	if err = dockerCmd.Wait(); err != nil {
+	if err = nucleiCmd.Run(); err != nil {
 		log.Printf("[ERROR] Nuclei command failed: %v", err)
 		return fmt.Errorf("nuclei execution failed: %v", err)
 	}
@@ -282,15 +265,10 @@ func executeNucleiScan(targets []string, templates []string, severities []string
 	log.Printf("[INFO] Nuclei scan completed successfully")
 
 	// Copy the output file from container to host
-	copyOutputCmd := exec.Command(
-		"docker", "cp",
-		"ars0n-framework-v2-nuclei-1:/output.jsonl",
-		outputFile,
-	)
-	if err := copyOutputCmd.Run(); err != nil {
+	if err := CopyFromTool("nuclei", "/output.jsonl", outputFile); err != nil {
 		log.Printf("[WARN] Failed to copy output file from container: %v", err)
 		// Try to read output directly from container
-		readOutputCmd := exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "cat", "/output.jsonl")
+		readOutputCmd := NewToolCommandContext(ctx, "nuclei", "cat", "/output.jsonl")
 		if outputContent, readErr := readOutputCmd.Output(); readErr == nil {
 			if writeErr := os.WriteFile(outputFile, outputContent, 0644); writeErr != nil {
 				return fmt.Errorf("failed to copy output from container and write to host: %v", writeErr)
@@ -319,7 +297,7 @@ func executeNucleiScan(targets []string, templates []string, severities []string
 	}
 
 	// Clean up files in container
-	cleanupCmd := exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "rm", "-f", "/targets.txt", "/output.jsonl")
+	cleanupCmd := NewToolCommandContext(ctx, "nuclei", "rm", "-f", "/targets.txt", "/output.jsonl")
 	cleanupCmd.Run() // Ignore errors for cleanup
 
 	return nil
@@ -367,7 +345,7 @@ func parseNucleiResults(outputFile string) ([]NucleiFinding, error) {
 }
 
 // ExecuteNucleiScanForScopeTarget executes a complete Nuclei scan for a scope target
-func ExecuteNucleiScanForScopeTarget(scopeTargetID string, selectedTargets []string, selectedTemplates []string, selectedSeverities []string, uploadedTemplates []map[string]interface{}, dbPool *pgxpool.Pool) (string, []NucleiFinding, error) {
+func ExecuteNucleiScanForScopeTarget(ctx context.Context, scopeTargetID string, selectedTargets []string, selectedTemplates []string, selectedSeverities []string, uploadedTemplates []map[string]interface{}, dbPool *pgxpool.Pool) (string, []NucleiFinding, error) {
 	// Convert attack surface assets to Nuclei targets
 	targets, err := convertAttackSurfaceAssetsToTargets(selectedTargets, scopeTargetID, dbPool)
 	if err != nil {
@@ -387,7 +365,7 @@ func ExecuteNucleiScanForScopeTarget(scopeTargetID string, selectedTargets []str
 	outputFile := filepath.Join(outputDir, fmt.Sprintf("nuclei_scan_%s_%d.jsonl", scopeTargetID, time.Now().Unix()))
 
 	// Execute the scan
-	if err := executeNucleiScan(targets, selectedTemplates, selectedSeverities, uploadedTemplates, outputFile); err != nil {
+	if err := executeNucleiScan(ctx, targets, selectedTemplates, selectedSeverities, uploadedTemplates, outputFile); err != nil {
 		return "", nil, fmt.Errorf("scan execution failed: %v", err)
 	}
 
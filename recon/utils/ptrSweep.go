@@ -0,0 +1,516 @@
+package utils
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ptrSweepTool resolves the PTR record of every address in a company's
+// consolidated network ranges through the DNS resolver pool. Progress is
+// kept per range in ptr_sweep_cursors, so a retried job, or a new sweep
+// after a cancelled one, continues where the last one stopped.
+type ptrSweepTool struct{ executorTool }
+
+func init() {
+	RegisterTool(ptrSweepTool{executorTool{
+		toolBase: toolBase{name: "ptr-sweep", input: ToolInputCIDR, table: "ptr_sweep_scans"},
+		execute:  ExecutePTRSweep,
+	}})
+}
+
+func (ptrSweepTool) Schema() string {
+	return scanTableSchema("ptr_sweep_scans", "")
+}
+
+const (
+	ptrSweepBatchSize     = 256
+	ptrSweepLookupTimeout = 5 * time.Second
+)
+
+type PTRSweepCursor struct {
+	CIDRBlock      string     `json:"cidr_block"`
+	ScanID         string     `json:"scan_id"`
+	TotalAddresses int64      `json:"total_addresses"`
+	NextOffset     int64      `json:"next_offset"`
+	Status         string     `json:"status"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
+
+type PTRSweepRecord struct {
+	IPAddress string    `json:"ip_address"`
+	Hostname  string    `json:"hostname"`
+	CIDRBlock string    `json:"cidr_block"`
+	Generic   bool      `json:"generic"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+func createPTRSweepTables() {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS ptr_sweep_cursors (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			cidr_block TEXT NOT NULL,
+			scan_id UUID,
+			total_addresses BIGINT NOT NULL DEFAULT 0,
+			next_offset BIGINT NOT NULL DEFAULT 0,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			updated_at TIMESTAMP DEFAULT NOW(),
+			completed_at TIMESTAMP,
+			UNIQUE(scope_target_id, cidr_block)
+		);`,
+		`CREATE TABLE IF NOT EXISTS ptr_sweep_records (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			scan_id UUID,
+			cidr_block TEXT NOT NULL,
+			ip_address INET NOT NULL,
+			hostname TEXT NOT NULL,
+			generic BOOLEAN NOT NULL DEFAULT false,
+			first_seen TIMESTAMP DEFAULT NOW(),
+			last_seen TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scope_target_id, ip_address, hostname)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_ptr_sweep_records_scope_target_id ON ptr_sweep_records(scope_target_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_ptr_sweep_records_scan_id ON ptr_sweep_records(scan_id);`,
+	}
+	for _, query := range queries {
+		if _, err := dbPool.Exec(context.Background(), query); err != nil {
+			log.Printf("[PTR-SWEEP] [ERROR] Failed to create table/index: %v", err)
+		}
+	}
+}
+
+// ptrSweepSize is the number of addresses in prefix, or false when the
+// range is bigger than max.
+func ptrSweepSize(prefix netip.Prefix, max int64) (int64, bool) {
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > 62 || int64(1)<<hostBits > max {
+		return 0, false
+	}
+	return int64(1) << hostBits, true
+}
+
+// ptrSweepAddress is the address offset places after the start of prefix.
+func ptrSweepAddress(prefix netip.Prefix, offset int64) netip.Addr {
+	start := prefix.Masked().Addr()
+	if start.Is4() {
+		b := start.As4()
+		return netip.AddrFrom4(binaryAdd4(b, uint32(offset)))
+	}
+	b := start.As16()
+	low := binary.BigEndian.Uint64(b[8:]) + uint64(offset)
+	binary.BigEndian.PutUint64(b[8:], low)
+	return netip.AddrFrom16(b)
+}
+
+func binaryAdd4(b [4]byte, n uint32) [4]byte {
+	binary.BigEndian.PutUint32(b[:], binary.BigEndian.Uint32(b[:])+n)
+	return b
+}
+
+// isGenericPTR reports PTR names that only spell out the address, such as
+// 203-0-113-7.static.example-isp.net, which say nothing about who uses it.
+func isGenericPTR(hostname string, addr netip.Addr) bool {
+	if !addr.Is4() {
+		return strings.Contains(hostname, strings.ReplaceAll(addr.StringExpanded(), ":", ""))
+	}
+	o := addr.As4()
+	forward := []string{strconv.Itoa(int(o[0])), strconv.Itoa(int(o[1])), strconv.Itoa(int(o[2])), strconv.Itoa(int(o[3]))}
+	reverse := []string{forward[3], forward[2], forward[1], forward[0]}
+	for _, parts := range [][]string{forward, reverse} {
+		for _, sep := range []string{"-", ".", "_", ""} {
+			if sep == "" {
+				if strings.Contains(hostname, fmt.Sprintf("%03d%03d%03d%03d", o[0], o[1], o[2], o[3])) {
+					return true
+				}
+				continue
+			}
+			if strings.Contains(hostname, strings.Join(parts, sep)) {
+				return true
+			}
+		}
+	}
+	return strings.Contains(hostname, fmt.Sprintf("%02x%02x%02x%02x", o[0], o[1], o[2], o[3]))
+}
+
+// preparePTRSweepCursors lines up one cursor per consolidated network range.
+// Ranges the previous sweep finished start over; unfinished ones keep their
+// offset and are taken over by this scan.
+func preparePTRSweepCursors(scanID, scopeTargetID string) ([]PTRSweepCursor, error) {
+	maxAddresses := int64(envInt("RECON_PTR_SWEEP_MAX_ADDRESSES", 1<<20))
+	rows, err := dbPool.Query(context.Background(),
+		`SELECT DISTINCT cidr_block FROM consolidated_network_ranges WHERE scope_target_id = $1 ORDER BY cidr_block`, scopeTargetID)
+	if err != nil {
+		return nil, err
+	}
+	var cidrs []string
+	for rows.Next() {
+		var cidr string
+		if err := rows.Scan(&cidr); err == nil {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+	rows.Close()
+
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			log.Printf("[PTR-SWEEP] [WARN] Skipping invalid range %s: %v", cidr, err)
+			continue
+		}
+		size, ok := ptrSweepSize(prefix, maxAddresses)
+		status := "pending"
+		if !ok {
+			log.Printf("[PTR-SWEEP] [WARN] Skipping %s: larger than RECON_PTR_SWEEP_MAX_ADDRESSES (%d)", cidr, maxAddresses)
+			status = "skipped"
+		}
+		_, err = dbPool.Exec(context.Background(), `
+			INSERT INTO ptr_sweep_cursors (scope_target_id, cidr_block, scan_id, total_addresses, status)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (scope_target_id, cidr_block) DO UPDATE SET
+				next_offset = CASE WHEN ptr_sweep_cursors.status IN ('completed', 'skipped') AND ptr_sweep_cursors.scan_id IS DISTINCT FROM EXCLUDED.scan_id
+					THEN 0 ELSE ptr_sweep_cursors.next_offset END,
+				status = CASE WHEN ptr_sweep_cursors.status = 'completed' AND ptr_sweep_cursors.scan_id = EXCLUDED.scan_id
+					THEN 'completed' ELSE EXCLUDED.status END,
+				completed_at = CASE WHEN ptr_sweep_cursors.scan_id = EXCLUDED.scan_id THEN ptr_sweep_cursors.completed_at END,
+				scan_id = EXCLUDED.scan_id,
+				total_addresses = EXCLUDED.total_addresses,
+				updated_at = NOW()`,
+			scopeTargetID, prefix.Masked().String(), scanID, size, status)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare cursor for %s: %v", cidr, err)
+		}
+	}
+	return loadPTRSweepCursors(scopeTargetID, scanID)
+}
+
+func loadPTRSweepCursors(scopeTargetID, scanID string) ([]PTRSweepCursor, error) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT cidr_block, COALESCE(scan_id::text, ''), total_addresses, next_offset, status, updated_at, completed_at
+		FROM ptr_sweep_cursors
+		WHERE scope_target_id = $1 AND ($2 = '' OR scan_id::text = $2)
+		ORDER BY cidr_block`, scopeTargetID, scanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cursors := []PTRSweepCursor{}
+	for rows.Next() {
+		var c PTRSweepCursor
+		if err := rows.Scan(&c.CIDRBlock, &c.ScanID, &c.TotalAddresses, &c.NextOffset, &c.Status, &c.UpdatedAt, &c.CompletedAt); err != nil {
+			return nil, err
+		}
+		cursors = append(cursors, c)
+	}
+	return cursors, rows.Err()
+}
+
+func ExecutePTRSweep(ctx context.Context, scanID string, input ToolInput) {
+	t, _ := LookupTool("ptr-sweep")
+	log.Printf("[PTR-SWEEP] [INFO] Starting PTR sweep %s for scope target %s", scanID, input.ScopeTargetID)
+	startTime := time.Now()
+	createPTRSweepTables()
+	UpdateToolScanStatus(t, scanID, "running", "", "", "", "")
+
+	cursors, err := preparePTRSweepCursors(scanID, input.ScopeTargetID)
+	if err != nil {
+		UpdateToolScanStatus(t, scanID, "error", "", fmt.Sprintf("failed to load network ranges: %v", err), "", time.Since(startTime).String())
+		return
+	}
+	qps := envInt("RECON_PTR_SWEEP_QPS", 100)
+	workers := envInt("RECON_PTR_SWEEP_WORKERS", 20)
+	if qps <= 0 {
+		qps = 100
+	}
+	if workers <= 0 {
+		workers = 20
+	}
+	command := fmt.Sprintf("native PTR sweep of %d ranges qps=%d workers=%d", len(cursors), qps, workers)
+
+	limiter := time.NewTicker(time.Second / time.Duration(qps))
+	defer limiter.Stop()
+	for _, cursor := range cursors {
+		if cursor.Status == "completed" || cursor.Status == "skipped" {
+			continue
+		}
+		if err := sweepPTRRange(ctx, limiter, workers, scanID, input.ScopeTargetID, cursor); err != nil {
+			if ctx.Err() != nil {
+				log.Printf("[PTR-SWEEP] [INFO] Sweep %s stopped in %s at offset saved in its cursor", scanID, cursor.CIDRBlock)
+				return
+			}
+			UpdateToolScanStatus(t, scanID, "error", "", err.Error(), command, time.Since(startTime).String())
+			return
+		}
+	}
+
+	if err := addPTRSweepCompanyDomains(input.ScopeTargetID); err != nil {
+		log.Printf("[PTR-SWEEP] [ERROR] Failed to add company domains: %v", err)
+	}
+	if err := attachPTRSweepRecords(input.ScopeTargetID); err != nil {
+		log.Printf("[PTR-SWEEP] [ERROR] Failed to attach PTR records to assets: %v", err)
+	}
+
+	var lines []string
+	rows, err := dbPool.Query(context.Background(),
+		`SELECT host(ip_address), hostname FROM ptr_sweep_records WHERE scan_id = $1 ORDER BY ip_address, hostname`, scanID)
+	if err == nil {
+		for rows.Next() {
+			var ip, hostname string
+			if rows.Scan(&ip, &hostname) == nil {
+				lines = append(lines, ip+" "+hostname)
+			}
+		}
+		rows.Close()
+	}
+	result := lineResult(lines)
+	stderr := ""
+	if result.Result == "" {
+		stderr = "No PTR records found"
+	}
+	UpdateToolScanStatus(t, scanID, result.Status, result.Result, stderr, command, time.Since(startTime).String())
+	log.Printf("[PTR-SWEEP] [INFO] Sweep %s found %d PTR records in %s", scanID, len(lines), time.Since(startTime))
+}
+
+// sweepPTRRange resolves the addresses of one range in batches, storing
+// each batch's records and advancing the cursor before the next.
+func sweepPTRRange(ctx context.Context, limiter *time.Ticker, workers int, scanID, scopeTargetID string, cursor PTRSweepCursor) error {
+	prefix, err := netip.ParsePrefix(cursor.CIDRBlock)
+	if err != nil {
+		return err
+	}
+	pool := defaultDNSPool()
+	log.Printf("[PTR-SWEEP] [INFO] Sweeping %s from offset %d of %d", cursor.CIDRBlock, cursor.NextOffset, cursor.TotalAddresses)
+
+	type ptrRecord struct {
+		addr     netip.Addr
+		hostname string
+	}
+	for offset := cursor.NextOffset; offset < cursor.TotalAddresses; offset += ptrSweepBatchSize {
+		end := offset + ptrSweepBatchSize
+		if end > cursor.TotalAddresses {
+			end = cursor.TotalAddresses
+		}
+		addresses := make(chan netip.Addr)
+		var mu sync.Mutex
+		var records []ptrRecord
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for addr := range addresses {
+					lookupCtx, cancel := context.WithTimeout(ctx, ptrSweepLookupTimeout)
+					names, _ := pool.LookupPTR(lookupCtx, addr.String())
+					cancel()
+					for _, name := range names {
+						name = strings.ToLower(strings.TrimSuffix(name, "."))
+						if name == "" {
+							continue
+						}
+						mu.Lock()
+						records = append(records, ptrRecord{addr, name})
+						mu.Unlock()
+					}
+				}
+			}()
+		}
+	feed:
+		for n := offset; n < end; n++ {
+			select {
+			case <-ctx.Done():
+				break feed
+			case <-limiter.C:
+			}
+			select {
+			case <-ctx.Done():
+				break feed
+			case addresses <- ptrSweepAddress(prefix, n):
+			}
+		}
+		close(addresses)
+		wg.Wait()
+		if ctx.Err() != nil {
+			// The cursor still points at this batch, which is redone on resume
+			return ctx.Err()
+		}
+
+		for _, record := range records {
+			_, err := dbPool.Exec(context.Background(), `
+				INSERT INTO ptr_sweep_records (scope_target_id, scan_id, cidr_block, ip_address, hostname, generic)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				ON CONFLICT (scope_target_id, ip_address, hostname) DO UPDATE SET
+					scan_id = EXCLUDED.scan_id, cidr_block = EXCLUDED.cidr_block, last_seen = NOW()`,
+				scopeTargetID, scanID, cursor.CIDRBlock, record.addr.String(), record.hostname, isGenericPTR(record.hostname, record.addr))
+			if err != nil {
+				return fmt.Errorf("failed to store PTR record for %s: %v", record.addr, err)
+			}
+		}
+		status := "running"
+		if end == cursor.TotalAddresses {
+			status = "completed"
+		}
+		_, err := dbPool.Exec(context.Background(), `
+			UPDATE ptr_sweep_cursors SET next_offset = $1, status = $2, updated_at = NOW(),
+				completed_at = CASE WHEN $2 = 'completed' THEN NOW() END
+			WHERE scope_target_id = $3 AND cidr_block = $4`,
+			end, status, scopeTargetID, cursor.CIDRBlock)
+		if err != nil {
+			return fmt.Errorf("failed to save cursor for %s: %v", cursor.CIDRBlock, err)
+		}
+	}
+	return nil
+}
+
+// addPTRSweepCompanyDomains adds the root domains of the non-generic PTR
+// names to the company domains. ConsolidateCompanyDomains picks them up
+// again from ptr_sweep_records when it rebuilds the list.
+func addPTRSweepCompanyDomains(scopeTargetID string) error {
+	domains, err := ptrSweepRootDomains(scopeTargetID)
+	if err != nil {
+		return err
+	}
+	for _, domain := range domains {
+		_, err := dbPool.Exec(context.Background(),
+			`INSERT INTO consolidated_company_domains (scope_target_id, domain, source) VALUES ($1, $2, 'ptr_sweep')
+			 ON CONFLICT (scope_target_id, domain) DO NOTHING`, scopeTargetID, domain)
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf("[PTR-SWEEP] [INFO] Found %d company domains in PTR records", len(domains))
+	return nil
+}
+
+// ptrSweepRootDomains returns the root domains of the non-generic PTR names
+// found for a scope target.
+func ptrSweepRootDomains(scopeTargetID string) ([]string, error) {
+	createPTRSweepTables()
+	rows, err := dbPool.Query(context.Background(),
+		`SELECT DISTINCT hostname FROM ptr_sweep_records WHERE scope_target_id = $1 AND NOT generic`, scopeTargetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	seen := make(map[string]bool)
+	var domains []string
+	for rows.Next() {
+		var hostname string
+		if err := rows.Scan(&hostname); err != nil {
+			return nil, err
+		}
+		if domain := extractRootDomain(hostname); domain != "" && !seen[domain] {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+	return domains, rows.Err()
+}
+
+// attachPTRSweepRecords merges the swept PTR names into the ptr_records of
+// the consolidated IP assets, adding IP assets for addresses only the sweep
+// has seen.
+func attachPTRSweepRecords(scopeTargetID string) error {
+	createPTRSweepTables()
+	result, err := dbPool.Exec(context.Background(), `
+		INSERT INTO consolidated_attack_surface_assets (
+			scope_target_id, asset_type, asset_identifier, ip_address, ip_type, ptr_records, httpx_sources
+		)
+		SELECT $1::uuid, 'ip_address', host(ip_address), host(ip_address),
+			CASE WHEN family(ip_address) = 6 THEN 'ipv6' ELSE 'ipv4' END,
+			ARRAY_AGG(DISTINCT hostname), ARRAY['ptr_sweep']
+		FROM ptr_sweep_records
+		WHERE scope_target_id = $1::uuid
+		GROUP BY ip_address
+		ON CONFLICT (scope_target_id, asset_type, asset_identifier) DO UPDATE SET
+			ptr_records = ARRAY(SELECT DISTINCT unnest(COALESCE(consolidated_attack_surface_assets.ptr_records, ARRAY[]::text[]) || EXCLUDED.ptr_records)),
+			httpx_sources = ARRAY(SELECT DISTINCT unnest(COALESCE(consolidated_attack_surface_assets.httpx_sources, ARRAY[]::text[]) || EXCLUDED.httpx_sources)),
+			last_updated = NOW()`, scopeTargetID)
+	if err != nil {
+		return err
+	}
+	log.Printf("[PTR-SWEEP] [INFO] Attached PTR records to %d IP assets", result.RowsAffected())
+	return nil
+}
+
+// GetPTRSweepProgress returns the sweep cursor of each network range of a
+// scope target.
+func GetPTRSweepProgress(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	if scopeTargetID == "" {
+		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
+		return
+	}
+
+	createPTRSweepTables()
+	cursors, err := loadPTRSweepCursors(scopeTargetID, "")
+	if err != nil {
+		log.Printf("[PTR-SWEEP] [ERROR] Failed to load cursors: %v", err)
+		http.Error(w, "Failed to get PTR sweep progress", http.StatusInternalServerError)
+		return
+	}
+	var total, done int64
+	for _, c := range cursors {
+		if c.Status == "skipped" {
+			continue
+		}
+		total += c.TotalAddresses
+		done += c.NextOffset
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ranges":              cursors,
+		"total_addresses":     total,
+		"processed_addresses": done,
+	})
+}
+
+// GetPTRSweepRecords returns the PTR names found by sweeps of a scope
+// target; ?generic=false leaves out names that only spell the address.
+func GetPTRSweepRecords(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	if scopeTargetID == "" {
+		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
+		return
+	}
+	includeGeneric := r.URL.Query().Get("generic") != "false"
+
+	createPTRSweepTables()
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT host(ip_address), hostname, cidr_block, generic, first_seen, last_seen
+		FROM ptr_sweep_records
+		WHERE scope_target_id = $1 AND ($2 OR NOT generic)
+		ORDER BY ip_address, hostname`, scopeTargetID, includeGeneric)
+	if err != nil {
+		log.Printf("[PTR-SWEEP] [ERROR] Failed to get PTR records: %v", err)
+		http.Error(w, "Failed to get PTR records", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	records := []PTRSweepRecord{}
+	for rows.Next() {
+		var rec PTRSweepRecord
+		if err := rows.Scan(&rec.IPAddress, &rec.Hostname, &rec.CIDRBlock, &rec.Generic, &rec.FirstSeen, &rec.LastSeen); err != nil {
+			log.Printf("[PTR-SWEEP] [ERROR] Error scanning PTR record: %v", err)
+			continue
+		}
+		records = append(records, rec)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
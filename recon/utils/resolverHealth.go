@@ -0,0 +1,581 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Resolver lists for the DNS tools live in dns_resolvers and are checked
+// periodically (RECON_RESOLVER_CHECK_INTERVAL_MINUTES, default 60). A
+// resolver is healthy when it answers, answers correctly and returns
+// NXDOMAIN for names that don't exist; it becomes trusted after
+// resolverTrustPasses healthy checks in a row within resolverTrustLatency.
+
+const (
+	resolverCheckConcurrency = 25
+	resolverCheckTimeout     = 3 * time.Second
+	resolverTrustPasses      = 3
+	resolverTrustLatency     = 1000 // ms
+	maxAmassResolvers        = 25
+)
+
+// resolverCheckNames resolve to fixed addresses, so answers can be checked
+// without a baseline resolver.
+var resolverCheckNames = []struct{ name, expect string }{
+	{"one.one.one.one", "1.1.1.1"},
+	{"dns.google", "8.8.8.8"},
+}
+
+// defaultPublicResolvers seed dns_resolvers and stand in for it when the
+// table is unavailable.
+var defaultPublicResolvers = []string{
+	"8.8.8.8", "8.8.4.4", "1.1.1.1", "1.0.0.1", "9.9.9.9", "149.112.112.112",
+	"64.6.64.6", "64.6.65.6", "208.67.222.222", "208.67.220.220", "76.76.19.19", "76.223.100.101",
+	"76.223.122.150", "8.26.56.26", "8.20.247.20", "185.228.168.9", "185.228.169.9", "77.88.8.8",
+	"77.88.8.1", "198.101.242.72", "176.103.130.130", "176.103.130.131", "94.140.14.14", "94.140.15.15",
+}
+
+type DNSResolverHealth struct {
+	ID                string     `json:"id"`
+	Address           string     `json:"address"`
+	ListName          string     `json:"list_name"`
+	Enabled           bool       `json:"enabled"`
+	Healthy           bool       `json:"healthy"`
+	Trusted           bool       `json:"trusted"`
+	Answers           bool       `json:"answers"`
+	AnswersCorrectly  bool       `json:"answers_correctly"`
+	NXDomainPoisoning bool       `json:"nxdomain_poisoning"`
+	LastLatencyMS     *int       `json:"last_latency_ms"`
+	ChecksTotal       int        `json:"checks_total"`
+	ChecksPassed      int        `json:"checks_passed"`
+	ConsecutivePasses int        `json:"consecutive_passes"`
+	LastError         *string    `json:"last_error"`
+	LastCheckedAt     *time.Time `json:"last_checked_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+const dnsResolverColumns = `id::text, address, list_name, enabled, healthy, trusted, answers, answers_correctly,
+	nxdomain_poisoning, last_latency_ms, checks_total, checks_passed, consecutive_passes, last_error,
+	last_checked_at, created_at`
+
+func scanDNSResolverHealth(row pgx.Row) (*DNSResolverHealth, error) {
+	var h DNSResolverHealth
+	err := row.Scan(&h.ID, &h.Address, &h.ListName, &h.Enabled, &h.Healthy, &h.Trusted, &h.Answers, &h.AnswersCorrectly,
+		&h.NXDomainPoisoning, &h.LastLatencyMS, &h.ChecksTotal, &h.ChecksPassed, &h.ConsecutivePasses, &h.LastError,
+		&h.LastCheckedAt, &h.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+func createResolverTables() error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS dns_resolvers (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			address TEXT NOT NULL UNIQUE,
+			list_name TEXT NOT NULL DEFAULT 'default',
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			healthy BOOLEAN NOT NULL DEFAULT FALSE,
+			trusted BOOLEAN NOT NULL DEFAULT FALSE,
+			answers BOOLEAN NOT NULL DEFAULT FALSE,
+			answers_correctly BOOLEAN NOT NULL DEFAULT FALSE,
+			nxdomain_poisoning BOOLEAN NOT NULL DEFAULT FALSE,
+			last_latency_ms INTEGER,
+			checks_total INTEGER NOT NULL DEFAULT 0,
+			checks_passed INTEGER NOT NULL DEFAULT 0,
+			consecutive_passes INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT,
+			last_checked_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS dns_resolvers_health_idx ON dns_resolvers (enabled, trusted, healthy)`,
+	}
+	for _, query := range queries {
+		if _, err := dbPool.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartResolverHealthMonitor creates and seeds dns_resolvers, then checks
+// every enabled resolver on an interval until ctx is cancelled.
+func StartResolverHealthMonitor(ctx context.Context) {
+	if err := createResolverTables(); err != nil {
+		log.Printf("[RESOLVERS] [ERROR] Failed to create resolver tables: %v", err)
+		return
+	}
+	seedResolvers()
+
+	interval := time.Duration(envInt("RECON_RESOLVER_CHECK_INTERVAL_MINUTES", 60)) * time.Minute
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			validateResolvers(ctx, nil)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	log.Printf("[RESOLVERS] [INFO] Resolver health monitor started (every %s)", interval)
+}
+
+// seedResolvers fills an empty dns_resolvers with the public defaults and
+// the list baked into the shuffledns image.
+func seedResolvers() {
+	var count int
+	if err := dbPool.QueryRow(context.Background(), `SELECT COUNT(*) FROM dns_resolvers`).Scan(&count); err != nil || count > 0 {
+		return
+	}
+	added := insertResolvers("default", defaultPublicResolvers)
+	if output, err := NewToolCommand("shuffledns", "cat", "/app/wordlists/resolvers.txt").Output(); err == nil {
+		addresses, _ := parseResolverList(string(output))
+		added += insertResolvers("shuffledns", addresses)
+	}
+	log.Printf("[RESOLVERS] [INFO] Seeded %d resolvers", added)
+}
+
+// parseResolverList reads one resolver per line (IP or IP:port, # comments
+// allowed) and returns the valid and invalid entries.
+func parseResolverList(text string) (valid, invalid []string) {
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		for _, entry := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			address, ok := normalizeResolverAddress(entry)
+			if !ok {
+				invalid = append(invalid, entry)
+				continue
+			}
+			if !seen[address] {
+				seen[address] = true
+				valid = append(valid, address)
+			}
+		}
+	}
+	return valid, invalid
+}
+
+// normalizeResolverAddress returns a bare IP for port 53 and ip:port
+// otherwise.
+func normalizeResolverAddress(entry string) (string, bool) {
+	if ip := net.ParseIP(strings.Trim(entry, "[]")); ip != nil {
+		return ip.String(), true
+	}
+	host, port, err := net.SplitHostPort(entry)
+	if err != nil || net.ParseIP(host) == nil {
+		return "", false
+	}
+	if port == "53" {
+		return net.ParseIP(host).String(), true
+	}
+	return net.JoinHostPort(net.ParseIP(host).String(), port), true
+}
+
+func insertResolvers(listName string, addresses []string) int {
+	added := 0
+	for _, address := range addresses {
+		tag, err := dbPool.Exec(context.Background(), `
+			INSERT INTO dns_resolvers (address, list_name) VALUES ($1, $2)
+			ON CONFLICT (address) DO UPDATE SET list_name = EXCLUDED.list_name, enabled = TRUE`,
+			address, listName)
+		if err != nil {
+			log.Printf("[RESOLVERS] [ERROR] Failed to store resolver %s: %v", address, err)
+			continue
+		}
+		added += int(tag.RowsAffected())
+	}
+	return added
+}
+
+type resolverCheckResult struct {
+	Answers           bool
+	AnswersCorrectly  bool
+	NXDomainPoisoning bool
+	Latency           time.Duration
+	Err               string
+}
+
+func (r resolverCheckResult) healthy() bool {
+	return r.Answers && r.AnswersCorrectly && !r.NXDomainPoisoning
+}
+
+// checkResolver asks the resolver for names with known answers and for a
+// random name under example.com, which must be NXDOMAIN.
+func checkResolver(ctx context.Context, address string) resolverCheckResult {
+	var result resolverCheckResult
+	server, _ := normalizeResolverAddress(address)
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	opts := dnsQueryOptions{Recursion: true}
+
+	result.AnswersCorrectly = true
+	for _, check := range resolverCheckNames {
+		start := time.Now()
+		resp, err := queryDNSServer(ctx, server, check.name, dnsTypeA, opts, resolverCheckTimeout)
+		if err != nil {
+			result.AnswersCorrectly = false
+			result.Err = err.Error()
+			continue
+		}
+		result.Answers = true
+		if latency := time.Since(start); latency > result.Latency {
+			result.Latency = latency
+		}
+		if resp.Rcode != dnsRcodeSuccess || !containsRecordValue(resp.Answers, "A", check.expect) {
+			result.AnswersCorrectly = false
+			result.Err = fmt.Sprintf("%s: expected %s, got %s %v", check.name, check.expect, dnsRcodeName(resp.Rcode), dnsRecordValues(resp.Answers, "A"))
+		}
+	}
+	if !result.Answers {
+		return result
+	}
+
+	label := make([]byte, 8)
+	rand.Read(label)
+	probe := hex.EncodeToString(label) + ".example.com"
+	resp, err := queryDNSServer(ctx, server, probe, dnsTypeA, opts, resolverCheckTimeout)
+	if err != nil {
+		result.Err = err.Error()
+		result.AnswersCorrectly = false
+		return result
+	}
+	if resp.Rcode != dnsRcodeNXDomain || len(dnsRecordValues(resp.Answers, "A")) > 0 {
+		result.NXDomainPoisoning = true
+		result.Err = fmt.Sprintf("%s: expected NXDOMAIN, got %s %v", probe, dnsRcodeName(resp.Rcode), dnsRecordValues(resp.Answers, "A"))
+	}
+	return result
+}
+
+func containsRecordValue(records []DNSResourceRecord, recordType, value string) bool {
+	for _, rr := range records {
+		if rr.Type == recordType && rr.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+var resolverValidationRunning atomic.Bool
+
+// validateResolvers checks the given resolver IDs, or every enabled
+// resolver when ids is nil. Overlapping runs are skipped.
+func validateResolvers(ctx context.Context, ids []string) {
+	if !resolverValidationRunning.CompareAndSwap(false, true) {
+		log.Printf("[RESOLVERS] [INFO] Resolver validation already running, skipping")
+		return
+	}
+	defer resolverValidationRunning.Store(false)
+
+	query := `SELECT id::text, address FROM dns_resolvers WHERE enabled`
+	args := []interface{}{}
+	if ids != nil {
+		query += ` AND id::text = ANY($1)`
+		args = append(args, ids)
+	}
+	rows, err := dbPool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("[RESOLVERS] [ERROR] Failed to load resolvers: %v", err)
+		return
+	}
+	type resolverRow struct{ id, address string }
+	var resolvers []resolverRow
+	for rows.Next() {
+		var r resolverRow
+		if err := rows.Scan(&r.id, &r.address); err == nil {
+			resolvers = append(resolvers, r)
+		}
+	}
+	rows.Close()
+
+	startTime := time.Now()
+	var healthy atomic.Int32
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, resolverCheckConcurrency)
+	for _, resolver := range resolvers {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(r resolverRow) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			result := checkResolver(ctx, r.address)
+			if result.healthy() {
+				healthy.Add(1)
+			}
+			recordResolverCheck(r.id, result)
+		}(resolver)
+	}
+	wg.Wait()
+	log.Printf("[RESOLVERS] [INFO] Checked %d resolvers in %s: %d healthy", len(resolvers), time.Since(startTime), healthy.Load())
+}
+
+func recordResolverCheck(id string, result resolverCheckResult) {
+	var latency *int
+	if result.Answers {
+		ms := int(result.Latency.Milliseconds())
+		latency = &ms
+	}
+	passed := result.healthy()
+	// SET expressions see the row as it was, so consecutive_passes + 1 is
+	// the streak including this check.
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE dns_resolvers SET
+			healthy = $2,
+			answers = $3,
+			answers_correctly = $4,
+			nxdomain_poisoning = $5,
+			last_latency_ms = $6,
+			last_error = NULLIF($7, ''),
+			checks_total = checks_total + 1,
+			checks_passed = checks_passed + CASE WHEN $2 THEN 1 ELSE 0 END,
+			consecutive_passes = CASE WHEN $2 THEN consecutive_passes + 1 ELSE 0 END,
+			trusted = $2 AND consecutive_passes + 1 >= $8 AND COALESCE($6, 0) <= $9,
+			last_checked_at = NOW()
+		WHERE id = $1::uuid`,
+		id, passed, result.Answers, result.AnswersCorrectly, result.NXDomainPoisoning, latency, result.Err,
+		resolverTrustPasses, resolverTrustLatency)
+	if err != nil {
+		log.Printf("[RESOLVERS] [ERROR] Failed to record check for resolver %s: %v", id, err)
+	}
+}
+
+// healthyResolvers returns up to limit (0 for all) resolvers for the DNS
+// tools: trusted first, then fastest first. Only resolvers that passed their
+// last check qualify, so a fresh upload is used once validated. It falls
+// back to defaultPublicResolvers if none are available.
+func healthyResolvers(limit int) []string {
+	query := `
+		SELECT address FROM dns_resolvers
+		WHERE enabled AND healthy
+		ORDER BY trusted DESC, last_latency_ms ASC NULLS LAST, address`
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	var addresses []string
+	rows, err := dbPool.Query(context.Background(), query)
+	if err == nil {
+		for rows.Next() {
+			var address string
+			if rows.Scan(&address) == nil {
+				addresses = append(addresses, address)
+			}
+		}
+		rows.Close()
+	}
+	if len(addresses) == 0 {
+		addresses = slices.Clone(defaultPublicResolvers)
+		if limit > 0 && len(addresses) > limit {
+			addresses = addresses[:limit]
+		}
+	}
+	return addresses
+}
+
+// resolverArgs repeats flag before every healthy resolver, e.g. amass -r.
+func resolverArgs(flag string, limit int) []string {
+	var args []string
+	for _, address := range healthyResolvers(limit) {
+		args = append(args, flag, address)
+	}
+	return args
+}
+
+// stageResolverFile writes the healthy resolvers plus extra entries to
+// /tmp/<name> in the tool's container and returns that path.
+func stageResolverFile(tool, name string, extra []string) (string, error) {
+	resolvers := append(healthyResolvers(0), extra...)
+	hostFile := filepath.Join(os.TempDir(), name)
+	if err := os.WriteFile(hostFile, []byte(strings.Join(resolvers, "\n")+"\n"), 0644); err != nil {
+		return "", err
+	}
+	defer os.Remove(hostFile)
+
+	toolPath := "/tmp/" + name
+	if err := CopyToTool(tool, hostFile, toolPath); err != nil {
+		return "", err
+	}
+	log.Printf("[RESOLVERS] [INFO] Staged %d resolvers for %s at %s", len(resolvers), tool, toolPath)
+	return toolPath, nil
+}
+
+// removeStagedFile deletes a file staged under /tmp in tool's container once
+// the run that used it is over. Other paths, such as the fallback lists baked
+// into the image, are left alone.
+func removeStagedFile(tool, path string) {
+	if !strings.HasPrefix(path, "/tmp/") {
+		return
+	}
+	if err := NewToolCommand(tool, "rm", "-f", path).Run(); err != nil {
+		log.Printf("[RESOLVERS] [WARN] Failed to remove %s from %s: %v", path, tool, err)
+	}
+}
+
+// healthyResolverFile stages the healthy resolvers for tool and falls back
+// to the list baked into its image if staging fails.
+func healthyResolverFile(tool, name, fallback string) string {
+	path, err := stageResolverFile(tool, name, nil)
+	if err != nil {
+		log.Printf("[RESOLVERS] [WARN] Failed to stage resolvers for %s, using %s: %v", tool, fallback, err)
+		return fallback
+	}
+	return path
+}
+
+// GetDNSResolvers lists the managed resolvers with their health. ?list=
+// limits to one list and ?status=healthy|trusted|unhealthy filters by state.
+func GetDNSResolvers(w http.ResponseWriter, r *http.Request) {
+	query := `SELECT ` + dnsResolverColumns + ` FROM dns_resolvers WHERE ($1 = '' OR list_name = $1)`
+	switch r.URL.Query().Get("status") {
+	case "":
+	case "healthy":
+		query += ` AND healthy`
+	case "trusted":
+		query += ` AND trusted`
+	case "unhealthy":
+		query += ` AND NOT healthy AND last_checked_at IS NOT NULL`
+	default:
+		http.Error(w, "status must be healthy, trusted or unhealthy", http.StatusBadRequest)
+		return
+	}
+	query += ` ORDER BY trusted DESC, healthy DESC, last_latency_ms ASC NULLS LAST, address`
+
+	rows, err := dbPool.Query(context.Background(), query, r.URL.Query().Get("list"))
+	if err != nil {
+		log.Printf("[RESOLVERS] [ERROR] Failed to list resolvers: %v", err)
+		http.Error(w, "Failed to list resolvers", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	resolvers := []DNSResolverHealth{}
+	summary := map[string]int{"total": 0, "enabled": 0, "healthy": 0, "trusted": 0, "nxdomain_poisoning": 0, "unchecked": 0}
+	for rows.Next() {
+		h, err := scanDNSResolverHealth(rows)
+		if err != nil {
+			log.Printf("[RESOLVERS] [ERROR] Error scanning resolver row: %v", err)
+			continue
+		}
+		resolvers = append(resolvers, *h)
+		summary["total"]++
+		for key, set := range map[string]bool{
+			"enabled": h.Enabled, "healthy": h.Healthy, "trusted": h.Trusted,
+			"nxdomain_poisoning": h.NXDomainPoisoning, "unchecked": h.LastCheckedAt == nil,
+		} {
+			if set {
+				summary[key]++
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"summary":            summary,
+		"validation_running": resolverValidationRunning.Load(),
+		"resolvers":          resolvers,
+	})
+}
+
+// UploadDNSResolvers adds resolvers to a list (?list=, default "custom")
+// and checks them in the background. The body is either JSON
+// {"list_name": "...", "resolvers": [...]} or plain text, one per line.
+func UploadDNSResolvers(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 5<<20))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	listName := r.URL.Query().Get("list")
+	text := string(body)
+	if strings.HasPrefix(strings.TrimSpace(text), "{") {
+		var req struct {
+			ListName  string   `json:"list_name"`
+			Resolvers []string `json:"resolvers"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.ListName != "" {
+			listName = req.ListName
+		}
+		text = strings.Join(req.Resolvers, "\n")
+	}
+	if listName == "" {
+		listName = "custom"
+	}
+
+	valid, invalid := parseResolverList(text)
+	if len(valid) == 0 {
+		http.Error(w, "No valid resolver addresses in upload", http.StatusBadRequest)
+		return
+	}
+	insertResolvers(listName, valid)
+
+	var ids []string
+	rows, err := dbPool.Query(context.Background(), `SELECT id::text FROM dns_resolvers WHERE address = ANY($1)`, valid)
+	if err == nil {
+		for rows.Next() {
+			var id string
+			if rows.Scan(&id) == nil {
+				ids = append(ids, id)
+			}
+		}
+		rows.Close()
+	}
+	go validateResolvers(context.Background(), ids)
+
+	log.Printf("[RESOLVERS] [INFO] Uploaded %d resolvers to list %s (%d invalid)", len(valid), listName, len(invalid))
+	if invalid == nil {
+		invalid = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"list_name": listName,
+		"added":     len(valid),
+		"invalid":   invalid,
+	})
+}
+
+// ValidateDNSResolvers starts an immediate check of every enabled resolver.
+func ValidateDNSResolvers(w http.ResponseWriter, r *http.Request) {
+	if resolverValidationRunning.Load() {
+		http.Error(w, "Resolver validation already running", http.StatusConflict)
+		return
+	}
+	go validateResolvers(context.Background(), nil)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "started"})
+}
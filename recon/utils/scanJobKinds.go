@@ -0,0 +1,116 @@
+package utils
+
+import "context"
+
+// payloadJob adapts an Execute* function to a ScanJobHandler that decodes the
+// common ScanJobPayload.
+func payloadJob(execute func(ctx context.Context, scanID string, payload ScanJobPayload)) ScanJobHandler {
+	return func(ctx context.Context, job *ScanJob) error {
+		var payload ScanJobPayload
+		if err := job.DecodePayload(&payload); err != nil {
+			return err
+		}
+		execute(ctx, job.ScanID, payload)
+		return nil
+	}
+}
+
+// targetJob adapts the common Execute*(ctx, scanID, target) shape.
+func targetJob(execute func(ctx context.Context, scanID, target string)) ScanJobHandler {
+	return payloadJob(func(ctx context.Context, scanID string, p ScanJobPayload) {
+		execute(ctx, scanID, p.Target)
+	})
+}
+
+// payloadTool adapts an Execute* function that needs more of the job
+// payload than a target string, e.g. the scope target ID or a per-scan config.
+func payloadTool(name string, input ToolInputKind, table string, execute func(ctx context.Context, scanID string, payload ScanJobPayload)) payloadExecutorTool {
+	return payloadExecutorTool{
+		executorTool: executorTool{
+			toolBase: toolBase{name: name, input: input, table: table},
+			execute: func(ctx context.Context, scanID string, in ToolInput) {
+				execute(ctx, scanID, ScanJobPayload{ScopeTargetID: in.ScopeTargetID, Target: in.Target, Targets: in.URLs})
+			},
+		},
+		executePayload: execute,
+	}
+}
+
+// scanJobOutputTables lists, per scan table, the tables its scans write rows
+// to under their scan_id, children before parents. A retry clears them
+// before the next attempt runs.
+var scanJobOutputTables = map[string][]string{
+	"amass_scans":              {"dns_records", "ips", "subdomains", "cloud_domains", "asns", "subnets", "service_providers"},
+	"amass_intel_scans":        {"intel_network_ranges", "intel_asn_data"},
+	"amass_enum_company_scans": {"amass_enum_cloud_domains", "amass_enum_dns_records", "amass_enum_raw_results"},
+	"dnsx_company_scans":       {"dnsx_dns_records", "dnsx_raw_results"},
+	"ip_port_scans":            {"discovered_services", "live_web_servers", "discovered_live_ips"},
+	"metabigor_company_scans":  {"metabigor_network_ranges", "metabigor_asn_data", "metabigor_ip_intelligence"},
+	"tls_fingerprint_scans":    {"tls_fingerprints"},
+	"tls_posture_scans":        {"tls_posture_results"},
+}
+
+func init() {
+	// Subdomain enumeration. assetfinder, subfinder and sublist3r are
+	// registered from their own files.
+	RegisterTool(executeTool("amass", ToolInputDomain, "amass_scans", ExecuteAndParseAmassScan))
+	RegisterTool(executeTool("gau", ToolInputDomain, "gau_scans", ExecuteAndParseGauScan))
+	RegisterTool(executeTool("ctl", ToolInputDomain, "ctl_scans", ExecuteAndParseCTLScan))
+	RegisterTool(executeTool("shuffledns", ToolInputDomain, "shuffledns_scans", ExecuteAndParseShuffleDNSScan))
+	RegisterScanJobKind("cewl-wordlist", "shuffledns_scans", targetJob(ExecuteAndParseShuffleDNSWithWordlist))
+	RegisterTool(executeTool("cewl", ToolInputDomain, "cewl_scans", ExecuteAndParseCeWLScan).withTargetColumn("url"))
+	RegisterScanJobKind("cewl-urls", "cewl_scans", payloadJob(func(ctx context.Context, scanID string, p ScanJobPayload) {
+		ExecuteAndParseCeWLScansForUrls(ctx, scanID, p.Targets)
+	}))
+	RegisterTool(executeTool("gospider", ToolInputDomain, "gospider_scans", executeAndParseGoSpiderScan))
+	RegisterTool(executeTool("subdomainizer", ToolInputDomain, "subdomainizer_scans", executeAndParseSubdomainizerScan))
+
+	// Live hosts and metadata
+	RegisterTool(executeTool("httpx", ToolInputDomain, "httpx_scans", ExecuteAndParseHttpxScan))
+	RegisterTool(executeTool("nuclei-screenshot", ToolInputDomain, "nuclei_screenshots", ExecuteAndParseNucleiScreenshotScan))
+	RegisterTool(executeTool("metadata", ToolInputDomain, "metadata_scans", ExecuteAndParseMetaDataScan))
+	RegisterScanJobKind("company-metadata", "company_metadata_scans", payloadJob(func(ctx context.Context, scanID string, p ScanJobPayload) {
+		ExecuteAndParseCompanyMetaDataScan(ctx, scanID, p.ScopeTargetID, p.IPPortScanID)
+	}))
+	RegisterTool(payloadTool("ip-port-scan", ToolInputCIDR, "ip_port_scans", func(ctx context.Context, scanID string, p ScanJobPayload) {
+		ExecuteIPPortScan(ctx, scanID, p.ScopeTargetID, p.IPPortScanConfig)
+	}))
+	RegisterTool(payloadTool("investigate", ToolInputCIDR, "investigate_scans", func(ctx context.Context, scanID string, p ScanJobPayload) {
+		ExecuteInvestigateScan(ctx, scanID, p.ScopeTargetID)
+	}))
+
+	// Company reconnaissance
+	RegisterTool(executeTool("amass-intel", ToolInputCompany, "amass_intel_scans", ExecuteAmassIntelScan))
+	RegisterScanJobKind("amass-enum-company", "amass_enum_company_scans", payloadJob(func(ctx context.Context, scanID string, p ScanJobPayload) {
+		ExecuteAmassEnumCompanyScan(ctx, scanID, p.Targets, p.ScopeTargetID)
+	}))
+	RegisterScanJobKind("dnsx-company", "dnsx_company_scans", payloadJob(func(ctx context.Context, scanID string, p ScanJobPayload) {
+		ExecuteDNSxCompanyScan(ctx, scanID, p.Targets, p.ScopeTargetID)
+	}))
+	RegisterScanJobKind("katana-company", "katana_company_scans", payloadJob(func(ctx context.Context, scanID string, p ScanJobPayload) {
+		ExecuteKatanaCompanyScan(ctx, scanID, p.Targets, p.ScopeTargetID)
+	}))
+	RegisterTool(executeTool("ctl-company", ToolInputCompany, "ctl_company_scans", ExecuteAndParseCTLCompanyScan).withSchema())
+	RegisterTool(executeTool("cloud-enum", ToolInputCompany, "cloud_enum_scans", ExecuteAndParseCloudEnumScan))
+	RegisterTool(executeTool("github-recon", ToolInputCompany, "github_recon_scans", ExecuteGitHubReconScan))
+	RegisterTool(executeTool("censys-company", ToolInputCompany, "censys_company_scans", ExecuteCensysCompanyScan))
+	RegisterTool(executeTool("shodan-company", ToolInputCompany, "shodan_company_scans", ExecuteShodanCompanyScan))
+	RegisterTool(executeTool("securitytrails-company", ToolInputCompany, "securitytrails_company_scans", ExecuteSecurityTrailsCompanyScan))
+	RegisterTool(executeTool("metabigor-company", ToolInputCompany, "metabigor_company_scans", ExecuteMetabigorCompanyScan))
+	RegisterScanJobKind("metabigor-netd", "metabigor_company_scans", targetJob(ExecuteMetabigorNetdScan))
+	RegisterScanJobKind("metabigor-asn", "metabigor_company_scans", payloadJob(func(ctx context.Context, scanID string, p ScanJobPayload) {
+		ExecuteMetabigorASNScan(ctx, scanID, p.Target, p.ScanType)
+	}))
+	RegisterScanJobKind("metabigor-ip", "metabigor_company_scans", payloadJob(func(ctx context.Context, scanID string, p ScanJobPayload) {
+		ExecuteMetabigorIPIntelligence(ctx, scanID, p.Target, p.ScanType)
+	}))
+
+	// URL scanning
+	RegisterScanJobKind("katana-url", "katana_url_scans", targetJob(ExecuteAndParseKatanaURLScan))
+	RegisterScanJobKind("linkfinder-url", "linkfinder_url_scans", targetJob(ExecuteAndParseLinkFinderURLScan))
+	RegisterScanJobKind("waybackurls", "waybackurls_scans", targetJob(ExecuteAndParseWaybackURLsScan))
+	RegisterScanJobKind("gau-url", "gau_url_scans", targetJob(ExecuteAndParseGAUURLScan))
+	RegisterScanJobKind("ffuf-url", "ffuf_url_scans", payloadJob(func(ctx context.Context, scanID string, p ScanJobPayload) {
+		ExecuteAndParseFFUFURLScan(ctx, scanID, p.Target, p.ScopeTargetID)
+	}))
+}
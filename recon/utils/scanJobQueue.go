@@ -0,0 +1,738 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// Scan jobs move through these states. A job that exhausts its attempts is
+// dead-lettered and stays there until someone retries it by hand.
+const (
+	ScanJobQueued       = "queued"
+	ScanJobRunning      = "running"
+	ScanJobRetrying     = "retrying"
+	ScanJobSucceeded    = "succeeded"
+	ScanJobDeadLettered = "dead_lettered"
+	ScanJobCancelled    = "cancelled"
+)
+
+const (
+	scanJobLeaseDuration = 2 * time.Minute
+	scanJobPollInterval  = 2 * time.Second
+	scanJobBaseBackoff   = 30 * time.Second
+	scanJobMaxBackoff    = 15 * time.Minute
+	scanJobSweepInterval = 10 * time.Minute
+)
+
+// ScanJob is a row in scan_jobs. Jobs point at their scan row through
+// scan_table and scan_id, and the scan row points back through job_id.
+type ScanJob struct {
+	ID             string          `json:"id"`
+	Kind           string          `json:"kind"`
+	ScanID         string          `json:"scan_id"`
+	ScanTable      string          `json:"scan_table"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	MaxAttempts    int             `json:"max_attempts"`
+	RunAfter       time.Time       `json:"run_after"`
+	LeaseOwner     sql.NullString  `json:"-"`
+	LeaseExpiresAt sql.NullTime    `json:"-"`
+	LastError      sql.NullString  `json:"-"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	StartedAt      sql.NullTime    `json:"-"`
+	FinishedAt     sql.NullTime    `json:"-"`
+}
+
+// ScanJobPayload covers the arguments the existing Execute* functions take.
+// Kinds that need something else can enqueue their own payload type and
+// decode it with DecodePayload.
+type ScanJobPayload struct {
+	Target        string   `json:"target,omitempty"`
+	Targets       []string `json:"targets,omitempty"`
+	ScopeTargetID string   `json:"scope_target_id,omitempty"`
+	ScanType      string   `json:"scan_type,omitempty"`
+	IPPortScanID  string   `json:"ip_port_scan_id,omitempty"`
+
+	IPPortScanConfig *IPPortScanConfig `json:"ip_port_scan_config,omitempty"`
+}
+
+// ScanJobHandler runs one attempt of a job. Execute* functions report their
+// outcome through the scan row, so a nil return only means the handler ran;
+// the queue still checks the row's status to decide whether to retry.
+type ScanJobHandler func(ctx context.Context, job *ScanJob) error
+
+type scanJobKind struct {
+	table   string
+	handler ScanJobHandler
+}
+
+var (
+	scanJobKindsMu sync.RWMutex
+	scanJobKinds   = make(map[string]scanJobKind)
+
+	scanJobWake     = make(chan struct{}, 1)
+	scanJobWorkerID = fmt.Sprintf("%s-%d-%s", hostnameOrUnknown(), os.Getpid(), uuid.New().String()[:8])
+)
+
+// RegisterScanJobKind makes kind runnable by the queue. table is the scan
+// table whose rows the kind updates; it must have scan_id and status columns,
+// and gets a job_id column when the workers start.
+func RegisterScanJobKind(kind, table string, handler ScanJobHandler) {
+	scanJobKindsMu.Lock()
+	defer scanJobKindsMu.Unlock()
+	scanJobKinds[kind] = scanJobKind{table: table, handler: handler}
+}
+
+func lookupScanJobKind(kind string) (scanJobKind, bool) {
+	scanJobKindsMu.RLock()
+	defer scanJobKindsMu.RUnlock()
+	k, ok := scanJobKinds[kind]
+	return k, ok
+}
+
+// scanJobTables returns each scan table used by a registered kind once.
+func scanJobTables() []string {
+	scanJobKindsMu.RLock()
+	defer scanJobKindsMu.RUnlock()
+
+	seen := make(map[string]bool)
+	var tables []string
+	for _, k := range scanJobKinds {
+		if !seen[k.table] {
+			seen[k.table] = true
+			tables = append(tables, k.table)
+		}
+	}
+	return tables
+}
+
+// sweptScanTables returns the registered scan tables plus any table that
+// queued jobs still point at, e.g. from a kind that has since been renamed.
+func sweptScanTables() []string {
+	tables := scanJobTables()
+	rows, err := dbPool.Query(context.Background(), `SELECT DISTINCT scan_table FROM scan_jobs`)
+	if err != nil {
+		log.Printf("[SCAN-JOBS] [WARN] Failed to list job scan tables: %v", err)
+		return tables
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table string
+		if rows.Scan(&table) == nil && !slices.Contains(tables, table) {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+// DecodePayload unmarshals the job payload into v.
+func (j *ScanJob) DecodePayload(v interface{}) error {
+	if len(j.Payload) == 0 {
+		return nil
+	}
+	return json.Unmarshal(j.Payload, v)
+}
+
+func hostnameOrUnknown() string {
+	name, err := os.Hostname()
+	if err != nil || name == "" {
+		return "unknown"
+	}
+	return name
+}
+
+func createScanJobTables() {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS scan_jobs (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			kind VARCHAR(64) NOT NULL,
+			scan_id TEXT NOT NULL,
+			scan_table VARCHAR(64) NOT NULL,
+			payload JSONB NOT NULL DEFAULT '{}'::jsonb,
+			status VARCHAR(32) NOT NULL DEFAULT 'queued',
+			attempts INT NOT NULL DEFAULT 0,
+			max_attempts INT NOT NULL DEFAULT 3,
+			run_after TIMESTAMP NOT NULL DEFAULT NOW(),
+			lease_owner TEXT,
+			lease_expires_at TIMESTAMP,
+			last_error TEXT,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			started_at TIMESTAMP,
+			finished_at TIMESTAMP
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_scan_jobs_claim ON scan_jobs(status, run_after);`,
+		`CREATE INDEX IF NOT EXISTS idx_scan_jobs_scan_id ON scan_jobs(scan_id);`,
+	}
+
+	for _, query := range queries {
+		if _, err := dbPool.Exec(context.Background(), query); err != nil {
+			log.Printf("[SCAN-JOBS] [ERROR] Failed to create table/index: %v", err)
+		}
+	}
+	for _, table := range scanJobTables() {
+		if _, err := dbPool.Exec(context.Background(), fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS job_id TEXT`, table)); err != nil {
+			log.Printf("[SCAN-JOBS] [ERROR] Failed to add job_id to %s: %v", table, err)
+		}
+	}
+}
+
+// EnqueueScanJob records a job for an already-inserted scan row. Workers pick
+// the job up on their next poll.
+func EnqueueScanJob(kind, scanID string, payload interface{}) (string, error) {
+	k, ok := lookupScanJobKind(kind)
+	if !ok {
+		return "", fmt.Errorf("unknown scan job kind %q", kind)
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode job payload: %v", err)
+	}
+
+	ctx := context.Background()
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var jobID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO scan_jobs (kind, scan_id, scan_table, payload, status, max_attempts)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id::text`,
+		kind, scanID, k.table, payloadJSON, ScanJobQueued, scanJobMaxAttempts(),
+	).Scan(&jobID)
+	if err != nil {
+		return "", fmt.Errorf("failed to insert scan job: %v", err)
+	}
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE %s SET job_id = $1 WHERE scan_id = $2`, k.table), jobID, scanID); err != nil {
+		return "", fmt.Errorf("failed to link scan %s to its job: %v", scanID, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("failed to commit scan job: %v", err)
+	}
+
+	log.Printf("[SCAN-JOBS] [INFO] Queued %s job %s for scan %s", kind, jobID, scanID)
+	wakeScanJobWorkers()
+	return jobID, nil
+}
+
+func wakeScanJobWorkers() {
+	select {
+	case scanJobWake <- struct{}{}:
+	default:
+	}
+}
+
+func scanJobMaxAttempts() int {
+	return envInt("RECON_JOB_MAX_ATTEMPTS", 3)
+}
+
+func envInt(name string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(name)); err == nil && v > 0 {
+		return v
+	}
+	return fallback
+}
+
+// StartScanJobWorkers creates the queue and tool tables, sweeps scan rows
+// no job will ever finish and starts RECON_JOB_WORKERS workers (default 8).
+// Jobs left running by a previous process are picked up again once their
+// lease expires.
+func StartScanJobWorkers(ctx context.Context) {
+	createToolTables()
+	createScanJobTables()
+	sweepOrphanedScans()
+	go func() {
+		ticker := time.NewTicker(scanJobSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sweepOrphanedScans()
+			}
+		}
+	}()
+
+	workers := envInt("RECON_JOB_WORKERS", 8)
+	log.Printf("[SCAN-JOBS] [INFO] Starting %d scan job workers as %s", workers, scanJobWorkerID)
+	for i := 0; i < workers; i++ {
+		go scanJobWorker(ctx, i)
+	}
+}
+
+// sweepOrphanedScans deals with scan rows whose job can no longer move them
+// on. Pending rows without any job were left by the pre-queue engine and are
+// deleted; rows younger than a minute are left alone in case another engine
+// is between inserting the row and queueing its job. Pending or running rows
+// whose job is finished, dead-lettered, cancelled or gone, e.g. after a
+// worker crashed, are marked as errors, as are retrying rows left behind.
+func sweepOrphanedScans() {
+	for _, table := range sweptScanTables() {
+		tag, err := dbPool.Exec(context.Background(), fmt.Sprintf(`
+			DELETE FROM %s t
+			WHERE t.status = 'pending'
+				AND t.job_id IS NULL
+				AND t.created_at < NOW() - INTERVAL '1 minute'
+				AND NOT EXISTS (
+					SELECT 1 FROM scan_jobs j WHERE j.scan_id = t.scan_id::text AND j.scan_table = $1
+				)`, table), table)
+		if err != nil {
+			log.Printf("[SCAN-JOBS] [WARN] Failed to delete orphaned pending scans from %s: %v", table, err)
+			continue
+		}
+		if tag.RowsAffected() > 0 {
+			log.Printf("[SCAN-JOBS] [INFO] Deleted %d orphaned pending scans from %s", tag.RowsAffected(), table)
+		}
+
+		tag, err = dbPool.Exec(context.Background(), fmt.Sprintf(`
+			UPDATE %s t SET status = 'error'
+			WHERE t.status IN ('pending', 'running', 'retrying')
+				AND t.created_at < NOW() - INTERVAL '1 minute'
+				AND NOT EXISTS (
+					SELECT 1 FROM scan_jobs j
+					WHERE (j.id::text = t.job_id OR (t.job_id IS NULL AND j.scan_id = t.scan_id::text AND j.scan_table = $1))
+						AND j.status IN ('queued', 'running', 'retrying')
+				)`, table), table)
+		if err != nil {
+			log.Printf("[SCAN-JOBS] [WARN] Failed to fail orphaned scans in %s: %v", table, err)
+			continue
+		}
+		if tag.RowsAffected() > 0 {
+			log.Printf("[SCAN-JOBS] [INFO] Marked %d scans without a live job in %s as errors", tag.RowsAffected(), table)
+		}
+	}
+}
+
+func scanJobWorker(ctx context.Context, n int) {
+	ticker := time.NewTicker(scanJobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			job, err := claimScanJob(ctx)
+			if err != nil {
+				log.Printf("[SCAN-JOBS] [ERROR] Worker %d failed to claim job: %v", n, err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			runScanJob(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-scanJobWake:
+		}
+	}
+}
+
+const scanJobColumns = `id, kind, scan_id, scan_table, payload, status, attempts, max_attempts, run_after,
+	lease_owner, lease_expires_at, last_error, created_at, updated_at, started_at, finished_at`
+
+func scanScanJob(row pgx.Row) (*ScanJob, error) {
+	var job ScanJob
+	err := row.Scan(&job.ID, &job.Kind, &job.ScanID, &job.ScanTable, &job.Payload, &job.Status,
+		&job.Attempts, &job.MaxAttempts, &job.RunAfter, &job.LeaseOwner, &job.LeaseExpiresAt,
+		&job.LastError, &job.CreatedAt, &job.UpdatedAt, &job.StartedAt, &job.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// claimScanJob leases the next due job, including running jobs whose lease
+// ran out because their worker died.
+func claimScanJob(ctx context.Context) (*ScanJob, error) {
+	row := dbPool.QueryRow(ctx, `
+		UPDATE scan_jobs SET
+			status = 'running',
+			attempts = attempts + 1,
+			lease_owner = $1,
+			lease_expires_at = NOW() + $2::interval,
+			started_at = NOW(),
+			updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM scan_jobs
+			WHERE (status IN ('queued', 'retrying') AND run_after <= NOW())
+			   OR (status = 'running' AND lease_expires_at < NOW())
+			ORDER BY run_after, created_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING `+scanJobColumns,
+		scanJobWorkerID, fmt.Sprintf("%d seconds", int(scanJobLeaseDuration.Seconds())))
+
+	job, err := scanScanJob(row)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return job, err
+}
+
+func runScanJob(ctx context.Context, job *ScanJob) {
+	k, ok := lookupScanJobKind(job.Kind)
+	if !ok {
+		finishScanJob(job, ScanJobDeadLettered, fmt.Sprintf("unknown job kind %q", job.Kind))
+		return
+	}
+
+	if job.Attempts > job.MaxAttempts {
+		finishScanJob(job, ScanJobDeadLettered, "lease expired after the last attempt")
+		markScanRowFailed(job)
+		return
+	}
+
+	log.Printf("[SCAN-JOBS] [INFO] Running %s job %s for scan %s (attempt %d/%d)", job.Kind, job.ID, job.ScanID, job.Attempts, job.MaxAttempts)
+
+	if job.Attempts > 1 {
+		resetScanRow(job)
+		clearScanJobOutput(job)
+	}
+
+	jobCtx, done := registerRunningScan(ctx, job.Kind, job.ScanTable, job.ScanID)
+	defer done()
+	go heartbeatScanJob(jobCtx, job)
+	PublishScanStatus(job.ScanID, "running")
+
+	err := callScanJobHandler(jobCtx, k.handler, job)
+
+	if jobCtx.Err() != nil {
+		// Either the scan was cancelled or another worker took the job over;
+		// in both cases this attempt's outcome no longer matters.
+		if scanJobStatus(job) == ScanJobCancelled {
+			if err := markScanCancelled(job.ScanTable, job.ScanID); err != nil {
+				log.Printf("[SCAN-JOBS] [ERROR] %v", err)
+			}
+			EndScanStream(job.ScanID, ScanCancelled)
+			log.Printf("[SCAN-JOBS] [INFO] %s job %s cancelled", job.Kind, job.ID)
+		}
+		return
+	}
+
+	if err == nil && scanRowFailed(job) {
+		err = fmt.Errorf("scan finished with status error")
+	}
+
+	if err == nil {
+		finishScanJob(job, ScanJobSucceeded, "")
+		endScanJobStream(job)
+		log.Printf("[SCAN-JOBS] [INFO] %s job %s succeeded", job.Kind, job.ID)
+		return
+	}
+
+	if job.Attempts >= job.MaxAttempts {
+		finishScanJob(job, ScanJobDeadLettered, err.Error())
+		endScanJobStream(job)
+		log.Printf("[SCAN-JOBS] [ERROR] %s job %s dead-lettered after %d attempts: %v", job.Kind, job.ID, job.Attempts, err)
+		return
+	}
+
+	backoff := scanJobBackoff(job.Attempts)
+	retryScanJob(job, err.Error(), backoff)
+	PublishScanStatus(job.ScanID, ScanJobRetrying)
+	log.Printf("[SCAN-JOBS] [WARN] %s job %s failed (attempt %d/%d), retrying in %s: %v", job.Kind, job.ID, job.Attempts, job.MaxAttempts, backoff, err)
+}
+
+// endScanJobStream closes the job's live stream with the scan row status.
+func endScanJobStream(job *ScanJob) {
+	status, err := scanRowStatus(job)
+	if err != nil {
+		status = scanJobStatus(job)
+	}
+	EndScanStream(job.ScanID, status)
+}
+
+func callScanJobHandler(ctx context.Context, handler ScanJobHandler, job *ScanJob) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[SCAN-JOBS] [ERROR] %s job %s panicked: %v\n%s", job.Kind, job.ID, r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// heartbeatScanJob keeps the lease alive while the handler runs. If the job
+// was cancelled or another worker has taken it over, the scan is cancelled.
+func heartbeatScanJob(ctx context.Context, job *ScanJob) {
+	ticker := time.NewTicker(scanJobLeaseDuration / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			tag, err := dbPool.Exec(context.Background(), `
+				UPDATE scan_jobs SET lease_expires_at = NOW() + $1::interval, updated_at = NOW()
+				WHERE id = $2 AND lease_owner = $3 AND status = 'running'`,
+				fmt.Sprintf("%d seconds", int(scanJobLeaseDuration.Seconds())), job.ID, scanJobWorkerID)
+			if err != nil {
+				log.Printf("[SCAN-JOBS] [WARN] Heartbeat failed for job %s: %v", job.ID, err)
+				continue
+			}
+			if tag.RowsAffected() == 0 {
+				log.Printf("[SCAN-JOBS] [WARN] Lost lease on job %s", job.ID)
+				cancelRunningScan(job.ScanID)
+				return
+			}
+		}
+	}
+}
+
+func scanJobBackoff(attempt int) time.Duration {
+	backoff := scanJobBaseBackoff
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+		if backoff >= scanJobMaxBackoff {
+			return scanJobMaxBackoff
+		}
+	}
+	return backoff
+}
+
+func finishScanJob(job *ScanJob, status, lastError string) {
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE scan_jobs SET status = $1, last_error = NULLIF($2, ''), lease_owner = NULL,
+			lease_expires_at = NULL, finished_at = NOW(), updated_at = NOW()
+		WHERE id = $3`, status, lastError, job.ID)
+	if err != nil {
+		log.Printf("[SCAN-JOBS] [ERROR] Failed to mark job %s %s: %v", job.ID, status, err)
+	}
+}
+
+func retryScanJob(job *ScanJob, lastError string, backoff time.Duration) {
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE scan_jobs SET status = $1, last_error = $2, lease_owner = NULL, lease_expires_at = NULL,
+			run_after = NOW() + $3::interval, updated_at = NOW()
+		WHERE id = $4`, ScanJobRetrying, lastError, fmt.Sprintf("%d seconds", int(backoff.Seconds())), job.ID)
+	if err != nil {
+		log.Printf("[SCAN-JOBS] [ERROR] Failed to schedule retry for job %s: %v", job.ID, err)
+	}
+
+	// Show the scan row as retrying so pollers keep waiting for it and can
+	// tell a backoff from a scan that has not started yet.
+	setScanRowStatus(job, ScanJobRetrying)
+}
+
+func scanJobStatus(job *ScanJob) string {
+	var status string
+	if err := dbPool.QueryRow(context.Background(), `SELECT status FROM scan_jobs WHERE id = $1`, job.ID).Scan(&status); err != nil {
+		log.Printf("[SCAN-JOBS] [WARN] Failed to read status of job %s: %v", job.ID, err)
+	}
+	return status
+}
+
+func scanRowStatus(job *ScanJob) (string, error) {
+	var status string
+	err := dbPool.QueryRow(context.Background(),
+		fmt.Sprintf(`SELECT status FROM %s WHERE scan_id = $1`, job.ScanTable), job.ScanID).Scan(&status)
+	return status, err
+}
+
+func scanRowFailed(job *ScanJob) bool {
+	status, err := scanRowStatus(job)
+	if err != nil {
+		log.Printf("[SCAN-JOBS] [WARN] Failed to read status of scan %s: %v", job.ScanID, err)
+		return false
+	}
+	return status == "error" || status == "failed"
+}
+
+func resetScanRow(job *ScanJob) {
+	setScanRowStatus(job, "pending")
+}
+
+func setScanRowStatus(job *ScanJob, status string) {
+	_, err := dbPool.Exec(context.Background(),
+		fmt.Sprintf(`UPDATE %s SET status = $1 WHERE scan_id = $2`, job.ScanTable), status, job.ScanID)
+	if err != nil {
+		log.Printf("[SCAN-JOBS] [WARN] Failed to set scan %s to %s: %v", job.ScanID, status, err)
+	}
+}
+
+// scanRowOutputColumns are the scan row columns an attempt writes its output
+// to; whichever the table has are cleared before a retry.
+var scanRowOutputColumns = []string{"result", "error", "stdout", "stderr", "execution_time"}
+
+// clearScanJobOutput removes what a failed attempt stored so the next one
+// does not add its results a second time: the output columns of the scan
+// row and the rows the scan wrote to its output tables.
+func clearScanJobOutput(job *ScanJob) {
+	ctx := context.Background()
+	rows, err := dbPool.Query(ctx, `
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 AND column_name = ANY($2)`,
+		job.ScanTable, scanRowOutputColumns)
+	if err != nil {
+		log.Printf("[SCAN-JOBS] [WARN] Failed to read output columns of %s: %v", job.ScanTable, err)
+	} else {
+		var sets []string
+		for rows.Next() {
+			var column string
+			if rows.Scan(&column) == nil {
+				sets = append(sets, column+" = NULL")
+			}
+		}
+		rows.Close()
+		if len(sets) > 0 {
+			_, err := dbPool.Exec(ctx, fmt.Sprintf(`UPDATE %s SET %s WHERE scan_id = $1`, job.ScanTable, strings.Join(sets, ", ")), job.ScanID)
+			if err != nil {
+				log.Printf("[SCAN-JOBS] [WARN] Failed to clear output of scan %s: %v", job.ScanID, err)
+			}
+		}
+	}
+
+	for _, table := range scanJobOutputTables[job.ScanTable] {
+		tag, err := dbPool.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE scan_id = $1`, table), job.ScanID)
+		if err != nil {
+			log.Printf("[SCAN-JOBS] [WARN] Failed to clear %s rows of scan %s: %v", table, job.ScanID, err)
+			continue
+		}
+		if tag.RowsAffected() > 0 {
+			log.Printf("[SCAN-JOBS] [INFO] Cleared %d %s rows left by the previous attempt of scan %s", tag.RowsAffected(), table, job.ScanID)
+		}
+	}
+}
+
+func markScanRowFailed(job *ScanJob) {
+	_, err := dbPool.Exec(context.Background(),
+		fmt.Sprintf(`UPDATE %s SET status = 'error' WHERE scan_id = $1 AND status IN ('pending', 'running', 'retrying')`, job.ScanTable), job.ScanID)
+	if err != nil {
+		log.Printf("[SCAN-JOBS] [WARN] Failed to mark scan %s as error: %v", job.ScanID, err)
+	}
+}
+
+func scanJobResponse(job *ScanJob) map[string]interface{} {
+	response := map[string]interface{}{
+		"id":           job.ID,
+		"kind":         job.Kind,
+		"scan_id":      job.ScanID,
+		"scan_table":   job.ScanTable,
+		"payload":      job.Payload,
+		"status":       job.Status,
+		"attempts":     job.Attempts,
+		"max_attempts": job.MaxAttempts,
+		"run_after":    job.RunAfter.Format(time.RFC3339),
+		"last_error":   nullStringToString(job.LastError),
+		"created_at":   job.CreatedAt.Format(time.RFC3339),
+		"updated_at":   job.UpdatedAt.Format(time.RFC3339),
+	}
+	if job.StartedAt.Valid {
+		response["started_at"] = job.StartedAt.Time.Format(time.RFC3339)
+	}
+	if job.FinishedAt.Valid {
+		response["finished_at"] = job.FinishedAt.Time.Format(time.RFC3339)
+	}
+	return response
+}
+
+// GetScanJobForScan returns the most recent job behind a scan row.
+func GetScanJobForScan(w http.ResponseWriter, r *http.Request) {
+	scanID := mux.Vars(r)["scan_id"]
+
+	row := dbPool.QueryRow(context.Background(),
+		`SELECT `+scanJobColumns+` FROM scan_jobs WHERE scan_id = $1 ORDER BY created_at DESC LIMIT 1`, scanID)
+	job, err := scanScanJob(row)
+	if err == pgx.ErrNoRows {
+		http.Error(w, "No job found for scan", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[SCAN-JOBS] [ERROR] Failed to get job for scan %s: %v", scanID, err)
+		http.Error(w, "Failed to get scan job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scanJobResponse(job))
+}
+
+// GetScanJobs lists jobs, optionally filtered by ?status= and ?kind=.
+func GetScanJobs(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	kind := r.URL.Query().Get("kind")
+	limit := 100
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 1000 {
+		limit = l
+	}
+
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT `+scanJobColumns+` FROM scan_jobs
+		WHERE ($1 = '' OR status = $1) AND ($2 = '' OR kind = $2)
+		ORDER BY created_at DESC
+		LIMIT $3`, status, kind, limit)
+	if err != nil {
+		log.Printf("[SCAN-JOBS] [ERROR] Failed to list jobs: %v", err)
+		http.Error(w, "Failed to list scan jobs", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	jobs := []map[string]interface{}{}
+	for rows.Next() {
+		job, err := scanScanJob(rows)
+		if err != nil {
+			log.Printf("[SCAN-JOBS] [ERROR] Failed to scan job row: %v", err)
+			continue
+		}
+		jobs = append(jobs, scanJobResponse(job))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// RetryScanJob puts a dead-lettered job back on the queue with a fresh set of
+// attempts.
+func RetryScanJob(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["job_id"]
+
+	row := dbPool.QueryRow(context.Background(), `
+		UPDATE scan_jobs SET status = 'queued', attempts = 0, run_after = NOW(), last_error = NULL,
+			finished_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'dead_lettered'
+		RETURNING `+scanJobColumns, jobID)
+	job, err := scanScanJob(row)
+	if err == pgx.ErrNoRows {
+		http.Error(w, "Job not found or not dead-lettered", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("[SCAN-JOBS] [ERROR] Failed to retry job %s: %v", jobID, err)
+		http.Error(w, "Failed to retry scan job", http.StatusInternalServerError)
+		return
+	}
+
+	resetScanRow(job)
+	clearScanJobOutput(job)
+	wakeScanJobWorkers()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scanJobResponse(job))
+}
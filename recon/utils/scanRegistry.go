@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ScanCancelled is the status a scan row ends in when it was stopped by a
+// user or by its auto-scan session being cancelled.
+const ScanCancelled = "cancelled"
+
+type runningScan struct {
+	tool      string
+	table     string
+	cancel    context.CancelFunc
+	startedAt time.Time
+}
+
+var (
+	runningScansMu sync.Mutex
+	runningScans   = make(map[string]*runningScan)
+)
+
+// registerRunningScan derives a cancellable context for scanID that also
+// routes tool output to the scan's live stream. The returned func must be
+// called when the scan finishes.
+func registerRunningScan(parent context.Context, tool, table, scanID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(withScanStreamID(parent, scanID))
+
+	runningScansMu.Lock()
+	runningScans[scanID] = &runningScan{tool: tool, table: table, cancel: cancel, startedAt: time.Now()}
+	runningScansMu.Unlock()
+
+	return ctx, func() {
+		runningScansMu.Lock()
+		delete(runningScans, scanID)
+		runningScansMu.Unlock()
+		cancel()
+	}
+}
+
+// cancelRunningScan cancels scanID's context if it runs in this process.
+func cancelRunningScan(scanID string) bool {
+	runningScansMu.Lock()
+	scan, ok := runningScans[scanID]
+	runningScansMu.Unlock()
+	if ok {
+		scan.cancel()
+	}
+	return ok
+}
+
+// CancelScan stops a scan wherever it is: queued jobs are withdrawn, a job
+// running in this engine has its context cancelled (which kills the tool),
+// and a job running in another engine loses its lease on the next heartbeat.
+// The scan row ends up cancelled either way.
+func CancelScan(table, scanID string) error {
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE scan_jobs SET status = $1, lease_owner = NULL, lease_expires_at = NULL,
+			finished_at = NOW(), updated_at = NOW()
+		WHERE scan_id = $2 AND scan_table = $3 AND status IN ('queued', 'running', 'retrying')`,
+		ScanJobCancelled, scanID, table)
+	if err != nil {
+		return fmt.Errorf("failed to cancel scan job: %v", err)
+	}
+
+	if cancelRunningScan(scanID) {
+		log.Printf("[SCAN-CANCEL] [INFO] Cancelled running scan %s", scanID)
+	}
+
+	return markScanCancelled(table, scanID)
+}
+
+func markScanCancelled(table, scanID string) error {
+	_, err := dbPool.Exec(context.Background(),
+		fmt.Sprintf(`UPDATE %s SET status = $1 WHERE scan_id = $2 AND status NOT IN ('success', 'completed')`, table),
+		ScanCancelled, scanID)
+	if err != nil {
+		return fmt.Errorf("failed to mark scan cancelled: %v", err)
+	}
+	return nil
+}
+
+// CancelScanHandler handles POST /scan/{tool}/{scan_id}/cancel. tool is the
+// scan job kind, e.g. amass, shuffledns or katana-url.
+func CancelScanHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tool := vars["tool"]
+	scanID := vars["scan_id"]
+
+	kind, ok := lookupScanJobKind(tool)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown scan tool: %s", tool), http.StatusNotFound)
+		return
+	}
+
+	var status string
+	err := dbPool.QueryRow(context.Background(),
+		fmt.Sprintf(`SELECT status FROM %s WHERE scan_id = $1`, kind.table), scanID).Scan(&status)
+	if err != nil {
+		http.Error(w, "Scan not found", http.StatusNotFound)
+		return
+	}
+
+	if status == "success" || status == "completed" || status == ScanCancelled {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID, "status": status})
+		return
+	}
+
+	if err := CancelScan(kind.table, scanID); err != nil {
+		log.Printf("[SCAN-CANCEL] [ERROR] Failed to cancel %s scan %s: %v", tool, scanID, err)
+		http.Error(w, "Failed to cancel scan", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[SCAN-CANCEL] [INFO] Cancelled %s scan %s", tool, scanID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID, "status": ScanCancelled})
+}
+
+// CancelAutoScanSessionScans cancels every unfinished scan started by an
+// auto-scan session and returns how many were cancelled.
+func CancelAutoScanSessionScans(sessionID string) int {
+	cancelled := 0
+	for _, table := range scanJobTables() {
+		rows, err := dbPool.Query(context.Background(),
+			fmt.Sprintf(`SELECT scan_id::text FROM %s WHERE auto_scan_session_id = $1 AND status IN ('pending', 'running')`, table),
+			sessionID)
+		if err != nil {
+			// Not every scan table is tied to auto-scan sessions.
+			continue
+		}
+		var scanIDs []string
+		for rows.Next() {
+			var scanID string
+			if err := rows.Scan(&scanID); err == nil {
+				scanIDs = append(scanIDs, scanID)
+			}
+		}
+		rows.Close()
+
+		for _, scanID := range scanIDs {
+			if err := CancelScan(table, scanID); err != nil {
+				log.Printf("[SCAN-CANCEL] [ERROR] Failed to cancel scan %s in %s: %v", scanID, table, err)
+				continue
+			}
+			cancelled++
+		}
+	}
+
+	log.Printf("[SCAN-CANCEL] [INFO] Cancelled %d scans for auto-scan session %s", cancelled, sessionID)
+	return cancelled
+}
@@ -0,0 +1,677 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // the engine image ships without a zoneinfo database
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	scheduleModeAutoScan = "auto_scan"
+	scheduleModeTools    = "tools"
+
+	// Misfire policies decide what happens to a run that was due while the
+	// engine was down for longer than the misfire grace period.
+	scheduleMisfireRunOnce = "run_once"
+	scheduleMisfireSkip    = "skip"
+
+	schedulePollInterval = 30 * time.Second
+)
+
+// ScanSchedule is a row of scan_schedules.
+type ScanSchedule struct {
+	ID             string          `json:"id"`
+	ScopeTargetID  string          `json:"scope_target_id"`
+	Name           string          `json:"name"`
+	CronExpression string          `json:"cron_expression"`
+	Timezone       string          `json:"timezone"`
+	JitterSeconds  int             `json:"jitter_seconds"`
+	Mode           string          `json:"mode"`
+	Tools          []string        `json:"tools"`
+	AutoScanConfig json.RawMessage `json:"auto_scan_config,omitempty"`
+	MisfirePolicy  string          `json:"misfire_policy"`
+	Enabled        bool            `json:"enabled"`
+	NextRunAt      *time.Time      `json:"next_run_at"`
+	ScheduledRunAt *time.Time      `json:"scheduled_run_at"`
+	LastRunAt      *time.Time      `json:"last_run_at"`
+	LastRunStatus  *string         `json:"last_run_status"`
+	LastRunError   *string         `json:"last_run_error"`
+	LastRunScanIDs []string        `json:"last_run_scan_ids"`
+	LastSessionID  *string         `json:"last_session_id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+const scanScheduleColumns = `id::text, scope_target_id::text, name, cron_expression, timezone, jitter_seconds,
+	mode, tools, auto_scan_config, misfire_policy, enabled, next_run_at, scheduled_run_at, last_run_at,
+	last_run_status, last_run_error, last_run_scan_ids, last_session_id::text, created_at, updated_at`
+
+func scanScanSchedule(row pgx.Row) (*ScanSchedule, error) {
+	var s ScanSchedule
+	var autoScanConfig []byte
+	err := row.Scan(&s.ID, &s.ScopeTargetID, &s.Name, &s.CronExpression, &s.Timezone, &s.JitterSeconds,
+		&s.Mode, &s.Tools, &autoScanConfig, &s.MisfirePolicy, &s.Enabled, &s.NextRunAt, &s.ScheduledRunAt, &s.LastRunAt,
+		&s.LastRunStatus, &s.LastRunError, &s.LastRunScanIDs, &s.LastSessionID, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if len(autoScanConfig) > 0 {
+		s.AutoScanConfig = autoScanConfig
+	}
+	if s.Tools == nil {
+		s.Tools = []string{}
+	}
+	if s.LastRunScanIDs == nil {
+		s.LastRunScanIDs = []string{}
+	}
+	return &s, nil
+}
+
+func createScanScheduleTables() error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS scan_schedules (
+			id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+			scope_target_id TEXT NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			name TEXT NOT NULL DEFAULT '',
+			cron_expression TEXT NOT NULL,
+			timezone TEXT NOT NULL DEFAULT 'UTC',
+			jitter_seconds INTEGER NOT NULL DEFAULT 0,
+			mode TEXT NOT NULL DEFAULT 'tools',
+			tools TEXT[] NOT NULL DEFAULT '{}',
+			auto_scan_config JSONB,
+			misfire_policy TEXT NOT NULL DEFAULT 'run_once',
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			next_run_at TIMESTAMP WITH TIME ZONE,
+			scheduled_run_at TIMESTAMP WITH TIME ZONE,
+			last_run_at TIMESTAMP WITH TIME ZONE,
+			last_run_status TEXT,
+			last_run_error TEXT,
+			last_run_scan_ids TEXT[],
+			last_session_id TEXT,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		)`,
+		`ALTER TABLE scan_schedules ADD COLUMN IF NOT EXISTS scheduled_run_at TIMESTAMP WITH TIME ZONE`,
+		`CREATE INDEX IF NOT EXISTS scan_schedules_due_idx ON scan_schedules (next_run_at)`,
+		`CREATE INDEX IF NOT EXISTS scan_schedules_scope_target_idx ON scan_schedules (scope_target_id)`,
+	}
+	for _, query := range queries {
+		if _, err := dbPool.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scheduleMisfireGrace is how late a run may start before it counts as a
+// misfire (RECON_SCHEDULE_MISFIRE_GRACE_SECONDS, default 10 minutes).
+func scheduleMisfireGrace() time.Duration {
+	return time.Duration(envInt("RECON_SCHEDULE_MISFIRE_GRACE_SECONDS", 600)) * time.Second
+}
+
+// StartScanScheduler creates the schedule table and enqueues due schedules
+// until ctx is cancelled. Schedules are claimed with SKIP LOCKED so several
+// engines can run the scheduler against the same database.
+func StartScanScheduler(ctx context.Context) {
+	if err := createScanScheduleTables(); err != nil {
+		log.Printf("[SCHEDULER] [ERROR] Failed to create scan schedule tables: %v", err)
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(schedulePollInterval)
+		defer ticker.Stop()
+		for {
+			runDueScanSchedules()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	log.Printf("[SCHEDULER] [INFO] Scan scheduler started")
+}
+
+func runDueScanSchedules() {
+	for {
+		schedule, misfired, err := claimDueScanSchedule()
+		if err != nil {
+			log.Printf("[SCHEDULER] [ERROR] Failed to claim due schedule: %v", err)
+			return
+		}
+		if schedule == nil {
+			return
+		}
+
+		if misfired && schedule.MisfirePolicy == scheduleMisfireSkip {
+			log.Printf("[SCHEDULER] [INFO] Skipping misfired run of schedule %s", schedule.ID)
+			recordScanScheduleRun(schedule.ID, "misfired", "run skipped after downtime", nil, nil)
+			continue
+		}
+
+		log.Printf("[SCHEDULER] [INFO] Firing schedule %s (%s) for scope target %s", schedule.ID, schedule.Mode, schedule.ScopeTargetID)
+		fireScanSchedule(schedule)
+	}
+}
+
+// claimDueScanSchedule locks one due schedule, moves its next_run_at forward
+// and returns it. Only one run is fired however many were missed.
+func claimDueScanSchedule() (*ScanSchedule, bool, error) {
+	ctx := context.Background()
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	schedule, err := scanScanSchedule(tx.QueryRow(ctx, `
+		SELECT `+scanScheduleColumns+` FROM scan_schedules
+		WHERE enabled AND next_run_at <= NOW()
+		ORDER BY next_run_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`))
+	if err == pgx.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	now := time.Now()
+	misfired := schedule.NextRunAt != nil && now.Sub(*schedule.NextRunAt) > scheduleMisfireGrace()
+
+	// The next run follows the cron time that just came due rather than the
+	// jittered moment it fired, so jitter never skips a cron time. After
+	// downtime the missed times collapse into this run.
+	after := now
+	if schedule.ScheduledRunAt != nil {
+		after = *schedule.ScheduledRunAt
+	} else if schedule.NextRunAt != nil {
+		after = *schedule.NextRunAt
+	}
+	slot, nextRun, err := nextScanScheduleRun(schedule.CronExpression, schedule.Timezone, schedule.JitterSeconds, after)
+	if err == nil && now.Sub(slot) > scheduleMisfireGrace() {
+		slot, nextRun, err = nextScanScheduleRun(schedule.CronExpression, schedule.Timezone, schedule.JitterSeconds, now)
+	}
+	if err != nil {
+		// The expression was validated on save, so this only happens if the
+		// row was edited by hand. Disable it instead of spinning on it.
+		log.Printf("[SCHEDULER] [ERROR] Disabling schedule %s: %v", schedule.ID, err)
+		_, err = tx.Exec(ctx, `UPDATE scan_schedules SET enabled = false, last_run_status = 'error', last_run_error = $1, updated_at = NOW() WHERE id = $2`,
+			err.Error(), schedule.ID)
+		if err != nil {
+			return nil, false, err
+		}
+		return nil, false, tx.Commit(ctx)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE scan_schedules SET next_run_at = $1, scheduled_run_at = $2, updated_at = NOW() WHERE id = $3`,
+		nextRun, slot, schedule.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, err
+	}
+	return schedule, misfired, nil
+}
+
+// nextScanScheduleRun returns the next cron time after the given time in the
+// schedule's timezone, and the moment to run it: that time pushed back by a
+// random delay of up to jitterSeconds.
+func nextScanScheduleRun(expr, timezone string, jitterSeconds int, after time.Time) (time.Time, time.Time, error) {
+	runs, err := previewScanScheduleRuns(expr, timezone, after, 1)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	slot := runs[0]
+	if jitterSeconds > 0 {
+		return slot, slot.Add(time.Duration(rand.Intn(jitterSeconds+1)) * time.Second), nil
+	}
+	return slot, slot, nil
+}
+
+// previewScanScheduleRuns lists the next count cron times after now, without
+// jitter.
+func previewScanScheduleRuns(expr, timezone string, now time.Time, count int) ([]time.Time, error) {
+	cron, err := ParseCronSchedule(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression: %v", err)
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone: %v", err)
+	}
+
+	var runs []time.Time
+	t := now.In(loc)
+	for len(runs) < count {
+		t = cron.Next(t)
+		if t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("cron expression %q never fires", expr)
+	}
+	return runs, nil
+}
+
+func fireScanSchedule(schedule *ScanSchedule) {
+	var scopeTarget, scopeType string
+	err := dbPool.QueryRow(context.Background(),
+		`SELECT scope_target, type FROM scope_targets WHERE id = $1`, schedule.ScopeTargetID).Scan(&scopeTarget, &scopeType)
+	if err != nil {
+		recordScanScheduleRun(schedule.ID, "error", fmt.Sprintf("failed to get scope target: %v", err), nil, nil)
+		return
+	}
+
+	if schedule.Mode == scheduleModeAutoScan {
+		sessionID, err := startScheduledAutoScan(schedule)
+		if err != nil {
+			log.Printf("[SCHEDULER] [ERROR] Schedule %s: %v", schedule.ID, err)
+			recordScanScheduleRun(schedule.ID, "error", err.Error(), nil, nil)
+			return
+		}
+		if sessionID == "" {
+			recordScanScheduleRun(schedule.ID, "skipped", "an auto scan is already running for this scope target", nil, nil)
+			return
+		}
+		recordScanScheduleRun(schedule.ID, "queued", "", nil, &sessionID)
+		return
+	}
+
+	target := scopeTarget
+	if scopeType == "Wildcard" {
+		target = strings.TrimPrefix(scopeTarget, "*.")
+	}
+
+	var scanIDs, failures []string
+	for _, kind := range schedule.Tools {
+		scanID, err := enqueueScheduledTool(kind, schedule.ScopeTargetID, target)
+		if err != nil {
+			log.Printf("[SCHEDULER] [ERROR] Schedule %s: failed to queue %s: %v", schedule.ID, kind, err)
+			failures = append(failures, fmt.Sprintf("%s: %v", kind, err))
+			continue
+		}
+		scanIDs = append(scanIDs, scanID)
+	}
+
+	status := "queued"
+	if len(failures) > 0 {
+		status = "error"
+		if len(scanIDs) > 0 {
+			status = "partial"
+		}
+	}
+	recordScanScheduleRun(schedule.ID, status, strings.Join(failures, "; "), scanIDs, nil)
+}
+
+func enqueueScheduledTool(kind, scopeTargetID, target string) (string, error) {
+	tool, ok := LookupTool(kind)
+	if !ok {
+		return "", fmt.Errorf("tool cannot be scheduled")
+	}
+	return QueueToolScan(tool, ToolInput{ScopeTargetID: scopeTargetID, Target: target}, "")
+}
+
+// startScheduledAutoScan opens an auto-scan session like the UI does. It
+// returns an empty session ID when a session is already running for the
+// scope target.
+func startScheduledAutoScan(schedule *ScanSchedule) (string, error) {
+	var running int
+	err := dbPool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM auto_scan_sessions WHERE scope_target_id = $1 AND status = 'running'`,
+		schedule.ScopeTargetID).Scan(&running)
+	if err != nil {
+		return "", fmt.Errorf("failed to check running sessions: %v", err)
+	}
+	if running > 0 {
+		return "", nil
+	}
+
+	snapshot := []byte(schedule.AutoScanConfig)
+	if len(snapshot) == 0 || string(snapshot) == "null" {
+		snapshot, err = json.Marshal(loadAutoScanConfig(nil))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var sessionID string
+	err = dbPool.QueryRow(context.Background(), `
+		INSERT INTO auto_scan_sessions (scope_target_id, config_snapshot, status, started_at)
+		VALUES ($1, $2, 'running', NOW())
+		RETURNING id`, schedule.ScopeTargetID, snapshot).Scan(&sessionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to create auto scan session: %v", err)
+	}
+
+	StartAutoScanSession(sessionID, schedule.ScopeTargetID)
+	return sessionID, nil
+}
+
+func recordScanScheduleRun(scheduleID, status, message string, scanIDs []string, sessionID *string) {
+	var errorMessage *string
+	if message != "" {
+		errorMessage = &message
+	}
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE scan_schedules
+		SET last_run_at = NOW(), last_run_status = $1, last_run_error = $2, last_run_scan_ids = $3,
+			last_session_id = $4, updated_at = NOW()
+		WHERE id = $5`, status, errorMessage, scanIDs, sessionID, scheduleID)
+	if err != nil {
+		log.Printf("[SCHEDULER] [ERROR] Failed to record run of schedule %s: %v", scheduleID, err)
+	}
+}
+
+type scanScheduleRequest struct {
+	Name           *string          `json:"name"`
+	CronExpression *string          `json:"cron_expression"`
+	Timezone       *string          `json:"timezone"`
+	JitterSeconds  *int             `json:"jitter_seconds"`
+	Mode           *string          `json:"mode"`
+	Tools          *[]string        `json:"tools"`
+	AutoScanConfig *json.RawMessage `json:"auto_scan_config"`
+	MisfirePolicy  *string          `json:"misfire_policy"`
+	Enabled        *bool            `json:"enabled"`
+}
+
+// apply copies the set fields of req onto s and validates the result.
+func (req *scanScheduleRequest) apply(s *ScanSchedule, scopeType string) error {
+	if req.Name != nil {
+		s.Name = *req.Name
+	}
+	if req.CronExpression != nil {
+		s.CronExpression = *req.CronExpression
+	}
+	if req.Timezone != nil {
+		s.Timezone = *req.Timezone
+	}
+	if req.JitterSeconds != nil {
+		s.JitterSeconds = *req.JitterSeconds
+	}
+	if req.Mode != nil {
+		s.Mode = *req.Mode
+	}
+	if req.Tools != nil {
+		s.Tools = *req.Tools
+	}
+	if req.AutoScanConfig != nil {
+		s.AutoScanConfig = *req.AutoScanConfig
+	}
+	if req.MisfirePolicy != nil {
+		s.MisfirePolicy = *req.MisfirePolicy
+	}
+	if req.Enabled != nil {
+		s.Enabled = *req.Enabled
+	}
+
+	if _, err := previewScanScheduleRuns(s.CronExpression, s.Timezone, time.Now(), 1); err != nil {
+		return err
+	}
+	if s.JitterSeconds < 0 || s.JitterSeconds > 86400 {
+		return fmt.Errorf("jitter_seconds must be between 0 and 86400")
+	}
+	if s.MisfirePolicy != scheduleMisfireRunOnce && s.MisfirePolicy != scheduleMisfireSkip {
+		return fmt.Errorf("misfire_policy must be %q or %q", scheduleMisfireRunOnce, scheduleMisfireSkip)
+	}
+
+	switch s.Mode {
+	case scheduleModeAutoScan:
+		if scopeType != "Wildcard" {
+			return fmt.Errorf("auto scan schedules require a wildcard scope target")
+		}
+		if len(s.AutoScanConfig) > 0 && string(s.AutoScanConfig) != "null" {
+			var config AutoScanConfig
+			if err := json.Unmarshal(s.AutoScanConfig, &config); err != nil {
+				return fmt.Errorf("invalid auto_scan_config: %v", err)
+			}
+		}
+	case scheduleModeTools:
+		if len(s.Tools) == 0 {
+			return fmt.Errorf("tools schedules need at least one tool")
+		}
+		for _, kind := range s.Tools {
+			tool, ok := LookupTool(kind)
+			if !ok || tool.InputKind() == ToolInputURLList {
+				return fmt.Errorf("tool %q cannot be scheduled", kind)
+			}
+			if toolScopeType(tool) != scopeType {
+				return fmt.Errorf("tool %q requires a %s scope target", kind, toolScopeType(tool))
+			}
+		}
+	default:
+		return fmt.Errorf("mode must be %q or %q", scheduleModeAutoScan, scheduleModeTools)
+	}
+	return nil
+}
+
+func nullableJSON(raw json.RawMessage) interface{} {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	return string(raw)
+}
+
+// CreateScanSchedule handles POST /scopetarget/{id}/schedules.
+func CreateScanSchedule(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+
+	var scopeType string
+	err := dbPool.QueryRow(context.Background(), `SELECT type FROM scope_targets WHERE id = $1`, scopeTargetID).Scan(&scopeType)
+	if err != nil {
+		http.Error(w, "Scope target not found", http.StatusNotFound)
+		return
+	}
+
+	var req scanScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	schedule := &ScanSchedule{
+		ScopeTargetID: scopeTargetID,
+		Timezone:      "UTC",
+		Mode:          scheduleModeTools,
+		MisfirePolicy: scheduleMisfireRunOnce,
+		Enabled:       true,
+	}
+	if err := req.apply(schedule, scopeType); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slot, nextRun, err := nextScanScheduleRun(schedule.CronExpression, schedule.Timezone, schedule.JitterSeconds, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := scanScanSchedule(dbPool.QueryRow(context.Background(), `
+		INSERT INTO scan_schedules (scope_target_id, name, cron_expression, timezone, jitter_seconds, mode, tools,
+			auto_scan_config, misfire_policy, enabled, next_run_at, scheduled_run_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING `+scanScheduleColumns,
+		scopeTargetID, schedule.Name, schedule.CronExpression, schedule.Timezone, schedule.JitterSeconds, schedule.Mode,
+		schedule.Tools, nullableJSON(schedule.AutoScanConfig), schedule.MisfirePolicy, schedule.Enabled, nextRun, slot))
+	if err != nil {
+		log.Printf("[SCHEDULER] [ERROR] Failed to create schedule: %v", err)
+		http.Error(w, "Failed to create schedule", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[SCHEDULER] [INFO] Created schedule %s for scope target %s (%s, next run %v)", created.ID, scopeTargetID, created.CronExpression, nextRun)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetScanSchedulesForScopeTarget handles GET /scopetarget/{id}/schedules.
+func GetScanSchedulesForScopeTarget(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+
+	rows, err := dbPool.Query(context.Background(),
+		`SELECT `+scanScheduleColumns+` FROM scan_schedules WHERE scope_target_id = $1 ORDER BY created_at`, scopeTargetID)
+	if err != nil {
+		log.Printf("[SCHEDULER] [ERROR] Failed to list schedules: %v", err)
+		http.Error(w, "Failed to get schedules", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	schedules := []*ScanSchedule{}
+	for rows.Next() {
+		schedule, err := scanScanSchedule(rows)
+		if err != nil {
+			log.Printf("[SCHEDULER] [ERROR] Failed to scan schedule row: %v", err)
+			continue
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedules)
+}
+
+// GetScanSchedule handles GET /schedules/{schedule_id}.
+func GetScanSchedule(w http.ResponseWriter, r *http.Request) {
+	schedule, err := scanScanSchedule(dbPool.QueryRow(context.Background(),
+		`SELECT `+scanScheduleColumns+` FROM scan_schedules WHERE id = $1`, mux.Vars(r)["schedule_id"]))
+	if err != nil {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// UpdateScanSchedule handles PUT /schedules/{schedule_id}. Only the fields
+// present in the body are changed; next_run_at is recomputed.
+func UpdateScanSchedule(w http.ResponseWriter, r *http.Request) {
+	scheduleID := mux.Vars(r)["schedule_id"]
+
+	schedule, err := scanScanSchedule(dbPool.QueryRow(context.Background(),
+		`SELECT `+scanScheduleColumns+` FROM scan_schedules WHERE id = $1`, scheduleID))
+	if err != nil {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+
+	var scopeType string
+	err = dbPool.QueryRow(context.Background(), `SELECT type FROM scope_targets WHERE id = $1`, schedule.ScopeTargetID).Scan(&scopeType)
+	if err != nil {
+		http.Error(w, "Scope target not found", http.StatusNotFound)
+		return
+	}
+
+	var req scanScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.apply(schedule, scopeType); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slot, nextRun, err := nextScanScheduleRun(schedule.CronExpression, schedule.Timezone, schedule.JitterSeconds, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := scanScanSchedule(dbPool.QueryRow(context.Background(), `
+		UPDATE scan_schedules
+		SET name = $1, cron_expression = $2, timezone = $3, jitter_seconds = $4, mode = $5, tools = $6,
+			auto_scan_config = $7, misfire_policy = $8, enabled = $9, next_run_at = $10, scheduled_run_at = $11,
+			updated_at = NOW()
+		WHERE id = $12
+		RETURNING `+scanScheduleColumns,
+		schedule.Name, schedule.CronExpression, schedule.Timezone, schedule.JitterSeconds, schedule.Mode, schedule.Tools,
+		nullableJSON(schedule.AutoScanConfig), schedule.MisfirePolicy, schedule.Enabled, nextRun, slot, scheduleID))
+	if err != nil {
+		log.Printf("[SCHEDULER] [ERROR] Failed to update schedule %s: %v", scheduleID, err)
+		http.Error(w, "Failed to update schedule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteScanSchedule handles DELETE /schedules/{schedule_id}. Scans already
+// queued by the schedule keep running.
+func DeleteScanSchedule(w http.ResponseWriter, r *http.Request) {
+	scheduleID := mux.Vars(r)["schedule_id"]
+
+	tag, err := dbPool.Exec(context.Background(), `DELETE FROM scan_schedules WHERE id = $1`, scheduleID)
+	if err != nil {
+		log.Printf("[SCHEDULER] [ERROR] Failed to delete schedule %s: %v", scheduleID, err)
+		http.Error(w, "Failed to delete schedule", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeScheduleRuns(w http.ResponseWriter, expr, timezone string, jitterSeconds int, r *http.Request) {
+	count := 5
+	if c, err := strconv.Atoi(r.URL.Query().Get("count")); err == nil && c > 0 && c <= 100 {
+		count = c
+	}
+
+	runs, err := previewScanScheduleRuns(expr, timezone, time.Now(), count)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"cron_expression": expr,
+		"timezone":        timezone,
+		"jitter_seconds":  jitterSeconds,
+		"next_runs":       runs,
+	})
+}
+
+// GetScanScheduleNextRuns handles GET /schedules/{schedule_id}/next-runs.
+// The times are the nominal cron times; each run may start up to
+// jitter_seconds later.
+func GetScanScheduleNextRuns(w http.ResponseWriter, r *http.Request) {
+	schedule, err := scanScanSchedule(dbPool.QueryRow(context.Background(),
+		`SELECT `+scanScheduleColumns+` FROM scan_schedules WHERE id = $1`, mux.Vars(r)["schedule_id"]))
+	if err != nil {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+	writeScheduleRuns(w, schedule.CronExpression, schedule.Timezone, schedule.JitterSeconds, r)
+}
+
+// PreviewScanSchedule handles GET /schedules/preview?cron=&timezone=&count=
+// so a schedule can be checked before it is saved.
+func PreviewScanSchedule(w http.ResponseWriter, r *http.Request) {
+	timezone := r.URL.Query().Get("timezone")
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	writeScheduleRuns(w, r.URL.Query().Get("cron"), timezone, 0, r)
+}
@@ -0,0 +1,599 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Scan stream event types.
+const (
+	ScanEventLog    = "log"
+	ScanEventPhase  = "phase"
+	ScanEventCount  = "count"
+	ScanEventStatus = "status"
+	ScanEventEnd    = "end"
+)
+
+const (
+	scanStreamBufferSize    = 1000
+	scanStreamRetention     = 5 * time.Minute
+	scanStreamCountInterval = time.Second
+	scanStreamKeepAlive     = 15 * time.Second
+	// scanStreamIdleTimeout closes connections, and drops streams nobody
+	// is subscribed to, once a scan has published nothing for this long,
+	// e.g. because its job runs on another engine and never ends here.
+	scanStreamIdleTimeout = 30 * time.Minute
+)
+
+// ScanStreamEvent is one message on a scan's live stream.
+type ScanStreamEvent struct {
+	Seq    int64          `json:"seq"`
+	Type   string         `json:"type"`
+	ScanID string         `json:"scan_id"`
+	Time   time.Time      `json:"time"`
+	Stream string         `json:"stream,omitempty"`
+	Line   string         `json:"line,omitempty"`
+	Phase  string         `json:"phase,omitempty"`
+	Status string         `json:"status,omitempty"`
+	Counts map[string]int `json:"counts,omitempty"`
+}
+
+// scanStream keeps the recent events of one scan so clients that connect
+// mid-scan (or reconnect with Last-Event-ID) can catch up.
+type scanStream struct {
+	mu         sync.Mutex
+	seq        int64
+	events     []ScanStreamEvent
+	subs       map[chan ScanStreamEvent]struct{}
+	ended      bool
+	lastActive time.Time
+}
+
+var (
+	scanStreamsMu    sync.Mutex
+	scanStreams      = make(map[string]*scanStream)
+	scanStreamReaper sync.Once
+)
+
+func getScanStream(scanID string, create bool) *scanStream {
+	scanStreamsMu.Lock()
+	defer scanStreamsMu.Unlock()
+	stream, ok := scanStreams[scanID]
+	if !ok && create {
+		stream = &scanStream{subs: make(map[chan ScanStreamEvent]struct{}), lastActive: time.Now()}
+		scanStreams[scanID] = stream
+		scanStreamReaper.Do(func() { go reapIdleScanStreams() })
+	}
+	return stream
+}
+
+// reapIdleScanStreams drops streams that never got an end event and have
+// been idle, with no subscribers, for scanStreamIdleTimeout.
+func reapIdleScanStreams() {
+	ticker := time.NewTicker(scanStreamIdleTimeout / 2)
+	defer ticker.Stop()
+	for range ticker.C {
+		scanStreamsMu.Lock()
+		for scanID, stream := range scanStreams {
+			stream.mu.Lock()
+			if len(stream.subs) == 0 && time.Since(stream.lastActive) > scanStreamIdleTimeout {
+				delete(scanStreams, scanID)
+			}
+			stream.mu.Unlock()
+		}
+		scanStreamsMu.Unlock()
+	}
+}
+
+// publishScanEvent appends ev to the scan's stream and fans it out. Slow
+// subscribers miss events rather than blocking the scan; they can tell from
+// the gap in seq.
+func publishScanEvent(scanID string, ev ScanStreamEvent) {
+	stream := getScanStream(scanID, true)
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	if stream.ended && ev.Type != ScanEventEnd {
+		// A retried or resumed scan reuses its stream.
+		stream.ended = false
+	}
+
+	stream.seq++
+	ev.Seq = stream.seq
+	ev.ScanID = scanID
+	ev.Time = time.Now()
+	stream.lastActive = ev.Time
+
+	stream.events = append(stream.events, ev)
+	if len(stream.events) > scanStreamBufferSize {
+		stream.events = stream.events[len(stream.events)-scanStreamBufferSize:]
+	}
+
+	for sub := range stream.subs {
+		select {
+		case sub <- ev:
+		default:
+		}
+	}
+
+	if ev.Type == ScanEventEnd {
+		stream.ended = true
+		for sub := range stream.subs {
+			close(sub)
+			delete(stream.subs, sub)
+		}
+		time.AfterFunc(scanStreamRetention, func() {
+			scanStreamsMu.Lock()
+			defer scanStreamsMu.Unlock()
+			stream.mu.Lock()
+			defer stream.mu.Unlock()
+			if stream.ended && scanStreams[scanID] == stream {
+				delete(scanStreams, scanID)
+			}
+		})
+	}
+}
+
+// subscribe returns the buffered events after afterSeq and a channel for new
+// ones. The channel is nil if the stream has already ended.
+func (s *scanStream) subscribe(afterSeq int64) ([]ScanStreamEvent, chan ScanStreamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var replay []ScanStreamEvent
+	for _, ev := range s.events {
+		if ev.Seq > afterSeq {
+			replay = append(replay, ev)
+		}
+	}
+	if s.ended {
+		return replay, nil
+	}
+
+	sub := make(chan ScanStreamEvent, 256)
+	s.subs[sub] = struct{}{}
+	return replay, sub
+}
+
+func (s *scanStream) unsubscribe(sub chan ScanStreamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subs[sub]; ok {
+		delete(s.subs, sub)
+		close(sub)
+	}
+}
+
+// PublishScanLog pushes one line of tool output to the scan's stream.
+func PublishScanLog(scanID, stream, line string) {
+	publishScanEvent(scanID, ScanStreamEvent{Type: ScanEventLog, Stream: stream, Line: line})
+}
+
+// PublishScanPhase announces that a multi-stage scan moved to a new phase,
+// e.g. discovering_ips -> port_scanning.
+func PublishScanPhase(scanID, phase string) {
+	publishScanEvent(scanID, ScanStreamEvent{Type: ScanEventPhase, Phase: phase})
+}
+
+// PublishScanCounts pushes incremental result counts for a running scan.
+func PublishScanCounts(scanID string, counts map[string]int) {
+	publishScanEvent(scanID, ScanStreamEvent{Type: ScanEventCount, Counts: counts})
+}
+
+// PublishScanStatus reports a scan row status change.
+func PublishScanStatus(scanID, status string) {
+	publishScanEvent(scanID, ScanStreamEvent{Type: ScanEventStatus, Status: status})
+}
+
+// EndScanStream closes the scan's stream with its final status.
+func EndScanStream(scanID, status string) {
+	publishScanEvent(scanID, ScanStreamEvent{Type: ScanEventEnd, Status: status})
+}
+
+type scanStreamIDKey struct{}
+
+// withScanStreamID marks ctx so tool commands run under it stream their
+// output to scanID.
+func withScanStreamID(ctx context.Context, scanID string) context.Context {
+	return context.WithValue(ctx, scanStreamIDKey{}, scanID)
+}
+
+func scanStreamIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	scanID, _ := ctx.Value(scanStreamIDKey{}).(string)
+	return scanID
+}
+
+// scanStreamWriter splits tool output into lines for the scan stream and
+// keeps a running line count.
+type scanStreamWriter struct {
+	scanID    string
+	stream    string
+	partial   []byte
+	lines     int
+	lastCount time.Time
+}
+
+func newScanStreamWriter(scanID, stream string) *scanStreamWriter {
+	return &scanStreamWriter{scanID: scanID, stream: stream, lastCount: time.Now()}
+}
+
+func (w *scanStreamWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+	if time.Since(w.lastCount) >= scanStreamCountInterval {
+		w.publishCount()
+	}
+	return len(p), nil
+}
+
+func (w *scanStreamWriter) emit(line string) {
+	line = strings.TrimRight(line, "\r")
+	if line == "" {
+		return
+	}
+	w.lines++
+	PublishScanLog(w.scanID, w.stream, line)
+}
+
+func (w *scanStreamWriter) publishCount() {
+	w.lastCount = time.Now()
+	PublishScanCounts(w.scanID, map[string]int{w.stream + "_lines": w.lines})
+}
+
+func (w *scanStreamWriter) flush() {
+	if len(w.partial) > 0 {
+		w.emit(string(w.partial))
+		w.partial = nil
+	}
+	w.publishCount()
+}
+
+// teeScanStream wraps dst so everything written to it also reaches the
+// scan stream.
+func teeScanStream(dst io.Writer, w *scanStreamWriter) io.Writer {
+	if dst == nil {
+		return w
+	}
+	return io.MultiWriter(dst, w)
+}
+
+// lookupScanStream finds the stream for scanID. A finished scan with no
+// buffered stream gets a one-off stream holding its final status; a queued
+// scan gets a live stream that its job will publish to once it starts here.
+func lookupScanStream(scanID string) (*scanStream, bool) {
+	if stream := getScanStream(scanID, false); stream != nil {
+		return stream, true
+	}
+
+	var jobStatus, table string
+	err := dbPool.QueryRow(context.Background(),
+		`SELECT status, scan_table FROM scan_jobs WHERE scan_id = $1 ORDER BY created_at DESC LIMIT 1`,
+		scanID).Scan(&jobStatus, &table)
+	if err != nil {
+		return nil, false
+	}
+
+	var rowStatus string
+	if err := dbPool.QueryRow(context.Background(),
+		fmt.Sprintf(`SELECT status FROM %s WHERE scan_id = $1`, table), scanID).Scan(&rowStatus); err != nil {
+		rowStatus = jobStatus
+	}
+
+	switch jobStatus {
+	case ScanJobSucceeded, ScanJobDeadLettered, ScanJobCancelled:
+		return &scanStream{
+			seq:    1,
+			ended:  true,
+			events: []ScanStreamEvent{{Seq: 1, Type: ScanEventEnd, ScanID: scanID, Time: time.Now(), Status: rowStatus}},
+		}, true
+	}
+
+	PublishScanStatus(scanID, rowStatus)
+	return getScanStream(scanID, true), true
+}
+
+// StreamScan handles GET /scans/{scan_id}/stream. It serves Server-Sent
+// Events, or a WebSocket when the request asks for an upgrade. Each message
+// is a JSON ScanStreamEvent; the stream ends with an "end" event. SSE clients
+// can resume with Last-Event-ID (or ?after=). Streams only cover scans run by
+// this engine.
+func StreamScan(w http.ResponseWriter, r *http.Request) {
+	scanID := mux.Vars(r)["scan_id"]
+
+	stream, ok := lookupScanStream(scanID)
+	if !ok {
+		http.Error(w, "Scan not found", http.StatusNotFound)
+		return
+	}
+
+	var afterSeq int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		afterSeq, _ = strconv.ParseInt(v, 10, 64)
+	} else if v := r.URL.Query().Get("after"); v != "" {
+		afterSeq, _ = strconv.ParseInt(v, 10, 64)
+	}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		serveScanStreamWebSocket(w, r, stream, afterSeq)
+		return
+	}
+	serveScanStreamSSE(w, r, stream, afterSeq)
+}
+
+func serveScanStreamSSE(w http.ResponseWriter, r *http.Request, stream *scanStream, afterSeq int64) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	writeEvent := func(ev ScanStreamEvent) error {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.Seq, ev.Type, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	replay, sub := stream.subscribe(afterSeq)
+	for _, ev := range replay {
+		if err := writeEvent(ev); err != nil {
+			if sub != nil {
+				stream.unsubscribe(sub)
+			}
+			return
+		}
+	}
+	if sub == nil {
+		return
+	}
+	defer stream.unsubscribe(sub)
+
+	keepAlive := time.NewTicker(scanStreamKeepAlive)
+	defer keepAlive.Stop()
+	lastEvent := time.Now()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if time.Since(lastEvent) > scanStreamIdleTimeout {
+				return
+			}
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case ev, ok := <-sub:
+			if !ok {
+				return
+			}
+			if err := writeEvent(ev); err != nil {
+				return
+			}
+			lastEvent = time.Now()
+		}
+	}
+}
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes used by the stream.
+const (
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xA
+)
+
+// serveScanStreamWebSocket speaks just enough RFC 6455 to push text frames
+// to the client and answer pings and closes.
+func serveScanStreamWebSocket(w http.ResponseWriter, r *http.Request, stream *scanStream, afterSeq int64) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" || !strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade") {
+		http.Error(w, "Bad WebSocket handshake", http.StatusBadRequest)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket unsupported", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		log.Printf("[SCAN-STREAM] [ERROR] WebSocket hijack failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(sum[:]))
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	var writeMu sync.Mutex
+	writeFrame := func(opcode byte, payload []byte) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		if err := writeWebSocketFrame(rw.Writer, opcode, payload); err != nil {
+			return err
+		}
+		return rw.Flush()
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			opcode, payload, err := readWebSocketFrame(rw.Reader)
+			if err != nil {
+				return
+			}
+			switch opcode {
+			case wsOpPing:
+				writeFrame(wsOpPong, payload)
+			case wsOpClose:
+				writeFrame(wsOpClose, nil)
+				return
+			}
+		}
+	}()
+
+	writeEvent := func(ev ScanStreamEvent) error {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		return writeFrame(wsOpText, data)
+	}
+
+	replay, sub := stream.subscribe(afterSeq)
+	if sub != nil {
+		defer stream.unsubscribe(sub)
+	}
+	for _, ev := range replay {
+		if err := writeEvent(ev); err != nil {
+			return
+		}
+	}
+
+	keepAlive := time.NewTicker(scanStreamKeepAlive)
+	defer keepAlive.Stop()
+	lastEvent := time.Now()
+
+	for sub != nil {
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if time.Since(lastEvent) > scanStreamIdleTimeout {
+				// 1001 = going away
+				writeFrame(wsOpClose, []byte{0x03, 0xE9})
+				return
+			}
+			if err := writeFrame(wsOpPing, nil); err != nil {
+				return
+			}
+		case ev, ok := <-sub:
+			if !ok {
+				sub = nil
+				break
+			}
+			if err := writeEvent(ev); err != nil {
+				return
+			}
+			lastEvent = time.Now()
+		}
+	}
+
+	// 1000 = normal closure
+	writeFrame(wsOpClose, []byte{0x03, 0xE8})
+}
+
+func writeWebSocketFrame(w io.Writer, opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// readWebSocketFrame reads one client frame. Client payloads are small
+// control frames, so anything over 64KiB is treated as an error.
+func readWebSocketFrame(r *bufio.Reader) (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > 64*1024 {
+		return 0, nil, fmt.Errorf("websocket frame too large")
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return opcode, payload, nil
+}
@@ -10,7 +10,6 @@ import (
 	"io"
 	"log"
 	"net/http"
-	"os/exec"
 	"strings"
 	"time"
 
@@ -99,7 +98,11 @@ func RunNucleiScreenshotScan(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("[INFO] Successfully inserted initial scan record for scan ID: %s", scanID)
 
-	go ExecuteAndParseNucleiScreenshotScan(scanID, domain)
+	if _, err := EnqueueScanJob("nuclei-screenshot", scanID, ScanJobPayload{Target: domain}); err != nil {
+		log.Printf("[ERROR] Failed to queue nuclei-screenshot scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	json.NewEncoder(w).Encode(map[string]string{
 		"scan_id": scanID,
@@ -107,7 +110,7 @@ func RunNucleiScreenshotScan(w http.ResponseWriter, r *http.Request) {
 }
 
 // ExecuteAndParseNucleiScreenshotScan runs the Nuclei screenshot scan and processes its results
-func ExecuteAndParseNucleiScreenshotScan(scanID, domain string) {
+func ExecuteAndParseNucleiScreenshotScan(ctx context.Context, scanID, domain string) {
 	log.Printf("[INFO] Starting Nuclei screenshot scan execution for scan ID: %s", scanID)
 	startTime := time.Now()
 
@@ -170,7 +173,6 @@ func ExecuteAndParseNucleiScreenshotScan(scanID, domain string) {
 
 	// Build base command
 	cmdArgs := []string{
-		"docker", "exec", "ars0n-framework-v2-nuclei-1",
 		"bash", "-c",
 	}
 
@@ -187,7 +189,7 @@ func ExecuteAndParseNucleiScreenshotScan(scanID, domain string) {
 	}
 
 	cmdArgs = append(cmdArgs, nucleiCmd)
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	cmd := NewToolCommandContext(ctx, "nuclei", cmdArgs...)
 	log.Printf("[INFO] Prepared Nuclei command for scan ID %s: %s", scanID, cmd.String())
 
 	var stdout, stderr bytes.Buffer
@@ -219,7 +221,7 @@ func ExecuteAndParseNucleiScreenshotScan(scanID, domain string) {
 
 	// Read and process screenshot files
 	var results []string
-	screenshotFiles, err := exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "ls", "/app/screenshots/").Output()
+	screenshotFiles, err := NewToolCommandContext(ctx, "nuclei", "ls", "/app/screenshots/").Output()
 	if err != nil {
 		log.Printf("[ERROR] Failed to list screenshot files for scan ID %s: %v", scanID, err)
 		UpdateNucleiScreenshotScanStatus(
@@ -246,7 +248,7 @@ func ExecuteAndParseNucleiScreenshotScan(scanID, domain string) {
 		log.Printf("[DEBUG] Processing screenshot file: %s", file)
 
 		// Read the screenshot file
-		imgData, err := exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "cat", "/app/screenshots/"+file).Output()
+		imgData, err := NewToolCommandContext(ctx, "nuclei", "cat", "/app/screenshots/"+file).Output()
 		if err != nil {
 			log.Printf("[WARN] Failed to read screenshot file %s: %v", file, err)
 			continue
@@ -307,7 +309,7 @@ func ExecuteAndParseNucleiScreenshotScan(scanID, domain string) {
 	)
 
 	// Clean up screenshots in the container
-	exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "rm", "-rf", "/app/screenshots/*").Run()
+	NewToolCommandContext(ctx, "nuclei", "rm", "-rf", "/app/screenshots/*").Run()
 }
 
 // UpdateNucleiScreenshotScanStatus updates the status of a Nuclei screenshot scan
@@ -322,117 +324,6 @@ func UpdateNucleiScreenshotScanStatus(scanID, status, result, stderr, command, e
 	}
 }
 
-// GetNucleiScreenshotScanStatus retrieves the status of a Nuclei screenshot scan
-func GetNucleiScreenshotScanStatus(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	scanID := vars["scan_id"]
-
-	var scan NucleiScreenshotStatus
-	query := `SELECT * FROM nuclei_screenshots WHERE scan_id = $1`
-	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
-		&scan.ID,
-		&scan.ScanID,
-		&scan.Domain,
-		&scan.Status,
-		&scan.Result,
-		&scan.Error,
-		&scan.StdOut,
-		&scan.StdErr,
-		&scan.Command,
-		&scan.ExecTime,
-		&scan.CreatedAt,
-		&scan.ScopeTargetID,
-		&scan.AutoScanSessionID,
-	)
-
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			http.Error(w, "Scan not found", http.StatusNotFound)
-		} else {
-			log.Printf("[ERROR] Failed to get scan status: %v", err)
-			http.Error(w, "Failed to get scan status", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	response := map[string]interface{}{
-		"id":                   scan.ID,
-		"scan_id":              scan.ScanID,
-		"domain":               scan.Domain,
-		"status":               scan.Status,
-		"result":               nullStringToString(scan.Result),
-		"error":                nullStringToString(scan.Error),
-		"stdout":               nullStringToString(scan.StdOut),
-		"stderr":               nullStringToString(scan.StdErr),
-		"command":              nullStringToString(scan.Command),
-		"execution_time":       nullStringToString(scan.ExecTime),
-		"created_at":           scan.CreatedAt.Format(time.RFC3339),
-		"scope_target_id":      scan.ScopeTargetID,
-		"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-// GetNucleiScreenshotScansForScopeTarget retrieves all Nuclei screenshot scans for a scope target
-func GetNucleiScreenshotScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	scopeTargetID := vars["id"]
-
-	query := `SELECT * FROM nuclei_screenshots WHERE scope_target_id = $1 ORDER BY created_at DESC`
-	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
-	if err != nil {
-		log.Printf("[ERROR] Failed to get scans: %v", err)
-		http.Error(w, "Failed to get scans", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	var scans []map[string]interface{}
-	for rows.Next() {
-		var scan NucleiScreenshotStatus
-		err := rows.Scan(
-			&scan.ID,
-			&scan.ScanID,
-			&scan.Domain,
-			&scan.Status,
-			&scan.Result,
-			&scan.Error,
-			&scan.StdOut,
-			&scan.StdErr,
-			&scan.Command,
-			&scan.ExecTime,
-			&scan.CreatedAt,
-			&scan.ScopeTargetID,
-			&scan.AutoScanSessionID,
-		)
-		if err != nil {
-			log.Printf("[ERROR] Failed to scan row: %v", err)
-			continue
-		}
-
-		scans = append(scans, map[string]interface{}{
-			"id":                   scan.ID,
-			"scan_id":              scan.ScanID,
-			"domain":               scan.Domain,
-			"status":               scan.Status,
-			"result":               nullStringToString(scan.Result),
-			"error":                nullStringToString(scan.Error),
-			"stdout":               nullStringToString(scan.StdOut),
-			"stderr":               nullStringToString(scan.StdErr),
-			"command":              nullStringToString(scan.Command),
-			"execution_time":       nullStringToString(scan.ExecTime),
-			"created_at":           scan.CreatedAt.Format(time.RFC3339),
-			"scope_target_id":      scan.ScopeTargetID,
-			"auto_scan_session_id": nullStringToString(scan.AutoScanSessionID),
-		})
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(scans)
-}
-
 // UpdateTargetURLFromScreenshot updates the screenshot for a target URL
 func UpdateTargetURLFromScreenshot(url, screenshot string) error {
 	log.Printf("[DEBUG] Updating screenshot for URL: %s", url)
@@ -0,0 +1,701 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Open ports that don't answer HTTP are fingerprinted natively: read the
+// greeting for protocols where the server speaks first (SSH, FTP, SMTP,
+// IMAP, POP3, MySQL), then try client-first probes (PostgreSQL, Redis,
+// MongoDB, Memcached, RDP, LDAP). The port's well-known service is tried
+// first so the common case costs a single connection.
+
+type DiscoveredService struct {
+	ID           string    `json:"id"`
+	ScanID       string    `json:"scan_id"`
+	IPAddress    string    `json:"ip_address"`
+	Port         int       `json:"port"`
+	Protocol     string    `json:"protocol"`
+	Service      string    `json:"service"`
+	Product      string    `json:"product,omitempty"`
+	Version      string    `json:"version,omitempty"`
+	Banner       string    `json:"banner,omitempty"`
+	TLS          bool      `json:"tls"`
+	DiscoveredAt time.Time `json:"discovered_at"`
+}
+
+const maxBannerLength = 512
+
+// serviceProbe is a client-first probe. run writes its request on conn and
+// returns nil if the reply doesn't look like the service.
+type serviceProbe struct {
+	service string
+	ports   []int
+	run     func(conn net.Conn) *DiscoveredService
+}
+
+var serviceProbes = []serviceProbe{
+	{"postgresql", []int{5432, 5433}, probePostgreSQL},
+	{"redis", []int{6379, 6380}, probeRedis},
+	{"mongodb", []int{27017, 27018, 27019}, probeMongoDB},
+	{"memcached", []int{11211}, probeMemcached},
+	{"rdp", []int{3389}, probeRDP},
+	{"ldap", []int{389, 636, 3268, 3269}, probeLDAP},
+}
+
+// Ports that speak TLS from the first byte.
+var implicitTLSPorts = map[int]bool{465: true, 636: true, 993: true, 995: true, 990: true, 3269: true}
+
+// fingerprintService identifies the service on an open TCP port. It always
+// returns a result; services it can't identify are reported as "unknown"
+// with whatever banner they sent.
+func fingerprintService(ctx context.Context, ip string, port int, timeout time.Duration) *DiscoveredService {
+	useTLS := implicitTLSPorts[port]
+	tried := make(map[string]bool)
+
+	// The port's well-known client-first service, if it has one.
+	for _, probe := range serviceProbes {
+		if containsPort(probe.ports, port) {
+			tried[probe.service] = true
+			if svc := runServiceProbe(ctx, ip, port, timeout, useTLS, probe); svc != nil {
+				return svc
+			}
+		}
+	}
+
+	// Server-first protocols announce themselves.
+	var greeting []byte
+	if conn, err := dialService(ctx, ip, port, timeout, useTLS); err == nil {
+		conn.SetDeadline(time.Now().Add(timeout))
+		greeting = readReply(conn, nil)
+		conn.Close()
+	}
+	if svc := matchGreeting(greeting, port); svc != nil {
+		return finishService(svc, ip, port, useTLS)
+	}
+
+	// A silent port may still be a client-first service on a non-standard port.
+	if len(greeting) == 0 {
+		for _, probe := range serviceProbes {
+			if tried[probe.service] || ctx.Err() != nil {
+				continue
+			}
+			if svc := runServiceProbe(ctx, ip, port, timeout, useTLS, probe); svc != nil {
+				return svc
+			}
+		}
+	}
+
+	return finishService(&DiscoveredService{Service: "unknown", Banner: printableBanner(greeting)}, ip, port, useTLS)
+}
+
+func runServiceProbe(ctx context.Context, ip string, port int, timeout time.Duration, useTLS bool, probe serviceProbe) *DiscoveredService {
+	conn, err := dialService(ctx, ip, port, timeout, useTLS)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	svc := probe.run(conn)
+	if svc == nil {
+		return nil
+	}
+	svc.Service = probe.service
+	return finishService(svc, ip, port, useTLS)
+}
+
+func dialService(ctx context.Context, ip string, port int, timeout time.Duration, useTLS bool) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+	if useTLS {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{InsecureSkipVerify: true}}
+		return tlsDialer.DialContext(ctx, "tcp", address)
+	}
+	return dialer.DialContext(ctx, "tcp", address)
+}
+
+func finishService(svc *DiscoveredService, ip string, port int, useTLS bool) *DiscoveredService {
+	svc.IPAddress = ip
+	svc.Port = port
+	svc.Protocol = "tcp"
+	svc.TLS = useTLS
+	svc.DiscoveredAt = time.Now()
+	if len(svc.Banner) > maxBannerLength {
+		svc.Banner = svc.Banner[:maxBannerLength]
+	}
+	return svc
+}
+
+// readReply reads until done reports a complete reply, the buffer fills or
+// the deadline passes. A nil done returns after the first read.
+func readReply(conn net.Conn, done func([]byte) bool) []byte {
+	buf := make([]byte, 0, 4096)
+	chunk := make([]byte, 4096)
+	for len(buf) < 64*1024 {
+		n, err := conn.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		if err != nil || done == nil || done(buf) {
+			break
+		}
+	}
+	return buf
+}
+
+func printableBanner(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		switch {
+		case c == '\r':
+		case c == '\n' || c == '\t' || (c >= 0x20 && c < 0x7f):
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte('.')
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+func containsPort(ports []int, port int) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+// Server-first greetings
+
+type productPattern struct {
+	re      *regexp.Regexp
+	product string
+}
+
+// Product patterns for text greetings. The first submatch, if any, is the version.
+var greetingProducts = []productPattern{
+	{regexp.MustCompile(`ProFTPD (\d[\w.]*)`), "ProFTPD"},
+	{regexp.MustCompile(`\(vsFTPd (\d[\w.]*)\)`), "vsftpd"},
+	{regexp.MustCompile(`Pure-FTPd`), "Pure-FTPd"},
+	{regexp.MustCompile(`FileZilla Server(?: version)? ?(\d[\w.]*)?`), "FileZilla Server"},
+	{regexp.MustCompile(`Microsoft FTP Service`), "Microsoft ftpd"},
+	{regexp.MustCompile(`Postfix`), "Postfix"},
+	{regexp.MustCompile(`Exim (\d[\w.]*)`), "Exim"},
+	{regexp.MustCompile(`Sendmail (\d[\w.]*)`), "Sendmail"},
+	{regexp.MustCompile(`Microsoft ESMTP MAIL Service(?:, Version: (\d[\w.]*))?`), "Microsoft Exchange"},
+	{regexp.MustCompile(`Dovecot`), "Dovecot"},
+	{regexp.MustCompile(`Courier-IMAP`), "Courier"},
+	{regexp.MustCompile(`Cyrus (?:IMAP|POP3)(?: v| )?(\d[\w.]*)?`), "Cyrus"},
+	{regexp.MustCompile(`Microsoft Exchange`), "Microsoft Exchange"},
+}
+
+func matchGreeting(greeting []byte, port int) *DiscoveredService {
+	if len(greeting) == 0 {
+		return nil
+	}
+	text := string(greeting)
+	banner := printableBanner(greeting)
+
+	switch {
+	case strings.HasPrefix(text, "SSH-"):
+		svc := &DiscoveredService{Service: "ssh", Banner: banner}
+		// SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.1
+		line := strings.TrimSpace(strings.SplitN(text, "\n", 2)[0])
+		if parts := strings.SplitN(line, "-", 3); len(parts) == 3 {
+			software := strings.Fields(parts[2])
+			if len(software) > 0 {
+				product, version, _ := strings.Cut(software[0], "_")
+				svc.Product, svc.Version = product, version
+			}
+		}
+		return svc
+
+	case strings.HasPrefix(text, "220"):
+		upper := strings.ToUpper(text)
+		service := "ftp"
+		if strings.Contains(upper, "SMTP") || strings.Contains(upper, "MAIL") ||
+			(!strings.Contains(upper, "FTP") && (port == 25 || port == 465 || port == 587 || port == 2525)) {
+			service = "smtp"
+		}
+		svc := &DiscoveredService{Service: service, Banner: banner}
+		matchGreetingProduct(svc, text)
+		return svc
+
+	case strings.HasPrefix(text, "* OK"):
+		svc := &DiscoveredService{Service: "imap", Banner: banner}
+		matchGreetingProduct(svc, text)
+		return svc
+
+	case strings.HasPrefix(text, "+OK"):
+		svc := &DiscoveredService{Service: "pop3", Banner: banner}
+		matchGreetingProduct(svc, text)
+		return svc
+	}
+
+	if svc := parseMySQLGreeting(greeting); svc != nil {
+		return svc
+	}
+	return nil
+}
+
+func matchGreetingProduct(svc *DiscoveredService, text string) {
+	for _, p := range greetingProducts {
+		if m := p.re.FindStringSubmatch(text); m != nil {
+			svc.Product = p.product
+			if len(m) > 1 {
+				svc.Version = m[1]
+			}
+			return
+		}
+	}
+}
+
+// parseMySQLGreeting recognises the initial handshake packet (protocol 10)
+// and the error packet a server sends to hosts it won't talk to.
+func parseMySQLGreeting(b []byte) *DiscoveredService {
+	if len(b) < 6 || b[3] != 0 {
+		return nil
+	}
+	length := int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+	if length == 0 || length > len(b)-4 {
+		return nil
+	}
+	payload := b[4 : 4+length]
+
+	switch payload[0] {
+	case 0x0a:
+		end := bytes.IndexByte(payload[1:], 0)
+		if end <= 0 {
+			return nil
+		}
+		version := string(payload[1 : 1+end])
+		if version[0] < '0' || version[0] > '9' {
+			return nil
+		}
+		svc := &DiscoveredService{Service: "mysql", Product: "MySQL", Version: version, Banner: version}
+		if strings.Contains(version, "MariaDB") {
+			svc.Product = "MariaDB"
+			svc.Version = strings.TrimPrefix(version, "5.5.5-")
+			svc.Version, _, _ = strings.Cut(svc.Version, "-MariaDB")
+		}
+		return svc
+	case 0xff:
+		if len(payload) < 3 {
+			return nil
+		}
+		message := printableBanner(payload[3:])
+		if !strings.Contains(message, "MySQL") && !strings.Contains(message, "MariaDB") && !strings.Contains(message, "is not allowed to connect") {
+			return nil
+		}
+		return &DiscoveredService{Service: "mysql", Banner: message}
+	}
+	return nil
+}
+
+// Client-first probes
+
+func probePostgreSQL(conn net.Conn) *DiscoveredService {
+	// SSLRequest: length 8, code 80877103. The server answers with one byte.
+	if _, err := conn.Write([]byte{0, 0, 0, 8, 0x04, 0xd2, 0x16, 0x2f}); err != nil {
+		return nil
+	}
+	reply := readReply(conn, nil)
+	if len(reply) != 1 || (reply[0] != 'S' && reply[0] != 'N') {
+		return nil
+	}
+	banner := "SSL not supported"
+	if reply[0] == 'S' {
+		banner = "SSL supported"
+	}
+	return &DiscoveredService{Product: "PostgreSQL", Banner: banner}
+}
+
+var redisVersionPattern = regexp.MustCompile(`redis_version:([\w.]+)`)
+
+func probeRedis(conn net.Conn) *DiscoveredService {
+	if _, err := conn.Write([]byte("PING\r\n")); err != nil {
+		return nil
+	}
+	reply := string(readReply(conn, nil))
+	switch {
+	case strings.HasPrefix(reply, "+PONG"):
+	case strings.HasPrefix(reply, "-NOAUTH"), strings.HasPrefix(reply, "-DENIED"), strings.HasPrefix(reply, "-ERR operation not permitted"):
+		return &DiscoveredService{Product: "Redis", Banner: printableBanner([]byte(reply))}
+	default:
+		return nil
+	}
+
+	// No authentication required: ask for the version.
+	svc := &DiscoveredService{Product: "Redis", Banner: "+PONG (no authentication)"}
+	if _, err := conn.Write([]byte("INFO server\r\n")); err == nil {
+		info := readReply(conn, func(b []byte) bool { return bytes.Contains(b, []byte("\r\n\r\n")) })
+		if m := redisVersionPattern.FindSubmatch(info); m != nil {
+			svc.Version = string(m[1])
+		}
+	}
+	return svc
+}
+
+func probeMemcached(conn net.Conn) *DiscoveredService {
+	if _, err := conn.Write([]byte("version\r\n")); err != nil {
+		return nil
+	}
+	reply := strings.TrimSpace(string(readReply(conn, nil)))
+	if !strings.HasPrefix(reply, "VERSION ") {
+		return nil
+	}
+	return &DiscoveredService{Product: "Memcached", Version: strings.TrimPrefix(reply, "VERSION "), Banner: reply}
+}
+
+// probeMongoDB sends {buildinfo: 1} as an OP_MSG, which MongoDB 3.6+ answers
+// without authentication.
+func probeMongoDB(conn net.Conn) *DiscoveredService {
+	doc := bsonDocument(
+		bsonInt32("buildinfo", 1),
+		bsonString("$db", "admin"),
+	)
+	msg := make([]byte, 16, 16+5+len(doc))
+	msg = append(msg, 0, 0, 0, 0) // flagBits
+	msg = append(msg, 0)          // section kind 0: body
+	msg = append(msg, doc...)
+	binary.LittleEndian.PutUint32(msg[0:], uint32(len(msg)))
+	binary.LittleEndian.PutUint32(msg[4:], 1)     // requestID
+	binary.LittleEndian.PutUint32(msg[12:], 2013) // OP_MSG
+
+	if _, err := conn.Write(msg); err != nil {
+		return nil
+	}
+	reply := readReply(conn, func(b []byte) bool {
+		return len(b) >= 4 && int(binary.LittleEndian.Uint32(b)) <= len(b)
+	})
+	if len(reply) < 21 || binary.LittleEndian.Uint32(reply[12:]) != 2013 || reply[20] != 0 {
+		return nil
+	}
+	svc := &DiscoveredService{Product: "MongoDB"}
+	if version, ok := bsonLookupString(reply[21:], "version"); ok {
+		svc.Version = version
+		svc.Banner = "buildinfo: " + version
+	}
+	return svc
+}
+
+func probeRDP(conn net.Conn) *DiscoveredService {
+	// TPKT + X.224 Connection Request with an RDP Negotiation Request.
+	request := []byte{
+		0x03, 0x00, 0x00, 0x13, 0x0e, 0xe0, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x08, 0x00, 0x03, 0x00, 0x00, 0x00,
+	}
+	if _, err := conn.Write(request); err != nil {
+		return nil
+	}
+	reply := readReply(conn, nil)
+	// TPKT version 3 carrying an X.224 Connection Confirm.
+	if len(reply) < 11 || reply[0] != 0x03 || reply[5] != 0xd0 {
+		return nil
+	}
+	svc := &DiscoveredService{Product: "Microsoft Terminal Services"}
+	if len(reply) >= 19 {
+		switch reply[11] {
+		case 0x02:
+			svc.Banner = "RDP negotiation response: protocol " + strconv.Itoa(int(reply[15]))
+		case 0x03:
+			svc.Banner = "RDP negotiation failure: code " + strconv.Itoa(int(reply[15]))
+		}
+	}
+	return svc
+}
+
+// probeLDAP reads vendorName and vendorVersion from the root DSE with an
+// anonymous base search.
+func probeLDAP(conn net.Conn) *DiscoveredService {
+	attributes := berTLV(0x30, append(berOctetString("vendorName"), berOctetString("vendorVersion")...))
+	search := berTLV(0x63, concatBytes(
+		berOctetString(""),                  // baseObject
+		[]byte{0x0a, 0x01, 0x00},            // scope: baseObject
+		[]byte{0x0a, 0x01, 0x00},            // derefAliases: never
+		[]byte{0x02, 0x01, 0x00},            // sizeLimit
+		[]byte{0x02, 0x01, 0x00},            // timeLimit
+		[]byte{0x01, 0x01, 0x00},            // typesOnly: false
+		berTLV(0x87, []byte("objectClass")), // filter: (objectClass=*)
+		attributes,
+	))
+	request := berTLV(0x30, append([]byte{0x02, 0x01, 0x01}, search...))
+
+	if _, err := conn.Write(request); err != nil {
+		return nil
+	}
+	reply := readReply(conn, ldapSearchDone)
+
+	op, ok := ldapProtocolOp(reply)
+	if !ok || (op != 0x64 && op != 0x65) {
+		return nil
+	}
+	svc := &DiscoveredService{}
+	svc.Product, _ = ldapAttributeValue(reply, "vendorName")
+	svc.Version, _ = ldapAttributeValue(reply, "vendorVersion")
+	svc.Banner = strings.TrimSpace(svc.Product + " " + svc.Version)
+	return svc
+}
+
+// ldapProtocolOp returns the protocolOp tag of an LDAPMessage:
+// SEQUENCE { messageID INTEGER, protocolOp }.
+func ldapProtocolOp(msg []byte) (byte, bool) {
+	body, _, ok := berContents(msg, 0x30)
+	if !ok || len(body) < 3 || body[0] != 0x02 || len(body) <= 2+int(body[1]) {
+		return 0, false
+	}
+	return body[2+int(body[1])], true
+}
+
+// ldapSearchDone reports whether b holds a complete SearchResultDone.
+func ldapSearchDone(b []byte) bool {
+	for len(b) > 0 {
+		_, size, ok := berContents(b, 0x30)
+		if !ok {
+			return false
+		}
+		if op, _ := ldapProtocolOp(b[:size]); op == 0x65 {
+			return true
+		}
+		b = b[size:]
+	}
+	return false
+}
+
+// Minimal BER helpers for the LDAP probe. Only definite lengths are handled.
+
+func berTLV(tag byte, value []byte) []byte {
+	out := []byte{tag}
+	switch n := len(value); {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n <= 0xff:
+		out = append(out, 0x81, byte(n))
+	default:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	}
+	return append(out, value...)
+}
+
+func berOctetString(s string) []byte {
+	return berTLV(0x04, []byte(s))
+}
+
+// berContents returns the value of the element at the start of b and the
+// element's total encoded size.
+func berContents(b []byte, tag byte) ([]byte, int, bool) {
+	if len(b) < 2 || b[0] != tag {
+		return nil, 0, false
+	}
+	length, offset := int(b[1]), 2
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 3 || len(b) < 2+n {
+			return nil, 0, false
+		}
+		length = 0
+		for _, c := range b[2 : 2+n] {
+			length = length<<8 | int(c)
+		}
+		offset += n
+	}
+	if len(b) < offset+length {
+		return nil, 0, false
+	}
+	return b[offset : offset+length], offset + length, true
+}
+
+// ldapAttributeValue finds a PartialAttribute by name and returns its first value.
+func ldapAttributeValue(b []byte, name string) (string, bool) {
+	idx := bytes.Index(b, append([]byte{0x04, byte(len(name))}, name...))
+	if idx < 0 {
+		return "", false
+	}
+	set, _, ok := berContents(b[idx+2+len(name):], 0x31)
+	if !ok {
+		return "", false
+	}
+	value, _, ok := berContents(set, 0x04)
+	if !ok {
+		return "", false
+	}
+	return string(value), true
+}
+
+func concatBytes(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// Minimal BSON helpers for the MongoDB probe.
+
+func bsonDocument(elements ...[]byte) []byte {
+	body := concatBytes(elements...)
+	doc := make([]byte, 4, 4+len(body)+1)
+	doc = append(doc, body...)
+	doc = append(doc, 0)
+	binary.LittleEndian.PutUint32(doc, uint32(len(doc)))
+	return doc
+}
+
+func bsonInt32(key string, v int32) []byte {
+	out := append([]byte{0x10}, key...)
+	out = append(out, 0)
+	return binary.LittleEndian.AppendUint32(out, uint32(v))
+}
+
+func bsonString(key, v string) []byte {
+	out := append([]byte{0x02}, key...)
+	out = append(out, 0)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(v)+1))
+	out = append(out, v...)
+	return append(out, 0)
+}
+
+// bsonLookupString returns a top-level string field of a BSON document.
+// Truncated or malformed documents yield false rather than a panic.
+func bsonLookupString(doc []byte, key string) (string, bool) {
+	if len(doc) < 5 {
+		return "", false
+	}
+	end := len(doc)
+	if n := binary.LittleEndian.Uint32(doc); n < uint32(end) {
+		end = int(n)
+	}
+	// lengthAt reads the int32 length prefix at i, or -1 if it is cut off.
+	lengthAt := func(i int) int {
+		if i+4 > end {
+			return -1
+		}
+		n := binary.LittleEndian.Uint32(doc[i:])
+		if n > uint32(end) {
+			return -1
+		}
+		return int(n)
+	}
+
+	i := 4
+	for i < end-1 {
+		elemType := doc[i]
+		nameEnd := bytes.IndexByte(doc[i+1:end], 0)
+		if nameEnd < 0 {
+			return "", false
+		}
+		name := string(doc[i+1 : i+1+nameEnd])
+		i += nameEnd + 2
+
+		var size int
+		switch elemType {
+		case 0x01, 0x09, 0x11, 0x12: // double, datetime, timestamp, int64
+			size = 8
+		case 0x02, 0x0d, 0x0e: // string, javascript, symbol
+			n := lengthAt(i)
+			if n < 1 || i+4+n > end || doc[i+4+n-1] != 0 {
+				return "", false
+			}
+			size = 4 + n
+			if elemType == 0x02 && name == key {
+				return string(doc[i+4 : i+size-1]), true
+			}
+		case 0x03, 0x04: // document, array
+			size = lengthAt(i)
+			if size < 5 {
+				return "", false
+			}
+		case 0x05: // binary
+			n := lengthAt(i)
+			if n < 0 {
+				return "", false
+			}
+			size = 5 + n
+		case 0x07: // ObjectId
+			size = 12
+		case 0x08: // bool
+			size = 1
+		case 0x0a, 0x06, 0x7f, 0xff: // null, undefined, max/min key
+			size = 0
+		case 0x10: // int32
+			size = 4
+		case 0x13: // decimal128
+			size = 16
+		default:
+			return "", false
+		}
+		if i+size > end {
+			return "", false
+		}
+		i += size
+	}
+	return "", false
+}
+
+// Persistence and API
+
+func insertDiscoveredService(scanID string, svc DiscoveredService) {
+	query := `INSERT INTO discovered_services (scan_id, ip_address, port, protocol, service, product, version, banner, tls)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			  ON CONFLICT (scan_id, ip_address, port) DO UPDATE SET
+			  service = EXCLUDED.service, product = EXCLUDED.product, version = EXCLUDED.version,
+			  banner = EXCLUDED.banner, tls = EXCLUDED.tls, discovered_at = NOW()`
+
+	_, err := dbPool.Exec(context.Background(), query,
+		scanID, svc.IPAddress, svc.Port, svc.Protocol, svc.Service, svc.Product, svc.Version, svc.Banner, svc.TLS)
+	if err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to insert discovered service: %v", err)
+	}
+}
+
+func GetDiscoveredServices(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	scanID := vars["scan_id"]
+
+	if scanID == "" {
+		http.Error(w, "Scan ID is required", http.StatusBadRequest)
+		return
+	}
+
+	query := `SELECT id, scan_id, ip_address, port, protocol, service, COALESCE(product, ''), COALESCE(version, ''),
+			  COALESCE(banner, ''), tls, discovered_at
+			  FROM discovered_services WHERE scan_id = $1 ORDER BY ip_address, port`
+
+	rows, err := dbPool.Query(context.Background(), query, scanID)
+	if err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to get discovered services: %v", err)
+		http.Error(w, "Failed to get discovered services", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	services := []DiscoveredService{}
+	for rows.Next() {
+		var svc DiscoveredService
+		var ipAddress net.IP
+		err := rows.Scan(&svc.ID, &svc.ScanID, &ipAddress, &svc.Port, &svc.Protocol, &svc.Service,
+			&svc.Product, &svc.Version, &svc.Banner, &svc.TLS, &svc.DiscoveredAt)
+		if err != nil {
+			log.Printf("[IP-PORT-SCAN] [ERROR] Error scanning discovered service row: %v", err)
+			continue
+		}
+		svc.IPAddress = ipAddress.String()
+		services = append(services, svc)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(services)
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestBsonLookupString(t *testing.T) {
+	nested := append([]byte{0x03}, "sub\x00"...)
+	nested = append(nested, bsonDocument(bsonString("version", "inner"))...)
+	valid := bsonDocument(bsonInt32("ok", 1), nested, bsonString("version", "7.0.2"))
+
+	withLength := func(doc []byte, off int, n uint32) []byte {
+		out := append([]byte(nil), doc...)
+		binary.LittleEndian.PutUint32(out[off:], n)
+		return out
+	}
+	// Offset of the "version" string length prefix in valid.
+	versionLen := len(valid) - 1 - len("7.0.2") - 1 - 4
+
+	tests := []struct {
+		name   string
+		doc    []byte
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{name: "top-level string", doc: valid, key: "version", want: "7.0.2", wantOK: true},
+		{name: "missing key", doc: valid, key: "gitVersion"},
+		{name: "non-string key", doc: valid, key: "ok"},
+		{name: "nil", doc: nil, key: "version"},
+		{name: "shorter than header", doc: []byte{5, 0, 0}, key: "version"},
+		{name: "empty document", doc: bsonDocument(), key: "version"},
+		{name: "declared size beyond buffer", doc: withLength(valid, 0, 1<<31), key: "version", want: "7.0.2", wantOK: true},
+		{name: "declared size cuts element", doc: withLength(valid, 0, uint32(versionLen+2)), key: "version"},
+		{name: "zero string length", doc: withLength(valid, versionLen, 0), key: "version"},
+		{name: "negative string length", doc: withLength(valid, versionLen, 0xffffffff), key: "version"},
+		{name: "string length past end", doc: withLength(valid, versionLen, 1000), key: "version"},
+		{name: "string missing NUL", doc: withLength(valid, versionLen, uint32(len("7.0.2"))), key: "version"},
+		{name: "nested size too small", doc: withLength(valid, 4+1+len("ok\x00")+4+1+len("sub\x00"), 1), key: "version"},
+		{name: "unknown element type", doc: bsonDocument([]byte{0x42, 'x', 0}), key: "version"},
+		{name: "unterminated name", doc: []byte{9, 0, 0, 0, 0x02, 'v', 'e', 'r', 's'}, key: "version"},
+		{name: "fixed size past end", doc: bsonDocument([]byte{0x01, 'd', 0, 1, 2}), key: "version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := bsonLookupString(tt.doc, tt.key)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("bsonLookupString() = %q, %v; want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestBsonLookupStringTruncated(t *testing.T) {
+	doc := bsonDocument(bsonInt32("ok", 1), bsonString("version", "7.0.2"))
+	for n := 0; n < len(doc); n++ {
+		// Only the document terminator may be missing for the field to be read.
+		if v, ok := bsonLookupString(doc[:n], "version"); ok && (v != "7.0.2" || n < len(doc)-1) {
+			t.Errorf("truncated to %d bytes: got %q", n, v)
+		}
+	}
+}
+
+func TestBerContents(t *testing.T) {
+	long := make([]byte, 300)
+
+	tests := []struct {
+		name     string
+		b        []byte
+		tag      byte
+		wantLen  int
+		wantSize int
+		wantOK   bool
+	}{
+		{name: "short form", b: berOctetString("abc"), tag: 0x04, wantLen: 3, wantSize: 5, wantOK: true},
+		{name: "one length byte", b: berTLV(0x04, long[:200]), tag: 0x04, wantLen: 200, wantSize: 203, wantOK: true},
+		{name: "two length bytes", b: berTLV(0x30, long), tag: 0x30, wantLen: 300, wantSize: 304, wantOK: true},
+		{name: "trailing data", b: append(berOctetString("abc"), 0x30, 0x00), tag: 0x04, wantLen: 3, wantSize: 5, wantOK: true},
+		{name: "empty value", b: []byte{0x04, 0x00}, tag: 0x04, wantSize: 2, wantOK: true},
+		{name: "wrong tag", b: berOctetString("abc"), tag: 0x30},
+		{name: "empty", b: nil, tag: 0x04},
+		{name: "tag only", b: []byte{0x04}, tag: 0x04},
+		{name: "indefinite length", b: []byte{0x30, 0x80, 0x00, 0x00}, tag: 0x30},
+		{name: "length of length too big", b: []byte{0x04, 0x84, 0, 0, 0, 1, 'a'}, tag: 0x04},
+		{name: "length bytes missing", b: []byte{0x04, 0x82, 0x01}, tag: 0x04},
+		{name: "value past end", b: berTLV(0x04, long)[:100], tag: 0x04},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, size, ok := berContents(tt.b, tt.tag)
+			if len(value) != tt.wantLen || size != tt.wantSize || ok != tt.wantOK {
+				t.Errorf("berContents() = %d bytes, size %d, %v; want %d bytes, size %d, %v",
+					len(value), size, ok, tt.wantLen, tt.wantSize, tt.wantOK)
+			}
+		})
+	}
+}
+
+// testLDAPRootDSE is a SearchResultEntry carrying attrs followed by a
+// successful SearchResultDone.
+func testLDAPRootDSE(attrs ...[2]string) []byte {
+	var list []byte
+	for _, attr := range attrs {
+		list = append(list, berTLV(0x30, concatBytes(berOctetString(attr[0]), berTLV(0x31, berOctetString(attr[1]))))...)
+	}
+	messageID := []byte{0x02, 0x01, 0x01}
+	entry := berTLV(0x30, concatBytes(messageID, berTLV(0x64, concatBytes(berOctetString(""), berTLV(0x30, list)))))
+	done := berTLV(0x30, concatBytes(messageID, berTLV(0x65, concatBytes([]byte{0x0a, 0x01, 0x00}, berOctetString(""), berOctetString("")))))
+	return append(entry, done...)
+}
+
+func TestLDAPRootDSE(t *testing.T) {
+	reply := testLDAPRootDSE([2]string{"vendorName", "OpenLDAP"}, [2]string{"vendorVersion", "2.6.7"})
+	// vendorName with an INTEGER where the SET of values belongs.
+	badSet := append(append([]byte(nil), reply...), berTLV(0x30, concatBytes(berOctetString("vendorName"), []byte{0x02, 0x01, 0x00}))...)
+	// Cut inside the value SET that follows the vendorName attribute type.
+	cut := bytes.Index(reply, []byte("vendorName")) + len("vendorName") + 3
+
+	if op, ok := ldapProtocolOp(reply); !ok || op != 0x64 {
+		t.Errorf("ldapProtocolOp() = %#x, %v; want 0x64", op, ok)
+	}
+	if !ldapSearchDone(reply) {
+		t.Error("ldapSearchDone(full reply) = false")
+	}
+
+	tests := []struct {
+		name   string
+		reply  []byte
+		attr   string
+		want   string
+		wantOK bool
+	}{
+		{name: "vendorName", reply: reply, attr: "vendorName", want: "OpenLDAP", wantOK: true},
+		{name: "vendorVersion", reply: reply, attr: "vendorVersion", want: "2.6.7", wantOK: true},
+		{name: "missing attribute", reply: reply, attr: "namingContexts"},
+		{name: "value set past end", reply: reply[:cut], attr: "vendorName"},
+		{name: "not a set", reply: badSet[len(reply):], attr: "vendorName"},
+		{name: "empty", reply: nil, attr: "vendorName"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ldapAttributeValue(tt.reply, tt.attr)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ldapAttributeValue() = %q, %v; want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLDAPReplyTruncated(t *testing.T) {
+	reply := testLDAPRootDSE([2]string{"vendorName", "OpenLDAP"})
+	for n := 0; n < len(reply); n++ {
+		if ldapSearchDone(reply[:n]) {
+			t.Errorf("ldapSearchDone(%d of %d bytes) = true", n, len(reply))
+		}
+		if op, ok := ldapProtocolOp(reply[:n]); ok && op != 0x64 {
+			t.Errorf("ldapProtocolOp(%d of %d bytes) = %#x", n, len(reply), op)
+		}
+	}
+	if _, ok := ldapProtocolOp([]byte{0x30, 0x03, 0x02, 0x05, 0x01}); ok {
+		t.Error("ldapProtocolOp() with a messageID past the sequence succeeded")
+	}
+}
@@ -134,14 +134,18 @@ func RunShodanCompanyScan(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("[SHODAN-COMPANY] [INFO] Successfully created Shodan Company scan record in database")
 
-	go ExecuteShodanCompanyScan(scanID, companyName)
+	if _, err := EnqueueScanJob("shodan-company", scanID, ScanJobPayload{Target: companyName}); err != nil {
+		log.Printf("[ERROR] Failed to queue shodan-company scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	log.Printf("[SHODAN-COMPANY] [INFO] Shodan Company scan initiated successfully, returning scan ID: %s", scanID)
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteShodanCompanyScan(scanID, companyName string) {
+func ExecuteShodanCompanyScan(ctx context.Context, scanID, companyName string) {
 	log.Printf("[SHODAN-COMPANY] [INFO] Starting Shodan Company scan execution for company %s (scan ID: %s)", companyName, scanID)
 	startTime := time.Now()
 
@@ -0,0 +1,495 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Takeover detection walks the CNAME chain of every FQDN asset and checks
+// the targets against takeover_fingerprints.json. A fingerprint matches on
+// the CNAME target (suffix, or a path.Match glob when it contains "*") and
+// then needs the target to be NXDOMAIN, or the site to serve one of its
+// body fingerprints. RECON_TAKEOVER_FINGERPRINTS points at a replacement
+// file.
+
+//go:embed data/takeover_fingerprints.json
+var defaultTakeoverFingerprints []byte
+
+const (
+	takeoverConcurrency = 20
+	takeoverHTTPTimeout = 10 * time.Second
+	takeoverMaxChain    = 10
+	takeoverBodyLimit   = 256 << 10
+	takeoverSnippetSize = 200
+)
+
+type TakeoverFingerprint struct {
+	Service      string   `json:"service"`
+	CNAME        []string `json:"cname"`
+	NXDomain     bool     `json:"nxdomain"`
+	Fingerprints []string `json:"fingerprints"`
+	HTTPStatus   int      `json:"http_status,omitempty"`
+	Vulnerable   bool     `json:"vulnerable"`
+	Reference    string   `json:"reference,omitempty"`
+}
+
+func (f TakeoverFingerprint) matchesCNAME(target string) bool {
+	for _, pattern := range f.CNAME {
+		pattern = strings.ToLower(pattern)
+		if strings.Contains(pattern, "*") {
+			if ok, _ := path.Match(pattern, target); ok {
+				return true
+			}
+			continue
+		}
+		if target == pattern || strings.HasSuffix(target, "."+pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+type TakeoverFinding struct {
+	ID         string    `json:"id"`
+	AssetID    *string   `json:"asset_id"`
+	FQDN       string    `json:"fqdn"`
+	Service    string    `json:"service"`
+	Status     string    `json:"status"`
+	Method     string    `json:"method"`
+	CNAMEChain []string  `json:"cname_chain"`
+	Evidence   string    `json:"evidence"`
+	HTTPStatus *int      `json:"http_status"`
+	Reference  *string   `json:"reference"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+var (
+	takeoverFingerprintsOnce sync.Once
+	takeoverFingerprints     []TakeoverFingerprint
+)
+
+// loadTakeoverFingerprints reads the fingerprint file once, falling back to
+// the embedded copy if RECON_TAKEOVER_FINGERPRINTS is unset or unreadable.
+func loadTakeoverFingerprints() []TakeoverFingerprint {
+	takeoverFingerprintsOnce.Do(func() {
+		data := defaultTakeoverFingerprints
+		if file := os.Getenv("RECON_TAKEOVER_FINGERPRINTS"); file != "" {
+			if custom, err := os.ReadFile(file); err != nil {
+				log.Printf("[TAKEOVER] [ERROR] Failed to read %s, using built-in fingerprints: %v", file, err)
+			} else {
+				data = custom
+			}
+		}
+		if err := json.Unmarshal(data, &takeoverFingerprints); err != nil {
+			log.Printf("[TAKEOVER] [ERROR] Failed to parse takeover fingerprints: %v", err)
+			json.Unmarshal(defaultTakeoverFingerprints, &takeoverFingerprints)
+		}
+		log.Printf("[TAKEOVER] [INFO] Loaded %d takeover fingerprints", len(takeoverFingerprints))
+	})
+	return takeoverFingerprints
+}
+
+func createTakeoverTable() {
+	_, err := dbPool.Exec(context.Background(), `
+		CREATE TABLE IF NOT EXISTS subdomain_takeover_findings (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			asset_id UUID,
+			fqdn TEXT NOT NULL,
+			service TEXT NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			method VARCHAR(20) NOT NULL,
+			cname_chain TEXT[],
+			evidence TEXT,
+			http_status INT,
+			reference TEXT,
+			detected_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scope_target_id, fqdn, service)
+		)`)
+	if err != nil {
+		log.Printf("[TAKEOVER] [ERROR] Failed to create subdomain_takeover_findings table: %v", err)
+	}
+}
+
+// cnameChain follows CNAMEs from name and returns the chain (without name)
+// and the rcode of an A query for the last target.
+func cnameChain(ctx context.Context, name string) ([]string, int, error) {
+	pool := defaultDNSPool()
+	var chain []string
+	current := name
+	seen := map[string]bool{name: true}
+	for len(chain) < takeoverMaxChain {
+		records, err := pool.Lookup(ctx, current, dnsTypeCNAME)
+		if err != nil {
+			return chain, 0, err
+		}
+		if len(records) == 0 {
+			break
+		}
+		target := strings.TrimSuffix(strings.ToLower(records[0].Value), ".")
+		if seen[target] {
+			break
+		}
+		seen[target] = true
+		chain = append(chain, target)
+		current = target
+	}
+	if len(chain) == 0 {
+		return nil, dnsRcodeSuccess, nil
+	}
+	resp, err := pool.Query(ctx, current, dnsTypeA)
+	if err != nil {
+		return chain, 0, err
+	}
+	return chain, resp.Rcode, nil
+}
+
+var takeoverHTTPClient = &http.Client{
+	Timeout: takeoverHTTPTimeout,
+	Transport: &http.Transport{
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		TLSHandshakeTimeout: takeoverHTTPTimeout,
+		DisableKeepAlives:   true,
+	},
+}
+
+// fetchTakeoverPage returns the status and body of the first of
+// https://host/ and http://host/ that answers.
+func fetchTakeoverPage(ctx context.Context, host string) (int, string, bool) {
+	for _, scheme := range []string{"https", "http"} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+host+"/", nil)
+		if err != nil {
+			return 0, "", false
+		}
+		resp, err := takeoverHTTPClient.Do(req)
+		if err != nil {
+			continue
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, takeoverBodyLimit))
+		resp.Body.Close()
+		return resp.StatusCode, string(body), true
+	}
+	return 0, "", false
+}
+
+// snippetAround returns the text around the first match of needle.
+func snippetAround(body, needle string) string {
+	i := strings.Index(body, needle)
+	if i < 0 {
+		return ""
+	}
+	start := max(0, i-takeoverSnippetSize/2)
+	end := min(len(body), i+len(needle)+takeoverSnippetSize/2)
+	return strings.TrimSpace(body[start:end])
+}
+
+// checkTakeover returns the findings for one FQDN. A fingerprint marked
+// vulnerable gives status "vulnerable", others "potential". A chain that
+// ends in NXDOMAIN without a matching fingerprint is a "potential"
+// dangling CNAME. It fails when the CNAME chain can't be resolved.
+func checkTakeover(ctx context.Context, fqdn string, fingerprints []TakeoverFingerprint) ([]TakeoverFinding, error) {
+	chain, rcode, err := cnameChain(ctx, fqdn)
+	if err != nil {
+		return nil, err
+	}
+	if len(chain) == 0 {
+		return nil, nil
+	}
+
+	var findings []TakeoverFinding
+	var page struct {
+		fetched, ok bool
+		status      int
+		body        string
+	}
+	for _, fp := range fingerprints {
+		matched := ""
+		for _, target := range chain {
+			if fp.matchesCNAME(target) {
+				matched = target
+				break
+			}
+		}
+		if matched == "" {
+			continue
+		}
+
+		status := "potential"
+		if fp.Vulnerable {
+			status = "vulnerable"
+		}
+		finding := TakeoverFinding{FQDN: fqdn, Service: fp.Service, Status: status, CNAMEChain: chain}
+		if fp.Reference != "" {
+			finding.Reference = &fp.Reference
+		}
+
+		if fp.NXDomain && rcode == dnsRcodeNXDomain {
+			finding.Method = "nxdomain"
+			finding.Evidence = fmt.Sprintf("%s -> %s returns NXDOMAIN", fqdn, strings.Join(chain, " -> "))
+			findings = append(findings, finding)
+			continue
+		}
+		if len(fp.Fingerprints) == 0 {
+			continue
+		}
+		if !page.fetched {
+			page.fetched = true
+			page.status, page.body, page.ok = fetchTakeoverPage(ctx, fqdn)
+		}
+		if !page.ok || (fp.HTTPStatus != 0 && page.status != fp.HTTPStatus) {
+			continue
+		}
+		for _, needle := range fp.Fingerprints {
+			if snippet := snippetAround(page.body, needle); snippet != "" {
+				httpStatus := page.status
+				finding.Method = "http_fingerprint"
+				finding.Evidence = snippet
+				finding.HTTPStatus = &httpStatus
+				findings = append(findings, finding)
+				break
+			}
+		}
+	}
+
+	if len(findings) == 0 && rcode == dnsRcodeNXDomain {
+		findings = append(findings, TakeoverFinding{
+			FQDN:       fqdn,
+			Service:    "Dangling CNAME",
+			Status:     "potential",
+			Method:     "nxdomain",
+			CNAMEChain: chain,
+			Evidence:   fmt.Sprintf("%s -> %s returns NXDOMAIN", fqdn, strings.Join(chain, " -> ")),
+		})
+	}
+	return findings, nil
+}
+
+var takeoverRuns sync.Map
+
+// RunTakeoverDetection checks every FQDN asset of a scope target, replaces
+// its stored findings and attaches them to the assets as
+// subdomain_takeover metadata. FQDNs whose check failed keep their previous
+// findings. Runs for the same scope target don't overlap.
+func RunTakeoverDetection(scopeTargetID string) error {
+	if _, running := takeoverRuns.LoadOrStore(scopeTargetID, true); running {
+		log.Printf("[TAKEOVER] [INFO] Detection already running for %s, skipping", scopeTargetID)
+		return nil
+	}
+	defer takeoverRuns.Delete(scopeTargetID)
+
+	createTakeoverTable()
+	startTime := time.Now()
+	fingerprints := loadTakeoverFingerprints()
+
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT DISTINCT ON (LOWER(fqdn)) id::text, LOWER(fqdn)
+		FROM consolidated_attack_surface_assets
+		WHERE scope_target_id = $1::uuid AND asset_type = 'fqdn' AND fqdn IS NOT NULL AND fqdn != ''
+		ORDER BY LOWER(fqdn), created_at DESC`, scopeTargetID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch FQDN assets: %v", err)
+	}
+	type fqdnAsset struct{ id, fqdn string }
+	var assets []fqdnAsset
+	for rows.Next() {
+		var a fqdnAsset
+		if err := rows.Scan(&a.id, &a.fqdn); err == nil {
+			assets = append(assets, a)
+		}
+	}
+	rows.Close()
+
+	ctx := context.Background()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var findings []TakeoverFinding
+	current := make([]string, 0, len(assets))
+	checked := make([]string, 0, len(assets))
+	semaphore := make(chan struct{}, takeoverConcurrency)
+	for _, asset := range assets {
+		current = append(current, asset.fqdn)
+		wg.Add(1)
+		go func(a fqdnAsset) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			found, err := checkTakeover(ctx, a.fqdn, fingerprints)
+			if err != nil {
+				log.Printf("[TAKEOVER] [WARN] Failed to check %s, keeping its previous findings: %v", a.fqdn, err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			checked = append(checked, a.fqdn)
+			for _, f := range found {
+				assetID := a.id
+				f.AssetID = &assetID
+				findings = append(findings, f)
+			}
+		}(asset)
+	}
+	wg.Wait()
+
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Replace the findings of FQDNs checked cleanly and drop those of FQDNs
+	// that are no longer assets.
+	_, err = tx.Exec(ctx, `
+		DELETE FROM subdomain_takeover_findings
+		WHERE scope_target_id = $1::uuid AND (LOWER(fqdn) = ANY($2) OR NOT LOWER(fqdn) = ANY($3))`,
+		scopeTargetID, checked, current)
+	if err != nil {
+		return fmt.Errorf("failed to clear takeover findings: %v", err)
+	}
+	_, err = tx.Exec(ctx, `
+		DELETE FROM consolidated_attack_surface_metadata m
+		USING consolidated_attack_surface_assets a
+		WHERE m.asset_id = a.id AND a.scope_target_id = $1::uuid AND m.metadata_type = 'subdomain_takeover'
+		  AND LOWER(a.fqdn) = ANY($2)`,
+		scopeTargetID, checked)
+	if err != nil {
+		return fmt.Errorf("failed to clear takeover metadata: %v", err)
+	}
+	for _, f := range findings {
+		evidence, _ := json.Marshal(map[string]interface{}{
+			"status":      f.Status,
+			"method":      f.Method,
+			"cname_chain": f.CNAMEChain,
+			"evidence":    f.Evidence,
+			"http_status": f.HTTPStatus,
+			"reference":   f.Reference,
+		})
+		_, err := tx.Exec(ctx, `
+			INSERT INTO subdomain_takeover_findings
+				(scope_target_id, asset_id, fqdn, service, status, method, cname_chain, evidence, http_status, reference)
+			VALUES ($1::uuid, $2::uuid, $3, $4, $5, $6, $7, $8, $9, $10)
+			ON CONFLICT (scope_target_id, fqdn, service) DO NOTHING`,
+			scopeTargetID, f.AssetID, f.FQDN, f.Service, f.Status, f.Method, f.CNAMEChain, f.Evidence, f.HTTPStatus, f.Reference)
+		if err != nil {
+			return fmt.Errorf("failed to insert takeover finding for %s: %v", f.FQDN, err)
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO consolidated_attack_surface_metadata (asset_id, metadata_type, metadata_key, metadata_value, metadata_json)
+			VALUES ($1::uuid, 'subdomain_takeover', $2, $3, $4)
+			ON CONFLICT (asset_id, metadata_type, metadata_key) DO UPDATE SET
+				metadata_value = EXCLUDED.metadata_value,
+				metadata_json = EXCLUDED.metadata_json`,
+			f.AssetID, f.Service, f.Status, evidence)
+		if err != nil {
+			return fmt.Errorf("failed to attach takeover finding for %s: %v", f.FQDN, err)
+		}
+	}
+	// Findings kept for FQDNs whose check failed follow the FQDN to its
+	// current asset, which consolidation may have recreated.
+	_, err = tx.Exec(ctx, `
+		WITH kept AS (
+			UPDATE subdomain_takeover_findings f SET asset_id = a.id
+			FROM (
+				SELECT DISTINCT ON (LOWER(fqdn)) id, LOWER(fqdn) AS fqdn
+				FROM consolidated_attack_surface_assets
+				WHERE scope_target_id = $1::uuid AND asset_type = 'fqdn' AND fqdn IS NOT NULL AND fqdn != ''
+				ORDER BY LOWER(fqdn), created_at DESC
+			) a
+			WHERE f.scope_target_id = $1::uuid AND LOWER(f.fqdn) = a.fqdn AND NOT LOWER(f.fqdn) = ANY($2)
+			RETURNING f.*
+		)
+		INSERT INTO consolidated_attack_surface_metadata (asset_id, metadata_type, metadata_key, metadata_value, metadata_json)
+		SELECT asset_id, 'subdomain_takeover', service, status, jsonb_build_object(
+			'status', status, 'method', method, 'cname_chain', cname_chain, 'evidence', evidence,
+			'http_status', http_status, 'reference', reference)
+		FROM kept
+		ON CONFLICT (asset_id, metadata_type, metadata_key) DO UPDATE SET
+			metadata_value = EXCLUDED.metadata_value,
+			metadata_json = EXCLUDED.metadata_json`,
+		scopeTargetID, checked)
+	if err != nil {
+		return fmt.Errorf("failed to carry over takeover findings: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit takeover findings: %v", err)
+	}
+
+	log.Printf("[TAKEOVER] [INFO] Checked %d of %d FQDNs for %s in %s: %d findings",
+		len(checked), len(assets), scopeTargetID, time.Since(startTime), len(findings))
+	return nil
+}
+
+// GetTakeoverFindings lists a scope target's takeover findings, vulnerable
+// first. ?status= limits to vulnerable or potential.
+func GetTakeoverFindings(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	if scopeTargetID == "" {
+		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status != "" && status != "vulnerable" && status != "potential" {
+		http.Error(w, "status must be vulnerable or potential", http.StatusBadRequest)
+		return
+	}
+
+	createTakeoverTable()
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT id::text, asset_id::text, fqdn, service, status, method, COALESCE(cname_chain, '{}'),
+		       COALESCE(evidence, ''), http_status, reference, detected_at
+		FROM subdomain_takeover_findings
+		WHERE scope_target_id = $1::uuid AND ($2 = '' OR status = $2)
+		ORDER BY status = 'vulnerable' DESC, fqdn, service`, scopeTargetID, status)
+	if err != nil {
+		log.Printf("[TAKEOVER] [ERROR] Failed to fetch findings: %v", err)
+		http.Error(w, "Failed to fetch takeover findings", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	findings := []TakeoverFinding{}
+	for rows.Next() {
+		var f TakeoverFinding
+		if err := rows.Scan(&f.ID, &f.AssetID, &f.FQDN, &f.Service, &f.Status, &f.Method, &f.CNAMEChain,
+			&f.Evidence, &f.HTTPStatus, &f.Reference, &f.DetectedAt); err != nil {
+			log.Printf("[TAKEOVER] [ERROR] Error scanning finding row: %v", err)
+			continue
+		}
+		findings = append(findings, f)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(findings)
+}
+
+// RunTakeoverDetectionHandler starts takeover detection for a scope target
+// in the background.
+func RunTakeoverDetectionHandler(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	if scopeTargetID == "" {
+		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
+		return
+	}
+
+	go func() {
+		if err := RunTakeoverDetection(scopeTargetID); err != nil {
+			log.Printf("[TAKEOVER] [ERROR] Detection failed for %s: %v", scopeTargetID, err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "started"})
+}
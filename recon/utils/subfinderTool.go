@@ -0,0 +1,17 @@
+package utils
+
+// subfinderTool finds subdomains of a wildcard target with subfinder's
+// passive sources.
+type subfinderTool struct{ toolBase }
+
+func init() {
+	RegisterTool(subfinderTool{toolBase{name: "subfinder", input: ToolInputDomain, table: "subfinder_scans"}})
+}
+
+func (subfinderTool) BuildArgs(input ToolInput) []string {
+	return []string{"subfinder", "-d", input.Target, "-silent"}
+}
+
+func (subfinderTool) ParseOutput(input ToolInput, stdout, stderr string) (ToolResult, error) {
+	return lineResult(uniqueLines(stdout)), nil
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// sublist3rTool finds subdomains of a wildcard target with Sublist3r.
+type sublist3rTool struct{ toolBase }
+
+var sublist3rANSIPattern = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+
+func init() {
+	RegisterTool(sublist3rTool{toolBase{name: "sublist3r", input: ToolInputDomain, table: "sublist3r_scans"}})
+}
+
+func (sublist3rTool) BuildArgs(input ToolInput) []string {
+	return []string{"python", "/app/sublist3r.py", "-d", input.Target, "-v", "-t", "50", "-o", "/dev/stdout"}
+}
+
+// ParseOutput strips Sublist3r's colours, banner and progress lines and keeps
+// the subdomains of the target. Sublist3r reports success even when it finds
+// nothing.
+func (sublist3rTool) ParseOutput(input ToolInput, stdout, stderr string) (ToolResult, error) {
+	var kept []string
+	for _, line := range strings.Split(stdout, "\n") {
+		line = strings.TrimSpace(sublist3rANSIPattern.ReplaceAllString(line, ""))
+		if line == "" ||
+			strings.Contains(line, "Sublist3r") ||
+			strings.Contains(line, "==") ||
+			strings.Contains(line, "Total Unique Subdomains Found:") ||
+			strings.HasPrefix(line, "[-]") ||
+			strings.HasPrefix(line, "[!]") ||
+			strings.HasPrefix(line, "[~]") ||
+			strings.HasPrefix(line, "[+]") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "SSL Certificates: ")
+		if strings.HasSuffix(line, input.Target) {
+			kept = append(kept, line)
+		}
+	}
+
+	subdomains := uniqueLines(strings.Join(kept, "\n"))
+	return ToolResult{Status: "success", Result: strings.Join(subdomains, "\n"), Items: subdomains}, nil
+}
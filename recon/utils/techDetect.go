@@ -0,0 +1,651 @@
+package utils
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Technologies are detected with Wappalyzer-format fingerprints. The
+// bundled data/technologies.json covers common servers, frameworks and
+// appliances; RECON_TECH_FINGERPRINTS points at a replacement file, or at a
+// directory laid out like Wappalyzer's src/ (categories.json plus
+// technologies/*.json), so the database can be updated without a rebuild.
+// Besides the Wappalyzer keys a fingerprint may list mmh3 favicon hashes
+// under "favicon".
+
+//go:embed data/technologies.json
+var defaultTechFingerprints []byte
+
+// techHTMLLimit caps how much of a page the html patterns run over
+const techHTMLLimit = 512 << 10
+
+type Technology struct {
+	Name       string   `json:"name"`
+	Version    string   `json:"version,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	Confidence int      `json:"confidence"`
+}
+
+// String is the name and version joined the way httpx reports them.
+func (t Technology) String() string {
+	if t.Version == "" {
+		return t.Name
+	}
+	return t.Name + ":" + t.Version
+}
+
+type techPattern struct {
+	re         *regexp.Regexp
+	version    string
+	confidence int
+}
+
+// jsCheck finds a JavaScript global by looking for assignments to it in
+// the page's inline scripts.
+type jsCheck struct {
+	path     string
+	needle   string
+	assign   *regexp.Regexp
+	declare  *regexp.Regexp
+	patterns []techPattern
+}
+
+type techFingerprint struct {
+	name      string
+	cats      []int
+	headers   map[string][]techPattern
+	cookies   map[string][]techPattern
+	meta      map[string][]techPattern
+	js        []jsCheck
+	scriptSrc []techPattern
+	scripts   []techPattern
+	html      []techPattern
+	url       []techPattern
+	favicons  []int32
+	implies   []string
+	excludes  []string
+}
+
+type techDatabase struct {
+	fingerprints []*techFingerprint
+	byName       map[string]*techFingerprint
+	categories   map[int]string
+}
+
+type rawTechFingerprint struct {
+	Cats      []int                      `json:"cats"`
+	Headers   map[string]json.RawMessage `json:"headers"`
+	Cookies   map[string]json.RawMessage `json:"cookies"`
+	Meta      map[string]json.RawMessage `json:"meta"`
+	JS        map[string]json.RawMessage `json:"js"`
+	ScriptSrc json.RawMessage            `json:"scriptSrc"`
+	Scripts   json.RawMessage            `json:"scripts"`
+	HTML      json.RawMessage            `json:"html"`
+	URL       json.RawMessage            `json:"url"`
+	Implies   json.RawMessage            `json:"implies"`
+	Excludes  json.RawMessage            `json:"excludes"`
+	Favicon   []int32                    `json:"favicon"`
+}
+
+type rawTechCategory struct {
+	Name string `json:"name"`
+}
+
+// techStrings reads a fingerprint value that is either a string or a list
+// of strings.
+func techStrings(raw json.RawMessage) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var one string
+	if err := json.Unmarshal(raw, &one); err == nil {
+		return []string{one}
+	}
+	var many []string
+	json.Unmarshal(raw, &many)
+	return many
+}
+
+// parseTechPattern splits a Wappalyzer pattern such as
+// `nginx(?:/([\d.]+))?\;version:\1` into its regex and tags.
+func parseTechPattern(s string) (techPattern, error) {
+	parts := strings.Split(s, `\;`)
+	p := techPattern{confidence: 100}
+	for _, tag := range parts[1:] {
+		key, value, _ := strings.Cut(tag, ":")
+		switch key {
+		case "version":
+			p.version = value
+		case "confidence":
+			if n, err := strconv.Atoi(value); err == nil {
+				p.confidence = n
+			}
+		}
+	}
+	re, err := regexp.Compile("(?i)" + parts[0])
+	if err != nil {
+		return p, err
+	}
+	p.re = re
+	return p, nil
+}
+
+var (
+	techVersionTernary = regexp.MustCompile(`^\\(\d+)\?([^:]*):(.*)$`)
+	techVersionRef     = regexp.MustCompile(`\\\d+`)
+)
+
+// match reports whether value matches and the version the pattern
+// extracts from it.
+func (p techPattern) match(value string) (bool, string) {
+	m := p.re.FindStringSubmatch(value)
+	if m == nil {
+		return false, ""
+	}
+	version := p.version
+	if version == "" {
+		return true, ""
+	}
+	if t := techVersionTernary.FindStringSubmatch(version); t != nil {
+		n, _ := strconv.Atoi(t[1])
+		if n < len(m) && m[n] != "" {
+			version = t[2]
+		} else {
+			version = t[3]
+		}
+	}
+	for i := len(m) - 1; i >= 1; i-- {
+		version = strings.ReplaceAll(version, `\`+strconv.Itoa(i), m[i])
+	}
+	return true, strings.TrimSpace(techVersionRef.ReplaceAllString(version, ""))
+}
+
+func newJSCheck(path string, patterns []techPattern) (jsCheck, error) {
+	segments := strings.Split(path, ".")
+	check := jsCheck{path: path, needle: segments[len(segments)-1], patterns: patterns}
+	var err error
+	check.assign, err = regexp.Compile(`(?:^|[^\w$.])(?:window\.|self\.|globalThis\.)?` + regexp.QuoteMeta(path) +
+		`\s*=\s*(?:"([^"]*)"|'([^']*)'|([\w$.]+)|[{\[(])`)
+	if err != nil {
+		return check, err
+	}
+	if len(segments) == 1 {
+		check.declare, err = regexp.Compile(`(?:\b(?:var|let|const|function|class)\s+|window\[["'])` + regexp.QuoteMeta(path) + `\b`)
+	}
+	return check, err
+}
+
+// find returns the value assigned to the global in scripts, empty when
+// it is an object or function, and whether the global was found.
+func (c jsCheck) find(scripts string) (string, bool) {
+	if !strings.Contains(scripts, c.needle) {
+		return "", false
+	}
+	if m := c.assign.FindStringSubmatch(scripts); m != nil {
+		return m[1] + m[2] + m[3], true
+	}
+	if c.declare != nil && c.declare.MatchString(scripts) {
+		return "", true
+	}
+	return "", false
+}
+
+func buildTechDatabase(categories map[string]rawTechCategory, techs map[string]rawTechFingerprint) *techDatabase {
+	db := &techDatabase{byName: make(map[string]*techFingerprint, len(techs)), categories: make(map[int]string, len(categories))}
+	for id, category := range categories {
+		if n, err := strconv.Atoi(id); err == nil {
+			db.categories[n] = category.Name
+		}
+	}
+
+	skipped := 0
+	patterns := func(values []string) []techPattern {
+		var out []techPattern
+		for _, value := range values {
+			p, err := parseTechPattern(value)
+			if err != nil {
+				// Lookarounds and other JavaScript-only syntax
+				skipped++
+				continue
+			}
+			out = append(out, p)
+		}
+		return out
+	}
+	patternMap := func(raw map[string]json.RawMessage) map[string][]techPattern {
+		out := make(map[string][]techPattern, len(raw))
+		for key, value := range raw {
+			if ps := patterns(techStrings(value)); len(ps) > 0 {
+				out[strings.ToLower(key)] = ps
+			}
+		}
+		return out
+	}
+
+	for name, raw := range techs {
+		fp := &techFingerprint{
+			name:      name,
+			cats:      raw.Cats,
+			headers:   patternMap(raw.Headers),
+			cookies:   patternMap(raw.Cookies),
+			meta:      patternMap(raw.Meta),
+			scriptSrc: patterns(techStrings(raw.ScriptSrc)),
+			scripts:   patterns(techStrings(raw.Scripts)),
+			html:      patterns(techStrings(raw.HTML)),
+			url:       patterns(techStrings(raw.URL)),
+			favicons:  raw.Favicon,
+			implies:   techStrings(raw.Implies),
+			excludes:  techStrings(raw.Excludes),
+		}
+		for path, value := range raw.JS {
+			ps := patterns(techStrings(value))
+			if len(ps) == 0 {
+				continue
+			}
+			check, err := newJSCheck(path, ps)
+			if err != nil {
+				skipped++
+				continue
+			}
+			fp.js = append(fp.js, check)
+		}
+		db.fingerprints = append(db.fingerprints, fp)
+		db.byName[strings.ToLower(name)] = fp
+	}
+	slices.SortFunc(db.fingerprints, func(a, b *techFingerprint) int { return strings.Compare(a.name, b.name) })
+	if skipped > 0 {
+		log.Printf("[TECH-DETECT] [WARN] Skipped %d fingerprint patterns that are not valid Go regular expressions", skipped)
+	}
+	return db
+}
+
+// parseTechFingerprints reads a single fingerprint file, either a bare
+// technologies map or an object with "technologies" (or the older "apps")
+// and "categories".
+func parseTechFingerprints(data []byte) (map[string]rawTechCategory, map[string]rawTechFingerprint, error) {
+	var file struct {
+		Categories   map[string]rawTechCategory    `json:"categories"`
+		Technologies map[string]rawTechFingerprint `json:"technologies"`
+		Apps         map[string]rawTechFingerprint `json:"apps"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, nil, err
+	}
+	if file.Technologies == nil {
+		file.Technologies = file.Apps
+	}
+	if file.Technologies == nil {
+		if err := json.Unmarshal(data, &file.Technologies); err != nil {
+			return nil, nil, err
+		}
+	}
+	return file.Categories, file.Technologies, nil
+}
+
+// readTechFingerprints loads the fingerprint file or directory at path.
+func readTechFingerprints(path string) (*techDatabase, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		categories, techs, err := parseTechFingerprints(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+		return buildTechDatabase(categories, techs), nil
+	}
+
+	categories := make(map[string]rawTechCategory)
+	if data, err := os.ReadFile(filepath.Join(path, "categories.json")); err == nil {
+		if err := json.Unmarshal(data, &categories); err != nil {
+			return nil, fmt.Errorf("failed to parse categories.json: %v", err)
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(path, "technologies", "*.json"))
+	flat, _ := filepath.Glob(filepath.Join(path, "*.json"))
+	techs := make(map[string]rawTechFingerprint)
+	for _, file := range append(files, flat...) {
+		if filepath.Base(file) == "categories.json" {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fileCategories, fileTechs, err := parseTechFingerprints(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", file, err)
+		}
+		for id, category := range fileCategories {
+			categories[id] = category
+		}
+		for name, tech := range fileTechs {
+			techs[name] = tech
+		}
+	}
+	if len(techs) == 0 {
+		return nil, fmt.Errorf("no fingerprints found in %s", path)
+	}
+	return buildTechDatabase(categories, techs), nil
+}
+
+var (
+	techDBOnce sync.Once
+	techDB     *techDatabase
+)
+
+func loadTechDatabase() *techDatabase {
+	techDBOnce.Do(func() {
+		if path := os.Getenv("RECON_TECH_FINGERPRINTS"); path != "" {
+			db, err := readTechFingerprints(path)
+			if err == nil {
+				techDB = db
+				log.Printf("[TECH-DETECT] [INFO] Loaded %d technology fingerprints from %s", len(db.fingerprints), path)
+				return
+			}
+			log.Printf("[TECH-DETECT] [ERROR] Failed to load %s, using built-in fingerprints: %v", path, err)
+		}
+		categories, techs, err := parseTechFingerprints(defaultTechFingerprints)
+		if err != nil {
+			log.Printf("[TECH-DETECT] [ERROR] Failed to parse built-in fingerprints: %v", err)
+		}
+		techDB = buildTechDatabase(categories, techs)
+	})
+	return techDB
+}
+
+var (
+	metaTagPattern   = regexp.MustCompile(`(?is)<meta\b[^>]*>`)
+	scriptTagPattern = regexp.MustCompile(`(?is)<script\b([^>]*)>(.*?)</script>`)
+	htmlAttrPattern  = regexp.MustCompile(`(?i)([\w:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+)
+
+func htmlAttrs(tag string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range htmlAttrPattern.FindAllStringSubmatch(tag, -1) {
+		attrs[strings.ToLower(m[1])] = m[2] + m[3] + m[4]
+	}
+	return attrs
+}
+
+// techPage is what the fingerprints are matched against.
+type techPage struct {
+	url       string
+	headers   http.Header
+	cookies   map[string]string
+	html      string
+	meta      map[string][]string
+	scriptSrc []string
+	scripts   string
+	favicons  []FaviconHash
+}
+
+func newTechPage(pageURL string, header http.Header, body []byte, favicons []FaviconHash) *techPage {
+	if len(body) > techHTMLLimit {
+		body = body[:techHTMLLimit]
+	}
+	page := &techPage{
+		url:      pageURL,
+		headers:  header,
+		cookies:  make(map[string]string),
+		html:     string(body),
+		meta:     make(map[string][]string),
+		favicons: favicons,
+	}
+	for _, cookie := range (&http.Response{Header: header}).Cookies() {
+		page.cookies[strings.ToLower(cookie.Name)] = cookie.Value
+	}
+	for _, tag := range metaTagPattern.FindAllString(page.html, -1) {
+		attrs := htmlAttrs(tag)
+		for _, key := range []string{"name", "property", "http-equiv"} {
+			if name := strings.ToLower(attrs[key]); name != "" {
+				page.meta[name] = append(page.meta[name], attrs["content"])
+			}
+		}
+	}
+	var scripts []string
+	for _, m := range scriptTagPattern.FindAllStringSubmatch(page.html, -1) {
+		if src := htmlAttrs(m[1])["src"]; src != "" {
+			page.scriptSrc = append(page.scriptSrc, src)
+		}
+		if content := strings.TrimSpace(m[2]); content != "" {
+			scripts = append(scripts, content)
+		}
+	}
+	page.scripts = strings.Join(scripts, "\n")
+	return page
+}
+
+// DetectTechnologies matches a response against the fingerprint database:
+// headers, cookies, meta tags, script sources, inline scripts, the HTML,
+// the URL, JavaScript globals assigned in inline scripts and favicon
+// hashes. Implied technologies are added and excluded ones dropped.
+func DetectTechnologies(pageURL string, header http.Header, body []byte, favicons []FaviconHash) []Technology {
+	db := loadTechDatabase()
+	page := newTechPage(pageURL, header, body, favicons)
+
+	type hit struct {
+		version    string
+		confidence int
+	}
+	hits := make(map[string]*hit)
+	record := func(name, version string, confidence int) {
+		h := hits[name]
+		if h == nil {
+			h = &hit{}
+			hits[name] = h
+		}
+		h.confidence += confidence
+		if len(version) > len(h.version) {
+			h.version = version
+		}
+	}
+	try := func(fp *techFingerprint, patterns []techPattern, values ...string) {
+		for _, p := range patterns {
+			for _, value := range values {
+				if ok, version := p.match(value); ok {
+					record(fp.name, version, p.confidence)
+					break
+				}
+			}
+		}
+	}
+
+	for _, fp := range db.fingerprints {
+		for name, patterns := range fp.headers {
+			if values := page.headers.Values(name); len(values) > 0 {
+				try(fp, patterns, strings.Join(values, ", "))
+			}
+		}
+		for name, patterns := range fp.cookies {
+			if value, ok := page.cookies[name]; ok {
+				try(fp, patterns, value)
+			}
+		}
+		for name, patterns := range fp.meta {
+			try(fp, patterns, page.meta[name]...)
+		}
+		try(fp, fp.scriptSrc, page.scriptSrc...)
+		if page.scripts != "" {
+			try(fp, fp.scripts, page.scripts)
+			for _, check := range fp.js {
+				if value, ok := check.find(page.scripts); ok {
+					try(fp, check.patterns, value)
+				}
+			}
+		}
+		if page.html != "" {
+			try(fp, fp.html, page.html)
+		}
+		try(fp, fp.url, page.url)
+		for _, favicon := range page.favicons {
+			if slices.Contains(fp.favicons, favicon.MMH3) {
+				record(fp.name, "", 100)
+			}
+		}
+	}
+	for _, favicon := range page.favicons {
+		if product := faviconProduct(favicon.MMH3); product != "" && hits[product] == nil {
+			if fp := db.byName[strings.ToLower(product)]; fp != nil {
+				product = fp.name
+			}
+			record(product, "", 100)
+		}
+	}
+
+	// Add implied technologies until nothing new turns up
+	queue := make([]string, 0, len(hits))
+	for name := range hits {
+		queue = append(queue, name)
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		fp := db.byName[strings.ToLower(name)]
+		if fp == nil {
+			continue
+		}
+		for _, implied := range fp.implies {
+			p, err := parseTechPattern(implied)
+			impliedName := strings.Split(implied, `\;`)[0]
+			if target := db.byName[strings.ToLower(impliedName)]; target != nil {
+				impliedName = target.name
+			}
+			if err != nil || hits[impliedName] != nil {
+				continue
+			}
+			record(impliedName, "", min(p.confidence, hits[name].confidence))
+			queue = append(queue, impliedName)
+		}
+	}
+	for name := range hits {
+		if fp := db.byName[strings.ToLower(name)]; fp != nil {
+			for _, excluded := range fp.excludes {
+				delete(hits, excluded)
+			}
+		}
+	}
+
+	technologies := make([]Technology, 0, len(hits))
+	for name, h := range hits {
+		tech := Technology{Name: name, Version: h.version, Confidence: min(h.confidence, 100)}
+		if fp := db.byName[strings.ToLower(name)]; fp != nil {
+			tech.Categories = db.categoryNames(fp.cats)
+		}
+		technologies = append(technologies, tech)
+	}
+	slices.SortFunc(technologies, func(a, b Technology) int { return strings.Compare(a.Name, b.Name) })
+	return technologies
+}
+
+func (db *techDatabase) categoryNames(ids []int) []string {
+	var names []string
+	for _, id := range ids {
+		if name, ok := db.categories[id]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// technologyNames flattens detected technologies into the name:version
+// strings kept in the technologies columns.
+func technologyNames(technologies []Technology) []string {
+	names := make([]string, 0, len(technologies))
+	for _, tech := range technologies {
+		names = append(names, tech.String())
+	}
+	return names
+}
+
+// technologiesFromNames turns name:version strings reported by httpx into
+// technologies, taking canonical names and categories from the database.
+func technologiesFromNames(names []string) []Technology {
+	db := loadTechDatabase()
+	technologies := make([]Technology, 0, len(names))
+	for _, entry := range names {
+		name, version, _ := strings.Cut(entry, ":")
+		tech := Technology{Name: strings.TrimSpace(name), Version: strings.TrimSpace(version), Confidence: 100}
+		if fp := db.byName[strings.ToLower(tech.Name)]; fp != nil {
+			tech.Name = fp.name
+			tech.Categories = db.categoryNames(fp.cats)
+		}
+		technologies = append(technologies, tech)
+	}
+	return technologies
+}
+
+// attachTechnologyDetails copies technology details from target URLs and
+// live web servers onto the consolidated live web server assets, falling
+// back to the technologies columns for rows probed before detection
+// recorded details.
+func attachTechnologyDetails(scopeTargetID string) error {
+	result, err := dbPool.Exec(context.Background(), `
+		UPDATE consolidated_attack_surface_assets a SET technology_details = t.technology_details
+		FROM (
+			SELECT DISTINCT ON (url) url, technology_details
+			FROM (
+				SELECT url, technology_details, 0 AS priority
+				FROM target_urls
+				WHERE scope_target_id::text = $1 AND technology_details IS NOT NULL
+				UNION ALL
+				SELECT lws.url, lws.technology_details, 1
+				FROM live_web_servers lws
+				JOIN ip_port_scans ips ON ips.scan_id = lws.scan_id
+				WHERE ips.scope_target_id::text = $1 AND lws.technology_details IS NOT NULL
+			) details
+			ORDER BY url, priority
+		) t
+		WHERE a.scope_target_id::text = $1 AND a.asset_type = 'live_web_server' AND a.url = t.url`, scopeTargetID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT id, technologies FROM consolidated_attack_surface_assets
+		WHERE scope_target_id::text = $1 AND technology_details IS NULL AND cardinality(technologies) > 0`, scopeTargetID)
+	if err != nil {
+		return err
+	}
+	type pending struct {
+		id      string
+		details []byte
+	}
+	var updates []pending
+	for rows.Next() {
+		var id string
+		var technologies []string
+		if err := rows.Scan(&id, &technologies); err != nil {
+			rows.Close()
+			return err
+		}
+		details, _ := json.Marshal(technologiesFromNames(technologies))
+		updates = append(updates, pending{id, details})
+	}
+	rows.Close()
+	for _, update := range updates {
+		if _, err := dbPool.Exec(context.Background(),
+			`UPDATE consolidated_attack_surface_assets SET technology_details = $1::jsonb WHERE id = $2`,
+			update.details, update.id); err != nil {
+			return err
+		}
+	}
+	log.Printf("[TECH-DETECT] [INFO] Attached technology details to %d assets", int(result.RowsAffected())+len(updates))
+	return nil
+}
@@ -0,0 +1,388 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// tlsFingerprintTool computes JARM, JA4S and JA4X for every TLS endpoint of
+// a scope target: https live web servers found by IP/Port scans and live
+// https target URLs. A URL list, if given, limits the scan to those URLs.
+type tlsFingerprintTool struct{ executorTool }
+
+func init() {
+	RegisterTool(tlsFingerprintTool{executorTool{
+		toolBase: toolBase{name: "tls-fingerprint", input: ToolInputURLList, table: "tls_fingerprint_scans"},
+		execute:  ExecuteTLSFingerprintScan,
+	}})
+}
+
+func (tlsFingerprintTool) Schema() string {
+	return scanTableSchema("tls_fingerprint_scans", "url")
+}
+
+const (
+	tlsFingerprintConcurrency = 10
+	tlsFingerprintTimeout     = 5 * time.Second
+)
+
+type tlsEndpoint struct {
+	URL       string
+	Host      string
+	IPAddress string
+	Port      int
+	Source    string
+}
+
+type TLSFingerprintAsset struct {
+	URL             string    `json:"url"`
+	Host            string    `json:"host"`
+	IPAddress       string    `json:"ip_address,omitempty"`
+	Port            int       `json:"port"`
+	Source          string    `json:"source"`
+	JARM            string    `json:"jarm,omitempty"`
+	JA4S            string    `json:"ja4s,omitempty"`
+	JA4X            string    `json:"ja4x,omitempty"`
+	TLSVersion      string    `json:"tls_version,omitempty"`
+	CipherSuite     string    `json:"cipher_suite,omitempty"`
+	CertSubject     string    `json:"cert_subject,omitempty"`
+	CertIssuer      string    `json:"cert_issuer,omitempty"`
+	FingerprintedAt time.Time `json:"fingerprinted_at"`
+}
+
+type TLSFingerprintGroup struct {
+	FingerprintType string                `json:"fingerprint_type"`
+	Fingerprint     string                `json:"fingerprint"`
+	Count           int                   `json:"count"`
+	Assets          []TLSFingerprintAsset `json:"assets"`
+}
+
+func createTLSFingerprintTable() {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS tls_fingerprints (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scan_id UUID REFERENCES tls_fingerprint_scans(scan_id) ON DELETE CASCADE,
+			scope_target_id UUID REFERENCES scope_targets(id) ON DELETE CASCADE,
+			source VARCHAR(30) NOT NULL,
+			url TEXT NOT NULL,
+			host TEXT NOT NULL,
+			ip_address TEXT,
+			port INT NOT NULL,
+			jarm TEXT,
+			ja4s TEXT,
+			ja4x TEXT,
+			tls_version TEXT,
+			cipher_suite TEXT,
+			alpn TEXT,
+			cert_subject TEXT,
+			cert_issuer TEXT,
+			created_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scan_id, url)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_tls_fingerprints_scope_target_id ON tls_fingerprints(scope_target_id);`,
+	}
+	for _, query := range queries {
+		if _, err := dbPool.Exec(context.Background(), query); err != nil {
+			log.Printf("[TLS-FINGERPRINT] [ERROR] Failed to create table/index: %v", err)
+		}
+	}
+}
+
+func ExecuteTLSFingerprintScan(ctx context.Context, scanID string, input ToolInput) {
+	t, _ := LookupTool("tls-fingerprint")
+	log.Printf("[TLS-FINGERPRINT] [INFO] Starting TLS fingerprint scan %s for scope target %s", scanID, input.ScopeTargetID)
+	startTime := time.Now()
+	createTLSFingerprintTable()
+	UpdateToolScanStatus(t, scanID, "running", "", "", "", "")
+
+	var endpoints []tlsEndpoint
+	var err error
+	if len(input.URLs) > 0 {
+		endpoints = tlsEndpointsFromURLs(input.URLs, "url_list")
+	} else {
+		endpoints, err = loadTLSEndpoints(input.ScopeTargetID)
+		if err != nil {
+			UpdateToolScanStatus(t, scanID, "error", "", fmt.Sprintf("failed to load TLS endpoints: %v", err), "", time.Since(startTime).String())
+			return
+		}
+	}
+	command := fmt.Sprintf("native JARM/JA4S/JA4X fingerprinting of %d endpoints", len(endpoints))
+	log.Printf("[TLS-FINGERPRINT] [INFO] Fingerprinting %d TLS endpoints", len(endpoints))
+
+	var lines []string
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, tlsFingerprintConcurrency)
+
+	for _, endpoint := range endpoints {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(ep tlsEndpoint) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			if ctx.Err() != nil {
+				return
+			}
+
+			fp, err := fingerprintTLS(ctx, ep.Host, ep.Port, tlsFingerprintTimeout)
+			if err != nil {
+				log.Printf("[TLS-FINGERPRINT] [DEBUG] Skipping %s: %v", ep.URL, err)
+				return
+			}
+			insertTLSFingerprint(scanID, input.ScopeTargetID, ep, fp)
+
+			mu.Lock()
+			lines = append(lines, fmt.Sprintf("%s jarm=%s ja4s=%s ja4x=%s", ep.URL, fp.JARM, fp.JA4S, fp.JA4X))
+			found := len(lines)
+			mu.Unlock()
+			PublishScanCounts(scanID, map[string]int{"fingerprinted": found, "total": len(endpoints)})
+		}(endpoint)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		log.Printf("[TLS-FINGERPRINT] [INFO] Scan %s cancelled", scanID)
+		return
+	}
+
+	if err := attachTLSFingerprintMetadata(input.ScopeTargetID); err != nil {
+		log.Printf("[TLS-FINGERPRINT] [ERROR] Failed to attach fingerprints to assets: %v", err)
+	}
+
+	sort.Strings(lines)
+	result := lineResult(lines)
+	stderr := ""
+	if result.Result == "" {
+		stderr = "No TLS endpoints could be fingerprinted"
+	}
+	UpdateToolScanStatus(t, scanID, result.Status, result.Result, stderr, command, time.Since(startTime).String())
+	log.Printf("[TLS-FINGERPRINT] [INFO] Fingerprinted %d/%d endpoints in %s", len(lines), len(endpoints), time.Since(startTime))
+}
+
+// loadTLSEndpoints returns the https live web servers and live https target
+// URLs of a scope target.
+func loadTLSEndpoints(scopeTargetID string) ([]tlsEndpoint, error) {
+	var endpoints []tlsEndpoint
+	seen := make(map[string]bool)
+
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT DISTINCT lws.url, host(lws.ip_address), lws.port
+		FROM live_web_servers lws
+		JOIN ip_port_scans ips ON lws.scan_id = ips.scan_id
+		WHERE ips.scope_target_id = $1 AND lws.protocol = 'https'`, scopeTargetID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var ep tlsEndpoint
+		if err := rows.Scan(&ep.URL, &ep.IPAddress, &ep.Port); err != nil {
+			continue
+		}
+		ep.Host = ep.IPAddress
+		ep.Source = "live_web_server"
+		if !seen[ep.URL] {
+			seen[ep.URL] = true
+			endpoints = append(endpoints, ep)
+		}
+	}
+	rows.Close()
+
+	rows, err = dbPool.Query(context.Background(), `
+		SELECT url, COALESCE(ip_address, '')
+		FROM target_urls
+		WHERE scope_target_id = $1 AND url LIKE 'https://%' AND no_longer_live = false`, scopeTargetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rawURL, ipAddress string
+		if err := rows.Scan(&rawURL, &ipAddress); err != nil {
+			continue
+		}
+		for _, ep := range tlsEndpointsFromURLs([]string{rawURL}, "target_url") {
+			ep.IPAddress = ipAddress
+			if !seen[ep.URL] {
+				seen[ep.URL] = true
+				endpoints = append(endpoints, ep)
+			}
+		}
+	}
+	return endpoints, nil
+}
+
+func tlsEndpointsFromURLs(urls []string, source string) []tlsEndpoint {
+	var endpoints []tlsEndpoint
+	for _, raw := range urls {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		if !strings.Contains(raw, "://") {
+			raw = "https://" + raw
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+			continue
+		}
+		port := 443
+		if p := u.Port(); p != "" {
+			if n, err := strconv.Atoi(p); err == nil {
+				port = n
+			}
+		}
+		ep := tlsEndpoint{URL: raw, Host: u.Hostname(), Port: port, Source: source}
+		if ip := net.ParseIP(ep.Host); ip != nil {
+			ep.IPAddress = ip.String()
+		}
+		endpoints = append(endpoints, ep)
+	}
+	return endpoints
+}
+
+func insertTLSFingerprint(scanID, scopeTargetID string, ep tlsEndpoint, fp *TLSFingerprint) {
+	query := `INSERT INTO tls_fingerprints (scan_id, scope_target_id, source, url, host, ip_address, port,
+			  jarm, ja4s, ja4x, tls_version, cipher_suite, alpn, cert_subject, cert_issuer)
+			  VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10, $11, $12, $13, $14, $15)
+			  ON CONFLICT (scan_id, url) DO NOTHING`
+	_, err := dbPool.Exec(context.Background(), query, scanID, scopeTargetID, ep.Source, ep.URL, ep.Host, ep.IPAddress, ep.Port,
+		fp.JARM, fp.JA4S, fp.JA4X, fp.TLSVersion, fp.CipherSuite, fp.ALPN, fp.CertSubject, fp.CertIssuer)
+	if err != nil {
+		log.Printf("[TLS-FINGERPRINT] [ERROR] Failed to store fingerprint for %s: %v", ep.URL, err)
+	}
+}
+
+// attachTLSFingerprintMetadata copies the latest fingerprint of each
+// endpoint onto the matching live web server assets.
+func attachTLSFingerprintMetadata(scopeTargetID string) error {
+	createTLSFingerprintTable()
+	query := `
+		INSERT INTO consolidated_attack_surface_metadata (asset_id, metadata_type, metadata_key, metadata_value)
+		SELECT DISTINCT ON (a.id, m.key) a.id, 'tls_fingerprint', m.key, m.value
+		FROM (
+			SELECT DISTINCT ON (url) url, ip_address, port, jarm, ja4s, ja4x, created_at
+			FROM tls_fingerprints
+			WHERE scope_target_id = $1::uuid
+			ORDER BY url, created_at DESC
+		) f
+		JOIN consolidated_attack_surface_assets a
+			ON a.scope_target_id = $1::uuid
+			AND a.asset_type = 'live_web_server'
+			AND (a.url = f.url OR (f.ip_address IS NOT NULL AND a.ip_address = f.ip_address AND a.port = f.port AND a.protocol = 'https'))
+		CROSS JOIN LATERAL (VALUES ('jarm', f.jarm), ('ja4s', f.ja4s), ('ja4x', f.ja4x)) AS m(key, value)
+		WHERE m.value IS NOT NULL AND m.value <> ''
+		ORDER BY a.id, m.key, f.created_at DESC
+		ON CONFLICT (asset_id, metadata_type, metadata_key) DO UPDATE SET
+			metadata_value = EXCLUDED.metadata_value
+	`
+	result, err := dbPool.Exec(context.Background(), query, scopeTargetID)
+	if err != nil {
+		return err
+	}
+	log.Printf("[TLS-FINGERPRINT] [INFO] Attached %d fingerprint values to assets", result.RowsAffected())
+	return nil
+}
+
+// GetTLSFingerprintGroups groups a scope target's TLS endpoints by shared
+// JARM, JA4S or JA4X. ?type= limits the fingerprint kind and ?min_count=
+// hides fingerprints seen on fewer endpoints.
+func GetTLSFingerprintGroups(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	if scopeTargetID == "" {
+		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
+		return
+	}
+
+	types := []string{"jarm", "ja4s", "ja4x"}
+	if t := strings.ToLower(r.URL.Query().Get("type")); t != "" {
+		if t != "jarm" && t != "ja4s" && t != "ja4x" {
+			http.Error(w, "type must be jarm, ja4s or ja4x", http.StatusBadRequest)
+			return
+		}
+		types = []string{t}
+	}
+	minCount := 1
+	if v := r.URL.Query().Get("min_count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "min_count must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		minCount = n
+	}
+
+	createTLSFingerprintTable()
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT DISTINCT ON (url) url, host, COALESCE(ip_address, ''), port, source,
+		       COALESCE(jarm, ''), COALESCE(ja4s, ''), COALESCE(ja4x, ''), COALESCE(tls_version, ''),
+		       COALESCE(cipher_suite, ''), COALESCE(cert_subject, ''), COALESCE(cert_issuer, ''), created_at
+		FROM tls_fingerprints
+		WHERE scope_target_id = $1
+		ORDER BY url, created_at DESC`, scopeTargetID)
+	if err != nil {
+		log.Printf("[TLS-FINGERPRINT] [ERROR] Failed to get TLS fingerprints: %v", err)
+		http.Error(w, "Failed to get TLS fingerprints", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var assets []TLSFingerprintAsset
+	for rows.Next() {
+		var a TLSFingerprintAsset
+		if err := rows.Scan(&a.URL, &a.Host, &a.IPAddress, &a.Port, &a.Source, &a.JARM, &a.JA4S, &a.JA4X,
+			&a.TLSVersion, &a.CipherSuite, &a.CertSubject, &a.CertIssuer, &a.FingerprintedAt); err != nil {
+			log.Printf("[TLS-FINGERPRINT] [ERROR] Error scanning TLS fingerprint row: %v", err)
+			continue
+		}
+		assets = append(assets, a)
+	}
+
+	groups := []TLSFingerprintGroup{}
+	for _, fpType := range types {
+		byValue := make(map[string][]TLSFingerprintAsset)
+		for _, a := range assets {
+			value := map[string]string{"jarm": a.JARM, "ja4s": a.JA4S, "ja4x": a.JA4X}[fpType]
+			if value == "" || value == jarmEmptyHash {
+				continue
+			}
+			byValue[value] = append(byValue[value], a)
+		}
+		for value, members := range byValue {
+			if len(members) < minCount {
+				continue
+			}
+			groups = append(groups, TLSFingerprintGroup{FingerprintType: fpType, Fingerprint: value, Count: len(members), Assets: members})
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		if groups[i].FingerprintType != groups[j].FingerprintType {
+			return groups[i].FingerprintType < groups[j].FingerprintType
+		}
+		return groups[i].Fingerprint < groups[j].Fingerprint
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"scope_target_id": scopeTargetID,
+		"total_endpoints": len(assets),
+		"groups":          groups,
+	})
+}
@@ -0,0 +1,577 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The TLS posture check enumerates protocol versions and cipher suites with
+// raw ClientHellos (crypto/tls can't speak SSLv3 or offer most legacy
+// suites), then does one regular handshake to inspect the certificate chain.
+
+type tlsCipherSuite struct {
+	id   uint16
+	name string
+}
+
+var tls13CipherSuites = []tlsCipherSuite{
+	{0x1301, "TLS_AES_128_GCM_SHA256"},
+	{0x1302, "TLS_AES_256_GCM_SHA384"},
+	{0x1303, "TLS_CHACHA20_POLY1305_SHA256"},
+	{0x1304, "TLS_AES_128_CCM_SHA256"},
+	{0x1305, "TLS_AES_128_CCM_8_SHA256"},
+}
+
+var legacyCipherSuites = []tlsCipherSuite{
+	{0xc02c, "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"},
+	{0xc030, "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+	{0xcca9, "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256"},
+	{0xcca8, "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256"},
+	{0xc02b, "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+	{0xc02f, "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+	{0x009f, "TLS_DHE_RSA_WITH_AES_256_GCM_SHA384"},
+	{0x009e, "TLS_DHE_RSA_WITH_AES_128_GCM_SHA256"},
+	{0xccaa, "TLS_DHE_RSA_WITH_CHACHA20_POLY1305_SHA256"},
+	{0xc0ad, "TLS_ECDHE_ECDSA_WITH_AES_256_CCM"},
+	{0xc0ac, "TLS_ECDHE_ECDSA_WITH_AES_128_CCM"},
+	{0xc09f, "TLS_DHE_RSA_WITH_AES_256_CCM"},
+	{0xc09e, "TLS_DHE_RSA_WITH_AES_128_CCM"},
+	{0xc024, "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384"},
+	{0xc028, "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384"},
+	{0xc023, "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256"},
+	{0xc027, "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256"},
+	{0xc00a, "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA"},
+	{0xc014, "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA"},
+	{0xc009, "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA"},
+	{0xc013, "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA"},
+	{0x006b, "TLS_DHE_RSA_WITH_AES_256_CBC_SHA256"},
+	{0x0067, "TLS_DHE_RSA_WITH_AES_128_CBC_SHA256"},
+	{0x0039, "TLS_DHE_RSA_WITH_AES_256_CBC_SHA"},
+	{0x0033, "TLS_DHE_RSA_WITH_AES_128_CBC_SHA"},
+	{0x0038, "TLS_DHE_DSS_WITH_AES_256_CBC_SHA"},
+	{0x0032, "TLS_DHE_DSS_WITH_AES_128_CBC_SHA"},
+	{0x00c4, "TLS_DHE_RSA_WITH_CAMELLIA_256_CBC_SHA256"},
+	{0x00be, "TLS_DHE_RSA_WITH_CAMELLIA_128_CBC_SHA256"},
+	{0x0088, "TLS_DHE_RSA_WITH_CAMELLIA_256_CBC_SHA"},
+	{0x0045, "TLS_DHE_RSA_WITH_CAMELLIA_128_CBC_SHA"},
+	{0x009d, "TLS_RSA_WITH_AES_256_GCM_SHA384"},
+	{0x009c, "TLS_RSA_WITH_AES_128_GCM_SHA256"},
+	{0xc09d, "TLS_RSA_WITH_AES_256_CCM"},
+	{0xc09c, "TLS_RSA_WITH_AES_128_CCM"},
+	{0x003d, "TLS_RSA_WITH_AES_256_CBC_SHA256"},
+	{0x003c, "TLS_RSA_WITH_AES_128_CBC_SHA256"},
+	{0x0035, "TLS_RSA_WITH_AES_256_CBC_SHA"},
+	{0x002f, "TLS_RSA_WITH_AES_128_CBC_SHA"},
+	{0x00c0, "TLS_RSA_WITH_CAMELLIA_256_CBC_SHA256"},
+	{0x00ba, "TLS_RSA_WITH_CAMELLIA_128_CBC_SHA256"},
+	{0x0084, "TLS_RSA_WITH_CAMELLIA_256_CBC_SHA"},
+	{0x0041, "TLS_RSA_WITH_CAMELLIA_128_CBC_SHA"},
+	{0xc012, "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA"},
+	{0xc008, "TLS_ECDHE_ECDSA_WITH_3DES_EDE_CBC_SHA"},
+	{0x0016, "TLS_DHE_RSA_WITH_3DES_EDE_CBC_SHA"},
+	{0x0013, "TLS_DHE_DSS_WITH_3DES_EDE_CBC_SHA"},
+	{0x000a, "TLS_RSA_WITH_3DES_EDE_CBC_SHA"},
+	{0xc011, "TLS_ECDHE_RSA_WITH_RC4_128_SHA"},
+	{0xc007, "TLS_ECDHE_ECDSA_WITH_RC4_128_SHA"},
+	{0x0005, "TLS_RSA_WITH_RC4_128_SHA"},
+	{0x0004, "TLS_RSA_WITH_RC4_128_MD5"},
+	{0x0015, "TLS_DHE_RSA_WITH_DES_CBC_SHA"},
+	{0x0009, "TLS_RSA_WITH_DES_CBC_SHA"},
+	{0x0008, "TLS_RSA_EXPORT_WITH_DES40_CBC_SHA"},
+	{0x0003, "TLS_RSA_EXPORT_WITH_RC4_40_MD5"},
+	{0x0034, "TLS_DH_anon_WITH_AES_128_CBC_SHA"},
+	{0x003a, "TLS_DH_anon_WITH_AES_256_CBC_SHA"},
+	{0x0018, "TLS_DH_anon_WITH_RC4_128_MD5"},
+	{0x0002, "TLS_RSA_WITH_NULL_SHA"},
+	{0x0001, "TLS_RSA_WITH_NULL_MD5"},
+}
+
+var tlsProtocolVersions = []struct {
+	version uint16
+	name    string
+}{
+	{0x0300, "SSLv3"},
+	{0x0301, "TLSv1.0"},
+	{0x0302, "TLSv1.1"},
+	{0x0303, "TLSv1.2"},
+	{0x0304, "TLSv1.3"},
+}
+
+// weakCipherMarkers flag suites with no or broken encryption or no
+// authentication.
+var weakCipherMarkers = []string{"_NULL_", "_EXPORT_", "_anon_", "_RC4_", "_DES_CBC_", "_3DES_"}
+
+// TLSPosture is the outcome of checkTLSPosture for one endpoint.
+type TLSPosture struct {
+	Protocols          []string            `json:"protocols"`
+	CipherSuites       map[string][]string `json:"cipher_suites"`
+	WeakCiphers        []string            `json:"weak_ciphers,omitempty"`
+	SSLVersion         string              `json:"ssl_version,omitempty"`
+	SSLCipherSuite     string              `json:"ssl_cipher_suite,omitempty"`
+	Subject            string              `json:"subject,omitempty"`
+	Issuer             string              `json:"issuer,omitempty"`
+	SANs               []string            `json:"sans,omitempty"`
+	SerialNumber       string              `json:"serial_number,omitempty"`
+	NotBefore          *time.Time          `json:"not_before,omitempty"`
+	NotAfter           *time.Time          `json:"not_after,omitempty"`
+	KeyType            string              `json:"key_type,omitempty"`
+	KeySize            int                 `json:"key_size,omitempty"`
+	SignatureAlgorithm string              `json:"signature_algorithm,omitempty"`
+	ChainValid         bool                `json:"chain_valid"`
+	ChainError         string              `json:"chain_error,omitempty"`
+	OCSPStapled        bool                `json:"ocsp_stapled"`
+	OCSPStatus         string              `json:"ocsp_status,omitempty"`
+	Wildcard           bool                `json:"wildcard"`
+	DeprecatedTLS      bool                `json:"deprecated_tls"`
+	Expired            bool                `json:"expired"`
+	Mismatched         bool                `json:"mismatched"`
+	Revoked            bool                `json:"revoked"`
+	SelfSigned         bool                `json:"self_signed"`
+	UntrustedRoot      bool                `json:"untrusted_root"`
+	WeakKey            bool                `json:"weak_key"`
+	WeakSignature      bool                `json:"weak_signature"`
+}
+
+// checkTLSPosture inspects the TLS service on host:port. host is sent as
+// SNI and checked against the certificate.
+func checkTLSPosture(ctx context.Context, host string, port int, timeout time.Duration) (*TLSPosture, error) {
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	posture := &TLSPosture{CipherSuites: make(map[string][]string)}
+
+	for _, v := range tlsProtocolVersions {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		candidates := legacyCipherSuites
+		if v.version == 0x0304 {
+			candidates = tls13CipherSuites
+		}
+		suites := enumerateCipherSuites(ctx, address, host, v.version, candidates, timeout)
+		if len(suites) == 0 {
+			continue
+		}
+		posture.Protocols = append(posture.Protocols, v.name)
+		posture.CipherSuites[v.name] = suites
+		// Highest version and its preferred suite win.
+		posture.SSLVersion = v.name
+		posture.SSLCipherSuite = suites[0]
+		if v.version < 0x0303 {
+			posture.DeprecatedTLS = true
+		}
+		for _, s := range suites {
+			if isWeakCipher(s) && !slices.Contains(posture.WeakCiphers, s) {
+				posture.WeakCiphers = append(posture.WeakCiphers, s)
+			}
+		}
+	}
+
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: timeout},
+		Config:    &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS10},
+	}
+	if net.ParseIP(host) == nil {
+		dialer.Config.ServerName = host
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		if len(posture.Protocols) == 0 {
+			return nil, fmt.Errorf("no TLS handshake with %s: %v", address, err)
+		}
+		posture.ChainError = fmt.Sprintf("handshake failed: %v", err)
+		return posture, nil
+	}
+	state := conn.(*tls.Conn).ConnectionState()
+	conn.Close()
+
+	if len(state.PeerCertificates) > 0 {
+		inspectCertificateChain(ctx, posture, host, state.PeerCertificates, state.OCSPResponse, timeout)
+	}
+	return posture, nil
+}
+
+// enumerateCipherSuites returns the suites the server accepts for version,
+// in the order it picks them: offer everything, drop the chosen suite,
+// repeat until the server refuses.
+func enumerateCipherSuites(ctx context.Context, address, host string, version uint16, candidates []tlsCipherSuite, timeout time.Duration) []string {
+	remaining := append([]tlsCipherSuite{}, candidates...)
+	var accepted []string
+
+	for len(remaining) > 0 && ctx.Err() == nil {
+		ids := make([]uint16, len(remaining))
+		for i, c := range remaining {
+			ids[i] = c.id
+		}
+		hello, ok := sendClientHello(ctx, address, buildPostureClientHello(host, version, ids), timeout)
+		if !ok || hello.version != version {
+			break
+		}
+		idx := -1
+		for i, c := range remaining {
+			if c.id == hello.cipher {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			break
+		}
+		accepted = append(accepted, remaining[idx].name)
+		remaining = append(remaining[:idx], remaining[idx+1:]...)
+	}
+	return accepted
+}
+
+func sendClientHello(ctx context.Context, address string, clientHello []byte, timeout time.Duration) (serverHello, bool) {
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return serverHello{}, false
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := conn.Write(clientHello); err != nil {
+		return serverHello{}, false
+	}
+	reply := readReply(conn, func(b []byte) bool {
+		return len(b) >= 5 && len(b) >= 5+int(binary.BigEndian.Uint16(b[3:5]))
+	})
+	return parseServerHello(reply)
+}
+
+// buildPostureClientHello offers exactly the given suites at one protocol
+// version. TLS 1.3 is requested through supported_versions with an x25519
+// key share; SSLv3 hellos carry no extensions.
+func buildPostureClientHello(host string, version uint16, ciphers []uint16) []byte {
+	helloVersion := version
+	if version == 0x0304 {
+		helloVersion = 0x0303
+	}
+
+	hello := binary.BigEndian.AppendUint16(nil, helloVersion)
+	hello = append(hello, randomBytes(32)...)
+	hello = append(hello, 32)
+	hello = append(hello, randomBytes(32)...)
+	hello = binary.BigEndian.AppendUint16(hello, uint16(2*len(ciphers)))
+	for _, c := range ciphers {
+		hello = binary.BigEndian.AppendUint16(hello, c)
+	}
+	hello = append(hello, 0x01, 0x00)
+
+	if version > 0x0300 {
+		var ext []byte
+		if net.ParseIP(host) == nil {
+			ext = append(ext, 0x00, 0x00)
+			ext = binary.BigEndian.AppendUint16(ext, uint16(len(host)+5))
+			ext = binary.BigEndian.AppendUint16(ext, uint16(len(host)+3))
+			ext = append(ext, 0x00)
+			ext = binary.BigEndian.AppendUint16(ext, uint16(len(host)))
+			ext = append(ext, host...)
+		}
+		ext = append(ext, 0x00, 0x0a, 0x00, 0x0a, 0x00, 0x08, 0x00, 0x1d, 0x00, 0x17, 0x00, 0x18, 0x00, 0x19) // supported_groups
+		ext = append(ext, 0x00, 0x0b, 0x00, 0x02, 0x01, 0x00)                                                 // ec_point_formats
+		ext = append(ext, 0x00, 0x0d, 0x00, 0x18, 0x00, 0x16, 0x04, 0x03, 0x05, 0x03, 0x06, 0x03, 0x08, 0x04,
+			0x08, 0x05, 0x08, 0x06, 0x04, 0x01, 0x05, 0x01, 0x06, 0x01, 0x02, 0x01, 0x02, 0x03) // signature_algorithms
+		ext = append(ext, 0xff, 0x01, 0x00, 0x01, 0x00) // renegotiation_info
+		ext = append(ext, 0x00, 0x17, 0x00, 0x00)       // extended_master_secret
+		if version == 0x0304 {
+			ext = append(ext, 0x00, 0x2b, 0x00, 0x03, 0x02, 0x03, 0x04) // supported_versions: TLS 1.3
+			ext = append(ext, 0x00, 0x2d, 0x00, 0x02, 0x01, 0x01)       // psk_key_exchange_modes
+			ext = append(ext, 0x00, 0x33, 0x00, 0x26, 0x00, 0x24, 0x00, 0x1d, 0x00, 0x20)
+			ext = append(ext, randomBytes(32)...) // key_share: x25519
+		}
+		hello = binary.BigEndian.AppendUint16(hello, uint16(len(ext)))
+		hello = append(hello, ext...)
+	}
+
+	handshake := []byte{0x01, 0x00}
+	handshake = binary.BigEndian.AppendUint16(handshake, uint16(len(hello)))
+	handshake = append(handshake, hello...)
+
+	recordVersion := helloVersion
+	if recordVersion > 0x0301 {
+		recordVersion = 0x0301
+	}
+	record := []byte{0x16}
+	record = binary.BigEndian.AppendUint16(record, recordVersion)
+	record = binary.BigEndian.AppendUint16(record, uint16(len(handshake)))
+	return append(record, handshake...)
+}
+
+func isWeakCipher(name string) bool {
+	for _, marker := range weakCipherMarkers {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func inspectCertificateChain(ctx context.Context, posture *TLSPosture, host string, chain []*x509.Certificate, stapled []byte, timeout time.Duration) {
+	leaf := chain[0]
+	now := time.Now()
+
+	posture.Subject = leaf.Subject.String()
+	posture.Issuer = leaf.Issuer.String()
+	posture.SANs = append(posture.SANs, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		posture.SANs = append(posture.SANs, ip.String())
+	}
+	posture.SerialNumber = leaf.SerialNumber.Text(16)
+	notBefore, notAfter := leaf.NotBefore, leaf.NotAfter
+	posture.NotBefore, posture.NotAfter = &notBefore, &notAfter
+	posture.SignatureAlgorithm = leaf.SignatureAlgorithm.String()
+	posture.WeakSignature = strings.Contains(posture.SignatureAlgorithm, "MD5") || strings.Contains(posture.SignatureAlgorithm, "SHA1")
+
+	switch key := leaf.PublicKey.(type) {
+	case *rsa.PublicKey:
+		posture.KeyType, posture.KeySize = "RSA", key.N.BitLen()
+		posture.WeakKey = posture.KeySize < 2048
+	case *ecdsa.PublicKey:
+		posture.KeyType, posture.KeySize = "ECDSA", key.Curve.Params().BitSize
+		posture.WeakKey = posture.KeySize < 256
+	case ed25519.PublicKey:
+		posture.KeyType, posture.KeySize = "Ed25519", 256
+	}
+
+	posture.Wildcard = strings.HasPrefix(leaf.Subject.CommonName, "*.")
+	for _, name := range leaf.DNSNames {
+		if strings.HasPrefix(name, "*.") {
+			posture.Wildcard = true
+		}
+	}
+
+	posture.Expired = now.After(leaf.NotAfter) || now.Before(leaf.NotBefore)
+	posture.Mismatched = leaf.VerifyHostname(host) != nil
+	posture.SelfSigned = bytes.Equal(leaf.RawIssuer, leaf.RawSubject) && leaf.CheckSignatureFrom(leaf) == nil
+
+	// Trust is judged inside the validity window so an expired certificate
+	// doesn't hide an untrusted one.
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	verifyAt := now
+	if posture.Expired {
+		verifyAt = leaf.NotBefore.Add(leaf.NotAfter.Sub(leaf.NotBefore) / 2)
+	}
+	_, trustErr := leaf.Verify(x509.VerifyOptions{Intermediates: intermediates, CurrentTime: verifyAt})
+	var unknownAuthority x509.UnknownAuthorityError
+	posture.UntrustedRoot = errors.As(trustErr, &unknownAuthority)
+
+	switch {
+	case trustErr != nil:
+		posture.ChainError = trustErr.Error()
+	case posture.Expired:
+		posture.ChainError = "certificate has expired or is not yet valid"
+	case posture.Mismatched:
+		posture.ChainError = fmt.Sprintf("certificate is not valid for %s", host)
+	}
+	posture.ChainValid = posture.ChainError == ""
+
+	var issuer *x509.Certificate
+	if len(chain) > 1 {
+		issuer = chain[1]
+	}
+	posture.OCSPStapled = len(stapled) > 0
+	if posture.OCSPStapled {
+		posture.OCSPStatus = ocspCertStatus(stapled, leaf, issuer)
+	} else if issuer != nil && len(leaf.OCSPServer) > 0 {
+		posture.OCSPStatus = queryOCSP(ctx, leaf, issuer, timeout)
+	}
+	posture.Revoked = posture.OCSPStatus == "revoked"
+}
+
+// Minimal OCSP (RFC 6960) structures.
+
+type ocspCertID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+type ocspRequestEntry struct {
+	Cert ocspCertID
+}
+
+type ocspTBSRequest struct {
+	Version     int `asn1:"explicit,tag:0,default:0,optional"`
+	RequestList []ocspRequestEntry
+}
+
+type ocspRequest struct {
+	TBSRequest ocspTBSRequest
+}
+
+type ocspResponse struct {
+	Status   asn1.Enumerated
+	Response ocspResponseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type ocspBasicResponse struct {
+	TBSResponseData    ocspResponseData
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseData struct {
+	Raw            asn1.RawContent
+	Version        int `asn1:"optional,default:0,explicit,tag:0"`
+	RawResponderID asn1.RawValue
+	ProducedAt     time.Time `asn1:"generalized"`
+	Responses      []ocspSingleResponse
+}
+
+type ocspSingleResponse struct {
+	CertID           ocspCertID
+	Good             asn1.Flag        `asn1:"tag:0,optional"`
+	Revoked          ocspRevokedInfo  `asn1:"tag:1,optional"`
+	Unknown          asn1.Flag        `asn1:"tag:2,optional"`
+	ThisUpdate       time.Time        `asn1:"generalized"`
+	NextUpdate       time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	SingleExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type ocspRevokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+var oidSHA1 = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+
+// ocspSignatureAlgorithms maps the signature algorithms OCSP responders use
+// to their crypto/x509 equivalent.
+var ocspSignatureAlgorithms = map[string]x509.SignatureAlgorithm{
+	"1.2.840.113549.1.1.5":  x509.SHA1WithRSA,
+	"1.2.840.113549.1.1.11": x509.SHA256WithRSA,
+	"1.2.840.113549.1.1.12": x509.SHA384WithRSA,
+	"1.2.840.113549.1.1.13": x509.SHA512WithRSA,
+	"1.2.840.10045.4.1":     x509.ECDSAWithSHA1,
+	"1.2.840.10045.4.3.2":   x509.ECDSAWithSHA256,
+	"1.2.840.10045.4.3.3":   x509.ECDSAWithSHA384,
+	"1.2.840.10045.4.3.4":   x509.ECDSAWithSHA512,
+	"1.3.101.112":           x509.PureEd25519,
+}
+
+// ocspCertStatus returns good, revoked or unknown for leaf in a DER OCSP
+// response, or "" if the response can't be read. A response that isn't
+// signed by issuer, or by a responder certificate issuer delegated OCSP
+// signing to, yields "unverified" so a forged staple can't mark a
+// certificate revoked.
+func ocspCertStatus(der []byte, leaf, issuer *x509.Certificate) string {
+	var resp ocspResponse
+	if _, err := asn1.Unmarshal(der, &resp); err != nil || resp.Status != 0 {
+		return ""
+	}
+	var basic ocspBasicResponse
+	if _, err := asn1.Unmarshal(resp.Response.Response, &basic); err != nil {
+		return ""
+	}
+	for _, single := range basic.TBSResponseData.Responses {
+		if single.CertID.SerialNumber == nil || single.CertID.SerialNumber.Cmp(leaf.SerialNumber) != 0 {
+			continue
+		}
+		if !ocspSignedBy(&basic, issuer) {
+			return "unverified"
+		}
+		switch {
+		case bool(single.Good):
+			return "good"
+		case bool(single.Unknown):
+			return "unknown"
+		default:
+			return "revoked"
+		}
+	}
+	return ""
+}
+
+// ocspSignedBy reports whether basic is signed by issuer or by a certificate
+// in the response that issuer signed for OCSP signing (RFC 6960 4.2.2.2).
+func ocspSignedBy(basic *ocspBasicResponse, issuer *x509.Certificate) bool {
+	algo, ok := ocspSignatureAlgorithms[basic.SignatureAlgorithm.Algorithm.String()]
+	if !ok || issuer == nil {
+		return false
+	}
+	signed, signature := basic.TBSResponseData.Raw, basic.Signature.RightAlign()
+	if issuer.CheckSignature(algo, signed, signature) == nil {
+		return true
+	}
+	for _, raw := range basic.Certificates {
+		responder, err := x509.ParseCertificate(raw.FullBytes)
+		if err != nil || !slices.Contains(responder.ExtKeyUsage, x509.ExtKeyUsageOCSPSigning) {
+			continue
+		}
+		if responder.CheckSignatureFrom(issuer) == nil && responder.CheckSignature(algo, signed, signature) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// queryOCSP asks the leaf's OCSP responder for its status.
+func queryOCSP(ctx context.Context, leaf, issuer *x509.Certificate, timeout time.Duration) string {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return ""
+	}
+	nameHash := sha1.Sum(issuer.RawSubject)
+	keyHash := sha1.Sum(spki.PublicKey.RightAlign())
+
+	request, err := asn1.Marshal(ocspRequest{TBSRequest: ocspTBSRequest{RequestList: []ocspRequestEntry{{Cert: ocspCertID{
+		HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA1, Parameters: asn1.RawValue{Tag: asn1.TagNull}},
+		NameHash:      nameHash[:],
+		IssuerKeyHash: keyHash[:],
+		SerialNumber:  leaf.SerialNumber,
+	}}}}})
+	if err != nil {
+		return ""
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, leaf.OCSPServer[0], bytes.NewReader(request))
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	return ocspCertStatus(body, leaf, issuer)
+}
@@ -0,0 +1,333 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// tlsPostureTool checks protocol versions, cipher suites and certificate
+// health for the same endpoints as tls-fingerprint, and writes the outcome
+// to target_urls and the consolidated assets.
+type tlsPostureTool struct{ executorTool }
+
+func init() {
+	RegisterTool(tlsPostureTool{executorTool{
+		toolBase: toolBase{name: "tls-posture", input: ToolInputURLList, table: "tls_posture_scans"},
+		execute:  ExecuteTLSPostureScan,
+	}})
+}
+
+func (tlsPostureTool) Schema() string {
+	return scanTableSchema("tls_posture_scans", "url")
+}
+
+const (
+	tlsPostureConcurrency = 5
+	tlsPostureTimeout     = 5 * time.Second
+)
+
+type TLSPostureResult struct {
+	URL       string     `json:"url"`
+	Host      string     `json:"host"`
+	IPAddress string     `json:"ip_address,omitempty"`
+	Port      int        `json:"port"`
+	Source    string     `json:"source"`
+	Posture   TLSPosture `json:"posture"`
+	ScannedAt time.Time  `json:"scanned_at"`
+}
+
+func createTLSPostureTable() {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS tls_posture_results (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scan_id UUID REFERENCES tls_posture_scans(scan_id) ON DELETE CASCADE,
+			scope_target_id UUID REFERENCES scope_targets(id) ON DELETE CASCADE,
+			source VARCHAR(30) NOT NULL,
+			url TEXT NOT NULL,
+			host TEXT NOT NULL,
+			ip_address TEXT,
+			port INT NOT NULL,
+			ssl_version TEXT,
+			ssl_cipher_suite TEXT,
+			ssl_protocols TEXT[],
+			weak_ciphers TEXT[],
+			cert_subject TEXT,
+			cert_issuer TEXT,
+			cert_not_after TIMESTAMP,
+			chain_valid BOOLEAN DEFAULT false,
+			has_deprecated_tls BOOLEAN DEFAULT false,
+			has_expired_ssl BOOLEAN DEFAULT false,
+			has_mismatched_ssl BOOLEAN DEFAULT false,
+			has_revoked_ssl BOOLEAN DEFAULT false,
+			has_self_signed_ssl BOOLEAN DEFAULT false,
+			has_untrusted_root_ssl BOOLEAN DEFAULT false,
+			has_wildcard_tls BOOLEAN DEFAULT false,
+			posture JSONB,
+			created_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scan_id, url)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_tls_posture_results_scope_target_id ON tls_posture_results(scope_target_id);`,
+		// Checks run by metadata scans have no scan row and keep one result per
+		// URL; drop duplicates stored before the index existed.
+		`DELETE FROM tls_posture_results a USING tls_posture_results b
+			WHERE a.scan_id IS NULL AND b.scan_id IS NULL
+			  AND a.scope_target_id = b.scope_target_id AND a.url = b.url
+			  AND (a.created_at, a.id::text) < (b.created_at, b.id::text);`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_tls_posture_results_unscanned ON tls_posture_results(scope_target_id, url) WHERE scan_id IS NULL;`,
+	}
+	for _, query := range queries {
+		if _, err := dbPool.Exec(context.Background(), query); err != nil {
+			log.Printf("[TLS-POSTURE] [ERROR] Failed to create table/index: %v", err)
+		}
+	}
+}
+
+func ExecuteTLSPostureScan(ctx context.Context, scanID string, input ToolInput) {
+	t, _ := LookupTool("tls-posture")
+	log.Printf("[TLS-POSTURE] [INFO] Starting TLS posture scan %s for scope target %s", scanID, input.ScopeTargetID)
+	startTime := time.Now()
+	createTLSPostureTable()
+	UpdateToolScanStatus(t, scanID, "running", "", "", "", "")
+
+	var endpoints []tlsEndpoint
+	var err error
+	if len(input.URLs) > 0 {
+		endpoints = tlsEndpointsFromURLs(input.URLs, "url_list")
+	} else {
+		endpoints, err = loadTLSEndpoints(input.ScopeTargetID)
+		if err != nil {
+			UpdateToolScanStatus(t, scanID, "error", "", fmt.Sprintf("failed to load TLS endpoints: %v", err), "", time.Since(startTime).String())
+			return
+		}
+	}
+	command := fmt.Sprintf("native TLS posture check of %d endpoints", len(endpoints))
+
+	lines := runTLSPostureChecks(ctx, scanID, input.ScopeTargetID, endpoints)
+	if ctx.Err() != nil {
+		log.Printf("[TLS-POSTURE] [INFO] Scan %s cancelled", scanID)
+		return
+	}
+
+	if err := attachTLSPosture(input.ScopeTargetID); err != nil {
+		log.Printf("[TLS-POSTURE] [ERROR] Failed to attach TLS posture to assets: %v", err)
+	}
+
+	result := lineResult(lines)
+	stderr := ""
+	if result.Result == "" {
+		stderr = "No TLS endpoints could be checked"
+	}
+	UpdateToolScanStatus(t, scanID, result.Status, result.Result, stderr, command, time.Since(startTime).String())
+	log.Printf("[TLS-POSTURE] [INFO] Checked %d/%d endpoints in %s", len(lines), len(endpoints), time.Since(startTime))
+}
+
+// runTLSPostureChecks checks every endpoint, stores the results and updates
+// the has_* flags of matching target URLs. scanID may be empty when the
+// check runs as part of a metadata scan. It returns one summary line per
+// checked endpoint.
+func runTLSPostureChecks(ctx context.Context, scanID, scopeTargetID string, endpoints []tlsEndpoint) []string {
+	createTLSPostureTable()
+	log.Printf("[TLS-POSTURE] [INFO] Checking %d TLS endpoints", len(endpoints))
+
+	var lines []string
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, tlsPostureConcurrency)
+
+	for _, endpoint := range endpoints {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(ep tlsEndpoint) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			if ctx.Err() != nil {
+				return
+			}
+
+			posture, err := checkTLSPosture(ctx, ep.Host, ep.Port, tlsPostureTimeout)
+			if err != nil {
+				log.Printf("[TLS-POSTURE] [DEBUG] Skipping %s: %v", ep.URL, err)
+				return
+			}
+			insertTLSPosture(scanID, scopeTargetID, ep, posture)
+			updateTargetURLTLSFlags(scopeTargetID, ep.URL, posture)
+
+			mu.Lock()
+			lines = append(lines, tlsPostureSummary(ep.URL, posture))
+			checked := len(lines)
+			mu.Unlock()
+			if scanID != "" {
+				PublishScanCounts(scanID, map[string]int{"checked": checked, "total": len(endpoints)})
+			}
+		}(endpoint)
+	}
+	wg.Wait()
+
+	sort.Strings(lines)
+	return lines
+}
+
+func tlsPostureSummary(url string, p *TLSPosture) string {
+	var issues []string
+	for _, flag := range []struct {
+		set  bool
+		name string
+	}{
+		{p.DeprecatedTLS, "deprecated_tls"},
+		{p.Expired, "expired"},
+		{p.Mismatched, "mismatched"},
+		{p.Revoked, "revoked"},
+		{p.SelfSigned, "self_signed"},
+		{p.UntrustedRoot, "untrusted_root"},
+		{p.WeakKey, "weak_key"},
+		{p.WeakSignature, "weak_signature"},
+		{len(p.WeakCiphers) > 0, "weak_ciphers"},
+	} {
+		if flag.set {
+			issues = append(issues, flag.name)
+		}
+	}
+	if len(issues) == 0 {
+		issues = append(issues, "ok")
+	}
+	return fmt.Sprintf("%s protocols=%s issues=%s", url, strings.Join(p.Protocols, ","), strings.Join(issues, ","))
+}
+
+func insertTLSPosture(scanID, scopeTargetID string, ep tlsEndpoint, p *TLSPosture) {
+	postureJSON, err := json.Marshal(p)
+	if err != nil {
+		log.Printf("[TLS-POSTURE] [ERROR] Failed to marshal posture for %s: %v", ep.URL, err)
+		return
+	}
+	// scan_id is NULL for metadata scan checks, which never match the
+	// (scan_id, url) constraint, so they replace the URL's previous result.
+	conflict := `ON CONFLICT (scan_id, url) DO NOTHING`
+	if scanID == "" {
+		conflict = `ON CONFLICT (scope_target_id, url) WHERE scan_id IS NULL DO UPDATE SET
+			  source = EXCLUDED.source, host = EXCLUDED.host, ip_address = EXCLUDED.ip_address, port = EXCLUDED.port,
+			  ssl_version = EXCLUDED.ssl_version, ssl_cipher_suite = EXCLUDED.ssl_cipher_suite,
+			  ssl_protocols = EXCLUDED.ssl_protocols, weak_ciphers = EXCLUDED.weak_ciphers,
+			  cert_subject = EXCLUDED.cert_subject, cert_issuer = EXCLUDED.cert_issuer, cert_not_after = EXCLUDED.cert_not_after,
+			  chain_valid = EXCLUDED.chain_valid, has_deprecated_tls = EXCLUDED.has_deprecated_tls,
+			  has_expired_ssl = EXCLUDED.has_expired_ssl, has_mismatched_ssl = EXCLUDED.has_mismatched_ssl,
+			  has_revoked_ssl = EXCLUDED.has_revoked_ssl, has_self_signed_ssl = EXCLUDED.has_self_signed_ssl,
+			  has_untrusted_root_ssl = EXCLUDED.has_untrusted_root_ssl, has_wildcard_tls = EXCLUDED.has_wildcard_tls,
+			  posture = EXCLUDED.posture, created_at = NOW()`
+	}
+	query := `INSERT INTO tls_posture_results (scan_id, scope_target_id, source, url, host, ip_address, port,
+			  ssl_version, ssl_cipher_suite, ssl_protocols, weak_ciphers, cert_subject, cert_issuer, cert_not_after,
+			  chain_valid, has_deprecated_tls, has_expired_ssl, has_mismatched_ssl, has_revoked_ssl,
+			  has_self_signed_ssl, has_untrusted_root_ssl, has_wildcard_tls, posture)
+			  VALUES (NULLIF($1, '')::uuid, $2, $3, $4, $5, NULLIF($6, ''), $7, NULLIF($8, ''), NULLIF($9, ''), $10, $11,
+			  NULLIF($12, ''), NULLIF($13, ''), $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+			  ` + conflict
+	_, err = dbPool.Exec(context.Background(), query, scanID, scopeTargetID, ep.Source, ep.URL, ep.Host, ep.IPAddress, ep.Port,
+		p.SSLVersion, p.SSLCipherSuite, p.Protocols, p.WeakCiphers, p.Subject, p.Issuer, p.NotAfter,
+		p.ChainValid, p.DeprecatedTLS, p.Expired, p.Mismatched, p.Revoked,
+		p.SelfSigned, p.UntrustedRoot, p.Wildcard, postureJSON)
+	if err != nil {
+		log.Printf("[TLS-POSTURE] [ERROR] Failed to store posture for %s: %v", ep.URL, err)
+	}
+}
+
+// updateTargetURLTLSFlags replaces the TLS flags of a target URL. Flags are
+// written both ways so a fixed issue clears on the next check.
+func updateTargetURLTLSFlags(scopeTargetID, url string, p *TLSPosture) {
+	query := `UPDATE target_urls SET
+			  has_deprecated_tls = $1, has_expired_ssl = $2, has_mismatched_ssl = $3, has_revoked_ssl = $4,
+			  has_self_signed_ssl = $5, has_untrusted_root_ssl = $6, has_wildcard_tls = $7, updated_at = NOW()
+			  WHERE scope_target_id = $8 AND (url = $9 OR url = $9 || '/')`
+	_, err := dbPool.Exec(context.Background(), query, p.DeprecatedTLS, p.Expired, p.Mismatched, p.Revoked,
+		p.SelfSigned, p.UntrustedRoot, p.Wildcard, scopeTargetID, strings.TrimSuffix(url, "/"))
+	if err != nil {
+		log.Printf("[TLS-POSTURE] [ERROR] Failed to update TLS flags for %s: %v", url, err)
+	}
+}
+
+// attachTLSPosture copies the latest posture of each endpoint onto the
+// ssl_* columns of matching live web server and FQDN assets.
+func attachTLSPosture(scopeTargetID string) error {
+	createTLSPostureTable()
+	query := `
+		UPDATE consolidated_attack_surface_assets a SET
+			ssl_version = p.ssl_version,
+			ssl_cipher_suite = p.ssl_cipher_suite,
+			ssl_protocols = p.ssl_protocols,
+			ssl_subject = p.cert_subject,
+			ssl_issuer = p.cert_issuer,
+			ssl_expiry_date = p.cert_not_after::date,
+			ssl_certificate = p.posture,
+			last_ssl_scan = p.created_at
+		FROM (
+			SELECT DISTINCT ON (url) url, host, ip_address, port, ssl_version, ssl_cipher_suite, ssl_protocols,
+			       cert_subject, cert_issuer, cert_not_after, posture, created_at
+			FROM tls_posture_results
+			WHERE scope_target_id = $1::uuid
+			ORDER BY url, created_at DESC
+		) p
+		WHERE a.scope_target_id = $1::uuid
+			AND (
+				(a.asset_type = 'live_web_server'
+					AND (a.url = p.url OR (p.ip_address IS NOT NULL AND a.ip_address = p.ip_address AND a.port = p.port AND a.protocol = 'https')))
+				OR (a.asset_type = 'fqdn' AND a.fqdn = p.host AND p.port = 443)
+			)
+	`
+	result, err := dbPool.Exec(context.Background(), query, scopeTargetID)
+	if err != nil {
+		return err
+	}
+	log.Printf("[TLS-POSTURE] [INFO] Attached TLS posture to %d assets", result.RowsAffected())
+	return nil
+}
+
+// GetTLSPostureResults returns the latest TLS posture of each endpoint of a
+// scope target.
+func GetTLSPostureResults(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	if scopeTargetID == "" {
+		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
+		return
+	}
+
+	createTLSPostureTable()
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT DISTINCT ON (url) url, host, COALESCE(ip_address, ''), port, source, posture, created_at
+		FROM tls_posture_results
+		WHERE scope_target_id = $1
+		ORDER BY url, created_at DESC`, scopeTargetID)
+	if err != nil {
+		log.Printf("[TLS-POSTURE] [ERROR] Failed to get TLS posture results: %v", err)
+		http.Error(w, "Failed to get TLS posture results", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	results := []TLSPostureResult{}
+	for rows.Next() {
+		var res TLSPostureResult
+		var postureJSON []byte
+		if err := rows.Scan(&res.URL, &res.Host, &res.IPAddress, &res.Port, &res.Source, &postureJSON, &res.ScannedAt); err != nil {
+			log.Printf("[TLS-POSTURE] [ERROR] Error scanning TLS posture row: %v", err)
+			continue
+		}
+		if len(postureJSON) > 0 {
+			json.Unmarshal(postureJSON, &res.Posture)
+		}
+		results = append(results, res)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func testCertificate(t *testing.T, template, parent *x509.Certificate, pub crypto.PublicKey, signer crypto.Signer) *x509.Certificate {
+	t.Helper()
+	if parent == nil {
+		parent = template
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	return cert
+}
+
+// testOCSPResponse builds a DER OCSP response for serial signed by signer,
+// optionally carrying a delegated responder certificate.
+func testOCSPResponse(t *testing.T, serial *big.Int, revoked bool, signer crypto.Signer, responder *x509.Certificate) []byte {
+	t.Helper()
+	now := time.Now().UTC().Truncate(time.Second)
+	single := ocspSingleResponse{
+		CertID: ocspCertID{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA1, Parameters: asn1.RawValue{Tag: asn1.TagNull}},
+			NameHash:      make([]byte, 20),
+			IssuerKeyHash: make([]byte, 20),
+			SerialNumber:  serial,
+		},
+		ThisUpdate: now,
+	}
+	if revoked {
+		single.Revoked = ocspRevokedInfo{RevocationTime: now}
+	} else {
+		single.Good = true
+	}
+	keyHash, _ := asn1.Marshal(make([]byte, 20))
+	responderID, _ := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, IsCompound: true, Bytes: keyHash})
+	tbs, err := asn1.Marshal(ocspResponseData{
+		RawResponderID: asn1.RawValue{FullBytes: responderID},
+		ProducedAt:     now,
+		Responses:      []ocspSingleResponse{single},
+	})
+	if err != nil {
+		t.Fatalf("marshal response data: %v", err)
+	}
+	digest := sha256.Sum256(tbs)
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	basic := ocspBasicResponse{
+		TBSResponseData:    ocspResponseData{Raw: tbs},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}},
+		Signature:          asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	}
+	if responder != nil {
+		basic.Certificates = []asn1.RawValue{{FullBytes: responder.Raw}}
+	}
+	basicDER, err := asn1.Marshal(basic)
+	if err != nil {
+		t.Fatalf("marshal basic response: %v", err)
+	}
+	der, err := asn1.Marshal(ocspResponse{Response: ocspResponseBytes{
+		ResponseType: asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1},
+		Response:     basicDER,
+	}})
+	if err != nil {
+		t.Fatalf("marshal response: %v", err)
+	}
+	return der
+}
+
+func TestOCSPCertStatus(t *testing.T) {
+	newKey := func() *ecdsa.PrivateKey {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey: %v", err)
+		}
+		return key
+	}
+	notBefore, notAfter := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	caKey := newKey()
+	ca := testCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "test CA"},
+		NotBefore: notBefore, NotAfter: notAfter,
+		IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign,
+	}, nil, &caKey.PublicKey, caKey)
+	leafKey := newKey()
+	leaf := testCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(42), Subject: pkix.Name{CommonName: "example.com"},
+		NotBefore: notBefore, NotAfter: notAfter,
+	}, ca, &leafKey.PublicKey, caKey)
+	responderKey := newKey()
+	responder := testCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(7), Subject: pkix.Name{CommonName: "test OCSP responder"},
+		NotBefore: notBefore, NotAfter: notAfter, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+	}, ca, &responderKey.PublicKey, caKey)
+	selfSignedResponder := testCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(8), Subject: pkix.Name{CommonName: "forged responder"},
+		NotBefore: notBefore, NotAfter: notAfter, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+	}, nil, &responderKey.PublicKey, responderKey)
+
+	tests := []struct {
+		name   string
+		der    []byte
+		issuer *x509.Certificate
+		want   string
+	}{
+		{name: "good signed by issuer", der: testOCSPResponse(t, leaf.SerialNumber, false, caKey, nil), issuer: ca, want: "good"},
+		{name: "revoked signed by issuer", der: testOCSPResponse(t, leaf.SerialNumber, true, caKey, nil), issuer: ca, want: "revoked"},
+		{name: "revoked signed by delegated responder", der: testOCSPResponse(t, leaf.SerialNumber, true, responderKey, responder), issuer: ca, want: "revoked"},
+		{name: "revoked signed by unrelated key", der: testOCSPResponse(t, leaf.SerialNumber, true, newKey(), nil), issuer: ca, want: "unverified"},
+		{name: "revoked signed by self-signed responder", der: testOCSPResponse(t, leaf.SerialNumber, true, responderKey, selfSignedResponder), issuer: ca, want: "unverified"},
+		{name: "revoked without known issuer", der: testOCSPResponse(t, leaf.SerialNumber, true, caKey, nil), issuer: nil, want: "unverified"},
+		{name: "other serial", der: testOCSPResponse(t, big.NewInt(43), true, caKey, nil), issuer: ca, want: ""},
+		{name: "garbage", der: []byte{0x30, 0x03, 0x0a, 0x01}, issuer: ca, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ocspCertStatus(tt.der, leaf, tt.issuer); got != tt.want {
+				t.Errorf("ocspCertStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,700 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// ToolInputKind is what a tool scans. It decides which scope targets the
+// tool accepts and which column of its scan table holds the target.
+type ToolInputKind string
+
+const (
+	// ToolInputDomain is the root domain of a wildcard scope target.
+	ToolInputDomain ToolInputKind = "domain"
+	// ToolInputCompany is the name of a company scope target.
+	ToolInputCompany ToolInputKind = "company"
+	// ToolInputURLList is an explicit list of URLs.
+	ToolInputURLList ToolInputKind = "url_list"
+	// ToolInputCIDR is the consolidated network ranges of a company scope
+	// target; the tool loads them itself.
+	ToolInputCIDR ToolInputKind = "cidr"
+)
+
+// ToolInput is what a tool scan runs against.
+type ToolInput struct {
+	ScopeTargetID string   `json:"scope_target_id"`
+	Target        string   `json:"target,omitempty"`
+	URLs          []string `json:"urls,omitempty"`
+}
+
+// ToolResult is a parsed tool run. Status and Result end up in the scan
+// row's status and result columns.
+type ToolResult struct {
+	Status string
+	Result string
+	Items  []string
+}
+
+// Tool is a recon tool served by the generic scan routes. The engine runs
+// BuildArgs in the tool's container, hands stdout to ParseOutput and lets
+// PersistResult store anything beyond the scan row itself.
+type Tool interface {
+	Name() string
+	InputKind() ToolInputKind
+	Table() string
+	BuildArgs(input ToolInput) []string
+	ParseOutput(input ToolInput, stdout, stderr string) (ToolResult, error)
+	PersistResult(ctx context.Context, scanID string, input ToolInput, result ToolResult) error
+}
+
+// ToolExecutor is implemented by tools whose scan is more than one command
+// (multi-stage pipelines, native Go scanners). Execute replaces the
+// BuildArgs/ParseOutput/PersistResult pipeline and must update the scan row
+// itself.
+type ToolExecutor interface {
+	Execute(ctx context.Context, scanID string, input ToolInput)
+}
+
+// toolTargetColumner lets a tool override the target column derived from
+// its input kind; an empty column means the row only has scope_target_id.
+type toolTargetColumner interface {
+	TargetColumn() string
+}
+
+// toolPayloadExecutor is implemented by tools whose queued jobs need more
+// of the job payload than ToolInput carries. The job queue calls
+// ExecutePayload instead of Execute.
+type toolPayloadExecutor interface {
+	ExecutePayload(ctx context.Context, scanID string, payload ScanJobPayload)
+}
+
+// toolSchema is implemented by tools whose scan table is not in the Prisma
+// schema. Schema returns a CREATE TABLE IF NOT EXISTS statement, usually
+// scanTableSchema.
+type toolSchema interface {
+	Schema() string
+}
+
+var (
+	toolsMu sync.RWMutex
+	tools   = make(map[string]Tool)
+)
+
+// RegisterTool makes t available on the generic scan routes, the job queue,
+// the scheduler and the exports. Call it from an init function.
+func RegisterTool(t Tool) {
+	toolsMu.Lock()
+	tools[t.Name()] = t
+	toolsMu.Unlock()
+
+	RegisterScanJobKind(t.Name(), t.Table(), func(ctx context.Context, job *ScanJob) error {
+		var payload ScanJobPayload
+		if err := job.DecodePayload(&payload); err != nil {
+			return err
+		}
+		if executor, ok := t.(toolPayloadExecutor); ok {
+			executor.ExecutePayload(ctx, job.ScanID, payload)
+			return nil
+		}
+		input := ToolInput{ScopeTargetID: payload.ScopeTargetID, Target: payload.Target, URLs: payload.Targets}
+		if executor, ok := t.(ToolExecutor); ok {
+			executor.Execute(ctx, job.ScanID, input)
+			return nil
+		}
+		runTool(ctx, t, job.ScanID, input)
+		return nil
+	})
+}
+
+// LookupTool returns the registered tool called name.
+func LookupTool(name string) (Tool, bool) {
+	toolsMu.RLock()
+	defer toolsMu.RUnlock()
+	t, ok := tools[name]
+	return t, ok
+}
+
+// RegisteredTools returns every registered tool sorted by name.
+func RegisteredTools() []Tool {
+	toolsMu.RLock()
+	defer toolsMu.RUnlock()
+	list := make([]Tool, 0, len(tools))
+	for _, t := range tools {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list
+}
+
+// scanTableSchema is the column layout every *_scans table shares. An empty
+// targetCol leaves the target column out.
+func scanTableSchema(table, targetCol string) string {
+	target := ""
+	if targetCol != "" {
+		target = fmt.Sprintf("\n\t\t\t%s TEXT NOT NULL,", targetCol)
+	}
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scan_id UUID NOT NULL UNIQUE,%s
+			status VARCHAR(50) NOT NULL,
+			result TEXT,
+			error TEXT,
+			stdout TEXT,
+			stderr TEXT,
+			command TEXT,
+			execution_time TEXT,
+			created_at TIMESTAMP DEFAULT NOW(),
+			scope_target_id UUID REFERENCES scope_targets(id) ON DELETE CASCADE,
+			auto_scan_session_id UUID REFERENCES auto_scan_sessions(id) ON DELETE SET NULL
+		);`, table, target)
+}
+
+// createToolTables creates the scan tables of tools that bring their own
+// schema.
+func createToolTables() {
+	for _, t := range RegisteredTools() {
+		schema, ok := t.(toolSchema)
+		if !ok || schema.Schema() == "" {
+			continue
+		}
+		if _, err := dbPool.Exec(context.Background(), schema.Schema()); err != nil {
+			log.Printf("[TOOLS] [ERROR] Failed to create %s table: %v", t.Table(), err)
+		}
+	}
+}
+
+func toolTargetColumn(t Tool) string {
+	if c, ok := t.(toolTargetColumner); ok {
+		return c.TargetColumn()
+	}
+	return defaultToolTargetColumn(t.InputKind())
+}
+
+func defaultToolTargetColumn(kind ToolInputKind) string {
+	switch kind {
+	case ToolInputDomain:
+		return "domain"
+	case ToolInputCompany:
+		return "company_name"
+	case ToolInputURLList:
+		return "url"
+	}
+	return ""
+}
+
+// toolScopeType is the scope_targets.type a tool runs against, or "" if it
+// is not tied to one.
+func toolScopeType(t Tool) string {
+	switch t.InputKind() {
+	case ToolInputDomain:
+		return "Wildcard"
+	case ToolInputCompany, ToolInputCIDR:
+		return "Company"
+	}
+	return ""
+}
+
+// toolTarget is the value stored in the tool's target column.
+func toolTarget(t Tool, input ToolInput) string {
+	if t.InputKind() == ToolInputURLList {
+		return strings.Join(input.URLs, ",")
+	}
+	return input.Target
+}
+
+// runTool is the generic scan pipeline for command-line tools.
+func runTool(ctx context.Context, t Tool, scanID string, input ToolInput) {
+	log.Printf("[INFO] Starting %s scan for %s (scan ID: %s)", t.Name(), toolTarget(t, input), scanID)
+	startTime := time.Now()
+
+	cmd := NewToolCommandContext(ctx, t.Name(), t.BuildArgs(input)...)
+	log.Printf("[INFO] Executing command: %s", cmd.String())
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	execTime := time.Since(startTime).String()
+	if err != nil {
+		log.Printf("[ERROR] %s scan failed for %s: %v", t.Name(), toolTarget(t, input), err)
+		log.Printf("[ERROR] stderr output: %s", stderr.String())
+		UpdateToolScanStatus(t, scanID, "error", "", stderr.String(), cmd.String(), execTime)
+		return
+	}
+
+	result, err := t.ParseOutput(input, stdout.String(), stderr.String())
+	if err != nil {
+		log.Printf("[ERROR] Failed to parse %s output for scan %s: %v", t.Name(), scanID, err)
+		UpdateToolScanStatus(t, scanID, "error", "", fmt.Sprintf("failed to parse output: %v", err), cmd.String(), execTime)
+		return
+	}
+
+	if err := t.PersistResult(ctx, scanID, input, result); err != nil {
+		log.Printf("[ERROR] Failed to store %s results for scan %s: %v", t.Name(), scanID, err)
+		UpdateToolScanStatus(t, scanID, "error", result.Result, fmt.Sprintf("failed to store results: %v", err), cmd.String(), execTime)
+		return
+	}
+
+	stderrText := stderr.String()
+	if result.Status == "completed" && result.Result == "" {
+		stderrText = "No results found"
+	}
+	log.Printf("[INFO] %s scan completed in %s for %s", t.Name(), execTime, toolTarget(t, input))
+	UpdateToolScanStatus(t, scanID, result.Status, result.Result, stderrText, cmd.String(), execTime)
+}
+
+// UpdateToolScanStatus writes the outcome of a scan to the tool's table.
+func UpdateToolScanStatus(t Tool, scanID, status, result, stderr, command, execTime string) {
+	log.Printf("[INFO] Updating %s scan status for %s to %s", t.Name(), scanID, status)
+	query := fmt.Sprintf(`UPDATE %s SET status = $1, result = $2, stderr = $3, command = $4, execution_time = $5 WHERE scan_id = $6`, t.Table())
+	_, err := dbPool.Exec(context.Background(), query, status, result, stderr, command, execTime, scanID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update %s scan status for %s: %v", t.Name(), scanID, err)
+	}
+}
+
+// QueueToolScan creates the scan row for t and queues its job.
+func QueueToolScan(t Tool, input ToolInput, autoScanSessionID string) (string, error) {
+	scanID := uuid.New().String()
+
+	columns := []string{"scan_id", "status", "scope_target_id"}
+	args := []interface{}{scanID, "pending", input.ScopeTargetID}
+	if col := toolTargetColumn(t); col != "" {
+		columns = append(columns, col)
+		args = append(args, toolTarget(t, input))
+	}
+	if autoScanSessionID != "" {
+		columns = append(columns, "auto_scan_session_id")
+		args = append(args, autoScanSessionID)
+	}
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s)`, t.Table(), strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+	if _, err := dbPool.Exec(context.Background(), query, args...); err != nil {
+		return "", fmt.Errorf("failed to create scan record: %v", err)
+	}
+
+	payload := ScanJobPayload{Target: input.Target, Targets: input.URLs, ScopeTargetID: input.ScopeTargetID}
+	if _, err := EnqueueScanJob(t.Name(), scanID, payload); err != nil {
+		return "", fmt.Errorf("failed to queue scan: %v", err)
+	}
+	return scanID, nil
+}
+
+// resolveToolScopeTarget fills input.ScopeTargetID (and Target when only the
+// scope target was given) and checks the scope target type fits the tool.
+func resolveToolScopeTarget(t Tool, input *ToolInput) error {
+	scopeType := toolScopeType(t)
+
+	if input.ScopeTargetID == "" {
+		var lookup string
+		switch t.InputKind() {
+		case ToolInputDomain:
+			lookup = "*." + input.Target
+		case ToolInputCompany:
+			lookup = input.Target
+		default:
+			return fmt.Errorf("`scope_target_id` is required")
+		}
+		err := dbPool.QueryRow(context.Background(),
+			`SELECT id FROM scope_targets WHERE type = $1 AND scope_target = $2`, scopeType, lookup).Scan(&input.ScopeTargetID)
+		if err != nil {
+			return fmt.Errorf("no matching %s scope target found", strings.ToLower(scopeType))
+		}
+		return nil
+	}
+
+	var scopeTarget, targetType string
+	err := dbPool.QueryRow(context.Background(),
+		`SELECT scope_target, type FROM scope_targets WHERE id = $1`, input.ScopeTargetID).Scan(&scopeTarget, &targetType)
+	if err != nil {
+		return fmt.Errorf("scope target not found")
+	}
+	if scopeType != "" && targetType != scopeType {
+		return fmt.Errorf("%s requires a %s scope target", t.Name(), strings.ToLower(scopeType))
+	}
+	if input.Target == "" && t.InputKind() != ToolInputURLList {
+		input.Target = strings.TrimPrefix(scopeTarget, "*.")
+	}
+	return nil
+}
+
+// WithTool binds a generic tool handler to one tool, for the per-tool routes
+// the UI already calls (e.g. /assetfinder/run).
+func WithTool(name string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := map[string]string{}
+		for k, v := range mux.Vars(r) {
+			vars[k] = v
+		}
+		vars["tool"] = name
+		handler(w, mux.SetURLVars(r, vars))
+	}
+}
+
+// RunToolScan handles POST /scans/{tool}/run. The body names the target the
+// same way the per-tool routes do (fqdn, company_name or urls), or passes a
+// scope_target_id directly.
+func RunToolScan(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["tool"]
+	t, ok := LookupTool(name)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown tool: %s", name), http.StatusNotFound)
+		return
+	}
+
+	var payload struct {
+		FQDN              string   `json:"fqdn"`
+		CompanyName       string   `json:"company_name"`
+		URLs              []string `json:"urls"`
+		ScopeTargetID     string   `json:"scope_target_id"`
+		AutoScanSessionID *string  `json:"auto_scan_session_id,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	input := ToolInput{ScopeTargetID: payload.ScopeTargetID, URLs: payload.URLs}
+	switch t.InputKind() {
+	case ToolInputDomain:
+		input.Target = payload.FQDN
+	case ToolInputCompany:
+		input.Target = payload.CompanyName
+	case ToolInputURLList:
+		if len(payload.URLs) == 0 {
+			http.Error(w, "Invalid request body. `urls` is required.", http.StatusBadRequest)
+			return
+		}
+	}
+	if input.Target == "" && input.ScopeTargetID == "" && t.InputKind() != ToolInputURLList {
+		field := map[ToolInputKind]string{ToolInputDomain: "fqdn", ToolInputCompany: "company_name", ToolInputCIDR: "scope_target_id"}[t.InputKind()]
+		http.Error(w, fmt.Sprintf("Invalid request body. `%s` is required.", field), http.StatusBadRequest)
+		return
+	}
+
+	if err := resolveToolScopeTarget(t, &input); err != nil {
+		log.Printf("[ERROR] %s scan request rejected: %v", name, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sessionID := ""
+	if payload.AutoScanSessionID != nil {
+		sessionID = *payload.AutoScanSessionID
+	}
+	scanID, err := QueueToolScan(t, input, sessionID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to start %s scan: %v", name, err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
+}
+
+// toolScanResponse flattens a scan row the way the per-tool handlers always
+// have: NULLs become empty strings and timestamps are RFC 3339.
+func toolScanResponse(row map[string]interface{}) map[string]interface{} {
+	for k, v := range row {
+		switch val := v.(type) {
+		case nil:
+			row[k] = ""
+		case time.Time:
+			row[k] = val.Format(time.RFC3339)
+		case [16]byte:
+			row[k] = uuid.UUID(val).String()
+		}
+	}
+	return row
+}
+
+// scanTableForTool resolves {tool} to its scan table. Any job kind works,
+// so the read routes also cover tools that are not Tool implementations.
+func scanTableForTool(name string) (string, bool) {
+	if t, ok := LookupTool(name); ok {
+		return t.Table(), true
+	}
+	kind, ok := lookupScanJobKind(name)
+	if !ok {
+		return "", false
+	}
+	return kind.table, true
+}
+
+// GetToolScan handles GET /scans/{tool}/{id}.
+func GetToolScan(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	table, ok := scanTableForTool(vars["tool"])
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown tool: %s", vars["tool"]), http.StatusNotFound)
+		return
+	}
+	scanID := vars["id"]
+	if scanID == "" {
+		scanID = vars["scan_id"]
+	}
+	if scanID == "" {
+		scanID = vars["scanID"]
+	}
+
+	rows, err := dbPool.Query(context.Background(), fmt.Sprintf(`SELECT * FROM %s WHERE scan_id = $1`, table), scanID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get scan status: %v", err)
+		http.Error(w, "Failed to get scan status", http.StatusInternalServerError)
+		return
+	}
+	scans, err := rowsToMaps(rows)
+	rows.Close()
+	if err != nil {
+		log.Printf("[ERROR] Failed to get scan status: %v", err)
+		http.Error(w, "Failed to get scan status", http.StatusInternalServerError)
+		return
+	}
+	if len(scans) == 0 {
+		http.Error(w, "Scan not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toolScanResponse(scans[0]))
+}
+
+// GetToolScansForScopeTarget handles GET /scopetarget/{id}/scans/{tool}.
+func GetToolScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	table, ok := scanTableForTool(vars["tool"])
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown tool: %s", vars["tool"]), http.StatusNotFound)
+		return
+	}
+	scopeTargetID := vars["id"]
+	if scopeTargetID == "" {
+		log.Printf("[ERROR] No scope target ID provided")
+		http.Error(w, "No scope target ID provided", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := dbPool.Query(context.Background(),
+		fmt.Sprintf(`SELECT * FROM %s WHERE scope_target_id = $1 ORDER BY created_at DESC`, table), scopeTargetID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get scans: %v", err)
+		http.Error(w, "Failed to get scans", http.StatusInternalServerError)
+		return
+	}
+	scans, err := rowsToMaps(rows)
+	rows.Close()
+	if err != nil {
+		log.Printf("[ERROR] Failed to get scans: %v", err)
+		http.Error(w, "Failed to get scans", http.StatusInternalServerError)
+		return
+	}
+
+	for _, scan := range scans {
+		toolScanResponse(scan)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scans)
+}
+
+// GetTools handles GET /tools and lists the registered tools.
+func GetTools(w http.ResponseWriter, r *http.Request) {
+	list := []map[string]string{}
+	for _, t := range RegisteredTools() {
+		list = append(list, map[string]string{
+			"name":       t.Name(),
+			"input_kind": string(t.InputKind()),
+			"table":      t.Table(),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// exportToolData writes <tool>_data.csv with the common scan columns.
+func exportToolData(zipWriter *zip.Writer, tempDir string, t Tool) error {
+	fileName := fmt.Sprintf("%s_data.csv", strings.ReplaceAll(t.Name(), "-", "_"))
+	path := filepath.Join(tempDir, fileName)
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"Scan ID", "Target", "Result", "Error", "Execution Time", "Command"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	rows, err := dbPool.Query(context.Background(), fmt.Sprintf(`
+		SELECT
+			s.scan_id,
+			st.scope_target,
+			COALESCE(s.result, ''),
+			COALESCE(s.error, ''),
+			COALESCE(s.execution_time, ''),
+			COALESCE(s.command, '')
+		FROM %s s
+		JOIN scope_targets st ON s.scope_target_id = st.id
+		ORDER BY s.scan_id
+	`, t.Table()))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var scanID, target, result, errorMsg, execTime, cmd string
+		if err := rows.Scan(&scanID, &target, &result, &errorMsg, &execTime, &cmd); err != nil {
+			return fmt.Errorf("error scanning %s row: %v", t.Name(), err)
+		}
+		if err := writer.Write([]string{scanID, target, result, errorMsg, execTime, cmd}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return addFileToZip(zipWriter, path, fileName)
+}
+
+// exportNamedToolData exports the registered tool called name.
+func exportNamedToolData(zipWriter *zip.Writer, tempDir, name string) error {
+	t, ok := LookupTool(name)
+	if !ok {
+		return fmt.Errorf("unknown tool %q", name)
+	}
+	return exportToolData(zipWriter, tempDir, t)
+}
+
+// databaseExportQueries returns exportTableQueries plus a query for every
+// registered tool table it does not list, so new tools are included in
+// database exports without editing that map.
+func databaseExportQueries() map[string]string {
+	queries := make(map[string]string, len(exportTableQueries))
+	for table, query := range exportTableQueries {
+		queries[table] = query
+	}
+	for _, t := range RegisteredTools() {
+		if _, ok := queries[t.Table()]; !ok {
+			queries[t.Table()] = fmt.Sprintf(`SELECT * FROM %s WHERE scope_target_id = ANY($1)`, t.Table())
+		}
+	}
+	return queries
+}
+
+// toolBase carries the descriptive half of Tool for tools defined in one
+// file; embedders add BuildArgs and ParseOutput.
+type toolBase struct {
+	name  string
+	input ToolInputKind
+	table string
+}
+
+func (b toolBase) Name() string             { return b.name }
+func (b toolBase) InputKind() ToolInputKind { return b.input }
+func (b toolBase) Table() string            { return b.table }
+
+// PersistResult is a no-op: the scan row already holds the result.
+func (b toolBase) PersistResult(ctx context.Context, scanID string, input ToolInput, result ToolResult) error {
+	return nil
+}
+
+// lineResult turns de-duplicated lines into a ToolResult: "success" with the
+// lines joined, or "completed" when there were none.
+func lineResult(lines []string) ToolResult {
+	if len(lines) == 0 {
+		return ToolResult{Status: "completed"}
+	}
+	return ToolResult{Status: "success", Result: strings.Join(lines, "\n"), Items: lines}
+}
+
+// executorTool adapts an existing Execute* function to Tool.
+type executorTool struct {
+	toolBase
+	targetCol *string
+	schema    string
+	execute   func(ctx context.Context, scanID string, input ToolInput)
+}
+
+func (t executorTool) BuildArgs(input ToolInput) []string { return nil }
+
+func (t executorTool) ParseOutput(input ToolInput, stdout, stderr string) (ToolResult, error) {
+	return ToolResult{}, fmt.Errorf("%s runs its own pipeline", t.name)
+}
+
+func (t executorTool) Execute(ctx context.Context, scanID string, input ToolInput) {
+	t.execute(ctx, scanID, input)
+}
+
+func (t executorTool) TargetColumn() string {
+	if t.targetCol != nil {
+		return *t.targetCol
+	}
+	return defaultToolTargetColumn(t.input)
+}
+
+// executeTool registers one of the engine's original Execute(ctx, scanID,
+// target) functions as a Tool.
+func executeTool(name string, input ToolInputKind, table string, execute func(ctx context.Context, scanID, target string)) executorTool {
+	return executorTool{
+		toolBase: toolBase{name: name, input: input, table: table},
+		execute: func(ctx context.Context, scanID string, in ToolInput) {
+			execute(ctx, scanID, in.Target)
+		},
+	}
+}
+
+// Schema returns the table created by withSchema, if any.
+func (t executorTool) Schema() string { return t.schema }
+
+// withSchema has the engine create the tool's scan table, for tables the
+// Prisma schema does not cover.
+func (t executorTool) withSchema() executorTool {
+	t.schema = scanTableSchema(t.table, t.TargetColumn())
+	return t
+}
+
+// withTargetColumn overrides the target column derived from the input kind;
+// "" means the scan rows only store the scope target ID.
+func (t executorTool) withTargetColumn(col string) executorTool {
+	t.targetCol = &col
+	return t
+}
+
+// payloadExecutorTool is an executorTool whose queued jobs get the whole job
+// payload.
+type payloadExecutorTool struct {
+	executorTool
+	executePayload func(ctx context.Context, scanID string, payload ScanJobPayload)
+}
+
+func (t payloadExecutorTool) ExecutePayload(ctx context.Context, scanID string, payload ScanJobPayload) {
+	t.executePayload(ctx, scanID, payload)
+}
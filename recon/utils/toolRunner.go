@@ -0,0 +1,554 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ToolCommand describes a single invocation of a recon tool. Args is the argv
+// the tool itself sees (starting with the binary name); how the tool is
+// reached (docker exec, local binary, recorded output) is up to the runner.
+// The Stdin/Stdout/Stderr/WaitDelay fields behave like their exec.Cmd
+// counterparts.
+type ToolCommand struct {
+	Tool      string
+	Args      []string
+	Stdin     io.Reader
+	Stdout    io.Writer
+	Stderr    io.Writer
+	WaitDelay time.Duration
+
+	ctx    context.Context
+	runner ToolRunner
+}
+
+// ToolRunner executes recon tools and moves files in and out of the place
+// where the tool runs.
+type ToolRunner interface {
+	Run(ctx context.Context, cmd *ToolCommand) error
+	CopyTo(ctx context.Context, tool, hostPath, toolPath string) error
+	CopyFrom(ctx context.Context, tool, toolPath, hostPath string) error
+	Describe(cmd *ToolCommand) string
+}
+
+var (
+	toolRunnerMu     sync.RWMutex
+	activeToolRunner ToolRunner
+)
+
+// GetToolRunner returns the process-wide runner, building it from the
+// environment on first use.
+func GetToolRunner() ToolRunner {
+	toolRunnerMu.RLock()
+	runner := activeToolRunner
+	toolRunnerMu.RUnlock()
+	if runner != nil {
+		return runner
+	}
+
+	toolRunnerMu.Lock()
+	defer toolRunnerMu.Unlock()
+	if activeToolRunner == nil {
+		activeToolRunner = NewToolRunnerFromEnv()
+	}
+	return activeToolRunner
+}
+
+// SetToolRunner replaces the process-wide runner (used by tests and replays).
+func SetToolRunner(runner ToolRunner) {
+	toolRunnerMu.Lock()
+	activeToolRunner = runner
+	toolRunnerMu.Unlock()
+}
+
+// NewToolRunnerFromEnv picks a runner based on RECON_TOOL_RUNNER
+// ("docker" by default, "local" or "fake").
+func NewToolRunnerFromEnv() ToolRunner {
+	switch strings.ToLower(os.Getenv("RECON_TOOL_RUNNER")) {
+	case "local":
+		log.Printf("[TOOL-RUNNER] [INFO] Using local binary runner")
+		return NewLocalRunner()
+	case "fake":
+		dir := os.Getenv("RECON_FAKE_RECORDINGS_DIR")
+		runner, err := LoadFakeRunner(dir)
+		if err != nil {
+			log.Printf("[TOOL-RUNNER] [ERROR] Failed to load fake recordings from %s: %v", dir, err)
+			return NewFakeRunner()
+		}
+		log.Printf("[TOOL-RUNNER] [INFO] Using fake runner with recordings from %s", dir)
+		return runner
+	default:
+		runner := NewDockerRunner()
+		log.Printf("[TOOL-RUNNER] [INFO] Using docker runner for compose project %s", runner.ProjectName)
+		return runner
+	}
+}
+
+// NewToolCommand builds a command for tool using the process-wide runner.
+func NewToolCommand(tool string, args ...string) *ToolCommand {
+	return NewToolCommandContext(context.Background(), tool, args...)
+}
+
+// NewToolCommandContext is like NewToolCommand but the command is stopped
+// when ctx is done.
+func NewToolCommandContext(ctx context.Context, tool string, args ...string) *ToolCommand {
+	return &ToolCommand{
+		Tool:   tool,
+		Args:   args,
+		ctx:    ctx,
+		runner: GetToolRunner(),
+	}
+}
+
+// CopyToTool copies a host file to toolPath wherever tool runs.
+func CopyToTool(tool, hostPath, toolPath string) error {
+	return GetToolRunner().CopyTo(context.Background(), tool, hostPath, toolPath)
+}
+
+// CopyFromTool copies toolPath from wherever tool runs to a host file.
+func CopyFromTool(tool, toolPath, hostPath string) error {
+	return GetToolRunner().CopyFrom(context.Background(), tool, toolPath, hostPath)
+}
+
+func (c *ToolCommand) Run() error {
+	// Commands run on behalf of a queued scan also feed its live stream.
+	if scanID := scanStreamIDFromContext(c.ctx); scanID != "" {
+		stdout, stderr := c.Stdout, c.Stderr
+		stdoutStream := newScanStreamWriter(scanID, "stdout")
+		stderrStream := newScanStreamWriter(scanID, "stderr")
+		c.Stdout = teeScanStream(stdout, stdoutStream)
+		c.Stderr = teeScanStream(stderr, stderrStream)
+		defer func() {
+			c.Stdout, c.Stderr = stdout, stderr
+			stdoutStream.flush()
+			stderrStream.flush()
+		}()
+	}
+	return c.runner.Run(c.ctx, c)
+}
+
+func (c *ToolCommand) Output() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, fmt.Errorf("tool runner: Stdout already set")
+	}
+	var stdout bytes.Buffer
+	c.Stdout = &stdout
+	err := c.Run()
+	return stdout.Bytes(), err
+}
+
+func (c *ToolCommand) CombinedOutput() ([]byte, error) {
+	if c.Stdout != nil || c.Stderr != nil {
+		return nil, fmt.Errorf("tool runner: Stdout or Stderr already set")
+	}
+	var combined bytes.Buffer
+	c.Stdout = &combined
+	c.Stderr = &combined
+	err := c.Run()
+	return combined.Bytes(), err
+}
+
+// String returns the command line as it is actually executed, which is what
+// gets stored in the scan tables' command column.
+func (c *ToolCommand) String() string {
+	return c.runner.Describe(c)
+}
+
+func runExecCommand(cmd *exec.Cmd, tc *ToolCommand) error {
+	cmd.Stdin = tc.Stdin
+	cmd.Stdout = tc.Stdout
+	cmd.Stderr = tc.Stderr
+	if tc.WaitDelay != 0 {
+		cmd.WaitDelay = tc.WaitDelay
+	}
+	return cmd.Run()
+}
+
+func commandContext(tc *ToolCommand) context.Context {
+	if tc.ctx != nil {
+		return tc.ctx
+	}
+	return context.Background()
+}
+
+// DockerRunner reaches tools through the docker CLI. Long-lived tools are
+// expected to run as containers named by ContainerFormat (compose's
+// <project>-<tool>-1 by default); tools listed in Images are started as
+// throwaway containers with docker run --rm.
+type DockerRunner struct {
+	ProjectName     string
+	ContainerFormat string
+	Containers      map[string]string
+	Images          map[string]string
+}
+
+// Default images for tools that are not part of the compose project.
+var defaultToolImages = map[string]string{
+	"amass": "caffix/amass",
+	"gau":   "sxcurity/gau:latest",
+}
+
+// NewDockerRunner reads COMPOSE_PROJECT_NAME for the container prefix,
+// RECON_TOOL_CONTAINER_FORMAT (a fmt pattern taking the tool name, e.g.
+// "asm-hawk-%s") for fixed container_name setups, and
+// RECON_TOOL_CONTAINER_<TOOL> / RECON_TOOL_IMAGE_<TOOL> for per-tool overrides.
+func NewDockerRunner() *DockerRunner {
+	project := os.Getenv("COMPOSE_PROJECT_NAME")
+	if project == "" {
+		project = "ars0n-framework-v2"
+	}
+	format := os.Getenv("RECON_TOOL_CONTAINER_FORMAT")
+	if format == "" {
+		format = project + "-%s-1"
+	}
+
+	runner := &DockerRunner{
+		ProjectName:     project,
+		ContainerFormat: format,
+		Containers:      make(map[string]string),
+		Images:          make(map[string]string),
+	}
+	for tool, image := range defaultToolImages {
+		runner.Images[tool] = image
+	}
+
+	for _, kv := range os.Environ() {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || value == "" {
+			continue
+		}
+		switch {
+		case key == "RECON_TOOL_CONTAINER_FORMAT":
+		case strings.HasPrefix(key, "RECON_TOOL_CONTAINER_"):
+			runner.Containers[toolFromEnvKey(key, "RECON_TOOL_CONTAINER_")] = value
+		case strings.HasPrefix(key, "RECON_TOOL_IMAGE_"):
+			runner.Images[toolFromEnvKey(key, "RECON_TOOL_IMAGE_")] = value
+		}
+	}
+
+	return runner
+}
+
+// toolFromEnvKey maps RECON_TOOL_CONTAINER_GITHUB_RECON to github-recon. The
+// cloud_enum service keeps its underscore, so it is special-cased.
+func toolFromEnvKey(key, prefix string) string {
+	tool := strings.ToLower(strings.TrimPrefix(key, prefix))
+	if tool == "cloud_enum" {
+		return tool
+	}
+	return strings.ReplaceAll(tool, "_", "-")
+}
+
+// ContainerName returns the container a tool is exec'd in.
+func (d *DockerRunner) ContainerName(tool string) string {
+	if name, ok := d.Containers[tool]; ok {
+		return name
+	}
+	return fmt.Sprintf(d.ContainerFormat, tool)
+}
+
+func (d *DockerRunner) dockerArgs(cmd *ToolCommand) []string {
+	var args []string
+	if image, ok := d.Images[cmd.Tool]; ok {
+		args = []string{"run", "--rm"}
+		if cmd.Stdin != nil {
+			args = append(args, "-i")
+		}
+		args = append(args, image)
+		// Images already have the tool as their entrypoint.
+		if len(cmd.Args) > 0 && cmd.Args[0] == cmd.Tool {
+			return append(args, cmd.Args[1:]...)
+		}
+		return append(args, cmd.Args...)
+	}
+
+	args = []string{"exec"}
+	if cmd.Stdin != nil {
+		args = append(args, "-i")
+	}
+	args = append(args, d.ContainerName(cmd.Tool))
+	return append(args, cmd.Args...)
+}
+
+// Run executes cmd through docker. Killing the docker CLI does not stop what
+// it started, so cancellable commands are tagged first: image tools get a
+// container name to docker kill, exec'd tools run under a shell that records
+// its pid so the whole process tree inside the container can be killed.
+func (d *DockerRunner) Run(ctx context.Context, cmd *ToolCommand) error {
+	if ctx.Done() == nil {
+		return runExecCommand(exec.Command("docker", d.dockerArgs(cmd)...), cmd)
+	}
+
+	handle := "recon-" + strings.ReplaceAll(uuid.New().String(), "-", "")[:16]
+	var args []string
+	var kill func() error
+
+	if _, ok := d.Images[cmd.Tool]; ok {
+		args = append([]string{"run", "--name", handle}, d.dockerArgs(cmd)[1:]...)
+		kill = func() error {
+			return exec.Command("docker", "kill", handle).Run()
+		}
+	} else {
+		container := d.ContainerName(cmd.Tool)
+		pidFile := "/tmp/." + handle + ".pid"
+		args = []string{"exec"}
+		if cmd.Stdin != nil {
+			args = append(args, "-i")
+		}
+		args = append(args, container, "sh", "-c",
+			fmt.Sprintf(`echo $$ > %s; "$@"; rc=$?; rm -f %s; exit $rc`, pidFile, pidFile), "sh")
+		args = append(args, cmd.Args...)
+		kill = func() error {
+			return exec.Command("docker", "exec", container, "sh", "-c", killTreeScript(pidFile)).Run()
+		}
+	}
+
+	c := exec.CommandContext(ctx, "docker", args...)
+	c.Cancel = func() error {
+		log.Printf("[TOOL-RUNNER] [INFO] Cancelling %s (%s)", cmd.Tool, handle)
+		if err := kill(); err != nil {
+			log.Printf("[TOOL-RUNNER] [WARN] Failed to kill %s inside docker: %v", handle, err)
+		}
+		return c.Process.Kill()
+	}
+	if cmd.WaitDelay == 0 {
+		c.WaitDelay = 10 * time.Second
+	}
+	return runExecCommand(c, cmd)
+}
+
+// killTreeScript kills the process recorded in pidFile and all of its
+// descendants, children first.
+func killTreeScript(pidFile string) string {
+	return fmt.Sprintf(`pid=$(cat %s 2>/dev/null) || exit 0
+kill_tree() {
+	for child in $(cat /proc/$1/task/*/children 2>/dev/null); do kill_tree "$child"; done
+	kill -TERM "$1" 2>/dev/null
+}
+kill_tree "$pid"
+rm -f %s`, pidFile, pidFile)
+}
+
+func (d *DockerRunner) CopyTo(ctx context.Context, tool, hostPath, toolPath string) error {
+	return exec.CommandContext(ctx, "docker", "cp", hostPath, d.ContainerName(tool)+":"+toolPath).Run()
+}
+
+func (d *DockerRunner) CopyFrom(ctx context.Context, tool, toolPath, hostPath string) error {
+	return exec.CommandContext(ctx, "docker", "cp", d.ContainerName(tool)+":"+toolPath, hostPath).Run()
+}
+
+func (d *DockerRunner) Describe(cmd *ToolCommand) string {
+	return "docker " + strings.Join(d.dockerArgs(cmd), " ")
+}
+
+// LocalRunner runs tools straight from PATH on the engine host. Paths passed
+// in Args are used as-is, so container-only paths such as /app/wordlists must
+// exist on the host too.
+type LocalRunner struct {
+	// Binaries maps a tool to the executable to run when it differs from Args[0].
+	Binaries map[string]string
+}
+
+func NewLocalRunner() *LocalRunner {
+	return &LocalRunner{Binaries: make(map[string]string)}
+}
+
+func (l *LocalRunner) argv(cmd *ToolCommand) []string {
+	if len(cmd.Args) == 0 {
+		return []string{cmd.Tool}
+	}
+	argv := append([]string(nil), cmd.Args...)
+	if binary, ok := l.Binaries[cmd.Tool]; ok && argv[0] == cmd.Tool {
+		argv[0] = binary
+	}
+	return argv
+}
+
+func (l *LocalRunner) Run(ctx context.Context, cmd *ToolCommand) error {
+	argv := l.argv(cmd)
+	c := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	killProcessGroupOnCancel(c)
+	return runExecCommand(c, cmd)
+}
+
+func (l *LocalRunner) CopyTo(ctx context.Context, tool, hostPath, toolPath string) error {
+	return copyLocalFile(hostPath, toolPath)
+}
+
+func (l *LocalRunner) CopyFrom(ctx context.Context, tool, toolPath, hostPath string) error {
+	return copyLocalFile(toolPath, hostPath)
+}
+
+func (l *LocalRunner) Describe(cmd *ToolCommand) string {
+	return strings.Join(l.argv(cmd), " ")
+}
+
+func copyLocalFile(src, dst string) error {
+	if filepath.Clean(src) == filepath.Clean(dst) {
+		return nil
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
+
+// FakeRecording is one canned tool response. A recording matches a command
+// when Tool is equal and every entry of ArgsContain appears in the argv.
+// Files are made available to CopyFrom once the recording has been replayed.
+type FakeRecording struct {
+	Tool        string            `json:"tool"`
+	ArgsContain []string          `json:"args_contain,omitempty"`
+	Stdout      string            `json:"stdout"`
+	Stderr      string            `json:"stderr,omitempty"`
+	ExitCode    int               `json:"exit_code,omitempty"`
+	Files       map[string]string `json:"files,omitempty"`
+}
+
+// FakeRunner replays recorded tool output in-process so parsers can be
+// exercised without any containers.
+type FakeRunner struct {
+	mu         sync.Mutex
+	recordings []FakeRecording
+	files      map[string][]byte
+	Calls      []ToolCommand
+}
+
+// FakeExitError is returned when a recording has a non-zero exit code.
+type FakeExitError struct {
+	Tool     string
+	ExitCode int
+}
+
+func (e *FakeExitError) Error() string {
+	return fmt.Sprintf("%s: exit status %d", e.Tool, e.ExitCode)
+}
+
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{files: make(map[string][]byte)}
+}
+
+// LoadFakeRunner reads every *.json file in dir; each file holds a JSON array
+// of FakeRecording entries.
+func LoadFakeRunner(dir string) (*FakeRunner, error) {
+	runner := NewFakeRunner()
+	if dir == "" {
+		return runner, fmt.Errorf("no recordings directory configured")
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return runner, err
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return runner, err
+		}
+		var recordings []FakeRecording
+		if err := json.Unmarshal(data, &recordings); err != nil {
+			return runner, fmt.Errorf("%s: %v", path, err)
+		}
+		for _, rec := range recordings {
+			runner.Record(rec)
+		}
+	}
+	return runner, nil
+}
+
+// Record adds a canned response. Earlier recordings win over later ones.
+func (f *FakeRunner) Record(rec FakeRecording) {
+	f.mu.Lock()
+	f.recordings = append(f.recordings, rec)
+	f.mu.Unlock()
+}
+
+func (f *FakeRunner) match(cmd *ToolCommand) (FakeRecording, bool) {
+	joined := strings.Join(cmd.Args, " ")
+	for _, rec := range f.recordings {
+		if rec.Tool != cmd.Tool {
+			continue
+		}
+		matched := true
+		for _, want := range rec.ArgsContain {
+			if !strings.Contains(joined, want) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return rec, true
+		}
+	}
+	return FakeRecording{}, false
+}
+
+func (f *FakeRunner) Run(ctx context.Context, cmd *ToolCommand) error {
+	f.mu.Lock()
+	f.Calls = append(f.Calls, ToolCommand{Tool: cmd.Tool, Args: append([]string(nil), cmd.Args...)})
+	rec, ok := f.match(cmd)
+	if ok {
+		for path, content := range rec.Files {
+			f.files[cmd.Tool+":"+path] = []byte(content)
+		}
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("fake runner: no recording for %s %s", cmd.Tool, strings.Join(cmd.Args, " "))
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if cmd.Stdout != nil {
+		io.WriteString(cmd.Stdout, rec.Stdout)
+	}
+	if cmd.Stderr != nil {
+		io.WriteString(cmd.Stderr, rec.Stderr)
+	}
+	if rec.ExitCode != 0 {
+		return &FakeExitError{Tool: cmd.Tool, ExitCode: rec.ExitCode}
+	}
+	return nil
+}
+
+func (f *FakeRunner) CopyTo(ctx context.Context, tool, hostPath, toolPath string) error {
+	data, err := os.ReadFile(hostPath)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.files[tool+":"+toolPath] = data
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *FakeRunner) CopyFrom(ctx context.Context, tool, toolPath, hostPath string) error {
+	f.mu.Lock()
+	data, ok := f.files[tool+":"+toolPath]
+	f.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("fake runner: %s has no file %s", tool, toolPath)
+	}
+	return os.WriteFile(hostPath, data, 0644)
+}
+
+func (f *FakeRunner) Describe(cmd *ToolCommand) string {
+	return "fake " + cmd.Tool + ": " + strings.Join(cmd.Args, " ")
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package utils
+
+import "os/exec"
+
+// killProcessGroupOnCancel falls back to exec's default of killing only the
+// direct child.
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// useFakeRunner installs runner as the process-wide runner for the test.
+func useFakeRunner(t *testing.T, runner *FakeRunner) {
+	t.Helper()
+	toolRunnerMu.RLock()
+	previous := activeToolRunner
+	toolRunnerMu.RUnlock()
+	SetToolRunner(runner)
+	t.Cleanup(func() { SetToolRunner(previous) })
+}
+
+func TestFakeRunnerReplaysToolOutput(t *testing.T) {
+	runner := NewFakeRunner()
+	runner.Record(FakeRecording{Tool: "subfinder", ArgsContain: []string{"-d example.com"},
+		Stdout: "b.example.com\n\na.example.com\n  b.example.com  \n"})
+	runner.Record(FakeRecording{Tool: "assetfinder", ArgsContain: []string{"example.com"}, Stdout: ""})
+	runner.Record(FakeRecording{Tool: "sublist3r", ArgsContain: []string{"-d example.com"},
+		Stdout: "\x1b[91m                 ____        _     _ _     _   _____\x1b[0m\n" +
+			"[-] Enumerating subdomains now for example.com\n" +
+			"\x1b[92mwww.example.com\x1b[0m\n" +
+			"SSL Certificates: mail.example.com\n" +
+			"other.test\n" +
+			"[-] Total Unique Subdomains Found: 2\n"})
+	useFakeRunner(t, runner)
+
+	tests := []struct {
+		tool string
+		want []string
+	}{
+		{tool: "subfinder", want: []string{"a.example.com", "b.example.com"}},
+		{tool: "assetfinder", want: nil},
+		{tool: "sublist3r", want: []string{"mail.example.com", "www.example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			tool, ok := LookupTool(tt.tool)
+			if !ok {
+				t.Fatalf("tool %s is not registered", tt.tool)
+			}
+			input := ToolInput{Target: "example.com"}
+			stdout, err := NewToolCommand(tool.Name(), tool.BuildArgs(input)...).Output()
+			if err != nil {
+				t.Fatalf("Output() error = %v", err)
+			}
+			result, err := tool.ParseOutput(input, string(stdout), "")
+			if err != nil {
+				t.Fatalf("ParseOutput() error = %v", err)
+			}
+			if !reflect.DeepEqual(result.Items, tt.want) {
+				t.Errorf("ParseOutput() items = %q, want %q", result.Items, tt.want)
+			}
+		})
+	}
+
+	if len(runner.Calls) != len(tests) {
+		t.Errorf("runner saw %d calls, want %d", len(runner.Calls), len(tests))
+	}
+}
+
+func TestFakeRunnerFailures(t *testing.T) {
+	runner := NewFakeRunner()
+	runner.Record(FakeRecording{Tool: "subfinder", Stderr: "rate limited", ExitCode: 2})
+	useFakeRunner(t, runner)
+
+	cmd := NewToolCommand("subfinder", "subfinder", "-d", "example.com")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	_, err := cmd.Output()
+	var exitErr *FakeExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode != 2 {
+		t.Fatalf("Output() error = %v, want exit status 2", err)
+	}
+	if stderr.String() != "rate limited" {
+		t.Errorf("stderr = %q, want %q", stderr.String(), "rate limited")
+	}
+
+	if err := NewToolCommand("amass", "amass", "enum").Run(); err == nil || !strings.Contains(err.Error(), "no recording") {
+		t.Errorf("Run() unrecorded tool error = %v, want no recording", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := NewToolCommandContext(ctx, "subfinder", "subfinder").Run(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() cancelled error = %v, want context.Canceled", err)
+	}
+}
+
+func TestFakeRunnerFiles(t *testing.T) {
+	runner := NewFakeRunner()
+	runner.Record(FakeRecording{Tool: "nuclei", ArgsContain: []string{"-o /output.jsonl"},
+		Files: map[string]string{"/output.jsonl": "{\"template-id\":\"tech-detect\"}\n"}})
+	useFakeRunner(t, runner)
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "output.jsonl")
+	if err := CopyFromTool("nuclei", "/output.jsonl", out); err == nil {
+		t.Fatal("CopyFromTool() before the run succeeded, want error")
+	}
+	if err := NewToolCommand("nuclei", "nuclei", "-l", "/targets.txt", "-o", "/output.jsonl").Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if err := CopyFromTool("nuclei", "/output.jsonl", out); err != nil {
+		t.Fatalf("CopyFromTool() error = %v", err)
+	}
+	if data, _ := os.ReadFile(out); string(data) != "{\"template-id\":\"tech-detect\"}\n" {
+		t.Errorf("copied file = %q", data)
+	}
+
+	in := filepath.Join(dir, "targets.txt")
+	os.WriteFile(in, []byte("https://example.com\n"), 0644)
+	if err := CopyToTool("nuclei", in, "/targets.txt"); err != nil {
+		t.Fatalf("CopyToTool() error = %v", err)
+	}
+	if err := CopyFromTool("nuclei", "/targets.txt", filepath.Join(dir, "back.txt")); err != nil {
+		t.Fatalf("CopyFromTool() round trip error = %v", err)
+	}
+}
+
+func TestLoadFakeRunner(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "subfinder.json"),
+		[]byte(`[{"tool":"subfinder","args_contain":["example.com"],"stdout":"a.example.com\n"}]`), 0644)
+	runner, err := LoadFakeRunner(dir)
+	if err != nil {
+		t.Fatalf("LoadFakeRunner() error = %v", err)
+	}
+	useFakeRunner(t, runner)
+	if out, err := NewToolCommand("subfinder", "subfinder", "-d", "example.com").Output(); err != nil || string(out) != "a.example.com\n" {
+		t.Errorf("Output() = %q, %v", out, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"tool":`), 0644)
+	if _, err := LoadFakeRunner(dir); err == nil || !strings.Contains(err.Error(), "broken.json") {
+		t.Errorf("LoadFakeRunner() malformed error = %v, want it to name broken.json", err)
+	}
+	if _, err := LoadFakeRunner(""); err == nil {
+		t.Error("LoadFakeRunner(\"\") succeeded, want error")
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os/exec"
 	"strings"
 	"time"
 
@@ -44,19 +43,21 @@ func RunKatanaURLScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteAndParseKatanaURLScan(scanID, targetURL)
+	if _, err := EnqueueScanJob("katana-url", scanID, ScanJobPayload{Target: targetURL}); err != nil {
+		log.Printf("[ERROR] Failed to queue katana-url scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteAndParseKatanaURLScan(scanID, targetURL string) {
+func ExecuteAndParseKatanaURLScan(ctx context.Context, scanID, targetURL string) {
 	log.Printf("[INFO] Starting Katana URL scan for %s (scan ID: %s)", targetURL, scanID)
 	startTime := time.Now()
 
-	dockerCmd := []string{
-		"docker", "exec",
-		"ars0n-framework-v2-katana-1",
+	toolArgs := []string{
 		"katana",
 		"-u", targetURL,
 		"-d", "5",
@@ -67,8 +68,8 @@ func ExecuteAndParseKatanaURLScan(scanID, targetURL string) {
 		"-p", "15",
 	}
 
-	cmd := exec.Command(dockerCmd[0], dockerCmd[1:]...)
-	log.Printf("[INFO] Executing command: %s", strings.Join(dockerCmd, " "))
+	cmd := NewToolCommandContext(ctx, "katana", toolArgs...)
+	log.Printf("[INFO] Executing command: %s", cmd.String())
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -80,7 +81,7 @@ func ExecuteAndParseKatanaURLScan(scanID, targetURL string) {
 	if err != nil {
 		log.Printf("[ERROR] Katana URL scan failed for %s: %v", targetURL, err)
 		log.Printf("[ERROR] stderr output: %s", stderr.String())
-		UpdateKatanaURLScanStatus(scanID, "error", "", stderr.String(), strings.Join(dockerCmd, " "), execTime)
+		UpdateKatanaURLScanStatus(scanID, "error", "", stderr.String(), cmd.String(), execTime)
 		return
 	}
 
@@ -88,7 +89,7 @@ func ExecuteAndParseKatanaURLScan(scanID, targetURL string) {
 	log.Printf("[INFO] Katana URL scan completed in %s for %s", execTime, targetURL)
 	log.Printf("[DEBUG] Found %d URLs", len(strings.Split(result, "\n")))
 
-	UpdateKatanaURLScanStatus(scanID, "success", result, "", strings.Join(dockerCmd, " "), execTime)
+	UpdateKatanaURLScanStatus(scanID, "success", result, "", cmd.String(), execTime)
 }
 
 func UpdateKatanaURLScanStatus(scanID, status, result, errorMsg, command, execTime string) {
@@ -210,26 +211,28 @@ func RunLinkFinderURLScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteAndParseLinkFinderURLScan(scanID, targetURL)
+	if _, err := EnqueueScanJob("linkfinder-url", scanID, ScanJobPayload{Target: targetURL}); err != nil {
+		log.Printf("[ERROR] Failed to queue linkfinder-url scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteAndParseLinkFinderURLScan(scanID, targetURL string) {
+func ExecuteAndParseLinkFinderURLScan(ctx context.Context, scanID, targetURL string) {
 	log.Printf("[INFO] Starting LinkFinder URL scan for %s (scan ID: %s)", targetURL, scanID)
 	startTime := time.Now()
 
-	dockerCmd := []string{
-		"docker", "exec",
-		"ars0n-framework-v2-linkfinder-1",
+	toolArgs := []string{
 		"python3", "linkfinder.py",
 		"-i", targetURL,
 		"-o", "cli",
 	}
 
-	cmd := exec.Command(dockerCmd[0], dockerCmd[1:]...)
-	log.Printf("[INFO] Executing command: %s", strings.Join(dockerCmd, " "))
+	cmd := NewToolCommandContext(ctx, "linkfinder", toolArgs...)
+	log.Printf("[INFO] Executing command: %s", cmd.String())
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -241,14 +244,14 @@ func ExecuteAndParseLinkFinderURLScan(scanID, targetURL string) {
 	if err != nil {
 		log.Printf("[ERROR] LinkFinder URL scan failed for %s: %v", targetURL, err)
 		log.Printf("[ERROR] stderr output: %s", stderr.String())
-		UpdateLinkFinderURLScanStatus(scanID, "error", "", stderr.String(), strings.Join(dockerCmd, " "), execTime)
+		UpdateLinkFinderURLScanStatus(scanID, "error", "", stderr.String(), cmd.String(), execTime)
 		return
 	}
 
 	result := stdout.String()
 	log.Printf("[INFO] LinkFinder URL scan completed in %s for %s", execTime, targetURL)
 
-	UpdateLinkFinderURLScanStatus(scanID, "success", result, "", strings.Join(dockerCmd, " "), execTime)
+	UpdateLinkFinderURLScanStatus(scanID, "success", result, "", cmd.String(), execTime)
 }
 
 func UpdateLinkFinderURLScanStatus(scanID, status, result, errorMsg, command, execTime string) {
@@ -370,25 +373,27 @@ func RunWaybackURLsScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteAndParseWaybackURLsScan(scanID, targetURL)
+	if _, err := EnqueueScanJob("waybackurls", scanID, ScanJobPayload{Target: targetURL}); err != nil {
+		log.Printf("[ERROR] Failed to queue waybackurls scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteAndParseWaybackURLsScan(scanID, targetURL string) {
+func ExecuteAndParseWaybackURLsScan(ctx context.Context, scanID, targetURL string) {
 	log.Printf("[INFO] Starting WaybackURLs scan for %s (scan ID: %s)", targetURL, scanID)
 	startTime := time.Now()
 
-	dockerCmd := []string{
-		"docker", "exec",
-		"ars0n-framework-v2-waybackurls-1",
+	toolArgs := []string{
 		"waybackurls",
 		targetURL,
 	}
 
-	cmd := exec.Command(dockerCmd[0], dockerCmd[1:]...)
-	log.Printf("[INFO] Executing command: %s", strings.Join(dockerCmd, " "))
+	cmd := NewToolCommandContext(ctx, "waybackurls", toolArgs...)
+	log.Printf("[INFO] Executing command: %s", cmd.String())
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -400,7 +405,7 @@ func ExecuteAndParseWaybackURLsScan(scanID, targetURL string) {
 	if err != nil {
 		log.Printf("[ERROR] WaybackURLs scan failed for %s: %v", targetURL, err)
 		log.Printf("[ERROR] stderr output: %s", stderr.String())
-		UpdateWaybackURLsScanStatus(scanID, "error", "", stderr.String(), strings.Join(dockerCmd, " "), execTime)
+		UpdateWaybackURLsScanStatus(scanID, "error", "", stderr.String(), cmd.String(), execTime)
 		return
 	}
 
@@ -408,7 +413,7 @@ func ExecuteAndParseWaybackURLsScan(scanID, targetURL string) {
 	log.Printf("[INFO] WaybackURLs scan completed in %s for %s", execTime, targetURL)
 	log.Printf("[DEBUG] Found %d URLs", len(strings.Split(result, "\n")))
 
-	UpdateWaybackURLsScanStatus(scanID, "success", result, "", strings.Join(dockerCmd, " "), execTime)
+	UpdateWaybackURLsScanStatus(scanID, "success", result, "", cmd.String(), execTime)
 }
 
 func UpdateWaybackURLsScanStatus(scanID, status, result, errorMsg, command, execTime string) {
@@ -530,30 +535,33 @@ func RunGAUURLScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteAndParseGAUURLScan(scanID, targetURL)
+	if _, err := EnqueueScanJob("gau-url", scanID, ScanJobPayload{Target: targetURL}); err != nil {
+		log.Printf("[ERROR] Failed to queue gau-url scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteAndParseGAUURLScan(scanID, targetURL string) {
+func ExecuteAndParseGAUURLScan(ctx context.Context, scanID, targetURL string) {
 	log.Printf("[INFO] Starting GAU URL scan for %s (scan ID: %s)", targetURL, scanID)
 	startTime := time.Now()
 
 	domain := strings.TrimPrefix(strings.TrimPrefix(targetURL, "https://"), "http://")
 	domain = strings.Split(domain, "/")[0]
 
-	dockerCmd := []string{
-		"docker", "run", "--rm",
-		"sxcurity/gau:latest",
+	toolArgs := []string{
+		"gau",
 		domain,
 		"--providers", "wayback,commoncrawl,otx",
 		"--json",
 		"--threads", "10",
 	}
 
-	cmd := exec.Command(dockerCmd[0], dockerCmd[1:]...)
-	log.Printf("[INFO] Executing command: %s", strings.Join(dockerCmd, " "))
+	cmd := NewToolCommandContext(ctx, "gau", toolArgs...)
+	log.Printf("[INFO] Executing command: %s", cmd.String())
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -565,7 +573,7 @@ func ExecuteAndParseGAUURLScan(scanID, targetURL string) {
 	if err != nil {
 		log.Printf("[ERROR] GAU URL scan failed for %s: %v", targetURL, err)
 		log.Printf("[ERROR] stderr output: %s", stderr.String())
-		UpdateGAUURLScanStatus(scanID, "error", "", stderr.String(), strings.Join(dockerCmd, " "), execTime)
+		UpdateGAUURLScanStatus(scanID, "error", "", stderr.String(), cmd.String(), execTime)
 		return
 	}
 
@@ -573,7 +581,7 @@ func ExecuteAndParseGAUURLScan(scanID, targetURL string) {
 	log.Printf("[INFO] GAU URL scan completed in %s for %s", execTime, targetURL)
 	log.Printf("[DEBUG] Found %d URLs", len(strings.Split(result, "\n")))
 
-	UpdateGAUURLScanStatus(scanID, "success", result, "", strings.Join(dockerCmd, " "), execTime)
+	UpdateGAUURLScanStatus(scanID, "success", result, "", cmd.String(), execTime)
 }
 
 func UpdateGAUURLScanStatus(scanID, status, result, errorMsg, command, execTime string) {
@@ -697,13 +705,17 @@ func RunFFUFURLScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteAndParseFFUFURLScan(scanID, targetURL, scopeTargetID)
+	if _, err := EnqueueScanJob("ffuf-url", scanID, ScanJobPayload{Target: targetURL, ScopeTargetID: scopeTargetID}); err != nil {
+		log.Printf("[ERROR] Failed to queue ffuf-url scan: %v", err)
+		http.Error(w, "Failed to queue scan.", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteAndParseFFUFURLScan(scanID, targetURL, scopeTargetID string) {
+func ExecuteAndParseFFUFURLScan(ctx context.Context, scanID, targetURL, scopeTargetID string) {
 	log.Printf("[FFUF-URL] Starting FFUF URL scan for %s (scan ID: %s)", targetURL, scanID)
 	startTime := time.Now()
 
@@ -743,9 +755,7 @@ func ExecuteAndParseFFUFURLScan(scanID, targetURL, scopeTargetID string) {
 		fuzzyURL = strings.TrimSuffix(fuzzyURL, "/") + "/FUZZ"
 	}
 
-	dockerCmd := []string{
-		"docker", "exec",
-		"ars0n-framework-v2-ffuf-1",
+	toolArgs := []string{
 		"ffuf",
 		"-w", wordlistPath,
 		"-u", fuzzyURL,
@@ -759,8 +769,8 @@ func ExecuteAndParseFFUFURLScan(scanID, targetURL, scopeTargetID string) {
 		"-timeout", "30",
 	}
 
-	cmd := exec.Command(dockerCmd[0], dockerCmd[1:]...)
-	log.Printf("[FFUF-URL] Executing command: %s", strings.Join(dockerCmd, " "))
+	cmd := NewToolCommandContext(ctx, "ffuf", toolArgs...)
+	log.Printf("[FFUF-URL] Executing command: %s", cmd.String())
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -772,15 +782,15 @@ func ExecuteAndParseFFUFURLScan(scanID, targetURL, scopeTargetID string) {
 	if err != nil {
 		log.Printf("[FFUF-URL] FFUF URL scan failed for %s: %v", targetURL, err)
 		log.Printf("[FFUF-URL] stderr output: %s", stderr.String())
-		UpdateFFUFURLScanStatus(scanID, "error", "", stderr.String(), strings.Join(dockerCmd, " "), execTime)
+		UpdateFFUFURLScanStatus(scanID, "error", "", stderr.String(), cmd.String(), execTime)
 		return
 	}
 
-	outputCmd := exec.Command("docker", "exec", "ars0n-framework-v2-ffuf-1", "cat", "/tmp/ffuf-output.json")
+	outputCmd := NewToolCommandContext(ctx, "ffuf", "cat", "/tmp/ffuf-output.json")
 	resultBytes, err := outputCmd.Output()
 	if err != nil {
 		log.Printf("[FFUF-URL] Failed to read FFUF results file: %v", err)
-		UpdateFFUFURLScanStatus(scanID, "error", "", "Failed to read results file", strings.Join(dockerCmd, " "), execTime)
+		UpdateFFUFURLScanStatus(scanID, "error", "", "Failed to read results file", cmd.String(), execTime)
 		return
 	}
 
@@ -796,7 +806,7 @@ func ExecuteAndParseFFUFURLScan(scanID, targetURL, scopeTargetID string) {
 
 	if err := json.Unmarshal(resultBytes, &ffufOutput); err != nil {
 		log.Printf("[FFUF-URL] Failed to parse FFUF results JSON: %v", err)
-		UpdateFFUFURLScanStatus(scanID, "error", "", "Failed to parse results JSON", strings.Join(dockerCmd, " "), execTime)
+		UpdateFFUFURLScanStatus(scanID, "error", "", "Failed to parse results JSON", cmd.String(), execTime)
 		return
 	}
 
@@ -820,7 +830,7 @@ func ExecuteAndParseFFUFURLScan(scanID, targetURL, scopeTargetID string) {
 	log.Printf("[FFUF-URL] FFUF URL scan completed in %s for %s", execTime, targetURL)
 	log.Printf("[FFUF-URL] Found %d endpoints", len(endpoints))
 
-	UpdateFFUFURLScanStatus(scanID, "success", string(resultJSON), "", strings.Join(dockerCmd, " "), execTime)
+	UpdateFFUFURLScanStatus(scanID, "success", string(resultJSON), "", cmd.String(), execTime)
 }
 
 func UpdateFFUFURLScanStatus(scanID, status, result, errorMsg, command, execTime string) {
@@ -0,0 +1,540 @@
+package utils
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Vendor attribution maps the DNS of a scope target's FQDN assets to the
+// SaaS vendors behind it, using the signatures in saas_vendors.json: CNAME
+// targets, MX hosts, NS hosts and SPF includes match on a domain suffix (or
+// a path.Match glob when they contain "*"), TXT records on a prefix such as
+// "google-site-verification=". Records stored by FQDN enrichment are used
+// where present; other FQDNs are looked up. Each vendor becomes a 'vendor'
+// asset linked to the FQDNs that use it. RECON_VENDOR_SIGNATURES points at
+// a replacement file.
+
+//go:embed data/saas_vendors.json
+var defaultVendorSignatures []byte
+
+const vendorAttributionConcurrency = 20
+
+type VendorSignature struct {
+	Vendor   string   `json:"vendor"`
+	Category string   `json:"category"`
+	Website  string   `json:"website,omitempty"`
+	CNAME    []string `json:"cname,omitempty"`
+	MX       []string `json:"mx,omitempty"`
+	NS       []string `json:"ns,omitempty"`
+	SPF      []string `json:"spf,omitempty"`
+	TXT      []string `json:"txt,omitempty"`
+}
+
+type VendorEvidence struct {
+	FQDN  string `json:"fqdn"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type VendorInventoryItem struct {
+	Vendor    string           `json:"vendor"`
+	Category  string           `json:"category"`
+	Website   string           `json:"website,omitempty"`
+	FQDNCount int              `json:"fqdn_count"`
+	Evidence  []VendorEvidence `json:"evidence"`
+	FirstSeen time.Time        `json:"first_seen"`
+	LastSeen  time.Time        `json:"last_seen"`
+}
+
+// fqdnDNSEvidence is the DNS of one FQDN that signatures are matched on.
+type fqdnDNSEvidence struct {
+	assetID string
+	fqdn    string
+	records map[string][]string // cname, mx, ns, spf, txt
+}
+
+var (
+	vendorSignaturesOnce sync.Once
+	vendorSignatures     []VendorSignature
+	vendorAttributionRun sync.Map
+)
+
+// loadVendorSignatures reads the signature file once, falling back to the
+// embedded copy if RECON_VENDOR_SIGNATURES is unset or unreadable.
+func loadVendorSignatures() []VendorSignature {
+	vendorSignaturesOnce.Do(func() {
+		data := defaultVendorSignatures
+		if file := os.Getenv("RECON_VENDOR_SIGNATURES"); file != "" {
+			if custom, err := os.ReadFile(file); err != nil {
+				log.Printf("[VENDORS] [ERROR] Failed to read %s, using built-in signatures: %v", file, err)
+			} else {
+				data = custom
+			}
+		}
+		if err := json.Unmarshal(data, &vendorSignatures); err != nil {
+			log.Printf("[VENDORS] [ERROR] Failed to parse vendor signatures: %v", err)
+			json.Unmarshal(defaultVendorSignatures, &vendorSignatures)
+		}
+		log.Printf("[VENDORS] [INFO] Loaded %d vendor signatures", len(vendorSignatures))
+	})
+	return vendorSignatures
+}
+
+func createVendorTables() {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS vendor_inventory (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			vendor TEXT NOT NULL,
+			category TEXT,
+			website TEXT,
+			fqdn_count INTEGER NOT NULL DEFAULT 0,
+			first_seen TIMESTAMP DEFAULT NOW(),
+			last_seen TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scope_target_id, vendor)
+		);`,
+		`CREATE TABLE IF NOT EXISTS vendor_evidence (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			vendor TEXT NOT NULL,
+			fqdn TEXT NOT NULL,
+			evidence_type VARCHAR(10) NOT NULL,
+			evidence_value TEXT NOT NULL,
+			first_seen TIMESTAMP DEFAULT NOW(),
+			last_seen TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scope_target_id, vendor, fqdn, evidence_type, evidence_value)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_vendor_evidence_scope_target_id ON vendor_evidence(scope_target_id);`,
+	}
+	for _, query := range queries {
+		if _, err := dbPool.Exec(context.Background(), query); err != nil {
+			log.Printf("[VENDORS] [ERROR] Failed to create table/index: %v", err)
+		}
+	}
+}
+
+// matchesVendorHost reports whether host is pattern or a subdomain of it.
+func matchesVendorHost(pattern, host string) bool {
+	pattern = strings.ToLower(pattern)
+	if strings.Contains(pattern, "*") {
+		for _, p := range []string{pattern, "*." + pattern} {
+			if ok, _ := path.Match(p, host); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return host == pattern || strings.HasSuffix(host, "."+pattern)
+}
+
+// spfIncludes returns the include and redirect domains of an SPF record.
+func spfIncludes(record string) []string {
+	var domains []string
+	for _, term := range strings.Fields(strings.ToLower(record)) {
+		term = strings.TrimLeft(term, "+-~?")
+		if value, ok := strings.CutPrefix(term, "include:"); ok {
+			domains = append(domains, value)
+		} else if value, ok := strings.CutPrefix(term, "redirect="); ok {
+			domains = append(domains, value)
+		}
+	}
+	return domains
+}
+
+// MatchVendors returns the evidence each signature finds in records, keyed
+// by vendor.
+func MatchVendors(fqdn string, records map[string][]string, signatures []VendorSignature) map[string][]VendorEvidence {
+	matches := make(map[string][]VendorEvidence)
+	for _, sig := range signatures {
+		var evidence []VendorEvidence
+		for _, kind := range []struct {
+			name     string
+			patterns []string
+		}{{"cname", sig.CNAME}, {"mx", sig.MX}, {"ns", sig.NS}, {"spf", sig.SPF}} {
+			for _, value := range records[kind.name] {
+				for _, pattern := range kind.patterns {
+					if matchesVendorHost(pattern, value) {
+						evidence = append(evidence, VendorEvidence{FQDN: fqdn, Type: kind.name, Value: value})
+						break
+					}
+				}
+			}
+		}
+		for _, value := range records["txt"] {
+			lower := strings.ToLower(value)
+			for _, prefix := range sig.TXT {
+				if strings.HasPrefix(lower, strings.ToLower(prefix)) {
+					evidence = append(evidence, VendorEvidence{FQDN: fqdn, Type: "txt", Value: value})
+					break
+				}
+			}
+		}
+		if len(evidence) > 0 {
+			matches[sig.Vendor] = evidence
+		}
+	}
+	return matches
+}
+
+// add files raw record values under the kinds signatures match.
+func (e *fqdnDNSEvidence) add(kind string, values ...string) {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if kind != "txt" {
+			v = strings.TrimSuffix(strings.ToLower(v), ".")
+		}
+		if kind == "mx" {
+			if fields := strings.Fields(v); len(fields) == 2 {
+				v = strings.TrimSuffix(fields[1], ".")
+			}
+		}
+		if v == "" || slices.Contains(e.records[kind], v) {
+			continue
+		}
+		e.records[kind] = append(e.records[kind], v)
+		if kind == "txt" && strings.HasPrefix(strings.ToLower(v), "v=spf1") {
+			e.add("spf", spfIncludes(v)...)
+		}
+	}
+}
+
+// loadFQDNDNSEvidence collects the stored DNS of every FQDN asset, plus the
+// company domains, and reports which still need a lookup.
+func loadFQDNDNSEvidence(ctx context.Context, scopeTargetID string) (map[string]*fqdnDNSEvidence, []*fqdnDNSEvidence, error) {
+	byFQDN := make(map[string]*fqdnDNSEvidence)
+	get := func(fqdn string) *fqdnDNSEvidence {
+		fqdn = strings.TrimSuffix(strings.ToLower(fqdn), ".")
+		e, ok := byFQDN[fqdn]
+		if !ok {
+			e = &fqdnDNSEvidence{fqdn: fqdn, records: make(map[string][]string)}
+			byFQDN[fqdn] = e
+		}
+		return e
+	}
+	scanned := make(map[string]bool)
+
+	rows, err := dbPool.Query(ctx, `
+		SELECT id::text, fqdn, last_dns_scan IS NOT NULL,
+			COALESCE(cname_records, '{}'), COALESCE(mx_records, '{}'), COALESCE(ns_records, '{}'),
+			COALESCE(txt_records, '{}'), COALESCE(spf_record, '')
+		FROM consolidated_attack_surface_assets
+		WHERE scope_target_id = $1::uuid AND asset_type = 'fqdn' AND fqdn IS NOT NULL AND fqdn != ''`, scopeTargetID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch FQDN assets: %v", err)
+	}
+	for rows.Next() {
+		var id, fqdn, spf string
+		var dnsScanned bool
+		var cnames, mx, ns, txt []string
+		if err := rows.Scan(&id, &fqdn, &dnsScanned, &cnames, &mx, &ns, &txt, &spf); err != nil {
+			continue
+		}
+		e := get(fqdn)
+		e.assetID = id
+		e.add("cname", cnames...)
+		e.add("mx", mx...)
+		e.add("ns", ns...)
+		e.add("txt", txt...)
+		e.add("spf", spfIncludes(spf)...)
+		if dnsScanned {
+			scanned[e.fqdn] = true
+		}
+	}
+	rows.Close()
+
+	rows, err = dbPool.Query(ctx, `
+		SELECT a.fqdn, r.record_type, r.record_value
+		FROM consolidated_attack_surface_dns_records r
+		JOIN consolidated_attack_surface_assets a ON a.id = r.asset_id
+		WHERE a.scope_target_id = $1::uuid AND a.asset_type = 'fqdn'
+			AND r.record_type IN ('CNAME', 'MX', 'NS', 'TXT')`, scopeTargetID)
+	if err == nil {
+		for rows.Next() {
+			var fqdn, recordType, value string
+			if rows.Scan(&fqdn, &recordType, &value) == nil {
+				get(fqdn).add(strings.ToLower(recordType), value)
+				scanned[strings.ToLower(fqdn)] = true
+			}
+		}
+		rows.Close()
+	}
+
+	domains, err := dbPool.Query(ctx, `SELECT domain FROM consolidated_company_domains WHERE scope_target_id = $1::uuid`, scopeTargetID)
+	if err == nil {
+		for domains.Next() {
+			var domain string
+			if domains.Scan(&domain) == nil {
+				get(domain)
+			}
+		}
+		domains.Close()
+	}
+
+	var pending []*fqdnDNSEvidence
+	for fqdn, e := range byFQDN {
+		if !scanned[fqdn] {
+			pending = append(pending, e)
+		}
+	}
+	return byFQDN, pending, nil
+}
+
+// lookupVendorEvidence resolves the CNAME chain of an FQDN, and the MX, NS
+// and TXT records of root domains, where verification tokens and SPF live.
+func lookupVendorEvidence(ctx context.Context, e *fqdnDNSEvidence) {
+	if chain, _, err := cnameChain(ctx, e.fqdn); err == nil {
+		e.add("cname", chain...)
+	}
+	if extractRootDomain(e.fqdn) != e.fqdn {
+		return
+	}
+	pool := defaultDNSPool()
+	for _, q := range []struct {
+		kind  string
+		qtype uint16
+	}{{"mx", dnsTypeMX}, {"ns", dnsTypeNS}, {"txt", dnsTypeTXT}} {
+		records, err := pool.Lookup(ctx, e.fqdn, q.qtype)
+		if err != nil {
+			continue
+		}
+		for _, rr := range records {
+			e.add(q.kind, rr.Value)
+		}
+	}
+}
+
+// RunVendorAttribution rebuilds a scope target's vendor inventory and its
+// 'vendor' assets and 'uses_vendor' relationships.
+func RunVendorAttribution(scopeTargetID string) error {
+	if _, running := vendorAttributionRun.LoadOrStore(scopeTargetID, true); running {
+		log.Printf("[VENDORS] [INFO] Attribution already running for %s, skipping", scopeTargetID)
+		return nil
+	}
+	defer vendorAttributionRun.Delete(scopeTargetID)
+
+	createVendorTables()
+	startTime := time.Now()
+	ctx := context.Background()
+	signatures := loadVendorSignatures()
+
+	byFQDN, pending, err := loadFQDNDNSEvidence(ctx, scopeTargetID)
+	if err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, vendorAttributionConcurrency)
+	for _, e := range pending {
+		wg.Add(1)
+		go func(e *fqdnDNSEvidence) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			lookupCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			defer cancel()
+			lookupVendorEvidence(lookupCtx, e)
+		}(e)
+	}
+	wg.Wait()
+
+	signatureByVendor := make(map[string]VendorSignature, len(signatures))
+	for _, sig := range signatures {
+		signatureByVendor[sig.Vendor] = sig
+	}
+	evidence := make(map[string][]VendorEvidence)
+	usedBy := make(map[string]map[string][]VendorEvidence) // vendor -> asset id -> evidence
+	for _, e := range byFQDN {
+		for vendor, found := range MatchVendors(e.fqdn, e.records, signatures) {
+			evidence[vendor] = append(evidence[vendor], found...)
+			if e.assetID == "" {
+				continue
+			}
+			if usedBy[vendor] == nil {
+				usedBy[vendor] = make(map[string][]VendorEvidence)
+			}
+			usedBy[vendor][e.assetID] = found
+		}
+	}
+
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Rows are stamped with the database's NOW(), which is fixed for the
+	// transaction, so stale rows are the ones older than it
+	var runStarted time.Time
+	if err := tx.QueryRow(ctx, `SELECT NOW()::timestamp`).Scan(&runStarted); err != nil {
+		return fmt.Errorf("failed to read database time: %v", err)
+	}
+
+	for vendor, found := range evidence {
+		sig := signatureByVendor[vendor]
+		fqdns := make(map[string]bool)
+		for _, ev := range found {
+			fqdns[ev.FQDN] = true
+			_, err := tx.Exec(ctx, `
+				INSERT INTO vendor_evidence (scope_target_id, vendor, fqdn, evidence_type, evidence_value)
+				VALUES ($1::uuid, $2, $3, $4, $5)
+				ON CONFLICT (scope_target_id, vendor, fqdn, evidence_type, evidence_value) DO UPDATE SET last_seen = NOW()`,
+				scopeTargetID, vendor, ev.FQDN, ev.Type, ev.Value)
+			if err != nil {
+				return fmt.Errorf("failed to store evidence for %s: %v", vendor, err)
+			}
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO vendor_inventory (scope_target_id, vendor, category, website, fqdn_count)
+			VALUES ($1::uuid, $2, $3, $4, $5)
+			ON CONFLICT (scope_target_id, vendor) DO UPDATE SET
+				category = EXCLUDED.category, website = EXCLUDED.website,
+				fqdn_count = EXCLUDED.fqdn_count, last_seen = NOW()`,
+			scopeTargetID, vendor, sig.Category, sig.Website, len(fqdns))
+		if err != nil {
+			return fmt.Errorf("failed to store vendor %s: %v", vendor, err)
+		}
+
+		var vendorAssetID string
+		err = tx.QueryRow(ctx, `
+			INSERT INTO consolidated_attack_surface_assets (scope_target_id, asset_type, asset_identifier, asset_subtype, domain)
+			VALUES ($1::uuid, 'vendor', $2, $3, $4)
+			ON CONFLICT (scope_target_id, asset_type, asset_identifier) DO UPDATE SET
+				asset_subtype = EXCLUDED.asset_subtype, domain = EXCLUDED.domain, last_updated = NOW()
+			RETURNING id::text`, scopeTargetID, vendor, sig.Category, sig.Website).Scan(&vendorAssetID)
+		if err != nil {
+			return fmt.Errorf("failed to store vendor asset %s: %v", vendor, err)
+		}
+		for assetID, found := range usedBy[vendor] {
+			data, _ := json.Marshal(map[string]interface{}{"evidence": found})
+			_, err := tx.Exec(ctx, `
+				INSERT INTO consolidated_attack_surface_relationships (parent_asset_id, child_asset_id, relationship_type, relationship_data)
+				VALUES ($1::uuid, $2::uuid, 'uses_vendor', $3)
+				ON CONFLICT (parent_asset_id, child_asset_id, relationship_type) DO UPDATE SET
+					relationship_data = EXCLUDED.relationship_data`,
+				assetID, vendorAssetID, data)
+			if err != nil {
+				return fmt.Errorf("failed to link %s to %s: %v", assetID, vendor, err)
+			}
+		}
+	}
+
+	// Drop what this run no longer sees
+	if _, err := tx.Exec(ctx, `DELETE FROM vendor_evidence WHERE scope_target_id = $1::uuid AND last_seen < $2`, scopeTargetID, runStarted); err != nil {
+		return fmt.Errorf("failed to clear stale evidence: %v", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM vendor_inventory WHERE scope_target_id = $1::uuid AND last_seen < $2`, scopeTargetID, runStarted); err != nil {
+		return fmt.Errorf("failed to clear stale vendors: %v", err)
+	}
+	_, err = tx.Exec(ctx, `
+		DELETE FROM consolidated_attack_surface_assets
+		WHERE scope_target_id = $1::uuid AND asset_type = 'vendor' AND last_updated < $2`, scopeTargetID, runStarted)
+	if err != nil {
+		return fmt.Errorf("failed to clear stale vendor assets: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit vendor inventory: %v", err)
+	}
+
+	log.Printf("[VENDORS] [INFO] Attributed %d vendors across %d FQDNs for %s in %s",
+		len(evidence), len(byFQDN), scopeTargetID, time.Since(startTime))
+	return nil
+}
+
+// GetVendorInventory lists the vendors of a scope target with the evidence
+// for each, most used first. ?category= limits to one category.
+func GetVendorInventory(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	if scopeTargetID == "" {
+		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
+		return
+	}
+	category := r.URL.Query().Get("category")
+
+	createVendorTables()
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT vendor, COALESCE(category, ''), COALESCE(website, ''), fqdn_count, first_seen, last_seen
+		FROM vendor_inventory
+		WHERE scope_target_id = $1::uuid AND ($2 = '' OR category = $2)`, scopeTargetID, category)
+	if err != nil {
+		log.Printf("[VENDORS] [ERROR] Failed to get vendor inventory: %v", err)
+		http.Error(w, "Failed to get vendor inventory", http.StatusInternalServerError)
+		return
+	}
+	items := make(map[string]*VendorInventoryItem)
+	for rows.Next() {
+		item := &VendorInventoryItem{Evidence: []VendorEvidence{}}
+		if err := rows.Scan(&item.Vendor, &item.Category, &item.Website, &item.FQDNCount, &item.FirstSeen, &item.LastSeen); err != nil {
+			log.Printf("[VENDORS] [ERROR] Error scanning vendor row: %v", err)
+			continue
+		}
+		items[item.Vendor] = item
+	}
+	rows.Close()
+
+	rows, err = dbPool.Query(context.Background(), `
+		SELECT vendor, fqdn, evidence_type, evidence_value
+		FROM vendor_evidence
+		WHERE scope_target_id = $1::uuid
+		ORDER BY fqdn, evidence_type, evidence_value`, scopeTargetID)
+	if err != nil {
+		log.Printf("[VENDORS] [ERROR] Failed to get vendor evidence: %v", err)
+		http.Error(w, "Failed to get vendor inventory", http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var vendor string
+		var ev VendorEvidence
+		if rows.Scan(&vendor, &ev.FQDN, &ev.Type, &ev.Value) != nil {
+			continue
+		}
+		if item, ok := items[vendor]; ok {
+			item.Evidence = append(item.Evidence, ev)
+		}
+	}
+	rows.Close()
+
+	inventory := make([]VendorInventoryItem, 0, len(items))
+	for _, item := range items {
+		inventory = append(inventory, *item)
+	}
+	sort.Slice(inventory, func(i, j int) bool {
+		if inventory[i].FQDNCount != inventory[j].FQDNCount {
+			return inventory[i].FQDNCount > inventory[j].FQDNCount
+		}
+		return inventory[i].Vendor < inventory[j].Vendor
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inventory)
+}
+
+// RunVendorAttributionHandler starts vendor attribution in the background.
+func RunVendorAttributionHandler(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	if scopeTargetID == "" {
+		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
+		return
+	}
+
+	go func() {
+		if err := RunVendorAttribution(scopeTargetID); err != nil {
+			log.Printf("[VENDORS] [ERROR] Attribution failed for %s: %v", scopeTargetID, err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "started"})
+}
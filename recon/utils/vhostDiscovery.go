@@ -0,0 +1,486 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// vhostDiscoveryTool replays requests to each live web server of a
+// company's IP/Port scans with candidate Host headers, and keeps the names
+// the server answers differently for. Candidates are the consolidated
+// subdomains and company domains plus the names on the certificates seen.
+type vhostDiscoveryTool struct{ executorTool }
+
+func init() {
+	RegisterTool(vhostDiscoveryTool{executorTool{
+		toolBase: toolBase{name: "vhost-discovery", input: ToolInputCompany, table: "vhost_discovery_scans"},
+		execute:  ExecuteVHostDiscovery,
+	}})
+}
+
+func (vhostDiscoveryTool) Schema() string {
+	return scanTableSchema("vhost_discovery_scans", "company_name")
+}
+
+type VHostBinding struct {
+	IPAddress      string    `json:"ip_address"`
+	Port           int       `json:"port"`
+	Scheme         string    `json:"scheme"`
+	Hostname       string    `json:"hostname"`
+	Source         string    `json:"source"`
+	URL            string    `json:"url"`
+	StatusCode     int       `json:"status_code"`
+	ContentLength  int64     `json:"content_length"`
+	Title          string    `json:"title"`
+	BaselineStatus int       `json:"baseline_status_code"`
+	BaselineLength int64     `json:"baseline_content_length"`
+	FirstSeen      time.Time `json:"first_seen"`
+	LastSeen       time.Time `json:"last_seen"`
+}
+
+// vhostResponse is what a response is compared on. The requested host is
+// replaced in the body and Location first, so servers that echo the Host
+// header do not look like they serve every name.
+type vhostResponse struct {
+	status   int
+	length   int64
+	hash     string
+	title    string
+	location string
+}
+
+type vhostServer struct {
+	ip     string
+	port   int
+	scheme string
+	sans   []string
+}
+
+type vhostCandidate struct {
+	hostname string
+	source   string
+}
+
+func createVHostTables() {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS vhost_bindings (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			scan_id UUID,
+			ip_address INET NOT NULL,
+			port INT NOT NULL,
+			scheme VARCHAR(10) NOT NULL,
+			hostname TEXT NOT NULL,
+			source TEXT,
+			url TEXT,
+			status_code INT,
+			content_length BIGINT,
+			body_sha256 TEXT,
+			title TEXT,
+			baseline_status_code INT,
+			baseline_content_length BIGINT,
+			first_seen TIMESTAMP DEFAULT NOW(),
+			last_seen TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scope_target_id, ip_address, port, hostname)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_vhost_bindings_scope_target_id ON vhost_bindings(scope_target_id);`,
+	}
+	for _, query := range queries {
+		if _, err := dbPool.Exec(context.Background(), query); err != nil {
+			log.Printf("[VHOST] [ERROR] Failed to create table/index: %v", err)
+		}
+	}
+}
+
+func fetchVHostResponse(ctx context.Context, p *HTTPProber, target, host string) (*vhostResponse, error) {
+	resp, _, err := p.do(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, httpProbeBodyLimit))
+
+	host = strings.ToLower(host)
+	body = bytes.ReplaceAll(bytes.ToLower(body), []byte(host), []byte("{host}"))
+	sum := sha256.Sum256(body)
+	return &vhostResponse{
+		status:   resp.StatusCode,
+		length:   int64(len(body)),
+		hash:     hex.EncodeToString(sum[:]),
+		title:    probeTitle(body),
+		location: strings.ReplaceAll(strings.ToLower(resp.Header.Get("Location")), host, "{host}"),
+	}, nil
+}
+
+// sameVHostResponse treats two responses as the same page when status and
+// redirect match and the bodies are equal, or close in length with the same
+// title, which allows for timestamps and tokens in otherwise static pages.
+func sameVHostResponse(a, b *vhostResponse) bool {
+	if a.status != b.status || a.location != b.location {
+		return false
+	}
+	if a.hash == b.hash {
+		return true
+	}
+	diff := a.length - b.length
+	if diff < 0 {
+		diff = -diff
+	}
+	return a.title == b.title && diff <= max(64, b.length/50)
+}
+
+func vhostURL(scheme, host string, port int) string {
+	// JoinHostPort brackets IPv6 literals; the default port is then dropped
+	hostPort := net.JoinHostPort(host, strconv.Itoa(port))
+	if (scheme == "https" && port == 443) || (scheme == "http" && port == 80) {
+		hostPort = strings.TrimSuffix(hostPort, ":"+strconv.Itoa(port))
+	}
+	return scheme + "://" + hostPort + "/"
+}
+
+// loadVHostServers returns the live web servers of the scope target's IP
+// scans along with the certificate names each presented.
+func loadVHostServers(scopeTargetID string) ([]vhostServer, error) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT DISTINCT ON (lws.ip_address, lws.port, lws.protocol)
+			host(lws.ip_address), lws.port, lws.url,
+			COALESCE(lws.ssl_info->>'subject_cn', ''),
+			COALESCE(ARRAY(SELECT jsonb_array_elements_text(lws.ssl_info->'subject_an')), ARRAY[]::text[])
+		FROM live_web_servers lws
+		JOIN ip_port_scans ips ON lws.scan_id = ips.scan_id
+		WHERE ips.scope_target_id = $1 AND ips.status = 'success'
+		ORDER BY lws.ip_address, lws.port, lws.protocol, lws.last_checked DESC`, scopeTargetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var servers []vhostServer
+	for rows.Next() {
+		var s vhostServer
+		var rawURL, subjectCN string
+		var sans []string
+		if err := rows.Scan(&s.ip, &s.port, &rawURL, &subjectCN, &sans); err != nil {
+			return nil, err
+		}
+		s.scheme = "http"
+		if u, err := url.Parse(rawURL); err == nil && u.Scheme != "" {
+			s.scheme = u.Scheme
+		}
+		s.sans = append(sans, subjectCN)
+		servers = append(servers, s)
+	}
+	return servers, rows.Err()
+}
+
+// loadVHostCandidates returns the names every server is tried with. Names
+// from the certificates of the scope's live servers and target URLs come
+// first, then company domains and consolidated subdomains.
+func loadVHostCandidates(scopeTargetID string, servers []vhostServer) []vhostCandidate {
+	var candidates []vhostCandidate
+	seen := make(map[string]bool)
+	add := func(name, source string) {
+		name = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+		if name == "" || seen[name] || strings.Contains(name, "*") || net.ParseIP(name) != nil || !strings.Contains(name, ".") {
+			return
+		}
+		seen[name] = true
+		candidates = append(candidates, vhostCandidate{name, source})
+	}
+
+	for _, s := range servers {
+		for _, name := range s.sans {
+			add(name, "cert_san")
+		}
+	}
+	queries := []struct{ source, query string }{
+		{"cert_san", `SELECT jsonb_array_elements_text(http_probe->'tls'->'subject_an') FROM target_urls
+			WHERE scope_target_id = $1 AND jsonb_typeof(http_probe->'tls'->'subject_an') = 'array'`},
+		{"company_domain", `SELECT domain FROM consolidated_company_domains WHERE scope_target_id = $1 ORDER BY domain`},
+		{"subdomain", `SELECT subdomain FROM consolidated_subdomains WHERE scope_target_id = $1 ORDER BY subdomain`},
+	}
+	for _, q := range queries {
+		rows, err := dbPool.Query(context.Background(), q.query, scopeTargetID)
+		if err != nil {
+			log.Printf("[VHOST] [WARN] Failed to load %s candidates: %v", q.source, err)
+			continue
+		}
+		for rows.Next() {
+			var name string
+			if rows.Scan(&name) == nil {
+				add(name, q.source)
+			}
+		}
+		rows.Close()
+	}
+	return candidates
+}
+
+func ExecuteVHostDiscovery(ctx context.Context, scanID string, input ToolInput) {
+	t, _ := LookupTool("vhost-discovery")
+	log.Printf("[VHOST] [INFO] Starting vhost discovery %s for scope target %s", scanID, input.ScopeTargetID)
+	startTime := time.Now()
+	createVHostTables()
+	UpdateToolScanStatus(t, scanID, "running", "", "", "", "")
+
+	servers, err := loadVHostServers(input.ScopeTargetID)
+	if err != nil {
+		UpdateToolScanStatus(t, scanID, "error", "", fmt.Sprintf("failed to load live web servers: %v", err), "", time.Since(startTime).String())
+		return
+	}
+	candidates := loadVHostCandidates(input.ScopeTargetID, servers)
+	if maxCandidates := envInt("RECON_VHOST_MAX_CANDIDATES", 2000); len(candidates) > maxCandidates {
+		log.Printf("[VHOST] [WARN] Trying %d of %d candidate hostnames (RECON_VHOST_MAX_CANDIDATES)", maxCandidates, len(candidates))
+		candidates = candidates[:maxCandidates]
+	}
+	workers := envInt("RECON_VHOST_WORKERS", 10)
+	if workers <= 0 {
+		workers = 10
+	}
+	command := fmt.Sprintf("native vhost discovery of %d servers with %d candidates", len(servers), len(candidates))
+	log.Printf("[VHOST] [INFO] %s", command)
+
+	jobs := make(chan vhostServer)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for server := range jobs {
+				discoverVHosts(ctx, scanID, input.ScopeTargetID, server, candidates)
+			}
+		}()
+	}
+feed:
+	for _, server := range servers {
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- server:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if ctx.Err() != nil {
+		log.Printf("[VHOST] [INFO] Vhost discovery %s cancelled", scanID)
+		return
+	}
+
+	if err := attachVHostBindings(input.ScopeTargetID); err != nil {
+		log.Printf("[VHOST] [ERROR] Failed to attach vhost bindings: %v", err)
+	}
+
+	var lines []string
+	rows, err := dbPool.Query(context.Background(),
+		`SELECT hostname, host(ip_address), port FROM vhost_bindings WHERE scan_id = $1 ORDER BY hostname, ip_address, port`, scanID)
+	if err == nil {
+		for rows.Next() {
+			var hostname, ip string
+			var port int
+			if rows.Scan(&hostname, &ip, &port) == nil {
+				lines = append(lines, fmt.Sprintf("%s %s", hostname, net.JoinHostPort(ip, strconv.Itoa(port))))
+			}
+		}
+		rows.Close()
+	}
+	result := lineResult(lines)
+	stderr := ""
+	if result.Result == "" {
+		stderr = "No virtual hosts found"
+	}
+	UpdateToolScanStatus(t, scanID, result.Status, result.Result, stderr, command, time.Since(startTime).String())
+	log.Printf("[VHOST] [INFO] Vhost discovery %s found %d bindings in %s", scanID, len(lines), time.Since(startTime))
+}
+
+// discoverVHosts tries every candidate against one server. A candidate is
+// kept when its response differs from all baselines twice in a row; the
+// baselines are the bare IP and two random names.
+func discoverVHosts(ctx context.Context, scanID, scopeTargetID string, server vhostServer, candidates []vhostCandidate) {
+	resolve := make(map[string]string, len(candidates)+2)
+	for _, c := range candidates {
+		resolve[c.hostname] = server.ip
+	}
+	randomHosts := []string{randomLabel() + ".invalid", randomLabel() + ".invalid"}
+	for _, h := range randomHosts {
+		resolve[h] = server.ip
+	}
+	prober := NewHTTPProber(HTTPProberOptions{
+		Timeout:   10 * time.Second,
+		RateLimit: envInt("RECON_VHOST_RPS", 10),
+		Resolve:   resolve,
+	})
+
+	var baselines []*vhostResponse
+	if resp, err := fetchVHostResponse(ctx, prober, vhostURL(server.scheme, server.ip, server.port), server.ip); err == nil {
+		baselines = append(baselines, resp)
+	}
+	for _, h := range randomHosts {
+		if resp, err := fetchVHostResponse(ctx, prober, vhostURL(server.scheme, h, server.port), h); err == nil {
+			baselines = append(baselines, resp)
+		}
+	}
+	if len(baselines) == 0 {
+		log.Printf("[VHOST] [WARN] %s:%d did not answer baseline requests, skipping", server.ip, server.port)
+		return
+	}
+
+	distinct := func(resp *vhostResponse) bool {
+		for _, b := range baselines {
+			if sameVHostResponse(resp, b) {
+				return false
+			}
+		}
+		return true
+	}
+
+	found := 0
+	for _, c := range candidates {
+		if ctx.Err() != nil {
+			return
+		}
+		target := vhostURL(server.scheme, c.hostname, server.port)
+		resp, err := fetchVHostResponse(ctx, prober, target, c.hostname)
+		if err != nil || !distinct(resp) {
+			continue
+		}
+		if again, err := fetchVHostResponse(ctx, prober, target, c.hostname); err != nil || !distinct(again) {
+			continue
+		}
+		found++
+
+		// The probe went to this IP, so it only seeds target_urls for a
+		// hostname not stored yet; the per-IP response lives in the binding.
+		storedURL, title := "", resp.title
+		if result, err := prober.Probe(ctx, target); err == nil {
+			title = result.Title
+			if err := insertTargetURLFromProbe(scopeTargetID, result); err != nil {
+				log.Printf("[VHOST] [ERROR] Failed to store target URL %s: %v", target, err)
+			} else {
+				storedURL = NormalizeURL(result.URL)
+			}
+		}
+		_, err = dbPool.Exec(context.Background(), `
+			INSERT INTO vhost_bindings (
+				scope_target_id, scan_id, ip_address, port, scheme, hostname, source, url,
+				status_code, content_length, body_sha256, title, baseline_status_code, baseline_content_length
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10, $11, $12, $13, $14)
+			ON CONFLICT (scope_target_id, ip_address, port, hostname) DO UPDATE SET
+				scan_id = EXCLUDED.scan_id, scheme = EXCLUDED.scheme, source = EXCLUDED.source,
+				url = COALESCE(EXCLUDED.url, vhost_bindings.url), status_code = EXCLUDED.status_code,
+				content_length = EXCLUDED.content_length, body_sha256 = EXCLUDED.body_sha256, title = EXCLUDED.title,
+				baseline_status_code = EXCLUDED.baseline_status_code,
+				baseline_content_length = EXCLUDED.baseline_content_length, last_seen = NOW()`,
+			scopeTargetID, scanID, server.ip, server.port, server.scheme, c.hostname, c.source, storedURL,
+			resp.status, resp.length, resp.hash, title, baselines[0].status, baselines[0].length)
+		if err != nil {
+			log.Printf("[VHOST] [ERROR] Failed to store binding %s -> %s:%d: %v", c.hostname, server.ip, server.port, err)
+		}
+	}
+	log.Printf("[VHOST] [INFO] %s:%d serves %d of %d candidate hostnames", server.ip, server.port, found, len(candidates))
+}
+
+// attachVHostBindings links each bound hostname's FQDN asset to the IP
+// asset it is served from. Consolidation rebuilds relationships, so this
+// runs again after it; FQDN assets missing for a hostname are added.
+func attachVHostBindings(scopeTargetID string) error {
+	createVHostTables()
+	rows, err := dbPool.Query(context.Background(),
+		`SELECT DISTINCT hostname FROM vhost_bindings WHERE scope_target_id = $1`, scopeTargetID)
+	if err != nil {
+		return err
+	}
+	var hostnames []string
+	for rows.Next() {
+		var hostname string
+		if rows.Scan(&hostname) == nil {
+			hostnames = append(hostnames, hostname)
+		}
+	}
+	rows.Close()
+
+	for _, hostname := range hostnames {
+		_, err := dbPool.Exec(context.Background(), `
+			INSERT INTO consolidated_attack_surface_assets (scope_target_id, asset_type, asset_identifier, fqdn, root_domain)
+			VALUES ($1, 'fqdn', $2, $2, $3)
+			ON CONFLICT (scope_target_id, asset_type, asset_identifier) DO NOTHING`,
+			scopeTargetID, hostname, extractRootDomain(hostname))
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := dbPool.Exec(context.Background(), `
+		INSERT INTO consolidated_attack_surface_relationships (
+			parent_asset_id, child_asset_id, relationship_type, relationship_data
+		)
+		SELECT fqdn.id, ip.id, 'virtual_host',
+			jsonb_build_object('ports', jsonb_agg(DISTINCT jsonb_build_object('port', vb.port, 'scheme', vb.scheme, 'url', vb.url)))
+		FROM vhost_bindings vb
+		JOIN consolidated_attack_surface_assets fqdn ON fqdn.scope_target_id = vb.scope_target_id
+			AND fqdn.asset_type = 'fqdn' AND fqdn.asset_identifier = vb.hostname
+		JOIN consolidated_attack_surface_assets ip ON ip.scope_target_id = vb.scope_target_id
+			AND ip.asset_type = 'ip_address' AND ip.ip_address = host(vb.ip_address)
+		WHERE vb.scope_target_id = $1
+		GROUP BY fqdn.id, ip.id
+		ON CONFLICT (parent_asset_id, child_asset_id, relationship_type) DO UPDATE SET
+			relationship_data = EXCLUDED.relationship_data`, scopeTargetID)
+	if err != nil {
+		return err
+	}
+	log.Printf("[VHOST] [INFO] Linked %d vhost bindings to IP assets", result.RowsAffected())
+	return nil
+}
+
+// GetVHostBindings returns the confirmed vhost to IP bindings of a scope
+// target.
+func GetVHostBindings(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	if scopeTargetID == "" {
+		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
+		return
+	}
+
+	createVHostTables()
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT host(ip_address), port, scheme, hostname, COALESCE(source, ''), COALESCE(url, ''),
+			COALESCE(status_code, 0), COALESCE(content_length, 0), COALESCE(title, ''),
+			COALESCE(baseline_status_code, 0), COALESCE(baseline_content_length, 0), first_seen, last_seen
+		FROM vhost_bindings
+		WHERE scope_target_id = $1
+		ORDER BY ip_address, port, hostname`, scopeTargetID)
+	if err != nil {
+		log.Printf("[VHOST] [ERROR] Failed to get vhost bindings: %v", err)
+		http.Error(w, "Failed to get vhost bindings", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	bindings := []VHostBinding{}
+	for rows.Next() {
+		var b VHostBinding
+		if err := rows.Scan(&b.IPAddress, &b.Port, &b.Scheme, &b.Hostname, &b.Source, &b.URL, &b.StatusCode,
+			&b.ContentLength, &b.Title, &b.BaselineStatus, &b.BaselineLength, &b.FirstSeen, &b.LastSeen); err != nil {
+			log.Printf("[VHOST] [ERROR] Error scanning vhost binding: %v", err)
+			continue
+		}
+		bindings = append(bindings, b)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bindings)
+}
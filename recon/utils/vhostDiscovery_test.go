@@ -0,0 +1,24 @@
+package utils
+
+import "testing"
+
+func TestVHostURL(t *testing.T) {
+	tests := []struct {
+		scheme, host string
+		port         int
+		want         string
+	}{
+		{"https", "192.0.2.10", 443, "https://192.0.2.10/"},
+		{"http", "192.0.2.10", 80, "http://192.0.2.10/"},
+		{"https", "192.0.2.10", 8443, "https://192.0.2.10:8443/"},
+		{"http", "192.0.2.10", 443, "http://192.0.2.10:443/"},
+		{"https", "2001:db8::1", 443, "https://[2001:db8::1]/"},
+		{"http", "2001:db8::1", 80, "http://[2001:db8::1]/"},
+		{"https", "2001:db8::1", 8443, "https://[2001:db8::1]:8443/"},
+	}
+	for _, tt := range tests {
+		if got := vhostURL(tt.scheme, tt.host, tt.port); got != tt.want {
+			t.Errorf("vhostURL(%q, %q, %d) = %q, want %q", tt.scheme, tt.host, tt.port, got, tt.want)
+		}
+	}
+}
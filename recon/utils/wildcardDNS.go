@@ -0,0 +1,653 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// Wildcard detection probes random labels under every parent zone of the
+// discovered names. A zone whose random names resolve is a wildcard zone; a
+// name below it is a wildcard suspect when its answers fall inside the
+// wildcard's answer set and its HTTP response looks like the wildcard's.
+
+const (
+	wildcardProbeLabels      = 3
+	wildcardZoneConcurrency  = 10
+	wildcardCheckConcurrency = 20
+	wildcardHTTPTimeout      = 5 * time.Second
+	wildcardDetectionTimeout = 15 * time.Minute
+	wildcardBodyLimit        = 64 << 10
+)
+
+// WildcardZone is the result of probing one parent zone.
+type WildcardZone struct {
+	Zone       string    `json:"zone"`
+	IsWildcard bool      `json:"is_wildcard"`
+	Answers    []string  `json:"answers"`
+	HTTP       *httpSig  `json:"http,omitempty"`
+	Suspects   int       `json:"suspects"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// httpSig summarises an HTTP response for comparing a name against its
+// wildcard zone. The body hash is taken after the host name is masked, since
+// wildcard pages often echo it.
+type httpSig struct {
+	Scheme   string `json:"scheme"`
+	Status   int    `json:"status"`
+	Length   int    `json:"length"`
+	BodyHash string `json:"body_hash"`
+	Location string `json:"location,omitempty"`
+}
+
+// matches reports whether two responses are the same page: same status and
+// redirect target, and the same body or lengths within 3%.
+func (s *httpSig) matches(o *httpSig) bool {
+	if s == nil || o == nil || s.Status != o.Status || s.Location != o.Location {
+		return false
+	}
+	if s.BodyHash == o.BodyHash {
+		return true
+	}
+	longer := max(s.Length, o.Length)
+	diff := s.Length - o.Length
+	if diff < 0 {
+		diff = -diff
+	}
+	return longer > 0 && diff*100 <= longer*3
+}
+
+// WildcardDNSConfig holds the per-scope-target override for httpx rounds.
+type WildcardDNSConfig struct {
+	IncludeWildcardSuspects bool `json:"include_wildcard_suspects"`
+}
+
+func createWildcardDNSTables() {
+	queries := []string{
+		`ALTER TABLE consolidated_subdomains ADD COLUMN IF NOT EXISTS wildcard_suspect BOOLEAN NOT NULL DEFAULT false`,
+		`CREATE TABLE IF NOT EXISTS wildcard_dns_zones (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			zone TEXT NOT NULL,
+			is_wildcard BOOLEAN NOT NULL DEFAULT false,
+			answers TEXT[],
+			http_signature JSONB,
+			suspects INT NOT NULL DEFAULT 0,
+			checked_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scope_target_id, zone)
+		)`,
+		`CREATE TABLE IF NOT EXISTS wildcard_dns_configs (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scope_target_id UUID NOT NULL UNIQUE REFERENCES scope_targets(id) ON DELETE CASCADE,
+			include_wildcard_suspects BOOLEAN NOT NULL DEFAULT false,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW()
+		)`,
+	}
+	for _, query := range queries {
+		if _, err := dbPool.Exec(context.Background(), query); err != nil {
+			log.Printf("[WILDCARD-DNS] [ERROR] Failed to create wildcard tables: %v", err)
+		}
+	}
+}
+
+// parentZones returns every parent of name down to and including baseDomain,
+// nearest first.
+func parentZones(name, baseDomain string) []string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	baseDomain = strings.ToLower(baseDomain)
+	var zones []string
+	for {
+		i := strings.Index(name, ".")
+		if i < 0 {
+			return zones
+		}
+		name = name[i+1:]
+		if name != baseDomain && !strings.HasSuffix(name, "."+baseDomain) {
+			return zones
+		}
+		zones = append(zones, name)
+		if name == baseDomain {
+			return zones
+		}
+	}
+}
+
+func randomLabel() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "wc-" + hex.EncodeToString(b)
+}
+
+// resolveAnswerSet returns the sorted A, AAAA and CNAME values of name. A
+// failed query or an answer other than NOERROR and NXDOMAIN is an error,
+// since the name's answers are then unknown rather than empty.
+func resolveAnswerSet(ctx context.Context, name string) ([]string, error) {
+	pool := defaultDNSPool()
+	seen := make(map[string]bool)
+	for _, qtype := range []uint16{dnsTypeA, dnsTypeAAAA} {
+		resp, err := pool.Query(ctx, name, qtype)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Rcode == dnsRcodeNXDomain {
+			continue
+		}
+		if resp.Rcode != dnsRcodeSuccess {
+			return nil, fmt.Errorf("%s %s: %s", name, dnsTypeName(qtype), dnsRcodeName(resp.Rcode))
+		}
+		for _, rr := range resp.Answers {
+			if rr.Type == "A" || rr.Type == "AAAA" || rr.Type == "CNAME" {
+				seen[strings.TrimSuffix(strings.ToLower(rr.Value), ".")] = true
+			}
+		}
+	}
+	answers := make([]string, 0, len(seen))
+	for answer := range seen {
+		answers = append(answers, answer)
+	}
+	sort.Strings(answers)
+	return answers, nil
+}
+
+// probeWildcardZone resolves random labels under zone. The zone is a
+// wildcard when most of them resolve; its answer set is the union of what
+// they resolved to. A probe that can't be resolved fails the zone.
+func probeWildcardZone(ctx context.Context, zone string) (*WildcardZone, error) {
+	result := &WildcardZone{Zone: zone, Answers: []string{}, CheckedAt: time.Now()}
+	resolved := 0
+	seen := make(map[string]bool)
+	var probe string
+	for i := 0; i < wildcardProbeLabels; i++ {
+		name := randomLabel() + "." + zone
+		answers, err := resolveAnswerSet(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if len(answers) == 0 {
+			continue
+		}
+		resolved++
+		probe = name
+		for _, answer := range answers {
+			if !seen[answer] {
+				seen[answer] = true
+				result.Answers = append(result.Answers, answer)
+			}
+		}
+	}
+	sort.Strings(result.Answers)
+	result.IsWildcard = resolved*2 > wildcardProbeLabels
+	if result.IsWildcard {
+		result.HTTP = fetchHTTPSig(ctx, probe)
+	}
+	return result, nil
+}
+
+var wildcardHTTPClient = &http.Client{
+	Timeout: wildcardHTTPTimeout,
+	Transport: &http.Transport{
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		TLSHandshakeTimeout: wildcardHTTPTimeout,
+		DisableKeepAlives:   true,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// fetchHTTPSig requests / on host over HTTPS, then HTTP, and returns the
+// signature of the first response or nil if neither answered.
+func fetchHTTPSig(ctx context.Context, host string) *httpSig {
+	masker := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(host))
+	for _, scheme := range []string{"https", "http"} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+host+"/", nil)
+		if err != nil {
+			return nil
+		}
+		resp, err := wildcardHTTPClient.Do(req)
+		if err != nil {
+			continue
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, wildcardBodyLimit))
+		resp.Body.Close()
+
+		masked := masker.ReplaceAll(body, []byte("{host}"))
+		sum := sha256.Sum256(masked)
+		return &httpSig{
+			Scheme:   scheme,
+			Status:   resp.StatusCode,
+			Length:   len(masked),
+			BodyHash: hex.EncodeToString(sum[:]),
+			Location: masker.ReplaceAllString(resp.Header.Get("Location"), "{host}"),
+		}
+	}
+	return nil
+}
+
+// isWildcardSuspect compares name against the wildcard zone it sits under.
+// Names whose answers leave the wildcard's set are real; names inside it
+// are suspects unless they serve a different HTTP response. It fails when
+// the name can't be resolved or, if the zone answered HTTP, fetched.
+func isWildcardSuspect(ctx context.Context, name string, zone *WildcardZone) (bool, error) {
+	answers, err := resolveAnswerSet(ctx, name)
+	if err != nil || len(answers) == 0 {
+		return false, err
+	}
+	for _, answer := range answers {
+		if !slices.Contains(zone.Answers, answer) {
+			return false, nil
+		}
+	}
+	if zone.HTTP == nil {
+		return true, nil
+	}
+	sig := fetchHTTPSig(ctx, name)
+	if sig == nil {
+		return false, fmt.Errorf("no HTTP response from %s", name)
+	}
+	return zone.HTTP.matches(sig), nil
+}
+
+// DetectWildcardSubdomains probes the parent zones of subdomains and returns
+// a verdict (true for a wildcard suspect) for every name it could check,
+// along with every zone probed. Names whose zone probe or own check failed
+// have no verdict. Zones are stored in wildcard_dns_zones for the scope
+// target; a storage failure is returned alongside the verdicts, which are
+// still valid.
+func DetectWildcardSubdomains(ctx context.Context, scopeTargetID, baseDomain string, subdomains []string) (map[string]bool, []*WildcardZone, error) {
+	createWildcardDNSTables()
+	ctx, cancel := context.WithTimeout(ctx, wildcardDetectionTimeout)
+	defer cancel()
+	startTime := time.Now()
+
+	zoneSet := make(map[string]bool)
+	for _, subdomain := range subdomains {
+		for _, zone := range parentZones(subdomain, baseDomain) {
+			zoneSet[zone] = true
+		}
+	}
+
+	zones := make(map[string]*WildcardZone, len(zoneSet))
+	failedZones := make(map[string]bool)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, wildcardZoneConcurrency)
+	for zone := range zoneSet {
+		wg.Add(1)
+		go func(zone string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			result, err := probeWildcardZone(ctx, zone)
+			mu.Lock()
+			if err != nil {
+				failedZones[zone] = true
+			} else {
+				zones[zone] = result
+			}
+			mu.Unlock()
+			if err != nil {
+				log.Printf("[WILDCARD-DNS] [WARN] Failed to probe %s: %v", zone, err)
+			}
+		}(zone)
+	}
+	wg.Wait()
+
+	// A name is judged against the nearest wildcard zone above it; a name
+	// under a wildcard that matches its own answers is not a suspect of a
+	// wildcard further up. A failed probe on the way up leaves the name
+	// undecided.
+	verdicts := make(map[string]bool)
+	semaphore = make(chan struct{}, wildcardCheckConcurrency)
+	for _, subdomain := range subdomains {
+		var zone *WildcardZone
+		undecided := false
+		for _, parent := range parentZones(subdomain, baseDomain) {
+			if failedZones[parent] {
+				undecided = true
+				break
+			}
+			if z := zones[parent]; z != nil && z.IsWildcard {
+				zone = z
+				break
+			}
+		}
+		if undecided {
+			continue
+		}
+		if zone == nil {
+			verdicts[subdomain] = false
+			continue
+		}
+		wg.Add(1)
+		go func(subdomain string, zone *WildcardZone) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			suspect, err := isWildcardSuspect(ctx, subdomain, zone)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			verdicts[subdomain] = suspect
+			if suspect {
+				zone.Suspects++
+			}
+			mu.Unlock()
+		}(subdomain, zone)
+	}
+	wg.Wait()
+	suspects := 0
+	for _, suspect := range verdicts {
+		if suspect {
+			suspects++
+		}
+	}
+
+	results := make([]*WildcardZone, 0, len(zones))
+	wildcards := 0
+	for _, zone := range zones {
+		results = append(results, zone)
+		if zone.IsWildcard {
+			wildcards++
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Zone < results[j].Zone })
+	var err error
+	if scopeTargetID != "" {
+		err = storeWildcardZones(scopeTargetID, results)
+	}
+
+	log.Printf("[WILDCARD-DNS] [INFO] Probed %d zones for %s in %s: %d wildcard, %d failed, %d of %d names suspect, %d undecided",
+		len(results), baseDomain, time.Since(startTime), wildcards, len(failedZones), suspects, len(subdomains), len(subdomains)-len(verdicts))
+	return verdicts, results, err
+}
+
+// wildcardFlagRun is the background wildcard pass of one scope target.
+// done closes once the pass and any passes queued behind it finish.
+type wildcardFlagRun struct {
+	done    chan struct{}
+	pending []string
+	queued  bool
+}
+
+var (
+	wildcardFlagMu   sync.Mutex
+	wildcardFlagRuns = make(map[string]*wildcardFlagRun)
+)
+
+// FlagWildcardSubdomains starts wildcard detection over a scope target's
+// consolidated subdomains in the background and sets wildcard_suspect on
+// the stored rows. A call while a pass runs queues one more pass over the
+// latest names; WaitForWildcardFlags blocks until they are all done.
+func FlagWildcardSubdomains(scopeTargetID, baseDomain string, subdomains []string) {
+	wildcardFlagMu.Lock()
+	if run := wildcardFlagRuns[scopeTargetID]; run != nil {
+		run.pending, run.queued = subdomains, true
+		wildcardFlagMu.Unlock()
+		log.Printf("[WILDCARD-DNS] [INFO] Wildcard detection already running for %s, queued another pass", scopeTargetID)
+		return
+	}
+	run := &wildcardFlagRun{done: make(chan struct{})}
+	wildcardFlagRuns[scopeTargetID] = run
+	wildcardFlagMu.Unlock()
+
+	go func() {
+		for {
+			if err := flagWildcardSubdomains(scopeTargetID, baseDomain, subdomains); err != nil {
+				log.Printf("[WILDCARD-DNS] [ERROR] Wildcard detection failed for scope target %s: %v", scopeTargetID, err)
+			}
+			wildcardFlagMu.Lock()
+			if !run.queued {
+				delete(wildcardFlagRuns, scopeTargetID)
+				close(run.done)
+				wildcardFlagMu.Unlock()
+				return
+			}
+			subdomains, run.pending, run.queued = run.pending, nil, false
+			wildcardFlagMu.Unlock()
+		}
+	}()
+}
+
+// WaitForWildcardFlags blocks until the scope target's wildcard passes are
+// done or ctx ends.
+func WaitForWildcardFlags(ctx context.Context, scopeTargetID string) {
+	wildcardFlagMu.Lock()
+	run := wildcardFlagRuns[scopeTargetID]
+	wildcardFlagMu.Unlock()
+	if run == nil {
+		return
+	}
+	log.Printf("[WILDCARD-DNS] [INFO] Waiting for wildcard detection of %s", scopeTargetID)
+	select {
+	case <-run.done:
+	case <-ctx.Done():
+	}
+}
+
+// flagWildcardSubdomains runs one wildcard pass. Only names with a verdict
+// are updated, so a name whose check failed keeps its previous flag.
+func flagWildcardSubdomains(scopeTargetID, baseDomain string, subdomains []string) error {
+	verdicts, _, storeErr := DetectWildcardSubdomains(context.Background(), scopeTargetID, baseDomain, subdomains)
+	if storeErr != nil {
+		log.Printf("[WILDCARD-DNS] [ERROR] %v", storeErr)
+	}
+
+	checked := make([]string, 0, len(verdicts))
+	suspects := make([]string, 0, len(verdicts))
+	for name, suspect := range verdicts {
+		checked = append(checked, name)
+		if suspect {
+			suspects = append(suspects, name)
+		}
+	}
+	_, err := dbPool.Exec(context.Background(),
+		`UPDATE consolidated_subdomains SET wildcard_suspect = (subdomain = ANY($2))
+		 WHERE scope_target_id = $1 AND subdomain = ANY($3)`,
+		scopeTargetID, suspects, checked)
+	if err != nil {
+		return fmt.Errorf("failed to flag wildcard suspects: %v", err)
+	}
+	log.Printf("[WILDCARD-DNS] [INFO] Flagged %d of %d checked consolidated subdomains as wildcard suspects for %s (%d undecided)",
+		len(suspects), len(checked), scopeTargetID, len(subdomains)-len(checked))
+	return nil
+}
+
+// storeWildcardZones upserts every zone and returns the first failure.
+func storeWildcardZones(scopeTargetID string, zones []*WildcardZone) error {
+	var firstErr error
+	for _, zone := range zones {
+		var signature []byte
+		if zone.HTTP != nil {
+			signature, _ = json.Marshal(zone.HTTP)
+		}
+		_, err := dbPool.Exec(context.Background(), `
+			INSERT INTO wildcard_dns_zones (scope_target_id, zone, is_wildcard, answers, http_signature, suspects, checked_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (scope_target_id, zone) DO UPDATE SET
+				is_wildcard = EXCLUDED.is_wildcard,
+				answers = EXCLUDED.answers,
+				http_signature = EXCLUDED.http_signature,
+				suspects = EXCLUDED.suspects,
+				checked_at = EXCLUDED.checked_at`,
+			scopeTargetID, zone.Zone, zone.IsWildcard, zone.Answers, signature, zone.Suspects, zone.CheckedAt)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to store wildcard zone %s: %v", zone.Zone, err)
+		}
+	}
+	return firstErr
+}
+
+// reportShuffleDNSWildcards logs how much of a brute-force result for domain
+// sits under a wildcard. Suspects are still stored; consolidation marks them.
+func reportShuffleDNSWildcards(ctx context.Context, scanID, domain, result string) {
+	var scopeTargetID string
+	dbPool.QueryRow(context.Background(), `
+		SELECT COALESCE(scope_target_id::text, '') FROM shuffledns_scans WHERE scan_id = $1
+		UNION ALL
+		SELECT COALESCE(scope_target_id::text, '') FROM shufflednscustom_scans WHERE scan_id = $1
+		LIMIT 1`, scanID).Scan(&scopeTargetID)
+
+	var names []string
+	for _, line := range strings.Split(result, "\n") {
+		if name := strings.TrimSpace(line); name != "" {
+			names = append(names, name)
+		}
+	}
+	verdicts, _, err := DetectWildcardSubdomains(ctx, scopeTargetID, domain, names)
+	if err != nil {
+		log.Printf("[WILDCARD-DNS] [ERROR] %v", err)
+	}
+	suspects := 0
+	for _, suspect := range verdicts {
+		if suspect {
+			suspects++
+		}
+	}
+	if suspects > 0 {
+		log.Printf("[WILDCARD-DNS] [WARN] %d of %d ShuffleDNS results for %s are wildcard suspects", suspects, len(names), domain)
+	}
+}
+
+func loadWildcardDNSConfig(scopeTargetID string) (WildcardDNSConfig, error) {
+	var c WildcardDNSConfig
+	err := dbPool.QueryRow(context.Background(),
+		`SELECT include_wildcard_suspects FROM wildcard_dns_configs WHERE scope_target_id = $1`,
+		scopeTargetID).Scan(&c.IncludeWildcardSuspects)
+	if err == pgx.ErrNoRows {
+		return c, nil
+	}
+	return c, err
+}
+
+// includeWildcardSuspects reports whether httpx rounds for the scope target
+// should probe wildcard suspects too.
+func includeWildcardSuspects(scopeTargetID string) bool {
+	createWildcardDNSTables()
+	c, err := loadWildcardDNSConfig(scopeTargetID)
+	if err != nil {
+		log.Printf("[WILDCARD-DNS] [ERROR] Failed to load config for %s: %v", scopeTargetID, err)
+	}
+	return c.IncludeWildcardSuspects
+}
+
+// GetWildcardDNS returns the probed zones, the wildcard suspects and the
+// httpx override for a scope target.
+func GetWildcardDNS(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	if scopeTargetID == "" {
+		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
+		return
+	}
+
+	createWildcardDNSTables()
+	config, err := loadWildcardDNSConfig(scopeTargetID)
+	if err != nil {
+		log.Printf("[WILDCARD-DNS] [ERROR] Failed to fetch config: %v", err)
+		http.Error(w, "Failed to fetch configuration", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT zone, is_wildcard, COALESCE(answers, '{}'), http_signature, suspects, checked_at
+		FROM wildcard_dns_zones WHERE scope_target_id = $1
+		ORDER BY is_wildcard DESC, zone`, scopeTargetID)
+	if err != nil {
+		log.Printf("[WILDCARD-DNS] [ERROR] Failed to fetch zones: %v", err)
+		http.Error(w, "Failed to fetch wildcard zones", http.StatusInternalServerError)
+		return
+	}
+	zones := []WildcardZone{}
+	for rows.Next() {
+		var zone WildcardZone
+		var signature []byte
+		if err := rows.Scan(&zone.Zone, &zone.IsWildcard, &zone.Answers, &signature, &zone.Suspects, &zone.CheckedAt); err != nil {
+			log.Printf("[WILDCARD-DNS] [ERROR] Error scanning zone row: %v", err)
+			continue
+		}
+		if signature != nil {
+			zone.HTTP = &httpSig{}
+			json.Unmarshal(signature, zone.HTTP)
+		}
+		zones = append(zones, zone)
+	}
+	rows.Close()
+
+	suspects := []string{}
+	rows, err = dbPool.Query(context.Background(), `
+		SELECT subdomain FROM consolidated_subdomains
+		WHERE scope_target_id = $1 AND wildcard_suspect
+		ORDER BY subdomain`, scopeTargetID)
+	if err == nil {
+		for rows.Next() {
+			var subdomain string
+			if rows.Scan(&subdomain) == nil {
+				suspects = append(suspects, subdomain)
+			}
+		}
+		rows.Close()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"config":   config,
+		"zones":    zones,
+		"suspects": suspects,
+	})
+}
+
+// SaveWildcardDNSConfig sets whether httpx rounds include wildcard suspects
+// for a scope target.
+func SaveWildcardDNSConfig(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	if scopeTargetID == "" {
+		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var config WildcardDNSConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	createWildcardDNSTables()
+	_, err := dbPool.Exec(context.Background(), `
+		INSERT INTO wildcard_dns_configs (scope_target_id, include_wildcard_suspects, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (scope_target_id) DO UPDATE SET
+			include_wildcard_suspects = EXCLUDED.include_wildcard_suspects,
+			updated_at = NOW()`,
+		scopeTargetID, config.IncludeWildcardSuspects)
+	if err != nil {
+		log.Printf("[WILDCARD-DNS] [ERROR] Failed to save config: %v", err)
+		http.Error(w, "Failed to save configuration", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[WILDCARD-DNS] [INFO] Saved config for scope target %s (include suspects: %t)", scopeTargetID, config.IncludeWildcardSuspects)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"config":  config,
+	})
+}